@@ -6,9 +6,19 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberconfig "github.com/codeready-toolchain/member-operator/pkg/config"
 	"github.com/codeready-toolchain/member-operator/pkg/controller"
+	"github.com/codeready-toolchain/member-operator/pkg/leadership"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+	membermetrics "github.com/codeready-toolchain/member-operator/pkg/metrics"
+	"github.com/codeready-toolchain/member-operator/pkg/profiling"
+	"github.com/codeready-toolchain/member-operator/pkg/shutdown"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/member-operator/pkg/tracing"
+	"github.com/codeready-toolchain/member-operator/pkg/webhook"
 	"github.com/codeready-toolchain/member-operator/version"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 
@@ -19,7 +29,6 @@ import (
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	kubemetrics "github.com/operator-framework/operator-sdk/pkg/kube-metrics"
 	"github.com/operator-framework/operator-sdk/pkg/leader"
-	"github.com/operator-framework/operator-sdk/pkg/log/zap"
 	"github.com/operator-framework/operator-sdk/pkg/metrics"
 	"github.com/operator-framework/operator-sdk/pkg/restmapper"
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
@@ -39,9 +48,46 @@ var (
 	metricsHost               = "0.0.0.0"
 	metricsPort         int32 = 8383
 	operatorMetricsPort int32 = 8686
+	// webhookPort the port of the webhook server, which is only started when a webhook is enabled
+	webhookPort = 8443
+	// probesPort the port of the liveness and readiness probes
+	probesPort = 8081
 )
 var log = logf.Log.WithName("cmd")
 
+// zapDevel when enabled, the logs are written in a human-readable format instead of JSON
+var zapDevel bool
+
+// warmStandby when enabled, the leader election is delegated to the manager: replicas which are not the leader keep
+// their caches synced, but their controllers remain idle until they acquire the leadership
+var warmStandby bool
+
+// the durations of the leader election in warm standby mode: a standby replica takes over at most `leaseDuration` after the leader is gone
+var (
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+)
+
+// shutdownGracePeriod the maximum time to wait for the reconciliations and the applies in progress when the operator is stopped
+var shutdownGracePeriod time.Duration
+
+// pprofPort the port of the pprof endpoints, which are only served (on the loopback interface) if it is set
+var pprofPort int
+
+// the client-side rate limit of the requests sent to the API server by the operator, shared by all the controllers
+var (
+	apiQPS   float32
+	apiBurst int
+)
+
+const (
+	// leaderLockName the name of the lock used when the operator becomes the leader for life
+	leaderLockName = "member-operator-lock"
+	// leaderElectionID the name of the lock used by the manager's leader election in warm standby mode
+	leaderElectionID = "member-operator-standby-lock"
+)
+
 func printVersion() {
 	log.Info(fmt.Sprintf("Go Version: %s", runtime.Version()))
 	log.Info(fmt.Sprintf("Go OS/Arch: %s/%s", runtime.GOOS, runtime.GOARCH))
@@ -51,25 +97,40 @@ func printVersion() {
 }
 
 func main() {
-	// Add the zap logger flag set to the CLI. The flag set must
-	// be added before calling pflag.Parse().
-	pflag.CommandLine.AddFlagSet(zap.FlagSet())
+	// render the templates of a tier without starting the operator
+	if len(os.Args) > 1 && os.Args[1] == renderCommand {
+		if err := runRender(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Add flags registered by imported packages (e.g. glog and
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	pflag.BoolVar(&zapDevel, "zap-devel", false, "log in a human-readable format instead of JSON, with the stacktraces of the errors")
+	pflag.BoolVar(&warmStandby, "warm-standby", false, "run the operator in warm standby mode, ie, with caches synced while waiting for the leadership")
+	pflag.DurationVar(&leaseDuration, "leader-election-lease-duration", 15*time.Second, "the duration that the standby replicas wait before taking over the leadership (warm standby mode only)")
+	pflag.DurationVar(&renewDeadline, "leader-election-renew-deadline", 10*time.Second, "the duration that the leader retries to renew its leadership before giving it up (warm standby mode only)")
+	pflag.DurationVar(&retryPeriod, "leader-election-retry-period", 2*time.Second, "the duration between two attempts to acquire or renew the leadership (warm standby mode only)")
+	pflag.IntVar(&pprofPort, "pprof-port", 0, "the port of the pprof endpoints, served on the loopback interface only. The endpoints are disabled if not set")
+	pflag.Float32Var(&apiQPS, "kube-api-qps", rest.DefaultQPS, "the number of requests per second sent to the API server by the operator, above which the requests are throttled client-side")
+	pflag.IntVar(&apiBurst, "kube-api-burst", rest.DefaultBurst, "the number of requests which can be sent to the API server at once, above the QPS")
+	pflag.DurationVar(&shutdownGracePeriod, "shutdown-grace-period", 30*time.Second, "the maximum time to wait for the reconciliations and the applies in progress when the operator is stopped")
+
 	pflag.Parse()
 
-	// Use a zap logr.Logger implementation. If none of the zap
-	// flags are configured (or if the zap flag set is not being
-	// used), this defaults to a production zap logger.
+	// Use a zap logr.Logger implementation, which logs at the info level until a debug level is configured
+	// in the MemberOperatorConfig (see the `logging` package).
 	//
 	// The logger instantiated here can be changed to any logger
 	// implementing the logr.Logger interface. This logger will
 	// be propagated through the whole operator, generating
 	// uniform and structured logs.
-	logf.SetLogger(zap.Logger())
+	// The levels of its logs can be changed without restarting the operator.
+	logf.SetLogger(logging.NewLogger(logging.NewZapLogger(zapDevel)))
 
 	printVersion()
 
@@ -85,14 +146,37 @@ func main() {
 		log.Error(err, "")
 		os.Exit(1)
 	}
+	// count the requests sent to the API server, so that the throttling level is reported in the MemberStatus
+	cfg.WrapTransport = membermetrics.WrapTransport
+	// rate limit the requests client-side, and measure how long they wait for the rate limiter
+	cfg.QPS, cfg.Burst = apiQPS, apiBurst
+	cfg.RateLimiter = membermetrics.NewRateLimiter(apiQPS, apiBurst)
 
 	ctx := context.TODO()
 
-	// Become the leader before proceeding
-	err = leader.Become(ctx, "member-operator-lock")
-	if err != nil {
-		log.Error(err, "")
-		os.Exit(1)
+	// serve the probes while waiting for the leadership, so that the standby replicas are not restarted
+	stopChannel := signals.SetupSignalHandler()
+	// on SIGTERM, the applies in chunks stop after the chunk in progress and report their partial progress
+	shutdown.InitiateOnStop(stopChannel)
+	leaderStatus := leadership.NewStatus()
+	// not ready until the cache of the manager is synced (see below)
+	leaderStatus.AddReadinessCheck("cache", func() error {
+		return fmt.Errorf("cache not started")
+	})
+	leadership.ServeProbes(fmt.Sprintf("%s:%d", metricsHost, probesPort), leaderStatus, stopChannel)
+	if pprofPort > 0 {
+		profiling.Serve(fmt.Sprintf("127.0.0.1:%d", pprofPort), stopChannel)
+	}
+
+	if warmStandby {
+		log.Info("Running in warm standby mode: controllers will start once the leadership is acquired")
+	} else {
+		// Become the leader before proceeding
+		err = leader.Become(ctx, leaderLockName)
+		if err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
 	}
 
 	if err := ensureKubeFedClusterCRD(cfg); err != nil {
@@ -105,6 +189,11 @@ func main() {
 		Namespace:          namespace,
 		MapperProvider:     restmapper.NewDynamicRESTMapper,
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		LeaderElection:     warmStandby,
+		LeaderElectionID:   leaderElectionID,
+		LeaseDuration:      &leaseDuration,
+		RenewDeadline:      &renewDeadline,
+		RetryPeriod:        &retryPeriod,
 	})
 	if err != nil {
 		log.Error(err, "")
@@ -119,12 +208,58 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Load the configuration before setting up the controllers, since their concurrency and rate limits are read when they are created.
+	// The cache of the manager is not started yet, hence a direct client is used
+	cl, err := client.New(cfg, client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	if err := memberconfig.LoadMemberOperatorConfig(cl, namespace); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	if err := logging.Configure(memberconfig.GetLogging()); err != nil {
+		log.Error(err, "the default levels of the logs are used")
+	}
+
 	// Setup all Controllers
 	if err := controller.AddToManager(mgr); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
 
+	// the webhooks are served by all the replicas, hence they are not registered on the webhook server of the Manager (which only runs on the leader)
+	webhookServer := webhook.NewServer(webhookPort)
+	if err := webhook.Add(mgr, webhookServer); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	leaderStatus.AddReadinessCheck("webhook server", webhookServer.Ready)
+
+	// the status is only started once the leadership is acquired
+	if err := mgr.Add(leaderStatus); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	// all the replicas are ready once their cache is synced, whether they are the leader or not
+	cacheSync := leadership.NewCacheSync(mgr.GetCache())
+	if err := mgr.Add(cacheSync); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	leaderStatus.AddReadinessCheck("cache", cacheSync.Synced)
+
+	// trace the reconciliations if a collector is configured
+	if endpoint := memberconfig.GetTracingEndpoint(); endpoint != "" {
+		exporter := tracing.NewExporter(endpoint)
+		if err := mgr.Add(exporter); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+		tracing.Enable(exporter)
+	}
+
 	if err = serveCRMetrics(cfg); err != nil {
 		log.Info("Could not generate and serve custom resource metrics", "error", err.Error())
 	}
@@ -153,8 +288,6 @@ func main() {
 		}
 	}
 
-	stopChannel := signals.SetupSignalHandler()
-
 	log.Info("Starting KubeFedCluster controllers.")
 	if err = controller.StartKubeFedClusterControllers(mgr, stopChannel); err != nil {
 		log.Error(err, "Unable to start the KubeFedCluster controllers")
@@ -165,9 +298,21 @@ func main() {
 
 	// Start the Cmd
 	if err := mgr.Start(stopChannel); err != nil {
+		// the leadership may be lost, hence the applies in progress are not awaited
 		log.Error(err, "Manager exited non-zero")
 		os.Exit(1)
 	}
+
+	// the controllers do not start new reconciliations anymore: let those in progress (and their applies) complete or checkpoint,
+	// so that the next leader does not find half-applied templates nor stale statuses
+	log.Info("Waiting for the reconciliations and the applies in progress", "timeout", shutdownGracePeriod.String())
+	deadline := time.Now().Add(shutdownGracePeriod)
+	if !shutdown.WaitForInFlightReconciles(shutdownGracePeriod) {
+		log.Info("Some reconciliations were still in progress when the grace period expired")
+	}
+	if !template.WaitForInFlightApplies(time.Until(deadline)) {
+		log.Info("Some applies were still in progress when the grace period expired")
+	}
 }
 
 // ensureKubeFedClusterCRD ensure that KubeFedCluster CRD exists in the cluster.
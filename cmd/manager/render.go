@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+
+	"github.com/codeready-toolchain/member-operator/pkg/render"
+	"github.com/spf13/pflag"
+)
+
+// renderCommand the name of the command which renders the templates of a tier to the standard output
+// (eg: `member-operator render --tier basic --username johnsmith --templates ./tiers`)
+const renderCommand = "render"
+
+// runRender renders the templates of a tier from local files, with the given command line arguments
+func runRender(args []string) error {
+	opts := render.Options{}
+	flags := pflag.NewFlagSet(renderCommand, pflag.ContinueOnError)
+	flags.StringVar(&opts.TemplatesDir, "templates", ".", "directory of the templates of the tiers, in files named <tier>-<type>.yaml")
+	flags.StringVar(&opts.Tier, "tier", "", "name of the tier to render")
+	flags.StringVar(&opts.Username, "username", "", "name of the user for whom the templates are rendered")
+	flags.StringSliceVar(&opts.Types, "type", nil, "types of the templates to render (all the templates of the tier by default)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	return render.Render(os.Stdout, opts)
+}
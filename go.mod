@@ -7,6 +7,7 @@ require (
 	github.com/codeready-toolchain/api v0.0.0-20191107090146-e29aacb17012
 	github.com/codeready-toolchain/toolchain-common v0.0.0-20191107144135-e8ba3faab2c8
 	github.com/go-logr/logr v0.1.0
+	github.com/go-logr/zapr v0.1.1
 	github.com/openshift/api v3.9.1-0.20190730142803-0922aa5a655b+incompatible
 	github.com/openshift/library-go v0.0.0-20190815190847-97bb8b699c92
 	github.com/operator-framework/operator-sdk v0.11.0
@@ -15,6 +16,7 @@ require (
 	github.com/satori/go.uuid v1.2.0
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.4.0
+	go.uber.org/zap v1.10.0
 	k8s.io/api v0.0.0
 	k8s.io/apimachinery v0.0.0
 	k8s.io/apiserver v0.0.0-20190111033246-d50e9ac5404f // indirect
@@ -10,6 +10,29 @@ test:
 	@echo "running the tests without coverage and excluding E2E tests..."
 	$(Q)go test ${V_FLAG} -race $(shell go list ./... | grep -v /test/e2e) -failfast
 	
+############################################################
+#
+# Scale Tests
+#
+############################################################
+
+SCALE_TARGET ?= envtest
+SCALE_USERS ?= 100
+SCALE_CONCURRENCY ?= 10
+SCALE_NAMESPACES ?= 2
+SCALE_OBJECTS ?= 10
+SCALE_TIMEOUT ?= 10m
+
+.PHONY: test-scale
+## provisions SCALE_USERS synthetic NSTemplateSets against envtest or the kind cluster of the current kubeconfig (SCALE_TARGET=kind)
+## and reports the throughput, the apply latency percentiles and the API calls. Fails if SCALE_MAX_P95 or SCALE_MIN_THROUGHPUT are exceeded
+test-scale:
+	@echo "running the scale tests against $(SCALE_TARGET) with $(SCALE_USERS) NSTemplateSets..."
+	$(Q)SCALE_TARGET=$(SCALE_TARGET) SCALE_USERS=$(SCALE_USERS) SCALE_CONCURRENCY=$(SCALE_CONCURRENCY) \
+		SCALE_NAMESPACES=$(SCALE_NAMESPACES) SCALE_OBJECTS=$(SCALE_OBJECTS) SCALE_TIMEOUT=$(SCALE_TIMEOUT) \
+		$(if $(SCALE_MAX_P95),SCALE_MAX_P95=$(SCALE_MAX_P95)) $(if $(SCALE_MIN_THROUGHPUT),SCALE_MIN_THROUGHPUT=$(SCALE_MIN_THROUGHPUT)) \
+		go test -v -count=1 -timeout 1h ./test/scale/... -run TestScale
+
 ############################################################
 #
 # Unit Tests (OpenShift CI )
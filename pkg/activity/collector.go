@@ -0,0 +1,195 @@
+package activity
+
+import (
+	"context"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/go-logr/logr"
+	oauthv1 "github.com/openshift/api/oauth/v1"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("activity_collector")
+
+const (
+	// LastActivityAnnotation the annotation on the UserAccount which holds the time of the last known activity of the user (RFC3339)
+	LastActivityAnnotation = "toolchain.dev.openshift.com/last-activity"
+	// LastActivitySourceAnnotation the annotation on the UserAccount which holds the kind of the last known activity of the user
+	LastActivitySourceAnnotation = "toolchain.dev.openshift.com/last-activity-source"
+
+	// DefaultInterval the default interval between two collections of the activity
+	DefaultInterval = 5 * time.Minute
+)
+
+// Source the kind of activity of a user
+type Source string
+
+const (
+	// Login the user logged in (eg, in the web console), ie, an OAuth access token was issued
+	Login Source = "login"
+	// APIAccess the user accessed the API with one of its OAuth access tokens
+	APIAccess Source = "api"
+	// PodCreation a pod was created in one of the user namespaces
+	PodCreation Source = "pod"
+)
+
+// Activity the last known activity of a user
+type Activity struct {
+	Time   time.Time
+	Source Source
+}
+
+// update replaces the activity with the given one if it is more recent
+func (a *Activity) update(t time.Time, source Source) {
+	if t.After(a.Time) {
+		a.Time = t
+		a.Source = source
+	}
+}
+
+// Add creates a new activity Collector and adds it to the Manager. The Collector only runs on the leader.
+func Add(mgr manager.Manager) error {
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	return mgr.Add(NewCollector(mgr.GetClient(), namespace, DefaultInterval))
+}
+
+// Collector periodically collects the last activity of the users (ie, their logins, their accesses to the API and the creation
+// of pods in their namespaces) and records it in annotations of the corresponding UserAccount, so that the host operator
+// can deactivate the dormant accounts. The annotations are only updated when the activity moves forward.
+type Collector struct {
+	client    client.Client
+	namespace string
+	interval  time.Duration
+	// tokenTimeouts the inactivity timeouts of the OAuth access tokens at the previous collection. The OAuth server extends the timeout
+	// of a token when it is used, hence an extended timeout means that the token was used since the previous collection.
+	tokenTimeouts map[types.UID]int32
+}
+
+// NewCollector returns a new Collector for the UserAccounts in the given namespace
+func NewCollector(cl client.Client, namespace string, interval time.Duration) *Collector {
+	return &Collector{
+		client:        cl,
+		namespace:     namespace,
+		interval:      interval,
+		tokenTimeouts: map[types.UID]int32{},
+	}
+}
+
+// Start collects the activity of the users at every interval, until the given channel is closed
+func (c *Collector) Start(stop <-chan struct{}) error {
+	log.Info("starting the activity collector", "interval", c.interval)
+	wait.Until(func() {
+		if err := c.Collect(); err != nil {
+			log.Error(err, "failed to collect the activity of the users")
+		}
+	}, c.interval, stop)
+	return nil
+}
+
+// Collect records the last activity of the users of all the UserAccounts
+func (c *Collector) Collect() error {
+	if !config.FeatureEnabled(config.ActivityTracking) {
+		return nil
+	}
+	userAccounts := &toolchainv1alpha1.UserAccountList{}
+	if err := c.client.List(context.TODO(), userAccounts, client.InNamespace(c.namespace)); err != nil {
+		return errs.Wrap(err, "failed to list the UserAccounts")
+	}
+	activities, err := c.tokenActivities(time.Now())
+	if err != nil {
+		return err
+	}
+	for i := range userAccounts.Items {
+		userAcc := &userAccounts.Items[i]
+		logger := log.WithValues("UserAccount", userAcc.Name)
+		if err := c.collect(logger, userAcc, activities[userAcc.Name]); err != nil {
+			// do not prevent the collection for the other users
+			logger.Error(err, "failed to collect the activity")
+		}
+	}
+	return nil
+}
+
+// tokenActivities returns the last logins and accesses to the API of the users, indexed by user name
+func (c *Collector) tokenActivities(now time.Time) (map[string]Activity, error) {
+	tokens := &oauthv1.OAuthAccessTokenList{}
+	if err := c.client.List(context.TODO(), tokens); err != nil {
+		return nil, errs.Wrap(err, "failed to list the OAuth access tokens")
+	}
+	activities := map[string]Activity{}
+	timeouts := make(map[types.UID]int32, len(tokens.Items))
+	for _, token := range tokens.Items {
+		activity := activities[token.UserName]
+		activity.update(token.CreationTimestamp.Time, Login)
+		if previous, found := c.tokenTimeouts[token.UID]; found && token.InactivityTimeoutSeconds > previous {
+			activity.update(now, APIAccess)
+		}
+		activities[token.UserName] = activity
+		timeouts[token.UID] = token.InactivityTimeoutSeconds
+	}
+	// also forget about the deleted tokens
+	c.tokenTimeouts = timeouts
+	return activities, nil
+}
+
+func (c *Collector) collect(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount, activity Activity) error {
+	userNamespaces := &corev1.NamespaceList{}
+	if err := c.client.List(context.TODO(), userNamespaces, client.MatchingLabels(map[string]string{"owner": userAcc.Name})); err != nil {
+		return errs.Wrapf(err, "failed to list namespace with label owner '%s'", userAcc.Name)
+	}
+	for _, ns := range userNamespaces.Items {
+		pods := &corev1.PodList{}
+		if err := c.client.List(context.TODO(), pods, client.InNamespace(ns.Name)); err != nil {
+			return errs.Wrapf(err, "failed to list the pods in namespace '%s'", ns.Name)
+		}
+		for _, pod := range pods.Items {
+			activity.update(pod.CreationTimestamp.Time, PodCreation)
+		}
+	}
+	if activity.Time.IsZero() {
+		return nil
+	}
+	last, err := LastActivity(userAcc)
+	if err != nil {
+		// overwrite the invalid annotation
+		logger.Error(err, "resetting the last activity")
+	}
+	// the annotation only has a precision of a second
+	if !activity.Time.Truncate(time.Second).After(last.Time) {
+		return nil
+	}
+	annotations := userAcc.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[LastActivityAnnotation] = activity.Time.UTC().Format(time.RFC3339)
+	annotations[LastActivitySourceAnnotation] = string(activity.Source)
+	userAcc.SetAnnotations(annotations)
+	logger.Info("recording the last activity", "time", annotations[LastActivityAnnotation], "source", activity.Source)
+	return c.client.Update(context.TODO(), userAcc)
+}
+
+// LastActivity returns the last activity recorded in the annotations of the given UserAccount, or a zero Activity if there is none
+func LastActivity(userAcc *toolchainv1alpha1.UserAccount) (Activity, error) {
+	value, found := userAcc.GetAnnotations()[LastActivityAnnotation]
+	if !found {
+		return Activity{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return Activity{}, errs.Wrapf(err, "invalid last activity of UserAccount '%s'", userAcc.Name)
+	}
+	return Activity{Time: t, Source: Source(userAcc.GetAnnotations()[LastActivitySourceAnnotation])}, nil
+}
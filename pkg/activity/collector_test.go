@@ -0,0 +1,195 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	oauthv1 "github.com/openshift/api/oauth/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	username      = "johnsmith"
+	namespaceName = "toolchain-member"
+)
+
+func TestCollect(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	loggedIn := time.Date(2019, 11, 12, 10, 0, 0, 0, time.UTC)
+	podCreated := time.Date(2019, 11, 12, 11, 0, 0, 0, time.UTC)
+
+	t.Run("login recorded", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newUserAccount(""), newAccessToken("token", username, loggedIn, 0),
+			newAccessToken("other", "alice", podCreated, 0))
+		c := NewCollector(cl, namespaceName, DefaultInterval)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		assertLastActivity(t, cl, loggedIn, Login)
+	})
+
+	t.Run("pod creation recorded", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newUserAccount(""), newAccessToken("token", username, loggedIn, 0),
+			newUserNamespace("dev"), newPod("dev", "app", podCreated), newPod("other", "app", podCreated.Add(time.Hour)))
+		c := NewCollector(cl, namespaceName, DefaultInterval)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		assertLastActivity(t, cl, podCreated, PodCreation)
+	})
+
+	t.Run("api access recorded when the token was used since the previous collection", func(t *testing.T) {
+		// given
+		token := newAccessToken("token", username, loggedIn, 300)
+		cl := test.NewFakeClient(t, newUserAccount(""), token)
+		c := NewCollector(cl, namespaceName, DefaultInterval)
+		err := c.Collect()
+		require.NoError(t, err)
+		assertLastActivity(t, cl, loggedIn, Login)
+
+		// when
+		token.InactivityTimeoutSeconds = 900
+		err = cl.Update(context.TODO(), token)
+		require.NoError(t, err)
+		before := time.Now().Truncate(time.Second)
+		err = c.Collect()
+
+		// then
+		require.NoError(t, err)
+		activity := getLastActivity(t, cl)
+		assert.Equal(t, APIAccess, activity.Source)
+		assert.False(t, activity.Time.Before(before))
+	})
+
+	t.Run("older activity ignored", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newUserAccount(podCreated.Format(time.RFC3339)), newAccessToken("token", username, loggedIn, 0))
+		cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			return errors.New("should not update")
+		}
+		c := NewCollector(cl, namespaceName, DefaultInterval)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid annotation overwritten", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newUserAccount("yesterday"), newAccessToken("token", username, loggedIn, 0))
+		c := NewCollector(cl, namespaceName, DefaultInterval)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		assertLastActivity(t, cl, loggedIn, Login)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("list tokens fails", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t, newUserAccount(""))
+			cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+				if _, ok := list.(*oauthv1.OAuthAccessTokenList); ok {
+					return errors.New("mock error")
+				}
+				return cl.Client.List(ctx, list, opts...)
+			}
+			c := NewCollector(cl, namespaceName, DefaultInterval)
+
+			// when
+			err := c.Collect()
+
+			// then
+			require.EqualError(t, err, "failed to list the OAuth access tokens: mock error")
+		})
+	})
+}
+
+func newUserAccount(lastActivity string) *toolchainv1alpha1.UserAccount {
+	userAcc := &toolchainv1alpha1.UserAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      username,
+			Namespace: namespaceName,
+		},
+	}
+	if lastActivity != "" {
+		userAcc.Annotations = map[string]string{LastActivityAnnotation: lastActivity}
+	}
+	return userAcc
+}
+
+func newAccessToken(name, user string, created time.Time, inactivityTimeout int32) *oauthv1.OAuthAccessToken {
+	return &oauthv1.OAuthAccessToken{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			UID:               types.UID(name),
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		UserName:                 user,
+		InactivityTimeoutSeconds: inactivityTimeout,
+	}
+}
+
+func newUserNamespace(typeName string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   username + "-" + typeName,
+			Labels: map[string]string{"owner": username, "type": typeName},
+		},
+	}
+}
+
+func newPod(typeName, name string, created time.Time) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         username + "-" + typeName,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+	}
+}
+
+func getLastActivity(t *testing.T, cl client.Client) Activity {
+	userAcc := &toolchainv1alpha1.UserAccount{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: username}, userAcc)
+	require.NoError(t, err)
+	activity, err := LastActivity(userAcc)
+	require.NoError(t, err)
+	return activity
+}
+
+func assertLastActivity(t *testing.T, cl client.Client, expectedTime time.Time, expectedSource Source) {
+	activity := getLastActivity(t, cl)
+	assert.True(t, expectedTime.Equal(activity.Time), "expected %s but was %s", expectedTime, activity.Time)
+	assert.Equal(t, expectedSource, activity.Source)
+}
@@ -2,8 +2,12 @@ package apis
 
 import (
 	"github.com/codeready-toolchain/api/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	appsv1 "github.com/openshift/api/apps/v1"
 	authv1 "github.com/openshift/api/authorization/v1"
+	oauthv1 "github.com/openshift/api/oauth/v1"
 	projectv1 "github.com/openshift/api/project/v1"
+	quotav1 "github.com/openshift/api/quota/v1"
 	templatev1 "github.com/openshift/api/template/v1"
 	userv1 "github.com/openshift/api/user/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -16,6 +20,11 @@ func AddToScheme(s *runtime.Scheme) error {
 	addToSchemes = append(addToSchemes, templatev1.Install)
 	addToSchemes = append(addToSchemes, projectv1.Install)
 	addToSchemes = append(addToSchemes, authv1.Install)
+	addToSchemes = append(addToSchemes, quotav1.Install)
+	addToSchemes = append(addToSchemes, appsv1.Install)
+	addToSchemes = append(addToSchemes, oauthv1.Install)
+	// add member specific resources
+	addToSchemes = append(addToSchemes, memberv1alpha1.AddToScheme)
 
 	return addToSchemes.AddToScheme(s)
 }
@@ -0,0 +1,67 @@
+package v1alpha1
+
+import (
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// IdlerIdledCondition the type of the condition set on the Idler when it idled some workloads of its namespace
+	IdlerIdledCondition toolchainv1alpha1.ConditionType = "Idled"
+)
+
+// IdlerSpec defines the desired state of Idler
+// +k8s:openapi-gen=true
+type IdlerSpec struct {
+	// TimeoutSeconds the number of seconds after which the pods of the namespace are considered as inactive
+	// and their workloads are idled. A value of `0` disables the idling
+	TimeoutSeconds int32 `json:"timeoutSeconds"`
+}
+
+// IdlerStatus defines the observed state of Idler
+// +k8s:openapi-gen=true
+type IdlerStatus struct {
+	// Conditions is an array of current Idler conditions
+	// Supported condition types:
+	// ConditionReady, Idled
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []toolchainv1alpha1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// ObservedGeneration the generation of the Idler which the conditions were set for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Idler is used to idle the workloads of the user namespace with the same name, once their pods have been running
+// for longer than the configured timeout
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=idlers,scope=Cluster
+// +kubebuilder:printcolumn:name="Timeout",type="integer",JSONPath=`.spec.timeoutSeconds`
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+type Idler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IdlerSpec   `json:"spec,omitempty"`
+	Status IdlerStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// IdlerList contains a list of Idler
+type IdlerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Idler `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Idler{}, &IdlerList{})
+}
@@ -0,0 +1,610 @@
+package v1alpha1
+
+import (
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// MemberOperatorConfigName the name of the (single) MemberOperatorConfig resource in the operator namespace
+	MemberOperatorConfigName = "config"
+)
+
+const (
+	// MemberConsoleReadyCondition the type of the condition set on the MemberOperatorConfig which reports the rollout of the member web console
+	MemberConsoleReadyCondition toolchainv1alpha1.ConditionType = "MemberConsoleReady"
+	// AutoscalingBufferReadyCondition the type of the condition set on the MemberOperatorConfig which reports the rollout of the autoscaling buffer
+	AutoscalingBufferReadyCondition toolchainv1alpha1.ConditionType = "AutoscalingBufferReady"
+)
+
+// MemberOperatorConfigSpec defines the configuration of the member operator
+// +k8s:openapi-gen=true
+type MemberOperatorConfigSpec struct {
+	// IdentityProvider the name of the OAuth identity provider of the cluster, used as the prefix
+	// of the Identity resources. Defaults to "rhd"
+	// +optional
+	IdentityProvider string `json:"identityProvider,omitempty"`
+
+	// UserHostPattern the glob pattern of the hosts which can be claimed by the Routes and Ingresses in the user namespaces,
+	// where `{username}` is replaced with the name of the owner of the namespace (eg: `*.{username}.apps.example.com`).
+	// The hosts are not restricted if the pattern is empty
+	// +optional
+	UserHostPattern string `json:"userHostPattern,omitempty"`
+
+	// EphemeralStorage the limits of the ephemeral storage (ie, the writable layers and logs of the containers, and the emptyDir volumes)
+	// in the user namespaces
+	// +optional
+	EphemeralStorage *EphemeralStorageConfig `json:"ephemeralStorage,omitempty"`
+
+	// PersistentStorage the limits of the persistent storage (ie, the PersistentVolumeClaims) in the user namespaces
+	// +optional
+	PersistentStorage *PersistentStorageConfig `json:"persistentStorage,omitempty"`
+
+	// Idler the configuration of the idling of the user namespaces
+	// +optional
+	Idler *IdlerConfig `json:"idler,omitempty"`
+
+	// Webhooks the runtime switches of the webhooks served by the operator
+	// +optional
+	Webhooks *WebhooksConfig `json:"webhooks,omitempty"`
+
+	// Console the URLs of the web consoles available to the users of the cluster
+	// +optional
+	Console *ConsoleConfig `json:"console,omitempty"`
+
+	// ForbiddenResources the resources which cannot be created by the users in their namespaces
+	// +optional
+	ForbiddenResources *ForbiddenResourcesConfig `json:"forbiddenResources,omitempty"`
+
+	// Autoscaler the size of the buffer kept by the cluster autoscaler for the user workloads
+	// +optional
+	Autoscaler *AutoscalerConfig `json:"autoscaler,omitempty"`
+
+	// PodScheduling the node selector and the tolerations set on the pods in the user namespaces, in order to pin them
+	// to the dedicated worker nodes
+	// +optional
+	PodScheduling *PodSchedulingConfig `json:"podScheduling,omitempty"`
+
+	// PodSecurity the security constraints of the pods in the user namespaces (ie, the SCC that their service accounts are allowed to use
+	// and the Pod Security Admission level of the namespaces), per tier
+	// +optional
+	PodSecurity *PodSecurityConfig `json:"podSecurity,omitempty"`
+
+	// Proxy the cluster-wide proxy settings and trusted CA bundle propagated to the user namespaces and to the environment of their pods,
+	// so that the user workloads (eg, the builds) can reach the external resources behind a corporate proxy
+	// +optional
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+
+	// VirtualMachines the defaults and the maximums of the resources of the KubeVirt VirtualMachines in the user namespaces, per tier
+	// +optional
+	VirtualMachines *VirtualMachinesConfig `json:"virtualMachines,omitempty"`
+
+	// NamespaceTermination the remediation of the user namespaces which remain stuck in the `Terminating` phase
+	// when their NSTemplateSet is deleted
+	// +optional
+	NamespaceTermination *NamespaceTerminationConfig `json:"namespaceTermination,omitempty"`
+
+	// PublicViewer the subject which is granted a read-only access to the namespaces of the users who opted in,
+	// so that their workspaces can be shared without creating any UserAccount
+	// +optional
+	PublicViewer *PublicViewerConfig `json:"publicViewer,omitempty"`
+
+	// TierRollout the progressive upgrade of the NSTemplateSets after a change of the revisions of their tier, so that a bad change
+	// does not break all the users at once. All the NSTemplateSets are upgraded right away if it is not specified
+	// +optional
+	TierRollout *TierRolloutConfig `json:"tierRollout,omitempty"`
+
+	// TemplateGuardrails the limits on the number, the size and the kinds of the objects of the tier templates, so that a malformed tier
+	// cannot flood the API server with objects
+	// +optional
+	TemplateGuardrails *TemplateGuardrailsConfig `json:"templateGuardrails,omitempty"`
+
+	// Inventory the limits of the inventory of the objects applied for each NSTemplateSet, which is kept in an annotation
+	// of the NSTemplateSet and hence must remain well below the 256KiB limit of the annotations
+	// +optional
+	Inventory *InventoryConfig `json:"inventory,omitempty"`
+
+	// CIAccess the tokens of the ServiceAccounts which the tier templates provision for the external CI systems in the user namespaces
+	// +optional
+	CIAccess *CIAccessConfig `json:"ciAccess,omitempty"`
+
+	// ImageMirrors the registries from which the container images of the workloads deployed by the operator (ie, the member web console,
+	// the autoscaling buffer and the workloads of the tier templates) are pulled instead of their original ones, on the disconnected clusters
+	// +optional
+	ImageMirrors *ImageMirrorsConfig `json:"imageMirrors,omitempty"`
+
+	// Logging the levels of the logs of the operator, which are changed without restarting the operator
+	// +optional
+	Logging *LoggingConfig `json:"logging,omitempty"`
+
+	// FeatureGates the experimental capabilities enabled or disabled on the cluster, per name of feature (eg: `VirtualMachineIdling`).
+	// The features which are not listed keep their default state
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// Controllers the concurrency, the rate limits and the backoff of the controllers, per name of controller (eg: `useraccount` or `nstemplateset`).
+	// They are read when the operator starts, hence changing them requires a restart of the operator
+	// +optional
+	Controllers map[string]ControllerConfig `json:"controllers,omitempty"`
+}
+
+// ControllerConfig defines the concurrency, the rate limit, the backoff and the API budget of a controller
+// +k8s:openapi-gen=true
+type ControllerConfig struct {
+	// MaxConcurrentReconciles the maximum number of reconciliations which can run concurrently. Defaults to 1
+	// +optional
+	MaxConcurrentReconciles int32 `json:"maxConcurrentReconciles,omitempty"`
+
+	// ReservedReconciles the number of concurrent reconciliations which are reserved for the provisioning of the new accounts:
+	// the reconciliations of the resources which were already provisioned (eg, the upgrades after a change of tier) are deferred
+	// while new accounts are waiting and all the other reconciliations are in progress. Only supported by the `nstemplateset`
+	// controller. Defaults to 0, ie, no reconciliation is deferred
+	// +optional
+	ReservedReconciles int32 `json:"reservedReconciles,omitempty"`
+
+	// RateLimiter the maximum rate of the reconciliations. They are not rate limited if it is not specified
+	// +optional
+	RateLimiter *RateLimiterConfig `json:"rateLimiter,omitempty"`
+
+	// Backoff the delays before the reconciliations which failed (or which asked to be requeued) are retried.
+	// The default backoff of the controller-runtime is used if it is not specified
+	// +optional
+	Backoff *BackoffConfig `json:"backoff,omitempty"`
+
+	// APIBudget the maximum rate of the requests sent by the controller to the API server to create, update, patch or delete objects
+	// (the reads are served by the cache), counted as requests per second instead of reconciliations, so that a single controller cannot
+	// use all the API bandwidth of the operator. The requests are not rate limited if it is not specified
+	// +optional
+	APIBudget *RateLimiterConfig `json:"apiBudget,omitempty"`
+}
+
+// BackoffConfig defines the bounded exponential backoff of the retries of a controller: the delay starts at the initial delay
+// and doubles after each consecutive failure of the same request, up to the max delay
+// +k8s:openapi-gen=true
+type BackoffConfig struct {
+	// InitialDelay the delay (eg: `1s`) before the first retry. Defaults to `1s`
+	// +optional
+	InitialDelay string `json:"initialDelay,omitempty"`
+
+	// MaxDelay the maximum delay (eg: `5m`) between two retries. Defaults to `5m`
+	// +optional
+	MaxDelay string `json:"maxDelay,omitempty"`
+
+	// JitterPercent the maximum percentage of the delay which is randomly added to it, so that the retries of the requests
+	// which failed at the same time (eg, during an outage of the API server) are spread over time. Defaults to `0`
+	// +optional
+	JitterPercent int32 `json:"jitterPercent,omitempty"`
+}
+
+// RateLimiterConfig defines the maximum rate of the reconciliations of a controller, as a token bucket
+// +k8s:openapi-gen=true
+type RateLimiterConfig struct {
+	// QPS the number of reconciliations per second. The reconciliations are not rate limited if it is `0`
+	QPS int32 `json:"qps"`
+
+	// Burst the maximum number of reconciliations which can run at once, above the QPS. Defaults to the QPS
+	// +optional
+	Burst int32 `json:"burst,omitempty"`
+}
+
+// NamespaceTerminationConfig defines the remediation of the user namespaces which remain stuck in the `Terminating` phase,
+// eg, because of the finalizers of the custom resources created by the users
+// +k8s:openapi-gen=true
+type NamespaceTerminationConfig struct {
+	// StuckTimeout the duration (eg: `30m`) after which a terminating user namespace is considered stuck. Defaults to `1h`
+	// +optional
+	StuckTimeout string `json:"stuckTimeout,omitempty"`
+
+	// SafeFinalizers the finalizers which can be safely removed from the resources left in the stuck namespaces.
+	// No finalizer is removed if it is empty
+	// +optional
+	SafeFinalizers []SafeFinalizer `json:"safeFinalizers,omitempty"`
+}
+
+// PublicViewerConfig defines the subject (eg, a group of users or a service account) which is granted a read-only access
+// to the shared user namespaces
+// +k8s:openapi-gen=true
+type PublicViewerConfig struct {
+	// Kind the kind of the subject: `Group`, `User` or `ServiceAccount`. Defaults to `Group`
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name the name of the subject (eg: `system:authenticated`)
+	Name string `json:"name"`
+
+	// Namespace the namespace of the subject, if it is a `ServiceAccount`
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// ClusterRole the cluster role granted to the subject in the shared namespaces. Defaults to `view`
+	// +optional
+	ClusterRole string `json:"clusterRole,omitempty"`
+}
+
+// TierRolloutConfig defines how the NSTemplateSets are upgraded after a change of the revisions of their tier: a few canary NSTemplateSets
+// first, then the other ones in batches, as long as the number of failed upgrades remains below a threshold
+// +k8s:openapi-gen=true
+type TierRolloutConfig struct {
+	// Canary the number of NSTemplateSets which are upgraded first. The other ones are upgraded once all the canaries are ready. Defaults to 1
+	// +optional
+	Canary int32 `json:"canary,omitempty"`
+
+	// MaxUnavailable the maximum number of NSTemplateSets whose upgrade is in progress or failed, after the canaries. Defaults to 1
+	// +optional
+	MaxUnavailable int32 `json:"maxUnavailable,omitempty"`
+
+	// FailureThreshold the number of failed upgrades at which the rollout is paused, until a new revision of the tier is rolled out
+	// or the threshold is raised. Defaults to 1
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+
+	// CheckInterval the delay (eg: `30s`) before checking again whether a pending upgrade can start. Defaults to `30s`
+	// +optional
+	CheckInterval string `json:"checkInterval,omitempty"`
+}
+
+// TemplateGuardrailsConfig defines the limits enforced on the processed tier templates. The templates which exceed them are rejected
+// +k8s:openapi-gen=true
+type TemplateGuardrailsConfig struct {
+	// MaxObjects the maximum number of objects of a template, including the objects of the fragments it includes. Defaults to `500`
+	// +optional
+	MaxObjects int32 `json:"maxObjects,omitempty"`
+
+	// MaxObjectSize the maximum size of each object of a template once serialized in JSON (eg: `512Ki`). Defaults to `1Mi`
+	// +optional
+	MaxObjectSize string `json:"maxObjectSize,omitempty"`
+
+	// AllowedKinds the kinds of the objects that the templates may contain, as `Kind` for the core kinds or `Kind.group`
+	// (eg: `RoleBinding.rbac.authorization.k8s.io`). All kinds are allowed if it is empty
+	// +optional
+	AllowedKinds []string `json:"allowedKinds,omitempty"`
+}
+
+// InventoryConfig defines the limits of the inventory of an NSTemplateSet. When the inventory exceeds the maximum size, the hashes
+// of the applied objects are dropped (ie, all the objects are applied again at the next update). The NSTemplateSets whose inventory
+// still exceeds one of the limits are not provisioned any further
+// +k8s:openapi-gen=true
+type InventoryConfig struct {
+	// MaxSize the maximum size of the inventory once serialized in JSON (eg: `64Ki`). Defaults to `32Ki`
+	// +optional
+	MaxSize string `json:"maxSize,omitempty"`
+
+	// MaxEntries the maximum number of objects recorded in the inventory. Defaults to `1000`
+	// +optional
+	MaxEntries int32 `json:"maxEntries,omitempty"`
+}
+
+// CIAccessConfig defines the tokens of the ServiceAccounts of the user namespaces which are dedicated to the external CI systems
+// +k8s:openapi-gen=true
+type CIAccessConfig struct {
+	// TokenRotationPeriod the duration (eg: `168h`) after which the token of a CI ServiceAccount is replaced with a new one.
+	// Defaults to `720h` (30 days)
+	// +optional
+	TokenRotationPeriod string `json:"tokenRotationPeriod,omitempty"`
+}
+
+// ImageMirrorsConfig defines the mirrors of the container images of the workloads deployed by the operator
+// +k8s:openapi-gen=true
+type ImageMirrorsConfig struct {
+	// Mirrors the mirrors of the registries or repositories of the images. When several sources match an image, the longest one is used
+	// +optional
+	Mirrors []ImageMirror `json:"mirrors,omitempty"`
+}
+
+// ImageMirror defines the mirror of a registry or of a repository of container images
+// +k8s:openapi-gen=true
+type ImageMirror struct {
+	// Source the registry (eg: `quay.io`) or the repository (eg: `quay.io/codeready-toolchain`) of the images to pull from the mirror
+	Source string `json:"source"`
+
+	// Mirror the registry or the repository which replaces the source in the references of the images (eg: `registry.example.com:5000/quay`)
+	Mirror string `json:"mirror"`
+}
+
+// LoggingConfig defines the levels of the logs of the operator
+// +k8s:openapi-gen=true
+type LoggingConfig struct {
+	// Level the level of the logs: `error`, `info` or `debug`. Defaults to `info`
+	// +optional
+	Level string `json:"level,omitempty"`
+
+	// Loggers the levels of the logs of some loggers, by name (eg: `controller_nstemplateset`), which take precedence over the default level.
+	// The level of a logger also applies to its children (eg, the template processor of a controller)
+	// +optional
+	Loggers map[string]string `json:"loggers,omitempty"`
+}
+
+// SafeFinalizer defines a finalizer which can be safely removed from the resources of a given kind
+// +k8s:openapi-gen=true
+type SafeFinalizer struct {
+	// APIVersion the API version of the resources (eg: `example.com/v1`)
+	APIVersion string `json:"apiVersion"`
+
+	// Kind the kind of the resources
+	Kind string `json:"kind"`
+
+	// Finalizer the name of the finalizer to remove
+	Finalizer string `json:"finalizer"`
+}
+
+// WebhooksConfig defines the runtime switches of the webhooks. A webhook can only be switched on if it is served,
+// ie, if it is enabled with its environment variable when the operator starts
+// +k8s:openapi-gen=true
+type WebhooksConfig struct {
+	// HostValidation whether the hosts of the Routes and Ingresses in the user namespaces are validated. Defaults to true
+	// +optional
+	HostValidation *bool `json:"hostValidation,omitempty"`
+
+	// PodMutation whether the ephemeral storage requests and limits of the pods in the user namespaces are set. Defaults to true
+	// +optional
+	PodMutation *bool `json:"podMutation,omitempty"`
+
+	// PodPriority whether the (low) priority class of the pods in the user namespaces is set. Defaults to true
+	// +optional
+	PodPriority *bool `json:"podPriority,omitempty"`
+
+	// PodScheduling whether the node selector and the tolerations of the pods in the user namespaces are set. Defaults to true
+	// +optional
+	PodScheduling *bool `json:"podScheduling,omitempty"`
+
+	// PodProxy whether the proxy settings and the trusted CA bundle are set on the pods in the user namespaces. Defaults to true
+	// +optional
+	PodProxy *bool `json:"podProxy,omitempty"`
+
+	// ResourceValidation whether the resources listed in the forbidden resources are denied in the user namespaces. Defaults to true
+	// +optional
+	ResourceValidation *bool `json:"resourceValidation,omitempty"`
+
+	// VirtualMachines whether the resources of the VirtualMachines in the user namespaces are defaulted and capped. Defaults to true
+	// +optional
+	VirtualMachines *bool `json:"virtualMachines,omitempty"`
+}
+
+// ProxyConfig defines the proxy settings and the trusted CA bundle of the cluster which are propagated to the user workloads
+// +k8s:openapi-gen=true
+type ProxyConfig struct {
+	// HTTPProxy the URL of the proxy for the HTTP requests, set as the `HTTP_PROXY` env var of the containers
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy the URL of the proxy for the HTTPS requests, set as the `HTTPS_PROXY` env var of the containers
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy the comma-separated list of the hosts, domains and CIDRs which are not proxied, set as the `NO_PROXY` env var of the containers
+	// +optional
+	NoProxy string `json:"noProxy,omitempty"`
+
+	// TrustedCABundle the name of the ConfigMap of the operator namespace whose `ca-bundle.crt` (eg, the CA of a TLS-intercepting proxy)
+	// is copied in the user namespaces and mounted in the containers
+	// +optional
+	TrustedCABundle string `json:"trustedCABundle,omitempty"`
+}
+
+// PodSchedulingConfig defines the node selector and the tolerations set on the pods in the user namespaces
+// +k8s:openapi-gen=true
+type PodSchedulingConfig struct {
+	// NodeSelector the labels of the nodes on which the pods can run. They replace the values of the same labels
+	// in the node selectors of the pods
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations the tolerations added to the pods (eg, for the taints of the dedicated worker nodes)
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// PodSecurityConfig defines the security constraints of the pods in the user namespaces
+// +k8s:openapi-gen=true
+type PodSecurityConfig struct {
+	// Default the constraints of the pods in the namespaces whose tier is not listed in the constraints per tier.
+	// These namespaces are left untouched if it is not specified
+	// +optional
+	Default *PodSecurityProfile `json:"default,omitempty"`
+
+	// Tiers the constraints of the pods per tier, which take precedence over the default constraints
+	// +optional
+	Tiers map[string]PodSecurityProfile `json:"tiers,omitempty"`
+}
+
+// PodSecurityProfile defines the security constraints of the pods in the namespaces of a tier
+// +k8s:openapi-gen=true
+type PodSecurityProfile struct {
+	// SCC the name of the SecurityContextConstraints that the service accounts of the namespaces are allowed to use (eg: `restricted-v2`).
+	// No SCC is granted if it is empty
+	// +optional
+	SCC string `json:"scc,omitempty"`
+
+	// Level the Pod Security Admission level enforced, audited and warned on the namespaces. The labels of the namespaces are left
+	// untouched if it is empty
+	// +optional
+	// +kubebuilder:validation:Enum=privileged;baseline;restricted
+	Level string `json:"level,omitempty"`
+}
+
+// VirtualMachinesConfig defines the defaults and the maximums of the resources of the KubeVirt VirtualMachines in the user namespaces
+// +k8s:openapi-gen=true
+type VirtualMachinesConfig struct {
+	// Default the limits of the VirtualMachines in the namespaces whose tier is not listed in the limits per tier.
+	// These VirtualMachines are left untouched if it is not specified
+	// +optional
+	Default *VirtualMachineLimits `json:"default,omitempty"`
+
+	// Tiers the limits of the VirtualMachines per tier, which take precedence over the default limits
+	// +optional
+	Tiers map[string]VirtualMachineLimits `json:"tiers,omitempty"`
+}
+
+// VirtualMachineLimitAction defines what happens to the VirtualMachines whose resources exceed the maximums
+type VirtualMachineLimitAction string
+
+const (
+	// VirtualMachineLimitReject the VirtualMachines which exceed the maximums are rejected
+	VirtualMachineLimitReject VirtualMachineLimitAction = "Reject"
+	// VirtualMachineLimitCap the resources of the VirtualMachines which exceed the maximums are lowered to the maximums
+	VirtualMachineLimitCap VirtualMachineLimitAction = "Cap"
+)
+
+// VirtualMachineLimits defines the defaults and the maximums of the resources of the VirtualMachines. The memory and the disk
+// are quantities (eg: `2Gi`), and each limit is not enforced if it is empty (or `0`)
+// +k8s:openapi-gen=true
+type VirtualMachineLimits struct {
+	// DefaultMemory the memory requested by the VirtualMachines which do not specify it
+	// +optional
+	DefaultMemory string `json:"defaultMemory,omitempty"`
+
+	// MaxMemory the maximum memory of the VirtualMachines (requested, limit or guest memory)
+	// +optional
+	MaxMemory string `json:"maxMemory,omitempty"`
+
+	// DefaultCPUCores the number of CPU cores of the VirtualMachines which do not specify their CPU
+	// +optional
+	DefaultCPUCores int32 `json:"defaultCPUCores,omitempty"`
+
+	// MaxCPUCores the maximum number of virtual CPUs (ie, cores * sockets * threads) of the VirtualMachines
+	// +optional
+	MaxCPUCores int32 `json:"maxCPUCores,omitempty"`
+
+	// MaxDisk the maximum storage requested by each DataVolume template of the VirtualMachines
+	// +optional
+	MaxDisk string `json:"maxDisk,omitempty"`
+
+	// Action what happens to the VirtualMachines which exceed the maximums: `Reject` or `Cap`. Defaults to `Reject`
+	// +optional
+	Action VirtualMachineLimitAction `json:"action,omitempty"`
+}
+
+// PersistentStorageConfig defines the limits of the persistent storage in the user namespaces
+// +k8s:openapi-gen=true
+type PersistentStorageConfig struct {
+	// Quota the maximum storage requested by all the PersistentVolumeClaims of a user namespace (eg: `5Gi`).
+	// It is added as `requests.storage` to the ResourceQuotas of the tier templates which do not define it
+	// +optional
+	Quota string `json:"quota,omitempty"`
+
+	// MaxClaims the maximum number of PersistentVolumeClaims in a user namespace. It is added as `persistentvolumeclaims`
+	// to the ResourceQuotas of the tier templates which do not define it
+	// +optional
+	MaxClaims int32 `json:"maxClaims,omitempty"`
+
+	// AllowedStorageClasses the StorageClasses which can be used by the PersistentVolumeClaims of the user namespaces
+	// (including the default StorageClass, for the claims which do not specify one). All the StorageClasses are allowed if it is empty
+	// +optional
+	AllowedStorageClasses []string `json:"allowedStorageClasses,omitempty"`
+}
+
+// ForbiddenResourcesConfig defines the resources which cannot be created by the users in their namespaces
+// +k8s:openapi-gen=true
+type ForbiddenResourcesConfig struct {
+	// ClusterRoles the glob patterns of the names of the ClusterRoles which cannot be bound by the RoleBindings of the user namespaces
+	// (eg: `cluster-admin` or `system:*`)
+	// +optional
+	ClusterRoles []string `json:"clusterRoles,omitempty"`
+
+	// ServiceTypes the types of the Services which cannot be created in the user namespaces (eg: `NodePort` or `LoadBalancer`)
+	// +optional
+	ServiceTypes []string `json:"serviceTypes,omitempty"`
+
+	// PodPrivileges the privileges which cannot be requested by the pods of the user namespaces, among `privileged`, `hostNetwork`,
+	// `hostPID`, `hostIPC`, `hostPath` and `hostPort`
+	// +optional
+	PodPrivileges []string `json:"podPrivileges,omitempty"`
+}
+
+// ConsoleConfig defines the URLs of the web consoles available to the users of the cluster
+// +k8s:openapi-gen=true
+type ConsoleConfig struct {
+	// URL the URL of the OpenShift web console
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// CheDashboardURL the URL of the Che dashboard
+	// +optional
+	CheDashboardURL string `json:"cheDashboardURL,omitempty"`
+}
+
+// AutoscalerConfig defines the size of the buffer kept by the cluster autoscaler for the user workloads
+// +k8s:openapi-gen=true
+type AutoscalerConfig struct {
+	// BufferMemory the memory requested by each replica of the buffer (eg: `2Gi`). The buffer is disabled if it is empty
+	// +optional
+	BufferMemory string `json:"bufferMemory,omitempty"`
+
+	// BufferReplicas the number of replicas of the buffer in each zone of the compute nodes. The buffer is disabled if it is `0`
+	// +optional
+	BufferReplicas int32 `json:"bufferReplicas,omitempty"`
+}
+
+// IdlerConfig defines the configuration of the idling of the user namespaces
+// +k8s:openapi-gen=true
+type IdlerConfig struct {
+	// TierTimeoutsSeconds the idle timeouts (in seconds) of the namespaces per tier, which take precedence over the timeouts of their Idlers.
+	// A value of `0` disables the idling of the namespaces of the tier. The Idlers of the namespaces whose tier is not listed keep their own timeout
+	// +optional
+	TierTimeoutsSeconds map[string]int32 `json:"tierTimeoutsSeconds,omitempty"`
+}
+
+// EphemeralStorageConfig defines the limits of the ephemeral storage in the user namespaces. Each limit is a quantity (eg: `2Gi`),
+// and is not enforced if it is empty
+// +k8s:openapi-gen=true
+type EphemeralStorageConfig struct {
+	// Quota the maximum ephemeral storage requested by (and the sum of the limits of) all the pods of a user namespace.
+	// It is added to the ResourceQuotas of the tier templates which do not define it
+	// +optional
+	Quota string `json:"quota,omitempty"`
+
+	// DefaultRequest the ephemeral storage requested by the containers of the user pods which do not specify it
+	// +optional
+	DefaultRequest string `json:"defaultRequest,omitempty"`
+
+	// DefaultLimit the ephemeral storage limit of the containers of the user pods which do not specify it
+	// +optional
+	DefaultLimit string `json:"defaultLimit,omitempty"`
+
+	// MaxEmptyDirSize the maximum size limit of the emptyDir volumes of the user pods
+	// +optional
+	MaxEmptyDirSize string `json:"maxEmptyDirSize,omitempty"`
+}
+
+// MemberOperatorConfigStatus defines the observed state of the workloads deployed by the operator according to the MemberOperatorConfig
+// +k8s:openapi-gen=true
+type MemberOperatorConfigStatus struct {
+	// Conditions is an array of current MemberOperatorConfig conditions, which report the rollout of the workloads deployed by the operator
+	// Supported condition types:
+	// MemberConsoleReady, AutoscalingBufferReady
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []toolchainv1alpha1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MemberOperatorConfig is used to configure the member operator
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=memberoperatorconfigs,scope=Namespaced
+// +kubebuilder:printcolumn:name="IdentityProvider",type="string",JSONPath=`.spec.identityProvider`
+type MemberOperatorConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MemberOperatorConfigSpec   `json:"spec,omitempty"`
+	Status MemberOperatorConfigStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MemberOperatorConfigList contains a list of MemberOperatorConfig
+type MemberOperatorConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MemberOperatorConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MemberOperatorConfig{}, &MemberOperatorConfigList{})
+}
@@ -0,0 +1,245 @@
+package v1alpha1
+
+import (
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// MemberStatusName the name of the (single) MemberStatus resource in the operator namespace
+	MemberStatusName = "toolchain-member-status"
+
+	// ConformanceAnnotation the annotation to set on the MemberStatus resource to trigger a run of the conformance checks.
+	// Its value is the name of the tier to use during the run (or "run" for the default tier)
+	ConformanceAnnotation = "toolchain.dev.openshift.com/conformance"
+
+	// MemberConsoleName the name of the Deployment, Service, Route and ServiceAccount of the member web console in the operator namespace
+	MemberConsoleName = "member-console"
+)
+
+// MemberStatusSpec defines the desired state of MemberStatus
+// +k8s:openapi-gen=true
+type MemberStatusSpec struct {
+}
+
+// MemberStatusStatus defines the observed state of the member cluster
+// +k8s:openapi-gen=true
+type MemberStatusStatus struct {
+	// Conformance the results of the last run of the conformance checks
+	// +optional
+	Conformance *ConformanceStatus `json:"conformance,omitempty"`
+
+	// ResourceUsage the capacity and the resource consumption of the member cluster, refreshed periodically
+	// +optional
+	ResourceUsage *ResourceUsageStatus `json:"resourceUsage,omitempty"`
+
+	// Routes the URLs of the web consoles available to the users of the member cluster along with their health, refreshed periodically
+	// +optional
+	Routes *RoutesStatus `json:"routes,omitempty"`
+
+	// MemberConsole the state of the member web console deployed by the operator, refreshed periodically
+	// +optional
+	MemberConsole *MemberConsoleStatus `json:"memberConsole,omitempty"`
+
+	// Components the health of the components of the member cluster (eg, the connection to the host cluster or the webhooks),
+	// refreshed periodically. The Ready condition is true if all the components are healthy
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Components []ComponentHealth `json:"components,omitempty"`
+
+	// Conditions is an array of current MemberStatus conditions
+	// Supported condition types:
+	// ConditionReady
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []toolchainv1alpha1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// ComponentHealth the result of the last health check of a component of the member cluster
+// +k8s:openapi-gen=true
+type ComponentHealth struct {
+	// Name the name of the component
+	Name string `json:"name"`
+
+	// Healthy is true if the component passed its last health check
+	Healthy bool `json:"healthy"`
+
+	// Reason a machine readable reason of the health of the component (eg: `HostNotReady`)
+	Reason string `json:"reason"`
+
+	// Message a human readable message explaining why the component is not healthy
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ConformanceStatus the results of a run of the conformance checks
+// +k8s:openapi-gen=true
+type ConformanceStatus struct {
+	// Tier the name of the tier used during the run
+	Tier string `json:"tier"`
+
+	// StartTime the time when the run started
+	StartTime metav1.Time `json:"startTime"`
+
+	// CompletionTime the time when the run completed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Passed is true if all checks passed
+	Passed bool `json:"passed"`
+
+	// Checks the results of the individual checks, in their order of execution
+	// +optional
+	// +listType=atomic
+	Checks []ConformanceCheck `json:"checks,omitempty"`
+}
+
+// ConformanceCheck the result of a single conformance check
+// +k8s:openapi-gen=true
+type ConformanceCheck struct {
+	// Name the name of the check
+	Name string `json:"name"`
+
+	// Passed is true if the check passed
+	Passed bool `json:"passed"`
+
+	// Message a human readable message explaining why the check failed or was skipped
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ResourceUsageStatus the capacity and the resource consumption of the member cluster
+// +k8s:openapi-gen=true
+type ResourceUsageStatus struct {
+	// Nodes the number of nodes of the cluster
+	Nodes int32 `json:"nodes"`
+
+	// Capacity the total capacity of the nodes
+	// +optional
+	Capacity corev1.ResourceList `json:"capacity,omitempty"`
+
+	// Allocatable the total resources of the nodes which are available for the pods
+	// +optional
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+
+	// UserAccounts the number of UserAccounts provisioned on the cluster
+	UserAccounts int32 `json:"userAccounts"`
+
+	// Roles the utilization of the nodes per role (eg, `master`, `worker` or `infra`). The nodes which have several roles
+	// are counted in each of them
+	// +optional
+	// +listType=map
+	// +listMapKey=role
+	Roles []RoleUtilization `json:"roles,omitempty"`
+
+	// LastUpdatedTime the time when the resource usage was computed
+	LastUpdatedTime metav1.Time `json:"lastUpdatedTime"`
+}
+
+// RoleUtilization the utilization of the nodes with a given role, based on the resources requested by their pods
+// +k8s:openapi-gen=true
+type RoleUtilization struct {
+	// Role the name of the role, as specified by the `node-role.kubernetes.io/<role>` label of the nodes (or `none` for the nodes without role)
+	Role string `json:"role"`
+
+	// Nodes the number of nodes with the role
+	Nodes int32 `json:"nodes"`
+
+	// Allocatable the total resources of the nodes which are available for the pods
+	// +optional
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+
+	// Requested the total resources requested by the pods running on the nodes
+	// +optional
+	Requested corev1.ResourceList `json:"requested,omitempty"`
+
+	// CPUPercent the percentage of the allocatable CPU which is requested by the pods
+	CPUPercent int32 `json:"cpuPercent"`
+
+	// MemoryPercent the percentage of the allocatable memory which is requested by the pods
+	MemoryPercent int32 `json:"memoryPercent"`
+}
+
+// RoutesStatus the URLs of the web consoles available to the users of the member cluster
+// +k8s:openapi-gen=true
+type RoutesStatus struct {
+	// Console the OpenShift web console
+	// +optional
+	Console *RouteStatus `json:"console,omitempty"`
+
+	// CheDashboard the dashboard of Che (or Dev Spaces), if it is installed on the cluster
+	// +optional
+	CheDashboard *RouteStatus `json:"cheDashboard,omitempty"`
+
+	// LastCheckTime the time when the routes were discovered and checked
+	LastCheckTime metav1.Time `json:"lastCheckTime"`
+}
+
+// RouteStatus the URL of a web console and the result of its last health check
+// +k8s:openapi-gen=true
+type RouteStatus struct {
+	// URL the URL of the web console
+	URL string `json:"url"`
+
+	// Healthy is true if the web console responded during the last health check
+	Healthy bool `json:"healthy"`
+
+	// Message a human readable message explaining why the web console is not healthy
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// MemberConsoleStatus the state of the Deployment of the member web console
+// +k8s:openapi-gen=true
+type MemberConsoleStatus struct {
+	// Image the image of the member web console
+	Image string `json:"image"`
+
+	// Replicas the desired number of replicas
+	Replicas int32 `json:"replicas"`
+
+	// AvailableReplicas the number of replicas which are available
+	AvailableReplicas int32 `json:"availableReplicas"`
+
+	// Ready is true if all the desired replicas of the latest version of the member web console are available
+	Ready bool `json:"ready"`
+
+	// Message a human readable message explaining why the member web console is not ready
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MemberStatus is used to track the state of the member cluster
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=memberstatuses,scope=Namespaced
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Conformance",type="boolean",JSONPath=`.status.conformance.passed`
+// +kubebuilder:printcolumn:name="UserAccounts",type="integer",JSONPath=`.status.resourceUsage.userAccounts`
+type MemberStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MemberStatusSpec   `json:"spec,omitempty"`
+	Status MemberStatusStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MemberStatusList contains a list of MemberStatus
+type MemberStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MemberStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MemberStatus{}, &MemberStatusList{})
+}
@@ -0,0 +1,352 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/nstemplateset"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/useraccount"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	userv1 "github.com/openshift/api/user/v1"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	errs "github.com/pkg/errors"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("startup_audit")
+
+const (
+	// DefaultBatchSize the default number of repair actions executed in a single batch
+	DefaultBatchSize = 10
+	// DefaultBatchInterval the default delay between two batches of repair actions
+	DefaultBatchInterval = 10 * time.Second
+
+	userAccountKind   = "UserAccount"
+	nsTemplateSetKind = "NSTemplateSet"
+)
+
+// Operation the operation of a repair action
+type Operation string
+
+const (
+	// Create the object is missing
+	Create Operation = "create"
+	// Update the object is out of date
+	Update Operation = "update"
+	// Delete the object is orphaned
+	Delete Operation = "delete"
+)
+
+// Action a single step of the repair plan
+type Action struct {
+	Operation Operation
+	Kind      string
+	Namespace string
+	Name      string
+	Reason    string
+	// OwnerKind and OwnerName identify the resource (in the operator namespace) whose reconciliation repairs the object.
+	// If empty, then the object is directly deleted.
+	OwnerKind string
+	OwnerName string
+	// obj the object to delete when there is no owner
+	obj runtime.Object
+}
+
+func (a Action) String() string {
+	if a.Name == "" {
+		return fmt.Sprintf("%s %s: %s", a.Operation, a.Kind, a.Reason)
+	}
+	name := a.Name
+	if a.Namespace != "" {
+		name = a.Namespace + "/" + a.Name
+	}
+	return fmt.Sprintf("%s %s '%s': %s", a.Operation, a.Kind, name, a.Reason)
+}
+
+// Plan the repair plan, ie, the list of actions which make the cluster state consistent with the UserAccounts and NSTemplateSets
+type Plan []Action
+
+// Add creates a new Auditor and adds it to the Manager. The Auditor only runs once on the leader, when it starts.
+func Add(mgr manager.Manager) error {
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	nsTmplSetReconciler, err := nstemplateset.NewReconciler(mgr)
+	if err != nil {
+		return err
+	}
+	reconcilers := map[string]reconcile.Reconciler{
+		userAccountKind:   useraccount.NewReconciler(mgr),
+		nsTemplateSetKind: nsTmplSetReconciler,
+	}
+	return mgr.Add(NewAuditor(mgr.GetClient(), namespace, reconcilers, config.StartupAuditRepairEnabled(), DefaultBatchSize, DefaultBatchInterval))
+}
+
+// Auditor compares the UserAccounts and NSTemplateSets against the actual cluster state and produces a repair plan, which
+// can optionally be executed in throttled batches. The missing or out of date objects are repaired by reconciling their owner,
+// while the orphaned objects are directly deleted.
+type Auditor struct {
+	client        client.Client
+	namespace     string
+	reconcilers   map[string]reconcile.Reconciler
+	repair        bool
+	batchSize     int
+	batchInterval time.Duration
+}
+
+// NewAuditor returns a new Auditor for the UserAccounts and NSTemplateSets in the given namespace. The given reconcilers
+// are indexed by the kind of resource that they reconcile.
+func NewAuditor(cl client.Client, namespace string, reconcilers map[string]reconcile.Reconciler, repair bool, batchSize int, batchInterval time.Duration) *Auditor {
+	return &Auditor{
+		client:        cl,
+		namespace:     namespace,
+		reconcilers:   reconcilers,
+		repair:        repair,
+		batchSize:     batchSize,
+		batchInterval: batchInterval,
+	}
+}
+
+// Start runs the audit, logs the repair plan and executes it if enabled
+func (a *Auditor) Start(stop <-chan struct{}) error {
+	log.Info("starting the audit")
+	plan, err := a.Audit()
+	if err != nil {
+		log.Error(err, "failed to audit the cluster state")
+		return nil
+	}
+	log.Info("audit completed", "actions", len(plan))
+	for _, action := range plan {
+		log.Info("repair plan", "action", action.String())
+	}
+	if a.repair && len(plan) > 0 {
+		a.Repair(plan, stop)
+	}
+	return nil
+}
+
+// Audit returns the repair plan for all the UserAccounts and NSTemplateSets in the namespace
+func (a *Auditor) Audit() (Plan, error) {
+	plan := Plan{}
+	userAccs := &toolchainv1alpha1.UserAccountList{}
+	if err := a.client.List(context.TODO(), userAccs, client.InNamespace(a.namespace)); err != nil {
+		return nil, errs.Wrap(err, "failed to list the UserAccounts")
+	}
+	userAccNames := map[string]bool{}
+	for i := range userAccs.Items {
+		userAcc := &userAccs.Items[i]
+		userAccNames[userAcc.Name] = true
+		if util.IsBeingDeleted(userAcc) {
+			continue
+		}
+		actions, err := a.auditUserAccount(userAcc)
+		if err != nil {
+			return nil, errs.Wrapf(err, "failed to audit UserAccount '%s'", userAcc.Name)
+		}
+		plan = append(plan, actions...)
+	}
+
+	nsTmplSets := &toolchainv1alpha1.NSTemplateSetList{}
+	if err := a.client.List(context.TODO(), nsTmplSets, client.InNamespace(a.namespace)); err != nil {
+		return nil, errs.Wrap(err, "failed to list the NSTemplateSets")
+	}
+	nsTmplSetNames := map[string]bool{}
+	for i := range nsTmplSets.Items {
+		nsTmplSet := &nsTmplSets.Items[i]
+		nsTmplSetNames[nsTmplSet.Name] = true
+		if util.IsBeingDeleted(nsTmplSet) {
+			continue
+		}
+		actions, err := a.auditNSTemplateSet(nsTmplSet)
+		if err != nil {
+			return nil, errs.Wrapf(err, "failed to audit NSTemplateSet '%s'", nsTmplSet.Name)
+		}
+		plan = append(plan, actions...)
+	}
+
+	actions, err := a.auditOrphans(userAccNames, nsTmplSetNames)
+	if err != nil {
+		return nil, err
+	}
+	return append(plan, actions...), nil
+}
+
+func (a *Auditor) auditUserAccount(userAcc *toolchainv1alpha1.UserAccount) ([]Action, error) {
+	actions := []Action{}
+	newAction := func(op Operation, kind, namespace, name, reason string) Action {
+		return Action{Operation: op, Kind: kind, Namespace: namespace, Name: name, Reason: reason, OwnerKind: userAccountKind, OwnerName: userAcc.Name}
+	}
+
+	userExists, err := a.exists(types.NamespacedName{Name: userAcc.Name}, &userv1.User{})
+	if err != nil {
+		return nil, err
+	}
+	identityName := useraccount.ToIdentityName(userAcc.Spec.UserID)
+	identityExists, err := a.exists(types.NamespacedName{Name: identityName}, &userv1.Identity{})
+	if err != nil {
+		return nil, err
+	}
+	if userAcc.Spec.Disabled {
+		if identityExists {
+			actions = append(actions, newAction(Delete, "Identity", "", identityName, "the user account is disabled"))
+		}
+		if userExists {
+			actions = append(actions, newAction(Delete, "User", "", userAcc.Name, "the user account is disabled"))
+		}
+		return actions, nil
+	}
+	if !userExists {
+		actions = append(actions, newAction(Create, "User", "", userAcc.Name, "the user is missing"))
+	}
+	// with the `lookup` strategy, the identity is created by the identity provider
+	if !identityExists && config.GetIdentityMappingStrategy() != config.IdentityMappingStrategyLookup {
+		actions = append(actions, newAction(Create, "Identity", "", identityName, "the identity is missing"))
+	}
+
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	if err := a.client.Get(context.TODO(), types.NamespacedName{Namespace: userAcc.Namespace, Name: userAcc.Name}, nsTmplSet); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		actions = append(actions, newAction(Create, nsTemplateSetKind, userAcc.Namespace, userAcc.Name, "the NSTemplateSet is missing"))
+	} else if !nsTmplSet.Spec.CompareTo(userAcc.Spec.NSTemplateSet) {
+		actions = append(actions, newAction(Update, nsTemplateSetKind, userAcc.Namespace, userAcc.Name, "the NSTemplateSet does not match the UserAccount"))
+	}
+	return actions, nil
+}
+
+func (a *Auditor) auditNSTemplateSet(nsTmplSet *toolchainv1alpha1.NSTemplateSet) ([]Action, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := a.client.List(context.TODO(), namespaces, client.MatchingLabels(map[string]string{"owner": nsTmplSet.Name})); err != nil {
+		return nil, err
+	}
+	tcNamespaces, _, err := nstemplateset.ExpandNamespaceSets(nsTmplSet)
+	if err != nil {
+		return nil, err
+	}
+	actions := []Action{}
+	for _, tcNamespace := range tcNamespaces {
+		if tcNamespace.Type == template.ClusterResourcesType {
+			continue
+		}
+		namespace := findNamespace(namespaces.Items, tcNamespace.Type)
+		if namespace == nil {
+			// the name of the namespace is only known once its template is processed
+			actions = append(actions, Action{Operation: Create, Kind: "Namespace", Reason: fmt.Sprintf("the namespace of type '%s' is missing", tcNamespace.Type),
+				OwnerKind: nsTemplateSetKind, OwnerName: nsTmplSet.Name})
+		} else if namespace.Labels["revision"] != tcNamespace.Revision {
+			actions = append(actions, Action{Operation: Update, Kind: "Namespace", Name: namespace.Name,
+				Reason:    fmt.Sprintf("the namespace is at revision '%s' instead of '%s'", namespace.Labels["revision"], tcNamespace.Revision),
+				OwnerKind: nsTemplateSetKind, OwnerName: nsTmplSet.Name})
+		}
+	}
+	return actions, nil
+}
+
+// auditOrphans returns the actions to delete the user namespaces without NSTemplateSet and the identities without UserAccount
+func (a *Auditor) auditOrphans(userAccNames, nsTmplSetNames map[string]bool) ([]Action, error) {
+	actions := []Action{}
+	namespaces := &corev1.NamespaceList{}
+	if err := a.client.List(context.TODO(), namespaces); err != nil {
+		return nil, errs.Wrap(err, "failed to list the namespaces")
+	}
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		owner, isUserNamespace := ns.Labels["owner"]
+		if !isUserNamespace || ns.Labels["type"] == "" || nsTmplSetNames[owner] || ns.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+		actions = append(actions, Action{Operation: Delete, Kind: "Namespace", Name: ns.Name,
+			Reason: fmt.Sprintf("the NSTemplateSet '%s' does not exist", owner), obj: ns})
+	}
+	identities := &userv1.IdentityList{}
+	if err := a.client.List(context.TODO(), identities); err != nil {
+		return nil, errs.Wrap(err, "failed to list the identities")
+	}
+	for i := range identities.Items {
+		identity := &identities.Items[i]
+		owner, found := identity.Labels["owner"]
+		if !found || userAccNames[owner] {
+			continue
+		}
+		actions = append(actions, Action{Operation: Delete, Kind: "Identity", Name: identity.Name,
+			Reason: fmt.Sprintf("the UserAccount '%s' does not exist", owner), obj: identity})
+	}
+	return actions, nil
+}
+
+// Repair executes the actions of the given plan in batches, waiting for the batch interval between two batches.
+// The owners of the missing or out of date objects are reconciled only once. Stops when the given channel is closed.
+func (a *Auditor) Repair(plan Plan, stop <-chan struct{}) {
+	reconciled := map[string]bool{}
+	executed := 0
+	for _, action := range plan {
+		if action.OwnerKind != "" {
+			key := action.OwnerKind + "/" + action.OwnerName
+			if reconciled[key] {
+				continue
+			}
+			reconciled[key] = true
+		}
+		if executed > 0 && executed%a.batchSize == 0 {
+			select {
+			case <-stop:
+				log.Info("repair interrupted", "remaining", len(plan)-executed)
+				return
+			case <-time.After(a.batchInterval):
+			}
+		}
+		executed++
+		if err := a.execute(action); err != nil {
+			log.Error(err, "failed to execute the repair action", "action", action.String())
+			continue
+		}
+		log.Info("repair action executed", "action", action.String())
+	}
+}
+
+func (a *Auditor) execute(action Action) error {
+	if action.OwnerKind == "" {
+		if err := a.client.Delete(context.TODO(), action.obj); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	r, found := a.reconcilers[action.OwnerKind]
+	if !found {
+		return fmt.Errorf("no reconciler for kind '%s'", action.OwnerKind)
+	}
+	_, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: a.namespace, Name: action.OwnerName}})
+	return err
+}
+
+func (a *Auditor) exists(name types.NamespacedName, obj runtime.Object) (bool, error) {
+	if err := a.client.Get(context.TODO(), name, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func findNamespace(namespaces []corev1.Namespace, typeName string) *corev1.Namespace {
+	for i := range namespaces {
+		if namespaces[i].Labels["type"] == typeName {
+			return &namespaces[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/useraccount"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	userv1 "github.com/openshift/api/user/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	username      = "johnsmith"
+	userID        = "12345"
+	namespaceName = "toolchain-member"
+)
+
+func TestAudit(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	t.Run("consistent state", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newUserAccount(), newNSTmplSet(), newUser(), newIdentity(),
+			newUserNamespace("dev", "abcde11"), newUserNamespace("code", "abcde21"))
+		a := NewAuditor(cl, namespaceName, nil, false, DefaultBatchSize, DefaultBatchInterval)
+
+		// when
+		plan, err := a.Audit()
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, plan)
+	})
+
+	t.Run("missing and outdated objects", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Spec.Namespaces[0].Revision = "abcde10"
+		cl := test.NewFakeClient(t, newUserAccount(), nsTmplSet, newUserNamespace("dev", "abcde10"))
+		a := NewAuditor(cl, namespaceName, nil, false, DefaultBatchSize, DefaultBatchInterval)
+
+		// when
+		plan, err := a.Audit()
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"create User 'johnsmith': the user is missing",
+			"create Identity 'rhd:12345': the identity is missing",
+			"update NSTemplateSet 'toolchain-member/johnsmith': the NSTemplateSet does not match the UserAccount",
+			"create Namespace: the namespace of type 'code' is missing",
+		}, actionsOf(plan))
+	})
+
+	t.Run("disabled user account", func(t *testing.T) {
+		// given
+		userAcc := newUserAccount()
+		userAcc.Spec.Disabled = true
+		cl := test.NewFakeClient(t, userAcc, newNSTmplSet(), newUser(), newIdentity(),
+			newUserNamespace("dev", "abcde11"), newUserNamespace("code", "abcde21"))
+		a := NewAuditor(cl, namespaceName, nil, false, DefaultBatchSize, DefaultBatchInterval)
+
+		// when
+		plan, err := a.Audit()
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"delete Identity 'rhd:12345': the user account is disabled",
+			"delete User 'johnsmith': the user account is disabled",
+		}, actionsOf(plan))
+	})
+
+	t.Run("orphans", func(t *testing.T) {
+		// given
+		orphanIdentity := &userv1.Identity{ObjectMeta: metav1.ObjectMeta{
+			Name:   "github:67890",
+			Labels: map[string]string{"owner": "another"},
+		}}
+		otherNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "other",
+			Labels: map[string]string{"owner": "another"},
+		}}
+		cl := test.NewFakeClient(t, newUserNamespace("dev", "abcde11"), orphanIdentity, otherNamespace)
+		a := NewAuditor(cl, namespaceName, nil, false, DefaultBatchSize, DefaultBatchInterval)
+
+		// when
+		plan, err := a.Audit()
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"delete Namespace 'johnsmith-dev': the NSTemplateSet 'johnsmith' does not exist",
+			"delete Identity 'github:67890': the UserAccount 'another' does not exist",
+		}, actionsOf(plan))
+	})
+}
+
+func TestRepair(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	t.Run("owners reconciled once and orphans deleted", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newUserAccount(), newUserNamespace("dev", "abcde11"))
+		userAccReconciler := &fakeReconciler{}
+		nsTmplSetReconciler := &fakeReconciler{}
+		a := NewAuditor(cl, namespaceName, map[string]reconcile.Reconciler{
+			userAccountKind:   userAccReconciler,
+			nsTemplateSetKind: nsTmplSetReconciler,
+		}, true, DefaultBatchSize, 0)
+		plan, err := a.Audit()
+		require.NoError(t, err)
+		require.Len(t, plan, 4)
+
+		// when
+		a.Repair(plan, make(chan struct{}))
+
+		// then
+		assert.Equal(t, []reconcile.Request{newRequest(username)}, userAccReconciler.requests)
+		assert.Empty(t, nsTmplSetReconciler.requests)
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: "johnsmith-dev"}, &corev1.Namespace{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("stopped between two batches", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		r := &fakeReconciler{}
+		a := NewAuditor(cl, namespaceName, map[string]reconcile.Reconciler{userAccountKind: r}, true, 1, time.Hour)
+		plan := Plan{
+			{Operation: Create, Kind: "User", Name: "user1", OwnerKind: userAccountKind, OwnerName: "user1"},
+			{Operation: Create, Kind: "User", Name: "user2", OwnerKind: userAccountKind, OwnerName: "user2"},
+		}
+		stop := make(chan struct{})
+		close(stop)
+
+		// when
+		a.Repair(plan, stop)
+
+		// then
+		assert.Equal(t, []reconcile.Request{newRequest("user1")}, r.requests)
+	})
+}
+
+type fakeReconciler struct {
+	requests []reconcile.Request
+}
+
+func (r *fakeReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	r.requests = append(r.requests, request)
+	return reconcile.Result{}, nil
+}
+
+func actionsOf(plan Plan) []string {
+	actions := make([]string, 0, len(plan))
+	for _, action := range plan {
+		actions = append(actions, action.String())
+	}
+	return actions
+}
+
+func newRequest(name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespaceName, Name: name}}
+}
+
+func newNSTmplSetSpec() toolchainv1alpha1.NSTemplateSetSpec {
+	return toolchainv1alpha1.NSTemplateSetSpec{
+		TierName: "basic",
+		Namespaces: []toolchainv1alpha1.NSTemplateSetNamespace{
+			{Type: "dev", Revision: "abcde11"},
+			{Type: "code", Revision: "abcde21"},
+		},
+	}
+}
+
+func newUserAccount() *toolchainv1alpha1.UserAccount {
+	return &toolchainv1alpha1.UserAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: username, Namespace: namespaceName},
+		Spec: toolchainv1alpha1.UserAccountSpec{
+			UserID:        userID,
+			NSTemplateSet: newNSTmplSetSpec(),
+		},
+	}
+}
+
+func newNSTmplSet() *toolchainv1alpha1.NSTemplateSet {
+	return &toolchainv1alpha1.NSTemplateSet{
+		ObjectMeta: metav1.ObjectMeta{Name: username, Namespace: namespaceName},
+		Spec:       newNSTmplSetSpec(),
+	}
+}
+
+func newUser() *userv1.User {
+	return &userv1.User{ObjectMeta: metav1.ObjectMeta{Name: username}}
+}
+
+func newIdentity() *userv1.Identity {
+	return &userv1.Identity{ObjectMeta: metav1.ObjectMeta{Name: useraccount.ToIdentityName(userID)}}
+}
+
+func newUserNamespace(typeName, revision string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: username + "-" + typeName,
+			Labels: map[string]string{
+				"owner":    username,
+				"type":     typeName,
+				"revision": revision,
+			},
+		},
+	}
+}
@@ -0,0 +1,308 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("audit_trail")
+
+const (
+	// UserLabel the label on the audit trail ConfigMaps which holds the name of the user
+	UserLabel = "toolchain.dev.openshift.com/audit-trail"
+	// EntriesKey the key of the data of the audit trail ConfigMaps which holds the entries, oldest first
+	EntriesKey = "entries"
+
+	configMapPrefix = "audit-trail-"
+)
+
+// Action the kind of mutation performed by the operator
+type Action string
+
+const (
+	// Create the object was created
+	Create Action = "create"
+	// Update the object was updated
+	Update Action = "update"
+	// Patch the object was patched
+	Patch Action = "patch"
+	// Delete the object was deleted
+	Delete Action = "delete"
+)
+
+// Entry a single mutation performed by the operator
+type Entry struct {
+	Time       metav1.Time `json:"time"`
+	Action     Action      `json:"action"`
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Namespace  string      `json:"namespace,omitempty"`
+	Name       string      `json:"name"`
+	// Changes the summary of the fields changed by an update (eg, `spec`, `labels`)
+	Changes []string `json:"changes,omitempty"`
+	// Trigger the reconcile request which caused the mutation (eg, `nstemplateset/johnsmith`)
+	Trigger string `json:"trigger"`
+}
+
+// ConfigMapName returns the name of the ConfigMap which holds the audit trail of the given user
+func ConfigMapName(user string) string {
+	return configMapPrefix + user
+}
+
+// Recorder records the entries of the audit trail in the logs and, if its size is positive,
+// in a ConfigMap per user which keeps the last `size` entries
+type Recorder struct {
+	client client.Client
+	scheme *runtime.Scheme
+	size   int
+}
+
+// NewRecorder returns a new Recorder which keeps the given number of entries per user. The entries are only logged if the size is `0`
+func NewRecorder(cl client.Client, scheme *runtime.Scheme, size int) *Recorder {
+	return &Recorder{
+		client: cl,
+		scheme: scheme,
+		size:   size,
+	}
+}
+
+// Record records the given entry of the given user. The ConfigMap (if any) is stored in the given namespace.
+// Failures are logged but never returned, since the mutation already happened anyway.
+func (r *Recorder) Record(namespace, user string, entry Entry) {
+	log.Info("operator mutation",
+		"user", user,
+		"action", entry.Action,
+		"apiVersion", entry.APIVersion,
+		"kind", entry.Kind,
+		"namespace", entry.Namespace,
+		"name", entry.Name,
+		"changes", entry.Changes,
+		"trigger", entry.Trigger)
+	if r.size <= 0 {
+		return
+	}
+	if err := r.append(namespace, user, entry); err != nil {
+		log.Error(err, "unable to record the audit trail entry", "user", user)
+	}
+}
+
+// append adds the given entry to the ConfigMap of the given user, dropping the oldest entries beyond the size of the Recorder
+func (r *Recorder) append(namespace, user string, entry Entry) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: ConfigMapName(user)}, cm)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errs.Wrap(err, "unable to get the audit trail")
+		}
+		notFound := apierrors.IsNotFound(err)
+		entries, err := Entries(cm)
+		if err != nil {
+			// start over rather than being stuck with a corrupted ConfigMap
+			log.Error(err, "discarding the corrupted audit trail", "user", user)
+			entries = nil
+		}
+		entries = append(entries, entry)
+		if len(entries) > r.size {
+			entries = entries[len(entries)-r.size:]
+		}
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return errs.Wrap(err, "unable to marshal the audit trail")
+		}
+		if notFound {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      ConfigMapName(user),
+					Labels:    map[string]string{UserLabel: user},
+				},
+				Data: map[string]string{EntriesKey: string(data)},
+			}
+			return r.client.Create(context.TODO(), cm)
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[EntriesKey] = string(data)
+		return r.client.Update(context.TODO(), cm)
+	})
+}
+
+// Entries returns the entries of the given audit trail ConfigMap, oldest first
+func Entries(cm *corev1.ConfigMap) ([]Entry, error) {
+	data, found := cm.Data[EntriesKey]
+	if !found || data == "" {
+		return nil, nil
+	}
+	var entries []Entry
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, errs.Wrapf(err, "unable to unmarshal the audit trail '%s'", cm.Name)
+	}
+	return entries, nil
+}
+
+// auditClient a client which records the mutations performed with it in the audit trail
+type auditClient struct {
+	client.Client
+	recorder  *Recorder
+	namespace string
+	user      string
+	trigger   string
+}
+
+// NewClient returns a client which records in the audit trail of the given user all the objects it successfully creates,
+// updates, patches or deletes, along with the given trigger. The updates of the status of the objects are not recorded.
+// Returns the given client unchanged if the recorder is nil.
+func NewClient(cl client.Client, recorder *Recorder, namespace, user, trigger string) client.Client {
+	if recorder == nil {
+		return cl
+	}
+	return &auditClient{
+		Client:    cl,
+		recorder:  recorder,
+		namespace: namespace,
+		user:      user,
+		trigger:   trigger,
+	}
+}
+
+// Create creates the given object and records it in the audit trail
+func (c *auditClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if err := c.Client.Create(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.record(Create, obj, nil)
+	return nil
+}
+
+// Update updates the given object and records it in the audit trail along with the summary of the changed fields
+func (c *auditClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	changes := c.changes(ctx, obj)
+	if err := c.Client.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.record(Update, obj, changes)
+	return nil
+}
+
+// Patch patches the given object and records it in the audit trail
+func (c *auditClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	c.record(Patch, obj, nil)
+	return nil
+}
+
+// Delete deletes the given object and records it in the audit trail
+func (c *auditClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	if err := c.Client.Delete(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.record(Delete, obj, nil)
+	return nil
+}
+
+func (c *auditClient) record(action Action, obj runtime.Object, changes []string) {
+	entry := Entry{
+		Time:    metav1.NewTime(time.Now()),
+		Action:  action,
+		Changes: changes,
+		Trigger: c.trigger,
+	}
+	if gvk, err := apiutil.GVKForObject(obj, c.recorder.scheme); err == nil {
+		entry.APIVersion, entry.Kind = gvk.ToAPIVersionAndKind()
+	}
+	if meta, ok := obj.(metav1.Object); ok {
+		entry.Namespace = meta.GetNamespace()
+		entry.Name = meta.GetName()
+	}
+	c.recorder.Record(c.namespace, c.user, entry)
+}
+
+// changes returns the summary of the fields of the given object which differ from the existing object, ie,
+// the top-level fields (except `metadata` and `status`) along with the labels and the annotations.
+// Returns nil if the existing object cannot be retrieved.
+func (c *auditClient) changes(ctx context.Context, obj runtime.Object) []string {
+	meta, ok := obj.(metav1.Object)
+	if !ok {
+		return nil
+	}
+	gvk, err := apiutil.GVKForObject(obj, c.recorder.scheme)
+	if err != nil {
+		return nil
+	}
+	var existing runtime.Object
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		existing = u
+	} else if existing, err = c.recorder.scheme.New(gvk); err != nil {
+		return nil
+	}
+	if err := c.Client.Get(ctx, types.NamespacedName{Namespace: meta.GetNamespace(), Name: meta.GetName()}, existing); err != nil {
+		return nil
+	}
+	return diff(existing, obj)
+}
+
+// diff returns the names of the top-level fields (except `metadata` and `status`) which differ between both objects,
+// along with `labels` and `annotations` if they differ
+func diff(existing, updated runtime.Object) []string {
+	before, err := toMap(existing)
+	if err != nil {
+		return nil
+	}
+	after, err := toMap(updated)
+	if err != nil {
+		return nil
+	}
+	var changes []string
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+	for field := range fields {
+		switch field {
+		case "apiVersion", "kind", "metadata", "status":
+			continue
+		}
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, field)
+		}
+	}
+	for _, field := range []string{"labels", "annotations"} {
+		b, _, _ := unstructured.NestedFieldNoCopy(before, "metadata", field)
+		a, _, _ := unstructured.NestedFieldNoCopy(after, "metadata", field)
+		if !reflect.DeepEqual(b, a) {
+			changes = append(changes, field)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func toMap(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.UnstructuredContent(), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
@@ -0,0 +1,140 @@
+package audittrail_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/audittrail"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	operatorNamespace = "toolchain-member-operator"
+	username          = "johnsmith"
+)
+
+func TestAuditClient(t *testing.T) {
+
+	t.Run("mutations recorded in the configmap", func(t *testing.T) {
+		// given
+		fakeClient := test.NewFakeClient(t)
+		recorder := audittrail.NewRecorder(fakeClient, scheme.Scheme, 10)
+		cl := audittrail.NewClient(fakeClient, recorder, operatorNamespace, username, "nstemplateset/johnsmith")
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "settings"},
+			Data:       map[string]string{"color": "blue"},
+		}
+
+		// when
+		err := cl.Create(context.TODO(), cm)
+		require.NoError(t, err)
+		cm.Data["color"] = "red"
+		cm.Labels = map[string]string{"owner": username}
+		err = cl.Update(context.TODO(), cm)
+		require.NoError(t, err)
+		err = cl.Delete(context.TODO(), cm)
+		require.NoError(t, err)
+
+		// then
+		entries := getEntries(t, fakeClient)
+		require.Len(t, entries, 3)
+		assertEntry(t, entries[0], audittrail.Create, nil)
+		assertEntry(t, entries[1], audittrail.Update, []string{"data", "labels"})
+		assertEntry(t, entries[2], audittrail.Delete, nil)
+	})
+
+	t.Run("oldest entries dropped", func(t *testing.T) {
+		// given
+		fakeClient := test.NewFakeClient(t)
+		recorder := audittrail.NewRecorder(fakeClient, scheme.Scheme, 2)
+		cl := audittrail.NewClient(fakeClient, recorder, operatorNamespace, username, "nstemplateset/johnsmith")
+
+		// when
+		for _, name := range []string{"first", "second", "third"} {
+			err := cl.Create(context.TODO(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: name}})
+			require.NoError(t, err)
+		}
+
+		// then
+		entries := getEntries(t, fakeClient)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "second", entries[0].Name)
+		assert.Equal(t, "third", entries[1].Name)
+	})
+
+	t.Run("failed mutation not recorded", func(t *testing.T) {
+		// given
+		fakeClient := test.NewFakeClient(t)
+		recorder := audittrail.NewRecorder(fakeClient, scheme.Scheme, 10)
+		cl := audittrail.NewClient(fakeClient, recorder, operatorNamespace, username, "nstemplateset/johnsmith")
+		fakeClient.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("mock error")
+		}
+
+		// when
+		err := cl.Delete(context.TODO(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "settings"}})
+
+		// then
+		require.EqualError(t, err, "mock error")
+		trail := &corev1.ConfigMap{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: audittrail.ConfigMapName(username)}, trail)
+		require.Error(t, err)
+	})
+
+	t.Run("no configmap when the size is zero", func(t *testing.T) {
+		// given
+		fakeClient := test.NewFakeClient(t)
+		recorder := audittrail.NewRecorder(fakeClient, scheme.Scheme, 0)
+		cl := audittrail.NewClient(fakeClient, recorder, operatorNamespace, username, "nstemplateset/johnsmith")
+
+		// when
+		err := cl.Create(context.TODO(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "settings"}})
+
+		// then
+		require.NoError(t, err)
+		trail := &corev1.ConfigMap{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: audittrail.ConfigMapName(username)}, trail)
+		require.Error(t, err)
+	})
+
+	t.Run("client unchanged without recorder", func(t *testing.T) {
+		// given
+		fakeClient := test.NewFakeClient(t)
+
+		// when
+		cl := audittrail.NewClient(fakeClient, nil, operatorNamespace, username, "nstemplateset/johnsmith")
+
+		// then
+		assert.Equal(t, fakeClient, cl)
+	})
+}
+
+func getEntries(t *testing.T, cl client.Client) []audittrail.Entry {
+	trail := &corev1.ConfigMap{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: audittrail.ConfigMapName(username)}, trail)
+	require.NoError(t, err)
+	assert.Equal(t, username, trail.Labels[audittrail.UserLabel])
+	entries, err := audittrail.Entries(trail)
+	require.NoError(t, err)
+	return entries
+}
+
+func assertEntry(t *testing.T, entry audittrail.Entry, action audittrail.Action, changes []string) {
+	assert.Equal(t, action, entry.Action)
+	assert.Equal(t, "v1", entry.APIVersion)
+	assert.Equal(t, "ConfigMap", entry.Kind)
+	assert.Equal(t, "johnsmith-dev", entry.Namespace)
+	assert.Equal(t, "settings", entry.Name)
+	assert.Equal(t, changes, entry.Changes)
+	assert.Equal(t, "nstemplateset/johnsmith", entry.Trigger)
+}
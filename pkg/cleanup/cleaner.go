@@ -0,0 +1,151 @@
+package cleanup
+
+import (
+	"context"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/pause"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/go-logr/logr"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("stale_resources_cleaner")
+
+const (
+	// providerLabelKey the key of the label set by the templates on all the resources that they provide
+	providerLabelKey = "provider"
+	// providerLabelValue the value of the label set by the templates on all the resources that they provide
+	providerLabelValue = "codeready-toolchain"
+
+	// DefaultInterval the default interval between two cleanups
+	DefaultInterval = time.Hour
+)
+
+// Add creates a new Cleaner and adds it to the Manager if the cleanup of the stale resources is enabled.
+// The Cleaner only runs on the leader.
+func Add(mgr manager.Manager) error {
+	if !config.StaleResourcesCleanupEnabled() {
+		return nil
+	}
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	return mgr.Add(NewCleaner(mgr.GetClient(), namespace, DefaultInterval))
+}
+
+// Cleaner periodically deletes the Secrets and ConfigMaps which were provided by the templates in the user namespaces,
+// but which are not recorded in the inventory of the corresponding NSTemplateSet anymore (ie, which are not part of the current
+// revisions of the templates). Namespaces without any entry in the inventory are skipped, since their resources may have been
+// applied before the inventory was introduced.
+type Cleaner struct {
+	client    client.Client
+	namespace string
+	interval  time.Duration
+}
+
+// NewCleaner returns a new Cleaner for the NSTemplateSets in the given namespace
+func NewCleaner(cl client.Client, namespace string, interval time.Duration) *Cleaner {
+	return &Cleaner{
+		client:    cl,
+		namespace: namespace,
+		interval:  interval,
+	}
+}
+
+// Start deletes the stale resources at every interval, until the given channel is closed
+func (c *Cleaner) Start(stop <-chan struct{}) error {
+	log.Info("starting the stale resources cleaner", "interval", c.interval)
+	wait.Until(func() {
+		if err := c.CleanAll(); err != nil {
+			log.Error(err, "failed to delete the stale resources")
+		}
+	}, c.interval, stop)
+	return nil
+}
+
+// CleanAll deletes the stale resources in the namespaces of all the NSTemplateSets, except those whose reconciliation is paused
+func (c *Cleaner) CleanAll() error {
+	nsTmplSets := &toolchainv1alpha1.NSTemplateSetList{}
+	if err := c.client.List(context.TODO(), nsTmplSets, client.InNamespace(c.namespace)); err != nil {
+		return errs.Wrap(err, "failed to list the NSTemplateSets")
+	}
+	for i := range nsTmplSets.Items {
+		nsTmplSet := &nsTmplSets.Items[i]
+		if nsTmplSet.DeletionTimestamp != nil || pause.IsPaused(nsTmplSet) {
+			continue
+		}
+		logger := log.WithValues("NSTemplateSet", nsTmplSet.Name)
+		if err := c.clean(logger, nsTmplSet); err != nil {
+			// do not prevent the cleanup for the other users
+			logger.Error(err, "failed to delete the stale resources")
+		}
+	}
+	return nil
+}
+
+func (c *Cleaner) clean(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet) error {
+	inventory, err := template.ParseInventory(nsTmplSet.GetAnnotations()[template.InventoryAnnotation])
+	if err != nil {
+		return err
+	}
+	userNamespaces := &corev1.NamespaceList{}
+	if err := c.client.List(context.TODO(), userNamespaces, client.MatchingLabels(map[string]string{"owner": nsTmplSet.Name})); err != nil {
+		return errs.Wrapf(err, "failed to list namespace with label owner '%s'", nsTmplSet.Name)
+	}
+	for _, ns := range userNamespaces.Items {
+		if len(inventory.EntriesInNamespace(ns.Name)) == 0 {
+			continue
+		}
+		for _, list := range []runtime.Object{&corev1.SecretList{}, &corev1.ConfigMapList{}} {
+			if err := c.cleanList(logger, inventory, ns.Name, list); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cleanList deletes the items of the given kind of list in the given namespace, which are provided by the templates
+// but which are not recorded in the inventory
+func (c *Cleaner) cleanList(logger logr.Logger, inventory *template.Inventory, namespace string, list runtime.Object) error {
+	if err := c.client.List(context.TODO(), list, client.InNamespace(namespace), client.MatchingLabels(map[string]string{providerLabelKey: providerLabelValue})); err != nil {
+		return errs.Wrapf(err, "failed to list the resources in namespace '%s'", namespace)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		acc, err := meta.Accessor(item)
+		if err != nil {
+			return err
+		}
+		gvk, err := apiutil.GVKForObject(item, scheme.Scheme)
+		if err != nil {
+			return err
+		}
+		if inventory.Contains(gvk, namespace, acc.GetName()) {
+			continue
+		}
+		logger.Info("deleting stale resource", "kind", gvk.Kind, "namespace", namespace, "name", acc.GetName())
+		if err := c.client.Delete(context.TODO(), item); err != nil && !apierrors.IsNotFound(err) {
+			return errs.Wrapf(err, "unable to delete the resource of kind '%s' and name '%s' in namespace '%s'", gvk.Kind, acc.GetName(), namespace)
+		}
+	}
+	return nil
+}
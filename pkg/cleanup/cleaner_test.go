@@ -0,0 +1,177 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/member-operator/pkg/pause"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	username      = "johnsmith"
+	namespaceName = "toolchain-member"
+)
+
+func TestCleanAll(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	inventory := `{"entries":[` +
+		`{"apiVersion":"v1","kind":"Secret","namespace":"johnsmith-dev","name":"current"},` +
+		`{"apiVersion":"v1","kind":"ConfigMap","namespace":"johnsmith-dev","name":"current"}]}`
+
+	t.Run("stale resources deleted", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(inventory), newUserNamespace("dev"), newUserNamespace("code"),
+			newSecret("dev", "current", true), newSecret("dev", "stale", true), newSecret("dev", "user-secret", false),
+			newConfigMap("dev", "current"), newConfigMap("dev", "stale"),
+			newSecret("code", "not-in-inventory", true))
+		c := NewCleaner(cl, namespaceName, DefaultInterval)
+
+		// when
+		err := c.CleanAll()
+
+		// then
+		require.NoError(t, err)
+		assertExists(t, cl, "dev", "current", &corev1.Secret{})
+		assertExists(t, cl, "dev", "current", &corev1.ConfigMap{})
+		assertDeleted(t, cl, "dev", "stale", &corev1.Secret{})
+		assertDeleted(t, cl, "dev", "stale", &corev1.ConfigMap{})
+		// not provided by a template
+		assertExists(t, cl, "dev", "user-secret", &corev1.Secret{})
+		// namespace not tracked in the inventory
+		assertExists(t, cl, "code", "not-in-inventory", &corev1.Secret{})
+	})
+
+	t.Run("nothing deleted without inventory", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(""), newUserNamespace("dev"), newSecret("dev", "stale", true))
+		c := NewCleaner(cl, namespaceName, DefaultInterval)
+
+		// when
+		err := c.CleanAll()
+
+		// then
+		require.NoError(t, err)
+		assertExists(t, cl, "dev", "stale", &corev1.Secret{})
+	})
+
+	t.Run("nothing deleted while paused", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet(inventory)
+		nsTmplSet.Annotations[pause.Annotation] = "true"
+		cl := test.NewFakeClient(t, nsTmplSet, newUserNamespace("dev"), newSecret("dev", "stale", true))
+		c := NewCleaner(cl, namespaceName, DefaultInterval)
+
+		// when
+		err := c.CleanAll()
+
+		// then
+		require.NoError(t, err)
+		assertExists(t, cl, "dev", "stale", &corev1.Secret{})
+	})
+
+	t.Run("failed to delete", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(inventory), newUserNamespace("dev"), newSecret("dev", "stale", true))
+		cl.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("mock error")
+		}
+		c := NewCleaner(cl, namespaceName, DefaultInterval)
+
+		// when
+		err := c.CleanAll()
+
+		// then the failure is only logged
+		require.NoError(t, err)
+		assertExists(t, cl, "dev", "stale", &corev1.Secret{})
+	})
+
+	t.Run("failed to list NSTemplateSets", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			return errors.New("mock error")
+		}
+		c := NewCleaner(cl, namespaceName, DefaultInterval)
+
+		// when
+		err := c.CleanAll()
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list the NSTemplateSets")
+	})
+}
+
+func assertExists(t *testing.T, cl client.Client, typeName, name string, obj runtime.Object) {
+	t.Helper()
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: username + "-" + typeName, Name: name}, obj)
+	require.NoError(t, err)
+}
+
+func assertDeleted(t *testing.T, cl client.Client, typeName, name string, obj runtime.Object) {
+	t.Helper()
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: username + "-" + typeName, Name: name}, obj)
+	require.Error(t, err)
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func newNSTmplSet(inventory string) *toolchainv1alpha1.NSTemplateSet {
+	return &toolchainv1alpha1.NSTemplateSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        username,
+			Namespace:   namespaceName,
+			Annotations: map[string]string{template.InventoryAnnotation: inventory},
+		},
+	}
+}
+
+func newUserNamespace(typeName string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   username + "-" + typeName,
+			Labels: map[string]string{"owner": username, "type": typeName},
+		},
+	}
+}
+
+func newSecret(typeName, name string, provided bool) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: username + "-" + typeName,
+		},
+	}
+	if provided {
+		secret.Labels = map[string]string{"provider": "codeready-toolchain"}
+	}
+	return secret
+}
+
+func newConfigMap(typeName, name string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: username + "-" + typeName,
+			Labels:    map[string]string{"provider": "codeready-toolchain"},
+		},
+	}
+}
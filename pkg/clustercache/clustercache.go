@@ -0,0 +1,33 @@
+// Package clustercache provides the cache of the objects of the user namespaces. The cache of the manager cannot be used for them,
+// since it only holds the (namespaced) objects of the operator namespace.
+package clustercache
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// New returns a new cache of the objects of all the namespaces, which is started along with the given manager.
+// The informers of the cache are only created for the kinds which are read or watched through it.
+func New(mgr manager.Manager) (cache.Cache, error) {
+	c, err := cache.New(mgr.GetConfig(), cache.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return nil, err
+	}
+	if err := mgr.Add(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Kind returns the source of the events of the objects of the given type in all the namespaces, as observed by the given cache.
+// The plain `source.Kind` only observes the objects of the operator namespace, since it relies on the cache of the manager
+func Kind(c cache.Cache, obj runtime.Object) (*source.Kind, error) {
+	src := &source.Kind{Type: obj}
+	if err := src.InjectCache(c); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
@@ -0,0 +1,105 @@
+package conditions
+
+import (
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The reasons of the Ready condition which are shared by the controllers
+const (
+	// ProvisioningReason the resource is being provisioned
+	ProvisioningReason = "Provisioning"
+	// ProvisionedReason the resource is provisioned
+	ProvisionedReason = "Provisioned"
+	// UpdatingReason the resource is being updated
+	UpdatingReason = "Updating"
+	// TerminatingReason the resource is being deleted
+	TerminatingReason = "Terminating"
+	// UnableToTerminateReason the resource could not be deleted
+	UnableToTerminateReason = "UnableToTerminate"
+)
+
+// Ready returns a Ready condition with the `True` status and the given reason
+func Ready(reason string) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:   toolchainv1alpha1.ConditionReady,
+		Status: corev1.ConditionTrue,
+		Reason: reason,
+	}
+}
+
+// NotReady returns a Ready condition with the `False` status and the given reason and message
+func NotReady(reason, message string) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:    toolchainv1alpha1.ConditionReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// Update adds the given new conditions to the given conditions, or replaces the existing conditions of the same types.
+// The `lastTransitionTime` of a condition is only set when its status changes (or when it is added), so that a change of its reason
+// or message (eg, a different error while retrying) does not hide since when the resource is in that state. The order of the
+// existing conditions is preserved.
+// Returns true if any of the conditions was added or changed, ie, if the status of the resource needs to be updated.
+func Update(conditions []toolchainv1alpha1.Condition, newConditions ...toolchainv1alpha1.Condition) ([]toolchainv1alpha1.Condition, bool) {
+	return update(conditions, metav1.Now(), newConditions...)
+}
+
+func update(conditions []toolchainv1alpha1.Condition, now metav1.Time, newConditions ...toolchainv1alpha1.Condition) ([]toolchainv1alpha1.Condition, bool) {
+	updated := false
+	for _, newCond := range newConditions {
+		newCond.LastTransitionTime = now
+		found := false
+		for i, cond := range conditions {
+			if cond.Type != newCond.Type {
+				continue
+			}
+			found = true
+			if cond.Status == newCond.Status {
+				if cond.Reason == newCond.Reason && cond.Message == newCond.Message {
+					break
+				}
+				newCond.LastTransitionTime = cond.LastTransitionTime
+			}
+			conditions[i] = newCond
+			updated = true
+			break
+		}
+		if !found {
+			conditions = append(conditions, newCond)
+			updated = true
+		}
+	}
+	return conditions, updated
+}
+
+// Remove removes the condition of the given type from the given conditions, if it exists.
+// Returns true if it was removed, ie, if the status of the resource needs to be updated.
+func Remove(conditions []toolchainv1alpha1.Condition, condType toolchainv1alpha1.ConditionType) ([]toolchainv1alpha1.Condition, bool) {
+	for i, cond := range conditions {
+		if cond.Type == condType {
+			return append(conditions[:i], conditions[i+1:]...), true
+		}
+	}
+	return conditions, false
+}
+
+// IsTrue returns true if the given conditions contain a condition of the given type with the `True` status
+func IsTrue(conditions []toolchainv1alpha1.Condition, condType toolchainv1alpha1.ConditionType) bool {
+	cond, found := condition.FindConditionByType(conditions, condType)
+	return found && cond.Status == corev1.ConditionTrue
+}
+
+// Observe sets the given observed generation of a status to the given generation of its resource, so that the clients can tell
+// whether the conditions reflect the latest spec. Returns true if it changed.
+func Observe(observedGeneration *int64, generation int64) bool {
+	if *observedGeneration == generation {
+		return false
+	}
+	*observedGeneration = generation
+	return true
+}
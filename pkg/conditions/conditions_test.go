@@ -0,0 +1,117 @@
+package conditions
+
+import (
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdate(t *testing.T) {
+	// given
+	before := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	paused := toolchainv1alpha1.Condition{
+		Type:               "Paused",
+		Status:             corev1.ConditionFalse,
+		Reason:             "Resumed",
+		LastTransitionTime: before,
+	}
+	existing := func() []toolchainv1alpha1.Condition {
+		ready := NotReady("UnableToProvision", "first error")
+		ready.LastTransitionTime = before
+		return []toolchainv1alpha1.Condition{ready, paused}
+	}
+
+	t.Run("condition added", func(t *testing.T) {
+		// when
+		conditions, updated := update(nil, now, Ready(ProvisionedReason))
+
+		// then
+		assert.True(t, updated)
+		require.Len(t, conditions, 1)
+		assert.Equal(t, corev1.ConditionTrue, conditions[0].Status)
+		assert.Equal(t, now, conditions[0].LastTransitionTime)
+	})
+
+	t.Run("unchanged condition", func(t *testing.T) {
+		// when
+		conditions, updated := update(existing(), now, NotReady("UnableToProvision", "first error"))
+
+		// then
+		assert.False(t, updated)
+		assert.Equal(t, existing(), conditions)
+	})
+
+	t.Run("transition time kept when only the message changed", func(t *testing.T) {
+		// when
+		conditions, updated := update(existing(), now, NotReady("UnableToProvision", "second error"))
+
+		// then
+		assert.True(t, updated)
+		require.Len(t, conditions, 2)
+		assert.Equal(t, "second error", conditions[0].Message)
+		assert.Equal(t, before, conditions[0].LastTransitionTime)
+		assert.Equal(t, paused, conditions[1])
+	})
+
+	t.Run("transition time set when the status changed", func(t *testing.T) {
+		// when
+		conditions, updated := update(existing(), now, Ready(ProvisionedReason))
+
+		// then
+		assert.True(t, updated)
+		require.Len(t, conditions, 2)
+		assert.Equal(t, ProvisionedReason, conditions[0].Reason)
+		assert.Empty(t, conditions[0].Message)
+		assert.Equal(t, now, conditions[0].LastTransitionTime)
+		assert.True(t, IsTrue(conditions, toolchainv1alpha1.ConditionReady))
+		assert.False(t, IsTrue(conditions, "Paused"))
+	})
+}
+
+func TestRemove(t *testing.T) {
+
+	t.Run("condition removed", func(t *testing.T) {
+		// given
+		existing := []toolchainv1alpha1.Condition{NotReady("UnableToProvision", "error"), {Type: "Paused", Status: corev1.ConditionFalse}}
+
+		// when
+		conditions, removed := Remove(existing, toolchainv1alpha1.ConditionReady)
+
+		// then
+		assert.True(t, removed)
+		require.Len(t, conditions, 1)
+		assert.Equal(t, toolchainv1alpha1.ConditionType("Paused"), conditions[0].Type)
+	})
+
+	t.Run("no condition of the type", func(t *testing.T) {
+		// given
+		existing := []toolchainv1alpha1.Condition{{Type: "Paused", Status: corev1.ConditionFalse}}
+
+		// when
+		conditions, removed := Remove(existing, toolchainv1alpha1.ConditionReady)
+
+		// then
+		assert.False(t, removed)
+		assert.Len(t, conditions, 1)
+	})
+}
+
+func TestObserve(t *testing.T) {
+	// given
+	var observed int64 = 1
+
+	// when
+	changed := Observe(&observed, 2)
+
+	// then
+	assert.True(t, changed)
+	assert.Equal(t, int64(2), observed)
+	assert.False(t, Observe(&observed, 2))
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/metrics"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("config")
+
+const (
+	// DefaultBackoffInitialDelay the delay before the first retry of a failed reconciliation when it is not specified in the backoff
+	// of the controller
+	DefaultBackoffInitialDelay = time.Second
+	// DefaultBackoffMaxDelay the maximum delay between two retries of a failed reconciliation when it is not specified in the backoff
+	// of the controller
+	DefaultBackoffMaxDelay = 5 * time.Minute
+)
+
+// ControllerOptions returns the options of the controller with the given name and reconciler, with the concurrency and the rate limit
+// specified in the last loaded MemberOperatorConfig. The reconciliations are rate limited by waiting for a token of a token bucket before
+// each of them, so that a burst of changes (eg, a tier update) does not overwhelm the API server. When a backoff is specified,
+// the failed reconciliations are retried with a bounded exponential backoff instead of the default backoff of the controller-runtime,
+// which starts at a few milliseconds and hammers the API server when it is unavailable.
+func ControllerOptions(name string, r reconcile.Reconciler) controller.Options {
+	cfg := GetControllerConfig(name)
+	options := controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: 1,
+	}
+	if cfg.MaxConcurrentReconciles > 0 {
+		options.MaxConcurrentReconciles = int(cfg.MaxConcurrentReconciles)
+	}
+	if cfg.RateLimiter != nil && cfg.RateLimiter.QPS > 0 {
+		burst := cfg.RateLimiter.Burst
+		if burst <= 0 {
+			burst = cfg.RateLimiter.QPS
+		}
+		options.Reconciler = &rateLimitedReconciler{
+			Reconciler: r,
+			limiter:    flowcontrol.NewTokenBucketRateLimiter(float32(cfg.RateLimiter.QPS), int(burst)),
+		}
+	}
+	if cfg.Backoff != nil {
+		options.Reconciler = newBackoffReconciler(name, options.Reconciler, cfg.Backoff.InitialDelay, cfg.Backoff.MaxDelay, cfg.Backoff.JitterPercent)
+	}
+	return options
+}
+
+// ControllerClient returns the client of the controller with the given name, which counts the requests of the controller that create,
+// update, patch or delete objects (including their status) and, if an API budget is specified for the controller in the last loaded
+// MemberOperatorConfig, waits for the budget before sending each of them. The reads are not counted, since they are served by the cache.
+func ControllerClient(name string, cl client.Client) client.Client {
+	c := &budgetClient{Client: cl, name: name}
+	if budget := GetControllerConfig(name).APIBudget; budget != nil && budget.QPS > 0 {
+		burst := budget.Burst
+		if burst <= 0 {
+			burst = budget.QPS
+		}
+		c.limiter = flowcontrol.NewTokenBucketRateLimiter(float32(budget.QPS), int(burst))
+	}
+	return c
+}
+
+// budgetClient a client which counts the requests of a controller that mutate objects, and waits for the API budget of the controller (if any)
+type budgetClient struct {
+	client.Client
+	name    string
+	limiter flowcontrol.RateLimiter
+}
+
+// Create waits for the API budget, then creates the given object
+func (c *budgetClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	c.wait()
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// Update waits for the API budget, then updates the given object
+func (c *budgetClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	c.wait()
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// Patch waits for the API budget, then patches the given object
+func (c *budgetClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.wait()
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// Delete waits for the API budget, then deletes the given object
+func (c *budgetClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	c.wait()
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+// Status returns a status writer which waits for the API budget before each update of the status
+func (c *budgetClient) Status() client.StatusWriter {
+	return &budgetStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+// wait waits for a token of the API budget (if any) and records the request
+func (c *budgetClient) wait() {
+	throttled := false
+	if c.limiter != nil && !c.limiter.TryAccept() {
+		throttled = true
+		c.limiter.Accept()
+	}
+	metrics.RecordControllerRequest(c.name, throttled)
+}
+
+type budgetStatusWriter struct {
+	client.StatusWriter
+	client *budgetClient
+}
+
+// Update waits for the API budget, then updates the status of the given object
+func (w *budgetStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	w.client.wait()
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+// Patch waits for the API budget, then patches the status of the given object
+func (w *budgetStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	w.client.wait()
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// rateLimitedReconciler a reconciler which waits for the rate limiter before each reconciliation
+type rateLimitedReconciler struct {
+	reconcile.Reconciler
+	limiter flowcontrol.RateLimiter
+}
+
+// Reconcile waits for the rate limiter, then reconciles the request
+func (r *rateLimitedReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	r.limiter.Accept()
+	return r.Reconciler.Reconcile(request)
+}
+
+// backoffReconciler a reconciler which retries the failed reconciliations (and those which asked to be requeued without delay)
+// after an exponential delay per request, rather than returning the error to the controller
+type backoffReconciler struct {
+	reconcile.Reconciler
+	name          string
+	initialDelay  time.Duration
+	maxDelay      time.Duration
+	jitterPercent int32
+	lock          sync.Mutex
+	failures      map[reconcile.Request]int
+}
+
+// newBackoffReconciler returns a reconciler which retries the failed reconciliations of the given reconciler with the given backoff.
+// The delays which are invalid or not specified are replaced with their default value
+func newBackoffReconciler(name string, r reconcile.Reconciler, initialDelay, maxDelay string, jitterPercent int32) *backoffReconciler {
+	initial, err := time.ParseDuration(initialDelay)
+	if err != nil || initial <= 0 {
+		initial = DefaultBackoffInitialDelay
+	}
+	maximum, err := time.ParseDuration(maxDelay)
+	if err != nil || maximum <= 0 {
+		maximum = DefaultBackoffMaxDelay
+	}
+	if maximum < initial {
+		maximum = initial
+	}
+	if jitterPercent < 0 {
+		jitterPercent = 0
+	}
+	return &backoffReconciler{
+		Reconciler:    r,
+		name:          name,
+		initialDelay:  initial,
+		maxDelay:      maximum,
+		jitterPercent: jitterPercent,
+		failures:      map[reconcile.Request]int{},
+	}
+}
+
+// Reconcile reconciles the request and, if it failed, requeues it after the next delay of its backoff.
+// The backoff of the request is reset as soon as a reconciliation succeeds.
+func (r *backoffReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	result, err := r.Reconciler.Reconcile(request)
+	if err == nil && (!result.Requeue || result.RequeueAfter > 0) {
+		r.forget(request)
+		return result, nil
+	}
+	delay := r.next(request)
+	if err != nil {
+		log.Error(err, "reconciliation failed, retrying with backoff", "controller", r.name, "request", request.NamespacedName, "delay", delay)
+	}
+	return reconcile.Result{RequeueAfter: delay}, nil
+}
+
+// next returns the delay before the next retry of the given request, and records its failure
+func (r *backoffReconciler) next(request reconcile.Request) time.Duration {
+	r.lock.Lock()
+	failures := r.failures[request]
+	r.failures[request] = failures + 1
+	r.lock.Unlock()
+
+	delay := r.initialDelay
+	for i := 0; i < failures && delay < r.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.maxDelay {
+		delay = r.maxDelay
+	}
+	if jitter := int64(delay) * int64(r.jitterPercent) / 100; jitter > 0 {
+		delay += time.Duration(rand.Int63n(jitter + 1))
+	}
+	return delay
+}
+
+// forget resets the backoff of the given request
+func (r *backoffReconciler) forget(request reconcile.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.failures, request)
+}
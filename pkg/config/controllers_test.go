@@ -0,0 +1,243 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestControllerOptions(t *testing.T) {
+	defer set(nil)
+	r := &countingReconciler{}
+
+	t.Run("default options", func(t *testing.T) {
+		// given
+		set(nil)
+
+		// when
+		options := ControllerOptions("useraccount", r)
+
+		// then
+		assert.Equal(t, 1, options.MaxConcurrentReconciles)
+		assert.Same(t, r, options.Reconciler)
+	})
+
+	t.Run("concurrency from config", func(t *testing.T) {
+		// given
+		set(&memberv1alpha1.MemberOperatorConfigSpec{Controllers: map[string]memberv1alpha1.ControllerConfig{
+			"useraccount": {MaxConcurrentReconciles: 10},
+		}})
+
+		// when
+		options := ControllerOptions("useraccount", r)
+
+		// then
+		assert.Equal(t, 10, options.MaxConcurrentReconciles)
+		assert.Same(t, r, options.Reconciler)
+		// other controllers keep the default options
+		assert.Equal(t, 1, ControllerOptions("nstemplateset", r).MaxConcurrentReconciles)
+	})
+
+	t.Run("rate limiter from config", func(t *testing.T) {
+		// given
+		set(&memberv1alpha1.MemberOperatorConfigSpec{Controllers: map[string]memberv1alpha1.ControllerConfig{
+			"useraccount": {RateLimiter: &memberv1alpha1.RateLimiterConfig{QPS: 100}},
+		}})
+
+		// when
+		options := ControllerOptions("useraccount", r)
+
+		// then
+		require.IsType(t, &rateLimitedReconciler{}, options.Reconciler)
+		_, err := options.Reconciler.Reconcile(reconcile.Request{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, r.count)
+	})
+
+	t.Run("no rate limiter without qps", func(t *testing.T) {
+		// given
+		set(&memberv1alpha1.MemberOperatorConfigSpec{Controllers: map[string]memberv1alpha1.ControllerConfig{
+			"useraccount": {RateLimiter: &memberv1alpha1.RateLimiterConfig{Burst: 10}},
+		}})
+
+		// when
+		options := ControllerOptions("useraccount", r)
+
+		// then
+		assert.Same(t, r, options.Reconciler)
+	})
+}
+
+func TestControllerClient(t *testing.T) {
+	defer set(nil)
+
+	t.Run("no API budget", func(t *testing.T) {
+		// given
+		set(nil)
+		fakeClient := test.NewFakeClient(t)
+
+		// when
+		cl := ControllerClient("useraccount", fakeClient)
+
+		// then
+		require.IsType(t, &budgetClient{}, cl)
+		assert.Nil(t, cl.(*budgetClient).limiter)
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "toolchain-member", Name: "test"}}
+		require.NoError(t, cl.Create(context.TODO(), cm))
+		require.NoError(t, fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "toolchain-member", Name: "test"}, &corev1.ConfigMap{}))
+	})
+
+	t.Run("API budget from config", func(t *testing.T) {
+		// given
+		set(&memberv1alpha1.MemberOperatorConfigSpec{Controllers: map[string]memberv1alpha1.ControllerConfig{
+			"useraccount": {APIBudget: &memberv1alpha1.RateLimiterConfig{QPS: 100, Burst: 1}},
+		}})
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "toolchain-member", Name: "test"}}
+		fakeClient := test.NewFakeClient(t, cm)
+
+		// when
+		cl := ControllerClient("useraccount", fakeClient)
+
+		// then
+		require.IsType(t, &budgetClient{}, cl)
+		require.NotNil(t, cl.(*budgetClient).limiter)
+		assert.Equal(t, float32(100), cl.(*budgetClient).limiter.QPS())
+		cm.Data = map[string]string{"key": "value"}
+		require.NoError(t, cl.Update(context.TODO(), cm))
+		require.NoError(t, cl.Status().Update(context.TODO(), cm))
+		require.NoError(t, cl.Delete(context.TODO(), cm))
+		// other controllers have no budget
+		assert.Nil(t, ControllerClient("nstemplateset", fakeClient).(*budgetClient).limiter)
+	})
+}
+
+func TestBackoffReconciler(t *testing.T) {
+	defer set(nil)
+
+	t.Run("backoff from config", func(t *testing.T) {
+		// given
+		set(&memberv1alpha1.MemberOperatorConfigSpec{Controllers: map[string]memberv1alpha1.ControllerConfig{
+			"useraccount": {
+				RateLimiter: &memberv1alpha1.RateLimiterConfig{QPS: 100},
+				Backoff:     &memberv1alpha1.BackoffConfig{InitialDelay: "2s", MaxDelay: "10s"},
+			},
+		}})
+
+		// when
+		options := ControllerOptions("useraccount", &countingReconciler{})
+
+		// then
+		require.IsType(t, &backoffReconciler{}, options.Reconciler)
+		backoff := options.Reconciler.(*backoffReconciler)
+		assert.Equal(t, 2*time.Second, backoff.initialDelay)
+		assert.Equal(t, 10*time.Second, backoff.maxDelay)
+		assert.IsType(t, &rateLimitedReconciler{}, backoff.Reconciler)
+	})
+
+	t.Run("default delays", func(t *testing.T) {
+		// when
+		backoff := newBackoffReconciler("useraccount", &countingReconciler{}, "", "invalid", 0)
+
+		// then
+		assert.Equal(t, DefaultBackoffInitialDelay, backoff.initialDelay)
+		assert.Equal(t, DefaultBackoffMaxDelay, backoff.maxDelay)
+	})
+
+	t.Run("delays doubled up to the max delay", func(t *testing.T) {
+		// given
+		r := &failingReconciler{err: errors.New("mock error")}
+		backoff := newBackoffReconciler("useraccount", r, "1s", "5s", 0)
+		req := reconcile.Request{}
+
+		for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+			// when
+			res, err := backoff.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{RequeueAfter: expected}, res)
+		}
+
+		t.Run("backoff reset after success", func(t *testing.T) {
+			// given
+			r.err = nil
+
+			// when
+			res, err := backoff.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{}, res)
+			r.err = errors.New("mock error")
+			res, err = backoff.Reconcile(req)
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{RequeueAfter: time.Second}, res)
+		})
+	})
+
+	t.Run("requeue without delay backed off", func(t *testing.T) {
+		// given
+		r := &failingReconciler{result: reconcile.Result{Requeue: true}}
+		backoff := newBackoffReconciler("useraccount", r, "1s", "5s", 0)
+
+		// when
+		res, err := backoff.Reconcile(reconcile.Request{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: time.Second}, res)
+	})
+
+	t.Run("requeue after a delay kept", func(t *testing.T) {
+		// given
+		r := &failingReconciler{result: reconcile.Result{RequeueAfter: time.Minute}}
+		backoff := newBackoffReconciler("useraccount", r, "1s", "5s", 0)
+
+		// when
+		res, err := backoff.Reconcile(reconcile.Request{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: time.Minute}, res)
+	})
+
+	t.Run("jitter added to the delay", func(t *testing.T) {
+		// given
+		backoff := newBackoffReconciler("useraccount", &failingReconciler{err: errors.New("mock error")}, "10s", "1m", 50)
+
+		// when
+		res, err := backoff.Reconcile(reconcile.Request{})
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, res.RequeueAfter >= 10*time.Second && res.RequeueAfter <= 15*time.Second, "unexpected delay: %s", res.RequeueAfter)
+	})
+}
+
+type failingReconciler struct {
+	result reconcile.Result
+	err    error
+}
+
+func (r *failingReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	return r.result, r.err
+}
+
+type countingReconciler struct {
+	count int
+}
+
+func (r *countingReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	r.count++
+	return reconcile.Result{}, nil
+}
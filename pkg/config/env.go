@@ -1,6 +1,378 @@
 package config
 
-func GetIdP() string {
-	// TODO get from openshift
-	return "rhd"
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/version"
+)
+
+// ApplyWithProtobufEnvVar the name of the env var to set to `true` in order to use the protobuf content type
+// when applying the objects of native kinds from the templates
+const ApplyWithProtobufEnvVar = "MEMBER_OPERATOR_APPLY_WITH_PROTOBUF"
+
+// StaleResourcesCleanupEnvVar the name of the env var to set to `true` in order to enable the cleanup of the stale Secrets
+// and ConfigMaps in the user namespaces
+const StaleResourcesCleanupEnvVar = "MEMBER_OPERATOR_STALE_RESOURCES_CLEANUP"
+
+// ScaleDownDisabledUsersEnvVar the name of the env var to set to `true` in order to scale down the workloads in the namespaces
+// of the disabled users
+const ScaleDownDisabledUsersEnvVar = "MEMBER_OPERATOR_SCALE_DOWN_DISABLED_USERS"
+
+// StartupAuditRepairEnvVar the name of the env var to set to `true` in order to execute the repair plan of the startup audit
+const StartupAuditRepairEnvVar = "MEMBER_OPERATOR_STARTUP_AUDIT_REPAIR"
+
+// HostValidationWebhookEnvVar the name of the env var to set to `true` in order to serve the webhook which validates the hosts
+// of the Routes and Ingresses in the user namespaces
+const HostValidationWebhookEnvVar = "MEMBER_OPERATOR_HOST_VALIDATION_WEBHOOK"
+
+// PodMutationWebhookEnvVar the name of the env var to set to `true` in order to serve the webhook which sets the ephemeral storage
+// requests and limits of the pods in the user namespaces
+const PodMutationWebhookEnvVar = "MEMBER_OPERATOR_POD_MUTATION_WEBHOOK"
+
+// PodPriorityWebhookEnvVar the name of the env var to set to `true` in order to serve the webhook which sets the (low) priority class
+// of the pods in the user namespaces
+const PodPriorityWebhookEnvVar = "MEMBER_OPERATOR_POD_PRIORITY_WEBHOOK"
+
+// PodSchedulingWebhookEnvVar the name of the env var to set to `true` in order to serve the webhook which sets the node selector
+// and the tolerations of the pods in the user namespaces
+const PodSchedulingWebhookEnvVar = "MEMBER_OPERATOR_POD_SCHEDULING_WEBHOOK"
+
+// PodProxyWebhookEnvVar the name of the env var to set to `true` in order to serve the webhook which sets the proxy settings
+// and the trusted CA bundle of the pods in the user namespaces
+const PodProxyWebhookEnvVar = "MEMBER_OPERATOR_POD_PROXY_WEBHOOK"
+
+// ResourceValidationWebhookEnvVar the name of the env var to set to `true` in order to serve the webhook which denies the forbidden
+// resources in the user namespaces
+const ResourceValidationWebhookEnvVar = "MEMBER_OPERATOR_RESOURCE_VALIDATION_WEBHOOK"
+
+// VirtualMachineWebhookEnvVar the name of the env var to set to `true` in order to serve the webhook which defaults and caps the resources
+// of the VirtualMachines in the user namespaces
+const VirtualMachineWebhookEnvVar = "MEMBER_OPERATOR_VIRTUAL_MACHINE_WEBHOOK"
+
+const (
+	// WebhookCertificatesEnvVar the name of the env var which defines who provides the serving certificate of the webhooks
+	// and the CA bundle of their configurations
+	WebhookCertificatesEnvVar = "MEMBER_OPERATOR_WEBHOOK_CERTIFICATES"
+	// WebhookCertificatesServiceCA the certificate and the CA bundle are provided by the OpenShift service CA operator (default)
+	WebhookCertificatesServiceCA = "service-ca"
+	// WebhookCertificatesOperator the certificate and the CA bundle are generated and rotated by the operator itself
+	WebhookCertificatesOperator = "operator"
+)
+
+const (
+	// QuotaUsageHistorySizeEnvVar the name of the env var which defines the number of quota usage samples kept per namespace
+	QuotaUsageHistorySizeEnvVar = "MEMBER_OPERATOR_QUOTA_USAGE_HISTORY_SIZE"
+	// DefaultQuotaUsageHistorySize the default number of quota usage samples kept per namespace (ie, a day with hourly samples)
+	DefaultQuotaUsageHistorySize = 24
+	// AnnotationMaxSizeEnvVar the name of the env var which defines the maximum size (in bytes) of the quota usage history annotation,
+	// beyond which it is compacted. The limits of the inventory are specified in the MemberOperatorConfig
+	AnnotationMaxSizeEnvVar = "MEMBER_OPERATOR_ANNOTATION_MAX_SIZE"
+	// DefaultAnnotationMaxSize the default maximum size of the quota usage history annotation, well below the 256KiB limit
+	// of all the annotations of an object
+	DefaultAnnotationMaxSize = 32 * 1024
+	// AuditTrailSizeEnvVar the name of the env var which defines the number of audit trail entries kept per user in a ConfigMap
+	// of the operator namespace. The entries are only logged if the env var is not set
+	AuditTrailSizeEnvVar = "MEMBER_OPERATOR_AUDIT_TRAIL_SIZE"
+	// ApplyChunkSizeEnvVar the name of the env var which defines the number of template objects applied per chunk, after which
+	// a checkpoint is recorded on the NSTemplateSet
+	ApplyChunkSizeEnvVar = "MEMBER_OPERATOR_APPLY_CHUNK_SIZE"
+	// DefaultApplyChunkSize the default number of template objects applied per chunk
+	DefaultApplyChunkSize = 100
+)
+
+const (
+	// PolicyEngineURLEnvVar the name of the env var which defines the URL of the external policy engine consulted by the validating webhook.
+	// The policy engine is not consulted if the env var is not set
+	PolicyEngineURLEnvVar = "MEMBER_OPERATOR_POLICY_ENGINE_URL"
+	// PolicyEngineTimeoutEnvVar the name of the env var which defines the timeout of the calls to the policy engine (eg: `500ms`)
+	PolicyEngineTimeoutEnvVar = "MEMBER_OPERATOR_POLICY_ENGINE_TIMEOUT"
+	// DefaultPolicyEngineTimeout the default timeout of the calls to the policy engine
+	DefaultPolicyEngineTimeout = 2 * time.Second
+	// PolicyEngineFailurePolicyEnvVar the name of the env var which defines how the admission requests are handled when
+	// the policy engine cannot be consulted
+	PolicyEngineFailurePolicyEnvVar = "MEMBER_OPERATOR_POLICY_ENGINE_FAILURE_POLICY"
+	// PolicyEngineFailurePolicyFail the requests are denied when the policy engine cannot be consulted (default)
+	PolicyEngineFailurePolicyFail = "Fail"
+	// PolicyEngineFailurePolicyIgnore the requests are allowed when the policy engine cannot be consulted
+	PolicyEngineFailurePolicyIgnore = "Ignore"
+)
+
+// TemplateDecryptionKeysSecretEnvVar the name of the env var which defines the name of the Secret of the operator namespace which holds
+// the private keys (in PEM format) used to decrypt the encrypted Secrets and the SealedSecrets of the templates. They are not decrypted
+// if the env var is not set
+const TemplateDecryptionKeysSecretEnvVar = "MEMBER_OPERATOR_TEMPLATE_DECRYPTION_KEYS_SECRET"
+
+// TracingEndpointEnvVar the name of the env var which defines the OTLP/HTTP endpoint of the OpenTelemetry collector to which the traces
+// of the reconciliations are exported (eg: `http://otel-collector:4318`). The reconciliations are not traced if the env var is not set
+const TracingEndpointEnvVar = "MEMBER_OPERATOR_TRACING_ENDPOINT"
+
+const (
+	// MemberStatusRefreshPeriodEnvVar the name of the env var which defines the period of the refresh of the resource usage
+	// reported in the MemberStatus (eg: `30s`)
+	MemberStatusRefreshPeriodEnvVar = "MEMBER_OPERATOR_MEMBER_STATUS_REFRESH_PERIOD"
+	// DefaultMemberStatusRefreshPeriod the default period of the refresh of the resource usage reported in the MemberStatus
+	DefaultMemberStatusRefreshPeriod = time.Minute
+)
+
+const (
+	// RolloutTimeoutEnvVar the name of the env var which defines how long the operator waits for the rollout of the workloads it deploys
+	// (eg, the member web console or the autoscaling buffer) before reporting it as timed out in the conditions of the MemberOperatorConfig (eg: `5m`)
+	RolloutTimeoutEnvVar = "MEMBER_OPERATOR_ROLLOUT_TIMEOUT"
+	// DefaultRolloutTimeout the default timeout of the rollout of the workloads deployed by the operator
+	DefaultRolloutTimeout = 10 * time.Minute
+)
+
+const (
+	// OrphanedNamespacesGracePeriodEnvVar the name of the env var which defines how long a user namespace can remain without
+	// NSTemplateSet and UserAccount before it is deleted (eg: `30m`)
+	OrphanedNamespacesGracePeriodEnvVar = "MEMBER_OPERATOR_ORPHANED_NAMESPACES_GRACE_PERIOD"
+	// DefaultOrphanedNamespacesGracePeriod the default grace period before the deletion of an orphaned user namespace
+	DefaultOrphanedNamespacesGracePeriod = time.Hour
+)
+
+const (
+	// AutoscalingBufferImageEnvVar the name of the env var which defines the image of the containers of the autoscaling buffer
+	AutoscalingBufferImageEnvVar = "MEMBER_OPERATOR_AUTOSCALING_BUFFER_IMAGE"
+	// DefaultAutoscalingBufferImage the default image of the containers of the autoscaling buffer, which do nothing
+	DefaultAutoscalingBufferImage = "k8s.gcr.io/pause:3.1"
+)
+
+const (
+	// MemberConsoleImageEnvVar the name of the env var which defines the image of the member web console
+	MemberConsoleImageEnvVar = "MEMBER_OPERATOR_MEMBER_CONSOLE_IMAGE"
+	// MemberConsoleImageRepository the repository of the default image of the member web console, which is tagged with the commit
+	// of the operator release
+	MemberConsoleImageRepository = "quay.io/codeready-toolchain/member-console"
+)
+
+const (
+	// IdentityMappingStrategyEnvVar the name of the env var which defines how the Identities are linked to the Users
+	IdentityMappingStrategyEnvVar = "MEMBER_OPERATOR_IDENTITY_MAPPING_STRATEGY"
+	// IdentityMappingStrategyDirect the Identity and the User directly reference each other (default)
+	IdentityMappingStrategyDirect = "direct"
+	// IdentityMappingStrategyMapping the Identity is created without any reference to the User, and both are linked with a UserIdentityMapping
+	IdentityMappingStrategyMapping = "mapping"
+	// IdentityMappingStrategyLookup the Identity is created by the (external) identity provider, and linked to the User with a UserIdentityMapping
+	// once it exists
+	IdentityMappingStrategyLookup = "lookup"
+)
+
+const (
+	// NamespaceCreationModeEnvVar the name of the env var which defines how the user namespaces are created
+	NamespaceCreationModeEnvVar = "MEMBER_OPERATOR_NAMESPACE_CREATION_MODE"
+	// NamespaceCreationModeNamespace the user namespaces are created as plain Namespaces (default)
+	NamespaceCreationModeNamespace = "namespace"
+	// NamespaceCreationModeProject the user namespaces are created with OpenShift ProjectRequests, so that they get the annotations
+	// (eg, the UID ranges) and the objects of the project template of the cluster
+	NamespaceCreationModeProject = "project"
+)
+
+// ApplyWithProtobuf returns true if the objects of native kinds should be applied using the protobuf content type
+func ApplyWithProtobuf() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(ApplyWithProtobufEnvVar))
+	return enabled
+}
+
+// StaleResourcesCleanupEnabled returns true if the stale Secrets and ConfigMaps in the user namespaces should be deleted
+func StaleResourcesCleanupEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(StaleResourcesCleanupEnvVar))
+	return enabled
+}
+
+// ScaleDownDisabledUsers returns true if the workloads in the namespaces of the disabled users should be scaled down
+func ScaleDownDisabledUsers() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(ScaleDownDisabledUsersEnvVar))
+	return enabled
+}
+
+// StartupAuditRepairEnabled returns true if the repair plan produced by the startup audit should be executed
+func StartupAuditRepairEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(StartupAuditRepairEnvVar))
+	return enabled
+}
+
+// HostValidationWebhookEnabled returns true if the webhook which validates the hosts of the Routes and Ingresses should be served
+func HostValidationWebhookEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(HostValidationWebhookEnvVar))
+	return enabled
+}
+
+// PodMutationWebhookEnabled returns true if the webhook which sets the ephemeral storage requests and limits of the pods should be served
+func PodMutationWebhookEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(PodMutationWebhookEnvVar))
+	return enabled
+}
+
+// PodPriorityWebhookEnabled returns true if the webhook which sets the priority class of the pods should be served
+func PodPriorityWebhookEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(PodPriorityWebhookEnvVar))
+	return enabled
+}
+
+// PodSchedulingWebhookEnabled returns true if the webhook which sets the node selector and the tolerations of the pods should be served
+func PodSchedulingWebhookEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(PodSchedulingWebhookEnvVar))
+	return enabled
+}
+
+// PodProxyWebhookEnabled returns true if the webhook which sets the proxy settings and the trusted CA bundle of the pods should be served
+func PodProxyWebhookEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(PodProxyWebhookEnvVar))
+	return enabled
+}
+
+// ResourceValidationWebhookEnabled returns true if the webhook which denies the forbidden resources should be served
+func ResourceValidationWebhookEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(ResourceValidationWebhookEnvVar))
+	return enabled
+}
+
+// VirtualMachineWebhookEnabled returns true if the webhook which defaults and caps the resources of the VirtualMachines should be served
+func VirtualMachineWebhookEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(VirtualMachineWebhookEnvVar))
+	return enabled
+}
+
+// GetQuotaUsageHistorySize returns the number of quota usage samples kept per namespace. Defaults to `DefaultQuotaUsageHistorySize`
+// if the env var is not set or is not a positive number
+func GetQuotaUsageHistorySize() int {
+	return getPositiveInt(QuotaUsageHistorySizeEnvVar, DefaultQuotaUsageHistorySize)
+}
+
+// GetAuditTrailSize returns the number of audit trail entries kept per user in a ConfigMap. Returns `0` (ie, the entries are only logged)
+// if the env var is not set or is not a positive number
+func GetAuditTrailSize() int {
+	return getPositiveInt(AuditTrailSizeEnvVar, 0)
+}
+
+// GetApplyChunkSize returns the number of template objects applied per chunk. Defaults to `DefaultApplyChunkSize`
+// if the env var is not set or is not a positive number
+func GetApplyChunkSize() int {
+	return getPositiveInt(ApplyChunkSizeEnvVar, DefaultApplyChunkSize)
+}
+
+// GetAnnotationMaxSize returns the maximum size (in bytes) of the quota usage history annotation. Defaults to `DefaultAnnotationMaxSize`
+// if the env var is not set or is not a positive number
+func GetAnnotationMaxSize() int {
+	return getPositiveInt(AnnotationMaxSizeEnvVar, DefaultAnnotationMaxSize)
+}
+
+// GetPolicyEngineURL returns the URL of the external policy engine consulted by the validating webhook, or an empty string
+// if no policy engine is configured
+func GetPolicyEngineURL() string {
+	return os.Getenv(PolicyEngineURLEnvVar)
+}
+
+// GetTemplateDecryptionKeysSecret returns the name of the Secret which holds the private keys used to decrypt the secrets of the templates,
+// or an empty string if they are not decrypted
+func GetTemplateDecryptionKeysSecret() string {
+	return os.Getenv(TemplateDecryptionKeysSecretEnvVar)
+}
+
+// GetTracingEndpoint returns the OTLP/HTTP endpoint to which the traces are exported, or an empty string if the reconciliations are not traced
+func GetTracingEndpoint() string {
+	return os.Getenv(TracingEndpointEnvVar)
+}
+
+// GetPolicyEngineTimeout returns the timeout of the calls to the policy engine. Defaults to `DefaultPolicyEngineTimeout`
+// if the env var is not set or is not a positive duration
+func GetPolicyEngineTimeout() time.Duration {
+	timeout, err := time.ParseDuration(os.Getenv(PolicyEngineTimeoutEnvVar))
+	if err != nil || timeout <= 0 {
+		return DefaultPolicyEngineTimeout
+	}
+	return timeout
+}
+
+// PolicyEngineFailOpen returns true if the admission requests should be allowed when the policy engine cannot be consulted,
+// ie, if the failure policy is `Ignore`. Defaults to `false` (ie, `Fail`)
+func PolicyEngineFailOpen() bool {
+	return os.Getenv(PolicyEngineFailurePolicyEnvVar) == PolicyEngineFailurePolicyIgnore
+}
+
+// GetRolloutTimeout returns how long the operator waits for the rollout of the workloads it deploys before reporting it as timed out.
+// Defaults to `DefaultRolloutTimeout` if the env var is not set or is not a positive duration
+func GetRolloutTimeout() time.Duration {
+	timeout, err := time.ParseDuration(os.Getenv(RolloutTimeoutEnvVar))
+	if err != nil || timeout <= 0 {
+		return DefaultRolloutTimeout
+	}
+	return timeout
+}
+
+// GetMemberStatusRefreshPeriod returns the period of the refresh of the resource usage reported in the MemberStatus.
+// Defaults to `DefaultMemberStatusRefreshPeriod` if the env var is not set or is not a positive duration
+func GetMemberStatusRefreshPeriod() time.Duration {
+	period, err := time.ParseDuration(os.Getenv(MemberStatusRefreshPeriodEnvVar))
+	if err != nil || period <= 0 {
+		return DefaultMemberStatusRefreshPeriod
+	}
+	return period
+}
+
+// GetOrphanedNamespacesGracePeriod returns how long a user namespace can remain without NSTemplateSet and UserAccount before it is deleted.
+// Defaults to `DefaultOrphanedNamespacesGracePeriod` if the env var is not set or is not a positive duration
+func GetOrphanedNamespacesGracePeriod() time.Duration {
+	period, err := time.ParseDuration(os.Getenv(OrphanedNamespacesGracePeriodEnvVar))
+	if err != nil || period <= 0 {
+		return DefaultOrphanedNamespacesGracePeriod
+	}
+	return period
+}
+
+func getPositiveInt(envVar string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(envVar))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// GetIdentityMappingStrategy returns the strategy to link the Identities to the Users. Defaults to `direct` if the env var
+// is not set or has an unknown value
+func GetIdentityMappingStrategy() string {
+	switch strategy := os.Getenv(IdentityMappingStrategyEnvVar); strategy {
+	case IdentityMappingStrategyMapping, IdentityMappingStrategyLookup:
+		return strategy
+	default:
+		return IdentityMappingStrategyDirect
+	}
+}
+
+// GetAutoscalingBufferImage returns the image of the containers of the autoscaling buffer. Defaults to `DefaultAutoscalingBufferImage`
+// if the env var is not set
+func GetAutoscalingBufferImage() string {
+	if image := os.Getenv(AutoscalingBufferImageEnvVar); image != "" {
+		return image
+	}
+	return DefaultAutoscalingBufferImage
+}
+
+// GetMemberConsoleImage returns the image of the member web console. Defaults to the image of the same release as the operator,
+// ie, `MemberConsoleImageRepository` tagged with the commit of the operator, if the env var is not set
+func GetMemberConsoleImage() string {
+	if image := os.Getenv(MemberConsoleImageEnvVar); image != "" {
+		return image
+	}
+	return MemberConsoleImageRepository + ":" + version.Commit
+}
+
+// GetNamespaceCreationMode returns how the user namespaces are created. Defaults to `namespace` if the env var
+// is not set or has an unknown value
+func GetNamespaceCreationMode() string {
+	if os.Getenv(NamespaceCreationModeEnvVar) == NamespaceCreationModeProject {
+		return NamespaceCreationModeProject
+	}
+	return NamespaceCreationModeNamespace
+}
+
+// GetWebhookCertificates returns who provides the serving certificate of the webhooks. Defaults to `service-ca` if the env var
+// is not set or has an unknown value
+func GetWebhookCertificates() string {
+	if os.Getenv(WebhookCertificatesEnvVar) == WebhookCertificatesOperator {
+		return WebhookCertificatesOperator
+	}
+	return WebhookCertificatesServiceCA
 }
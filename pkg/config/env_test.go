@@ -0,0 +1,250 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/version"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAnnotationMaxSize(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(AnnotationMaxSizeEnvVar)
+		require.NoError(t, err)
+	}()
+
+	t.Run("default when not set", func(t *testing.T) {
+		assert.Equal(t, DefaultAnnotationMaxSize, GetAnnotationMaxSize())
+	})
+
+	t.Run("from env var", func(t *testing.T) {
+		// given
+		err := os.Setenv(AnnotationMaxSizeEnvVar, "1024")
+		require.NoError(t, err)
+
+		// then
+		assert.Equal(t, 1024, GetAnnotationMaxSize())
+	})
+
+	t.Run("default when invalid", func(t *testing.T) {
+		for _, value := range []string{"abc", "0", "-1"} {
+			// given
+			err := os.Setenv(AnnotationMaxSizeEnvVar, value)
+			require.NoError(t, err)
+
+			// then
+			assert.Equal(t, DefaultAnnotationMaxSize, GetAnnotationMaxSize(), "value: %s", value)
+		}
+	})
+}
+
+func TestGetQuotaUsageHistorySize(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(QuotaUsageHistorySizeEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Equal(t, DefaultQuotaUsageHistorySize, GetQuotaUsageHistorySize())
+
+	err := os.Setenv(QuotaUsageHistorySizeEnvVar, "48")
+	require.NoError(t, err)
+	assert.Equal(t, 48, GetQuotaUsageHistorySize())
+}
+
+func TestGetApplyChunkSize(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(ApplyChunkSizeEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Equal(t, DefaultApplyChunkSize, GetApplyChunkSize())
+
+	err := os.Setenv(ApplyChunkSizeEnvVar, "0")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultApplyChunkSize, GetApplyChunkSize())
+
+	err = os.Setenv(ApplyChunkSizeEnvVar, "20")
+	require.NoError(t, err)
+	assert.Equal(t, 20, GetApplyChunkSize())
+}
+
+func TestGetAuditTrailSize(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(AuditTrailSizeEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Equal(t, 0, GetAuditTrailSize())
+
+	err := os.Setenv(AuditTrailSizeEnvVar, "-1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, GetAuditTrailSize())
+
+	err = os.Setenv(AuditTrailSizeEnvVar, "50")
+	require.NoError(t, err)
+	assert.Equal(t, 50, GetAuditTrailSize())
+}
+
+func TestGetPolicyEngineTimeout(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(PolicyEngineTimeoutEnvVar)
+		require.NoError(t, err)
+	}()
+
+	t.Run("default when not set", func(t *testing.T) {
+		assert.Equal(t, DefaultPolicyEngineTimeout, GetPolicyEngineTimeout())
+	})
+
+	t.Run("from env var", func(t *testing.T) {
+		// given
+		err := os.Setenv(PolicyEngineTimeoutEnvVar, "500ms")
+		require.NoError(t, err)
+
+		// then
+		assert.Equal(t, 500*time.Millisecond, GetPolicyEngineTimeout())
+	})
+
+	t.Run("default when invalid", func(t *testing.T) {
+		for _, value := range []string{"abc", "0s", "-1s"} {
+			// given
+			err := os.Setenv(PolicyEngineTimeoutEnvVar, value)
+			require.NoError(t, err)
+
+			// then
+			assert.Equal(t, DefaultPolicyEngineTimeout, GetPolicyEngineTimeout(), "value: %s", value)
+		}
+	})
+}
+
+func TestPolicyEngineFailOpen(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(PolicyEngineFailurePolicyEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.False(t, PolicyEngineFailOpen())
+
+	err := os.Setenv(PolicyEngineFailurePolicyEnvVar, PolicyEngineFailurePolicyIgnore)
+	require.NoError(t, err)
+	assert.True(t, PolicyEngineFailOpen())
+
+	err = os.Setenv(PolicyEngineFailurePolicyEnvVar, PolicyEngineFailurePolicyFail)
+	require.NoError(t, err)
+	assert.False(t, PolicyEngineFailOpen())
+}
+
+func TestGetMemberStatusRefreshPeriod(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(MemberStatusRefreshPeriodEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Equal(t, DefaultMemberStatusRefreshPeriod, GetMemberStatusRefreshPeriod())
+
+	err := os.Setenv(MemberStatusRefreshPeriodEnvVar, "30s")
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, GetMemberStatusRefreshPeriod())
+
+	err = os.Setenv(MemberStatusRefreshPeriodEnvVar, "-1s")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMemberStatusRefreshPeriod, GetMemberStatusRefreshPeriod())
+}
+
+func TestGetRolloutTimeout(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(RolloutTimeoutEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Equal(t, DefaultRolloutTimeout, GetRolloutTimeout())
+
+	err := os.Setenv(RolloutTimeoutEnvVar, "5m")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, GetRolloutTimeout())
+
+	err = os.Setenv(RolloutTimeoutEnvVar, "0s")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultRolloutTimeout, GetRolloutTimeout())
+}
+
+func TestGetOrphanedNamespacesGracePeriod(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(OrphanedNamespacesGracePeriodEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Equal(t, DefaultOrphanedNamespacesGracePeriod, GetOrphanedNamespacesGracePeriod())
+
+	err := os.Setenv(OrphanedNamespacesGracePeriodEnvVar, "30m")
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, GetOrphanedNamespacesGracePeriod())
+
+	err = os.Setenv(OrphanedNamespacesGracePeriodEnvVar, "invalid")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultOrphanedNamespacesGracePeriod, GetOrphanedNamespacesGracePeriod())
+}
+
+func TestGetAutoscalingBufferImage(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(AutoscalingBufferImageEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Equal(t, DefaultAutoscalingBufferImage, GetAutoscalingBufferImage())
+
+	err := os.Setenv(AutoscalingBufferImageEnvVar, "registry.example.com/pause:3.1")
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/pause:3.1", GetAutoscalingBufferImage())
+}
+
+func TestGetMemberConsoleImage(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(MemberConsoleImageEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Equal(t, MemberConsoleImageRepository+":"+version.Commit, GetMemberConsoleImage())
+
+	err := os.Setenv(MemberConsoleImageEnvVar, "registry.example.com/member-console:v1")
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/member-console:v1", GetMemberConsoleImage())
+}
+
+func TestGetNamespaceCreationMode(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(NamespaceCreationModeEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Equal(t, NamespaceCreationModeNamespace, GetNamespaceCreationMode())
+
+	err := os.Setenv(NamespaceCreationModeEnvVar, "project")
+	require.NoError(t, err)
+	assert.Equal(t, NamespaceCreationModeProject, GetNamespaceCreationMode())
+
+	err = os.Setenv(NamespaceCreationModeEnvVar, "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, NamespaceCreationModeNamespace, GetNamespaceCreationMode())
+}
+
+func TestGetWebhookCertificates(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(WebhookCertificatesEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Equal(t, WebhookCertificatesServiceCA, GetWebhookCertificates())
+
+	err := os.Setenv(WebhookCertificatesEnvVar, "operator")
+	require.NoError(t, err)
+	assert.Equal(t, WebhookCertificatesOperator, GetWebhookCertificates())
+
+	err = os.Setenv(WebhookCertificatesEnvVar, "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, WebhookCertificatesServiceCA, GetWebhookCertificates())
+}
+
+func TestGetTemplateDecryptionKeysSecret(t *testing.T) {
+	defer func() {
+		err := os.Unsetenv(TemplateDecryptionKeysSecretEnvVar)
+		require.NoError(t, err)
+	}()
+	assert.Empty(t, GetTemplateDecryptionKeysSecret())
+
+	err := os.Setenv(TemplateDecryptionKeysSecretEnvVar, "sealed-secrets-key")
+	require.NoError(t, err)
+	assert.Equal(t, "sealed-secrets-key", GetTemplateDecryptionKeysSecret())
+}
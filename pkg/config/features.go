@@ -0,0 +1,40 @@
+package config
+
+// Feature the name of an experimental capability of the operator, which can be enabled or disabled per cluster
+// with the feature gates of the MemberOperatorConfig
+type Feature string
+
+const (
+	// VirtualMachineIdling the KubeVirt VirtualMachines (and VirtualMachineInstances) are stopped by the Idlers
+	VirtualMachineIdling Feature = "VirtualMachineIdling"
+	// CachedTemplateReads the existing template objects are looked up in the cache of the manager before being updated
+	CachedTemplateReads Feature = "CachedTemplateReads"
+	// UnidleOnRequest the workloads idled by the Idlers are scaled up again by OpenShift when their Services receive traffic
+	UnidleOnRequest Feature = "UnidleOnRequest"
+	// ActivityTracking the last activity of the users is recorded on their UserAccounts
+	ActivityTracking Feature = "ActivityTracking"
+	// MemberConsole the member web console is deployed by the operator in its namespace
+	MemberConsole Feature = "MemberConsole"
+	// DeltaApply only the template objects which changed since they were last applied are applied again when a tier is upgraded
+	DeltaApply Feature = "DeltaApply"
+)
+
+// defaultFeatureGates the known features, along with their state when they are not listed in the MemberOperatorConfig
+var defaultFeatureGates = map[Feature]bool{
+	VirtualMachineIdling: true,
+	CachedTemplateReads:  true,
+	UnidleOnRequest:      true,
+	ActivityTracking:     true,
+	MemberConsole:        false,
+	DeltaApply:           true,
+}
+
+// FeatureEnabled returns true if the given feature is enabled, as specified in the last loaded MemberOperatorConfig.
+// Defaults to the state of the feature when it is not listed. The unknown features are always disabled.
+func FeatureEnabled(feature Feature) bool {
+	if enabled, found := current().featureGates[string(feature)]; found {
+		_, known := defaultFeatureGates[feature]
+		return known && enabled
+	}
+	return defaultFeatureGates[feature]
+}
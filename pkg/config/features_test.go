@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestFeatureEnabled(t *testing.T) {
+	err := memberv1alpha1.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	defer set(nil)
+
+	t.Run("default state when no config", func(t *testing.T) {
+		// given
+		set(&memberv1alpha1.MemberOperatorConfigSpec{FeatureGates: map[string]bool{string(VirtualMachineIdling): false}})
+		cl := test.NewFakeClient(t)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, FeatureEnabled(VirtualMachineIdling))
+		assert.True(t, FeatureEnabled(CachedTemplateReads))
+		assert.False(t, FeatureEnabled(Feature("Unknown")))
+	})
+
+	t.Run("state from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.FeatureGates = map[string]bool{
+			string(VirtualMachineIdling): false,
+			"Unknown":                    true,
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, FeatureEnabled(VirtualMachineIdling))
+		// not listed
+		assert.True(t, FeatureEnabled(CachedTemplateReads))
+		// unknown features are never enabled
+		assert.False(t, FeatureEnabled(Feature("Unknown")))
+	})
+}
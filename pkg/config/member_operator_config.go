@@ -0,0 +1,470 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultIdP the name of the identity provider used when it is not specified in the MemberOperatorConfig
+const DefaultIdP = "rhd"
+
+// DefaultStuckNamespaceTimeout the duration after which a terminating user namespace is considered stuck when it is not specified
+// in the MemberOperatorConfig
+const DefaultStuckNamespaceTimeout = time.Hour
+
+// TrustedCABundleConfigMapName the name of the ConfigMap holding the trusted CA bundle in the user namespaces
+const TrustedCABundleConfigMapName = "trusted-ca-bundle"
+
+// TrustedCABundleKey the key of the trusted CA bundle in the ConfigMaps
+const TrustedCABundleKey = "ca-bundle.crt"
+
+// DefaultPublicViewerKind the kind of the public viewer subject when it is not specified in the MemberOperatorConfig
+const DefaultPublicViewerKind = "Group"
+
+// DefaultPublicViewerClusterRole the cluster role granted to the public viewer when it is not specified in the MemberOperatorConfig
+const DefaultPublicViewerClusterRole = "view"
+
+// DefaultTierRolloutCheckInterval the delay before checking again whether a pending upgrade of an NSTemplateSet can start, when it is
+// not specified in the MemberOperatorConfig
+const DefaultTierRolloutCheckInterval = 30 * time.Second
+
+// DefaultCITokenRotationPeriod the duration after which the token of a CI ServiceAccount is replaced when it is not specified
+// in the MemberOperatorConfig
+const DefaultCITokenRotationPeriod = 30 * 24 * time.Hour
+
+// DefaultTemplateMaxObjects the maximum number of objects of a tier template when it is not specified in the MemberOperatorConfig
+const DefaultTemplateMaxObjects = 500
+
+// DefaultTemplateMaxObjectSize the maximum size of each object of a tier template when it is not specified in the MemberOperatorConfig
+const DefaultTemplateMaxObjectSize = "1Mi"
+
+// DefaultInventoryMaxSize the maximum size of the inventory of an NSTemplateSet when it is not specified in the MemberOperatorConfig
+const DefaultInventoryMaxSize = "32Ki"
+
+// DefaultInventoryMaxEntries the maximum number of entries of the inventory of an NSTemplateSet when it is not specified in the MemberOperatorConfig
+const DefaultInventoryMaxEntries = 1000
+
+// snapshot the configuration loaded from the MemberOperatorConfig. A snapshot is never modified once it is loaded: the whole snapshot
+// is replaced when the MemberOperatorConfig changes, so that the readers never observe a partially loaded configuration
+type snapshot struct {
+	idp                string
+	userHostPattern    string
+	ephemeralStorage   memberv1alpha1.EphemeralStorageConfig
+	persistentStorage  memberv1alpha1.PersistentStorageConfig
+	tierIdleTimeouts   map[string]int32
+	webhooks           memberv1alpha1.WebhooksConfig
+	forbiddenResources memberv1alpha1.ForbiddenResourcesConfig
+	console            memberv1alpha1.ConsoleConfig
+	autoscaler         memberv1alpha1.AutoscalerConfig
+	podScheduling      memberv1alpha1.PodSchedulingConfig
+	podSecurity        memberv1alpha1.PodSecurityConfig
+	proxy              memberv1alpha1.ProxyConfig
+	virtualMachines    memberv1alpha1.VirtualMachinesConfig
+	nsTermination      memberv1alpha1.NamespaceTerminationConfig
+	publicViewer       memberv1alpha1.PublicViewerConfig
+	tierRollout        *memberv1alpha1.TierRolloutConfig
+	ciAccess           memberv1alpha1.CIAccessConfig
+	templateGuardrails memberv1alpha1.TemplateGuardrailsConfig
+	inventory          memberv1alpha1.InventoryConfig
+	imageMirrors       memberv1alpha1.ImageMirrorsConfig
+	logging            memberv1alpha1.LoggingConfig
+	featureGates       map[string]bool
+	controllers        map[string]memberv1alpha1.ControllerConfig
+}
+
+var (
+	lock   sync.RWMutex
+	loaded = newSnapshot(nil)
+)
+
+// current returns the last loaded snapshot
+func current() *snapshot {
+	lock.RLock()
+	defer lock.RUnlock()
+	return loaded
+}
+
+// GetIdP returns the name of the identity provider, as specified in the last loaded MemberOperatorConfig
+func GetIdP() string {
+	return current().idp
+}
+
+// GetUserHostPattern returns the pattern of the hosts which can be claimed by the Routes and Ingresses in the user namespaces,
+// as specified in the last loaded MemberOperatorConfig. Returns an empty string if the hosts are not restricted.
+func GetUserHostPattern() string {
+	return current().userHostPattern
+}
+
+// GetEphemeralStorage returns the limits of the ephemeral storage in the user namespaces, as specified in the last loaded MemberOperatorConfig.
+// The limits which are not specified are empty.
+func GetEphemeralStorage() memberv1alpha1.EphemeralStorageConfig {
+	return current().ephemeralStorage
+}
+
+// GetPersistentStorage returns the limits of the persistent storage in the user namespaces, as specified in the last loaded MemberOperatorConfig.
+// The limits which are not specified are empty.
+func GetPersistentStorage() memberv1alpha1.PersistentStorageConfig {
+	return current().persistentStorage
+}
+
+// GetTierIdleTimeout returns the idle timeout (in seconds) of the namespaces of the given tier, as specified in the last loaded MemberOperatorConfig.
+// Returns `false` if no timeout is specified for the tier.
+func GetTierIdleTimeout(tier string) (int32, bool) {
+	cfg := current()
+	timeout, found := cfg.tierIdleTimeouts[tier]
+	return timeout, found
+}
+
+// HostValidationEnforced returns true if the hosts of the Routes and Ingresses in the user namespaces should be validated,
+// as specified in the last loaded MemberOperatorConfig. Defaults to true.
+func HostValidationEnforced() bool {
+	cfg := current()
+	return cfg.webhooks.HostValidation == nil || *cfg.webhooks.HostValidation
+}
+
+// PodMutationEnforced returns true if the ephemeral storage requests and limits of the pods in the user namespaces should be set,
+// as specified in the last loaded MemberOperatorConfig. Defaults to true.
+func PodMutationEnforced() bool {
+	cfg := current()
+	return cfg.webhooks.PodMutation == nil || *cfg.webhooks.PodMutation
+}
+
+// PodPriorityEnforced returns true if the priority class of the pods in the user namespaces should be set,
+// as specified in the last loaded MemberOperatorConfig. Defaults to true.
+func PodPriorityEnforced() bool {
+	cfg := current()
+	return cfg.webhooks.PodPriority == nil || *cfg.webhooks.PodPriority
+}
+
+// PodSchedulingEnforced returns true if the node selector and the tolerations of the pods in the user namespaces should be set,
+// as specified in the last loaded MemberOperatorConfig. Defaults to true.
+func PodSchedulingEnforced() bool {
+	cfg := current()
+	return cfg.webhooks.PodScheduling == nil || *cfg.webhooks.PodScheduling
+}
+
+// PodProxyEnforced returns true if the proxy settings and the trusted CA bundle of the pods in the user namespaces should be set,
+// as specified in the last loaded MemberOperatorConfig. Defaults to true.
+func PodProxyEnforced() bool {
+	cfg := current()
+	return cfg.webhooks.PodProxy == nil || *cfg.webhooks.PodProxy
+}
+
+// ResourceValidationEnforced returns true if the forbidden resources should be denied in the user namespaces,
+// as specified in the last loaded MemberOperatorConfig. Defaults to true.
+func ResourceValidationEnforced() bool {
+	cfg := current()
+	return cfg.webhooks.ResourceValidation == nil || *cfg.webhooks.ResourceValidation
+}
+
+// VirtualMachineLimitsEnforced returns true if the resources of the VirtualMachines in the user namespaces should be defaulted and capped,
+// as specified in the last loaded MemberOperatorConfig. Defaults to true.
+func VirtualMachineLimitsEnforced() bool {
+	cfg := current()
+	return cfg.webhooks.VirtualMachines == nil || *cfg.webhooks.VirtualMachines
+}
+
+// GetForbiddenResources returns the resources which cannot be created by the users in their namespaces, as specified
+// in the last loaded MemberOperatorConfig. Nothing is forbidden if it is not specified.
+func GetForbiddenResources() memberv1alpha1.ForbiddenResourcesConfig {
+	return current().forbiddenResources
+}
+
+// GetConsole returns the URLs of the web consoles, as specified in the last loaded MemberOperatorConfig.
+// The URLs which are not specified are empty.
+func GetConsole() memberv1alpha1.ConsoleConfig {
+	return current().console
+}
+
+// GetAutoscaler returns the size of the autoscaling buffer, as specified in the last loaded MemberOperatorConfig.
+// The buffer is disabled if its number of replicas is `0`.
+func GetAutoscaler() memberv1alpha1.AutoscalerConfig {
+	return current().autoscaler
+}
+
+// GetPodScheduling returns the node selector and the tolerations of the pods in the user namespaces, as specified in the last loaded
+// MemberOperatorConfig. The pods are not pinned to any node if it is not specified.
+func GetPodScheduling() memberv1alpha1.PodSchedulingConfig {
+	return *current().podScheduling.DeepCopy()
+}
+
+// GetPodSecurity returns the security constraints of the pods in the namespaces of the given tier, as specified in the last loaded
+// MemberOperatorConfig. Defaults to the default constraints if the tier is not listed. Returns false if there are no constraints
+// for the tier.
+func GetPodSecurity(tier string) (memberv1alpha1.PodSecurityProfile, bool) {
+	cfg := current()
+	if profile, found := cfg.podSecurity.Tiers[tier]; found {
+		return profile, true
+	}
+	if cfg.podSecurity.Default != nil {
+		return *cfg.podSecurity.Default, true
+	}
+	return memberv1alpha1.PodSecurityProfile{}, false
+}
+
+// GetProxy returns the proxy settings and the trusted CA bundle propagated to the user workloads, as specified in the last loaded
+// MemberOperatorConfig. Nothing is propagated if it is not specified.
+func GetProxy() memberv1alpha1.ProxyConfig {
+	return current().proxy
+}
+
+// GetVirtualMachineLimits returns the limits of the resources of the VirtualMachines in the namespaces of the given tier, as specified
+// in the last loaded MemberOperatorConfig. Defaults to the default limits if the tier is not listed. Returns false if there are no limits
+// for the tier.
+func GetVirtualMachineLimits(tier string) (memberv1alpha1.VirtualMachineLimits, bool) {
+	cfg := current()
+	if limits, found := cfg.virtualMachines.Tiers[tier]; found {
+		return limits, true
+	}
+	if cfg.virtualMachines.Default != nil {
+		return *cfg.virtualMachines.Default, true
+	}
+	return memberv1alpha1.VirtualMachineLimits{}, false
+}
+
+// GetStuckNamespaceTimeout returns the duration after which a terminating user namespace is considered stuck, as specified in the last
+// loaded MemberOperatorConfig. Defaults to `DefaultStuckNamespaceTimeout` if it is not specified or is not a positive duration.
+func GetStuckNamespaceTimeout() time.Duration {
+	cfg := current()
+	timeout, err := time.ParseDuration(cfg.nsTermination.StuckTimeout)
+	if err != nil || timeout <= 0 {
+		return DefaultStuckNamespaceTimeout
+	}
+	return timeout
+}
+
+// GetSafeFinalizers returns the finalizers which can be safely removed from the resources left in the stuck user namespaces,
+// as specified in the last loaded MemberOperatorConfig. No finalizer is removed if it is not specified.
+func GetSafeFinalizers() []memberv1alpha1.SafeFinalizer {
+	return append([]memberv1alpha1.SafeFinalizer{}, current().nsTermination.SafeFinalizers...)
+}
+
+// GetPublicViewer returns the subject which is granted a read-only access to the shared user namespaces, with its defaults,
+// as specified in the last loaded MemberOperatorConfig. Returns `false` if no public viewer is specified.
+func GetPublicViewer() (memberv1alpha1.PublicViewerConfig, bool) {
+	cfg := current()
+	if cfg.publicViewer.Name == "" {
+		return memberv1alpha1.PublicViewerConfig{}, false
+	}
+	viewer := cfg.publicViewer
+	if viewer.Kind == "" {
+		viewer.Kind = DefaultPublicViewerKind
+	}
+	if viewer.ClusterRole == "" {
+		viewer.ClusterRole = DefaultPublicViewerClusterRole
+	}
+	return viewer, true
+}
+
+// GetTierRollout returns the progressive upgrade of the NSTemplateSets after a change of the revisions of their tier, with its defaults,
+// as specified in the last loaded MemberOperatorConfig. Returns `false` if it is not specified, ie, if all the NSTemplateSets are upgraded right away.
+func GetTierRollout() (memberv1alpha1.TierRolloutConfig, bool) {
+	cfg := current()
+	if cfg.tierRollout == nil {
+		return memberv1alpha1.TierRolloutConfig{}, false
+	}
+	rollout := *cfg.tierRollout
+	if rollout.Canary <= 0 {
+		rollout.Canary = 1
+	}
+	if rollout.MaxUnavailable <= 0 {
+		rollout.MaxUnavailable = 1
+	}
+	if rollout.FailureThreshold <= 0 {
+		rollout.FailureThreshold = 1
+	}
+	return rollout, true
+}
+
+// GetTierRolloutCheckInterval returns the delay before checking again whether a pending upgrade of an NSTemplateSet can start, as specified
+// in the last loaded MemberOperatorConfig. Defaults to `DefaultTierRolloutCheckInterval` if it is not specified or is not a positive duration.
+func GetTierRolloutCheckInterval() time.Duration {
+	cfg := current()
+	if cfg.tierRollout == nil {
+		return DefaultTierRolloutCheckInterval
+	}
+	interval, err := time.ParseDuration(cfg.tierRollout.CheckInterval)
+	if err != nil || interval <= 0 {
+		return DefaultTierRolloutCheckInterval
+	}
+	return interval
+}
+
+// GetCITokenRotationPeriod returns the duration after which the token of a CI ServiceAccount is replaced, as specified in the last
+// loaded MemberOperatorConfig. Defaults to `DefaultCITokenRotationPeriod` if it is not specified or is not a positive duration.
+func GetCITokenRotationPeriod() time.Duration {
+	cfg := current()
+	period, err := time.ParseDuration(cfg.ciAccess.TokenRotationPeriod)
+	if err != nil || period <= 0 {
+		return DefaultCITokenRotationPeriod
+	}
+	return period
+}
+
+// GetTemplateGuardrails returns the limits enforced on the processed tier templates, with their defaults, as specified in the last
+// loaded MemberOperatorConfig
+func GetTemplateGuardrails() memberv1alpha1.TemplateGuardrailsConfig {
+	cfg := current()
+	guardrails := *cfg.templateGuardrails.DeepCopy()
+	if guardrails.MaxObjects <= 0 {
+		guardrails.MaxObjects = DefaultTemplateMaxObjects
+	}
+	if guardrails.MaxObjectSize == "" {
+		guardrails.MaxObjectSize = DefaultTemplateMaxObjectSize
+	}
+	return guardrails
+}
+
+// GetInventoryLimits returns the limits of the inventory of the NSTemplateSets, with their defaults, as specified in the last
+// loaded MemberOperatorConfig
+func GetInventoryLimits() memberv1alpha1.InventoryConfig {
+	limits := current().inventory
+	if limits.MaxSize == "" {
+		limits.MaxSize = DefaultInventoryMaxSize
+	}
+	if limits.MaxEntries <= 0 {
+		limits.MaxEntries = DefaultInventoryMaxEntries
+	}
+	return limits
+}
+
+// GetImageMirrors returns the mirrors of the container images by source (ie, registry or repository), as specified in the last
+// loaded MemberOperatorConfig. The mirrors with an empty source or an empty mirror are ignored
+func GetImageMirrors() map[string]string {
+	cfg := current()
+	mirrors := make(map[string]string, len(cfg.imageMirrors.Mirrors))
+	for _, m := range cfg.imageMirrors.Mirrors {
+		source, mirror := strings.TrimSuffix(m.Source, "/"), strings.TrimSuffix(m.Mirror, "/")
+		if source == "" || mirror == "" {
+			continue
+		}
+		mirrors[source] = mirror
+	}
+	return mirrors
+}
+
+// GetLogging returns the levels of the logs, as specified in the last loaded MemberOperatorConfig
+func GetLogging() memberv1alpha1.LoggingConfig {
+	return *current().logging.DeepCopy()
+}
+
+// GetControllerConfig returns the concurrency and the rate limit of the controller with the given name, as specified
+// in the last loaded MemberOperatorConfig. The values which are not specified are empty.
+func GetControllerConfig(name string) memberv1alpha1.ControllerConfig {
+	controllerCfg := current().controllers[name]
+	return *controllerCfg.DeepCopy()
+}
+
+// LoadMemberOperatorConfig loads the MemberOperatorConfig resource of the given namespace. The default values are used
+// if the resource does not exist. The previous configuration is kept if the resource cannot be loaded.
+func LoadMemberOperatorConfig(cl client.Client, namespace string) error {
+	cfg := &memberv1alpha1.MemberOperatorConfig{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: memberv1alpha1.MemberOperatorConfigName}, cfg); err != nil {
+		if !errors.IsNotFound(err) {
+			return errs.Wrap(err, "failed to load the MemberOperatorConfig")
+		}
+		set(nil)
+		return nil
+	}
+	set(&cfg.Spec)
+	return nil
+}
+
+// set replaces the loaded snapshot with the snapshot of the given spec, or with the default values if the spec is nil
+func set(spec *memberv1alpha1.MemberOperatorConfigSpec) {
+	s := newSnapshot(spec)
+	lock.Lock()
+	defer lock.Unlock()
+	loaded = s
+}
+
+// newSnapshot returns the snapshot of the given spec, or of the default values if the spec is nil. The spec is copied,
+// so that the snapshot does not share anything with the MemberOperatorConfig resource
+func newSnapshot(spec *memberv1alpha1.MemberOperatorConfigSpec) *snapshot {
+	s := &snapshot{
+		idp:              DefaultIdP,
+		tierIdleTimeouts: map[string]int32{},
+		featureGates:     map[string]bool{},
+		controllers:      map[string]memberv1alpha1.ControllerConfig{},
+	}
+	if spec == nil {
+		return s
+	}
+	spec = spec.DeepCopy()
+	if spec.IdentityProvider != "" {
+		s.idp = spec.IdentityProvider
+	}
+	s.userHostPattern = spec.UserHostPattern
+	if spec.EphemeralStorage != nil {
+		s.ephemeralStorage = *spec.EphemeralStorage
+	}
+	if spec.PersistentStorage != nil {
+		s.persistentStorage = *spec.PersistentStorage
+	}
+	if spec.Idler != nil {
+		for tier, timeout := range spec.Idler.TierTimeoutsSeconds {
+			s.tierIdleTimeouts[tier] = timeout
+		}
+	}
+	if spec.Webhooks != nil {
+		s.webhooks = *spec.Webhooks
+	}
+	if spec.ForbiddenResources != nil {
+		s.forbiddenResources = *spec.ForbiddenResources
+	}
+	if spec.Console != nil {
+		s.console = *spec.Console
+	}
+	if spec.Autoscaler != nil {
+		s.autoscaler = *spec.Autoscaler
+	}
+	if spec.PodScheduling != nil {
+		s.podScheduling = *spec.PodScheduling
+	}
+	if spec.PodSecurity != nil {
+		s.podSecurity = *spec.PodSecurity
+	}
+	if spec.Proxy != nil {
+		s.proxy = *spec.Proxy
+	}
+	if spec.VirtualMachines != nil {
+		s.virtualMachines = *spec.VirtualMachines
+	}
+	if spec.NamespaceTermination != nil {
+		s.nsTermination = *spec.NamespaceTermination
+	}
+	if spec.PublicViewer != nil {
+		s.publicViewer = *spec.PublicViewer
+	}
+	s.tierRollout = spec.TierRollout
+	if spec.CIAccess != nil {
+		s.ciAccess = *spec.CIAccess
+	}
+	if spec.TemplateGuardrails != nil {
+		s.templateGuardrails = *spec.TemplateGuardrails
+	}
+	if spec.Inventory != nil {
+		s.inventory = *spec.Inventory
+	}
+	if spec.ImageMirrors != nil {
+		s.imageMirrors = *spec.ImageMirrors
+	}
+	if spec.Logging != nil {
+		s.logging = *spec.Logging
+	}
+	for name, enabled := range spec.FeatureGates {
+		s.featureGates[name] = enabled
+	}
+	for name, controllerCfg := range spec.Controllers {
+		s.controllers[name] = controllerCfg
+	}
+	return s
+}
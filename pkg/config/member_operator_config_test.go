@@ -0,0 +1,719 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const namespaceName = "toolchain-member"
+
+func TestLoadMemberOperatorConfig(t *testing.T) {
+	err := memberv1alpha1.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	defer set(nil)
+
+	t.Run("default identity provider when no config", func(t *testing.T) {
+		// given
+		set(&memberv1alpha1.MemberOperatorConfigSpec{IdentityProvider: "other"})
+		cl := test.NewFakeClient(t)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, DefaultIdP, GetIdP())
+		assert.Empty(t, GetUserHostPattern())
+	})
+
+	t.Run("default identity provider when not specified", func(t *testing.T) {
+		// given
+		set(&memberv1alpha1.MemberOperatorConfigSpec{IdentityProvider: "other"})
+		cl := test.NewFakeClient(t, newMemberOperatorConfig(""))
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, DefaultIdP, GetIdP())
+	})
+
+	t.Run("identity provider from config", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newMemberOperatorConfig("sso"))
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "sso", GetIdP())
+	})
+
+	t.Run("user host pattern from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.UserHostPattern = "*.{username}.apps.example.com"
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, DefaultIdP, GetIdP())
+		assert.Equal(t, "*.{username}.apps.example.com", GetUserHostPattern())
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Empty(t, GetUserHostPattern())
+		})
+	})
+
+	t.Run("ephemeral storage from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.EphemeralStorage = &memberv1alpha1.EphemeralStorageConfig{
+			Quota:           "10Gi",
+			DefaultLimit:    "1Gi",
+			MaxEmptyDirSize: "512Mi",
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, memberv1alpha1.EphemeralStorageConfig{
+			Quota:           "10Gi",
+			DefaultLimit:    "1Gi",
+			MaxEmptyDirSize: "512Mi",
+		}, GetEphemeralStorage())
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, memberv1alpha1.EphemeralStorageConfig{}, GetEphemeralStorage())
+		})
+	})
+
+	t.Run("persistent storage from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.PersistentStorage = &memberv1alpha1.PersistentStorageConfig{
+			Quota:                 "5Gi",
+			MaxClaims:             3,
+			AllowedStorageClasses: []string{"gp2"},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, memberv1alpha1.PersistentStorageConfig{
+			Quota:                 "5Gi",
+			MaxClaims:             3,
+			AllowedStorageClasses: []string{"gp2"},
+		}, GetPersistentStorage())
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, memberv1alpha1.PersistentStorageConfig{}, GetPersistentStorage())
+		})
+	})
+
+	t.Run("tier idle timeouts from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.Idler = &memberv1alpha1.IdlerConfig{
+			TierTimeoutsSeconds: map[string]int32{"basic": 28800, "paid": 0},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		timeout, found := GetTierIdleTimeout("basic")
+		assert.True(t, found)
+		assert.Equal(t, int32(28800), timeout)
+		timeout, found = GetTierIdleTimeout("paid")
+		assert.True(t, found)
+		assert.Equal(t, int32(0), timeout)
+		_, found = GetTierIdleTimeout("advanced")
+		assert.False(t, found)
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			_, found := GetTierIdleTimeout("basic")
+			assert.False(t, found)
+		})
+	})
+
+	t.Run("forbidden resources from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.ForbiddenResources = &memberv1alpha1.ForbiddenResourcesConfig{
+			ClusterRoles:  []string{"cluster-admin"},
+			ServiceTypes:  []string{"NodePort"},
+			PodPrivileges: []string{"privileged", "hostNetwork"},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, ResourceValidationEnforced())
+		assert.Equal(t, memberv1alpha1.ForbiddenResourcesConfig{
+			ClusterRoles:  []string{"cluster-admin"},
+			ServiceTypes:  []string{"NodePort"},
+			PodPrivileges: []string{"privileged", "hostNetwork"},
+		}, GetForbiddenResources())
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, memberv1alpha1.ForbiddenResourcesConfig{}, GetForbiddenResources())
+		})
+	})
+
+	t.Run("webhooks, console and autoscaler from config", func(t *testing.T) {
+		// given
+		disabled := false
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.Webhooks = &memberv1alpha1.WebhooksConfig{PodMutation: &disabled}
+		cfg.Spec.Console = &memberv1alpha1.ConsoleConfig{
+			URL:             "https://console-openshift-console.apps.example.com",
+			CheDashboardURL: "https://che.apps.example.com",
+		}
+		cfg.Spec.Autoscaler = &memberv1alpha1.AutoscalerConfig{
+			BufferMemory:   "2Gi",
+			BufferReplicas: 3,
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, HostValidationEnforced())
+		assert.False(t, PodMutationEnforced())
+		assert.True(t, PodPriorityEnforced())
+		assert.Equal(t, "https://console-openshift-console.apps.example.com", GetConsole().URL)
+		assert.Equal(t, "https://che.apps.example.com", GetConsole().CheDashboardURL)
+		assert.Equal(t, memberv1alpha1.AutoscalerConfig{BufferMemory: "2Gi", BufferReplicas: 3}, GetAutoscaler())
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, HostValidationEnforced())
+			assert.True(t, PodMutationEnforced())
+			assert.True(t, PodPriorityEnforced())
+			assert.Equal(t, memberv1alpha1.ConsoleConfig{}, GetConsole())
+			assert.Equal(t, memberv1alpha1.AutoscalerConfig{}, GetAutoscaler())
+		})
+	})
+
+	t.Run("virtual machine limits from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.VirtualMachines = &memberv1alpha1.VirtualMachinesConfig{
+			Default: &memberv1alpha1.VirtualMachineLimits{MaxMemory: "2Gi"},
+			Tiers: map[string]memberv1alpha1.VirtualMachineLimits{
+				"advanced": {MaxMemory: "8Gi", MaxCPUCores: 4, Action: memberv1alpha1.VirtualMachineLimitCap},
+			},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, VirtualMachineLimitsEnforced())
+		limits, found := GetVirtualMachineLimits("advanced")
+		assert.True(t, found)
+		assert.Equal(t, cfg.Spec.VirtualMachines.Tiers["advanced"], limits)
+		limits, found = GetVirtualMachineLimits("basic")
+		assert.True(t, found)
+		assert.Equal(t, *cfg.Spec.VirtualMachines.Default, limits)
+
+		t.Run("no limits when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			_, found := GetVirtualMachineLimits("advanced")
+			assert.False(t, found)
+		})
+	})
+
+	t.Run("pod scheduling from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.PodScheduling = &memberv1alpha1.PodSchedulingConfig{
+			NodeSelector: map[string]string{"node-role.kubernetes.io/sandbox": ""},
+			Tolerations: []corev1.Toleration{
+				{Key: "sandbox", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+			},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, PodSchedulingEnforced())
+		assert.Equal(t, *cfg.Spec.PodScheduling, GetPodScheduling())
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, memberv1alpha1.PodSchedulingConfig{}, GetPodScheduling())
+		})
+	})
+
+	t.Run("pod security from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.PodSecurity = &memberv1alpha1.PodSecurityConfig{
+			Default: &memberv1alpha1.PodSecurityProfile{Level: "restricted"},
+			Tiers: map[string]memberv1alpha1.PodSecurityProfile{
+				"advanced": {SCC: "nonroot-v2", Level: "baseline"},
+			},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		profile, found := GetPodSecurity("advanced")
+		assert.True(t, found)
+		assert.Equal(t, memberv1alpha1.PodSecurityProfile{SCC: "nonroot-v2", Level: "baseline"}, profile)
+		profile, found = GetPodSecurity("basic")
+		assert.True(t, found)
+		assert.Equal(t, memberv1alpha1.PodSecurityProfile{Level: "restricted"}, profile)
+
+		t.Run("no constraints when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			_, found := GetPodSecurity("advanced")
+			assert.False(t, found)
+		})
+	})
+
+	t.Run("controllers from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.Controllers = map[string]memberv1alpha1.ControllerConfig{
+			"useraccount": {
+				MaxConcurrentReconciles: 5,
+				RateLimiter:             &memberv1alpha1.RateLimiterConfig{QPS: 20, Burst: 50},
+			},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, memberv1alpha1.ControllerConfig{
+			MaxConcurrentReconciles: 5,
+			RateLimiter:             &memberv1alpha1.RateLimiterConfig{QPS: 20, Burst: 50},
+		}, GetControllerConfig("useraccount"))
+		assert.Equal(t, memberv1alpha1.ControllerConfig{}, GetControllerConfig("nstemplateset"))
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, memberv1alpha1.ControllerConfig{}, GetControllerConfig("useraccount"))
+		})
+	})
+
+	t.Run("namespace termination from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.NamespaceTermination = &memberv1alpha1.NamespaceTerminationConfig{
+			StuckTimeout: "30m",
+			SafeFinalizers: []memberv1alpha1.SafeFinalizer{
+				{APIVersion: "example.com/v1", Kind: "Widget", Finalizer: "widgets.example.com/cleanup"},
+			},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Minute, GetStuckNamespaceTimeout())
+		assert.Equal(t, cfg.Spec.NamespaceTermination.SafeFinalizers, GetSafeFinalizers())
+
+		t.Run("invalid timeout", func(t *testing.T) {
+			// given
+			cfg.Spec.NamespaceTermination.StuckTimeout = "soon"
+			err := cl.Update(context.TODO(), cfg)
+			require.NoError(t, err)
+
+			// when
+			err = LoadMemberOperatorConfig(cl, namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, DefaultStuckNamespaceTimeout, GetStuckNamespaceTimeout())
+		})
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, DefaultStuckNamespaceTimeout, GetStuckNamespaceTimeout())
+			assert.Empty(t, GetSafeFinalizers())
+		})
+	})
+
+	t.Run("proxy from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.Proxy = &memberv1alpha1.ProxyConfig{
+			HTTPSProxy:      "http://proxy.example.com:3128",
+			NoProxy:         ".cluster.local,.svc",
+			TrustedCABundle: "proxy-ca",
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, PodProxyEnforced())
+		assert.Equal(t, *cfg.Spec.Proxy, GetProxy())
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, memberv1alpha1.ProxyConfig{}, GetProxy())
+		})
+	})
+
+	t.Run("public viewer from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.PublicViewer = &memberv1alpha1.PublicViewerConfig{Name: "system:authenticated"}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		viewer, found := GetPublicViewer()
+		require.True(t, found)
+		assert.Equal(t, memberv1alpha1.PublicViewerConfig{Kind: "Group", Name: "system:authenticated", ClusterRole: "view"}, viewer)
+
+		t.Run("service account", func(t *testing.T) {
+			// given
+			cfg.Spec.PublicViewer = &memberv1alpha1.PublicViewerConfig{Kind: "ServiceAccount", Name: "viewer", Namespace: "portal", ClusterRole: "toolchain-viewer"}
+			err := cl.Update(context.TODO(), cfg)
+			require.NoError(t, err)
+
+			// when
+			err = LoadMemberOperatorConfig(cl, namespaceName)
+
+			// then
+			require.NoError(t, err)
+			viewer, found := GetPublicViewer()
+			require.True(t, found)
+			assert.Equal(t, *cfg.Spec.PublicViewer, viewer)
+		})
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			_, found := GetPublicViewer()
+			assert.False(t, found)
+		})
+	})
+
+	t.Run("tier rollout from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.TierRollout = &memberv1alpha1.TierRolloutConfig{Canary: 5, CheckInterval: "1m"}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		rollout, found := GetTierRollout()
+		require.True(t, found)
+		assert.Equal(t, memberv1alpha1.TierRolloutConfig{Canary: 5, MaxUnavailable: 1, FailureThreshold: 1, CheckInterval: "1m"}, rollout)
+		assert.Equal(t, time.Minute, GetTierRolloutCheckInterval())
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			_, found := GetTierRollout()
+			assert.False(t, found)
+			assert.Equal(t, DefaultTierRolloutCheckInterval, GetTierRolloutCheckInterval())
+		})
+	})
+
+	t.Run("ci token rotation period from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.CIAccess = &memberv1alpha1.CIAccessConfig{TokenRotationPeriod: "168h"}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 168*time.Hour, GetCITokenRotationPeriod())
+
+		t.Run("default when invalid", func(t *testing.T) {
+			// given
+			cfg := newMemberOperatorConfig("")
+			cfg.Spec.CIAccess = &memberv1alpha1.CIAccessConfig{TokenRotationPeriod: "-1h"}
+			cl := test.NewFakeClient(t, cfg)
+
+			// when
+			err := LoadMemberOperatorConfig(cl, namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, DefaultCITokenRotationPeriod, GetCITokenRotationPeriod())
+		})
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, DefaultCITokenRotationPeriod, GetCITokenRotationPeriod())
+		})
+	})
+
+	t.Run("template guardrails from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.TemplateGuardrails = &memberv1alpha1.TemplateGuardrailsConfig{
+			MaxObjects:   100,
+			AllowedKinds: []string{"ConfigMap", "RoleBinding.rbac.authorization.k8s.io"},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, memberv1alpha1.TemplateGuardrailsConfig{
+			MaxObjects:    100,
+			MaxObjectSize: DefaultTemplateMaxObjectSize,
+			AllowedKinds:  []string{"ConfigMap", "RoleBinding.rbac.authorization.k8s.io"},
+		}, GetTemplateGuardrails())
+
+		t.Run("defaults when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, memberv1alpha1.TemplateGuardrailsConfig{
+				MaxObjects:    DefaultTemplateMaxObjects,
+				MaxObjectSize: DefaultTemplateMaxObjectSize,
+			}, GetTemplateGuardrails())
+		})
+	})
+
+	t.Run("inventory limits from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.Inventory = &memberv1alpha1.InventoryConfig{
+			MaxSize: "64Ki",
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, memberv1alpha1.InventoryConfig{
+			MaxSize:    "64Ki",
+			MaxEntries: DefaultInventoryMaxEntries,
+		}, GetInventoryLimits())
+
+		t.Run("defaults when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, memberv1alpha1.InventoryConfig{
+				MaxSize:    DefaultInventoryMaxSize,
+				MaxEntries: DefaultInventoryMaxEntries,
+			}, GetInventoryLimits())
+		})
+	})
+
+	t.Run("image mirrors from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.ImageMirrors = &memberv1alpha1.ImageMirrorsConfig{
+			Mirrors: []memberv1alpha1.ImageMirror{
+				{Source: "quay.io", Mirror: "registry.example.com:5000/quay"},
+				{Source: "quay.io/codeready-toolchain/", Mirror: "registry.example.com:5000/toolchain/"},
+				{Source: "docker.io", Mirror: ""},
+			},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"quay.io":                     "registry.example.com:5000/quay",
+			"quay.io/codeready-toolchain": "registry.example.com:5000/toolchain",
+		}, GetImageMirrors())
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Empty(t, GetImageMirrors())
+		})
+	})
+
+	t.Run("logging from config", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig("")
+		cfg.Spec.Logging = &memberv1alpha1.LoggingConfig{
+			Level:   "error",
+			Loggers: map[string]string{"controller_nstemplateset": "debug"},
+		}
+		cl := test.NewFakeClient(t, cfg)
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, memberv1alpha1.LoggingConfig{
+			Level:   "error",
+			Loggers: map[string]string{"controller_nstemplateset": "debug"},
+		}, GetLogging())
+
+		t.Run("reset when config removed", func(t *testing.T) {
+			// when
+			err := LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, memberv1alpha1.LoggingConfig{}, GetLogging())
+		})
+	})
+
+	t.Run("load failed", func(t *testing.T) {
+		// given
+		set(&memberv1alpha1.MemberOperatorConfigSpec{IdentityProvider: "sso"})
+		cl := test.NewFakeClient(t)
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			return errors.New("mock error")
+		}
+
+		// when
+		err := LoadMemberOperatorConfig(cl, namespaceName)
+
+		// then
+		require.EqualError(t, err, "failed to load the MemberOperatorConfig: mock error")
+		assert.Equal(t, "sso", GetIdP())
+	})
+}
+
+func newMemberOperatorConfig(idp string) *memberv1alpha1.MemberOperatorConfig {
+	return &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      memberv1alpha1.MemberOperatorConfigName,
+		},
+		Spec: memberv1alpha1.MemberOperatorConfigSpec{
+			IdentityProvider: idp,
+		},
+	}
+}
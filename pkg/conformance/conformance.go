@@ -0,0 +1,351 @@
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/useraccount"
+	"github.com/codeready-toolchain/member-operator/pkg/nstemplatetier"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	userv1 "github.com/openshift/api/user/v1"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	skippedMessage = "skipped because of a previous failure"
+
+	// idleTimeoutSeconds the idle timeout of the namespace of the synthetic user, short enough for the idling to be verified during the run
+	idleTimeoutSeconds = 5
+	// idledPodName the name of the pod which is expected to be idled
+	idledPodName = "conformance-idled"
+	// idledPodImage the image of the pod which is expected to be idled
+	idledPodImage = "registry.access.redhat.com/ubi8/ubi-minimal"
+)
+
+// Check a single step of the conformance suite
+type Check struct {
+	Name string
+	// Cleanup is true if the check must be executed even if a previous check failed
+	Cleanup bool
+	Run     func() error
+}
+
+// Run runs the given checks in their order and returns `true` if all of them passed, along with their individual results.
+// Once a check failed, all subsequent checks are skipped, except those which perform some cleanup.
+func Run(checks []Check) (bool, []memberv1alpha1.ConformanceCheck) {
+	passed := true
+	results := make([]memberv1alpha1.ConformanceCheck, 0, len(checks))
+	for _, c := range checks {
+		if !passed && !c.Cleanup {
+			results = append(results, memberv1alpha1.ConformanceCheck{
+				Name:    c.Name,
+				Passed:  false,
+				Message: skippedMessage,
+			})
+			continue
+		}
+		result := memberv1alpha1.ConformanceCheck{
+			Name:   c.Name,
+			Passed: true,
+		}
+		if err := c.Run(); err != nil {
+			passed = false
+			result.Passed = false
+			result.Message = err.Error()
+		}
+		results = append(results, result)
+	}
+	return passed, results
+}
+
+// Suite the conformance suite which provisions a synthetic user on the member cluster, verifies that all its resources
+// were provisioned and eventually deletes it
+type Suite struct {
+	cl        client.Client
+	scheme    *runtime.Scheme
+	namespace string
+	username  string
+	tier      string
+	templates nstemplatetier.NSTemplates
+	interval  time.Duration
+	timeout   time.Duration
+	// idler the name of the Idler created by the suite, if any
+	idler string
+}
+
+// NewSuite returns a new conformance Suite for the given tier, whose UserAccount will be created in the given namespace
+func NewSuite(cl client.Client, scheme *runtime.Scheme, namespace, tier string, templates nstemplatetier.NSTemplates) *Suite {
+	return &Suite{
+		cl:        cl,
+		scheme:    scheme,
+		namespace: namespace,
+		username:  fmt.Sprintf("conformance-%d", time.Now().Unix()),
+		tier:      tier,
+		templates: templates,
+		interval:  2 * time.Second,
+		timeout:   2 * time.Minute,
+	}
+}
+
+// Checks returns the checks of this suite, in their order of execution
+func (s *Suite) Checks() []Check {
+	return []Check{
+		{Name: "UserAccountCreated", Run: s.createUserAccount},
+		{Name: "UserAndIdentityProvisioned", Run: s.verifyUserAndIdentity},
+		{Name: "NamespacesProvisioned", Run: s.verifyNamespaces},
+		{Name: "NamespaceResourcesProvisioned", Run: s.verifyNamespaceResources},
+		{Name: "UserAccountReady", Run: s.verifyUserAccountReady},
+		{Name: "WorkloadsIdled", Run: s.verifyIdling},
+		{Name: "IdlerDeleted", Run: s.deleteIdler, Cleanup: true},
+		{Name: "UserAccountDeleted", Run: s.deleteUserAccount, Cleanup: true},
+	}
+}
+
+func (s *Suite) createUserAccount() error {
+	userAcc := &toolchainv1alpha1.UserAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.username,
+			Namespace: s.namespace,
+		},
+		Spec: toolchainv1alpha1.UserAccountSpec{
+			UserID:  s.username,
+			NSLimit: "default",
+			NSTemplateSet: toolchainv1alpha1.NSTemplateSetSpec{
+				TierName: s.tier,
+			},
+		},
+	}
+	for typeName, tmpl := range s.templates {
+		userAcc.Spec.NSTemplateSet.Namespaces = append(userAcc.Spec.NSTemplateSet.Namespaces, toolchainv1alpha1.NSTemplateSetNamespace{
+			Type:     typeName,
+			Revision: tmpl.Revision,
+		})
+	}
+	return s.cl.Create(context.TODO(), userAcc)
+}
+
+func (s *Suite) verifyUserAndIdentity() error {
+	return s.waitUntil(func() (bool, error) {
+		if err := s.cl.Get(context.TODO(), types.NamespacedName{Name: s.username}, &userv1.User{}); err != nil {
+			return false, ignoreNotFound(err)
+		}
+		identityName := useraccount.ToIdentityName(s.username)
+		if err := s.cl.Get(context.TODO(), types.NamespacedName{Name: identityName}, &userv1.Identity{}); err != nil {
+			return false, ignoreNotFound(err)
+		}
+		return true, nil
+	}, "user and identity were not provisioned")
+}
+
+func (s *Suite) verifyNamespaces() error {
+	return s.waitUntil(func() (bool, error) {
+		namespaces := &corev1.NamespaceList{}
+		if err := s.cl.List(context.TODO(), namespaces, client.MatchingLabels(map[string]string{"owner": s.username})); err != nil {
+			return false, err
+		}
+		for typeName, tmpl := range s.templates {
+			if typeName == template.ClusterResourcesType {
+				continue
+			}
+			if !containsNamespace(namespaces.Items, typeName, tmpl.Revision) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}, "namespaces were not provisioned")
+}
+
+func (s *Suite) verifyNamespaceResources() error {
+	processor := template.NewProcessor(s.cl, s.scheme)
+	for typeName, tmpl := range s.templates {
+		tmpl := tmpl.Template
+		objs, err := processor.Process(&tmpl, map[string]string{"USERNAME": s.username}, template.RetainAllButNamespaces)
+		if err != nil {
+			return errs.Wrapf(err, "unable to process the template of namespace type '%s'", typeName)
+		}
+		for _, rawObj := range objs {
+			if err := s.verifyObjectExists(rawObj.Object); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Suite) verifyObjectExists(obj runtime.Object) error {
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	return s.waitUntil(func() (bool, error) {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(gvk)
+		if err := s.cl.Get(context.TODO(), types.NamespacedName{Namespace: acc.GetNamespace(), Name: acc.GetName()}, existing); err != nil {
+			return false, ignoreNotFound(err)
+		}
+		return true, nil
+	}, fmt.Sprintf("resource of kind '%s' and name '%s' in namespace '%s' was not provisioned", gvk.Kind, acc.GetName(), acc.GetNamespace()))
+}
+
+func (s *Suite) verifyUserAccountReady() error {
+	return s.waitUntil(func() (bool, error) {
+		userAcc := &toolchainv1alpha1.UserAccount{}
+		if err := s.cl.Get(context.TODO(), types.NamespacedName{Namespace: s.namespace, Name: s.username}, userAcc); err != nil {
+			return false, err
+		}
+		readyCond, found := condition.FindConditionByType(userAcc.Status.Conditions, toolchainv1alpha1.ConditionReady)
+		return found && readyCond.Status == corev1.ConditionTrue, nil
+	}, "user account is not ready")
+}
+
+// verifyIdling sets a short idle timeout on the first namespace of the user and verifies that a pod running in this namespace
+// is deleted once the timeout elapsed
+func (s *Suite) verifyIdling() error {
+	namespaces := &corev1.NamespaceList{}
+	if err := s.cl.List(context.TODO(), namespaces, client.MatchingLabels(map[string]string{"owner": s.username})); err != nil {
+		return errs.Wrap(err, "unable to list the namespaces of the user")
+	}
+	if len(namespaces.Items) == 0 {
+		return fmt.Errorf("user has no namespace")
+	}
+	sort.Slice(namespaces.Items, func(i, j int) bool {
+		return namespaces.Items[i].Name < namespaces.Items[j].Name
+	})
+	namespace := namespaces.Items[0].Name
+	if err := s.setIdleTimeout(namespace); err != nil {
+		return err
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      idledPodName,
+			Namespace: namespace,
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:    "sleep",
+				Image:   idledPodImage,
+				Command: []string{"sleep", "3600"},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m"), corev1.ResourceMemory: resource.MustParse("16Mi")},
+					Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m"), corev1.ResourceMemory: resource.MustParse("16Mi")},
+				},
+			}},
+		},
+	}
+	if err := s.cl.Create(context.TODO(), pod); err != nil {
+		return errs.Wrapf(err, "unable to create the pod to idle in namespace '%s'", namespace)
+	}
+	return s.waitUntil(func() (bool, error) {
+		existing := &corev1.Pod{}
+		if err := s.cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: idledPodName}, existing); err != nil {
+			return apierrors.IsNotFound(err), ignoreNotFound(err)
+		}
+		return existing.DeletionTimestamp != nil, nil
+	}, fmt.Sprintf("pod '%s' in namespace '%s' was not idled", idledPodName, namespace))
+}
+
+// setIdleTimeout creates the Idler of the given namespace with the short timeout of the suite, or sets this timeout on the
+// Idler if it already exists (eg, if it is provided by the templates of the tier)
+func (s *Suite) setIdleTimeout(namespace string) error {
+	idler := &memberv1alpha1.Idler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace,
+		},
+		Spec: memberv1alpha1.IdlerSpec{
+			TimeoutSeconds: idleTimeoutSeconds,
+		},
+	}
+	err := s.cl.Create(context.TODO(), idler)
+	if apierrors.IsAlreadyExists(err) {
+		if err = s.cl.Get(context.TODO(), types.NamespacedName{Name: namespace}, idler); err == nil {
+			idler.Spec.TimeoutSeconds = idleTimeoutSeconds
+			err = s.cl.Update(context.TODO(), idler)
+		}
+	}
+	if err != nil {
+		return errs.Wrapf(err, "unable to set the idle timeout of namespace '%s'", namespace)
+	}
+	s.idler = namespace
+	return nil
+}
+
+// deleteIdler deletes the Idler created by the suite, if any
+func (s *Suite) deleteIdler() error {
+	if s.idler == "" {
+		return nil
+	}
+	idler := &memberv1alpha1.Idler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: s.idler,
+		},
+	}
+	if err := s.cl.Delete(context.TODO(), idler); err != nil && !apierrors.IsNotFound(err) {
+		return errs.Wrapf(err, "unable to delete the idler '%s'", s.idler)
+	}
+	return nil
+}
+
+func (s *Suite) deleteUserAccount() error {
+	userAcc := &toolchainv1alpha1.UserAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.username,
+			Namespace: s.namespace,
+		},
+	}
+	if err := s.cl.Delete(context.TODO(), userAcc); err != nil {
+		return ignoreNotFound(err)
+	}
+	return s.waitUntil(func() (bool, error) {
+		if err := s.cl.Get(context.TODO(), types.NamespacedName{Namespace: s.namespace, Name: s.username}, &toolchainv1alpha1.UserAccount{}); err == nil || !apierrors.IsNotFound(err) {
+			return false, ignoreNotFound(err)
+		}
+		if err := s.cl.Get(context.TODO(), types.NamespacedName{Name: s.username}, &userv1.User{}); err == nil || !apierrors.IsNotFound(err) {
+			return false, ignoreNotFound(err)
+		}
+		return true, nil
+	}, "user account was not deleted")
+}
+
+func (s *Suite) waitUntil(cond wait.ConditionFunc, msg string) error {
+	if err := wait.PollImmediate(s.interval, s.timeout, cond); err != nil {
+		if err == wait.ErrWaitTimeout {
+			return fmt.Errorf("%s after %s", msg, s.timeout)
+		}
+		return errs.Wrap(err, msg)
+	}
+	return nil
+}
+
+func containsNamespace(namespaces []corev1.Namespace, typeName, revision string) bool {
+	for _, ns := range namespaces {
+		if ns.Labels["type"] == typeName && ns.Labels["revision"] == revision {
+			return true
+		}
+	}
+	return false
+}
+
+func ignoreNotFound(err error) error {
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package conformance_test
+
+import (
+	"errors"
+	"testing"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/conformance"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+
+	ok := func() error { return nil }
+	ko := func() error { return errors.New("mock error") }
+
+	t.Run("all checks passed", func(t *testing.T) {
+		// when
+		passed, results := conformance.Run([]conformance.Check{
+			{Name: "first", Run: ok},
+			{Name: "second", Run: ok},
+			{Name: "cleanup", Run: ok, Cleanup: true},
+		})
+
+		// then
+		assert.True(t, passed)
+		assert.Equal(t, []memberv1alpha1.ConformanceCheck{
+			{Name: "first", Passed: true},
+			{Name: "second", Passed: true},
+			{Name: "cleanup", Passed: true},
+		}, results)
+	})
+
+	t.Run("subsequent checks skipped after failure except cleanup", func(t *testing.T) {
+		// given
+		executed := false
+
+		// when
+		passed, results := conformance.Run([]conformance.Check{
+			{Name: "first", Run: ko},
+			{Name: "second", Run: func() error {
+				executed = true
+				return nil
+			}},
+			{Name: "cleanup", Run: ok, Cleanup: true},
+		})
+
+		// then
+		assert.False(t, passed)
+		assert.False(t, executed)
+		assert.Equal(t, []memberv1alpha1.ConformanceCheck{
+			{Name: "first", Passed: false, Message: "mock error"},
+			{Name: "second", Passed: false, Message: "skipped because of a previous failure"},
+			{Name: "cleanup", Passed: true},
+		}, results)
+	})
+
+	t.Run("cleanup failed", func(t *testing.T) {
+		// when
+		passed, results := conformance.Run([]conformance.Check{
+			{Name: "first", Run: ok},
+			{Name: "cleanup", Run: ko, Cleanup: true},
+		})
+
+		// then
+		assert.False(t, passed)
+		assert.Equal(t, []memberv1alpha1.ConformanceCheck{
+			{Name: "first", Passed: true},
+			{Name: "cleanup", Passed: false, Message: "mock error"},
+		}, results)
+	})
+}
@@ -0,0 +1,149 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestVerifyIdling(t *testing.T) {
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	t.Run("workloads idled", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNamespace("dev"), newNamespace("code"))
+		var created *corev1.Pod
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				// the pod is idled as soon as it is created
+				created = pod
+				return nil
+			}
+			return cl.Client.Create(ctx, obj, opts...)
+		}
+		s := newSuite(cl)
+
+		// when
+		err := s.verifyIdling()
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, created)
+		assert.Equal(t, "conformance-1-code", created.Namespace)
+		idler := &memberv1alpha1.Idler{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: "conformance-1-code"}, idler)
+		require.NoError(t, err)
+		assert.Equal(t, int32(idleTimeoutSeconds), idler.Spec.TimeoutSeconds)
+
+		t.Run("idler deleted", func(t *testing.T) {
+			// when
+			err := s.deleteIdler()
+
+			// then
+			require.NoError(t, err)
+			err = cl.Get(context.TODO(), types.NamespacedName{Name: "conformance-1-code"}, &memberv1alpha1.Idler{})
+			assert.True(t, apierrors.IsNotFound(err))
+		})
+	})
+
+	t.Run("timeout of existing idler changed", func(t *testing.T) {
+		// given
+		idler := &memberv1alpha1.Idler{
+			ObjectMeta: metav1.ObjectMeta{Name: "conformance-1-dev"},
+			Spec:       memberv1alpha1.IdlerSpec{TimeoutSeconds: 43200},
+		}
+		cl := test.NewFakeClient(t, newNamespace("dev"), idler)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*corev1.Pod); ok {
+				return nil
+			}
+			return cl.Client.Create(ctx, obj, opts...)
+		}
+		s := newSuite(cl)
+
+		// when
+		err := s.verifyIdling()
+
+		// then
+		require.NoError(t, err)
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: "conformance-1-dev"}, idler)
+		require.NoError(t, err)
+		assert.Equal(t, int32(idleTimeoutSeconds), idler.Spec.TimeoutSeconds)
+	})
+
+	t.Run("workloads not idled", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNamespace("dev"))
+		s := newSuite(cl)
+
+		// when
+		err := s.verifyIdling()
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, "pod 'conformance-idled' in namespace 'conformance-1-dev' was not idled after 100ms", err.Error())
+	})
+
+	t.Run("no namespace", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		s := newSuite(cl)
+
+		// when
+		err := s.verifyIdling()
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, "user has no namespace", err.Error())
+		assert.NoError(t, s.deleteIdler())
+	})
+
+	t.Run("failed to create idler", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNamespace("dev"))
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("mock error")
+		}
+		s := newSuite(cl)
+
+		// when
+		err := s.verifyIdling()
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, "unable to set the idle timeout of namespace 'conformance-1-dev': mock error", err.Error())
+	})
+}
+
+func newSuite(cl client.Client) *Suite {
+	s := NewSuite(cl, scheme.Scheme, "toolchain-member", "basic", nil)
+	s.username = "conformance-1"
+	s.interval = 10 * time.Millisecond
+	s.timeout = 100 * time.Millisecond
+	return s
+}
+
+func newNamespace(typeName string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "conformance-1-" + typeName,
+			Labels: map[string]string{"owner": "conformance-1", "type": typeName},
+		},
+	}
+}
@@ -0,0 +1,329 @@
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+	"github.com/codeready-toolchain/member-operator/pkg/predicate"
+	"github.com/codeready-toolchain/member-operator/pkg/rollout"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+
+	"github.com/go-logr/logr"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	sdkpredicate "github.com/operator-framework/operator-sdk/pkg/predicate"
+	errs "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_autoscaler")
+
+const (
+	// BufferPriorityClassName the name of the PriorityClass of the pods of the autoscaling buffer
+	BufferPriorityClassName = "member-operator-autoscaling-buffer"
+	// BufferPriority the priority of the pods of the autoscaling buffer: lower than the priority of the user pods, so that they are preempted
+	// to make room for the user workloads, but higher than the cutoff of the cluster autoscaler (`-10`), so that the preempted pods
+	// trigger a scale up of the cluster
+	BufferPriority = int32(-5)
+
+	// bufferNamePrefix the prefix of the names of the Deployments of the autoscaling buffer, followed by the zone
+	bufferNamePrefix = "autoscaling-buffer"
+	// BufferLabel the label set on the Deployments (and pods) of the autoscaling buffer, with the zone as its value
+	BufferLabel = "toolchain.dev.openshift.com/autoscaling-buffer"
+	// workerRoleLabel the label of the compute nodes
+	workerRoleLabel = "node-role.kubernetes.io/worker"
+)
+
+// zoneLabels the labels which define the zone of the nodes, by order of precedence
+var zoneLabels = []string{"topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone"}
+
+// Add creates a new Autoscaler Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	return add(mgr, newReconciler(mgr, namespace), namespace)
+}
+
+func newReconciler(mgr manager.Manager, namespace string) reconcile.Reconciler {
+	return &ReconcileAutoscaler{
+		client:    config.ControllerClient("autoscaler", mgr.GetClient()),
+		scheme:    mgr.GetScheme(),
+		namespace: namespace,
+	}
+}
+
+func add(mgr manager.Manager, r reconcile.Reconciler, namespace string) error {
+	c, err := controller.New("autoscaler-controller", mgr, config.ControllerOptions("autoscaler", r))
+	if err != nil {
+		return err
+	}
+	// Watch for changes to the MemberOperatorConfig, which defines the size of the buffer
+	enqueueConfig := &handler.EnqueueRequestsFromMapFunc{ToRequests: toConfig(namespace)}
+	if err := c.Watch(&source.Kind{Type: &memberv1alpha1.MemberOperatorConfig{}}, enqueueConfig, sdkpredicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+	// Watch for the nodes which are added, removed or relabelled, since the buffer is deployed in each zone
+	if err := c.Watch(&source.Kind{Type: &corev1.Node{}}, enqueueConfig, predicate.LabelsChangedOrCreatedDeleted{}); err != nil {
+		return err
+	}
+	// Watch for changes to the Deployments of the buffer, so that they are restored if they are changed or deleted
+	enqueueConfigOfBuffer := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(func(obj handler.MapObject) []reconcile.Request {
+		if _, found := obj.Meta.GetLabels()[BufferLabel]; !found {
+			return nil
+		}
+		return toConfig(namespace)(obj)
+	})}
+	return c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, enqueueConfigOfBuffer, sdkpredicate.GenerationChangedPredicate{})
+}
+
+// toConfig returns a mapper which maps all the objects to the MemberOperatorConfig of the given namespace
+func toConfig(namespace string) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		return []reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: namespace, Name: memberv1alpha1.MemberOperatorConfigName}},
+		}
+	}
+}
+
+var _ reconcile.Reconciler = &ReconcileAutoscaler{}
+
+// ReconcileAutoscaler maintains the autoscaling buffer: a Deployment of low-priority pods which do nothing in each zone of the compute nodes,
+// which are preempted by the user pods when the nodes are full. The preempted buffer pods then remain pending, which triggers a scale up
+// of the cluster by the cluster autoscaler, so that it always keeps some headroom for the new user workloads.
+type ReconcileAutoscaler struct {
+	client    client.Client
+	scheme    *runtime.Scheme
+	namespace string
+}
+
+// Reconcile creates, updates or deletes the Deployments of the autoscaling buffer, according to the MemberOperatorConfig
+// and to the zones of the compute nodes. The rollout of the buffer is reported in the `AutoscalingBufferReady` condition of the
+// MemberOperatorConfig, and checked again until it is complete
+func (r *ReconcileAutoscaler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := logging.ForRequest(log, request)
+
+	cfg := config.GetAutoscaler()
+	desired := map[string]*appsv1.Deployment{}
+	if cfg.BufferReplicas > 0 && cfg.BufferMemory != "" {
+		memory, err := resource.ParseQuantity(cfg.BufferMemory)
+		if err != nil {
+			return reconcile.Result{}, errs.Wrapf(err, "invalid buffer memory '%s'", cfg.BufferMemory)
+		}
+		zones, err := r.zones()
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		for zone, zoneLabel := range zones {
+			buffer := newBuffer(r.namespace, zone, zoneLabel, cfg.BufferReplicas, memory)
+			desired[buffer.Name] = buffer
+		}
+		if len(desired) > 0 {
+			if err := r.ensurePriorityClass(); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+	}
+
+	progresses := make(map[string]rollout.Progress, len(desired))
+	for name, buffer := range desired {
+		deployment, err := r.ensureBuffer(reqLogger, buffer)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		progresses[name] = rollout.DeploymentProgress(deployment)
+	}
+	if err := r.deleteObsoleteBuffers(reqLogger, desired); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// no condition when there is no buffer
+	var progress *rollout.Progress
+	if len(desired) > 0 {
+		combined := rollout.Combine(progresses)
+		progress = &combined
+	}
+	requeueAfter, err := rollout.UpdateConfigStatus(r.client, r.namespace, memberv1alpha1.AutoscalingBufferReadyCondition, progress, config.GetRolloutTimeout())
+	return reconcile.Result{RequeueAfter: requeueAfter}, err
+}
+
+// zones returns the zones of the schedulable compute nodes, along with the label which defines the zone of their nodes.
+// The nodes which are not in a zone are in the (single) empty zone.
+func (r *ReconcileAutoscaler) zones() (map[string]string, error) {
+	nodes := &corev1.NodeList{}
+	if err := r.client.List(context.TODO(), nodes); err != nil {
+		return nil, errs.Wrap(err, "failed to list the nodes")
+	}
+	zones := map[string]string{}
+	for _, node := range nodes.Items {
+		if _, worker := node.Labels[workerRoleLabel]; !worker || node.Spec.Unschedulable {
+			continue
+		}
+		zone, zoneLabel := zoneOf(node)
+		zones[zone] = zoneLabel
+	}
+	return zones, nil
+}
+
+// zoneOf returns the zone of the given node and the label which defines it, or empty strings if the node is not in a zone
+func zoneOf(node corev1.Node) (string, string) {
+	for _, label := range zoneLabels {
+		if zone := node.Labels[label]; zone != "" {
+			return zone, label
+		}
+	}
+	return "", ""
+}
+
+// ensurePriorityClass creates the PriorityClass of the pods of the buffer if it does not exist yet
+func (r *ReconcileAutoscaler) ensurePriorityClass() error {
+	priorityClass := &schedulingv1.PriorityClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   BufferPriorityClassName,
+			Labels: map[string]string{"provider": "codeready-toolchain"},
+		},
+		Value:       BufferPriority,
+		Description: "Priority class of the pods of the autoscaling buffer, which are preempted by the user pods",
+	}
+	if err := r.client.Create(context.TODO(), priorityClass); err != nil && !errors.IsAlreadyExists(err) {
+		return errs.Wrapf(err, "failed to create the '%s' PriorityClass", BufferPriorityClassName)
+	}
+	return nil
+}
+
+// ensureBuffer creates the given Deployment of the buffer, or updates its replicas and template if they changed.
+// Returns the Deployment of the buffer as it is in the cluster
+func (r *ReconcileAutoscaler) ensureBuffer(logger logr.Logger, buffer *appsv1.Deployment) (*appsv1.Deployment, error) {
+	existing := &appsv1.Deployment{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: buffer.Namespace, Name: buffer.Name}, existing); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, errs.Wrapf(err, "failed to get the buffer '%s'", buffer.Name)
+		}
+		logger.Info("creating the buffer", "name", buffer.Name, "replicas", *buffer.Spec.Replicas)
+		if err := r.client.Create(context.TODO(), buffer); err != nil {
+			return nil, errs.Wrapf(err, "failed to create the buffer '%s'", buffer.Name)
+		}
+		return buffer, nil
+	}
+	if !bufferChanged(existing, buffer) {
+		return existing, nil
+	}
+	logger.Info("updating the buffer", "name", buffer.Name, "replicas", *buffer.Spec.Replicas)
+	existing.Spec.Replicas = buffer.Spec.Replicas
+	existing.Spec.Template = buffer.Spec.Template
+	if err := r.client.Update(context.TODO(), existing); err != nil {
+		return nil, errs.Wrapf(err, "failed to update the buffer '%s'", buffer.Name)
+	}
+	return existing, nil
+}
+
+// bufferChanged returns true if the replicas or the pod template of the existing Deployment of the buffer differ from the desired ones
+func bufferChanged(existing, desired *appsv1.Deployment) bool {
+	if !reflect.DeepEqual(existing.Spec.Replicas, desired.Spec.Replicas) || len(existing.Spec.Template.Spec.Containers) != 1 {
+		return true
+	}
+	existingPod, desiredPod := existing.Spec.Template.Spec, desired.Spec.Template.Spec
+	return existingPod.PriorityClassName != desiredPod.PriorityClassName ||
+		!reflect.DeepEqual(existingPod.NodeSelector, desiredPod.NodeSelector) ||
+		existingPod.Containers[0].Image != desiredPod.Containers[0].Image ||
+		!equalResources(existingPod.Containers[0].Resources.Requests, desiredPod.Containers[0].Resources.Requests) ||
+		!equalResources(existingPod.Containers[0].Resources.Limits, desiredPod.Containers[0].Resources.Limits)
+}
+
+// equalResources returns true if both lists contain the same quantities of the same resources
+func equalResources(list1, list2 corev1.ResourceList) bool {
+	if len(list1) != len(list2) {
+		return false
+	}
+	for name, quantity1 := range list1 {
+		quantity2, found := list2[name]
+		if !found || quantity1.Cmp(quantity2) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// deleteObsoleteBuffers deletes the Deployments of the buffer which are not desired anymore, ie, those of the zones which have no compute
+// nodes anymore, or all of them when the buffer is disabled
+func (r *ReconcileAutoscaler) deleteObsoleteBuffers(logger logr.Logger, desired map[string]*appsv1.Deployment) error {
+	deployments := &appsv1.DeploymentList{}
+	if err := r.client.List(context.TODO(), deployments, client.InNamespace(r.namespace)); err != nil {
+		return errs.Wrap(err, "failed to list the buffers")
+	}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		if _, found := deployment.Labels[BufferLabel]; !found {
+			continue
+		}
+		if _, found := desired[deployment.Name]; found {
+			continue
+		}
+		logger.Info("deleting the buffer", "name", deployment.Name)
+		if err := r.client.Delete(context.TODO(), deployment); err != nil && !errors.IsNotFound(err) {
+			return errs.Wrapf(err, "failed to delete the buffer '%s'", deployment.Name)
+		}
+	}
+	return nil
+}
+
+// newBuffer returns the Deployment of the buffer of the given zone
+func newBuffer(namespace, zone, zoneLabel string, replicas int32, memory resource.Quantity) *appsv1.Deployment {
+	name := bufferNamePrefix
+	nodeSelector := map[string]string{workerRoleLabel: ""}
+	if zone != "" {
+		name = fmt.Sprintf("%s-%s", bufferNamePrefix, strings.ToLower(zone))
+		nodeSelector[zoneLabel] = zone
+	}
+	labels := map[string]string{BufferLabel: zone}
+	gracePeriod := int64(0)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					PriorityClassName:             BufferPriorityClassName,
+					TerminationGracePeriodSeconds: &gracePeriod,
+					NodeSelector:                  nodeSelector,
+					Containers: []corev1.Container{
+						{
+							Name:  "buffer",
+							Image: template.ImageMirrors(config.GetImageMirrors()).Rewrite(config.GetAutoscalingBufferImage()),
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{corev1.ResourceMemory: memory},
+								Limits:   corev1.ResourceList{corev1.ResourceMemory: memory},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,346 @@
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/rollout"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	operatorNamespace = "toolchain-member-operator"
+	zoneLabel         = "failure-domain.beta.kubernetes.io/zone"
+)
+
+func TestReconcile(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+
+	t.Run("buffer created in each zone", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newConfig("2Gi", 3),
+			newWorker("worker-1", "us-east-1a"), newWorker("worker-2", "us-east-1b"), newWorker("worker-3", "us-east-1b"),
+			newMaster("master-1", "us-east-1c"))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: rollout.CheckPeriod}, res)
+		assertBuffer(t, cl, "autoscaling-buffer-us-east-1a", "us-east-1a", "2Gi", 3)
+		assertBuffer(t, cl, "autoscaling-buffer-us-east-1b", "us-east-1b", "2Gi", 3)
+		assertNoBuffer(t, cl, "autoscaling-buffer-us-east-1c")
+		priorityClass := &schedulingv1.PriorityClass{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: BufferPriorityClassName}, priorityClass)
+		require.NoError(t, err)
+		assert.Equal(t, BufferPriority, priorityClass.Value)
+		assertBufferCondition(t, cl, corev1.ConditionFalse, rollout.InProgressReason,
+			"autoscaling-buffer-us-east-1a: 0 out of 3 replicas are updated; autoscaling-buffer-us-east-1b: 0 out of 3 replicas are updated")
+
+		t.Run("buffer resized", func(t *testing.T) {
+			// given
+			cfg := &memberv1alpha1.MemberOperatorConfig{}
+			err := cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName}, cfg)
+			require.NoError(t, err)
+			cfg.Spec.Autoscaler.BufferMemory = "1Gi"
+			cfg.Spec.Autoscaler.BufferReplicas = 1
+			err = cl.Update(context.TODO(), cfg)
+			require.NoError(t, err)
+			require.NoError(t, config.LoadMemberOperatorConfig(cl, operatorNamespace))
+
+			// when
+			_, err = r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assertBuffer(t, cl, "autoscaling-buffer-us-east-1a", "us-east-1a", "1Gi", 1)
+			assertBuffer(t, cl, "autoscaling-buffer-us-east-1b", "us-east-1b", "1Gi", 1)
+
+			t.Run("buffer of removed zone deleted", func(t *testing.T) {
+				// given
+				err := cl.Delete(context.TODO(), newWorker("worker-1", "us-east-1a"))
+				require.NoError(t, err)
+
+				// when
+				_, err = r.Reconcile(req)
+
+				// then
+				require.NoError(t, err)
+				assertNoBuffer(t, cl, "autoscaling-buffer-us-east-1a")
+				assertBuffer(t, cl, "autoscaling-buffer-us-east-1b", "us-east-1b", "1Gi", 1)
+
+				t.Run("all buffers deleted when disabled", func(t *testing.T) {
+					// given
+					err := cl.Delete(context.TODO(), cfg)
+					require.NoError(t, err)
+					require.NoError(t, config.LoadMemberOperatorConfig(cl, operatorNamespace))
+
+					// when
+					_, err = r.Reconcile(req)
+
+					// then
+					require.NoError(t, err)
+					assertNoBuffer(t, cl, "autoscaling-buffer-us-east-1b")
+				})
+			})
+		})
+	})
+
+	t.Run("single buffer when no zone", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newConfig("2Gi", 1), newWorker("worker-1", ""))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		buffer := assertBuffer(t, cl, "autoscaling-buffer", "", "2Gi", 1)
+		assert.Equal(t, map[string]string{workerRoleLabel: ""}, buffer.Spec.Template.Spec.NodeSelector)
+	})
+
+	t.Run("unschedulable nodes ignored", func(t *testing.T) {
+		// given
+		worker := newWorker("worker-1", "us-east-1a")
+		worker.Spec.Unschedulable = true
+		r, req, cl := prepareReconcile(t, newConfig("2Gi", 1), worker)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertNoBuffer(t, cl, "autoscaling-buffer-us-east-1a")
+	})
+
+	t.Run("changed buffer restored", func(t *testing.T) {
+		// given
+		changed := newBuffer(operatorNamespace, "us-east-1a", zoneLabel, 5, resource.MustParse("2Gi"))
+		changed.Spec.Template.Spec.Containers[0].Image = "other"
+		r, req, cl := prepareReconcile(t, newConfig("2Gi", 1), newWorker("worker-1", "us-east-1a"), changed)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		buffer := assertBuffer(t, cl, "autoscaling-buffer-us-east-1a", "us-east-1a", "2Gi", 1)
+		assert.Equal(t, "k8s.gcr.io/pause:3.1", buffer.Spec.Template.Spec.Containers[0].Image)
+	})
+
+	t.Run("buffer ready once rolled out", func(t *testing.T) {
+		// given
+		buffer := newBuffer(operatorNamespace, "us-east-1a", zoneLabel, 1, resource.MustParse("2Gi"))
+		buffer.Status.UpdatedReplicas = 1
+		buffer.Status.AvailableReplicas = 1
+		r, req, cl := prepareReconcile(t, newConfig("2Gi", 1), newWorker("worker-1", "us-east-1a"), buffer)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		assertBufferCondition(t, cl, corev1.ConditionTrue, rollout.CompleteReason, "")
+	})
+
+	t.Run("condition removed when no buffer", func(t *testing.T) {
+		// given
+		cfg := newConfig("", 0)
+		cfg.Status.Conditions = []toolchainv1alpha1.Condition{{
+			Type:   memberv1alpha1.AutoscalingBufferReadyCondition,
+			Status: corev1.ConditionFalse,
+			Reason: rollout.InProgressReason,
+		}}
+		r, req, cl := prepareReconcile(t, cfg, newWorker("worker-1", "us-east-1a"))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName}, cfg)
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Status.Conditions)
+	})
+
+	t.Run("buffer image pulled from its mirror", func(t *testing.T) {
+		// given
+		cfg := newConfig("2Gi", 1)
+		cfg.Spec.ImageMirrors = &memberv1alpha1.ImageMirrorsConfig{
+			Mirrors: []memberv1alpha1.ImageMirror{{Source: "k8s.gcr.io", Mirror: "registry.example.com:5000/k8s"}},
+		}
+		r, req, cl := prepareReconcile(t, cfg, newWorker("worker-1", "us-east-1a"))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		buffer := assertBuffer(t, cl, "autoscaling-buffer-us-east-1a", "us-east-1a", "2Gi", 1)
+		assert.Equal(t, "registry.example.com:5000/k8s/pause:3.1", buffer.Spec.Template.Spec.Containers[0].Image)
+	})
+
+	t.Run("other deployments not deleted", func(t *testing.T) {
+		// given
+		other := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: "member-operator"}}
+		r, req, cl := prepareReconcile(t, other)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: "member-operator"}, &appsv1.Deployment{})
+		require.NoError(t, err)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("invalid memory", func(t *testing.T) {
+			// given
+			r, req, _ := prepareReconcile(t, newConfig("a lot", 1), newWorker("worker-1", "us-east-1a"))
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid buffer memory 'a lot'")
+		})
+
+		t.Run("list nodes fails", func(t *testing.T) {
+			// given
+			r, req, cl := prepareReconcile(t, newConfig("2Gi", 1), newWorker("worker-1", "us-east-1a"))
+			cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+				return errors.New("mock error")
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.EqualError(t, err, "failed to list the nodes: mock error")
+		})
+
+		t.Run("create buffer fails", func(t *testing.T) {
+			// given
+			r, req, cl := prepareReconcile(t, newConfig("2Gi", 1), newWorker("worker-1", "us-east-1a"))
+			cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+				if _, ok := obj.(*appsv1.Deployment); ok {
+					return errors.New("mock error")
+				}
+				return cl.Client.Create(ctx, obj, opts...)
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.EqualError(t, err, "failed to create the buffer 'autoscaling-buffer-us-east-1a': mock error")
+		})
+	})
+}
+
+func prepareReconcile(t *testing.T, initObjs ...runtime.Object) (*ReconcileAutoscaler, reconcile.Request, *test.FakeClient) {
+	cl := test.NewFakeClient(t, initObjs...)
+	// the configuration is loaded by the MemberOperatorConfig controller
+	require.NoError(t, config.LoadMemberOperatorConfig(cl, operatorNamespace))
+	r := &ReconcileAutoscaler{
+		client:    cl,
+		scheme:    scheme.Scheme,
+		namespace: operatorNamespace,
+	}
+	return r, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName}}, cl
+}
+
+func newConfig(memory string, replicas int32) *memberv1alpha1.MemberOperatorConfig {
+	return &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName},
+		Spec: memberv1alpha1.MemberOperatorConfigSpec{
+			Autoscaler: &memberv1alpha1.AutoscalerConfig{
+				BufferMemory:   memory,
+				BufferReplicas: replicas,
+			},
+		},
+	}
+}
+
+func newWorker(name, zone string) *corev1.Node {
+	return newNode(name, zone, workerRoleLabel)
+}
+
+func newMaster(name, zone string) *corev1.Node {
+	return newNode(name, zone, "node-role.kubernetes.io/master")
+}
+
+func newNode(name, zone, roleLabel string) *corev1.Node {
+	labels := map[string]string{roleLabel: ""}
+	if zone != "" {
+		labels[zoneLabel] = zone
+	}
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func assertBuffer(t *testing.T, cl client.Client, name, zone, memory string, replicas int32) *appsv1.Deployment {
+	buffer := &appsv1.Deployment{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: name}, buffer)
+	require.NoError(t, err)
+	require.NotNil(t, buffer.Spec.Replicas)
+	assert.Equal(t, replicas, *buffer.Spec.Replicas)
+	assert.Equal(t, zone, buffer.Labels[BufferLabel])
+	podSpec := buffer.Spec.Template.Spec
+	assert.Equal(t, BufferPriorityClassName, podSpec.PriorityClassName)
+	if zone != "" {
+		assert.Equal(t, zone, podSpec.NodeSelector[zoneLabel])
+	}
+	require.Len(t, podSpec.Containers, 1)
+	requested := podSpec.Containers[0].Resources.Requests[corev1.ResourceMemory]
+	assert.Equal(t, memory, requested.String())
+	limit := podSpec.Containers[0].Resources.Limits[corev1.ResourceMemory]
+	assert.Equal(t, memory, limit.String())
+	return buffer
+}
+
+func assertBufferCondition(t *testing.T, cl client.Client, status corev1.ConditionStatus, reason, message string) {
+	cfg := &memberv1alpha1.MemberOperatorConfig{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName}, cfg)
+	require.NoError(t, err)
+	cond, found := condition.FindConditionByType(cfg.Status.Conditions, memberv1alpha1.AutoscalingBufferReadyCondition)
+	require.True(t, found)
+	assert.Equal(t, status, cond.Status)
+	assert.Equal(t, reason, cond.Reason)
+	assert.Equal(t, message, cond.Message)
+}
+
+func assertNoBuffer(t *testing.T, cl client.Client, name string) {
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: name}, &appsv1.Deployment{})
+	require.Error(t, err)
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -0,0 +1,186 @@
+package citoken
+
+import (
+	"context"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+	"github.com/codeready-toolchain/member-operator/pkg/predicate"
+
+	"github.com/go-logr/logr"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_citoken")
+
+const (
+	// CIAccessLabel the label to set to `true` on the ServiceAccounts of the tier templates which are dedicated to the external CI systems
+	// (eg, the pipelines which push images or deploy into the user namespaces). The permissions of such a ServiceAccount are the ones
+	// granted by the Roles and RoleBindings of the same template.
+	CIAccessLabel = "toolchain.dev.openshift.com/ci-access"
+	// RotatedAtAnnotation the annotation of the token Secrets which holds the time at which the token was (re)created, in RFC3339 format
+	RotatedAtAnnotation = "toolchain.dev.openshift.com/rotated-at"
+	// tokenSecretSuffix the suffix of the name of the token Secret of a CI ServiceAccount
+	tokenSecretSuffix = "-ci-token"
+)
+
+// Add creates a new CIToken Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileCIToken{
+		client: config.ControllerClient("citoken", mgr.GetClient()),
+		scheme: mgr.GetScheme(),
+	}
+}
+
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("citoken-controller", mgr, config.ControllerOptions("citoken", r))
+	if err != nil {
+		return err
+	}
+	// Watch for the ServiceAccounts which are created or relabelled, eg, when the tier templates are applied
+	if err := c.Watch(&source.Kind{Type: &corev1.ServiceAccount{}}, &handler.EnqueueRequestForObject{}, predicate.LabelsChangedOrCreatedDeleted{}); err != nil {
+		return err
+	}
+	// Watch for changes to the token Secrets, so that they are restored if they are deleted
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &corev1.ServiceAccount{},
+	})
+}
+
+var _ reconcile.Reconciler = &ReconcileCIToken{}
+
+// ReconcileCIToken provisions a token Secret for each ServiceAccount of the user namespaces which has the `toolchain.dev.openshift.com/ci-access=true`
+// label, and replaces it with a new one once the rotation period configured in the MemberOperatorConfig has elapsed, so that a leaked
+// token only remains valid for a limited time
+type ReconcileCIToken struct {
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile creates the token Secret of the CI ServiceAccount, or replaces it if it is older than the rotation period.
+// The ServiceAccount is requeued when its token must be rotated next
+func (r *ReconcileCIToken) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := logging.ForRequest(log, request)
+
+	sa := &corev1.ServiceAccount{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, sa); err != nil {
+		if errors.IsNotFound(err) {
+			// the token Secret is garbage collected along with its ServiceAccount
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errs.Wrapf(err, "failed to get the service account '%s'", request.Name)
+	}
+	if sa.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: sa.Namespace, Name: tokenSecretName(sa.Name)}, secret); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, errs.Wrapf(err, "failed to get the token secret of service account '%s'", sa.Name)
+		}
+		secret = nil
+	}
+	if secret != nil && !metav1.IsControlledBy(secret, sa) {
+		reqLogger.Info("not overwriting a secret created by the user", "name", secret.Name)
+		return reconcile.Result{}, nil
+	}
+	if sa.Labels[CIAccessLabel] != "true" {
+		// the ServiceAccount is not dedicated to the CI systems anymore
+		if secret != nil {
+			reqLogger.Info("deleting the token of a service account without CI access")
+			if err := r.client.Delete(context.TODO(), secret); err != nil && !errors.IsNotFound(err) {
+				return reconcile.Result{}, errs.Wrapf(err, "failed to delete the token secret of service account '%s'", sa.Name)
+			}
+		}
+		return reconcile.Result{}, nil
+	}
+	userNamespace, err := r.isUserNamespace(sa.Namespace)
+	if err != nil || !userNamespace {
+		return reconcile.Result{}, err
+	}
+
+	period := config.GetCITokenRotationPeriod()
+	if secret != nil {
+		rotatedAt, err := time.Parse(time.RFC3339, secret.Annotations[RotatedAtAnnotation])
+		if err == nil {
+			if remaining := period - time.Since(rotatedAt); remaining > 0 {
+				return reconcile.Result{RequeueAfter: remaining}, nil
+			}
+		}
+		// deleting the Secret invalidates the token it holds
+		reqLogger.Info("rotating the token of the service account", "rotated_at", secret.Annotations[RotatedAtAnnotation])
+		if err := r.client.Delete(context.TODO(), secret); err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, errs.Wrapf(err, "failed to delete the token secret of service account '%s'", sa.Name)
+		}
+	}
+	if err := r.createTokenSecret(reqLogger, sa); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: period}, nil
+}
+
+// isUserNamespace returns true if the given namespace was provisioned for a user
+func (r *ReconcileCIToken) isUserNamespace(name string) (bool, error) {
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: name}, ns); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errs.Wrapf(err, "failed to get the namespace '%s'", name)
+	}
+	return ns.Labels["owner"] != "" && ns.Status.Phase != corev1.NamespaceTerminating, nil
+}
+
+// createTokenSecret creates a new token Secret bound to the given ServiceAccount. The token itself is generated by the token controller
+// of the cluster, which also deletes the Secret if the ServiceAccount is recreated with another UID
+func (r *ReconcileCIToken) createTokenSecret(logger logr.Logger, sa *corev1.ServiceAccount) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: sa.Namespace,
+			Name:      tokenSecretName(sa.Name),
+			// no `provider` label: the Secret is not recorded in the inventory, so the stale resources cleaner would delete it
+			Labels: map[string]string{
+				CIAccessLabel: "true",
+			},
+			Annotations: map[string]string{
+				corev1.ServiceAccountNameKey: sa.Name,
+				corev1.ServiceAccountUIDKey:  string(sa.UID),
+				RotatedAtAnnotation:          time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+	if err := controllerutil.SetControllerReference(sa, secret, r.scheme); err != nil {
+		return errs.Wrapf(err, "failed to set the owner of the token secret of service account '%s'", sa.Name)
+	}
+	logger.Info("creating the token of the service account", "name", secret.Name)
+	if err := r.client.Create(context.TODO(), secret); err != nil {
+		return errs.Wrapf(err, "failed to create the token secret of service account '%s'", sa.Name)
+	}
+	return nil
+}
+
+// tokenSecretName returns the name of the token Secret of the given CI ServiceAccount
+func tokenSecretName(serviceAccount string) string {
+	return serviceAccount + tokenSecretSuffix
+}
@@ -0,0 +1,293 @@
+package citoken
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/cleanup"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	operatorNamespace = "toolchain-member-operator"
+	userNamespace     = "johnsmith-dev"
+)
+
+func TestReconcile(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+
+	t.Run("token created", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newServiceAccount("pipeline", true), newNamespace(userNamespace, "johnsmith"))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: config.DefaultCITokenRotationPeriod}, res)
+		secret := getSecret(t, cl, "pipeline-ci-token")
+		assert.Equal(t, corev1.SecretTypeServiceAccountToken, secret.Type)
+		assert.Equal(t, "pipeline", secret.Annotations[corev1.ServiceAccountNameKey])
+		assert.Equal(t, "uid-pipeline", secret.Annotations[corev1.ServiceAccountUIDKey])
+		assert.Equal(t, "true", secret.Labels[CIAccessLabel])
+		rotatedAt, err := time.Parse(time.RFC3339, secret.Annotations[RotatedAtAnnotation])
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), rotatedAt, time.Minute)
+		require.Len(t, secret.OwnerReferences, 1)
+		assert.Equal(t, "ServiceAccount", secret.OwnerReferences[0].Kind)
+		assert.Equal(t, "pipeline", secret.OwnerReferences[0].Name)
+	})
+
+	t.Run("token kept by the stale resources cleaner", func(t *testing.T) {
+		// given
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: operatorNamespace,
+				Name:      "johnsmith",
+				Annotations: map[string]string{
+					template.InventoryAnnotation: `{"entries":[{"apiVersion":"v1","kind":"ServiceAccount","namespace":"johnsmith-dev","name":"pipeline"}]}`,
+				},
+			},
+		}
+		r, req, cl := prepareReconcile(t, nsTmplSet, newServiceAccount("pipeline", true), newNamespace(userNamespace, "johnsmith"))
+		_, err := r.Reconcile(req)
+		require.NoError(t, err)
+
+		// when
+		err = cleanup.NewCleaner(cl, operatorNamespace, cleanup.DefaultInterval).CleanAll()
+
+		// then
+		require.NoError(t, err)
+		secret := getSecret(t, cl, "pipeline-ci-token")
+		assert.NotContains(t, secret.Labels, "provider")
+	})
+
+	t.Run("token not rotated before the end of the period", func(t *testing.T) {
+		// given
+		sa := newServiceAccount("pipeline", true)
+		secret := newTokenSecret(t, sa, time.Now().Add(-24*time.Hour))
+		r, req, cl := prepareReconcile(t, sa, secret, newNamespace(userNamespace, "johnsmith"))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, res.RequeueAfter > config.DefaultCITokenRotationPeriod-25*time.Hour)
+		assert.True(t, res.RequeueAfter <= config.DefaultCITokenRotationPeriod-24*time.Hour)
+		assert.Equal(t, secret.Annotations[RotatedAtAnnotation], getSecret(t, cl, "pipeline-ci-token").Annotations[RotatedAtAnnotation])
+	})
+
+	t.Run("token rotated at the end of the configured period", func(t *testing.T) {
+		// given
+		cfg := &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				CIAccess: &memberv1alpha1.CIAccessConfig{TokenRotationPeriod: "168h"},
+			},
+		}
+		sa := newServiceAccount("pipeline", true)
+		secret := newTokenSecret(t, sa, time.Now().Add(-169*time.Hour))
+		secret.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte("old-token")}
+		r, req, cl := prepareReconcile(t, sa, secret, newNamespace(userNamespace, "johnsmith"), cfg)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: 168 * time.Hour}, res)
+		rotated := getSecret(t, cl, "pipeline-ci-token")
+		assert.Empty(t, rotated.Data)
+		rotatedAt, err := time.Parse(time.RFC3339, rotated.Annotations[RotatedAtAnnotation])
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), rotatedAt, time.Minute)
+	})
+
+	t.Run("token deleted when the service account has no CI access anymore", func(t *testing.T) {
+		// given
+		sa := newServiceAccount("pipeline", false)
+		r, req, cl := prepareReconcile(t, sa, newTokenSecret(t, sa, time.Now()), newNamespace(userNamespace, "johnsmith"))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		assertNoSecret(t, cl, "pipeline-ci-token")
+	})
+
+	t.Run("no token", func(t *testing.T) {
+
+		t.Run("service account without CI access", func(t *testing.T) {
+			// given
+			r, req, cl := prepareReconcile(t, newServiceAccount("pipeline", false), newNamespace(userNamespace, "johnsmith"))
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assertNoSecret(t, cl, "pipeline-ci-token")
+		})
+
+		t.Run("not a user namespace", func(t *testing.T) {
+			// given
+			r, req, cl := prepareReconcile(t, newServiceAccount("pipeline", true), newNamespace(userNamespace, ""))
+
+			// when
+			res, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{}, res)
+			assertNoSecret(t, cl, "pipeline-ci-token")
+		})
+
+		t.Run("service account not found", func(t *testing.T) {
+			// given
+			r, req, _ := prepareReconcile(t, newNamespace(userNamespace, "johnsmith"))
+
+			// when
+			res, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{}, res)
+		})
+	})
+
+	t.Run("secret of the user not overwritten", func(t *testing.T) {
+		// given
+		own := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: userNamespace, Name: "pipeline-ci-token"},
+			Data:       map[string][]byte{"value": []byte("mine")},
+		}
+		r, req, cl := prepareReconcile(t, newServiceAccount("pipeline", true), own, newNamespace(userNamespace, "johnsmith"))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "mine", string(getSecret(t, cl, "pipeline-ci-token").Data["value"]))
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("token creation failed", func(t *testing.T) {
+			// given
+			r, req, cl := prepareReconcile(t, newServiceAccount("pipeline", true), newNamespace(userNamespace, "johnsmith"))
+			cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+				return errors.New("mock error")
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.EqualError(t, err, "failed to create the token secret of service account 'pipeline': mock error")
+		})
+
+		t.Run("token deletion failed", func(t *testing.T) {
+			// given
+			sa := newServiceAccount("pipeline", true)
+			r, req, cl := prepareReconcile(t, sa, newTokenSecret(t, sa, time.Now().Add(-1000*time.Hour)), newNamespace(userNamespace, "johnsmith"))
+			cl.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+				return errors.New("mock error")
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.EqualError(t, err, "failed to delete the token secret of service account 'pipeline': mock error")
+		})
+	})
+}
+
+func prepareReconcile(t *testing.T, initObjs ...runtime.Object) (*ReconcileCIToken, reconcile.Request, *test.FakeClient) {
+	cl := test.NewFakeClient(t, initObjs...)
+	// the configuration is loaded by the MemberOperatorConfig controller
+	require.NoError(t, config.LoadMemberOperatorConfig(cl, operatorNamespace))
+	r := &ReconcileCIToken{
+		client: cl,
+		scheme: scheme.Scheme,
+	}
+	return r, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "pipeline"}}, cl
+}
+
+func newNamespace(name, owner string) *corev1.Namespace {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+	}
+	if owner != "" {
+		ns.Labels = map[string]string{"owner": owner}
+	}
+	return ns
+}
+
+func newServiceAccount(name string, ciAccess bool) *corev1.ServiceAccount {
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Namespace: userNamespace, Name: name, UID: types.UID("uid-" + name)},
+	}
+	if ciAccess {
+		sa.Labels = map[string]string{CIAccessLabel: "true"}
+	}
+	return sa
+}
+
+func newTokenSecret(t *testing.T, sa *corev1.ServiceAccount, rotatedAt time.Time) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: sa.Namespace,
+			Name:      tokenSecretName(sa.Name),
+			Labels:    map[string]string{CIAccessLabel: "true"},
+			Annotations: map[string]string{
+				corev1.ServiceAccountNameKey: sa.Name,
+				RotatedAtAnnotation:          rotatedAt.UTC().Format(time.RFC3339),
+			},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+	require.NoError(t, controllerutil.SetControllerReference(sa, secret, scheme.Scheme))
+	return secret
+}
+
+func getSecret(t *testing.T, cl client.Client, name string) *corev1.Secret {
+	secret := &corev1.Secret{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: userNamespace, Name: name}, secret)
+	require.NoError(t, err)
+	return secret
+}
+
+func assertNoSecret(t *testing.T, cl client.Client, name string) {
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: userNamespace, Name: name}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err), "secret '%s' should not exist", name)
+}
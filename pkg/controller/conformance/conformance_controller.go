@@ -0,0 +1,128 @@
+package conformance
+
+import (
+	"context"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/conformance"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+	"github.com/codeready-toolchain/member-operator/pkg/nstemplatetier"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_conformance")
+
+const (
+	// defaultTier the tier used by the conformance checks when none was specified in the annotation
+	defaultTier = "basic"
+	// runValue the annotation value to trigger a run with the default tier
+	runValue = "run"
+)
+
+// Add creates a new Conformance Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileConformance{
+		client:    config.ControllerClient("conformance", mgr.GetClient()),
+		scheme:    mgr.GetScheme(),
+		getChecks: newChecks(mgr.GetClient(), mgr.GetScheme()),
+	}
+}
+
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("conformance-controller", mgr, config.ControllerOptions("conformance", r))
+	if err != nil {
+		return err
+	}
+	// Watch for changes to primary resource MemberStatus (the runs are triggered via an annotation, hence no predicate on the generation)
+	return c.Watch(&source.Kind{Type: &memberv1alpha1.MemberStatus{}}, &handler.EnqueueRequestForObject{})
+}
+
+// newChecks returns a function which retrieves the templates of the given tier from the host cluster and returns the checks of
+// a conformance suite using them
+func newChecks(cl client.Client, scheme *runtime.Scheme) func(namespace, tier string) ([]conformance.Check, error) {
+	return func(namespace, tier string) ([]conformance.Check, error) {
+		templates, err := nstemplatetier.GetNSTemplates(cluster.GetHostCluster, tier)
+		if err != nil {
+			return nil, err
+		}
+		return conformance.NewSuite(cl, scheme, namespace, tier, templates).Checks(), nil
+	}
+}
+
+var _ reconcile.Reconciler = &ReconcileConformance{}
+
+// ReconcileConformance runs the conformance checks when the MemberStatus resource is annotated accordingly
+type ReconcileConformance struct {
+	client    client.Client
+	scheme    *runtime.Scheme
+	getChecks func(namespace, tier string) ([]conformance.Check, error)
+}
+
+// Reconcile runs the conformance checks if the MemberStatus has the `toolchain.dev.openshift.com/conformance` annotation,
+// and reports the results in the MemberStatus status.
+// Note: the checks are executed synchronously, which means that this controller is busy until all checks completed.
+func (r *ReconcileConformance) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := logging.ForRequest(log, request)
+
+	memberStatus := &memberv1alpha1.MemberStatus{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, memberStatus); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	tier, found := memberStatus.Annotations[memberv1alpha1.ConformanceAnnotation]
+	if !found {
+		return reconcile.Result{}, nil
+	}
+	if tier == "" || tier == runValue {
+		tier = defaultTier
+	}
+	reqLogger.Info("Running the conformance checks", "tier", tier)
+
+	// remove the annotation first, so that the checks do not run again if the operator restarts in the middle of the run
+	delete(memberStatus.Annotations, memberv1alpha1.ConformanceAnnotation)
+	if err := r.client.Update(context.TODO(), memberStatus); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	result := &memberv1alpha1.ConformanceStatus{
+		Tier:      tier,
+		StartTime: metav1.Now(),
+	}
+	checks, err := r.getChecks(request.Namespace, tier)
+	if err != nil {
+		result.Checks = []memberv1alpha1.ConformanceCheck{
+			{
+				Name:    "TierTemplatesRetrieved",
+				Passed:  false,
+				Message: err.Error(),
+			},
+		}
+	} else {
+		result.Passed, result.Checks = conformance.Run(checks)
+	}
+	now := metav1.Now()
+	result.CompletionTime = &now
+	reqLogger.Info("Conformance checks completed", "passed", result.Passed)
+
+	memberStatus.Status.Conformance = result
+	return reconcile.Result{}, r.client.Status().Update(context.TODO(), memberStatus)
+}
@@ -0,0 +1,135 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/conformance"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const operatorNamespace = "toolchain-member-operator"
+
+func TestReconcile(t *testing.T) {
+
+	t.Run("no annotation", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus(nil), func(namespace, tier string) ([]conformance.Check, error) {
+			t.Fatal("checks should not be run")
+			return nil, nil
+		})
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		assert.Nil(t, getMemberStatus(t, cl).Status.Conformance)
+	})
+
+	t.Run("checks run with default tier", func(t *testing.T) {
+		// given
+		var actualTier string
+		r, req, cl := prepareReconcile(t, newMemberStatus(map[string]string{memberv1alpha1.ConformanceAnnotation: "run"}), func(namespace, tier string) ([]conformance.Check, error) {
+			actualTier = tier
+			return []conformance.Check{
+				{Name: "first", Run: func() error { return nil }},
+			}, nil
+		})
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "basic", actualTier)
+		memberStatus := getMemberStatus(t, cl)
+		assert.NotContains(t, memberStatus.Annotations, memberv1alpha1.ConformanceAnnotation)
+		require.NotNil(t, memberStatus.Status.Conformance)
+		assert.True(t, memberStatus.Status.Conformance.Passed)
+		assert.Equal(t, "basic", memberStatus.Status.Conformance.Tier)
+		assert.NotNil(t, memberStatus.Status.Conformance.CompletionTime)
+		assert.Equal(t, []memberv1alpha1.ConformanceCheck{{Name: "first", Passed: true}}, memberStatus.Status.Conformance.Checks)
+	})
+
+	t.Run("checks run with given tier and failed", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus(map[string]string{memberv1alpha1.ConformanceAnnotation: "advanced"}), func(namespace, tier string) ([]conformance.Check, error) {
+			return []conformance.Check{
+				{Name: "first", Run: func() error { return errors.New("mock error") }},
+			}, nil
+		})
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		memberStatus := getMemberStatus(t, cl)
+		require.NotNil(t, memberStatus.Status.Conformance)
+		assert.False(t, memberStatus.Status.Conformance.Passed)
+		assert.Equal(t, "advanced", memberStatus.Status.Conformance.Tier)
+		assert.Equal(t, []memberv1alpha1.ConformanceCheck{{Name: "first", Passed: false, Message: "mock error"}}, memberStatus.Status.Conformance.Checks)
+	})
+
+	t.Run("failed to retrieve the tier templates", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus(map[string]string{memberv1alpha1.ConformanceAnnotation: "basic"}), func(namespace, tier string) ([]conformance.Check, error) {
+			return nil, errors.New("the host cluster is not ready")
+		})
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		memberStatus := getMemberStatus(t, cl)
+		require.NotNil(t, memberStatus.Status.Conformance)
+		assert.False(t, memberStatus.Status.Conformance.Passed)
+		assert.Equal(t, []memberv1alpha1.ConformanceCheck{{Name: "TierTemplatesRetrieved", Passed: false, Message: "the host cluster is not ready"}}, memberStatus.Status.Conformance.Checks)
+	})
+}
+
+func newMemberStatus(annotations map[string]string) *memberv1alpha1.MemberStatus {
+	return &memberv1alpha1.MemberStatus{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        memberv1alpha1.MemberStatusName,
+			Namespace:   operatorNamespace,
+			Annotations: annotations,
+		},
+	}
+}
+
+func getMemberStatus(t *testing.T, cl *test.FakeClient) *memberv1alpha1.MemberStatus {
+	memberStatus := &memberv1alpha1.MemberStatus{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberStatusName}, memberStatus)
+	require.NoError(t, err)
+	return memberStatus
+}
+
+func prepareReconcile(t *testing.T, memberStatus *memberv1alpha1.MemberStatus, getChecks func(namespace, tier string) ([]conformance.Check, error)) (*ReconcileConformance, reconcile.Request, *test.FakeClient) {
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+	cl := test.NewFakeClient(t, memberStatus)
+	r := &ReconcileConformance{
+		client:    cl,
+		scheme:    s,
+		getChecks: getChecks,
+	}
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberStatusName},
+	}
+	return r, req, cl
+}
@@ -1,9 +1,24 @@
 package controller
 
 import (
+	"github.com/codeready-toolchain/member-operator/pkg/activity"
+	"github.com/codeready-toolchain/member-operator/pkg/audit"
+	"github.com/codeready-toolchain/member-operator/pkg/cleanup"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/autoscaler"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/citoken"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/conformance"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/idler"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/memberconsole"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/memberoperatorconfig"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/memberstatus"
 	"github.com/codeready-toolchain/member-operator/pkg/controller/nstemplateset"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/secretpropagation"
 	"github.com/codeready-toolchain/member-operator/pkg/controller/useraccount"
 	"github.com/codeready-toolchain/member-operator/pkg/controller/useraccountstatus"
+	"github.com/codeready-toolchain/member-operator/pkg/health"
+	"github.com/codeready-toolchain/member-operator/pkg/metrics"
+	"github.com/codeready-toolchain/member-operator/pkg/orphans"
+	"github.com/codeready-toolchain/member-operator/pkg/quota"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
@@ -11,9 +26,24 @@ import (
 var addToManagerFuncs []func(manager.Manager) error
 
 func init() {
+	addToManagerFuncs = append(addToManagerFuncs, memberoperatorconfig.Add)
 	addToManagerFuncs = append(addToManagerFuncs, useraccount.Add)
 	addToManagerFuncs = append(addToManagerFuncs, useraccountstatus.Add)
 	addToManagerFuncs = append(addToManagerFuncs, nstemplateset.Add)
+	addToManagerFuncs = append(addToManagerFuncs, secretpropagation.Add)
+	addToManagerFuncs = append(addToManagerFuncs, citoken.Add)
+	addToManagerFuncs = append(addToManagerFuncs, conformance.Add)
+	addToManagerFuncs = append(addToManagerFuncs, memberstatus.Add)
+	addToManagerFuncs = append(addToManagerFuncs, idler.Add)
+	addToManagerFuncs = append(addToManagerFuncs, autoscaler.Add)
+	addToManagerFuncs = append(addToManagerFuncs, memberconsole.Add)
+	addToManagerFuncs = append(addToManagerFuncs, quota.Add)
+	addToManagerFuncs = append(addToManagerFuncs, activity.Add)
+	addToManagerFuncs = append(addToManagerFuncs, health.Add)
+	addToManagerFuncs = append(addToManagerFuncs, cleanup.Add)
+	addToManagerFuncs = append(addToManagerFuncs, audit.Add)
+	addToManagerFuncs = append(addToManagerFuncs, orphans.Add)
+	addToManagerFuncs = append(addToManagerFuncs, metrics.Add)
 }
 
 // AddToManager adds all Controllers to the Manager
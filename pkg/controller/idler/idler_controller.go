@@ -0,0 +1,534 @@
+package idler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/clustercache"
+	"github.com/codeready-toolchain/member-operator/pkg/conditions"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+	"github.com/codeready-toolchain/member-operator/pkg/metrics"
+	memberpredicate "github.com/codeready-toolchain/member-operator/pkg/predicate"
+
+	"github.com/go-logr/logr"
+	openshiftappsv1 "github.com/openshift/api/apps/v1"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"github.com/operator-framework/operator-sdk/pkg/predicate"
+	errs "github.com/pkg/errors"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_idler")
+
+// Status condition reasons
+const (
+	runningReason        = "Running"
+	unableToIdleReason   = "UnableToIdle"
+	timeoutExpiredReason = "TimeoutExpired"
+)
+
+// idledEventReason the reason of the events recorded on the Idler for each idled workload
+const idledEventReason = "Idled"
+
+// haltedRunStrategy the run strategy of the stopped KubeVirt VirtualMachines
+const haltedRunStrategy = "Halted"
+
+// Add creates a new Idler Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	// the workloads are in the user namespaces, which are not in the cache of the manager
+	userObjects, err := clustercache.New(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, newReconciler(mgr, userObjects, namespace), userObjects)
+}
+
+func newReconciler(mgr manager.Manager, userObjects client.Reader, namespace string) reconcile.Reconciler {
+	return &ReconcileIdler{
+		client:      config.ControllerClient("idler", mgr.GetClient()),
+		userObjects: userObjects,
+		scheme:      mgr.GetScheme(),
+		recorder:    mgr.GetEventRecorderFor("idler-controller"),
+		namespace:   namespace,
+	}
+}
+
+func add(mgr manager.Manager, r reconcile.Reconciler, userObjects cache.Cache) error {
+	c, err := controller.New("idler-controller", mgr, config.ControllerOptions("idler", r))
+	if err != nil {
+		return err
+	}
+	// Watch for changes to primary resource Idler
+	if err := c.Watch(&source.Kind{Type: &memberv1alpha1.Idler{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+	// Watch for the pods, so that their timeout is computed as soon as they are started
+	enqueueIdlerOfNamespace := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(idlerOfNamespace)}
+	pods, err := clustercache.Kind(userObjects, &corev1.Pod{})
+	if err != nil {
+		return err
+	}
+	if err := c.Watch(pods, enqueueIdlerOfNamespace); err != nil {
+		return err
+	}
+	// Watch for the unidle requests of the users
+	for _, obj := range []runtime.Object{&appsv1.Deployment{}, &appsv1.ReplicaSet{}, &appsv1.StatefulSet{}, &corev1.ReplicationController{}, &openshiftappsv1.DeploymentConfig{}} {
+		workloads, err := clustercache.Kind(userObjects, obj)
+		if err != nil {
+			return err
+		}
+		if err := c.Watch(workloads, enqueueIdlerOfNamespace, memberpredicate.AnnotationChanged{Key: UnidleAnnotation}); err != nil {
+			return err
+		}
+	}
+	// Watch for changes to the MemberOperatorConfig and to the NSTemplateSets, since the timeouts may be defined per tier
+	enqueueAllIdlers := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(allIdlers(mgr.GetClient()))}
+	if err := c.Watch(&source.Kind{Type: &memberv1alpha1.MemberOperatorConfig{}}, enqueueAllIdlers, predicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+	enqueueIdlersOfOwner := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(idlersOfOwner(mgr.GetClient()))}
+	return c.Watch(&source.Kind{Type: &toolchainv1alpha1.NSTemplateSet{}}, enqueueIdlersOfOwner, predicate.GenerationChangedPredicate{})
+}
+
+// idlerOfNamespace maps the given object to the Idler which has the same name as its namespace
+func idlerOfNamespace(obj handler.MapObject) []reconcile.Request {
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: obj.Meta.GetNamespace()}},
+	}
+}
+
+// allIdlers returns a mapper which enqueues all the Idlers
+func allIdlers(cl client.Client) func(handler.MapObject) []reconcile.Request {
+	return func(obj handler.MapObject) []reconcile.Request {
+		idlers := &memberv1alpha1.IdlerList{}
+		if err := cl.List(context.TODO(), idlers); err != nil {
+			log.Error(err, "failed to list the idlers")
+			return nil
+		}
+		requests := make([]reconcile.Request, len(idlers.Items))
+		for i, idler := range idlers.Items {
+			requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Name: idler.Name}}
+		}
+		return requests
+	}
+}
+
+// idlersOfOwner returns a mapper which enqueues the Idlers of the namespaces owned by the user with the same name as the mapped object
+func idlersOfOwner(cl client.Client) func(handler.MapObject) []reconcile.Request {
+	return func(obj handler.MapObject) []reconcile.Request {
+		namespaces := &corev1.NamespaceList{}
+		if err := cl.List(context.TODO(), namespaces, client.MatchingLabels(map[string]string{"owner": obj.Meta.GetName()})); err != nil {
+			log.Error(err, "failed to list the namespaces", "owner", obj.Meta.GetName())
+			return nil
+		}
+		requests := make([]reconcile.Request, len(namespaces.Items))
+		for i, ns := range namespaces.Items {
+			requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Name: ns.Name}}
+		}
+		return requests
+	}
+}
+
+var _ reconcile.Reconciler = &ReconcileIdler{}
+
+// ReconcileIdler idles the workloads of the user namespaces
+type ReconcileIdler struct {
+	client client.Client
+	// userObjects the reader of the typed objects of the user namespaces, ie, the pods, the workloads and the services (see `clustercache.New`).
+	// The unstructured objects (ie, the KubeVirt resources) are read from the API server by the client
+	userObjects client.Reader
+	scheme      *runtime.Scheme
+	recorder    record.EventRecorder
+	// namespace the namespace of the operator, which contains the MemberOperatorConfig and the NSTemplateSets
+	namespace string
+}
+
+// Reconcile idles the workloads of the namespace with the same name as the Idler, once their pods have been running for
+// longer than the idle timeout of the namespace (see `idleTimeout`): the Deployments, DeploymentConfigs, StatefulSets, ReplicaSets and ReplicationControllers
+// are scaled down to zero, the KubeVirt VirtualMachines are stopped, while the standalone pods and VirtualMachineInstances are deleted. The pods controlled by other kinds of objects (eg, DaemonSets or Jobs)
+// are left untouched.
+// The workloads which the user asked to unidle are scaled up first (see `UnidleAnnotation`), even if the idling is disabled.
+// The Idler is requeued until the timeout of the next pod expires.
+func (r *ReconcileIdler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := logging.ForRequest(log, request)
+
+	idler := &memberv1alpha1.Idler{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, idler); err != nil {
+		if errors.IsNotFound(err) {
+			// the namespace is not idled
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if util.IsBeingDeleted(idler) {
+		return reconcile.Result{}, nil
+	}
+	// Scale up the workloads which the user asked to unidle, even if the idling is disabled
+	if err := r.unidleRequestedWorkloads(reqLogger, idler); err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, idler, r.setStatusFailed, err, "failed to unidle the workloads of namespace '%s'", idler.Name)
+	}
+	timeout, err := r.idleTimeout(idler)
+	if err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, idler, r.setStatusFailed, err, "failed to get the idle timeout of namespace '%s'", idler.Name)
+	}
+	if timeout <= 0 {
+		return reconcile.Result{}, nil
+	}
+
+	idled, requeueAfter, err := r.idleInactiveWorkloads(reqLogger, idler, timeout)
+	if err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, idler, r.setStatusFailed, err, "failed to idle the workloads of namespace '%s'", idler.Name)
+	}
+	if err := r.setStatusReady(idler, idled); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// idleTimeout returns the idle timeout (in seconds) of the namespace of the given Idler: the timeout of the tier of the namespace if it
+// is specified in the MemberOperatorConfig, or the timeout of the Idler otherwise. The tier of the namespace is the one of the NSTemplateSet
+// of its owner.
+func (r *ReconcileIdler) idleTimeout(idler *memberv1alpha1.Idler) (int32, error) {
+	tier, err := r.tierOf(idler.Name)
+	if err != nil {
+		return 0, err
+	}
+	if timeout, found := config.GetTierIdleTimeout(tier); tier != "" && found {
+		return timeout, nil
+	}
+	return idler.Spec.TimeoutSeconds, nil
+}
+
+// tierOf returns the tier of the NSTemplateSet of the owner of the given namespace, or an empty string if the namespace
+// or the NSTemplateSet does not exist
+func (r *ReconcileIdler) tierOf(name string) (string, error) {
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: name}, ns); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errs.Wrapf(err, "failed to get namespace '%s'", name)
+	}
+	owner := ns.Labels["owner"]
+	if owner == "" {
+		return "", nil
+	}
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: r.namespace, Name: owner}, nsTmplSet); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errs.Wrapf(err, "failed to get the NSTemplateSet of user '%s'", owner)
+	}
+	return nsTmplSet.Spec.TierName, nil
+}
+
+// idleInactiveWorkloads idles the workloads whose pods have been running for longer than the given timeout (in seconds).
+// Returns the workloads which were idled, and the duration until the timeout of the next pod expires (or 0 if there is no such pod)
+func (r *ReconcileIdler) idleInactiveWorkloads(logger logr.Logger, idler *memberv1alpha1.Idler, timeoutSeconds int32) ([]string, time.Duration, error) {
+	pods := &corev1.PodList{}
+	if err := r.userObjects.List(context.TODO(), pods, client.InNamespace(idler.Name)); err != nil {
+		return nil, 0, errs.Wrap(err, "failed to list the pods")
+	}
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	var idled []string
+	var requeueAfter time.Duration
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.StartTime == nil || util.IsBeingDeleted(pod) {
+			continue
+		}
+		if remaining := time.Until(pod.Status.StartTime.Add(timeout)); remaining > 0 {
+			if requeueAfter == 0 || remaining < requeueAfter {
+				requeueAfter = remaining
+			}
+			continue
+		}
+		workload, err := r.idle(pod)
+		if err != nil {
+			return idled, 0, err
+		}
+		if workload == "" {
+			// already idled, or not a kind of workload which is idled
+			continue
+		}
+		logger.Info("workload idled", "workload", workload, "pod", pod.Name)
+		metrics.RecordIdled(workloadKind(workload))
+		r.recorder.Eventf(idler, corev1.EventTypeNormal, idledEventReason, "%s idled after running for more than %d seconds", workload, timeoutSeconds)
+		idled = append(idled, workload)
+	}
+	return idled, requeueAfter, nil
+}
+
+// idle idles the workload which controls the given pod, or deletes the pod if it is not controlled.
+// Returns the kind and name of the idled workload, or an empty string if nothing was idled.
+func (r *ReconcileIdler) idle(pod *corev1.Pod) (string, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		if err := r.client.Delete(context.TODO(), pod); err != nil {
+			if errors.IsNotFound(err) {
+				return "", nil
+			}
+			return "", errs.Wrapf(err, "failed to delete pod '%s'", pod.Name)
+		}
+		return workloadName("Pod", pod.Name), nil
+	}
+	switch owner.Kind {
+	case "ReplicaSet":
+		return r.idleReplicaSet(pod, owner.Name)
+	case "ReplicationController":
+		return r.idleReplicationController(pod, owner.Name)
+	case "StatefulSet":
+		statefulSet := &appsv1.StatefulSet{}
+		return r.scaleToZero(pod, "StatefulSet", owner.Name, statefulSet, func() bool {
+			return scaleDown(&statefulSet.Spec.Replicas)
+		})
+	case "VirtualMachineInstance":
+		if !config.FeatureEnabled(config.VirtualMachineIdling) {
+			return "", nil
+		}
+		return r.idleVirtualMachineInstance(pod, owner)
+	}
+	return "", nil
+}
+
+// idleVirtualMachineInstance stops the VirtualMachine which controls the given VirtualMachineInstance, or deletes the VirtualMachineInstance
+// itself if it is standalone. The KubeVirt resources are handled as unstructured objects, so that the operator does not depend on the KubeVirt API
+// (and its version), nor require KubeVirt to be installed on the cluster.
+func (r *ReconcileIdler) idleVirtualMachineInstance(pod *corev1.Pod, owner *metav1.OwnerReference) (string, error) {
+	namespace := pod.Namespace
+	vmi := newUnstructured(owner)
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: owner.Name}, vmi); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errs.Wrapf(err, "failed to get virtualmachineinstance '%s'", owner.Name)
+	}
+	if vmOwner := metav1.GetControllerOf(vmi); vmOwner != nil && vmOwner.Kind == "VirtualMachine" {
+		vm := newUnstructured(vmOwner)
+		return r.scaleToZero(pod, "VirtualMachine", vmOwner.Name, vm, func() bool {
+			return stopVirtualMachine(vm)
+		})
+	}
+	if err := r.client.Delete(context.TODO(), vmi); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errs.Wrapf(err, "failed to delete virtualmachineinstance '%s'", owner.Name)
+	}
+	return workloadName("VirtualMachineInstance", owner.Name), nil
+}
+
+// idleReplicaSet scales down to zero the Deployment which controls the given ReplicaSet, or the ReplicaSet itself if it is standalone
+func (r *ReconcileIdler) idleReplicaSet(pod *corev1.Pod, name string) (string, error) {
+	namespace := pod.Namespace
+	replicaSet := &appsv1.ReplicaSet{}
+	if err := r.userObjects.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, replicaSet); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errs.Wrapf(err, "failed to get replicaset '%s'", name)
+	}
+	if owner := metav1.GetControllerOf(replicaSet); owner != nil && owner.Kind == "Deployment" {
+		deployment := &appsv1.Deployment{}
+		return r.scaleToZero(pod, "Deployment", owner.Name, deployment, func() bool {
+			return scaleDown(&deployment.Spec.Replicas)
+		})
+	}
+	return r.scaleToZero(pod, "ReplicaSet", name, replicaSet, func() bool {
+		return scaleDown(&replicaSet.Spec.Replicas)
+	})
+}
+
+// idleReplicationController scales down to zero the DeploymentConfig which controls the given ReplicationController, or
+// the ReplicationController itself if it is standalone
+func (r *ReconcileIdler) idleReplicationController(pod *corev1.Pod, name string) (string, error) {
+	namespace := pod.Namespace
+	replicationController := &corev1.ReplicationController{}
+	if err := r.userObjects.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, replicationController); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errs.Wrapf(err, "failed to get replicationcontroller '%s'", name)
+	}
+	if owner := metav1.GetControllerOf(replicationController); owner != nil && owner.Kind == "DeploymentConfig" {
+		deploymentConfig := &openshiftappsv1.DeploymentConfig{}
+		return r.scaleToZero(pod, "DeploymentConfig", owner.Name, deploymentConfig, func() bool {
+			if deploymentConfig.Spec.Replicas == 0 {
+				return false
+			}
+			deploymentConfig.Spec.Replicas = 0
+			return true
+		})
+	}
+	return r.scaleToZero(pod, "ReplicationController", name, replicationController, func() bool {
+		return scaleDown(&replicationController.Spec.Replicas)
+	})
+}
+
+// scaleToZero retrieves the workload with the given kind and name in the given object, applies the given func to scale it down to zero
+// (or to stop it), and updates the workload if the func returned `true` (ie, if the workload was not scaled down yet).
+// Its number of replicas is recorded in its annotations, and the workload is then recorded as an unidle target of the Services which
+// select the given pod (see `recordUnidleTarget`).
+// Returns the kind and name of the workload if it was scaled down, an empty string otherwise.
+func (r *ReconcileIdler) scaleToZero(pod *corev1.Pod, kind, name string, obj runtime.Object, scale func() bool) (string, error) {
+	namespace := pod.Namespace
+	if err := r.readerOf(obj).Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errs.Wrapf(err, "failed to get %s '%s'", strings.ToLower(kind), name)
+	}
+	replicas, scalable := replicasOf(obj)
+	if !scale() {
+		return "", nil
+	}
+	// the number of replicas is always recorded, so that the workload can be unidled on request (see `UnidleAnnotation`)
+	recordScale := scalable && replicas > 0
+	idledAt := time.Now()
+	if recordScale {
+		if err := markIdled(obj, replicas, idledAt); err != nil {
+			return "", errs.Wrapf(err, "failed to annotate %s '%s'", strings.ToLower(kind), name)
+		}
+	}
+	if err := r.client.Update(context.TODO(), obj); err != nil {
+		return "", errs.Wrapf(err, "failed to scale %s '%s'", strings.ToLower(kind), name)
+	}
+	if recordScale && config.FeatureEnabled(config.UnidleOnRequest) {
+		if err := r.recordUnidleTarget(pod, obj, kind, name, replicas, idledAt); err != nil {
+			return "", err
+		}
+	}
+	return workloadName(kind, name), nil
+}
+
+// readerOf returns the reader of the given object of a user namespace (see `ReconcileIdler.userObjects`)
+func (r *ReconcileIdler) readerOf(obj runtime.Object) client.Reader {
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		return r.client
+	}
+	return r.userObjects
+}
+
+// scaleDown sets the given number of replicas to zero. Returns `false` if it already was zero
+func scaleDown(replicas **int32) bool {
+	if *replicas != nil && **replicas == 0 {
+		return false
+	}
+	zero := int32(0)
+	*replicas = &zero
+	return true
+}
+
+// stopVirtualMachine sets the run strategy of the given VirtualMachine to `Halted` if it has one, or its `running` field to `false`
+// otherwise (both fields are mutually exclusive). Returns `false` if the VirtualMachine was already stopped
+func stopVirtualMachine(vm *unstructured.Unstructured) bool {
+	if strategy, found, _ := unstructured.NestedString(vm.Object, "spec", "runStrategy"); found {
+		if strategy == haltedRunStrategy {
+			return false
+		}
+		return unstructured.SetNestedField(vm.Object, haltedRunStrategy, "spec", "runStrategy") == nil
+	}
+	if running, found, _ := unstructured.NestedBool(vm.Object, "spec", "running"); found && !running {
+		return false
+	}
+	return unstructured.SetNestedField(vm.Object, false, "spec", "running") == nil
+}
+
+// newUnstructured returns an empty unstructured object with the API version and kind of the given owner reference
+func newUnstructured(owner *metav1.OwnerReference) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(owner.APIVersion)
+	obj.SetKind(owner.Kind)
+	return obj
+}
+
+func workloadName(kind, name string) string {
+	return fmt.Sprintf("%s '%s'", kind, name)
+}
+
+// workloadKind returns the kind of the given workload, as returned by `workloadName`
+func workloadKind(workload string) string {
+	return strings.SplitN(workload, " ", 2)[0]
+}
+
+func (r *ReconcileIdler) setStatusReady(idler *memberv1alpha1.Idler, idled []string) error {
+	conditions := []toolchainv1alpha1.Condition{
+		{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionTrue,
+			Reason: runningReason,
+		},
+	}
+	if len(idled) > 0 {
+		conditions = append(conditions, toolchainv1alpha1.Condition{
+			Type:    memberv1alpha1.IdlerIdledCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  timeoutExpiredReason,
+			Message: fmt.Sprintf("idled %s", strings.Join(idled, ", ")),
+		})
+	}
+	return r.updateStatusConditions(idler, conditions...)
+}
+
+func (r *ReconcileIdler) setStatusFailed(idler *memberv1alpha1.Idler, message string) error {
+	return r.updateStatusConditions(
+		idler,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToIdleReason,
+			Message: message,
+		})
+}
+
+// updateStatusConditions updates the Idler status conditions with the new conditions, along with the generation of the Idler they were set for
+func (r *ReconcileIdler) updateStatusConditions(idler *memberv1alpha1.Idler, newConditions ...toolchainv1alpha1.Condition) error {
+	var updated bool
+	idler.Status.Conditions, updated = conditions.Update(idler.Status.Conditions, newConditions...)
+	if conditions.Observe(&idler.Status.ObservedGeneration, idler.Generation) {
+		updated = true
+	}
+	if !updated {
+		// Nothing changed
+		return nil
+	}
+	return r.client.Status().Update(context.TODO(), idler)
+}
+
+// wrapErrorWithStatusUpdate wraps the error and updates the Idler status. If the update failed then logs the error.
+func (r *ReconcileIdler) wrapErrorWithStatusUpdate(logger logr.Logger, idler *memberv1alpha1.Idler, statusUpdater func(idler *memberv1alpha1.Idler, message string) error, err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	if err := statusUpdater(idler, err.Error()); err != nil {
+		logger.Error(err, "status update failed")
+	}
+	return errs.Wrapf(err, format, args...)
+}
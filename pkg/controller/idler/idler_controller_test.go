@@ -0,0 +1,725 @@
+package idler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	openshiftappsv1 "github.com/openshift/api/apps/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	namespace         = "johnsmith-dev"
+	operatorNamespace = "toolchain-member"
+	username          = "johnsmith"
+	timeout           = 60
+)
+
+func TestReconcile(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	t.Run("no idler", func(t *testing.T) {
+		// given
+		pod := newPod("standalone", time.Now().Add(-time.Hour))
+		r, req, cl, recorder := prepareReconcile(t, pod)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		assertExists(t, cl, pod)
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("idling disabled", func(t *testing.T) {
+		// given
+		idler := newIdler(0)
+		pod := newPod("standalone", time.Now().Add(-time.Hour))
+		r, req, cl, recorder := prepareReconcile(t, idler, pod)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		assertExists(t, cl, pod)
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("requeued until the timeout of the next pod expires", func(t *testing.T) {
+		// given
+		idler := newIdler(timeout)
+		pod := newPod("standalone", time.Now().Add(-30*time.Second))
+		notStarted := newPod("pending", time.Time{})
+		r, req, cl, recorder := prepareReconcile(t, idler, pod, notStarted)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, res.RequeueAfter > 0 && res.RequeueAfter <= 30*time.Second, "requeue after: %v", res.RequeueAfter)
+		assertExists(t, cl, pod)
+		assertExists(t, cl, notStarted)
+		assert.Empty(t, recorder.Events)
+		assertConditions(t, cl, readyCondition())
+	})
+
+	t.Run("observed generation reported", func(t *testing.T) {
+		// given
+		idler := newIdler(timeout)
+		idler.Generation = 3
+		r, req, cl, _ := prepareReconcile(t, idler, newPod("standalone", time.Now().Add(-30*time.Second)))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		updated := &memberv1alpha1.Idler{}
+		require.NoError(t, cl.Get(context.TODO(), req.NamespacedName, updated))
+		assert.Equal(t, int64(3), updated.Status.ObservedGeneration)
+	})
+
+	t.Run("workloads idled", func(t *testing.T) {
+		// given
+		idler := newIdler(timeout)
+		started := time.Now().Add(-2 * timeout * time.Second)
+		deployment := &appsv1.Deployment{ObjectMeta: newObjectMeta("app", nil), Spec: appsv1.DeploymentSpec{Replicas: replicas(3)}}
+		deploymentReplicaSet := &appsv1.ReplicaSet{ObjectMeta: newObjectMeta("app-123", controlledBy("Deployment", "app")), Spec: appsv1.ReplicaSetSpec{Replicas: replicas(3)}}
+		deploymentPod1 := newPodControlledBy("app-123-abc", started, "ReplicaSet", "app-123")
+		deploymentPod2 := newPodControlledBy("app-123-def", started, "ReplicaSet", "app-123")
+		replicaSet := &appsv1.ReplicaSet{ObjectMeta: newObjectMeta("rs", nil), Spec: appsv1.ReplicaSetSpec{Replicas: replicas(1)}}
+		replicaSetPod := newPodControlledBy("rs-abc", started, "ReplicaSet", "rs")
+		statefulSet := &appsv1.StatefulSet{ObjectMeta: newObjectMeta("db", nil), Spec: appsv1.StatefulSetSpec{Replicas: replicas(1)}}
+		statefulSetPod := newPodControlledBy("db-0", started, "StatefulSet", "db")
+		deploymentConfig := &openshiftappsv1.DeploymentConfig{ObjectMeta: newObjectMeta("legacy", nil), Spec: openshiftappsv1.DeploymentConfigSpec{Replicas: 2}}
+		replicationController := &corev1.ReplicationController{ObjectMeta: newObjectMeta("legacy-1", controlledBy("DeploymentConfig", "legacy")), Spec: corev1.ReplicationControllerSpec{Replicas: replicas(2)}}
+		deploymentConfigPod := newPodControlledBy("legacy-1-abc", started, "ReplicationController", "legacy-1")
+		daemonSetPod := newPodControlledBy("agent-abc", started, "DaemonSet", "agent")
+		standalonePod := newPod("standalone", started)
+		r, req, cl, recorder := prepareReconcile(t, idler, deployment, deploymentReplicaSet, deploymentPod1, deploymentPod2, replicaSet, replicaSetPod,
+			statefulSet, statefulSetPod, deploymentConfig, replicationController, deploymentConfigPod, daemonSetPod, standalonePod)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		assertDeploymentReplicas(t, cl, "app", 0)
+		assertReplicaSetReplicas(t, cl, "app-123", 3) // scaled down by the deployment controller
+		assertReplicaSetReplicas(t, cl, "rs", 0)
+		assertStatefulSetReplicas(t, cl, "db", 0)
+		assertDeploymentConfigReplicas(t, cl, "legacy", 0)
+		assertNotFound(t, cl, standalonePod)
+		assertExists(t, cl, daemonSetPod)
+		assert.Len(t, recorder.Events, 5)
+		cond := assertConditions(t, cl, readyCondition(), toolchainv1alpha1.Condition{Type: memberv1alpha1.IdlerIdledCondition, Status: corev1.ConditionTrue, Reason: "TimeoutExpired"})
+		for _, workload := range []string{"Deployment 'app'", "ReplicaSet 'rs'", "StatefulSet 'db'", "DeploymentConfig 'legacy'", "Pod 'standalone'"} {
+			assert.Contains(t, cond.Message, workload)
+		}
+
+		t.Run("already idled workloads ignored", func(t *testing.T) {
+			// given
+			recorder := record.NewFakeRecorder(10)
+			r.recorder = recorder
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Empty(t, recorder.Events)
+		})
+	})
+
+	t.Run("virtual machines idled", func(t *testing.T) {
+		// given
+		idler := newIdler(timeout)
+		started := time.Now().Add(-2 * timeout * time.Second)
+		runningVM := newVirtualMachine("running-vm", map[string]interface{}{"running": true})
+		runningVMI := newVirtualMachineInstance("running-vm", controlledBy("VirtualMachine", "running-vm"))
+		runningVMPod := newPodControlledBy("virt-launcher-running-vm-abc", started, "VirtualMachineInstance", "running-vm")
+		alwaysVM := newVirtualMachine("always-vm", map[string]interface{}{"runStrategy": "Always"})
+		alwaysVMI := newVirtualMachineInstance("always-vm", controlledBy("VirtualMachine", "always-vm"))
+		alwaysVMPod := newPodControlledBy("virt-launcher-always-vm-abc", started, "VirtualMachineInstance", "always-vm")
+		standaloneVMI := newVirtualMachineInstance("standalone-vmi", nil)
+		standaloneVMIPod := newPodControlledBy("virt-launcher-standalone-vmi-abc", started, "VirtualMachineInstance", "standalone-vmi")
+		for _, pod := range []*corev1.Pod{runningVMPod, alwaysVMPod, standaloneVMIPod} {
+			pod.OwnerReferences[0].APIVersion = kubevirtAPIVersion
+		}
+		r, req, cl, recorder := prepareReconcile(t, idler, runningVM, runningVMI, runningVMPod, alwaysVM, alwaysVMI, alwaysVMPod, standaloneVMI, standaloneVMIPod)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		vm := getVirtualMachine(t, cl, "running-vm")
+		running, _, err := unstructured.NestedBool(vm.Object, "spec", "running")
+		require.NoError(t, err)
+		assert.False(t, running)
+		vm = getVirtualMachine(t, cl, "always-vm")
+		runStrategy, _, err := unstructured.NestedString(vm.Object, "spec", "runStrategy")
+		require.NoError(t, err)
+		assert.Equal(t, "Halted", runStrategy)
+		_, found, err := unstructured.NestedFieldNoCopy(vm.Object, "spec", "running")
+		require.NoError(t, err)
+		assert.False(t, found)
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "standalone-vmi"}, newVirtualMachineInstance("", nil))
+		assert.True(t, apierrors.IsNotFound(err))
+		assert.Len(t, recorder.Events, 3)
+
+		t.Run("already stopped virtual machines ignored", func(t *testing.T) {
+			// given
+			recorder := record.NewFakeRecorder(10)
+			r.recorder = recorder
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Empty(t, recorder.Events)
+		})
+	})
+
+	t.Run("virtual machines not idled when feature disabled", func(t *testing.T) {
+		// given
+		started := time.Now().Add(-2 * timeout * time.Second)
+		vm := newVirtualMachine("running-vm", map[string]interface{}{"running": true})
+		vmi := newVirtualMachineInstance("running-vm", controlledBy("VirtualMachine", "running-vm"))
+		vmPod := newPodControlledBy("virt-launcher-running-vm-abc", started, "VirtualMachineInstance", "running-vm")
+		vmPod.OwnerReferences[0].APIVersion = kubevirtAPIVersion
+		cfg := &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				FeatureGates: map[string]bool{"VirtualMachineIdling": false},
+			},
+		}
+		r, req, cl, recorder := prepareReconcile(t, newIdler(timeout), vm, vmi, vmPod, cfg)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		running, _, err := unstructured.NestedBool(getVirtualMachine(t, cl, "running-vm").Object, "spec", "running")
+		require.NoError(t, err)
+		assert.True(t, running)
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("services annotated for the unidling", func(t *testing.T) {
+		// given
+		started := time.Now().Add(-2 * timeout * time.Second)
+		deployment := &appsv1.Deployment{ObjectMeta: newObjectMeta("app", nil), Spec: appsv1.DeploymentSpec{Replicas: replicas(3)}}
+		deploymentReplicaSet := &appsv1.ReplicaSet{ObjectMeta: newObjectMeta("app-123", controlledBy("Deployment", "app")), Spec: appsv1.ReplicaSetSpec{Replicas: replicas(3)}}
+		pod := newPodControlledBy("app-123-abc", started, "ReplicaSet", "app-123")
+		pod.Labels = map[string]string{"app": "app", "tier": "frontend"}
+		service := &corev1.Service{ObjectMeta: newObjectMeta("app", nil), Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "app"}}}
+		endpoints := &corev1.Endpoints{ObjectMeta: newObjectMeta("app", nil)}
+		otherService := &corev1.Service{ObjectMeta: newObjectMeta("db", nil), Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "db"}}}
+		externalService := &corev1.Service{ObjectMeta: newObjectMeta("external", nil)}
+
+		t.Run("workload scaled up by openshift on request", func(t *testing.T) {
+			// given
+			r, req, cl, _ := prepareReconcile(t, newIdler(timeout), deployment.DeepCopy(), deploymentReplicaSet.DeepCopy(), pod.DeepCopy(),
+				service.DeepCopy(), endpoints.DeepCopy(), otherService.DeepCopy(), externalService.DeepCopy())
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assertDeploymentReplicas(t, cl, "app", 0)
+			idled := &appsv1.Deployment{}
+			err = cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "app"}, idled)
+			require.NoError(t, err)
+			assert.Equal(t, "3", idled.Annotations[PreviousScaleAnnotation])
+			assert.NotEmpty(t, idled.Annotations[IdledAtAnnotation])
+			expectedTargets := `[{"kind":"Deployment","name":"app","group":"apps","replicas":3}]`
+			for _, obj := range []runtime.Object{&corev1.Service{}, &corev1.Endpoints{}} {
+				err = cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "app"}, obj)
+				require.NoError(t, err)
+				annotations := obj.(metav1.Object).GetAnnotations()
+				assert.JSONEq(t, expectedTargets, annotations[UnidleTargetsAnnotation])
+				assert.NotEmpty(t, annotations[IdledAtAnnotation])
+			}
+			for _, name := range []string{"db", "external"} {
+				other := &corev1.Service{}
+				err = cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, other)
+				require.NoError(t, err)
+				assert.Empty(t, other.Annotations)
+			}
+		})
+
+		t.Run("existing unidle target replaced", func(t *testing.T) {
+			// given
+			annotated := service.DeepCopy()
+			annotated.Annotations = map[string]string{
+				UnidleTargetsAnnotation: `[{"kind":"Deployment","name":"app","group":"apps","replicas":1},{"kind":"StatefulSet","name":"cache","group":"apps","replicas":1}]`,
+			}
+			r, req, cl, _ := prepareReconcile(t, newIdler(timeout), deployment.DeepCopy(), deploymentReplicaSet.DeepCopy(), pod.DeepCopy(), annotated)
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			updated := &corev1.Service{}
+			err = cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "app"}, updated)
+			require.NoError(t, err)
+			assert.JSONEq(t, `[{"kind":"Deployment","name":"app","group":"apps","replicas":3},{"kind":"StatefulSet","name":"cache","group":"apps","replicas":1}]`,
+				updated.Annotations[UnidleTargetsAnnotation])
+		})
+
+		t.Run("services not annotated when feature disabled", func(t *testing.T) {
+			// given
+			cfg := &memberv1alpha1.MemberOperatorConfig{
+				ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName},
+				Spec: memberv1alpha1.MemberOperatorConfigSpec{
+					FeatureGates: map[string]bool{"UnidleOnRequest": false},
+				},
+			}
+			r, req, cl, _ := prepareReconcile(t, newIdler(timeout), deployment.DeepCopy(), deploymentReplicaSet.DeepCopy(), pod.DeepCopy(),
+				service.DeepCopy(), endpoints.DeepCopy(), cfg)
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assertDeploymentReplicas(t, cl, "app", 0)
+			unchanged := &corev1.Service{}
+			err = cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "app"}, unchanged)
+			require.NoError(t, err)
+			assert.Empty(t, unchanged.Annotations)
+		})
+	})
+
+	t.Run("unidled on request", func(t *testing.T) {
+		// given
+		idled := &appsv1.Deployment{ObjectMeta: newObjectMeta("app", nil), Spec: appsv1.DeploymentSpec{Replicas: replicas(0)}}
+		idled.Annotations = map[string]string{
+			UnidleAnnotation:        "true",
+			PreviousScaleAnnotation: "3",
+			IdledAtAnnotation:       "2019-11-01T10:00:00Z",
+		}
+		service := &corev1.Service{ObjectMeta: newObjectMeta("app", nil)}
+		service.Annotations = map[string]string{
+			UnidleTargetsAnnotation: `[{"kind":"Deployment","name":"app","group":"apps","replicas":3},{"kind":"StatefulSet","name":"db","group":"apps","replicas":1}]`,
+			IdledAtAnnotation:       "2019-11-01T10:00:00Z",
+		}
+		endpoints := &corev1.Endpoints{ObjectMeta: newObjectMeta("app", nil)}
+		endpoints.Annotations = map[string]string{
+			UnidleTargetsAnnotation: `[{"kind":"Deployment","name":"app","group":"apps","replicas":3}]`,
+			IdledAtAnnotation:       "2019-11-01T10:00:00Z",
+		}
+		notIdled := &appsv1.StatefulSet{ObjectMeta: newObjectMeta("cache", nil), Spec: appsv1.StatefulSetSpec{Replicas: replicas(2)}}
+		notIdled.Annotations = map[string]string{UnidleAnnotation: "true"}
+		// the idling is disabled, but the unidle requests are still handled
+		r, req, cl, recorder := prepareReconcile(t, newIdler(0), idled, service, endpoints, notIdled)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertDeploymentReplicas(t, cl, "app", 3)
+		unidled := &appsv1.Deployment{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "app"}, unidled))
+		assert.Empty(t, unidled.Annotations)
+		updatedService := &corev1.Service{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "app"}, updatedService))
+		assert.JSONEq(t, `[{"kind":"StatefulSet","name":"db","group":"apps","replicas":1}]`, updatedService.Annotations[UnidleTargetsAnnotation])
+		updatedEndpoints := &corev1.Endpoints{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "app"}, updatedEndpoints))
+		assert.Empty(t, updatedEndpoints.Annotations)
+		assertStatefulSetReplicas(t, cl, "cache", 2)
+		unchanged := &appsv1.StatefulSet{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "cache"}, unchanged))
+		assert.Empty(t, unchanged.Annotations)
+		require.Len(t, recorder.Events, 1)
+		assert.Equal(t, "Normal Unidled Deployment 'app' scaled up to 3 replicas on request", <-recorder.Events)
+	})
+
+	t.Run("replicas recorded when the unidling by openshift is disabled", func(t *testing.T) {
+		// given
+		cfg := &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				FeatureGates: map[string]bool{"UnidleOnRequest": false},
+			},
+		}
+		started := time.Now().Add(-2 * timeout * time.Second)
+		deployment := &appsv1.Deployment{ObjectMeta: newObjectMeta("app", nil), Spec: appsv1.DeploymentSpec{Replicas: replicas(2)}}
+		replicaSet := &appsv1.ReplicaSet{ObjectMeta: newObjectMeta("app-123", controlledBy("Deployment", "app")), Spec: appsv1.ReplicaSetSpec{Replicas: replicas(2)}}
+		pod := newPodControlledBy("app-123-abc", started, "ReplicaSet", "app-123")
+		r, req, cl, _ := prepareReconcile(t, newIdler(timeout), deployment, replicaSet, pod, cfg)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertDeploymentReplicas(t, cl, "app", 0)
+		idled := &appsv1.Deployment{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "app"}, idled))
+		assert.Equal(t, "2", idled.Annotations[PreviousScaleAnnotation])
+	})
+
+	t.Run("timeout of the tier", func(t *testing.T) {
+		started := time.Now().Add(-2 * timeout * time.Second)
+
+		t.Run("tier timeout takes precedence", func(t *testing.T) {
+			// given
+			pod := newPod("standalone", started)
+			r, req, cl, recorder := prepareReconcile(t, newIdler(timeout), pod, newUserNamespace(), newNSTemplateSet("basic"),
+				newConfigWithTierTimeouts(map[string]int32{"basic": 8 * 3600}))
+
+			// when
+			res, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, res.RequeueAfter > 7*time.Hour, "requeue after: %v", res.RequeueAfter)
+			assertExists(t, cl, pod)
+			assert.Empty(t, recorder.Events)
+		})
+
+		t.Run("idling disabled for the tier", func(t *testing.T) {
+			// given
+			pod := newPod("standalone", started)
+			r, req, cl, recorder := prepareReconcile(t, newIdler(timeout), pod, newUserNamespace(), newNSTemplateSet("paid"),
+				newConfigWithTierTimeouts(map[string]int32{"paid": 0}))
+
+			// when
+			res, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{}, res)
+			assertExists(t, cl, pod)
+			assert.Empty(t, recorder.Events)
+		})
+
+		t.Run("timeout of the idler when the tier is not configured", func(t *testing.T) {
+			// given
+			pod := newPod("standalone", started)
+			r, req, cl, recorder := prepareReconcile(t, newIdler(timeout), pod, newUserNamespace(), newNSTemplateSet("advanced"),
+				newConfigWithTierTimeouts(map[string]int32{"paid": 0}))
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assertNotFound(t, cl, pod)
+			assert.Len(t, recorder.Events, 1)
+		})
+
+		t.Run("tier enabled for an idler without timeout", func(t *testing.T) {
+			// given
+			pod := newPod("standalone", started)
+			r, req, cl, _ := prepareReconcile(t, newIdler(0), pod, newUserNamespace(), newNSTemplateSet("basic"),
+				newConfigWithTierTimeouts(map[string]int32{"basic": timeout}))
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assertNotFound(t, cl, pod)
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("list pods fails", func(t *testing.T) {
+			// given
+			r, req, cl, _ := prepareReconcile(t, newIdler(timeout))
+			cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+				return errors.New("mock error")
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.EqualError(t, err, "failed to idle the workloads of namespace 'johnsmith-dev': failed to list the pods: mock error")
+			assertConditions(t, cl, toolchainv1alpha1.Condition{
+				Type:    toolchainv1alpha1.ConditionReady,
+				Status:  corev1.ConditionFalse,
+				Reason:  "UnableToIdle",
+				Message: "failed to list the pods: mock error",
+			})
+		})
+
+		t.Run("get namespace fails", func(t *testing.T) {
+			// given
+			r, req, cl, _ := prepareReconcile(t, newIdler(timeout), newUserNamespace())
+			cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+				if _, ok := obj.(*corev1.Namespace); ok {
+					return errors.New("mock error")
+				}
+				return cl.Client.Get(ctx, key, obj)
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.EqualError(t, err, "failed to get the idle timeout of namespace 'johnsmith-dev': failed to get namespace 'johnsmith-dev': mock error")
+		})
+
+		t.Run("scale fails", func(t *testing.T) {
+			// given
+			statefulSet := &appsv1.StatefulSet{ObjectMeta: newObjectMeta("db", nil), Spec: appsv1.StatefulSetSpec{Replicas: replicas(1)}}
+			statefulSetPod := newPodControlledBy("db-0", time.Now().Add(-time.Hour), "StatefulSet", "db")
+			r, req, cl, recorder := prepareReconcile(t, newIdler(timeout), statefulSet, statefulSetPod)
+			cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+				return errors.New("mock error")
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.EqualError(t, err, "failed to idle the workloads of namespace 'johnsmith-dev': failed to scale statefulset 'db': mock error")
+			assertStatefulSetReplicas(t, cl, "db", 1)
+			assert.Empty(t, recorder.Events)
+		})
+	})
+}
+
+func prepareReconcile(t *testing.T, initObjs ...runtime.Object) (*ReconcileIdler, reconcile.Request, *test.FakeClient, *record.FakeRecorder) {
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+	cl := test.NewFakeClient(t, initObjs...)
+	// the configuration is loaded by the MemberOperatorConfig controller
+	require.NoError(t, config.LoadMemberOperatorConfig(cl, operatorNamespace))
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileIdler{
+		// the client of the manager, whose cache only holds the objects of the operator namespace
+		client:      namespacedClient{Client: cl, namespace: operatorNamespace},
+		userObjects: cl,
+		scheme:      s,
+		recorder:    recorder,
+		namespace:   operatorNamespace,
+	}
+	return r, reconcile.Request{NamespacedName: types.NamespacedName{Name: namespace}}, cl, recorder
+}
+
+// namespacedClient a client which only reads the typed namespaced objects of the given namespace, like the client of the manager.
+// The unstructured objects are read from the API server, hence in all the namespaces
+type namespacedClient struct {
+	client.Client
+	namespace string
+}
+
+func (c namespacedClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if _, ok := obj.(*unstructured.Unstructured); !ok && key.Namespace != "" && key.Namespace != c.namespace {
+		return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c namespacedClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if _, ok := list.(*unstructured.UnstructuredList); !ok {
+		if listOpts := (&client.ListOptions{}).ApplyOptions(opts); listOpts.Namespace != "" && listOpts.Namespace != c.namespace {
+			return nil
+		}
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func newIdler(timeoutSeconds int32) *memberv1alpha1.Idler {
+	return &memberv1alpha1.Idler{
+		ObjectMeta: metav1.ObjectMeta{Name: namespace},
+		Spec:       memberv1alpha1.IdlerSpec{TimeoutSeconds: timeoutSeconds},
+	}
+}
+
+func newUserNamespace() *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   namespace,
+			Labels: map[string]string{"owner": username},
+		},
+	}
+}
+
+func newNSTemplateSet(tier string) *toolchainv1alpha1.NSTemplateSet {
+	return &toolchainv1alpha1.NSTemplateSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: username},
+		Spec:       toolchainv1alpha1.NSTemplateSetSpec{TierName: tier},
+	}
+}
+
+func newConfigWithTierTimeouts(timeouts map[string]int32) *memberv1alpha1.MemberOperatorConfig {
+	return &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName},
+		Spec: memberv1alpha1.MemberOperatorConfigSpec{
+			Idler: &memberv1alpha1.IdlerConfig{TierTimeoutsSeconds: timeouts},
+		},
+	}
+}
+
+func newObjectMeta(name string, ownerReferences []metav1.OwnerReference) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:       namespace,
+		Name:            name,
+		OwnerReferences: ownerReferences,
+	}
+}
+
+func controlledBy(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func newPod(name string, startTime time.Time) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: newObjectMeta(name, nil)}
+	if !startTime.IsZero() {
+		pod.Status.StartTime = &metav1.Time{Time: startTime}
+	}
+	return pod
+}
+
+func newPodControlledBy(name string, startTime time.Time, kind, ownerName string) *corev1.Pod {
+	pod := newPod(name, startTime)
+	pod.OwnerReferences = controlledBy(kind, ownerName)
+	return pod
+}
+
+const kubevirtAPIVersion = "kubevirt.io/v1alpha3"
+
+func newVirtualMachine(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	vm := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	vm.SetAPIVersion(kubevirtAPIVersion)
+	vm.SetKind("VirtualMachine")
+	vm.SetNamespace(namespace)
+	vm.SetName(name)
+	return vm
+}
+
+func newVirtualMachineInstance(name string, ownerReferences []metav1.OwnerReference) *unstructured.Unstructured {
+	vmi := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	vmi.SetAPIVersion(kubevirtAPIVersion)
+	vmi.SetKind("VirtualMachineInstance")
+	vmi.SetNamespace(namespace)
+	vmi.SetName(name)
+	for i := range ownerReferences {
+		ownerReferences[i].APIVersion = kubevirtAPIVersion
+	}
+	vmi.SetOwnerReferences(ownerReferences)
+	return vmi
+}
+
+func getVirtualMachine(t *testing.T, cl client.Client, name string) *unstructured.Unstructured {
+	vm := newVirtualMachine("", nil)
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, vm)
+	require.NoError(t, err)
+	return vm
+}
+
+func replicas(value int32) *int32 {
+	return &value
+}
+
+func readyCondition() toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:   toolchainv1alpha1.ConditionReady,
+		Status: corev1.ConditionTrue,
+		Reason: "Running",
+	}
+}
+
+// assertConditions checks the conditions of the Idler, ignoring the message of the Idled condition (which depends on the order
+// in which the pods are listed), and returns the Idled condition
+func assertConditions(t *testing.T, cl client.Client, expected ...toolchainv1alpha1.Condition) toolchainv1alpha1.Condition {
+	idler := &memberv1alpha1.Idler{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Name: namespace}, idler)
+	require.NoError(t, err)
+	idled, _ := condition.FindConditionByType(idler.Status.Conditions, memberv1alpha1.IdlerIdledCondition)
+	actual := make([]toolchainv1alpha1.Condition, 0, len(idler.Status.Conditions))
+	for _, c := range idler.Status.Conditions {
+		if c.Type == memberv1alpha1.IdlerIdledCondition {
+			c.Message = ""
+		}
+		actual = append(actual, c)
+	}
+	test.AssertConditionsMatch(t, actual, expected...)
+	return idled
+}
+
+func assertExists(t *testing.T, cl client.Client, pod *corev1.Pod) {
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &corev1.Pod{})
+	require.NoError(t, err)
+}
+
+func assertNotFound(t *testing.T, cl client.Client, pod *corev1.Pod) {
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &corev1.Pod{})
+	require.Error(t, err)
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func assertDeploymentReplicas(t *testing.T, cl client.Client, name string, expected int32) {
+	deployment := &appsv1.Deployment{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, deployment)
+	require.NoError(t, err)
+	require.NotNil(t, deployment.Spec.Replicas)
+	assert.Equal(t, expected, *deployment.Spec.Replicas)
+}
+
+func assertReplicaSetReplicas(t *testing.T, cl client.Client, name string, expected int32) {
+	replicaSet := &appsv1.ReplicaSet{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, replicaSet)
+	require.NoError(t, err)
+	require.NotNil(t, replicaSet.Spec.Replicas)
+	assert.Equal(t, expected, *replicaSet.Spec.Replicas)
+}
+
+func assertStatefulSetReplicas(t *testing.T, cl client.Client, name string, expected int32) {
+	statefulSet := &appsv1.StatefulSet{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, statefulSet)
+	require.NoError(t, err)
+	require.NotNil(t, statefulSet.Spec.Replicas)
+	assert.Equal(t, expected, *statefulSet.Spec.Replicas)
+}
+
+func assertDeploymentConfigReplicas(t *testing.T, cl client.Client, name string, expected int32) {
+	deploymentConfig := &openshiftappsv1.DeploymentConfig{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, deploymentConfig)
+	require.NoError(t, err)
+	assert.Equal(t, expected, deploymentConfig.Spec.Replicas)
+}
@@ -0,0 +1,175 @@
+package idler
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+
+	"github.com/go-logr/logr"
+	openshiftappsv1 "github.com/openshift/api/apps/v1"
+	errs "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// UnidleAnnotation the annotation which the users set (with any value) on their idled Deployments, DeploymentConfigs, StatefulSets,
+// ReplicaSets and ReplicationControllers to have them scaled up again to the number of replicas they had before they were idled
+// (see `PreviousScaleAnnotation`). The annotation is removed once the workload is unidled.
+const UnidleAnnotation = "toolchain.dev.openshift.com/unidle"
+
+// unidledEventReason the reason of the events recorded on the Idler when it unidled a workload on request
+const unidledEventReason = "Unidled"
+
+// unidleableWorkloads returns the empty lists of the kinds of workloads which can be unidled on request
+func unidleableWorkloads() []runtime.Object {
+	return []runtime.Object{
+		&appsv1.DeploymentList{},
+		&appsv1.ReplicaSetList{},
+		&appsv1.StatefulSetList{},
+		&corev1.ReplicationControllerList{},
+		&openshiftappsv1.DeploymentConfigList{},
+	}
+}
+
+// unidleRequestedWorkloads scales up the workloads of the namespace of the given Idler which have the `UnidleAnnotation`
+func (r *ReconcileIdler) unidleRequestedWorkloads(logger logr.Logger, idler *memberv1alpha1.Idler) error {
+	for _, list := range unidleableWorkloads() {
+		if err := r.userObjects.List(context.TODO(), list, client.InNamespace(idler.Name)); err != nil {
+			return errs.Wrap(err, "failed to list the workloads")
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return errs.Wrap(err, "failed to extract the workloads")
+		}
+		for _, obj := range items {
+			acc, err := meta.Accessor(obj)
+			if err != nil {
+				return err
+			}
+			if _, requested := acc.GetAnnotations()[UnidleAnnotation]; !requested {
+				continue
+			}
+			gvk, err := apiutil.GVKForObject(obj, r.scheme)
+			if err != nil {
+				return errs.Wrapf(err, "failed to get the kind of workload '%s'", acc.GetName())
+			}
+			workload := workloadName(gvk.Kind, acc.GetName())
+			replicas, unidled, err := r.unidle(obj, acc, gvk)
+			if err != nil {
+				return err
+			}
+			if !unidled {
+				logger.Info("unidle request ignored, the workload is not idled", "workload", workload)
+				continue
+			}
+			logger.Info("workload unidled on request", "workload", workload, "replicas", replicas)
+			r.recorder.Eventf(idler, corev1.EventTypeNormal, unidledEventReason, "%s scaled up to %d replicas on request", workload, replicas)
+		}
+	}
+	return nil
+}
+
+// unidle scales the given workload up to its number of replicas before it was idled, if it is still scaled down to zero, and removes
+// the annotations of the idling and of the unidle request. The workload is also removed from the unidle targets of the Services of
+// its namespace. Returns the number of replicas and `true` if the workload was scaled up, `false` if it was not idled
+func (r *ReconcileIdler) unidle(obj runtime.Object, acc metav1.Object, gvk schema.GroupVersionKind) (int32, bool, error) {
+	annotations := acc.GetAnnotations()
+	previous, err := strconv.Atoi(annotations[PreviousScaleAnnotation])
+	current, _ := replicasOf(obj)
+	replicas := int32(previous)
+	unidled := err == nil && replicas > 0 && current == 0
+	if unidled {
+		setReplicas(obj, replicas)
+	}
+	delete(annotations, UnidleAnnotation)
+	delete(annotations, IdledAtAnnotation)
+	delete(annotations, PreviousScaleAnnotation)
+	acc.SetAnnotations(annotations)
+	if err := r.client.Update(context.TODO(), obj); err != nil {
+		return 0, false, errs.Wrapf(err, "failed to unidle %s '%s'", strings.ToLower(gvk.Kind), acc.GetName())
+	}
+	if err := r.removeUnidleTarget(acc.GetNamespace(), unidleTarget{Kind: gvk.Kind, Name: acc.GetName(), Group: gvk.Group}); err != nil {
+		return 0, false, err
+	}
+	return replicas, unidled, nil
+}
+
+// setReplicas sets the number of replicas of the given workload
+func setReplicas(obj runtime.Object, replicas int32) {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		workload.Spec.Replicas = &replicas
+	case *appsv1.ReplicaSet:
+		workload.Spec.Replicas = &replicas
+	case *appsv1.StatefulSet:
+		workload.Spec.Replicas = &replicas
+	case *corev1.ReplicationController:
+		workload.Spec.Replicas = &replicas
+	case *openshiftappsv1.DeploymentConfig:
+		workload.Spec.Replicas = replicas
+	}
+}
+
+// removeUnidleTarget removes the given workload from the unidle targets of the Services and Endpoints of the given namespace,
+// so that OpenShift does not try to scale it up again when they receive traffic. The idling annotations are removed from the
+// Services and Endpoints which have no unidle targets left
+func (r *ReconcileIdler) removeUnidleTarget(namespace string, target unidleTarget) error {
+	for _, list := range []runtime.Object{&corev1.ServiceList{}, &corev1.EndpointsList{}} {
+		if err := r.userObjects.List(context.TODO(), list, client.InNamespace(namespace)); err != nil {
+			return errs.Wrap(err, "failed to list the services")
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return errs.Wrap(err, "failed to extract the services")
+		}
+		for _, obj := range items {
+			acc, err := meta.Accessor(obj)
+			if err != nil {
+				return err
+			}
+			annotations := acc.GetAnnotations()
+			content, found := annotations[UnidleTargetsAnnotation]
+			if !found {
+				continue
+			}
+			var targets []unidleTarget
+			if err := json.Unmarshal([]byte(content), &targets); err != nil {
+				// left to OpenShift, which removes the annotation when the service receives traffic
+				continue
+			}
+			remaining := make([]unidleTarget, 0, len(targets))
+			for _, t := range targets {
+				if t.Kind != target.Kind || t.Name != target.Name || t.Group != target.Group {
+					remaining = append(remaining, t)
+				}
+			}
+			if len(remaining) == len(targets) {
+				continue
+			}
+			if len(remaining) == 0 {
+				delete(annotations, UnidleTargetsAnnotation)
+				delete(annotations, IdledAtAnnotation)
+			} else {
+				content, err := json.Marshal(remaining)
+				if err != nil {
+					return err
+				}
+				annotations[UnidleTargetsAnnotation] = string(content)
+			}
+			acc.SetAnnotations(annotations)
+			if err := r.client.Update(context.TODO(), obj); err != nil {
+				return errs.Wrapf(err, "failed to remove the unidle target of '%s'", acc.GetName())
+			}
+		}
+	}
+	return nil
+}
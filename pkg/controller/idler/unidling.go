@@ -0,0 +1,153 @@
+package idler
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	openshiftappsv1 "github.com/openshift/api/apps/v1"
+	errs "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// The annotations set by `oc idle`, which let OpenShift scale the idled workloads up again as soon as their Services receive traffic
+const (
+	// IdledAtAnnotation the annotation of the idled workloads, Services and Endpoints which holds the time at which they were idled
+	IdledAtAnnotation = "idling.alpha.openshift.io/idled-at"
+	// UnidleTargetsAnnotation the annotation of the idled Services and Endpoints which holds the workloads to scale up when they receive traffic
+	UnidleTargetsAnnotation = "idling.alpha.openshift.io/unidle-targets"
+	// PreviousScaleAnnotation the annotation of the idled workloads which holds their number of replicas before they were idled
+	PreviousScaleAnnotation = "idling.alpha.openshift.io/previous-scale"
+)
+
+// unidleTarget a workload to scale up when an idled Service receives traffic, in the format expected by OpenShift
+type unidleTarget struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Group    string `json:"group,omitempty"`
+	Replicas int32  `json:"replicas"`
+}
+
+// replicasOf returns the number of replicas of the given workload, or `false` if it cannot be scaled up again by OpenShift
+// (eg, a VirtualMachine)
+func replicasOf(obj runtime.Object) (int32, bool) {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		return replicasOrDefault(workload.Spec.Replicas), true
+	case *appsv1.ReplicaSet:
+		return replicasOrDefault(workload.Spec.Replicas), true
+	case *appsv1.StatefulSet:
+		return replicasOrDefault(workload.Spec.Replicas), true
+	case *corev1.ReplicationController:
+		return replicasOrDefault(workload.Spec.Replicas), true
+	case *openshiftappsv1.DeploymentConfig:
+		return workload.Spec.Replicas, true
+	}
+	return 0, false
+}
+
+// replicasOrDefault returns the given number of replicas, or 1 if it is not set (ie, the default number of replicas of the workloads)
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// markIdled sets the annotations of the given workload with the time at which it was idled and its number of replicas at that time
+func markIdled(obj runtime.Object, replicas int32, idledAt time.Time) error {
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	annotations := acc.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[IdledAtAnnotation] = idledAt.UTC().Format(time.RFC3339)
+	annotations[PreviousScaleAnnotation] = strconv.Itoa(int(replicas))
+	acc.SetAnnotations(annotations)
+	return nil
+}
+
+// recordUnidleTarget adds the given idled workload to the unidle targets of the Services (and of their Endpoints) which select the given pod,
+// so that OpenShift scales the workload up again to the given number of replicas as soon as one of these Services receives traffic.
+// The pods created at that time are then idled again once the idle timeout expires.
+func (r *ReconcileIdler) recordUnidleTarget(pod *corev1.Pod, obj runtime.Object, kind, name string, replicas int32, idledAt time.Time) error {
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return errs.Wrapf(err, "failed to get the group of %s '%s'", kind, name)
+	}
+	target := unidleTarget{Kind: kind, Name: name, Group: gvk.Group, Replicas: replicas}
+	services := &corev1.ServiceList{}
+	if err := r.userObjects.List(context.TODO(), services, client.InNamespace(pod.Namespace)); err != nil {
+		return errs.Wrap(err, "failed to list the services")
+	}
+	for i := range services.Items {
+		service := &services.Items[i]
+		if len(service.Spec.Selector) == 0 || !labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if err := r.addUnidleTarget(service, target, idledAt); err != nil {
+			return errs.Wrapf(err, "failed to record the unidle target of service '%s'", service.Name)
+		}
+		endpoints := &corev1.Endpoints{}
+		if err := r.userObjects.Get(context.TODO(), types.NamespacedName{Namespace: service.Namespace, Name: service.Name}, endpoints); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return errs.Wrapf(err, "failed to get the endpoints of service '%s'", service.Name)
+		}
+		if err := r.addUnidleTarget(endpoints, target, idledAt); err != nil {
+			return errs.Wrapf(err, "failed to record the unidle target of the endpoints of service '%s'", service.Name)
+		}
+	}
+	return nil
+}
+
+// addUnidleTarget adds the given target to the unidle targets of the given object (replacing the previous entry of the same workload, if any),
+// and updates the object
+func (r *ReconcileIdler) addUnidleTarget(obj runtime.Object, target unidleTarget, idledAt time.Time) error {
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	annotations := acc.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	var targets []unidleTarget
+	if content, found := annotations[UnidleTargetsAnnotation]; found {
+		if err := json.Unmarshal([]byte(content), &targets); err != nil {
+			// start over rather than being stuck with a corrupted annotation
+			targets = nil
+		}
+	}
+	replaced := false
+	for i, t := range targets {
+		if t.Kind == target.Kind && t.Name == target.Name && t.Group == target.Group {
+			targets[i] = target
+			replaced = true
+		}
+	}
+	if !replaced {
+		targets = append(targets, target)
+	}
+	content, err := json.Marshal(targets)
+	if err != nil {
+		return err
+	}
+	annotations[UnidleTargetsAnnotation] = string(content)
+	annotations[IdledAtAnnotation] = idledAt.UTC().Format(time.RFC3339)
+	acc.SetAnnotations(annotations)
+	return r.client.Update(context.TODO(), obj)
+}
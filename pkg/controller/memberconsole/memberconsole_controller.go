@@ -0,0 +1,197 @@
+package memberconsole
+
+import (
+	"context"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+	"github.com/codeready-toolchain/member-operator/pkg/rollout"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/member-operator/version"
+
+	"github.com/go-logr/logr"
+	templatev1 "github.com/openshift/api/template/v1"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	sdkpredicate "github.com/operator-framework/operator-sdk/pkg/predicate"
+	errs "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_memberconsole")
+
+// kinds the kinds of the objects of the member web console, in the reverse order of their creation
+var kinds = []schema.GroupVersionKind{
+	{Group: "route.openshift.io", Version: "v1", Kind: "Route"},
+	{Group: "", Version: "v1", Kind: "Service"},
+	{Group: "apps", Version: "v1", Kind: "Deployment"},
+	{Group: "", Version: "v1", Kind: "ServiceAccount"},
+}
+
+// Add creates a new MemberConsole Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	return add(mgr, newReconciler(mgr, namespace), namespace)
+}
+
+func newReconciler(mgr manager.Manager, namespace string) reconcile.Reconciler {
+	return &ReconcileMemberConsole{
+		client:    config.ControllerClient("memberconsole", mgr.GetClient()),
+		scheme:    mgr.GetScheme(),
+		namespace: namespace,
+	}
+}
+
+func add(mgr manager.Manager, r reconcile.Reconciler, namespace string) error {
+	c, err := controller.New("memberconsole-controller", mgr, config.ControllerOptions("memberconsole", r))
+	if err != nil {
+		return err
+	}
+	// Watch for changes to the MemberOperatorConfig, whose feature gates enable or disable the member web console
+	enqueueConfig := &handler.EnqueueRequestsFromMapFunc{ToRequests: toConfig(namespace)}
+	if err := c.Watch(&source.Kind{Type: &memberv1alpha1.MemberOperatorConfig{}}, enqueueConfig, sdkpredicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+	// Watch for changes to the objects of the member web console, so that they are restored if they are changed or deleted
+	enqueueConfigOfConsole := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(func(obj handler.MapObject) []reconcile.Request {
+		if obj.Meta.GetNamespace() != namespace || obj.Meta.GetName() != memberv1alpha1.MemberConsoleName {
+			return nil
+		}
+		return toConfig(namespace)(obj)
+	})}
+	if err := c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, enqueueConfigOfConsole, sdkpredicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+	if err := c.Watch(&source.Kind{Type: &corev1.Service{}}, enqueueConfigOfConsole); err != nil {
+		return err
+	}
+	return c.Watch(&source.Kind{Type: &corev1.ServiceAccount{}}, enqueueConfigOfConsole)
+}
+
+// toConfig returns a mapper which maps all the objects to the MemberOperatorConfig of the given namespace
+func toConfig(namespace string) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		return []reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: namespace, Name: memberv1alpha1.MemberOperatorConfigName}},
+		}
+	}
+}
+
+var _ reconcile.Reconciler = &ReconcileMemberConsole{}
+
+// ReconcileMemberConsole deploys the member web console in the operator namespace, with the image of the same release as the operator
+type ReconcileMemberConsole struct {
+	client    client.Client
+	scheme    *runtime.Scheme
+	namespace string
+}
+
+// Reconcile applies the objects of the member web console if the `MemberConsole` feature is enabled in the MemberOperatorConfig,
+// or deletes them otherwise. The rollout of the Deployment and the Route of the console is reported in the `MemberConsoleReady` condition
+// of the MemberOperatorConfig, and checked again until it is complete
+func (r *ReconcileMemberConsole) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := logging.ForRequest(log, request)
+
+	if !config.FeatureEnabled(config.MemberConsole) {
+		if err := r.deleteConsole(reqLogger); err != nil {
+			return reconcile.Result{}, err
+		}
+		_, err := rollout.UpdateConfigStatus(r.client, r.namespace, memberv1alpha1.MemberConsoleReadyCondition, nil, config.GetRolloutTimeout())
+		return reconcile.Result{}, err
+	}
+
+	mirrors := template.ImageMirrors(config.GetImageMirrors())
+	processor := template.NewProcessor(r.client, r.scheme).WithMutators(mirrors).WithLogger(reqLogger.WithName("template"))
+	objs, err := r.consoleObjects(processor)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := processor.Apply(objs); err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "failed to apply the member console")
+	}
+	reqLogger.Info("member console applied", "image", mirrors.Rewrite(config.GetMemberConsoleImage()))
+
+	progress, err := r.consoleProgress()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	requeueAfter, err := rollout.UpdateConfigStatus(r.client, r.namespace, memberv1alpha1.MemberConsoleReadyCondition, &progress, config.GetRolloutTimeout())
+	return reconcile.Result{RequeueAfter: requeueAfter}, err
+}
+
+// consoleProgress returns the progress of the rollout of the Deployment and the Route (if any, since it is not served by all the clusters)
+// of the member web console
+func (r *ReconcileMemberConsole) consoleProgress() (rollout.Progress, error) {
+	name := types.NamespacedName{Namespace: r.namespace, Name: memberv1alpha1.MemberConsoleName}
+	deployment := &appsv1.Deployment{}
+	if err := r.client.Get(context.TODO(), name, deployment); err != nil {
+		return rollout.Progress{}, errs.Wrap(err, "failed to get the deployment of the member console")
+	}
+	progresses := map[string]rollout.Progress{"deployment": rollout.DeploymentProgress(deployment)}
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(kinds[0])
+	if err := r.client.Get(context.TODO(), name, route); err != nil {
+		if !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return rollout.Progress{}, errs.Wrap(err, "failed to get the route of the member console")
+		}
+	} else {
+		progresses["route"] = rollout.RouteProgress(route)
+	}
+	return rollout.Combine(progresses), nil
+}
+
+// consoleObjects returns the objects of the member web console, rendered with the given processor
+func (r *ReconcileMemberConsole) consoleObjects(processor template.Processor) ([]runtime.RawExtension, error) {
+	tmpl := &templatev1.Template{}
+	decoder := serializer.NewCodecFactory(r.scheme).UniversalDeserializer()
+	if _, _, err := decoder.Decode([]byte(memberConsoleTemplate), nil, tmpl); err != nil {
+		return nil, errs.Wrap(err, "unable to decode the template of the member console")
+	}
+	objs, err := processor.Process(tmpl, map[string]string{
+		"NAME":      memberv1alpha1.MemberConsoleName,
+		"NAMESPACE": r.namespace,
+		"IMAGE":     config.GetMemberConsoleImage(),
+		"VERSION":   version.Version,
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, "unable to process the template of the member console")
+	}
+	return objs, nil
+}
+
+// deleteConsole deletes the objects of the member web console, if they exist
+func (r *ReconcileMemberConsole) deleteConsole(logger logr.Logger) error {
+	for _, gvk := range kinds {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		obj.SetNamespace(r.namespace)
+		obj.SetName(memberv1alpha1.MemberConsoleName)
+		if err := r.client.Delete(context.TODO(), obj); err != nil {
+			if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return errs.Wrapf(err, "failed to delete the %s of the member console", gvk.Kind)
+		}
+		logger.Info("member console object deleted", "kind", gvk.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package memberconsole
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/rollout"
+	"github.com/codeready-toolchain/member-operator/version"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const operatorNamespace = "toolchain-member-operator"
+
+func TestReconcile(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+
+	t.Run("member console deployed", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newConfig(true))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: rollout.CheckPeriod}, res)
+		assertConsoleCondition(t, cl, corev1.ConditionFalse, rollout.InProgressReason,
+			"deployment: 0 out of 1 replicas are updated; route: the route is not admitted by a router yet")
+		deployment := &appsv1.Deployment{}
+		err = cl.Get(context.TODO(), consoleName(), deployment)
+		require.NoError(t, err)
+		assert.Equal(t, version.Version, deployment.Labels["toolchain.dev.openshift.com/version"])
+		require.Len(t, deployment.Spec.Template.Spec.Containers, 1)
+		assert.Equal(t, config.GetMemberConsoleImage(), deployment.Spec.Template.Spec.Containers[0].Image)
+		assert.Equal(t, memberv1alpha1.MemberConsoleName, deployment.Spec.Template.Spec.ServiceAccountName)
+		err = cl.Get(context.TODO(), consoleName(), &corev1.ServiceAccount{})
+		require.NoError(t, err)
+		err = cl.Get(context.TODO(), consoleName(), &corev1.Service{})
+		require.NoError(t, err)
+		route := newRoute()
+		err = cl.Get(context.TODO(), consoleName(), route)
+		require.NoError(t, err)
+		service, _, _ := unstructured.NestedString(route.Object, "spec", "to", "name")
+		assert.Equal(t, memberv1alpha1.MemberConsoleName, service)
+
+		t.Run("changed deployment restored", func(t *testing.T) {
+			// given
+			deployment.Spec.Template.Spec.Containers[0].Image = "quay.io/someone/member-console:latest"
+			err := cl.Update(context.TODO(), deployment)
+			require.NoError(t, err)
+
+			// when
+			_, err = r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			err = cl.Get(context.TODO(), consoleName(), deployment)
+			require.NoError(t, err)
+			assert.Equal(t, config.GetMemberConsoleImage(), deployment.Spec.Template.Spec.Containers[0].Image)
+		})
+
+		t.Run("member console ready once rolled out", func(t *testing.T) {
+			// given
+			cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+				if err := cl.Client.Get(ctx, key, obj); err != nil {
+					return err
+				}
+				return setRolledOut(obj)
+			}
+			defer func() { cl.MockGet = nil }()
+
+			// when
+			res, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{}, res)
+			assertConsoleCondition(t, cl, corev1.ConditionTrue, rollout.CompleteReason, "")
+		})
+
+		t.Run("member console deleted when disabled", func(t *testing.T) {
+			// given
+			cfg := &memberv1alpha1.MemberOperatorConfig{}
+			err := cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName}, cfg)
+			require.NoError(t, err)
+			cfg.Spec.FeatureGates[string(config.MemberConsole)] = false
+			err = cl.Update(context.TODO(), cfg)
+			require.NoError(t, err)
+			require.NoError(t, config.LoadMemberOperatorConfig(cl, operatorNamespace))
+
+			// when
+			_, err = r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assertNoConsole(t, cl)
+			err = cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName}, cfg)
+			require.NoError(t, err)
+			assert.Empty(t, cfg.Status.Conditions)
+		})
+	})
+
+	t.Run("member console rollout timed out", func(t *testing.T) {
+		// given
+		cfg := newConfig(true)
+		cfg.Status.Conditions = []toolchainv1alpha1.Condition{{
+			Type:               memberv1alpha1.MemberConsoleReadyCondition,
+			Status:             corev1.ConditionFalse,
+			Reason:             rollout.InProgressReason,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-config.GetRolloutTimeout() - time.Minute)),
+		}}
+		r, req, cl := prepareReconcile(t, cfg)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: rollout.CheckPeriod}, res)
+		assertConsoleCondition(t, cl, corev1.ConditionFalse, rollout.TimedOutReason,
+			"deployment: 0 out of 1 replicas are updated; route: the route is not admitted by a router yet")
+	})
+
+	t.Run("member console image pulled from its mirror", func(t *testing.T) {
+		// given
+		cfg := newConfig(true)
+		cfg.Spec.ImageMirrors = &memberv1alpha1.ImageMirrorsConfig{
+			Mirrors: []memberv1alpha1.ImageMirror{{Source: "quay.io/codeready-toolchain", Mirror: "registry.example.com:5000/toolchain"}},
+		}
+		r, req, cl := prepareReconcile(t, cfg)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		deployment := &appsv1.Deployment{}
+		err = cl.Get(context.TODO(), consoleName(), deployment)
+		require.NoError(t, err)
+		require.Len(t, deployment.Spec.Template.Spec.Containers, 1)
+		assert.Equal(t, "registry.example.com:5000/toolchain/member-console:"+version.Commit, deployment.Spec.Template.Spec.Containers[0].Image)
+	})
+
+	t.Run("member console not deployed by default", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertNoConsole(t, cl)
+	})
+
+	t.Run("apply fails", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newConfig(true))
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("mock error")
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to apply the member console")
+	})
+}
+
+func prepareReconcile(t *testing.T, initObjs ...runtime.Object) (*ReconcileMemberConsole, reconcile.Request, *test.FakeClient) {
+	cl := test.NewFakeClient(t, initObjs...)
+	// the configuration is loaded by the MemberOperatorConfig controller
+	require.NoError(t, config.LoadMemberOperatorConfig(cl, operatorNamespace))
+	r := &ReconcileMemberConsole{
+		client:    cl,
+		scheme:    scheme.Scheme,
+		namespace: operatorNamespace,
+	}
+	return r, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName}}, cl
+}
+
+func newConfig(enabled bool) *memberv1alpha1.MemberOperatorConfig {
+	return &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName},
+		Spec: memberv1alpha1.MemberOperatorConfigSpec{
+			FeatureGates: map[string]bool{string(config.MemberConsole): enabled},
+		},
+	}
+}
+
+func newRoute() *unstructured.Unstructured {
+	route := &unstructured.Unstructured{}
+	route.SetAPIVersion("route.openshift.io/v1")
+	route.SetKind("Route")
+	return route
+}
+
+func consoleName() types.NamespacedName {
+	return types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberConsoleName}
+}
+
+func assertNoConsole(t *testing.T, cl client.Client) {
+	for _, obj := range []runtime.Object{&appsv1.Deployment{}, &corev1.Service{}, &corev1.ServiceAccount{}, newRoute()} {
+		err := cl.Get(context.TODO(), consoleName(), obj)
+		require.Error(t, err)
+		assert.True(t, apierrors.IsNotFound(err))
+	}
+}
+
+func assertConsoleCondition(t *testing.T, cl client.Client, status corev1.ConditionStatus, reason, message string) {
+	cfg := &memberv1alpha1.MemberOperatorConfig{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName}, cfg)
+	require.NoError(t, err)
+	cond, found := condition.FindConditionByType(cfg.Status.Conditions, memberv1alpha1.MemberConsoleReadyCondition)
+	require.True(t, found)
+	assert.Equal(t, status, cond.Status)
+	assert.Equal(t, reason, cond.Reason)
+	assert.Equal(t, message, cond.Message)
+}
+
+// setRolledOut sets the status of the given Deployment or Route as if it was rolled out
+func setRolledOut(obj runtime.Object) error {
+	switch obj := obj.(type) {
+	case *appsv1.Deployment:
+		obj.Status.ObservedGeneration = obj.Generation
+		obj.Status.UpdatedReplicas = 1
+		obj.Status.AvailableReplicas = 1
+	case *unstructured.Unstructured:
+		if obj.GetKind() != "Route" {
+			return nil
+		}
+		return unstructured.SetNestedSlice(obj.Object, []interface{}{
+			map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Admitted", "status": "True"},
+				},
+			},
+		}, "status", "ingress")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package memberconsole
+
+// memberConsoleTemplate the template of the member web console deployed in the operator namespace: its ServiceAccount, its Deployment,
+// the Service in front of its pods and the Route which exposes it outside of the cluster.
+// The objects are labelled with the version of the operator, which the image of the console is pinned to.
+const memberConsoleTemplate = `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: member-console
+objects:
+- apiVersion: v1
+  kind: ServiceAccount
+  metadata:
+    name: ${NAME}
+    namespace: ${NAMESPACE}
+    labels:
+      app: ${NAME}
+      provider: codeready-toolchain
+      toolchain.dev.openshift.com/version: ${VERSION}
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: ${NAME}
+    namespace: ${NAMESPACE}
+    labels:
+      app: ${NAME}
+      provider: codeready-toolchain
+      toolchain.dev.openshift.com/version: ${VERSION}
+  spec:
+    replicas: 1
+    selector:
+      matchLabels:
+        app: ${NAME}
+    template:
+      metadata:
+        labels:
+          app: ${NAME}
+          provider: codeready-toolchain
+          toolchain.dev.openshift.com/version: ${VERSION}
+      spec:
+        serviceAccountName: ${NAME}
+        containers:
+        - name: console
+          image: ${IMAGE}
+          imagePullPolicy: IfNotPresent
+          ports:
+          - name: http
+            containerPort: 8080
+            protocol: TCP
+          env:
+          - name: WATCH_NAMESPACE
+            value: ${NAMESPACE}
+          resources:
+            requests:
+              cpu: 50m
+              memory: 64Mi
+            limits:
+              cpu: 500m
+              memory: 256Mi
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: ${NAME}
+    namespace: ${NAMESPACE}
+    labels:
+      app: ${NAME}
+      provider: codeready-toolchain
+      toolchain.dev.openshift.com/version: ${VERSION}
+  spec:
+    selector:
+      app: ${NAME}
+    ports:
+    - name: http
+      port: 80
+      protocol: TCP
+      targetPort: http
+- apiVersion: route.openshift.io/v1
+  kind: Route
+  metadata:
+    name: ${NAME}
+    namespace: ${NAMESPACE}
+    labels:
+      app: ${NAME}
+      provider: codeready-toolchain
+      toolchain.dev.openshift.com/version: ${VERSION}
+  spec:
+    to:
+      kind: Service
+      name: ${NAME}
+    port:
+      targetPort: http
+    tls:
+      termination: edge
+      insecureEdgeTerminationPolicy: Redirect
+parameters:
+- name: NAME
+  required: true
+- name: NAMESPACE
+  required: true
+- name: IMAGE
+  required: true
+- name: VERSION
+  required: true
+`
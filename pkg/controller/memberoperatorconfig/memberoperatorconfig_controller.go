@@ -0,0 +1,72 @@
+package memberoperatorconfig
+
+import (
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/leadership"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+
+	"github.com/operator-framework/operator-sdk/pkg/predicate"
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_memberoperatorconfig")
+
+// Add creates a new MemberOperatorConfig Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileMemberOperatorConfig{
+		client: config.ControllerClient("memberoperatorconfig", mgr.GetClient()),
+		scheme: mgr.GetScheme(),
+	}
+}
+
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// the configuration is reloaded on all the replicas, since the standby replicas serve the webhooks
+	c, err := controller.New("memberoperatorconfig-controller", leadership.OnAllReplicas(mgr), config.ControllerOptions("memberoperatorconfig", r))
+	if err != nil {
+		return err
+	}
+	// Watch for changes to primary resource MemberOperatorConfig
+	return c.Watch(&source.Kind{Type: &memberv1alpha1.MemberOperatorConfig{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{})
+}
+
+var _ reconcile.Reconciler = &ReconcileMemberOperatorConfig{}
+
+// ReconcileMemberOperatorConfig reloads the configuration of the operator when the MemberOperatorConfig changes. It is the only
+// reconciler which loads the configuration: the others read the last loaded one
+type ReconcileMemberOperatorConfig struct {
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile reloads the configuration of the operator from the MemberOperatorConfig, so that the changes are applied
+// without restarting the operator (including the levels of the logs). The default values are restored when the MemberOperatorConfig is deleted.
+func (r *ReconcileMemberOperatorConfig) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := logging.ForRequest(log, request)
+	if request.Name != memberv1alpha1.MemberOperatorConfigName {
+		reqLogger.Info("ignoring the MemberOperatorConfig", "expected_name", memberv1alpha1.MemberOperatorConfigName)
+		return reconcile.Result{}, nil
+	}
+	if err := config.LoadMemberOperatorConfig(r.client, request.Namespace); err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "failed to reload the configuration")
+	}
+	if err := logging.Configure(config.GetLogging()); err != nil {
+		// not retried, since the MemberOperatorConfig needs to be fixed anyway
+		reqLogger.Error(err, "the levels of the logs are left unchanged")
+	}
+	reqLogger.Info("configuration reloaded", "identity_provider", config.GetIdP())
+	return reconcile.Result{}, nil
+}
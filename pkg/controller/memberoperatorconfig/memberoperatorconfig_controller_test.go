@@ -0,0 +1,145 @@
+package memberoperatorconfig
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const operatorNamespace = "toolchain-member-operator"
+
+func TestReconcile(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, config.LoadMemberOperatorConfig(test.NewFakeClient(t), operatorNamespace))
+	}()
+
+	t.Run("config reloaded", func(t *testing.T) {
+		// given
+		cfg := newMemberOperatorConfig(memberv1alpha1.MemberOperatorConfigName)
+		r, cl := prepareReconcile(t, cfg)
+
+		// when
+		res, err := r.Reconcile(newRequest(memberv1alpha1.MemberOperatorConfigName))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		assert.Equal(t, "sso", config.GetIdP())
+		assert.Equal(t, int32(2), config.GetAutoscaler().BufferReplicas)
+
+		t.Run("defaults restored when config deleted", func(t *testing.T) {
+			// given
+			err := cl.Delete(context.TODO(), cfg)
+			require.NoError(t, err)
+
+			// when
+			_, err = r.Reconcile(newRequest(memberv1alpha1.MemberOperatorConfigName))
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, config.DefaultIdP, config.GetIdP())
+			assert.Equal(t, int32(0), config.GetAutoscaler().BufferReplicas)
+		})
+	})
+
+	t.Run("levels of the logs changed", func(t *testing.T) {
+		// given
+		defer func() {
+			require.NoError(t, logging.Configure(memberv1alpha1.LoggingConfig{}))
+		}()
+		logger := logging.NewLogger(logf.ZapLogger(true))
+		cfg := newMemberOperatorConfig(memberv1alpha1.MemberOperatorConfigName)
+		cfg.Spec.Logging = &memberv1alpha1.LoggingConfig{Level: "error", Loggers: map[string]string{"controller_nstemplateset": "debug"}}
+		r, _ := prepareReconcile(t, cfg)
+
+		// when
+		_, err := r.Reconcile(newRequest(memberv1alpha1.MemberOperatorConfigName))
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, logger.Enabled())
+		assert.True(t, logger.WithName("controller_nstemplateset").V(1).Enabled())
+
+		t.Run("invalid level ignored", func(t *testing.T) {
+			// given
+			cfg.Spec.Logging = &memberv1alpha1.LoggingConfig{Level: "verbose"}
+			r, _ := prepareReconcile(t, cfg)
+
+			// when
+			_, err := r.Reconcile(newRequest(memberv1alpha1.MemberOperatorConfigName))
+
+			// then the previous levels are left unchanged
+			require.NoError(t, err)
+			assert.Equal(t, "verbose", config.GetLogging().Level)
+			assert.False(t, logger.Enabled())
+		})
+	})
+
+	t.Run("other config ignored", func(t *testing.T) {
+		// given
+		r, _ := prepareReconcile(t, newMemberOperatorConfig("other"))
+
+		// when
+		_, err := r.Reconcile(newRequest("other"))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, config.DefaultIdP, config.GetIdP())
+	})
+
+	t.Run("reload failed", func(t *testing.T) {
+		// given
+		r, cl := prepareReconcile(t, newMemberOperatorConfig(memberv1alpha1.MemberOperatorConfigName))
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			return errors.New("mock error")
+		}
+
+		// when
+		_, err := r.Reconcile(newRequest(memberv1alpha1.MemberOperatorConfigName))
+
+		// then
+		require.EqualError(t, err, "failed to reload the configuration: failed to load the MemberOperatorConfig: mock error")
+	})
+}
+
+func prepareReconcile(t *testing.T, initObjs ...runtime.Object) (*ReconcileMemberOperatorConfig, *test.FakeClient) {
+	cl := test.NewFakeClient(t, initObjs...)
+	return &ReconcileMemberOperatorConfig{client: cl, scheme: scheme.Scheme}, cl
+}
+
+func newRequest(name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: operatorNamespace, Name: name}}
+}
+
+func newMemberOperatorConfig(name string) *memberv1alpha1.MemberOperatorConfig {
+	return &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: name},
+		Spec: memberv1alpha1.MemberOperatorConfigSpec{
+			IdentityProvider: "sso",
+			Autoscaler: &memberv1alpha1.AutoscalerConfig{
+				BufferMemory:   "1Gi",
+				BufferReplicas: 2,
+			},
+		},
+	}
+}
@@ -0,0 +1,274 @@
+package memberstatus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/conditions"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/autoscaler"
+	"github.com/codeready-toolchain/member-operator/pkg/metrics"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	errs "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+// The names of the components checked by default
+const (
+	HostConnectionComponent = "hostConnection"
+	WebhookComponent        = "webhook"
+	AutoscalerComponent     = "autoscaler"
+	IdlerComponent          = "idler"
+	APIThrottlingComponent  = "apiThrottling"
+)
+
+// The reasons of the health of the components and of the Ready condition of the MemberStatus
+const (
+	healthyReason            = "Healthy"
+	checkFailedReason        = "CheckFailed"
+	hostNotConnectedReason   = "HostNotConnected"
+	hostNotReadyReason       = "HostNotReady"
+	webhookUnreachableReason = "WebhookUnreachable"
+	bufferNotReadyReason     = "BufferNotReady"
+	idlersFailingReason      = "IdlersFailing"
+	apiThrottledReason       = "APIThrottled"
+
+	allComponentsReadyReason = "AllComponentsReady"
+	componentsNotReadyReason = "ComponentsNotReady"
+)
+
+const (
+	// webhookServiceName the name of the service of the webhooks of the member operator
+	webhookServiceName = "member-operator-webhook"
+	// maxThrottledPercent the maximum percentage of the requests sent to the API server between two checks which can be throttled
+	// before the API throttling is reported as unhealthy
+	maxThrottledPercent = 10
+)
+
+// ComponentCheck a health check of a component of the member cluster. The check returns nil if the component is healthy,
+// or an error whose message explains why it is not (see `Unhealthy` to also specify the reason)
+type ComponentCheck struct {
+	Name  string
+	Check func(cl client.Client, namespace string) error
+}
+
+var (
+	checksLock  sync.RWMutex
+	extraChecks []ComponentCheck
+)
+
+// RegisterComponentCheck registers a health check which is run along with the default checks at every refresh of the MemberStatus.
+// It must be called before the controller is added to the manager
+func RegisterComponentCheck(check ComponentCheck) {
+	checksLock.Lock()
+	defer checksLock.Unlock()
+	extraChecks = append(extraChecks, check)
+}
+
+// defaultChecks returns the checks of the components of the member cluster, followed by the registered checks
+func defaultChecks() []ComponentCheck {
+	checksLock.RLock()
+	defer checksLock.RUnlock()
+	checks := []ComponentCheck{
+		{Name: HostConnectionComponent, Check: hostConnectionCheck(cluster.GetHostCluster)},
+		{Name: WebhookComponent, Check: checkWebhook},
+		{Name: AutoscalerComponent, Check: checkAutoscaler},
+		{Name: IdlerComponent, Check: checkIdlers},
+		{Name: APIThrottlingComponent, Check: (&throttlingCheck{apiRequests: metrics.APIRequests}).check},
+	}
+	return append(checks, extraChecks...)
+}
+
+// componentError the error returned by a check when a component is unhealthy, along with the reason reported in its health
+type componentError struct {
+	reason  string
+	message string
+}
+
+func (e componentError) Error() string {
+	return e.message
+}
+
+// Unhealthy returns an error reporting that a component is unhealthy for the given reason
+func Unhealthy(reason, format string, args ...interface{}) error {
+	return componentError{reason: reason, message: fmt.Sprintf(format, args...)}
+}
+
+// componentsHealth runs the given checks and returns the health of each component
+func (r *ReconcileMemberStatus) componentsHealth(namespace string) []memberv1alpha1.ComponentHealth {
+	components := make([]memberv1alpha1.ComponentHealth, 0, len(r.checks))
+	for _, check := range r.checks {
+		health := memberv1alpha1.ComponentHealth{
+			Name:    check.Name,
+			Healthy: true,
+			Reason:  healthyReason,
+		}
+		if err := check.Check(r.client, namespace); err != nil {
+			health.Healthy = false
+			health.Reason = checkFailedReason
+			if cerr, ok := errs.Cause(err).(componentError); ok {
+				health.Reason = cerr.reason
+			}
+			health.Message = err.Error()
+		}
+		components = append(components, health)
+	}
+	return components
+}
+
+// readyCondition returns the Ready condition rolled up from the health of the given components
+func readyCondition(components []memberv1alpha1.ComponentHealth) toolchainv1alpha1.Condition {
+	var unhealthy []string
+	for _, component := range components {
+		if !component.Healthy {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s: %s", component.Name, component.Message))
+		}
+	}
+	if len(unhealthy) > 0 {
+		return toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  componentsNotReadyReason,
+			Message: strings.Join(unhealthy, "; "),
+		}
+	}
+	return toolchainv1alpha1.Condition{
+		Type:   toolchainv1alpha1.ConditionReady,
+		Status: corev1.ConditionTrue,
+		Reason: allComponentsReadyReason,
+	}
+}
+
+// setReady sets the health of the given components and the Ready condition rolled up from them in the status of the given MemberStatus
+func setReady(memberStatus *memberv1alpha1.MemberStatus, components []memberv1alpha1.ComponentHealth) {
+	memberStatus.Status.Components = components
+	memberStatus.Status.Conditions, _ = conditions.Update(memberStatus.Status.Conditions, readyCondition(components))
+}
+
+// hostConnectionCheck returns a check which verifies that a host cluster is registered and ready
+func hostConnectionCheck(getHostCluster func() (*cluster.FedCluster, bool)) func(client.Client, string) error {
+	return func(_ client.Client, _ string) error {
+		fedCluster, ok := getHostCluster()
+		if !ok {
+			return Unhealthy(hostNotConnectedReason, "there is no host cluster registered")
+		}
+		if !util.IsClusterReady(fedCluster.ClusterStatus) {
+			return Unhealthy(hostNotReadyReason, "the host cluster is not ready")
+		}
+		return nil
+	}
+}
+
+// checkWebhook verifies that the service of the webhooks has a ready endpoint, when at least one webhook is served
+func checkWebhook(cl client.Client, namespace string) error {
+	if !webhooksEnabled() {
+		return nil
+	}
+	endpoints := &corev1.Endpoints{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: webhookServiceName}, endpoints); err != nil {
+		if errors.IsNotFound(err) {
+			return Unhealthy(webhookUnreachableReason, "the webhook service '%s' does not exist", webhookServiceName)
+		}
+		return errs.Wrapf(err, "unable to get the endpoints of the webhook service '%s'", webhookServiceName)
+	}
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return nil
+		}
+	}
+	return Unhealthy(webhookUnreachableReason, "the webhook service '%s' has no ready endpoint", webhookServiceName)
+}
+
+// webhooksEnabled returns true if at least one webhook is served by the operator
+func webhooksEnabled() bool {
+	return config.HostValidationWebhookEnabled() ||
+		config.PodMutationWebhookEnabled() ||
+		config.PodPriorityWebhookEnabled() ||
+		config.PodSchedulingWebhookEnabled() ||
+		config.PodProxyWebhookEnabled() ||
+		config.ResourceValidationWebhookEnabled() ||
+		config.VirtualMachineWebhookEnabled()
+}
+
+// checkAutoscaler verifies that the Deployments of the autoscaling buffer exist and that their replicas are created, when a buffer
+// is configured. The buffer pods are expected to be preempted by the user workloads, hence they are not required to be running
+func checkAutoscaler(cl client.Client, namespace string) error {
+	if config.GetAutoscaler().BufferReplicas <= 0 {
+		return nil
+	}
+	deployments := &appsv1.DeploymentList{}
+	if err := cl.List(context.TODO(), deployments, client.InNamespace(namespace)); err != nil {
+		return errs.Wrap(err, "unable to list the deployments of the autoscaling buffer")
+	}
+	buffers := 0
+	var notReady []string
+	for _, deployment := range deployments.Items {
+		if _, found := deployment.Labels[autoscaler.BufferLabel]; !found {
+			continue
+		}
+		buffers++
+		if deployment.Spec.Replicas != nil && deployment.Status.Replicas < *deployment.Spec.Replicas {
+			notReady = append(notReady, deployment.Name)
+		}
+	}
+	if buffers == 0 {
+		return Unhealthy(bufferNotReadyReason, "there is no deployment of the autoscaling buffer")
+	}
+	if len(notReady) > 0 {
+		return Unhealthy(bufferNotReadyReason, "the replicas of the autoscaling buffer are not created: %s", strings.Join(notReady, ", "))
+	}
+	return nil
+}
+
+// checkIdlers verifies that none of the Idlers failed to idle the workloads of their namespaces
+func checkIdlers(cl client.Client, _ string) error {
+	idlers := &memberv1alpha1.IdlerList{}
+	if err := cl.List(context.TODO(), idlers); err != nil {
+		return errs.Wrap(err, "unable to list the idlers")
+	}
+	var failing []string
+	for _, idler := range idlers.Items {
+		if ready, found := condition.FindConditionByType(idler.Status.Conditions, toolchainv1alpha1.ConditionReady); found && ready.Status == corev1.ConditionFalse {
+			failing = append(failing, idler.Name)
+		}
+	}
+	if len(failing) > 0 {
+		return Unhealthy(idlersFailingReason, "unable to idle the workloads of the idlers: %s", strings.Join(failing, ", "))
+	}
+	return nil
+}
+
+// throttlingCheck verifies that the share of the requests throttled by the API server since the previous check stays under
+// `maxThrottledPercent`
+type throttlingCheck struct {
+	apiRequests   func() (sent, throttled uint64)
+	lock          sync.Mutex
+	lastSent      uint64
+	lastThrottled uint64
+}
+
+func (c *throttlingCheck) check(_ client.Client, _ string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	sent, throttled := c.apiRequests()
+	sentSinceLast, throttledSinceLast := sent-c.lastSent, throttled-c.lastThrottled
+	c.lastSent, c.lastThrottled = sent, throttled
+	if sentSinceLast == 0 {
+		return nil
+	}
+	if percent := throttledSinceLast * 100 / sentSinceLast; percent > maxThrottledPercent {
+		return Unhealthy(apiThrottledReason, "%d%% of the requests sent to the API server were throttled (%d out of %d)", percent, throttledSinceLast, sentSinceLast)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package memberstatus
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/autoscaler"
+	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	"sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+)
+
+func TestReconcileComponentsHealth(t *testing.T) {
+	healthy := ComponentCheck{Name: "healthy", Check: func(client.Client, string) error { return nil }}
+
+	t.Run("all components healthy", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus())
+		r.checks = []ComponentCheck{healthy}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		status := getMemberStatus(t, cl).Status
+		assert.Equal(t, []memberv1alpha1.ComponentHealth{{Name: "healthy", Healthy: true, Reason: "Healthy"}}, status.Components)
+		ready, found := condition.FindConditionByType(status.Conditions, toolchainv1alpha1.ConditionReady)
+		require.True(t, found)
+		assert.Equal(t, corev1.ConditionTrue, ready.Status)
+		assert.Equal(t, "AllComponentsReady", ready.Reason)
+	})
+
+	t.Run("components not healthy", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus())
+		r.checks = []ComponentCheck{
+			healthy,
+			{Name: "host", Check: func(client.Client, string) error { return Unhealthy("HostNotReady", "the host cluster is not ready") }},
+			{Name: "broken", Check: func(client.Client, string) error { return errors.New("mock error") }},
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		status := getMemberStatus(t, cl).Status
+		assert.Equal(t, []memberv1alpha1.ComponentHealth{
+			{Name: "healthy", Healthy: true, Reason: "Healthy"},
+			{Name: "host", Healthy: false, Reason: "HostNotReady", Message: "the host cluster is not ready"},
+			{Name: "broken", Healthy: false, Reason: "CheckFailed", Message: "mock error"},
+		}, status.Components)
+		ready, found := condition.FindConditionByType(status.Conditions, toolchainv1alpha1.ConditionReady)
+		require.True(t, found)
+		assert.Equal(t, corev1.ConditionFalse, ready.Status)
+		assert.Equal(t, "ComponentsNotReady", ready.Reason)
+		assert.Equal(t, "host: the host cluster is not ready; broken: mock error", ready.Message)
+	})
+}
+
+func TestHostConnectionCheck(t *testing.T) {
+	t.Run("host ready", func(t *testing.T) {
+		err := hostConnectionCheck(newGetHostCluster(true, corev1.ConditionTrue))(nil, operatorNamespace)
+		assert.NoError(t, err)
+	})
+
+	t.Run("host not ready", func(t *testing.T) {
+		err := hostConnectionCheck(newGetHostCluster(true, corev1.ConditionFalse))(nil, operatorNamespace)
+		assertUnhealthy(t, err, "HostNotReady", "the host cluster is not ready")
+	})
+
+	t.Run("no host registered", func(t *testing.T) {
+		err := hostConnectionCheck(newGetHostCluster(false, corev1.ConditionTrue))(nil, operatorNamespace)
+		assertUnhealthy(t, err, "HostNotConnected", "there is no host cluster registered")
+	})
+}
+
+func TestCheckWebhook(t *testing.T) {
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: "member-operator-webhook"},
+		Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}}},
+	}
+
+	t.Run("no webhook served", func(t *testing.T) {
+		err := checkWebhook(test.NewFakeClient(t), operatorNamespace)
+		assert.NoError(t, err)
+	})
+
+	require.NoError(t, os.Setenv(config.PodProxyWebhookEnvVar, "true"))
+	defer func() {
+		require.NoError(t, os.Unsetenv(config.PodProxyWebhookEnvVar))
+	}()
+
+	t.Run("webhook reachable", func(t *testing.T) {
+		err := checkWebhook(test.NewFakeClient(t, endpoints), operatorNamespace)
+		assert.NoError(t, err)
+	})
+
+	t.Run("no ready endpoint", func(t *testing.T) {
+		notReady := endpoints.DeepCopy()
+		notReady.Subsets = []corev1.EndpointSubset{{NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}}}
+		err := checkWebhook(test.NewFakeClient(t, notReady), operatorNamespace)
+		assertUnhealthy(t, err, "WebhookUnreachable", "the webhook service 'member-operator-webhook' has no ready endpoint")
+	})
+
+	t.Run("no webhook service", func(t *testing.T) {
+		err := checkWebhook(test.NewFakeClient(t), operatorNamespace)
+		assertUnhealthy(t, err, "WebhookUnreachable", "the webhook service 'member-operator-webhook' does not exist")
+	})
+}
+
+func TestCheckAutoscaler(t *testing.T) {
+	t.Run("no buffer configured", func(t *testing.T) {
+		err := checkAutoscaler(test.NewFakeClient(t), operatorNamespace)
+		assert.NoError(t, err)
+	})
+
+	loadAutoscaler(t, &memberv1alpha1.AutoscalerConfig{BufferMemory: "1Gi", BufferReplicas: 2})
+	defer loadAutoscaler(t, nil)
+
+	t.Run("buffer ready", func(t *testing.T) {
+		err := checkAutoscaler(test.NewFakeClient(t, newBufferDeployment("autoscaling-buffer-zone-a", 2, 2)), operatorNamespace)
+		assert.NoError(t, err)
+	})
+
+	t.Run("buffer replicas not created", func(t *testing.T) {
+		cl := test.NewFakeClient(t, newBufferDeployment("autoscaling-buffer-zone-a", 2, 2), newBufferDeployment("autoscaling-buffer-zone-b", 2, 1))
+		err := checkAutoscaler(cl, operatorNamespace)
+		assertUnhealthy(t, err, "BufferNotReady", "the replicas of the autoscaling buffer are not created: autoscaling-buffer-zone-b")
+	})
+
+	t.Run("no buffer deployment", func(t *testing.T) {
+		err := checkAutoscaler(test.NewFakeClient(t), operatorNamespace)
+		assertUnhealthy(t, err, "BufferNotReady", "there is no deployment of the autoscaling buffer")
+	})
+}
+
+func TestCheckIdlers(t *testing.T) {
+	t.Run("idlers running", func(t *testing.T) {
+		err := checkIdlers(test.NewFakeClient(t, newIdler("johnsmith-dev", corev1.ConditionTrue), newIdler("janedoe-dev", corev1.ConditionTrue)), operatorNamespace)
+		assert.NoError(t, err)
+	})
+
+	t.Run("idler failing", func(t *testing.T) {
+		err := checkIdlers(test.NewFakeClient(t, newIdler("johnsmith-dev", corev1.ConditionTrue), newIdler("janedoe-dev", corev1.ConditionFalse)), operatorNamespace)
+		assertUnhealthy(t, err, "IdlersFailing", "unable to idle the workloads of the idlers: janedoe-dev")
+	})
+}
+
+func TestThrottlingCheck(t *testing.T) {
+	// given
+	var sent, throttled uint64
+	c := &throttlingCheck{apiRequests: func() (uint64, uint64) { return sent, throttled }}
+
+	t.Run("no request sent", func(t *testing.T) {
+		assert.NoError(t, c.check(nil, operatorNamespace))
+	})
+
+	t.Run("few requests throttled", func(t *testing.T) {
+		sent, throttled = 100, 10
+		assert.NoError(t, c.check(nil, operatorNamespace))
+	})
+
+	t.Run("too many requests throttled since the last check", func(t *testing.T) {
+		sent, throttled = 200, 30
+		assertUnhealthy(t, c.check(nil, operatorNamespace), "APIThrottled", "20% of the requests sent to the API server were throttled (20 out of 100)")
+	})
+
+	t.Run("throttling over", func(t *testing.T) {
+		sent = 300
+		assert.NoError(t, c.check(nil, operatorNamespace))
+	})
+}
+
+func assertUnhealthy(t *testing.T, err error, reason, message string) {
+	require.EqualError(t, err, message)
+	cerr, ok := err.(componentError)
+	require.True(t, ok)
+	assert.Equal(t, reason, cerr.reason)
+}
+
+func newGetHostCluster(ok bool, status corev1.ConditionStatus) func() (*cluster.FedCluster, bool) {
+	return func() (*cluster.FedCluster, bool) {
+		if !ok {
+			return nil, false
+		}
+		return &cluster.FedCluster{
+			Type: cluster.Host,
+			ClusterStatus: &v1beta1.KubeFedClusterStatus{
+				Conditions: []v1beta1.ClusterCondition{{
+					Type:   common.ClusterReady,
+					Status: status,
+				}},
+			},
+		}, true
+	}
+}
+
+func newBufferDeployment(name string, replicas, created int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: name, Labels: map[string]string{autoscaler.BufferLabel: "zone"}},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{Replicas: created},
+	}
+}
+
+func newIdler(name string, ready corev1.ConditionStatus) runtime.Object {
+	return &memberv1alpha1.Idler{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: memberv1alpha1.IdlerStatus{
+			Conditions: []toolchainv1alpha1.Condition{{Type: toolchainv1alpha1.ConditionReady, Status: ready}},
+		},
+	}
+}
+
+func loadAutoscaler(t *testing.T, buffer *memberv1alpha1.AutoscalerConfig) {
+	cfg := &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName},
+		Spec:       memberv1alpha1.MemberOperatorConfigSpec{Autoscaler: buffer},
+	}
+	require.NoError(t, config.LoadMemberOperatorConfig(test.NewFakeClient(t, cfg), operatorNamespace))
+}
@@ -0,0 +1,39 @@
+package memberstatus
+
+import (
+	"context"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/rollout"
+
+	errs "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// memberConsoleStatus returns the state of the Deployment of the member web console in the given namespace,
+// or nil if the member web console is not deployed
+func (r *ReconcileMemberStatus) memberConsoleStatus(namespace string) (*memberv1alpha1.MemberConsoleStatus, error) {
+	deployment := &appsv1.Deployment{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: memberv1alpha1.MemberConsoleName}, deployment); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errs.Wrap(err, "failed to get the deployment of the member console")
+	}
+	status := &memberv1alpha1.MemberConsoleStatus{
+		Replicas:          1,
+		AvailableReplicas: deployment.Status.AvailableReplicas,
+	}
+	if deployment.Spec.Replicas != nil {
+		status.Replicas = *deployment.Spec.Replicas
+	}
+	if containers := deployment.Spec.Template.Spec.Containers; len(containers) > 0 {
+		status.Image = containers[0].Image
+	}
+	progress := rollout.DeploymentProgress(deployment)
+	status.Ready = progress.Done
+	status.Message = progress.Message
+	return status, nil
+}
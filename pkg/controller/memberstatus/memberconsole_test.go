@@ -0,0 +1,96 @@
+package memberstatus
+
+import (
+	"testing"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileMemberConsole(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	t.Run("member console ready", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus(), newMemberConsoleDeployment(2, 2, 2))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, &memberv1alpha1.MemberConsoleStatus{
+			Image:             "quay.io/codeready-toolchain/member-console:abcd123",
+			Replicas:          2,
+			AvailableReplicas: 2,
+			Ready:             true,
+		}, getMemberStatus(t, cl).Status.MemberConsole)
+	})
+
+	t.Run("member console not ready", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus(), newMemberConsoleDeployment(2, 2, 1))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		memberConsole := getMemberStatus(t, cl).Status.MemberConsole
+		require.NotNil(t, memberConsole)
+		assert.False(t, memberConsole.Ready)
+		assert.Equal(t, int32(1), memberConsole.AvailableReplicas)
+		assert.Equal(t, "1 out of 2 replicas are available", memberConsole.Message)
+	})
+
+	t.Run("member console being rolled out", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus(), newMemberConsoleDeployment(2, 1, 2))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		memberConsole := getMemberStatus(t, cl).Status.MemberConsole
+		require.NotNil(t, memberConsole)
+		assert.False(t, memberConsole.Ready)
+		assert.Equal(t, "1 out of 2 replicas are updated", memberConsole.Message)
+	})
+
+	t.Run("member console not deployed", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus())
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Nil(t, getMemberStatus(t, cl).Status.MemberConsole)
+	})
+}
+
+func newMemberConsoleDeployment(replicas, updated, available int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: memberv1alpha1.MemberConsoleName},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "console", Image: "quay.io/codeready-toolchain/member-console:abcd123"}},
+				},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			UpdatedReplicas:   updated,
+			AvailableReplicas: available,
+		},
+	}
+}
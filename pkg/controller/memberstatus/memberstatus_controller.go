@@ -0,0 +1,253 @@
+package memberstatus
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"github.com/operator-framework/operator-sdk/pkg/predicate"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_memberstatus")
+
+const (
+	// nodeRoleLabelPrefix the prefix of the labels which define the roles of the nodes
+	nodeRoleLabelPrefix = "node-role.kubernetes.io/"
+	// noRole the role of the nodes which have no role label
+	noRole = "none"
+)
+
+// Add creates a new MemberStatus Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started. The MemberStatus resource is created in the operator namespace if it does not exist yet.
+func Add(mgr manager.Manager) error {
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	if err := add(mgr, newReconciler(mgr)); err != nil {
+		return err
+	}
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		return CreateIfNotExists(mgr.GetClient(), namespace)
+	}))
+}
+
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileMemberStatus{
+		client:        config.ControllerClient("memberstatus", mgr.GetClient()),
+		scheme:        mgr.GetScheme(),
+		refreshPeriod: config.GetMemberStatusRefreshPeriod(),
+		httpClient:    &http.Client{Timeout: routeCheckTimeout},
+		checks:        defaultChecks(),
+	}
+}
+
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("memberstatus-controller", mgr, config.ControllerOptions("memberstatus", r))
+	if err != nil {
+		return err
+	}
+	// Watch for changes to primary resource MemberStatus (the updates of its status are ignored, since the resource usage is refreshed periodically)
+	return c.Watch(&source.Kind{Type: &memberv1alpha1.MemberStatus{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{})
+}
+
+// CreateIfNotExists creates the MemberStatus resource in the given namespace if it does not exist yet
+func CreateIfNotExists(cl client.Client, namespace string) error {
+	memberStatus := &memberv1alpha1.MemberStatus{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      memberv1alpha1.MemberStatusName,
+		},
+	}
+	if err := cl.Create(context.TODO(), memberStatus); err != nil && !errors.IsAlreadyExists(err) {
+		return errs.Wrap(err, "failed to create the MemberStatus")
+	}
+	return nil
+}
+
+var _ reconcile.Reconciler = &ReconcileMemberStatus{}
+
+// ReconcileMemberStatus reports the capacity and the resource consumption of the member cluster, along with the URLs
+// of its web consoles, the state of the member web console and the health of its components, in the MemberStatus resource
+type ReconcileMemberStatus struct {
+	client        client.Client
+	scheme        *runtime.Scheme
+	refreshPeriod time.Duration
+	httpClient    *http.Client
+	checks        []ComponentCheck
+}
+
+// Reconcile computes the capacity and the resource consumption of the member cluster, discovers and checks its web consoles,
+// checks the health of its components, reports them in the status of the MemberStatus (along with a Ready condition which
+// is true if all the components are healthy), and requeues the MemberStatus so that they are refreshed at every period
+func (r *ReconcileMemberStatus) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := logging.ForRequest(log, request)
+
+	memberStatus := &memberv1alpha1.MemberStatus{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, memberStatus); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	usage, err := r.resourceUsage(request.Namespace)
+	if err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "failed to compute the resource usage")
+	}
+	routes, err := r.routesStatus()
+	if err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "failed to discover the web consoles")
+	}
+	memberConsole, err := r.memberConsoleStatus(request.Namespace)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	memberStatus.Status.ResourceUsage = usage
+	memberStatus.Status.Routes = routes
+	memberStatus.Status.MemberConsole = memberConsole
+	setReady(memberStatus, r.componentsHealth(request.Namespace))
+	if err := r.client.Status().Update(context.TODO(), memberStatus); err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "failed to update the resource usage")
+	}
+	reqLogger.Info("resource usage updated", "nodes", usage.Nodes, "user_accounts", usage.UserAccounts)
+	return reconcile.Result{RequeueAfter: r.refreshPeriod}, nil
+}
+
+// resourceUsage returns the capacity of the nodes, the number of UserAccounts in the given namespace and the utilization of the nodes per role
+func (r *ReconcileMemberStatus) resourceUsage(namespace string) (*memberv1alpha1.ResourceUsageStatus, error) {
+	nodes := &corev1.NodeList{}
+	if err := r.client.List(context.TODO(), nodes); err != nil {
+		return nil, errs.Wrap(err, "failed to list the nodes")
+	}
+	pods := &corev1.PodList{}
+	if err := r.client.List(context.TODO(), pods); err != nil {
+		return nil, errs.Wrap(err, "failed to list the pods")
+	}
+	userAccs := &toolchainv1alpha1.UserAccountList{}
+	if err := r.client.List(context.TODO(), userAccs, client.InNamespace(namespace)); err != nil {
+		return nil, errs.Wrap(err, "failed to list the user accounts")
+	}
+
+	requestedPerNode := map[string]corev1.ResourceList{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		requested, found := requestedPerNode[pod.Spec.NodeName]
+		if !found {
+			requested = corev1.ResourceList{}
+			requestedPerNode[pod.Spec.NodeName] = requested
+		}
+		addResources(requested, podRequests(pod))
+	}
+
+	usage := &memberv1alpha1.ResourceUsageStatus{
+		Nodes:           int32(len(nodes.Items)),
+		Capacity:        corev1.ResourceList{},
+		Allocatable:     corev1.ResourceList{},
+		UserAccounts:    int32(len(userAccs.Items)),
+		LastUpdatedTime: metav1.Now(),
+	}
+	roles := map[string]*memberv1alpha1.RoleUtilization{}
+	for _, node := range nodes.Items {
+		addResources(usage.Capacity, node.Status.Capacity)
+		addResources(usage.Allocatable, node.Status.Allocatable)
+		for _, role := range nodeRoles(node) {
+			utilization, found := roles[role]
+			if !found {
+				utilization = &memberv1alpha1.RoleUtilization{
+					Role:        role,
+					Allocatable: corev1.ResourceList{},
+					Requested:   corev1.ResourceList{},
+				}
+				roles[role] = utilization
+			}
+			utilization.Nodes++
+			addResources(utilization.Allocatable, node.Status.Allocatable)
+			addResources(utilization.Requested, requestedPerNode[node.Name])
+		}
+	}
+	for _, utilization := range roles {
+		utilization.CPUPercent = percent(utilization.Requested.Cpu().MilliValue(), utilization.Allocatable.Cpu().MilliValue())
+		utilization.MemoryPercent = percent(utilization.Requested.Memory().Value(), utilization.Allocatable.Memory().Value())
+		usage.Roles = append(usage.Roles, *utilization)
+	}
+	sort.Slice(usage.Roles, func(i, j int) bool {
+		return usage.Roles[i].Role < usage.Roles[j].Role
+	})
+	return usage, nil
+}
+
+// nodeRoles returns the roles of the given node, as specified by its `node-role.kubernetes.io/<role>` labels
+func nodeRoles(node corev1.Node) []string {
+	var roles []string
+	for label := range node.Labels {
+		if strings.HasPrefix(label, nodeRoleLabelPrefix) {
+			roles = append(roles, strings.TrimPrefix(label, nodeRoleLabelPrefix))
+		}
+	}
+	if len(roles) == 0 {
+		return []string{noRole}
+	}
+	return roles
+}
+
+// podRequests returns the resources requested by the given pod, ie, for each resource, the sum of the requests of its containers
+// or the highest request of its init containers (which run sequentially) if it is higher
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResources(requests, container.Resources.Requests)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if current, found := requests[name]; !found || quantity.Cmp(current) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	return requests
+}
+
+// addResources adds the given resources to the given total
+func addResources(total, resources corev1.ResourceList) {
+	for name, quantity := range resources {
+		sum, found := total[name]
+		if !found {
+			sum = resource.Quantity{Format: quantity.Format}
+		}
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
+
+// percent returns the given value as a percentage of the given total, or 0 if the total is 0
+func percent(value, total int64) int32 {
+	if total <= 0 {
+		return 0
+	}
+	return int32(value * 100 / total)
+}
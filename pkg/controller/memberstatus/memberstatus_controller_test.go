@@ -0,0 +1,251 @@
+package memberstatus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	operatorNamespace = "toolchain-member-operator"
+	refreshPeriod     = 30 * time.Second
+)
+
+func TestReconcile(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	t.Run("resource usage reported", func(t *testing.T) {
+		// given
+		master := newNode("master-1", "8", "32Gi", "master")
+		worker1 := newNode("worker-1", "4", "16Gi", "worker")
+		worker2 := newNode("worker-2", "4", "16Gi", "worker", "infra")
+		pods := []runtime.Object{
+			newPod("api", "master-1", corev1.PodRunning, "2", "8Gi"),
+			newPod("app", "worker-1", corev1.PodRunning, "2", "4Gi"),
+			newPod("db", "worker-2", corev1.PodRunning, "1", "4Gi"),
+			newPod("completed", "worker-2", corev1.PodSucceeded, "4", "16Gi"),
+			newPod("pending", "", corev1.PodPending, "4", "16Gi"),
+		}
+		objs := append([]runtime.Object{newMemberStatus(), master, worker1, worker2, newUserAccount("johnsmith"), newUserAccount("janedoe")}, pods...)
+		r, req, cl := prepareReconcile(t, objs...)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: refreshPeriod}, res)
+		usage := getMemberStatus(t, cl).Status.ResourceUsage
+		require.NotNil(t, usage)
+		assert.Equal(t, int32(3), usage.Nodes)
+		assert.Equal(t, int32(2), usage.UserAccounts)
+		assertQuantity(t, "16", usage.Allocatable, corev1.ResourceCPU)
+		assertQuantity(t, "64Gi", usage.Allocatable, corev1.ResourceMemory)
+		assertQuantity(t, "16", usage.Capacity, corev1.ResourceCPU)
+		require.Len(t, usage.Roles, 3)
+		assert.Equal(t, "infra", usage.Roles[0].Role)
+		assert.Equal(t, int32(1), usage.Roles[0].Nodes)
+		assert.Equal(t, int32(25), usage.Roles[0].CPUPercent)
+		assert.Equal(t, int32(25), usage.Roles[0].MemoryPercent)
+		assert.Equal(t, "master", usage.Roles[1].Role)
+		assert.Equal(t, int32(25), usage.Roles[1].CPUPercent)
+		assert.Equal(t, int32(25), usage.Roles[1].MemoryPercent)
+		assert.Equal(t, "worker", usage.Roles[2].Role)
+		assert.Equal(t, int32(2), usage.Roles[2].Nodes)
+		assertQuantity(t, "3", usage.Roles[2].Requested, corev1.ResourceCPU)
+		assert.Equal(t, int32(37), usage.Roles[2].CPUPercent)
+		assert.Equal(t, int32(25), usage.Roles[2].MemoryPercent)
+	})
+
+	t.Run("nodes without role", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus(), newNode("node-1", "4", "16Gi"))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		usage := getMemberStatus(t, cl).Status.ResourceUsage
+		require.NotNil(t, usage)
+		require.Len(t, usage.Roles, 1)
+		assert.Equal(t, "none", usage.Roles[0].Role)
+		assert.Equal(t, int32(0), usage.Roles[0].CPUPercent)
+		assert.Equal(t, int32(0), usage.UserAccounts)
+	})
+
+	t.Run("no member status", func(t *testing.T) {
+		// given
+		r, req, _ := prepareReconcile(t, newNode("node-1", "4", "16Gi"))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+	})
+
+	t.Run("list nodes fails", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus())
+		cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			if _, ok := list.(*corev1.NodeList); ok {
+				return errors.New("mock error")
+			}
+			return cl.Client.List(ctx, list, opts...)
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, "failed to compute the resource usage: failed to list the nodes: mock error")
+		assert.Nil(t, getMemberStatus(t, cl).Status.ResourceUsage)
+	})
+}
+
+func TestCreateIfNotExists(t *testing.T) {
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+
+	t.Run("created", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		err := CreateIfNotExists(cl, operatorNamespace)
+
+		// then
+		require.NoError(t, err)
+		getMemberStatus(t, cl)
+	})
+
+	t.Run("already exists", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newMemberStatus())
+
+		// when
+		err := CreateIfNotExists(cl, operatorNamespace)
+
+		// then
+		require.NoError(t, err)
+	})
+}
+
+func TestPodRequests(t *testing.T) {
+	// given
+	pod := newPod("app", "node-1", corev1.PodRunning, "500m", "1Gi")
+	pod.Spec.Containers = append(pod.Spec.Containers, newContainer("sidecar", "500m", "1Gi"))
+	pod.Spec.InitContainers = []corev1.Container{newContainer("init", "2", "1Gi")}
+
+	// when
+	requests := podRequests(pod)
+
+	// then
+	assertQuantity(t, "2", requests, corev1.ResourceCPU)
+	assertQuantity(t, "2Gi", requests, corev1.ResourceMemory)
+}
+
+func prepareReconcile(t *testing.T, initObjs ...runtime.Object) (*ReconcileMemberStatus, reconcile.Request, *test.FakeClient) {
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+	cl := test.NewFakeClient(t, initObjs...)
+	r := &ReconcileMemberStatus{
+		client:        cl,
+		scheme:        s,
+		refreshPeriod: refreshPeriod,
+		httpClient:    &http.Client{Timeout: time.Second},
+	}
+	return r, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberStatusName}}, cl
+}
+
+func newMemberStatus() *memberv1alpha1.MemberStatus {
+	return &memberv1alpha1.MemberStatus{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      memberv1alpha1.MemberStatusName,
+			Namespace: operatorNamespace,
+		},
+	}
+}
+
+func newNode(name, cpu, memory string, roles ...string) *corev1.Node {
+	labels := map[string]string{}
+	for _, role := range roles {
+		labels["node-role.kubernetes.io/"+role] = ""
+	}
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status: corev1.NodeStatus{
+			Capacity:    resources,
+			Allocatable: resources,
+		},
+	}
+}
+
+func newPod(name, nodeName string, phase corev1.PodPhase, cpu, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: name},
+		Spec: corev1.PodSpec{
+			NodeName:   nodeName,
+			Containers: []corev1.Container{newContainer(name, cpu, memory)},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func newContainer(name, cpu, memory string) corev1.Container {
+	return corev1.Container{
+		Name: name,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		},
+	}
+}
+
+func newUserAccount(name string) *toolchainv1alpha1.UserAccount {
+	return &toolchainv1alpha1.UserAccount{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: name},
+	}
+}
+
+func getMemberStatus(t *testing.T, cl *test.FakeClient) *memberv1alpha1.MemberStatus {
+	memberStatus := &memberv1alpha1.MemberStatus{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: memberv1alpha1.MemberStatusName}, memberStatus)
+	require.NoError(t, err)
+	return memberStatus
+}
+
+func assertQuantity(t *testing.T, expected string, resources corev1.ResourceList, name corev1.ResourceName) {
+	actual, found := resources[name]
+	require.True(t, found, "resource '%s' not found", name)
+	assert.Equal(t, 0, actual.Cmp(resource.MustParse(expected)), "expected %s but was %s", expected, actual.String())
+}
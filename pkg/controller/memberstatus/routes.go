@@ -0,0 +1,109 @@
+package memberstatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// routeCheckTimeout the timeout of the health check of a single web console
+const routeCheckTimeout = 5 * time.Second
+
+// consoleRoute the Route of the OpenShift web console
+var consoleRoute = types.NamespacedName{Namespace: "openshift-console", Name: "console"}
+
+// cheDashboardRoutes the Routes of the Che dashboard, depending on the distribution which is installed on the cluster
+// (Dev Spaces, CodeReady Workspaces or upstream Che), in the order in which they are looked up
+var cheDashboardRoutes = []types.NamespacedName{
+	{Namespace: "openshift-devspaces", Name: "devspaces"},
+	{Namespace: "openshift-workspaces", Name: "codeready"},
+	{Namespace: "eclipse-che", Name: "che"},
+}
+
+// routesStatus discovers the URLs of the web console and of the Che dashboard (unless they are specified in the MemberOperatorConfig)
+// and checks that they respond
+func (r *ReconcileMemberStatus) routesStatus() (*memberv1alpha1.RoutesStatus, error) {
+	consoleURL := config.GetConsole().URL
+	if consoleURL == "" {
+		url, err := r.routeURL(consoleRoute, "/")
+		if err != nil {
+			return nil, err
+		}
+		consoleURL = url
+	}
+	cheDashboardURL := config.GetConsole().CheDashboardURL
+	for _, route := range cheDashboardRoutes {
+		if cheDashboardURL != "" {
+			break
+		}
+		url, err := r.routeURL(route, "/dashboard/")
+		if err != nil {
+			return nil, err
+		}
+		cheDashboardURL = url
+	}
+
+	status := &memberv1alpha1.RoutesStatus{
+		LastCheckTime: metav1.Now(),
+	}
+	if consoleURL != "" {
+		status.Console = r.checkRoute(consoleURL)
+	}
+	if cheDashboardURL != "" {
+		status.CheDashboard = r.checkRoute(cheDashboardURL)
+	}
+	return status, nil
+}
+
+// routeURL returns the URL of the given Route with the given path, or an empty string if the Route does not exist
+func (r *ReconcileMemberStatus) routeURL(name types.NamespacedName, path string) (string, error) {
+	route := &unstructured.Unstructured{}
+	route.SetAPIVersion("route.openshift.io/v1")
+	route.SetKind("Route")
+	if err := r.client.Get(context.TODO(), name, route); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errs.Wrapf(err, "failed to get the route '%s' in namespace '%s'", name.Name, name.Namespace)
+	}
+	host, _, _ := unstructured.NestedString(route.Object, "spec", "host")
+	if host == "" {
+		// not admitted yet
+		return "", nil
+	}
+	scheme := "http"
+	if _, found, _ := unstructured.NestedMap(route.Object, "spec", "tls"); found {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, host, path), nil
+}
+
+// checkRoute calls the given URL and returns its status. The web console is healthy if it responds without a server error
+// (the redirections to the login page are followed, and an authentication error still means that the console is up)
+func (r *ReconcileMemberStatus) checkRoute(url string) *memberv1alpha1.RouteStatus {
+	status := &memberv1alpha1.RouteStatus{
+		URL: url,
+	}
+	resp, err := r.httpClient.Get(url)
+	if err != nil {
+		status.Message = err.Error()
+		return status
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		status.Message = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		return status
+	}
+	status.Healthy = true
+	return status
+}
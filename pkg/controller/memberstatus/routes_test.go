@@ -0,0 +1,162 @@
+package memberstatus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileRoutes(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, "/unavailable") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	t.Run("routes discovered", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus(), newRoute("openshift-console", "console", host), newRoute("eclipse-che", "che", host))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		routes := getMemberStatus(t, cl).Status.Routes
+		require.NotNil(t, routes)
+		assert.Equal(t, &memberv1alpha1.RouteStatus{URL: server.URL + "/", Healthy: true}, routes.Console)
+		assert.Equal(t, &memberv1alpha1.RouteStatus{URL: server.URL + "/dashboard/", Healthy: true}, routes.CheDashboard)
+		assert.False(t, routes.LastCheckTime.IsZero())
+	})
+
+	t.Run("dev spaces dashboard discovered first", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus(), newRoute("openshift-devspaces", "devspaces", host), newRoute("eclipse-che", "che", "che.example.com"))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		routes := getMemberStatus(t, cl).Status.Routes
+		require.NotNil(t, routes)
+		assert.Nil(t, routes.Console)
+		require.NotNil(t, routes.CheDashboard)
+		assert.Equal(t, server.URL+"/dashboard/", routes.CheDashboard.URL)
+	})
+
+	t.Run("no route", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus())
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		routes := getMemberStatus(t, cl).Status.Routes
+		require.NotNil(t, routes)
+		assert.Nil(t, routes.Console)
+		assert.Nil(t, routes.CheDashboard)
+	})
+
+	t.Run("routes specified in the config", func(t *testing.T) {
+		// given
+		cfg := &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				Console: &memberv1alpha1.ConsoleConfig{
+					URL:             server.URL + "/unavailable",
+					CheDashboardURL: server.URL + "/che",
+				},
+			},
+		}
+		err := config.LoadMemberOperatorConfig(test.NewFakeClient(t, cfg), operatorNamespace)
+		require.NoError(t, err)
+		defer func() {
+			err := config.LoadMemberOperatorConfig(test.NewFakeClient(t), operatorNamespace)
+			require.NoError(t, err)
+		}()
+		r, req, cl := prepareReconcile(t, newMemberStatus(), newRoute("openshift-console", "console", host))
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		routes := getMemberStatus(t, cl).Status.Routes
+		require.NotNil(t, routes)
+		assert.Equal(t, &memberv1alpha1.RouteStatus{URL: server.URL + "/unavailable", Message: "unexpected status 503"}, routes.Console)
+		assert.Equal(t, &memberv1alpha1.RouteStatus{URL: server.URL + "/che", Healthy: true}, routes.CheDashboard)
+	})
+
+	t.Run("route not responding", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus(), newRoute("openshift-console", "console", "127.0.0.1:1"))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		routes := getMemberStatus(t, cl).Status.Routes
+		require.NotNil(t, routes)
+		require.NotNil(t, routes.Console)
+		assert.False(t, routes.Console.Healthy)
+		assert.NotEmpty(t, routes.Console.Message)
+	})
+
+	t.Run("get route fails", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, newMemberStatus())
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			if _, ok := obj.(*unstructured.Unstructured); ok {
+				return errors.New("mock error")
+			}
+			return cl.Client.Get(ctx, key, obj)
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, "failed to discover the web consoles: failed to get the route 'console' in namespace 'openshift-console': mock error")
+		assert.Nil(t, getMemberStatus(t, cl).Status.Routes)
+	})
+}
+
+func newRoute(namespace, name, host string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "route.openshift.io/v1",
+			"kind":       "Route",
+			"metadata": map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+			},
+			"spec": map[string]interface{}{
+				"host": host,
+			},
+		},
+	}
+}
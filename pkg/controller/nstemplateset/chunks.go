@@ -0,0 +1,132 @@
+package nstemplateset
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/go-logr/logr"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// applyCheckpointAnnotation the annotation on the NSTemplateSet which holds the checkpoint of the apply of a large set of objects,
+// which is applied in chunks
+const applyCheckpointAnnotation = "toolchain.dev.openshift.com/apply-checkpoint"
+
+// applyCheckpoint the progress of the apply of a set of objects in chunks
+type applyCheckpoint struct {
+	// Namespace the namespace in which the objects are applied (empty for the cluster resources)
+	Namespace string `json:"namespace"`
+	// Hash the hash of the objects, so that the checkpoint is ignored if the objects changed since then
+	Hash string `json:"hash"`
+	// Next the index of the next chunk to apply
+	Next int `json:"next"`
+}
+
+// applyInChunks applies the given objects of the given namespace (or of the cluster resources if it is empty) in chunks, recording
+// a checkpoint along with the inventory on the NSTemplateSet after each chunk, so that an apply which was interrupted (eg, by a restart
+// of the operator) resumes from the last applied chunk. When the operator shuts down, the apply stops after the chunk in progress.
+// The objects are applied as a single unit if they fit in a single chunk.
+func (r *ReconcileNSTemplateSet) applyInChunks(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, processor template.Processor,
+	inventory *template.Inventory, namespace string, objs []runtime.RawExtension) error {
+	size := config.GetApplyChunkSize()
+	if len(objs) <= size {
+		return processor.ApplyAll(objs)
+	}
+	hash, err := template.ObjectsHash(objs)
+	if err != nil {
+		return err
+	}
+	from := 0
+	if checkpoint, found := checkpointOf(nsTmplSet); found && checkpoint.Namespace == namespace && checkpoint.Hash == hash {
+		from = checkpoint.Next
+		logger.Info("resuming the apply from the last checkpoint", "namespace", namespace, "chunk", from+1, "chunks", template.ChunksCount(len(objs), size))
+	}
+	err = processor.ApplyChunks(objs, size, from, func(next int) error {
+		return r.saveCheckpoint(nsTmplSet, inventory, &applyCheckpoint{Namespace: namespace, Hash: hash, Next: next})
+	})
+	if err != nil {
+		return err
+	}
+	return r.saveCheckpoint(nsTmplSet, inventory, nil)
+}
+
+// checkpointOf returns the checkpoint recorded on the given NSTemplateSet, if any
+func checkpointOf(nsTmplSet *toolchainv1alpha1.NSTemplateSet) (applyCheckpoint, bool) {
+	checkpoint := applyCheckpoint{}
+	content, found := nsTmplSet.GetAnnotations()[applyCheckpointAnnotation]
+	if !found {
+		return checkpoint, false
+	}
+	if err := json.Unmarshal([]byte(content), &checkpoint); err != nil {
+		// start over rather than being stuck with a corrupted checkpoint
+		log.Error(err, "ignoring the invalid apply checkpoint", "name", nsTmplSet.Name)
+		return checkpoint, false
+	}
+	return checkpoint, true
+}
+
+// saveCheckpoint stores the given checkpoint along with the given inventory in the annotations of the NSTemplateSet,
+// or removes the checkpoint if it is nil
+func (r *ReconcileNSTemplateSet) saveCheckpoint(nsTmplSet *toolchainv1alpha1.NSTemplateSet, inventory *template.Inventory, checkpoint *applyCheckpoint) error {
+	content, err := inventoryContent(nsTmplSet, inventory)
+	if err != nil {
+		return err
+	}
+	annotations := nsTmplSet.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[inventoryAnnotation] = content
+	if checkpoint != nil {
+		data, err := json.Marshal(checkpoint)
+		if err != nil {
+			return errs.Wrap(err, "unable to marshal the apply checkpoint")
+		}
+		annotations[applyCheckpointAnnotation] = string(data)
+	} else {
+		delete(annotations, applyCheckpointAnnotation)
+	}
+	nsTmplSet.SetAnnotations(annotations)
+	return r.client.Update(context.TODO(), nsTmplSet)
+}
+
+// applyFailedStatusUpdater returns the given status updater for the failures of the apply, unless the apply was interrupted by the shutdown
+// of the operator or the inventory exceeds its limits: the failure is then reported with the `Interrupted` or the `InventoryLimitExceeded`
+// reason on the aggregate `Ready` condition as well as on the condition of the given type, if any
+func (r *ReconcileNSTemplateSet) applyFailedStatusUpdater(err error, conditionType toolchainv1alpha1.ConditionType,
+	failed func(*toolchainv1alpha1.NSTemplateSet, string) error) func(*toolchainv1alpha1.NSTemplateSet, string) error {
+	var reason string
+	switch {
+	case template.IsInterrupted(err):
+		reason = interruptedReason
+	case template.IsInventoryLimitError(err):
+		reason = inventoryLimitExceededReason
+	default:
+		return failed
+	}
+	return func(nsTmplSet *toolchainv1alpha1.NSTemplateSet, message string) error {
+		conds := []toolchainv1alpha1.Condition{
+			{
+				Type:    toolchainv1alpha1.ConditionReady,
+				Status:  corev1.ConditionFalse,
+				Reason:  reason,
+				Message: message,
+			},
+		}
+		if conditionType != "" {
+			conds = append(conds, toolchainv1alpha1.Condition{
+				Type:    conditionType,
+				Status:  corev1.ConditionFalse,
+				Reason:  reason,
+				Message: message,
+			})
+		}
+		return r.updateStatusConditions(nsTmplSet, conds...)
+	}
+}
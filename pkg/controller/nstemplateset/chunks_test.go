@@ -0,0 +1,182 @@
+package nstemplateset
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestApplyInChunks(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := os.Setenv(config.ApplyChunkSizeEnvVar, "2")
+	require.NoError(t, err)
+	defer func() {
+		err := os.Unsetenv(config.ApplyChunkSizeEnvVar)
+		require.NoError(t, err)
+	}()
+
+	t.Run("apply resumed from the last checkpoint", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		r, fakeClient := prepareController(t, nsTmplSet)
+		processor, inventory, err := r.newProcessor(log, nsTmplSet)
+		require.NoError(t, err)
+		objs := newConfigMaps(5)
+		created := 0
+		fakeClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if obj.(metav1.Object).GetName() == "cm-3" {
+				return errors.New("mock error")
+			}
+			created++
+			return fakeClient.Client.Create(ctx, obj, opts...)
+		}
+
+		// when
+		err = r.applyInChunks(log, nsTmplSet, processor, inventory, "johnsmith-dev", objs)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to apply chunk 2 of 3")
+		checkpoint := getCheckpoint(t, fakeClient)
+		assert.Equal(t, "johnsmith-dev", checkpoint.Namespace)
+		assert.Equal(t, 1, checkpoint.Next)
+
+		t.Run("remaining chunks applied", func(t *testing.T) {
+			// given
+			fakeClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+				created++
+				return fakeClient.Client.Create(ctx, obj, opts...)
+			}
+			created = 0
+
+			// when
+			err := r.applyInChunks(log, nsTmplSet, processor, inventory, "johnsmith-dev", objs)
+
+			// then
+			require.NoError(t, err)
+			// only the config maps of the last two chunks were created
+			assert.Equal(t, 3, created)
+			for i := 0; i < 5; i++ {
+				err := fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: fmt.Sprintf("cm-%d", i)}, &corev1.ConfigMap{})
+				require.NoError(t, err)
+			}
+			updated := &toolchainv1alpha1.NSTemplateSet{}
+			err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: username}, updated)
+			require.NoError(t, err)
+			assert.NotContains(t, updated.Annotations, applyCheckpointAnnotation)
+		})
+	})
+
+	t.Run("checkpoint of other objects ignored", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{applyCheckpointAnnotation: `{"namespace":"johnsmith-dev","hash":"other","next":2}`}
+		r, fakeClient := prepareController(t, nsTmplSet)
+		processor, inventory, err := r.newProcessor(log, nsTmplSet)
+		require.NoError(t, err)
+		created := 0
+		fakeClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			created++
+			return fakeClient.Client.Create(ctx, obj, opts...)
+		}
+
+		// when
+		err = r.applyInChunks(log, nsTmplSet, processor, inventory, "johnsmith-dev", newConfigMaps(5))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 5, created)
+	})
+
+	t.Run("partial progress reported when interrupted by the shutdown", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		r, fakeClient := prepareController(t, nsTmplSet)
+		interrupted := false
+		r.interrupted = func() bool { return interrupted }
+		processor, inventory, err := r.newProcessor(log, nsTmplSet)
+		require.NoError(t, err)
+		fakeClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if obj.(metav1.Object).GetName() == "cm-1" {
+				interrupted = true
+			}
+			return fakeClient.Client.Create(ctx, obj, opts...)
+		}
+
+		// when
+		err = r.applyInChunks(log, nsTmplSet, processor, inventory, "johnsmith-dev", newConfigMaps(5))
+		statusUpdater := r.applyFailedStatusUpdater(err, namespaceConditionType("dev"), r.setStatusNamespaceProvisionFailed("dev"))
+		statusErr := statusUpdater(nsTmplSet, err.Error())
+
+		// then
+		require.Error(t, err)
+		require.NoError(t, statusErr)
+		assert.True(t, template.IsInterrupted(err))
+		assert.Equal(t, 1, getCheckpoint(t, fakeClient).Next)
+		updated := &toolchainv1alpha1.NSTemplateSet{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: username}, updated)
+		require.NoError(t, err)
+		for _, conditionType := range []toolchainv1alpha1.ConditionType{toolchainv1alpha1.ConditionReady, "DevNamespaceReady"} {
+			cond, found := condition.FindConditionByType(updated.Status.Conditions, conditionType)
+			require.True(t, found, "missing condition %s", conditionType)
+			assert.Equal(t, corev1.ConditionFalse, cond.Status)
+			assert.Equal(t, interruptedReason, cond.Reason)
+			assert.Equal(t, "applied 1 of 3 chunks: interrupted", cond.Message)
+		}
+	})
+
+	t.Run("single chunk applied without checkpoint", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		r, fakeClient := prepareController(t, nsTmplSet)
+		processor, inventory, err := r.newProcessor(log, nsTmplSet)
+		require.NoError(t, err)
+		fakeClient.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			return errors.New("unexpected update")
+		}
+
+		// when
+		err = r.applyInChunks(log, nsTmplSet, processor, inventory, "johnsmith-dev", newConfigMaps(2))
+
+		// then
+		require.NoError(t, err)
+	})
+}
+
+func newConfigMaps(count int) []runtime.RawExtension {
+	objs := make([]runtime.RawExtension, count)
+	for i := range objs {
+		objs[i] = runtime.RawExtension{Object: &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: fmt.Sprintf("cm-%d", i)},
+		}}
+	}
+	return objs
+}
+
+func getCheckpoint(t *testing.T, cl client.Client) applyCheckpoint {
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: username}, nsTmplSet)
+	require.NoError(t, err)
+	checkpoint := applyCheckpoint{}
+	err = json.Unmarshal([]byte(nsTmplSet.Annotations[applyCheckpointAnnotation]), &checkpoint)
+	require.NoError(t, err)
+	return checkpoint
+}
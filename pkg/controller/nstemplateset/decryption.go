@@ -0,0 +1,72 @@
+package nstemplateset
+
+import (
+	"context"
+	"crypto/rsa"
+	"sort"
+	"sync"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// newTemplateDecrypter returns the decrypter of the secrets of the templates, whose private keys are held by the Secret configured
+// with the `MEMBER_OPERATOR_TEMPLATE_DECRYPTION_KEYS_SECRET` env var in the given namespace, or `nil` if the env var is not set
+func newTemplateDecrypter(cl client.Client, namespace string) template.Decrypter {
+	name := config.GetTemplateDecryptionKeysSecret()
+	if name == "" {
+		return nil
+	}
+	return &secretKeysDecrypter{client: cl, secret: types.NamespacedName{Namespace: namespace, Name: name}}
+}
+
+// secretKeysDecrypter decrypts the values with the private keys of a Secret, which is only read when the first value is decrypted,
+// so that the templates without any encrypted secret do not need it
+type secretKeysDecrypter struct {
+	client    client.Client
+	secret    types.NamespacedName
+	once      sync.Once
+	decrypter template.Decrypter
+	err       error
+}
+
+// Decrypt decrypts the given ciphertext with the private keys of the Secret
+func (d *secretKeysDecrypter) Decrypt(ciphertext []byte, label string) ([]byte, error) {
+	d.once.Do(func() {
+		d.decrypter, d.err = d.load()
+	})
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d.decrypter.Decrypt(ciphertext, label)
+}
+
+// load returns the decrypter with all the private keys of the Secret, in the order of their names
+func (d *secretKeysDecrypter) load() (template.Decrypter, error) {
+	secret := &corev1.Secret{}
+	if err := d.client.Get(context.TODO(), d.secret, secret); err != nil {
+		return nil, errs.Wrapf(err, "unable to get the secret '%s' of the template decryption keys", d.secret.Name)
+	}
+	names := make([]string, 0, len(secret.Data))
+	for name := range secret.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var keys []*rsa.PrivateKey
+	for _, name := range names {
+		parsed, err := template.ParsePrivateKeys(secret.Data[name])
+		if err != nil {
+			return nil, errs.Wrapf(err, "invalid key '%s' in the secret '%s' of the template decryption keys", name, d.secret.Name)
+		}
+		keys = append(keys, parsed...)
+	}
+	if len(keys) == 0 {
+		return nil, errs.Errorf("no private key in the secret '%s' of the template decryption keys", d.secret.Name)
+	}
+	return template.NewRSADecrypter(keys...), nil
+}
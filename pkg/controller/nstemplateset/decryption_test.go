@@ -0,0 +1,122 @@
+package nstemplateset
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewTemplateDecrypter(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	previousKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	t.Run("no decrypter when not configured", func(t *testing.T) {
+		assert.Nil(t, newTemplateDecrypter(test.NewFakeClient(t), namespaceName))
+	})
+
+	require.NoError(t, os.Setenv(config.TemplateDecryptionKeysSecretEnvVar, "template-keys"))
+	defer func() {
+		require.NoError(t, os.Unsetenv(config.TemplateDecryptionKeysSecretEnvVar))
+	}()
+
+	t.Run("values decrypted with the keys of the secret", func(t *testing.T) {
+		// given
+		secret := newKeysSecret(map[string][]byte{
+			"tls.key":      pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+			"previous.key": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(previousKey)}),
+		})
+		decrypter := newTemplateDecrypter(test.NewFakeClient(t, secret), namespaceName)
+
+		// when
+		plaintext, err := decrypter.Decrypt(encryptValue(t, &key.PublicKey, "s3cr3t"), "")
+		require.NoError(t, err)
+		previousPlaintext, err := decrypter.Decrypt(encryptValue(t, &previousKey.PublicKey, "0ld"), "")
+		require.NoError(t, err)
+
+		// then
+		assert.Equal(t, "s3cr3t", string(plaintext))
+		assert.Equal(t, "0ld", string(previousPlaintext))
+	})
+
+	t.Run("secret not read until a value is decrypted", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		decrypter := newTemplateDecrypter(cl, namespaceName)
+
+		// then
+		require.NotNil(t, decrypter)
+		_, err := decrypter.Decrypt(encryptValue(t, &key.PublicKey, "s3cr3t"), "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to get the secret 'template-keys' of the template decryption keys")
+	})
+
+	t.Run("no key in the secret", func(t *testing.T) {
+		// given
+		secret := newKeysSecret(map[string][]byte{"README": []byte("no key")})
+		decrypter := newTemplateDecrypter(test.NewFakeClient(t, secret), namespaceName)
+
+		// when
+		_, err := decrypter.Decrypt(encryptValue(t, &key.PublicKey, "s3cr3t"), "")
+
+		// then
+		require.EqualError(t, err, "no private key in the secret 'template-keys' of the template decryption keys")
+	})
+
+	t.Run("invalid key in the secret", func(t *testing.T) {
+		// given
+		secret := newKeysSecret(map[string][]byte{
+			"tls.key": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("invalid")}),
+		})
+		decrypter := newTemplateDecrypter(test.NewFakeClient(t, secret), namespaceName)
+
+		// when
+		_, err := decrypter.Decrypt(encryptValue(t, &key.PublicKey, "s3cr3t"), "")
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid key 'tls.key' in the secret 'template-keys' of the template decryption keys")
+	})
+}
+
+func newKeysSecret(data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: "template-keys"},
+		Data:       data,
+	}
+}
+
+// encryptValue encrypts the given value with the given public key, in the hybrid format of the SealedSecrets
+func encryptValue(t *testing.T, key *rsa.PublicKey, value string) []byte {
+	sessionKey := make([]byte, 32)
+	_, err := rand.Read(sessionKey)
+	require.NoError(t, err)
+	encryptedSessionKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key, sessionKey, nil)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(sessionKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	ciphertext := make([]byte, 2)
+	binary.BigEndian.PutUint16(ciphertext, uint16(len(encryptedSessionKey)))
+	ciphertext = append(ciphertext, encryptedSessionKey...)
+	return append(ciphertext, gcm.Seal(nil, make([]byte, gcm.NonceSize()), []byte(value), nil)...)
+}
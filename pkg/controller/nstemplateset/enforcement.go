@@ -0,0 +1,167 @@
+package nstemplateset
+
+import (
+	"context"
+	"fmt"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/go-logr/logr"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// enforcedKinds the kinds of the objects of the user namespaces which are restored as soon as they are deleted or changed
+// (eg, by the users who are admin of their namespaces), along with the type of the objects which are watched and retrieved
+// from the cache of the client. The OpenShift RoleBindings are retrieved from the API server, and their changes are notified
+// by the watch on the RBAC RoleBindings (which are the same objects on the server side)
+var enforcedKinds = map[schema.GroupKind]runtime.Object{
+	networkPolicyGVK.GroupKind():                               &networkingv1.NetworkPolicy{},
+	{Group: "", Kind: "ResourceQuota"}:                         &corev1.ResourceQuota{},
+	{Group: "", Kind: "LimitRange"}:                            &corev1.LimitRange{},
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:  &rbacv1.RoleBinding{},
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:         &rbacv1.Role{},
+	{Group: "authorization.openshift.io", Kind: "RoleBinding"}: nil,
+}
+
+// watchedEnforcedTypes returns the types of the objects of the enforced kinds which are watched
+func watchedEnforcedTypes() []runtime.Object {
+	var objs []runtime.Object
+	for _, obj := range enforcedKinds {
+		if obj != nil {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// recordSpecHashes records the hash of the `spec` of the given objects of the enforced kinds in the inventory, once they were applied
+// (and hence contain the defaults set by the API server). The objects which were not applied (ie, which have no resource version because
+// they did not change since they were last applied) keep their recorded hash
+func recordSpecHashes(inventory *template.Inventory, objs []runtime.RawExtension) error {
+	for _, rawObj := range objs {
+		if rawObj.Object == nil {
+			continue
+		}
+		gvk := rawObj.Object.GetObjectKind().GroupVersionKind()
+		if _, enforced := enforcedKinds[gvk.GroupKind()]; !enforced {
+			continue
+		}
+		acc, err := meta.Accessor(rawObj.Object)
+		if err != nil {
+			return errs.Wrapf(err, "invalid object of kind '%s'", gvk.Kind)
+		}
+		if acc.GetResourceVersion() == "" {
+			continue
+		}
+		hash, err := template.SpecHash(rawObj.Object)
+		if err != nil {
+			return err
+		}
+		inventory.RecordSpecHash(gvk, acc.GetNamespace(), acc.GetName(), hash)
+	}
+	return nil
+}
+
+// ensureEnforcedObjects re-applies the objects of the user namespaces in which an object of the enforced kinds, as recorded
+// in the inventory of the NSTemplateSet, is missing or was changed since it was applied. The given namespace entries and their templates
+// are the ones of the NSTemplateSet once its namespace sets are expanded (see `ExpandNamespaceSets`)
+func (r *ReconcileNSTemplateSet) ensureEnforcedObjects(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcNamespaces []toolchainv1alpha1.NSTemplateSetNamespace, nsTemplates map[string]NamespaceTemplate, userNamespaces []corev1.Namespace) error {
+	inventory, err := template.ParseInventory(nsTmplSet.GetAnnotations()[inventoryAnnotation])
+	if err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusProvisionFailed, err, "failed to load the inventory")
+	}
+	for _, tcNamespace := range tcNamespaces {
+		if tcNamespace.Type == template.ClusterResourcesType {
+			continue
+		}
+		namespace, found := findNamespace(userNamespaces, tcNamespace.Type)
+		if !found || namespace.Status.Phase != corev1.NamespaceActive {
+			continue
+		}
+		drifted, err := r.driftedObjects(inventory, namespace.Name)
+		if err != nil {
+			return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to verify the objects in namespace '%s'", namespace.Name)
+		}
+		if len(drifted) == 0 {
+			continue
+		}
+		logger.Info("restoring the objects which were deleted or changed", "namespace", namespace.Name, "objects", drifted)
+		// all the objects are applied, since the drifted ones are unchanged in the template
+		if err := r.ensureInnerNamespaceResources(logger, nsTmplSet, &tcNamespace, nsTemplates[tcNamespace.Type], &namespace, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// driftedObjects returns the objects of the enforced kinds in the given namespace (as `<kind>/<name>`) which are recorded in the inventory
+// but which do not exist anymore, or whose `spec` changed since they were applied
+func (r *ReconcileNSTemplateSet) driftedObjects(inventory *template.Inventory, namespace string) ([]string, error) {
+	var drifted []string
+	for _, entry := range inventory.EntriesInNamespace(namespace) {
+		gvk := schema.FromAPIVersionAndKind(entry.APIVersion, entry.Kind)
+		watched, enforced := enforcedKinds[gvk.GroupKind()]
+		if !enforced {
+			continue
+		}
+		var existing runtime.Object
+		if watched != nil {
+			// typed objects are retrieved from the cache of the client
+			existing = watched.DeepCopyObject()
+		} else {
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(gvk)
+			existing = u
+		}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: entry.Name}, existing); err != nil {
+			if errors.IsNotFound(err) {
+				drifted = append(drifted, fmt.Sprintf("%s/%s", entry.Kind, entry.Name))
+				continue
+			}
+			return nil, errs.Wrapf(err, "failed to get the %s '%s'", entry.Kind, entry.Name)
+		}
+		if entry.SpecHash == "" {
+			// applied before the hashes were recorded
+			continue
+		}
+		hash, err := template.SpecHash(existing)
+		if err != nil {
+			return nil, err
+		}
+		if hash != entry.SpecHash {
+			drifted = append(drifted, fmt.Sprintf("%s/%s", entry.Kind, entry.Name))
+		}
+	}
+	return drifted, nil
+}
+
+// toNSTemplateSetOfNamespace returns a mapper which maps the objects to the NSTemplateSet (in the given namespace) of the owner of their namespace.
+// The objects in the namespaces which are not owned by a user are ignored.
+func toNSTemplateSetOfNamespace(cl client.Reader, namespace string) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		ns := &corev1.Namespace{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Name: obj.Meta.GetNamespace()}, ns); err != nil {
+			log.Error(err, "unable to get the namespace of the object", "namespace", obj.Meta.GetNamespace(), "name", obj.Meta.GetName())
+			return nil
+		}
+		owner := ns.Labels["owner"]
+		if owner == "" {
+			return nil
+		}
+		return []reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: namespace, Name: owner}},
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package nstemplateset
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileEnforcedObjects(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	t.Run("spec hashes recorded in the inventory", func(t *testing.T) {
+		// when
+		r, req, fakeClient := provisionDevNamespace(t)
+
+		// then
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err := fakeClient.Get(context.TODO(), req.NamespacedName, nsTmplSet)
+		require.NoError(t, err)
+		inventory := nsTmplSet.Annotations[inventoryAnnotation]
+		assert.Contains(t, inventory, `"kind":"ResourceQuota","namespace":"johnsmith-dev","name":"compute-resources","specHash":"`)
+		assert.Contains(t, inventory, `"kind":"LimitRange","namespace":"johnsmith-dev","name":"resource-limits","specHash":"`)
+		assert.Contains(t, inventory, `"kind":"NetworkPolicy","namespace":"johnsmith-dev","name":"default-deny","specHash":"`)
+		assert.Contains(t, inventory, `"kind":"RoleBinding","namespace":"johnsmith-dev","name":"user-edit","specHash":"`)
+		drifted, err := r.driftedObjects(mustParseInventory(t, inventory), "johnsmith-dev")
+		require.NoError(t, err)
+		assert.Empty(t, drifted)
+	})
+
+	t.Run("changed quota restored", func(t *testing.T) {
+		// given
+		r, req, fakeClient := provisionDevNamespace(t)
+		quota := &corev1.ResourceQuota{}
+		err := fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "compute-resources"}, quota)
+		require.NoError(t, err)
+		quota.Spec.Hard[corev1.ResourceLimitsMemory] = resource.MustParse("70Gi")
+		err = fakeClient.Update(context.TODO(), quota)
+		require.NoError(t, err)
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "compute-resources"}, quota)
+		require.NoError(t, err)
+		memory := quota.Spec.Hard[corev1.ResourceLimitsMemory]
+		assert.Equal(t, "7Gi", memory.String())
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+	})
+
+	t.Run("deleted limit range restored", func(t *testing.T) {
+		// given
+		r, req, fakeClient := provisionDevNamespace(t)
+		limitRange := &corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "resource-limits"}}
+		err := fakeClient.Delete(context.TODO(), limitRange)
+		require.NoError(t, err)
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "resource-limits"}, limitRange)
+		require.NoError(t, err)
+		require.Len(t, limitRange.Spec.Limits, 1)
+		assert.Equal(t, corev1.LimitTypeContainer, limitRange.Spec.Limits[0].Type)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+	})
+
+	t.Run("changed role binding restored", func(t *testing.T) {
+		// given
+		r, req, fakeClient := provisionDevNamespace(t)
+		roleBinding := &unstructured.Unstructured{}
+		roleBinding.SetAPIVersion("authorization.openshift.io/v1")
+		roleBinding.SetKind("RoleBinding")
+		err := fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "user-edit"}, roleBinding)
+		require.NoError(t, err)
+		err = unstructured.SetNestedField(roleBinding.Object, "admin", "roleRef", "name")
+		require.NoError(t, err)
+		err = fakeClient.Update(context.TODO(), roleBinding)
+		require.NoError(t, err)
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "user-edit"}, roleBinding)
+		require.NoError(t, err)
+		role, _, _ := unstructured.NestedString(roleBinding.Object, "roleRef", "name")
+		assert.Equal(t, "edit", role)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+	})
+
+	t.Run("deleted space role binding restored", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			spaceRolesAnnotation: `[{"username":"jane","role":"viewer"}]`,
+		}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		createNamespace(t, fakeClient, "", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+		// both namespaces are provisioned with the space roles
+		for i := 0; i < 3; i++ {
+			_, err := r.Reconcile(req)
+			require.NoError(t, err)
+		}
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		roleBinding := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "space-viewer-jane"}}
+		err := fakeClient.Delete(context.TODO(), roleBinding)
+		require.NoError(t, err)
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "space-viewer-jane"}, roleBinding)
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+	})
+
+	t.Run("deleted network policy restored", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		// entries recorded before the hashes of the objects were recorded
+		nsTmplSet.Annotations = map[string]string{
+			inventoryAnnotation: `{"entries":[` +
+				`{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","namespace":"johnsmith-dev","name":"default-deny"},` +
+				`{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","namespace":"johnsmith-dev","name":"allow-from-same-owner"}]}`,
+		}
+		existing := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "allow-from-same-owner"}}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet, existing)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkNetworkPolicy(t, fakeClient, "johnsmith-dev", "default-deny")
+		checkInnerResources(t, fakeClient, "johnsmith-dev")
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+	})
+
+	t.Run("nothing restored when all objects exist", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			inventoryAnnotation: `{"entries":[{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","namespace":"johnsmith-dev","name":"default-deny"}]}`,
+		}
+		existing := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "default-deny"}}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet, existing)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		// the template was not applied again
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "allow-from-same-owner"}, &networkingv1.NetworkPolicy{})
+		assert.Error(t, err)
+	})
+
+	t.Run("fail to get the enforced objects", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			inventoryAnnotation: `{"entries":[{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","namespace":"johnsmith-dev","name":"default-deny"}]}`,
+		}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+		fakeClient.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			if _, ok := obj.(*networkingv1.NetworkPolicy); ok {
+				return errors.New("mock error")
+			}
+			return fakeClient.Client.Get(ctx, key, obj)
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, "failed to verify the objects in namespace 'johnsmith-dev': failed to get the NetworkPolicy 'default-deny': mock error")
+		checkStatus(t, fakeClient, "UnableToProvisionNamespace")
+	})
+}
+
+func TestToNSTemplateSetOfNamespace(t *testing.T) {
+	// given
+	_, fakeClient := prepareController(t)
+	createNamespace(t, fakeClient, "abcde11", "dev")
+	other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "openshift-monitoring"}}
+	err := fakeClient.Create(context.TODO(), other)
+	require.NoError(t, err)
+	mapper := toNSTemplateSetOfNamespace(fakeClient, namespaceName)
+
+	t.Run("object in a user namespace", func(t *testing.T) {
+		// when
+		requests := mapper(handler.MapObject{Meta: &metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "compute-resources"}})
+
+		// then
+		assert.Equal(t, []reconcile.Request{newReconcileRequest(username)}, requests)
+	})
+
+	t.Run("object in another namespace", func(t *testing.T) {
+		// when
+		requests := mapper(handler.MapObject{Meta: &metav1.ObjectMeta{Namespace: "openshift-monitoring", Name: "compute-resources"}})
+
+		// then
+		assert.Empty(t, requests)
+	})
+
+	t.Run("unknown namespace", func(t *testing.T) {
+		// when
+		requests := mapper(handler.MapObject{Meta: &metav1.ObjectMeta{Namespace: "unknown", Name: "compute-resources"}})
+
+		// then
+		assert.Empty(t, requests)
+	})
+}
+
+// provisionDevNamespace provisions the 'dev' namespace of the NSTemplateSet (the 'code' namespace already exists)
+// and reconciles once more, so that the NSTemplateSet is ready
+func provisionDevNamespace(t *testing.T) (*ReconcileNSTemplateSet, reconcile.Request, *test.FakeClient) {
+	r, req, fakeClient := prepareReconcile(t, newNSTmplSet())
+	createNamespace(t, fakeClient, "", "dev")
+	createNamespace(t, fakeClient, "abcde21", "code")
+	_, err := r.Reconcile(req)
+	require.NoError(t, err)
+	_, err = r.Reconcile(req)
+	require.NoError(t, err)
+	checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+	return r, req, fakeClient
+}
+
+func mustParseInventory(t *testing.T, content string) *template.Inventory {
+	inventory, err := template.ParseInventory(content)
+	require.NoError(t, err)
+	return inventory
+}
@@ -0,0 +1,135 @@
+package nstemplateset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// namespaceSetsAnnotation the annotation on the NSTemplateSet which declares the namespace sets, as a JSON object whose keys are
+	// the types of the namespace entries of the spec that are provisioned as a set of namespaces (see `NamespaceSet`)
+	namespaceSetsAnnotation = "toolchain.dev.openshift.com/namespace-sets"
+	// NamespaceSetMemberParam the template parameter which holds the name of the member of the namespace set being provisioned,
+	// so that the template can give a distinct name to each namespace of the set (eg, `${USERNAME}-${NAMESPACE_SET_MEMBER}`)
+	NamespaceSetMemberParam = "NAMESPACE_SET_MEMBER"
+)
+
+// NamespaceSet a set of namespaces which are all provisioned from the template of a single namespace entry of the NSTemplateSet,
+// eg, the `dev`, `stage` and `prod` namespaces of an application
+type NamespaceSet struct {
+	// Members the names of the namespaces of the set
+	Members []string `json:"members"`
+	// Params the values of the template parameters shared by all the namespaces of the set
+	Params map[string]string `json:"params,omitempty"`
+	// Overrides the values of the template parameters of some namespaces of the set, by member name, which take precedence over the shared ones
+	Overrides map[string]map[string]string `json:"overrides,omitempty"`
+}
+
+// NamespaceTemplate the template of a namespace of the NSTemplateSet, along with the values of its parameters
+type NamespaceTemplate struct {
+	// Type the type of the template in the tier
+	Type string
+	// Params the values of the template parameters
+	Params map[string]string
+}
+
+// ExpandNamespaceSets returns the namespace entries of the given NSTemplateSet, in which each entry declared as a namespace set
+// is replaced with one entry per member of the set, whose type is `<type>-<member>`. The template of each (expanded) entry is
+// returned by type.
+func ExpandNamespaceSets(nsTmplSet *toolchainv1alpha1.NSTemplateSet) ([]toolchainv1alpha1.NSTemplateSetNamespace, map[string]NamespaceTemplate, error) {
+	sets, err := parseNamespaceSets(nsTmplSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	tcNamespaces := make([]toolchainv1alpha1.NSTemplateSetNamespace, 0, len(nsTmplSet.Spec.Namespaces))
+	templates := make(map[string]NamespaceTemplate, len(nsTmplSet.Spec.Namespaces))
+	add := func(tcNamespace toolchainv1alpha1.NSTemplateSetNamespace, tmpl NamespaceTemplate) error {
+		if _, exists := templates[tcNamespace.Type]; exists {
+			return fmt.Errorf("invalid namespace sets: duplicate namespace type '%s'", tcNamespace.Type)
+		}
+		tcNamespaces = append(tcNamespaces, tcNamespace)
+		templates[tcNamespace.Type] = tmpl
+		return nil
+	}
+	for _, tcNamespace := range nsTmplSet.Spec.Namespaces {
+		set, found := sets[tcNamespace.Type]
+		if !found {
+			if err := add(tcNamespace, NamespaceTemplate{Type: tcNamespace.Type, Params: map[string]string{"USERNAME": nsTmplSet.GetName()}}); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		delete(sets, tcNamespace.Type)
+		for _, member := range set.Members {
+			params := make(map[string]string, len(set.Params)+len(set.Overrides[member])+2)
+			for k, v := range set.Params {
+				params[k] = v
+			}
+			for k, v := range set.Overrides[member] {
+				params[k] = v
+			}
+			params["USERNAME"] = nsTmplSet.GetName()
+			params[NamespaceSetMemberParam] = member
+			memberNamespace := toolchainv1alpha1.NSTemplateSetNamespace{
+				Type:     fmt.Sprintf("%s-%s", tcNamespace.Type, member),
+				Revision: tcNamespace.Revision,
+				Template: tcNamespace.Template,
+			}
+			if err := add(memberNamespace, NamespaceTemplate{Type: tcNamespace.Type, Params: params}); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if len(sets) > 0 {
+		types := make([]string, 0, len(sets))
+		for t := range sets {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		return nil, nil, fmt.Errorf("invalid namespace sets: no namespace of type '%s' in the spec", strings.Join(types, "', '"))
+	}
+	return tcNamespaces, templates, nil
+}
+
+// parseNamespaceSets returns the namespace sets of the given NSTemplateSet, by type
+func parseNamespaceSets(nsTmplSet *toolchainv1alpha1.NSTemplateSet) (map[string]NamespaceSet, error) {
+	content := nsTmplSet.GetAnnotations()[namespaceSetsAnnotation]
+	if content == "" {
+		return nil, nil
+	}
+	sets := map[string]NamespaceSet{}
+	if err := json.Unmarshal([]byte(content), &sets); err != nil {
+		return nil, errs.Wrap(err, "unable to parse the namespace sets")
+	}
+	for setType, set := range sets {
+		if setType == template.ClusterResourcesType {
+			return nil, fmt.Errorf("invalid namespace set '%s': the cluster resources cannot be a namespace set", setType)
+		}
+		if len(set.Members) == 0 {
+			return nil, fmt.Errorf("invalid namespace set '%s': missing members", setType)
+		}
+		members := make(map[string]bool, len(set.Members))
+		for _, member := range set.Members {
+			if msgs := validation.IsDNS1123Label(member); len(msgs) > 0 {
+				return nil, fmt.Errorf("invalid namespace set '%s': invalid member '%s': %s", setType, member, strings.Join(msgs, ", "))
+			}
+			if members[member] {
+				return nil, fmt.Errorf("invalid namespace set '%s': duplicate member '%s'", setType, member)
+			}
+			members[member] = true
+		}
+		for member := range set.Overrides {
+			if !members[member] {
+				return nil, fmt.Errorf("invalid namespace set '%s': overrides of unknown member '%s'", setType, member)
+			}
+		}
+	}
+	return sets, nil
+}
@@ -0,0 +1,191 @@
+package nstemplateset
+
+import (
+	"context"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const envNamespaceSets = `{"env":{"members":["stage","prod"],"params":{"CPU":"1"},"overrides":{"prod":{"CPU":"4"}}}}`
+
+func TestExpandNamespaceSets(t *testing.T) {
+
+	t.Run("no namespace set", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+
+		// when
+		tcNamespaces, templates, err := ExpandNamespaceSets(nsTmplSet)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, nsTmplSet.Spec.Namespaces, tcNamespaces)
+		assert.Equal(t, map[string]NamespaceTemplate{
+			"dev":  {Type: "dev", Params: map[string]string{"USERNAME": "johnsmith"}},
+			"code": {Type: "code", Params: map[string]string{"USERNAME": "johnsmith"}},
+		}, templates)
+	})
+
+	t.Run("namespace set expanded", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSetWithNamespaceSets(envNamespaceSets)
+
+		// when
+		tcNamespaces, templates, err := ExpandNamespaceSets(nsTmplSet)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []toolchainv1alpha1.NSTemplateSetNamespace{
+			{Type: "dev", Revision: "abcde11"},
+			{Type: "env-stage", Revision: "abcde41"},
+			{Type: "env-prod", Revision: "abcde41"},
+		}, tcNamespaces)
+		assert.Equal(t, map[string]NamespaceTemplate{
+			"dev":       {Type: "dev", Params: map[string]string{"USERNAME": "johnsmith"}},
+			"env-stage": {Type: "env", Params: map[string]string{"USERNAME": "johnsmith", NamespaceSetMemberParam: "stage", "CPU": "1"}},
+			"env-prod":  {Type: "env", Params: map[string]string{"USERNAME": "johnsmith", NamespaceSetMemberParam: "prod", "CPU": "4"}},
+		}, templates)
+	})
+
+	t.Run("reserved parameters not overridden", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSetWithNamespaceSets(`{"env":{"members":["stage"],"params":{"USERNAME":"jane"},"overrides":{"stage":{"NAMESPACE_SET_MEMBER":"prod"}}}}`)
+
+		// when
+		_, templates, err := ExpandNamespaceSets(nsTmplSet)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"USERNAME": "johnsmith", NamespaceSetMemberParam: "stage"}, templates["env-stage"].Params)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		for _, tc := range []struct {
+			name        string
+			sets        string
+			expectedErr string
+		}{
+			{"invalid content", `{"env":[]}`, "unable to parse the namespace sets"},
+			{"missing members", `{"env":{}}`, "invalid namespace set 'env': missing members"},
+			{"invalid member", `{"env":{"members":["Stage"]}}`, "invalid namespace set 'env': invalid member 'Stage'"},
+			{"duplicate member", `{"env":{"members":["stage","stage"]}}`, "invalid namespace set 'env': duplicate member 'stage'"},
+			{"overrides of unknown member", `{"env":{"members":["stage"],"overrides":{"prod":{}}}}`, "invalid namespace set 'env': overrides of unknown member 'prod'"},
+			{"unknown type", `{"env":{"members":["stage"]},"test":{"members":["stage"]}}`, "invalid namespace sets: no namespace of type 'test' in the spec"},
+			{"cluster resources", `{"clusterresources":{"members":["stage"]}}`, "invalid namespace set 'clusterresources': the cluster resources cannot be a namespace set"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				// given
+				nsTmplSet := newNSTmplSetWithNamespaceSets(tc.sets)
+
+				// when
+				_, _, err := ExpandNamespaceSets(nsTmplSet)
+
+				// then
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			})
+		}
+
+		t.Run("member with the type of another namespace", func(t *testing.T) {
+			// given
+			nsTmplSet := newNSTmplSetWithNamespaceSets(`{"dev":{"members":["env"]}}`)
+			nsTmplSet.Spec.Namespaces = append(nsTmplSet.Spec.Namespaces, toolchainv1alpha1.NSTemplateSetNamespace{Type: "dev-env", Revision: "abcde51"})
+
+			// when
+			_, _, err := ExpandNamespaceSets(nsTmplSet)
+
+			// then
+			require.EqualError(t, err, "invalid namespace sets: duplicate namespace type 'dev-env'")
+		})
+	})
+}
+
+func TestReconcileNamespaceSets(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	t.Run("first namespace of the set created", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSetWithNamespaceSets(envNamespaceSets)
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkNamespaceCond(t, fakeClient, "env-stage", corev1.ConditionFalse, "Provisioning", "provisioning revision 'abcde41'")
+		ns := &corev1.Namespace{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "johnsmith-stage"}, ns)
+		require.NoError(t, err)
+		assert.Equal(t, "env-stage", ns.Labels["type"])
+		assert.Equal(t, "johnsmith", ns.Labels["owner"])
+	})
+
+	t.Run("namespaces of the set provisioned with their own parameters", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSetWithNamespaceSets(envNamespaceSets)
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet,
+			newSetMemberNamespace("stage", "abcde41"),
+			newSetMemberNamespace("prod", ""))
+		createNamespace(t, fakeClient, "abcde11", "dev")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkNamespaceCond(t, fakeClient, "env-prod", corev1.ConditionTrue, "Provisioned", "revision 'abcde41' applied at ")
+		quota := &corev1.ResourceQuota{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-prod", Name: "compute-resources"}, quota)
+		require.NoError(t, err)
+		cpu := quota.Spec.Hard[corev1.ResourceLimitsCPU]
+		assert.Equal(t, "4", cpu.String())
+		ns := &corev1.Namespace{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "johnsmith-prod"}, ns)
+		require.NoError(t, err)
+		assert.Equal(t, "abcde41", ns.Labels["revision"])
+	})
+
+	t.Run("invalid namespace sets", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSetWithNamespaceSets(`{"env":{"members":[]}}`)
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid namespace set 'env': missing members")
+		checkStatus(t, fakeClient, "UnableToProvision")
+	})
+}
+
+func newNSTmplSetWithNamespaceSets(sets string) *toolchainv1alpha1.NSTemplateSet {
+	nsTmplSet := newNSTmplSet()
+	nsTmplSet.Annotations = map[string]string{namespaceSetsAnnotation: sets}
+	nsTmplSet.Spec.Namespaces = []toolchainv1alpha1.NSTemplateSetNamespace{
+		{Type: "dev", Revision: "abcde11"},
+		{Type: "env", Revision: "abcde41"},
+	}
+	return nsTmplSet
+}
+
+func newSetMemberNamespace(member, revision string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "johnsmith-" + member,
+			Labels: map[string]string{"owner": username, "revision": revision, "type": "env-" + member},
+		},
+		Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+	}
+}
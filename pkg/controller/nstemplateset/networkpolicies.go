@@ -0,0 +1,116 @@
+package nstemplateset
+
+import (
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	templatev1 "github.com/openshift/api/template/v1"
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+// networkPolicyGVK the kind of the NetworkPolicies
+var networkPolicyGVK = schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"}
+
+// networkPoliciesTemplate the template of the NetworkPolicies applied in every user namespace, along with the objects of the tier:
+// the ingress traffic is denied by default, except from the namespaces of the same user, from the OpenShift router and from the
+// cluster monitoring. Hence the traffic between the namespaces of different users is blocked.
+// A tier can replace any of these NetworkPolicies by providing a NetworkPolicy with the same name in its template.
+const networkPoliciesTemplate = `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: network-policies
+objects:
+- apiVersion: networking.k8s.io/v1
+  kind: NetworkPolicy
+  metadata:
+    name: default-deny
+    namespace: ${NAMESPACE}
+    labels:
+      owner: ${USERNAME}
+      provider: codeready-toolchain
+  spec:
+    podSelector: {}
+    policyTypes:
+    - Ingress
+- apiVersion: networking.k8s.io/v1
+  kind: NetworkPolicy
+  metadata:
+    name: allow-from-same-owner
+    namespace: ${NAMESPACE}
+    labels:
+      owner: ${USERNAME}
+      provider: codeready-toolchain
+  spec:
+    podSelector: {}
+    ingress:
+    - from:
+      - namespaceSelector:
+          matchLabels:
+            owner: ${USERNAME}
+    policyTypes:
+    - Ingress
+- apiVersion: networking.k8s.io/v1
+  kind: NetworkPolicy
+  metadata:
+    name: allow-from-openshift-ingress
+    namespace: ${NAMESPACE}
+    labels:
+      owner: ${USERNAME}
+      provider: codeready-toolchain
+  spec:
+    podSelector: {}
+    ingress:
+    - from:
+      - namespaceSelector:
+          matchLabels:
+            network.openshift.io/policy-group: ingress
+    policyTypes:
+    - Ingress
+- apiVersion: networking.k8s.io/v1
+  kind: NetworkPolicy
+  metadata:
+    name: allow-from-openshift-monitoring
+    namespace: ${NAMESPACE}
+    labels:
+      owner: ${USERNAME}
+      provider: codeready-toolchain
+  spec:
+    podSelector: {}
+    ingress:
+    - from:
+      - namespaceSelector:
+          matchLabels:
+            network.openshift.io/policy-group: monitoring
+    policyTypes:
+    - Ingress
+parameters:
+- name: USERNAME
+  required: true
+- name: NAMESPACE
+  required: true
+`
+
+// networkPolicies returns the NetworkPolicies to apply in the given namespace, rendered with the given processor, except those
+// which are replaced by a NetworkPolicy with the same name among the given objects of the tier
+func (r *ReconcileNSTemplateSet) networkPolicies(processor template.Processor, username, namespace string, tierObjs []runtime.RawExtension) ([]runtime.RawExtension, error) {
+	tmpl := &templatev1.Template{}
+	decoder := serializer.NewCodecFactory(r.scheme).UniversalDeserializer()
+	if _, _, err := decoder.Decode([]byte(networkPoliciesTemplate), nil, tmpl); err != nil {
+		return nil, errs.Wrap(err, "unable to decode the template of the network policies")
+	}
+	replaced := map[string]bool{}
+	for _, rawObj := range tierObjs {
+		if rawObj.Object == nil || rawObj.Object.GetObjectKind().GroupVersionKind().GroupKind() != networkPolicyGVK.GroupKind() {
+			continue
+		}
+		if acc, err := meta.Accessor(rawObj.Object); err == nil {
+			replaced[acc.GetName()] = true
+		}
+	}
+	return processor.Process(tmpl, map[string]string{"USERNAME": username, "NAMESPACE": namespace}, func(obj runtime.RawExtension) bool {
+		acc, err := meta.Accessor(obj.Object)
+		return err == nil && !replaced[acc.GetName()]
+	})
+}
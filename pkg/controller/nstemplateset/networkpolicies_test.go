@@ -0,0 +1,95 @@
+package nstemplateset
+
+import (
+	"context"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var defaultNetworkPolicies = []string{"default-deny", "allow-from-same-owner", "allow-from-openshift-ingress", "allow-from-openshift-monitoring"}
+
+func TestNetworkPolicies(t *testing.T) {
+
+	t.Run("all network policies rendered", func(t *testing.T) {
+		// given
+		r, _ := prepareController(t)
+
+		// when
+		objs, err := r.networkPolicies(template.NewProcessor(r.client, r.scheme), username, "johnsmith-dev", nil)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 4)
+		for i, obj := range objs {
+			policy, ok := obj.Object.(*unstructured.Unstructured)
+			require.True(t, ok)
+			assert.Equal(t, "NetworkPolicy", policy.GetKind())
+			assert.Equal(t, defaultNetworkPolicies[i], policy.GetName())
+			assert.Equal(t, "johnsmith-dev", policy.GetNamespace())
+			assert.Equal(t, username, policy.GetLabels()["owner"])
+		}
+	})
+
+	t.Run("network policy replaced by the tier", func(t *testing.T) {
+		// given
+		r, _ := prepareController(t)
+		tierPolicy := &unstructured.Unstructured{}
+		tierPolicy.SetAPIVersion("networking.k8s.io/v1")
+		tierPolicy.SetKind("NetworkPolicy")
+		tierPolicy.SetName("allow-from-openshift-monitoring")
+
+		// when
+		objs, err := r.networkPolicies(template.NewProcessor(r.client, r.scheme), username, "johnsmith-dev", []runtime.RawExtension{{Object: tierPolicy}})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 3)
+		for _, obj := range objs {
+			assert.NotEqual(t, "allow-from-openshift-monitoring", obj.Object.(*unstructured.Unstructured).GetName())
+		}
+	})
+}
+
+func TestReconcileNetworkPolicies(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	t.Run("network policies created along with the inner resources", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSet())
+		createNamespace(t, fakeClient, "", "dev")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkInnerResources(t, fakeClient, "johnsmith-dev")
+		for _, name := range defaultNetworkPolicies {
+			checkNetworkPolicy(t, fakeClient, "johnsmith-dev", name)
+		}
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err = fakeClient.Get(context.TODO(), req.NamespacedName, nsTmplSet)
+		require.NoError(t, err)
+		assert.Contains(t, nsTmplSet.Annotations[inventoryAnnotation], `"kind":"NetworkPolicy","namespace":"johnsmith-dev","name":"default-deny","specHash":"`)
+	})
+}
+
+func checkNetworkPolicy(t *testing.T, cl client.Client, namespace, name string) {
+	t.Helper()
+
+	policy := &networkingv1.NetworkPolicy{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, policy)
+	require.NoError(t, err)
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}, policy.Spec.PolicyTypes)
+}
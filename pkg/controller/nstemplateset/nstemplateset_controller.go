@@ -2,8 +2,21 @@ package nstemplateset
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/codeready-toolchain/member-operator/pkg/audittrail"
+	"github.com/codeready-toolchain/member-operator/pkg/conditions"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+	"github.com/codeready-toolchain/member-operator/pkg/metrics"
+	"github.com/codeready-toolchain/member-operator/pkg/nstemplatetier"
+	"github.com/codeready-toolchain/member-operator/pkg/pause"
+	memberpredicate "github.com/codeready-toolchain/member-operator/pkg/predicate"
+	"github.com/codeready-toolchain/member-operator/pkg/shutdown"
 	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/member-operator/pkg/tracing"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 	"github.com/go-logr/logr"
@@ -11,19 +24,23 @@ import (
 	"github.com/operator-framework/operator-sdk/pkg/predicate"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crpredicate "sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
 	templatev1 "github.com/openshift/api/template/v1"
 	errs "github.com/pkg/errors"
+	"github.com/redhat-cop/operator-utils/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
@@ -32,33 +49,104 @@ var log = logf.Log.WithName("controller_nstemplateset")
 
 const (
 	// Status condition reasons
-	unableToProvisionReason          = "UnableToProvision"
-	unableToProvisionNamespaceReason = "UnableToProvisionNamespace"
-	provisioningReason               = "Provisioning"
-	provisionedReason                = "Provisioned"
+	unableToProvisionReason                 = "UnableToProvision"
+	unableToProvisionNamespaceReason        = "UnableToProvisionNamespace"
+	provisioningReason                      = conditions.ProvisioningReason
+	provisionedReason                       = conditions.ProvisionedReason
+	updatingReason                          = conditions.UpdatingReason
+	unableToProvisionClusterResourcesReason = "UnableToProvisionClusterResources"
+	terminatingReason                       = conditions.TerminatingReason
+	terminationStuckReason                  = "TerminationStuck"
+	unableToTerminateReason                 = conditions.UnableToTerminateReason
+	interruptedReason                       = "Interrupted"
+	inventoryLimitExceededReason            = "InventoryLimitExceeded"
+
+	// Finalizers
+	nsTmplSetFinalizerName = "finalizer.toolchain.dev.openshift.com"
+
+	// namespaceReadyConditionSuffix the suffix of the type of the condition which reports the status of
+	// a single namespace, eg: `DevNamespaceReady` for the namespace of type `dev`
+	namespaceReadyConditionSuffix = "NamespaceReady"
+
+	// inventoryAnnotation the annotation on the NSTemplateSet which holds the inventory of the objects that were applied
+	inventoryAnnotation = template.InventoryAnnotation
+	// clusterResourcesRevisionAnnotation the annotation on the NSTemplateSet which holds the revision of the cluster resources template that was applied
+	clusterResourcesRevisionAnnotation = "toolchain.dev.openshift.com/cluster-resources-revision"
+
+	// clusterResourcesReadyCondition the type of the condition which reports the status of the cluster-scoped resources
+	clusterResourcesReadyCondition toolchainv1alpha1.ConditionType = "ClusterResourcesReady"
 )
 
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	r, err := NewReconciler(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, r)
+}
+
+// AddWithTemplates creates a new NSTemplateSet controller whose templates are retrieved with the given func instead of
+// from the NSTemplateTiers of the host cluster (eg, when the controller runs in the scale harness, without any host cluster)
+func AddWithTemplates(mgr manager.Manager, getTemplateContent func(tierName, typeName string) (*templatev1.Template, error)) error {
+	r, err := NewReconciler(mgr)
+	if err != nil {
+		return err
+	}
+	r.getTemplateContent = getTemplateContent
+	return add(mgr, r)
 }
 
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileNSTemplateSet{
-		client:             mgr.GetClient(),
+// NewReconciler returns a new NSTemplateSet reconciler
+func NewReconciler(mgr manager.Manager) (*ReconcileNSTemplateSet, error) {
+	r := &ReconcileNSTemplateSet{
+		client:             config.ControllerClient("nstemplateset", mgr.GetClient()),
 		scheme:             mgr.GetScheme(),
+		mapper:             mgr.GetRESTMapper(),
+		cache:              mgr.GetCache(),
+		trail:              audittrail.NewRecorder(mgr.GetClient(), mgr.GetScheme(), config.GetAuditTrailSize()),
 		getTemplateContent: getTemplateContentFromHost,
+		share:              newFairShare("nstemplateset"),
+		interrupted:        shutdown.Initiated,
 	}
+	if config.ApplyWithProtobuf() {
+		protoClient, err := template.NewProtobufClient(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+		if err != nil {
+			return nil, err
+		}
+		r.protoClient = protoClient
+	}
+	return r, nil
 }
 
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+func add(mgr manager.Manager, r *ReconcileNSTemplateSet) error {
 	// Create a new controller
-	c, err := controller.New("nstemplateset-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("nstemplateset-controller", mgr, config.ControllerOptions("nstemplateset", r))
 	if err != nil {
 		return err
 	}
 
-	// Watch for changes to primary resource
-	err = c.Watch(&source.Kind{Type: &toolchainv1alpha1.NSTemplateSet{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{})
+	// Watch for changes to primary resource, while tracking the new accounts which are waiting to be provisioned
+	err = c.Watch(&source.Kind{Type: &toolchainv1alpha1.NSTemplateSet{}}, &enqueueWithFairShare{share: r.share}, predicate.GenerationChangedPredicate{})
+	if err != nil {
+		return err
+	}
+	// the space roles are not part of the spec, hence their changes do not increase the generation
+	err = c.Watch(&source.Kind{Type: &toolchainv1alpha1.NSTemplateSet{}}, &handler.EnqueueRequestForObject{}, memberpredicate.AnnotationChanged{Key: spaceRolesAnnotation})
+	if err != nil {
+		return err
+	}
+	// nor does opting in or out of the access of the public viewer
+	err = c.Watch(&source.Kind{Type: &toolchainv1alpha1.NSTemplateSet{}}, &handler.EnqueueRequestForObject{}, memberpredicate.AnnotationChanged{Key: publicViewerAnnotation})
+	if err != nil {
+		return err
+	}
+	// nor does declaring the namespace sets
+	err = c.Watch(&source.Kind{Type: &toolchainv1alpha1.NSTemplateSet{}}, &handler.EnqueueRequestForObject{}, memberpredicate.AnnotationChanged{Key: namespaceSetsAnnotation})
+	if err != nil {
+		return err
+	}
+	// neither does pausing or resuming the reconciliation
+	err = c.Watch(&source.Kind{Type: &toolchainv1alpha1.NSTemplateSet{}}, &handler.EnqueueRequestForObject{}, memberpredicate.AnnotationChanged{Key: pause.Annotation})
 	if err != nil {
 		return err
 	}
@@ -71,6 +159,19 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	if err := c.Watch(&source.Kind{Type: &corev1.Namespace{}}, enqueueRequestForOwner); err != nil {
 		return err
 	}
+	// Watch for changes to the objects of the enforced kinds in the user namespaces, so that the NSTemplateSet is requeued and the objects
+	// are restored within seconds when they are deleted or changed. The changes of the status of the ResourceQuotas also refresh the quota
+	// usage reported on the NSTemplateSet
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	enqueueNSTemplateSetOfNamespace := &handler.EnqueueRequestsFromMapFunc{ToRequests: toNSTemplateSetOfNamespace(mgr.GetClient(), namespace)}
+	for _, obj := range watchedEnforcedTypes() {
+		if err := c.Watch(&source.Kind{Type: obj}, enqueueNSTemplateSetOfNamespace, crpredicate.ResourceVersionChangedPredicate{}); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -79,15 +180,51 @@ var _ reconcile.Reconciler = &ReconcileNSTemplateSet{}
 
 type ReconcileNSTemplateSet struct {
 	client             client.Client
+	protoClient        client.Client // optional client to apply the objects of native kinds with the protobuf content type
 	scheme             *runtime.Scheme
+	mapper             meta.RESTMapper      // optional mapper to upgrade the API versions of the template objects which are not served anymore
+	cache              client.Reader        // optional cache in which the existing template objects are looked up before being updated
+	trail              *audittrail.Recorder // optional recorder of the mutations in the audit trail of the users
 	getTemplateContent func(tierName, typeName string) (*templatev1.Template, error)
+	share              *fairShare    // optional share of the reconciliations which are reserved for the new accounts
+	span               *tracing.Span // optional span of the reconciliation in progress, in the copy of the reconciler used by the reconciliation
+	interrupted        func() bool   // optional func which tells whether the applies in chunks must stop, ie, when the operator shuts down
+}
+
+// withAuditTrail returns a copy of this reconciler whose client records the mutations in the audit trail of the given user
+func (r *ReconcileNSTemplateSet) withAuditTrail(namespace, username string) *ReconcileNSTemplateSet {
+	if r.trail == nil {
+		return r
+	}
+	c := *r
+	c.client = audittrail.NewClient(r.client, r.trail, namespace, username, "nstemplateset/"+username)
+	return &c
+}
+
+// withSpan returns a copy of this reconciler which traces its operations under the given span
+func (r *ReconcileNSTemplateSet) withSpan(span *tracing.Span) *ReconcileNSTemplateSet {
+	if span == nil {
+		return r
+	}
+	c := *r
+	c.span = span
+	return &c
 }
 
 // Reconcile reads that state of the cluster for a NSTemplateSet object and makes changes based on the state read
-// and what is in the NSTemplateSet.Spec
+// and what is in the NSTemplateSet.Spec. The reconciliation is traced if the tracing is enabled
 func (r *ReconcileNSTemplateSet) Reconcile(request reconcile.Request) (reconcile.Result, error) {
-	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	span := tracing.Start("NSTemplateSet.Reconcile", "namespace", request.Namespace, "name", request.Name)
+	res, err := r.withSpan(span).reconcileNSTemplateSet(request)
+	span.End(err)
+	return res, err
+}
+
+func (r *ReconcileNSTemplateSet) reconcileNSTemplateSet(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := logging.ForRequest(log, request)
 	reqLogger.Info("Reconciling NSTemplateSet")
+	// the reconciliations in progress are awaited when the operator shuts down
+	defer shutdown.TrackReconcile()()
 
 	var err error
 	namespace, err := getNamespaceName(request)
@@ -95,18 +232,53 @@ func (r *ReconcileNSTemplateSet) Reconcile(request reconcile.Request) (reconcile
 		reqLogger.Error(err, "failed to determine resource namespace")
 		return reconcile.Result{}, err
 	}
+	r = r.withAuditTrail(namespace, request.Name)
 
 	// Fetch the NSTemplateSet instance
 	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
 	err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: request.Name}, nsTmplSet)
 	if err != nil {
 		if errors.IsNotFound(err) {
+			r.share.forget(types.NamespacedName{Namespace: namespace, Name: request.Name})
 			return reconcile.Result{}, nil
 		}
 		reqLogger.Error(err, "failed to get NSTemplateSet")
 		return reconcile.Result{}, err
 	}
 
+	// Defer the reconciliation of the NSTemplateSets which were already provisioned (eg, after a change of tier)
+	// while the reconciliations which are reserved for the new accounts are needed
+	release, admitted := r.share.admit(nsTmplSet)
+	if !admitted {
+		reqLogger.Info("deferring the reconciliation to the provisioning of the new accounts", "delay", deferredReconcileDelay)
+		return reconcile.Result{RequeueAfter: deferredReconcileDelay}, nil
+	}
+	defer release()
+
+	// Skip the reconciliation (including the clean up) while it is paused, eg, to debug the resources of the user
+	if pause.IsPaused(nsTmplSet) {
+		reqLogger.Info("reconciliation is paused")
+		return reconcile.Result{}, r.updateStatusConditions(nsTmplSet, pause.PausedCondition())
+	}
+	if err := r.updateStatusConditions(nsTmplSet, pause.ResumedConditions(nsTmplSet.Status.Conditions)...); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// If the NSTemplateSet has been deleted, delete the user namespaces and cluster resources before removing the finalizer
+	if util.IsBeingDeleted(nsTmplSet) {
+		if !util.HasFinalizer(nsTmplSet, nsTmplSetFinalizerName) {
+			return reconcile.Result{}, nil
+		}
+		res, err := r.manageCleanUp(reqLogger, nsTmplSet)
+		if err != nil {
+			reqLogger.Error(err, "failed to clean up the NSTemplateSet")
+		}
+		return res, err
+	}
+	// Add the finalizer if it is not present
+	if err := r.addFinalizer(nsTmplSet); err != nil {
+		return reconcile.Result{}, err
+	}
 	done, err := r.ensureUserNamespaces(reqLogger, nsTmplSet)
 	if !done || err != nil {
 		if err != nil {
@@ -114,6 +286,10 @@ func (r *ReconcileNSTemplateSet) Reconcile(request reconcile.Request) (reconcile
 		}
 		return reconcile.Result{}, err
 	}
+	if err := r.ensureClusterResources(reqLogger, nsTmplSet); err != nil {
+		reqLogger.Error(err, "failed to provision cluster resources")
+		return reconcile.Result{}, err
+	}
 	return reconcile.Result{}, r.setStatusReady(nsTmplSet)
 }
 
@@ -129,50 +305,76 @@ func (r *ReconcileNSTemplateSet) ensureUserNamespaces(logger logr.Logger, nsTmpl
 	}
 	userNamespaces := userNamespaceList.Items
 
+	if _, err := parseSpaceRoles(nsTmplSet); err != nil {
+		return false, r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusProvisionFailed, err, "invalid space roles")
+	}
+	tcNamespaces, nsTemplates, err := ExpandNamespaceSets(nsTmplSet)
+	if err != nil {
+		return false, r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusProvisionFailed, err, "invalid namespace sets")
+	}
+
 	// find next namespace for provisioning namespace resource
-	tcNamespace, userNamespace, found := nextNamespaceToProvision(nsTmplSet.Spec.Namespaces, userNamespaces)
+	tcNamespace, userNamespace, found := nextNamespaceToProvision(tcNamespaces, userNamespaces, nsTmplSet.GetAnnotations()[spaceRolesAnnotation], publicViewer(nsTmplSet), podSecurityLevel(nsTmplSet))
 	if !found {
+		if err := r.compactInventory(logger, nsTmplSet, userNamespaces); err != nil {
+			statusUpdater := r.applyFailedStatusUpdater(err, "", r.setStatusProvisionFailed)
+			return false, r.wrapErrorWithStatusUpdate(logger, nsTmplSet, statusUpdater, err, "failed to compact the inventory")
+		}
+		if err := r.ensureEnforcedObjects(logger, nsTmplSet, tcNamespaces, nsTemplates, userNamespaces); err != nil {
+			return false, err
+		}
+		if err := r.updateQuotaUsage(nsTmplSet, userNamespaces); err != nil {
+			return false, r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusProvisionFailed, err, "failed to update the quota usage")
+		}
 		return true, nil
 	}
 
 	// create namespace resource
-	return false, r.ensureNamespace(logger, nsTmplSet, tcNamespace, userNamespace)
+	return false, r.ensureNamespace(logger, nsTmplSet, tcNamespace, nsTemplates[tcNamespace.Type], userNamespace)
 }
 
-func (r *ReconcileNSTemplateSet) ensureNamespace(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcNamespace *toolchainv1alpha1.NSTemplateSetNamespace, userNamespace *corev1.Namespace) error {
-	username := nsTmplSet.GetName()
-
-	log.Info("provisioning namespace", "namespace", tcNamespace)
-	if err := r.setStatusProvisioning(nsTmplSet); err != nil {
-		return err
+func (r *ReconcileNSTemplateSet) ensureNamespace(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcNamespace *toolchainv1alpha1.NSTemplateSetNamespace, nsTemplate NamespaceTemplate, userNamespace *corev1.Namespace) error {
+	if userNamespace != nil && userNamespace.Labels["revision"] != "" {
+		log.Info("updating namespace", "namespace", tcNamespace, "current_revision", userNamespace.Labels["revision"])
+		if err := r.setStatusUpdating(nsTmplSet, tcNamespace, userNamespace.Labels["revision"]); err != nil {
+			return err
+		}
+	} else {
+		log.Info("provisioning namespace", "namespace", tcNamespace)
+		if err := r.setStatusProvisioning(nsTmplSet, tcNamespace); err != nil {
+			return err
+		}
 	}
 
-	params := map[string]string{"USERNAME": username}
-
 	if userNamespace == nil {
-		return r.ensureNamespaceResource(logger, nsTmplSet, tcNamespace, params)
+		return r.ensureNamespaceResource(logger, nsTmplSet, tcNamespace, nsTemplate)
 	}
-	return r.ensureInnerNamespaceResources(logger, nsTmplSet, tcNamespace, params, userNamespace)
+	// the objects of a namespace which was not provisioned yet are all applied, even if some of them are still recorded in the inventory
+	// (eg, if the namespace was deleted and created again)
+	return r.ensureInnerNamespaceResources(logger, nsTmplSet, tcNamespace, nsTemplate, userNamespace, userNamespace.Labels["revision"] != "")
 }
 
-func (r *ReconcileNSTemplateSet) ensureNamespaceResource(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcNamespace *toolchainv1alpha1.NSTemplateSetNamespace, params map[string]string) error {
+func (r *ReconcileNSTemplateSet) ensureNamespaceResource(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcNamespace *toolchainv1alpha1.NSTemplateSetNamespace, nsTemplate NamespaceTemplate) error {
 	username := nsTmplSet.GetName()
 
-	tmpl, err := r.getTemplateContent(nsTmplSet.Spec.TierName, tcNamespace.Type)
+	tmpl, err := r.getTemplateContent(nsTmplSet.Spec.TierName, nsTemplate.Type)
 	if err != nil {
-		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed, err, "failed to to retrieve template for namespace type '%s'", tcNamespace.Type)
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to to retrieve template for namespace type '%s'", tcNamespace.Type)
 	}
 
-	tmplProcessor := template.NewProcessor(r.client, r.scheme)
-	objs, err := tmplProcessor.Process(tmpl, params, template.RetainNamespaces)
+	tmplProcessor, inventory, err := r.newProcessor(logger, nsTmplSet)
 	if err != nil {
-		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed, err, "failed to process template for namespace type '%s'", tcNamespace.Type)
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to load the inventory for namespace type '%s'", tcNamespace.Type)
+	}
+	objs, err := tmplProcessor.Process(tmpl, nsTemplate.Params, template.RetainNamespaces)
+	if err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to process template for namespace type '%s'", tcNamespace.Type)
 	}
 
 	for _, rawObj := range objs {
 		acc, err := meta.Accessor(rawObj.Object)
 		if err != nil {
-			return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed, err, "invalid element in template for namespace type '%s'", tcNamespace.Type)
+			return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "invalid element in template for namespace type '%s'", tcNamespace.Type)
 		}
 
 		// set labels
@@ -186,59 +388,418 @@ func (r *ReconcileNSTemplateSet) ensureNamespaceResource(logger logr.Logger, nsT
 
 		// set owner ref
 		if err := controllerutil.SetControllerReference(nsTmplSet, acc, r.scheme); err != nil {
-			return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed, err, "failed to set controller reference for namespace type '%s'", tcNamespace.Type)
+			return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to set controller reference for namespace type '%s'", tcNamespace.Type)
 		}
 	}
 
-	err = tmplProcessor.Apply(objs)
+	if err := r.requestProjects(logger, objs); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to request the project for namespace type '%s'", tcNamespace.Type)
+	}
+	err = tmplProcessor.ApplyAll(objs)
 	if err != nil {
-		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed, err, "failed to create namespace with type '%s'", tcNamespace.Type)
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to create namespace with type '%s'", tcNamespace.Type)
+	}
+	if err := r.saveInventory(nsTmplSet, inventory); err != nil {
+		statusUpdater := r.applyFailedStatusUpdater(err, namespaceConditionType(tcNamespace.Type), r.setStatusNamespaceProvisionFailed(tcNamespace.Type))
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, statusUpdater, err, "failed to save the inventory for namespace type '%s'", tcNamespace.Type)
 	}
 
 	log.Info("namespace provisioned", "namespace", tcNamespace)
 	return nil
 }
 
-func (r *ReconcileNSTemplateSet) ensureInnerNamespaceResources(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcNamespace *toolchainv1alpha1.NSTemplateSetNamespace, params map[string]string, namespace *corev1.Namespace) error {
+// ensureInnerNamespaceResources applies the objects of the template of the given namespace, and prunes those which are not part of it anymore.
+// If `deltaOnly` is true, only the objects which changed since they were last applied are applied (see the `DeltaApply` feature)
+func (r *ReconcileNSTemplateSet) ensureInnerNamespaceResources(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcNamespace *toolchainv1alpha1.NSTemplateSetNamespace, nsTemplate NamespaceTemplate, namespace *corev1.Namespace, deltaOnly bool) error {
 	nsName := namespace.GetName()
 
-	tmplContent, err := r.getTemplateContent(nsTmplSet.Spec.TierName, tcNamespace.Type)
+	tmplContent, err := r.getTemplateContent(nsTmplSet.Spec.TierName, nsTemplate.Type)
 	if err != nil {
-		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed, err, "failed to to retrieve template for namespace '%s'", nsName)
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to to retrieve template for namespace '%s'", nsName)
 	}
 
-	tmplProcessor := template.NewProcessor(r.client, r.scheme)
-	objs, err := tmplProcessor.Process(tmplContent, params, template.RetainAllButNamespaces)
+	tmplProcessor, inventory, err := r.newProcessor(logger, nsTmplSet)
+	if err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to load the inventory for namespace '%s'", nsName)
+	}
+	tmplProcessor = tmplProcessor.WithDeltaOnly(deltaOnly && config.FeatureEnabled(config.DeltaApply))
+	objs, err := tmplProcessor.Process(tmplContent, nsTemplate.Params, template.RetainAllButNamespaces)
+	if err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to process template for namespace '%s'", nsName)
+	}
+	if err := setEphemeralStorageQuota(objs); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to set the ephemeral storage quota for namespace '%s'", nsName)
+	}
+	if err := setPersistentStorageQuota(objs); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to set the persistent storage quota for namespace '%s'", nsName)
+	}
+	policies, err := r.networkPolicies(tmplProcessor, nsTmplSet.GetName(), nsName, objs)
+	if err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to render the network policies for namespace '%s'", nsName)
+	}
+	objs = append(objs, policies...)
+	roleBindings, err := spaceRoleBindings(nsTmplSet, nsName)
 	if err != nil {
-		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed, err, "failed to process template for namespace '%s'", nsName)
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to render the space roles for namespace '%s'", nsName)
 	}
-	err = tmplProcessor.Apply(objs)
+	objs = append(objs, roleBindings...)
+	objs = append(objs, publicViewerRoleBindings(nsTmplSet, nsName)...)
+	objs = append(objs, podSecurityRoleBindings(nsTmplSet, nsName)...)
+	caBundle, err := r.trustedCABundle(nsTmplSet, nsName)
 	if err != nil {
-		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed, err, "failed to provision namespace '%s' with required resources", nsName)
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to copy the trusted CA bundle in namespace '%s'", nsName)
+	}
+	objs = append(objs, caBundle...)
+	err = r.applyInChunks(logger, nsTmplSet, tmplProcessor, inventory, nsName, objs)
+	if err != nil {
+		statusUpdater := r.applyFailedStatusUpdater(err, namespaceConditionType(tcNamespace.Type), r.setStatusNamespaceProvisionFailed(tcNamespace.Type))
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, statusUpdater, err, "failed to provision namespace '%s' with required resources", nsName)
+	}
+	if err := recordSpecHashes(inventory, objs); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to record the objects applied in namespace '%s'", nsName)
+	}
+	// delete the objects which are not part of the template anymore (in case of an update to a new revision)
+	if err := tmplProcessor.Prune(nsName, objs); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to delete obsolete resources in namespace '%s'", nsName)
+	}
+	if err := r.saveInventory(nsTmplSet, inventory); err != nil {
+		statusUpdater := r.applyFailedStatusUpdater(err, namespaceConditionType(tcNamespace.Type), r.setStatusNamespaceProvisionFailed(tcNamespace.Type))
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, statusUpdater, err, "failed to save the inventory for namespace '%s'", nsName)
 	}
 
 	if namespace.Labels == nil {
 		namespace.Labels = make(map[string]string)
 	}
 	namespace.Labels["revision"] = tcNamespace.Revision
+	template.SetTemplateRefsHash(namespace, template.TemplateRef{Tier: nsTmplSet.Spec.TierName, Type: nsTemplate.Type, Revision: tcNamespace.Revision})
+	setNamespaceAnnotation(namespace, spaceRolesAnnotation, nsTmplSet.GetAnnotations()[spaceRolesAnnotation])
+	setNamespaceAnnotation(namespace, publicViewerAnnotation, publicViewer(nsTmplSet))
+	setPodSecurityLabels(namespace, podSecurityLevel(nsTmplSet))
 	if err := r.client.Update(context.TODO(), namespace); err != nil {
-		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed, err, "failed to update namespace '%s'", nsName)
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusNamespaceProvisionFailed(tcNamespace.Type), err, "failed to update namespace '%s'", nsName)
 	}
 
+	if err := r.setStatusNamespaceReady(nsTmplSet, tcNamespace); err != nil {
+		return err
+	}
 	log.Info("namespace provisioned with required resources", "namespace", tcNamespace)
 
 	// TODO add validation for other objects
 	return nil
 }
 
+// ensureClusterResources applies the template of the cluster-scoped resources if its revision changed since the last time
+// it was applied, and deletes the resources which are not part of it anymore. If the NSTemplateSet has no cluster resources
+// (anymore), the resources which were previously applied are deleted.
+func (r *ReconcileNSTemplateSet) ensureClusterResources(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet) error {
+	tcClusterResources, found := findClusterResources(nsTmplSet.Spec.Namespaces)
+	currentRevision, applied := nsTmplSet.GetAnnotations()[clusterResourcesRevisionAnnotation]
+	if (!found && !applied) || (found && applied && currentRevision == tcClusterResources.Revision) {
+		return nil
+	}
+
+	tmplProcessor, inventory, err := r.newProcessor(logger, nsTmplSet)
+	if err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusClusterResourcesProvisionFailed, err, "failed to load the inventory for the cluster resources")
+	}
+	// the cluster resources are recorded apart from the user namespaces, which are not namespaced either
+	tmplProcessor = tmplProcessor.WithTemplateType(template.ClusterResourcesType)
+	var objs []runtime.RawExtension
+	if found {
+		log.Info("provisioning cluster resources", "revision", tcClusterResources.Revision, "current_revision", currentRevision)
+		// only the objects which changed since the previous revision are applied
+		tmplProcessor = tmplProcessor.WithDeltaOnly(applied && config.FeatureEnabled(config.DeltaApply))
+		tmpl, err := r.getTemplateContent(nsTmplSet.Spec.TierName, tcClusterResources.Type)
+		if err != nil {
+			return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusClusterResourcesProvisionFailed, err, "failed to retrieve the template for the cluster resources")
+		}
+		objs, err = tmplProcessor.Process(tmpl, map[string]string{"USERNAME": nsTmplSet.GetName()})
+		if err != nil {
+			return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusClusterResourcesProvisionFailed, err, "failed to process the template for the cluster resources")
+		}
+		if err := setEphemeralStorageQuota(objs); err != nil {
+			return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusClusterResourcesProvisionFailed, err, "failed to set the ephemeral storage quota for the cluster resources")
+		}
+		if err := setPersistentStorageQuota(objs); err != nil {
+			return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusClusterResourcesProvisionFailed, err, "failed to set the persistent storage quota for the cluster resources")
+		}
+		for _, rawObj := range objs {
+			acc, err := meta.Accessor(rawObj.Object)
+			if err != nil {
+				return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusClusterResourcesProvisionFailed, err, "invalid element in the template for the cluster resources")
+			}
+			// cluster-scoped resources cannot have an owner reference to the (namespaced) NSTemplateSet
+			labels := acc.GetLabels()
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels["owner"] = nsTmplSet.GetName()
+			acc.SetLabels(labels)
+		}
+		if err := r.applyInChunks(logger, nsTmplSet, tmplProcessor, inventory, "", objs); err != nil {
+			statusUpdater := r.applyFailedStatusUpdater(err, clusterResourcesReadyCondition, r.setStatusClusterResourcesProvisionFailed)
+			return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, statusUpdater, err, "failed to apply the cluster resources")
+		}
+	} else {
+		log.Info("deleting cluster resources", "current_revision", currentRevision)
+	}
+	if err := tmplProcessor.Prune("", objs); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusClusterResourcesProvisionFailed, err, "failed to delete obsolete cluster resources")
+	}
+
+	// save the inventory along with the revision that was applied
+	content, err := inventoryContent(nsTmplSet, inventory)
+	if err != nil {
+		statusUpdater := r.applyFailedStatusUpdater(err, clusterResourcesReadyCondition, r.setStatusClusterResourcesProvisionFailed)
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, statusUpdater, err, "failed to save the inventory for the cluster resources")
+	}
+	annotations := nsTmplSet.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[inventoryAnnotation] = content
+	if found {
+		annotations[clusterResourcesRevisionAnnotation] = tcClusterResources.Revision
+	} else {
+		delete(annotations, clusterResourcesRevisionAnnotation)
+	}
+	nsTmplSet.SetAnnotations(annotations)
+	if err := r.client.Update(context.TODO(), nsTmplSet); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusClusterResourcesProvisionFailed, err, "failed to update the NSTemplateSet with the revision of the cluster resources")
+	}
+	if !found {
+		// the condition would report a revision which is not applied anymore
+		return r.removeStatusCondition(nsTmplSet, clusterResourcesReadyCondition)
+	}
+	return r.setStatusClusterResourcesReady(nsTmplSet, tcClusterResources)
+}
+
+// addFinalizer adds the finalizer to the NSTemplateSet if it is not present yet
+func (r *ReconcileNSTemplateSet) addFinalizer(nsTmplSet *toolchainv1alpha1.NSTemplateSet) error {
+	if !util.HasFinalizer(nsTmplSet, nsTmplSetFinalizerName) {
+		util.AddFinalizer(nsTmplSet, nsTmplSetFinalizerName)
+		if err := r.client.Update(context.TODO(), nsTmplSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manageCleanUp deletes the user namespaces and waits until they are gone, then deletes the cluster resources
+// and finally removes the finalizer when the NSTemplateSet is being deleted. The namespaces which remain stuck
+// in the `Terminating` phase are reported in the status, and their known-safe finalizers are removed.
+func (r *ReconcileNSTemplateSet) manageCleanUp(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet) (reconcile.Result, error) {
+	username := nsTmplSet.GetName()
+	userNamespaces := &corev1.NamespaceList{}
+	if err := r.client.List(context.TODO(), userNamespaces, client.MatchingLabels(map[string]string{"owner": username})); err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusTerminationFailed, err, "failed to list namespace with label owner '%s'", username)
+	}
+	if len(userNamespaces.Items) > 0 {
+		for i := range userNamespaces.Items {
+			ns := &userNamespaces.Items[i]
+			if ns.DeletionTimestamp != nil {
+				// already being deleted
+				continue
+			}
+			log.Info("deleting namespace", "namespace", ns.Name)
+			if err := r.client.Delete(context.TODO(), ns); err != nil && !errors.IsNotFound(err) {
+				return reconcile.Result{}, r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusTerminationFailed, err, "failed to delete namespace '%s'", ns.Name)
+			}
+		}
+		// wait until all the namespaces are gone (the deletion of a namespace triggers a new reconcile of its owner)
+		return r.checkStuckNamespaces(logger, nsTmplSet, userNamespaces.Items)
+	}
+
+	// delete all the cluster resources recorded in the inventory
+	tmplProcessor, _, err := r.newProcessor(logger, nsTmplSet)
+	if err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusTerminationFailed, err, "failed to load the inventory")
+	}
+	if err := tmplProcessor.WithTemplateType(template.ClusterResourcesType).Prune("", nil); err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusTerminationFailed, err, "failed to delete the cluster resources")
+	}
+	// the user namespaces are all gone at this point, hence the other cluster-scoped objects of the inventory are the cluster resources
+	// which were recorded before they had their own template type
+	if err := tmplProcessor.Prune("", nil); err != nil {
+		return reconcile.Result{}, r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusTerminationFailed, err, "failed to delete the cluster resources")
+	}
+
+	util.RemoveFinalizer(nsTmplSet, nsTmplSetFinalizerName)
+	return reconcile.Result{}, r.client.Update(context.TODO(), nsTmplSet)
+}
+
+// setEphemeralStorageQuota adds the ephemeral storage quota of the MemberOperatorConfig (if any) to the quotas among the given objects
+// which do not define it
+func setEphemeralStorageQuota(objs []runtime.RawExtension) error {
+	quota := config.GetEphemeralStorage().Quota
+	if quota == "" {
+		return nil
+	}
+	quantity, err := resource.ParseQuantity(quota)
+	if err != nil {
+		return errs.Wrapf(err, "invalid ephemeral storage quota '%s'", quota)
+	}
+	return template.SetDefaultQuota(objs, corev1.ResourceList{
+		corev1.ResourceRequestsEphemeralStorage: quantity,
+		corev1.ResourceLimitsEphemeralStorage:   quantity,
+	})
+}
+
+// setPersistentStorageQuota adds the persistent storage quota and the maximum number of claims of the MemberOperatorConfig (if any)
+// to the quotas among the given objects which do not define them
+func setPersistentStorageQuota(objs []runtime.RawExtension) error {
+	cfg := config.GetPersistentStorage()
+	hard := corev1.ResourceList{}
+	if cfg.Quota != "" {
+		quantity, err := resource.ParseQuantity(cfg.Quota)
+		if err != nil {
+			return errs.Wrapf(err, "invalid persistent storage quota '%s'", cfg.Quota)
+		}
+		hard[corev1.ResourceRequestsStorage] = quantity
+	}
+	if cfg.MaxClaims > 0 {
+		hard[corev1.ResourcePersistentVolumeClaims] = *resource.NewQuantity(int64(cfg.MaxClaims), resource.DecimalSI)
+	}
+	return template.SetDefaultQuota(objs, hard)
+}
+
+// newProcessor returns a new template processor which logs with the given logger, along with the inventory of the objects
+// that were previously applied for the given NSTemplateSet
+func (r *ReconcileNSTemplateSet) newProcessor(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet) (template.Processor, *template.Inventory, error) {
+	inventory, err := template.ParseInventory(nsTmplSet.GetAnnotations()[inventoryAnnotation])
+	if err != nil {
+		return template.Processor{}, nil, err
+	}
+	options := template.Options{
+		Inventory:      inventory,
+		ProtobufClient: r.protoClient,
+		RESTMapper:     r.mapper,
+		Decrypter:      newTemplateDecrypter(r.client, nsTmplSet.Namespace),
+		Fragments:      nstemplatetier.NewFragmentGetter(cluster.GetHostCluster),
+		Span:           r.span,
+		Guardrails:     templateGuardrails(),
+		Interrupted:    r.interrupted,
+		Logger:         logger.WithName("template"),
+	}
+	if mirrors := config.GetImageMirrors(); len(mirrors) > 0 {
+		options.Mutators = append(options.Mutators, template.ImageMirrors(mirrors))
+	}
+	if config.FeatureEnabled(config.CachedTemplateReads) {
+		options.Cache = r.cache
+	}
+	processor := template.NewProcessorWithOptions(r.client, r.scheme, options)
+	return processor, inventory, nil
+}
+
+// templateGuardrails returns the limits enforced on the processed tier templates, as specified in the MemberOperatorConfig.
+// An invalid maximum object size is replaced with its default value
+func templateGuardrails() template.Guardrails {
+	cfg := config.GetTemplateGuardrails()
+	maxObjectSize, err := resource.ParseQuantity(cfg.MaxObjectSize)
+	if err != nil {
+		log.Error(err, "invalid maximum size of the template objects, using the default size", "max_object_size", cfg.MaxObjectSize)
+		maxObjectSize = resource.MustParse(config.DefaultTemplateMaxObjectSize)
+	}
+	guardrails := template.Guardrails{
+		MaxObjects:    int(cfg.MaxObjects),
+		MaxObjectSize: maxObjectSize.Value(),
+	}
+	for _, kind := range cfg.AllowedKinds {
+		guardrails.AllowedKinds = append(guardrails.AllowedKinds, schema.ParseGroupKind(strings.TrimSpace(kind)))
+	}
+	return guardrails
+}
+
+// saveInventory stores the given inventory in the annotations of the NSTemplateSet, if it changed
+func (r *ReconcileNSTemplateSet) saveInventory(nsTmplSet *toolchainv1alpha1.NSTemplateSet, inventory *template.Inventory) error {
+	content, err := inventoryContent(nsTmplSet, inventory)
+	if err != nil {
+		return err
+	}
+	annotations := nsTmplSet.GetAnnotations()
+	if annotations[inventoryAnnotation] == content {
+		return nil
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[inventoryAnnotation] = content
+	nsTmplSet.SetAnnotations(annotations)
+	return r.client.Update(context.TODO(), nsTmplSet)
+}
+
+// compactInventory removes the entries of the objects in the namespaces which do not exist anymore from the inventory,
+// so that it does not grow indefinitely as the namespaces of the user come and go. The inventory is saved within its limits
+// (see `inventoryContent`), hence an error is returned if it still exceeds them once compacted
+func (r *ReconcileNSTemplateSet) compactInventory(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, userNamespaces []corev1.Namespace) error {
+	inventory, err := template.ParseInventory(nsTmplSet.GetAnnotations()[inventoryAnnotation])
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(userNamespaces))
+	for i, ns := range userNamespaces {
+		names[i] = ns.Name
+	}
+	if removed := inventory.Compact(names); removed > 0 {
+		logger.Info("compacting the inventory", "removed_entries", removed)
+		template.RecordCompaction(inventoryAnnotation)
+	}
+	return r.saveInventory(nsTmplSet, inventory)
+}
+
+// inventoryLimits returns the limits of the inventory, as specified in the MemberOperatorConfig. An invalid maximum size
+// is replaced with its default value
+func inventoryLimits() template.InventoryLimits {
+	cfg := config.GetInventoryLimits()
+	maxSize, err := resource.ParseQuantity(cfg.MaxSize)
+	if err != nil {
+		log.Error(err, "invalid maximum size of the inventory, using the default size", "max_size", cfg.MaxSize)
+		maxSize = resource.MustParse(config.DefaultInventoryMaxSize)
+	}
+	return template.InventoryLimits{
+		MaxSize:    int(maxSize.Value()),
+		MaxEntries: int(cfg.MaxEntries),
+	}
+}
+
+// inventoryContent returns the content of the inventory annotation of the given NSTemplateSet, within the limits of the inventory.
+// The hashes of the applied objects are dropped when the inventory exceeds its maximum size, and an `*InventoryLimitError` is returned
+// when it still exceeds its limits, in which case the annotation is left as is
+func inventoryContent(nsTmplSet *toolchainv1alpha1.NSTemplateSet, inventory *template.Inventory) (string, error) {
+	content, dropped, err := inventory.Bounded(inventoryLimits())
+	if dropped {
+		log.Info("dropping the hashes of the applied objects from the inventory", "name", nsTmplSet.Name)
+		template.RecordCompaction(inventoryAnnotation)
+	}
+	return content, err
+}
+
+// setNamespaceAnnotation sets the given annotation on the namespace, or removes it if the value is empty
+func setNamespaceAnnotation(namespace *corev1.Namespace, key, value string) {
+	if value == "" {
+		delete(namespace.Annotations, key)
+		return
+	}
+	if namespace.Annotations == nil {
+		namespace.Annotations = make(map[string]string)
+	}
+	namespace.Annotations[key] = value
+}
+
 // nextNamespaceToProvision returns first namespace (from given namespaces) with
-// namespace status is active and revision not set
+// namespace status is active and revision not set or not matching the expected revision, or space roles, public viewer
+// or pod security level not matching the expected ones (ie, the namespace needs to be updated)
 // or namespace present in tcNamespaces but not found in given namespaces
-func nextNamespaceToProvision(tcNamespaces []toolchainv1alpha1.NSTemplateSetNamespace, namespaces []corev1.Namespace) (*toolchainv1alpha1.NSTemplateSetNamespace, *corev1.Namespace, bool) {
+func nextNamespaceToProvision(tcNamespaces []toolchainv1alpha1.NSTemplateSetNamespace, namespaces []corev1.Namespace, spaceRoles, publicViewer, podSecurityLevel string) (*toolchainv1alpha1.NSTemplateSetNamespace, *corev1.Namespace, bool) {
 	for _, tcNamespace := range tcNamespaces {
+		if tcNamespace.Type == template.ClusterResourcesType {
+			continue
+		}
 		namespace, found := findNamespace(namespaces, tcNamespace.Type)
 		if found {
-			if namespace.Status.Phase == corev1.NamespaceActive && namespace.Labels["revision"] == "" {
+			if namespace.Status.Phase == corev1.NamespaceActive &&
+				(namespace.Labels["revision"] != tcNamespace.Revision || namespace.Annotations[spaceRolesAnnotation] != spaceRoles ||
+					namespace.Annotations[publicViewerAnnotation] != publicViewer || !hasPodSecurityLabels(namespace, podSecurityLevel)) {
 				return &tcNamespace, &namespace, true
 			}
 		} else {
@@ -248,6 +809,16 @@ func nextNamespaceToProvision(tcNamespaces []toolchainv1alpha1.NSTemplateSetName
 	return nil, nil, false
 }
 
+// findClusterResources returns the entry of the cluster-scoped resources, if there is one
+func findClusterResources(tcNamespaces []toolchainv1alpha1.NSTemplateSetNamespace) (*toolchainv1alpha1.NSTemplateSetNamespace, bool) {
+	for _, tcNamespace := range tcNamespaces {
+		if tcNamespace.Type == template.ClusterResourcesType {
+			return &tcNamespace, true
+		}
+	}
+	return nil, false
+}
+
 func findNamespace(namespaces []corev1.Namespace, typeName string) (corev1.Namespace, bool) {
 	for _, ns := range namespaces {
 		if ns.Labels["type"] == typeName {
@@ -258,7 +829,7 @@ func findNamespace(namespaces []corev1.Namespace, typeName string) (corev1.Names
 }
 
 func getTemplateContentFromHost(tierName, typeName string) (*templatev1.Template, error) {
-	templates, err := template.GetNSTemplates(cluster.GetHostCluster, tierName)
+	templates, err := nstemplatetier.GetNSTemplates(cluster.GetHostCluster, tierName)
 	if err != nil {
 		return nil, err
 	}
@@ -288,12 +859,28 @@ func (r *ReconcileNSTemplateSet) wrapErrorWithStatusUpdate(logger logr.Logger, n
 
 func (r *ReconcileNSTemplateSet) updateStatusConditions(nsTmplSet *toolchainv1alpha1.NSTemplateSet, newConditions ...toolchainv1alpha1.Condition) error {
 	var updated bool
-	nsTmplSet.Status.Conditions, updated = condition.AddOrUpdateStatusConditions(nsTmplSet.Status.Conditions, newConditions...)
+	nsTmplSet.Status.Conditions, updated = conditions.Update(nsTmplSet.Status.Conditions, newConditions...)
 	if !updated {
 		// Nothing changed
 		return nil
 	}
-	return r.client.Status().Update(context.TODO(), nsTmplSet)
+	span := r.span.Child("update status")
+	err := r.client.Status().Update(context.TODO(), nsTmplSet)
+	span.End(err)
+	return err
+}
+
+// removeStatusCondition removes the condition of the given type from the status of the given NSTemplateSet, if it exists
+func (r *ReconcileNSTemplateSet) removeStatusCondition(nsTmplSet *toolchainv1alpha1.NSTemplateSet, condType toolchainv1alpha1.ConditionType) error {
+	var removed bool
+	nsTmplSet.Status.Conditions, removed = conditions.Remove(nsTmplSet.Status.Conditions, condType)
+	if !removed {
+		return nil
+	}
+	span := r.span.Child("update status")
+	err := r.client.Status().Update(context.TODO(), nsTmplSet)
+	span.End(err)
+	return err
 }
 
 func (r *ReconcileNSTemplateSet) setStatusProvisionFailed(nsTmplSet *toolchainv1alpha1.NSTemplateSet, message string) error {
@@ -307,17 +894,40 @@ func (r *ReconcileNSTemplateSet) setStatusProvisionFailed(nsTmplSet *toolchainv1
 		})
 }
 
-func (r *ReconcileNSTemplateSet) setStatusProvisioning(nsTmplSet *toolchainv1alpha1.NSTemplateSet) error {
+func (r *ReconcileNSTemplateSet) setStatusProvisioning(nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcNamespace *toolchainv1alpha1.NSTemplateSetNamespace) error {
 	return r.updateStatusConditions(
 		nsTmplSet,
 		toolchainv1alpha1.Condition{
 			Type:   toolchainv1alpha1.ConditionReady,
 			Status: corev1.ConditionFalse,
 			Reason: provisioningReason,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    namespaceConditionType(tcNamespace.Type),
+			Status:  corev1.ConditionFalse,
+			Reason:  provisioningReason,
+			Message: fmt.Sprintf("provisioning revision '%s'", tcNamespace.Revision),
+		})
+}
+
+func (r *ReconcileNSTemplateSet) setStatusUpdating(nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcNamespace *toolchainv1alpha1.NSTemplateSetNamespace, currentRevision string) error {
+	return r.updateStatusConditions(
+		nsTmplSet,
+		toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: updatingReason,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    namespaceConditionType(tcNamespace.Type),
+			Status:  corev1.ConditionFalse,
+			Reason:  updatingReason,
+			Message: fmt.Sprintf("updating from revision '%s' to revision '%s'", currentRevision, tcNamespace.Revision),
 		})
 }
 
 func (r *ReconcileNSTemplateSet) setStatusReady(nsTmplSet *toolchainv1alpha1.NSTemplateSet) error {
+	metrics.RecordProvisioned(metrics.NSTemplateSetKind, nsTmplSet.CreationTimestamp, nsTmplSet.Status.Conditions)
 	return r.updateStatusConditions(
 		nsTmplSet,
 		toolchainv1alpha1.Condition{
@@ -327,13 +937,113 @@ func (r *ReconcileNSTemplateSet) setStatusReady(nsTmplSet *toolchainv1alpha1.NST
 		})
 }
 
-func (r *ReconcileNSTemplateSet) setStatusNamespaceProvisionFailed(nsTmplSet *toolchainv1alpha1.NSTemplateSet, message string) error {
+// setStatusNamespaceProvisionFailed returns a status updater which reports the failure on the aggregate `Ready` condition
+// as well as on the condition of the namespace with the given type
+func (r *ReconcileNSTemplateSet) setStatusNamespaceProvisionFailed(typeName string) func(*toolchainv1alpha1.NSTemplateSet, string) error {
+	return func(nsTmplSet *toolchainv1alpha1.NSTemplateSet, message string) error {
+		return r.updateStatusConditions(
+			nsTmplSet,
+			toolchainv1alpha1.Condition{
+				Type:    toolchainv1alpha1.ConditionReady,
+				Status:  corev1.ConditionFalse,
+				Reason:  unableToProvisionNamespaceReason,
+				Message: message,
+			},
+			toolchainv1alpha1.Condition{
+				Type:    namespaceConditionType(typeName),
+				Status:  corev1.ConditionFalse,
+				Reason:  unableToProvisionNamespaceReason,
+				Message: message,
+			})
+	}
+}
+
+// setStatusNamespaceReady sets the condition of the namespace with the given type to `true`, along with
+// the revision of the template that was applied and the time at which it was applied
+func (r *ReconcileNSTemplateSet) setStatusNamespaceReady(nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcNamespace *toolchainv1alpha1.NSTemplateSetNamespace) error {
+	condType := namespaceConditionType(tcNamespace.Type)
+	return r.updateStatusConditions(
+		nsTmplSet,
+		toolchainv1alpha1.Condition{
+			Type:    condType,
+			Status:  corev1.ConditionTrue,
+			Reason:  provisionedReason,
+			Message: revisionAppliedMessage(nsTmplSet, condType, tcNamespace.Revision),
+		})
+}
+
+// revisionAppliedMessage returns the message of the given condition once the given revision is applied. The message of the
+// current condition is kept if it already reports the same revision, so that the status is not updated at every reconcile
+func revisionAppliedMessage(nsTmplSet *toolchainv1alpha1.NSTemplateSet, condType toolchainv1alpha1.ConditionType, revision string) string {
+	prefix := fmt.Sprintf("revision '%s' applied at ", revision)
+	if cond, found := condition.FindConditionByType(nsTmplSet.Status.Conditions, condType); found &&
+		cond.Status == corev1.ConditionTrue && strings.HasPrefix(cond.Message, prefix) {
+		return cond.Message
+	}
+	return prefix + time.Now().UTC().Format(time.RFC3339)
+}
+
+// namespaceConditionType returns the type of the condition which reports the status of the namespace with the given type
+func namespaceConditionType(typeName string) toolchainv1alpha1.ConditionType {
+	return toolchainv1alpha1.ConditionType(strings.Title(typeName) + namespaceReadyConditionSuffix)
+}
+
+func (r *ReconcileNSTemplateSet) setStatusClusterResourcesProvisionFailed(nsTmplSet *toolchainv1alpha1.NSTemplateSet, message string) error {
+	return r.updateStatusConditions(
+		nsTmplSet,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToProvisionClusterResourcesReason,
+			Message: message,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    clusterResourcesReadyCondition,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToProvisionClusterResourcesReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileNSTemplateSet) setStatusClusterResourcesReady(nsTmplSet *toolchainv1alpha1.NSTemplateSet, tcClusterResources *toolchainv1alpha1.NSTemplateSetNamespace) error {
+	return r.updateStatusConditions(
+		nsTmplSet,
+		toolchainv1alpha1.Condition{
+			Type:    clusterResourcesReadyCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  provisionedReason,
+			Message: revisionAppliedMessage(nsTmplSet, clusterResourcesReadyCondition, tcClusterResources.Revision),
+		})
+}
+
+func (r *ReconcileNSTemplateSet) setStatusTerminating(nsTmplSet *toolchainv1alpha1.NSTemplateSet) error {
+	return r.updateStatusConditions(
+		nsTmplSet,
+		toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: terminatingReason,
+		})
+}
+
+func (r *ReconcileNSTemplateSet) setStatusTerminationStuck(nsTmplSet *toolchainv1alpha1.NSTemplateSet, message string) error {
+	return r.updateStatusConditions(
+		nsTmplSet,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  terminationStuckReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileNSTemplateSet) setStatusTerminationFailed(nsTmplSet *toolchainv1alpha1.NSTemplateSet, message string) error {
 	return r.updateStatusConditions(
 		nsTmplSet,
 		toolchainv1alpha1.Condition{
 			Type:    toolchainv1alpha1.ConditionReady,
 			Status:  corev1.ConditionFalse,
-			Reason:  unableToProvisionNamespaceReason,
+			Reason:  unableToTerminateReason,
 			Message: message,
 		})
 }
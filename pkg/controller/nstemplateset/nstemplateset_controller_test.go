@@ -10,6 +10,10 @@ import (
 	"testing"
 
 	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/pause"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
 	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 
@@ -25,8 +29,10 @@ import (
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
 	authv1 "github.com/openshift/api/authorization/v1"
+	quotav1 "github.com/openshift/api/quota/v1"
 	templatev1 "github.com/openshift/api/template/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	apierros "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
@@ -81,7 +87,7 @@ func TestNextMissingNamespace(t *testing.T) {
 
 	t.Run("revision_not_set", func(t *testing.T) {
 		// test
-		tcNS, userNS, found := nextNamespaceToProvision(tcNamespaces, userNamespaces)
+		tcNS, userNS, found := nextNamespaceToProvision(tcNamespaces, userNamespaces, "", "", "")
 
 		assert.True(t, found)
 		assert.Equal(t, "code", tcNS.Type)
@@ -89,10 +95,10 @@ func TestNextMissingNamespace(t *testing.T) {
 	})
 
 	t.Run("missing_namespace", func(t *testing.T) {
-		userNamespaces[1].Labels["revision"] = "abcde11"
+		userNamespaces[1].Labels["revision"] = "abcde21"
 
 		// test
-		tcNS, userNS, found := nextNamespaceToProvision(tcNamespaces, userNamespaces)
+		tcNS, userNS, found := nextNamespaceToProvision(tcNamespaces, userNamespaces, "", "", "")
 
 		assert.True(t, found)
 		assert.Equal(t, "stage", tcNS.Type)
@@ -100,19 +106,78 @@ func TestNextMissingNamespace(t *testing.T) {
 	})
 
 	t.Run("namespace_not_found", func(t *testing.T) {
-		userNamespaces[1].Labels["revision"] = "abcde11"
+		userNamespaces[1].Labels["revision"] = "abcde21"
 		userNamespaces = append(userNamespaces, corev1.Namespace{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: "johnsmith-stage", Labels: map[string]string{"revision": "abcde11", "type": "stage"},
+				Name: "johnsmith-stage", Labels: map[string]string{"revision": "abcde31", "type": "stage"},
 			},
 			Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
 		})
 
 		// test
-		_, _, found := nextNamespaceToProvision(tcNamespaces, userNamespaces)
+		_, _, found := nextNamespaceToProvision(tcNamespaces, userNamespaces, "", "", "")
 
 		assert.False(t, found)
 	})
+
+	t.Run("revision_changed", func(t *testing.T) {
+		updatedTCNamespaces := []toolchainv1alpha1.NSTemplateSetNamespace{
+			{Type: "dev", Revision: "abcde11"},
+			{Type: "code", Revision: "abcde22"},
+			{Type: "stage", Revision: "abcde31"},
+		}
+
+		// test
+		tcNS, userNS, found := nextNamespaceToProvision(updatedTCNamespaces, userNamespaces, "", "", "")
+
+		assert.True(t, found)
+		assert.Equal(t, "code", tcNS.Type)
+		assert.Equal(t, "abcde22", tcNS.Revision)
+		assert.Equal(t, "johnsmith-code", userNS.GetName())
+	})
+
+	t.Run("space_roles_changed", func(t *testing.T) {
+		// test
+		tcNS, userNS, found := nextNamespaceToProvision(tcNamespaces, userNamespaces, `[{"username":"jane","role":"admin"}]`, "", "")
+
+		assert.True(t, found)
+		assert.Equal(t, "dev", tcNS.Type)
+		assert.Equal(t, "johnsmith-dev", userNS.GetName())
+	})
+
+	t.Run("public_viewer_changed", func(t *testing.T) {
+		// test
+		tcNS, userNS, found := nextNamespaceToProvision(tcNamespaces, userNamespaces, "", "Group::system:authenticated:view", "")
+
+		assert.True(t, found)
+		assert.Equal(t, "dev", tcNS.Type)
+		assert.Equal(t, "johnsmith-dev", userNS.GetName())
+	})
+
+	t.Run("pod_security_level_changed", func(t *testing.T) {
+		// test
+		tcNS, userNS, found := nextNamespaceToProvision(tcNamespaces, userNamespaces, "", "", "restricted")
+
+		assert.True(t, found)
+		assert.Equal(t, "dev", tcNS.Type)
+		assert.Equal(t, "johnsmith-dev", userNS.GetName())
+	})
+
+	t.Run("cluster_resources_ignored", func(t *testing.T) {
+		withClusterResources := append([]toolchainv1alpha1.NSTemplateSetNamespace{
+			{Type: "clusterresources", Revision: "abcde41"},
+		}, tcNamespaces...)
+
+		// test
+		_, _, found := nextNamespaceToProvision(withClusterResources, userNamespaces, "", "", "")
+
+		assert.False(t, found)
+	})
+}
+
+func TestNamespaceConditionType(t *testing.T) {
+	assert.Equal(t, toolchainv1alpha1.ConditionType("DevNamespaceReady"), namespaceConditionType("dev"))
+	assert.Equal(t, toolchainv1alpha1.ConditionType("StageNamespaceReady"), namespaceConditionType("stage"))
 }
 
 func TestGetNamespaceName(t *testing.T) {
@@ -195,6 +260,7 @@ func TestReconcileProvisionOK(t *testing.T) {
 		reconcile(r, req)
 
 		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Provisioning")
+		checkNamespaceCond(t, fakeClient, "dev", corev1.ConditionFalse, "Provisioning", "provisioning revision 'abcde11'")
 		checkNamespace(t, r.client, username, "dev")
 	})
 
@@ -221,7 +287,47 @@ func TestReconcileProvisionOK(t *testing.T) {
 		reconcile(r, req)
 
 		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Provisioning")
+		checkNamespaceCond(t, fakeClient, "dev", corev1.ConditionTrue, "Provisioned", "revision 'abcde11' applied at ")
 		checkInnerResources(t, fakeClient, namespace.GetName())
+		err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: namespace.GetName()}, namespace)
+		require.NoError(t, err)
+		assert.True(t, template.TemplateRefsHashMatches(namespace, template.TemplateRef{Tier: "basic", Type: "dev", Revision: "abcde11"}))
+	})
+
+	t.Run("namespace_ready_message_kept_for_same_revision", func(t *testing.T) {
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Status.Conditions = []toolchainv1alpha1.Condition{{
+			Type:    namespaceConditionType("dev"),
+			Status:  corev1.ConditionTrue,
+			Reason:  "Provisioned",
+			Message: "revision 'abcde11' applied at 2019-11-01T10:00:00Z",
+		}}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		createNamespace(t, fakeClient, "", "dev")
+
+		// test
+		reconcile(r, req)
+
+		checkNamespaceCond(t, fakeClient, "dev", corev1.ConditionTrue, "Provisioned", "revision 'abcde11' applied at 2019-11-01T10:00:00Z")
+	})
+
+	t.Run("namespace_ready_message_refreshed_for_new_revision", func(t *testing.T) {
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Status.Conditions = []toolchainv1alpha1.Condition{{
+			Type:    namespaceConditionType("dev"),
+			Status:  corev1.ConditionTrue,
+			Reason:  "Provisioned",
+			Message: "revision 'abcde10' applied at 2019-11-01T10:00:00Z",
+		}}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		createNamespace(t, fakeClient, "", "dev")
+
+		// test
+		reconcile(r, req)
+
+		checkNamespaceCond(t, fakeClient, "dev", corev1.ConditionTrue, "Provisioned", "revision 'abcde11' applied at ")
+		nsCond := getNamespaceCond(t, fakeClient, "dev")
+		assert.NotContains(t, nsCond.Message, "2019-11-01T10:00:00Z")
 	})
 
 	t.Run("status_provisioned_ok", func(t *testing.T) {
@@ -237,6 +343,105 @@ func TestReconcileProvisionOK(t *testing.T) {
 		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
 	})
 
+	t.Run("namespace_updated_to_new_revision_ok", func(t *testing.T) {
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+
+		// create namesapces, with dev in a previous revision
+		namespace := createNamespace(t, fakeClient, "abcde10", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// test
+		reconcile(r, req)
+
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Updating")
+		checkNamespaceCond(t, fakeClient, "dev", corev1.ConditionTrue, "Provisioned", "revision 'abcde11' applied at ")
+		checkInnerResources(t, fakeClient, namespace.GetName())
+		updatedNamespace := &corev1.Namespace{}
+		err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: namespace.GetName()}, updatedNamespace)
+		require.NoError(t, err)
+		assert.Equal(t, "abcde11", updatedNamespace.Labels["revision"])
+	})
+
+	t.Run("obsolete_resources_deleted_on_update_ok", func(t *testing.T) {
+		// given an inventory with a resource which is not part of the dev template anymore
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			inventoryAnnotation: `{"entries":[{"apiVersion":"v1","kind":"ConfigMap","namespace":"johnsmith-dev","name":"obsolete"}]}`,
+		}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		createNamespace(t, fakeClient, "abcde10", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "obsolete", Namespace: "johnsmith-dev"}}
+		err := fakeClient.Create(context.TODO(), cm)
+		require.NoError(t, err)
+
+		// test
+		reconcile(r, req)
+
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "obsolete", Namespace: "johnsmith-dev"}, &corev1.ConfigMap{})
+		require.Error(t, err)
+		assert.True(t, apierros.IsNotFound(err))
+		updatedNSTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, updatedNSTmplSet)
+		require.NoError(t, err)
+		assert.NotContains(t, updatedNSTmplSet.Annotations[inventoryAnnotation], "obsolete")
+	})
+
+	t.Run("inventory_of_deleted_namespaces_compacted_ok", func(t *testing.T) {
+		// given an inventory with the resources of a namespace which does not exist anymore
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			inventoryAnnotation: `{"entries":[` +
+				`{"apiVersion":"v1","kind":"ConfigMap","namespace":"johnsmith-dev","name":"current"},` +
+				`{"apiVersion":"v1","kind":"ConfigMap","namespace":"johnsmith-stage","name":"deleted"}]}`,
+		}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// test
+		reconcile(r, req)
+
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		updatedNSTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, updatedNSTmplSet)
+		require.NoError(t, err)
+		assert.Contains(t, updatedNSTmplSet.Annotations[inventoryAnnotation], "johnsmith-dev")
+		assert.NotContains(t, updatedNSTmplSet.Annotations[inventoryAnnotation], "johnsmith-stage")
+	})
+
+	t.Run("applied_hashes_dropped_when_inventory_exceeds_max_size_ok", func(t *testing.T) {
+		// given an inventory which exceeds the maximum size with the hashes of the applied objects
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			inventoryAnnotation: `{"entries":[` +
+				`{"apiVersion":"v1","kind":"ConfigMap","namespace":"johnsmith-dev","name":"first","appliedHash":"0123456789abcdef"},` +
+				`{"apiVersion":"v1","kind":"ConfigMap","namespace":"johnsmith-dev","name":"second","appliedHash":"0123456789abcdef"}]}`,
+		}
+		cfg := &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				Inventory: &memberv1alpha1.InventoryConfig{MaxSize: "200"},
+			},
+		}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet, cfg)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// test
+		reconcile(r, req)
+
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		updatedNSTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, updatedNSTmplSet)
+		require.NoError(t, err)
+		inventory := updatedNSTmplSet.Annotations[inventoryAnnotation]
+		assert.True(t, len(inventory) <= 200, "size: %d", len(inventory))
+		assert.Contains(t, inventory, `"name":"first"`)
+		assert.Contains(t, inventory, `"name":"second"`)
+		assert.NotContains(t, inventory, "appliedHash")
+	})
+
 	t.Run("nstmplset_not_found", func(t *testing.T) {
 		r, req, _ := prepareReconcile(t)
 
@@ -267,6 +472,7 @@ func TestReconcileProvisionFail(t *testing.T) {
 		reconcile(r, req, "unable to create namespace")
 
 		checkStatus(t, fakeClient, "UnableToProvisionNamespace")
+		checkNamespaceCond(t, fakeClient, "dev", corev1.ConditionFalse, "UnableToProvisionNamespace", "unable to create namespace")
 	})
 
 	t.Run("fail_create_inner_resources", func(t *testing.T) {
@@ -282,6 +488,101 @@ func TestReconcileProvisionFail(t *testing.T) {
 		reconcile(r, req, "unable to create some object")
 
 		checkStatus(t, fakeClient, "UnableToProvisionNamespace")
+		checkNamespaceCond(t, fakeClient, "dev", corev1.ConditionFalse, "UnableToProvisionNamespace", "unable to create some object")
+	})
+
+	t.Run("fail_template_rejected_by_guardrails", func(t *testing.T) {
+		cfg := &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				TemplateGuardrails: &memberv1alpha1.TemplateGuardrailsConfig{MaxObjects: 1},
+			},
+		}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet, cfg)
+
+		createNamespace(t, fakeClient, "", "dev")
+
+		// test
+		reconcile(r, req, "objects exceed the maximum of 1 objects")
+
+		checkStatus(t, fakeClient, "UnableToProvisionNamespace")
+	})
+
+	t.Run("fail_apply_interrupted_by_shutdown", func(t *testing.T) {
+		err := os.Setenv(config.ApplyChunkSizeEnvVar, "2")
+		require.NoError(t, err)
+		defer func() {
+			err := os.Unsetenv(config.ApplyChunkSizeEnvVar)
+			require.NoError(t, err)
+		}()
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSet())
+		interrupted := false
+		r.interrupted = func() bool { return interrupted }
+		// the dev namespace is updated to its new revision, and the operator shuts down after the first object is applied
+		createNamespace(t, fakeClient, "abcde10", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+		fakeClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			interrupted = true
+			return fakeClient.Client.Create(ctx, obj, opts...)
+		}
+
+		// test
+		reconcile(r, req, "applied 1 of")
+
+		checkStatus(t, fakeClient, "Interrupted")
+		checkNamespaceCond(t, fakeClient, "dev", corev1.ConditionFalse, "Interrupted", "interrupted")
+		assert.Equal(t, 1, getCheckpoint(t, fakeClient).Next)
+	})
+
+	t.Run("fail_inventory_exceeds_max_entries", func(t *testing.T) {
+		// given an inventory with more entries than allowed
+		nsTmplSet := newNSTmplSet()
+		content := `{"entries":[` +
+			`{"apiVersion":"v1","kind":"ConfigMap","namespace":"johnsmith-dev","name":"first"},` +
+			`{"apiVersion":"v1","kind":"ConfigMap","namespace":"johnsmith-dev","name":"second"},` +
+			`{"apiVersion":"v1","kind":"ConfigMap","namespace":"johnsmith-dev","name":"third"}]}`
+		nsTmplSet.Annotations = map[string]string{inventoryAnnotation: content}
+		cfg := &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				Inventory: &memberv1alpha1.InventoryConfig{MaxEntries: 2},
+			},
+		}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet, cfg)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// test
+		reconcile(r, req, "3 entries exceed the maximum of 2 entries")
+
+		// the inventory does not grow any further, and the failure is reported
+		checkStatus(t, fakeClient, "InventoryLimitExceeded")
+		updatedNSTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, updatedNSTmplSet)
+		require.NoError(t, err)
+		assert.Equal(t, content, updatedNSTmplSet.Annotations[inventoryAnnotation])
+	})
+
+	t.Run("failure_reported_on_namespace_being_updated_only", func(t *testing.T) {
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde20", "code")
+
+		fakeClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("unable to create some object")
+		}
+
+		// test
+		reconcile(r, req, "unable to create some object")
+
+		checkStatus(t, fakeClient, "UnableToProvisionNamespace")
+		checkNamespaceCond(t, fakeClient, "code", corev1.ConditionFalse, "UnableToProvisionNamespace", "unable to create some object")
+		updatedNSTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, updatedNSTmplSet)
+		require.NoError(t, err)
+		_, found := condition.FindConditionByType(updatedNSTmplSet.Status.Conditions, namespaceConditionType("dev"))
+		assert.False(t, found)
 	})
 
 	t.Run("fail_update_status_for_inner_resources", func(t *testing.T) {
@@ -384,6 +685,541 @@ func TestReconcileProvisionFail(t *testing.T) {
 	})
 }
 
+func TestReconcileClusterResources(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	newNSTmplSetWithClusterResources := func() *toolchainv1alpha1.NSTemplateSet {
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Spec.Namespaces = append(nsTmplSet.Spec.Namespaces, toolchainv1alpha1.NSTemplateSetNamespace{
+			Type: "clusterresources", Revision: "abcde41",
+		})
+		return nsTmplSet
+	}
+
+	t.Run("cluster_resources_created_ok", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSetWithClusterResources())
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		quota := &quotav1.ClusterResourceQuota{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "for-" + username}, quota)
+		require.NoError(t, err)
+		assert.Equal(t, username, quota.Labels["owner"])
+		updatedNSTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, updatedNSTmplSet)
+		require.NoError(t, err)
+		assert.Equal(t, "abcde41", updatedNSTmplSet.Annotations[clusterResourcesRevisionAnnotation])
+		assert.Contains(t, updatedNSTmplSet.Annotations[inventoryAnnotation], "for-"+username)
+		clusterResourcesCond, found := condition.FindConditionByType(updatedNSTmplSet.Status.Conditions, clusterResourcesReadyCondition)
+		require.True(t, found)
+		assert.Equal(t, corev1.ConditionTrue, clusterResourcesCond.Status)
+		assert.Contains(t, clusterResourcesCond.Message, "revision 'abcde41' applied at ")
+	})
+
+	t.Run("cluster_resources_created_with_ephemeral_storage_quota", func(t *testing.T) {
+		// given
+		cfg := &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				EphemeralStorage: &memberv1alpha1.EphemeralStorageConfig{Quota: "10Gi"},
+			},
+		}
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSetWithClusterResources(), cfg)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		quota := &quotav1.ClusterResourceQuota{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "for-" + username}, quota)
+		require.NoError(t, err)
+		requests := quota.Spec.Quota.Hard[corev1.ResourceRequestsEphemeralStorage]
+		limits := quota.Spec.Quota.Hard[corev1.ResourceLimitsEphemeralStorage]
+		assert.Equal(t, "10Gi", requests.String())
+		assert.Equal(t, "10Gi", limits.String())
+		cpu := quota.Spec.Quota.Hard[corev1.ResourceLimitsCPU]
+		assert.Equal(t, "2", cpu.String())
+	})
+
+	t.Run("cluster_resources_created_with_persistent_storage_quota", func(t *testing.T) {
+		// given
+		cfg := &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				PersistentStorage: &memberv1alpha1.PersistentStorageConfig{Quota: "5Gi", MaxClaims: 3},
+			},
+		}
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSetWithClusterResources(), cfg)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		quota := &quotav1.ClusterResourceQuota{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "for-" + username}, quota)
+		require.NoError(t, err)
+		storage := quota.Spec.Quota.Hard[corev1.ResourceRequestsStorage]
+		claims := quota.Spec.Quota.Hard[corev1.ResourcePersistentVolumeClaims]
+		assert.Equal(t, "5Gi", storage.String())
+		assert.Equal(t, "3", claims.String())
+	})
+
+	t.Run("cluster_resources_not_applied_before_namespaces", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSetWithClusterResources())
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Provisioning")
+		checkNamespace(t, r.client, username, "dev")
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "for-" + username}, &quotav1.ClusterResourceQuota{})
+		assert.True(t, apierros.IsNotFound(err))
+	})
+
+	t.Run("cluster_resources_not_reapplied_with_same_revision", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSetWithClusterResources()
+		nsTmplSet.Annotations = map[string]string{clusterResourcesRevisionAnnotation: "abcde41"}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "for-" + username}, &quotav1.ClusterResourceQuota{})
+		assert.True(t, apierros.IsNotFound(err))
+	})
+
+	t.Run("cluster_resources_deleted_when_removed_from_spec", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			clusterResourcesRevisionAnnotation: "abcde41",
+			inventoryAnnotation:                `{"entries":[{"apiVersion":"quota.openshift.io/v1","kind":"ClusterResourceQuota","name":"for-johnsmith","templateType":"clusterresources"}]}`,
+		}
+		nsTmplSet.Status.Conditions = []toolchainv1alpha1.Condition{{
+			Type:    clusterResourcesReadyCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  "Provisioned",
+			Message: "revision 'abcde41' applied at 2019-11-01T10:00:00Z",
+		}}
+		quota := &quotav1.ClusterResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "for-" + username}}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet, quota)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "for-" + username}, &quotav1.ClusterResourceQuota{})
+		assert.True(t, apierros.IsNotFound(err))
+		updatedNSTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, updatedNSTmplSet)
+		require.NoError(t, err)
+		assert.NotContains(t, updatedNSTmplSet.Annotations, clusterResourcesRevisionAnnotation)
+		assert.NotContains(t, updatedNSTmplSet.Annotations[inventoryAnnotation], "for-"+username)
+		_, found := condition.FindConditionByType(updatedNSTmplSet.Status.Conditions, clusterResourcesReadyCondition)
+		assert.False(t, found)
+	})
+
+	t.Run("namespaces_kept_when_cluster_resources_applied", func(t *testing.T) {
+		// given the namespaces provisioned by the controller, hence recorded in the inventory along with the cluster resources
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSetWithClusterResources())
+		for i := 0; i < 5; i++ {
+			_, err := r.Reconcile(req)
+			require.NoError(t, err)
+			activateNamespaces(t, fakeClient, "dev", "code")
+		}
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, nsTmplSet)
+		require.NoError(t, err)
+		nsTmplSet.Spec.Namespaces[2].Revision = "abcde42"
+		err = fakeClient.Update(context.TODO(), nsTmplSet)
+		require.NoError(t, err)
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		checkNamespace(t, r.client, username, "dev")
+		checkNamespace(t, r.client, username, "code")
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "for-" + username}, &quotav1.ClusterResourceQuota{})
+		require.NoError(t, err)
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, nsTmplSet)
+		require.NoError(t, err)
+		assert.Equal(t, "abcde42", nsTmplSet.Annotations[clusterResourcesRevisionAnnotation])
+
+		t.Run("namespaces_kept_when_cluster_resources_removed", func(t *testing.T) {
+			// given
+			nsTmplSet.Spec.Namespaces = nsTmplSet.Spec.Namespaces[:2]
+			err := fakeClient.Update(context.TODO(), nsTmplSet)
+			require.NoError(t, err)
+
+			// when
+			_, err = r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			checkNamespace(t, r.client, username, "dev")
+			checkNamespace(t, r.client, username, "code")
+			err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "for-" + username}, &quotav1.ClusterResourceQuota{})
+			assert.True(t, apierros.IsNotFound(err))
+		})
+	})
+
+	t.Run("fail_create_cluster_resources", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSetWithClusterResources())
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+		fakeClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("unable to create cluster resource quota")
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to create cluster resource quota")
+		checkStatus(t, fakeClient, "UnableToProvisionClusterResources")
+		updatedNSTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, updatedNSTmplSet)
+		require.NoError(t, err)
+		assert.NotContains(t, updatedNSTmplSet.Annotations, clusterResourcesRevisionAnnotation)
+	})
+}
+
+func TestReconcileSpaceRoles(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	t.Run("space_role_bindings_created", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{spaceRolesAnnotation: `[{"username":"jane","role":"contributor"}]`}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Updating")
+		rb := &rbacv1.RoleBinding{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "space-contributor-jane"}, rb)
+		require.NoError(t, err)
+		assert.Equal(t, "edit", rb.RoleRef.Name)
+		require.Len(t, rb.Subjects, 1)
+		assert.Equal(t, "jane", rb.Subjects[0].Name)
+		// template resources are still there
+		checkInnerResources(t, fakeClient, "johnsmith-dev")
+		ns := &corev1.Namespace{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "johnsmith-dev"}, ns)
+		require.NoError(t, err)
+		assert.Equal(t, `[{"username":"jane","role":"contributor"}]`, ns.Annotations[spaceRolesAnnotation])
+	})
+
+	t.Run("space_role_binding_deleted_when_user_removed", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			inventoryAnnotation: `{"entries":[{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"RoleBinding","namespace":"johnsmith-dev","name":"space-contributor-jane"}]}`,
+		}
+		rb := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "space-contributor-jane"}}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet, rb)
+		ns := createNamespace(t, fakeClient, "abcde11", "dev")
+		ns.Annotations = map[string]string{spaceRolesAnnotation: `[{"username":"jane","role":"contributor"}]`}
+		err := fakeClient.Update(context.TODO(), ns)
+		require.NoError(t, err)
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "space-contributor-jane"}, &rbacv1.RoleBinding{})
+		assert.True(t, apierros.IsNotFound(err))
+		checkInnerResources(t, fakeClient, "johnsmith-dev")
+		updatedNS := &corev1.Namespace{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "johnsmith-dev"}, updatedNS)
+		require.NoError(t, err)
+		assert.NotContains(t, updatedNS.Annotations, spaceRolesAnnotation)
+	})
+
+	t.Run("invalid_space_roles", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{spaceRolesAnnotation: `[{"username":"jane","role":"owner"}]`}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid space role 'owner' for user 'jane'")
+		checkStatus(t, fakeClient, "UnableToProvision")
+	})
+}
+
+func TestDeleteNSTemplateSet(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	newDeletedNSTmplSet := func() *toolchainv1alpha1.NSTemplateSet {
+		nsTmplSet := newNSTmplSet()
+		deletionTS := metav1.Now()
+		nsTmplSet.DeletionTimestamp = &deletionTS
+		return nsTmplSet
+	}
+	getNSTmplSet := func(t *testing.T, cl client.Client) *toolchainv1alpha1.NSTemplateSet {
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err := cl.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, nsTmplSet)
+		require.NoError(t, err)
+		return nsTmplSet
+	}
+
+	t.Run("finalizer_added", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Finalizers = nil
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{nsTmplSetFinalizerName}, getNSTmplSet(t, fakeClient).Finalizers)
+	})
+
+	t.Run("namespaces_deleted", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newDeletedNSTmplSet())
+		devNS := createNamespace(t, fakeClient, "abcde11", "dev")
+		codeNS := createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Terminating")
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: devNS.Name}, &corev1.Namespace{})
+		assert.True(t, apierros.IsNotFound(err))
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: codeNS.Name}, &corev1.Namespace{})
+		assert.True(t, apierros.IsNotFound(err))
+		// finalizer is kept until the namespaces are gone
+		assert.Equal(t, []string{nsTmplSetFinalizerName}, getNSTmplSet(t, fakeClient).Finalizers)
+	})
+
+	t.Run("terminating_namespace_not_deleted_again", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newDeletedNSTmplSet())
+		ns := createNamespace(t, fakeClient, "abcde11", "dev")
+		deletionTS := metav1.Now()
+		ns.DeletionTimestamp = &deletionTS
+		err := fakeClient.Update(context.TODO(), ns)
+		require.NoError(t, err)
+		fakeClient.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("should not be called")
+		}
+
+		// when
+		_, err = r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Terminating")
+		assert.Equal(t, []string{nsTmplSetFinalizerName}, getNSTmplSet(t, fakeClient).Finalizers)
+	})
+
+	t.Run("cluster_resources_deleted_and_finalizer_removed", func(t *testing.T) {
+		// given
+		nsTmplSet := newDeletedNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			inventoryAnnotation: `{"entries":[{"apiVersion":"quota.openshift.io/v1","kind":"ClusterResourceQuota","name":"for-johnsmith"}]}`,
+		}
+		quota := &quotav1.ClusterResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "for-" + username}}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet, quota)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: "for-" + username}, &quotav1.ClusterResourceQuota{})
+		assert.True(t, apierros.IsNotFound(err))
+		assert.Empty(t, getNSTmplSet(t, fakeClient).Finalizers)
+	})
+
+	t.Run("nothing_done_without_finalizer", func(t *testing.T) {
+		// given
+		nsTmplSet := newDeletedNSTmplSet()
+		nsTmplSet.Finalizers = nil
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkNamespace(t, r.client, username, "dev")
+	})
+
+	t.Run("fail_delete_namespace", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newDeletedNSTmplSet())
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		fakeClient.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("unable to delete namespace")
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to delete namespace")
+		checkStatus(t, fakeClient, "UnableToTerminate")
+		assert.Equal(t, []string{nsTmplSetFinalizerName}, getNSTmplSet(t, fakeClient).Finalizers)
+	})
+
+	t.Run("fail_delete_cluster_resources", func(t *testing.T) {
+		// given
+		nsTmplSet := newDeletedNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			inventoryAnnotation: `{"entries":[{"apiVersion":"quota.openshift.io/v1","kind":"ClusterResourceQuota","name":"for-johnsmith"}]}`,
+		}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		fakeClient.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("unable to delete cluster resource quota")
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to delete cluster resource quota")
+		checkStatus(t, fakeClient, "UnableToTerminate")
+		assert.Equal(t, []string{nsTmplSetFinalizerName}, getNSTmplSet(t, fakeClient).Finalizers)
+	})
+}
+
+func TestPausedNSTemplateSet(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	newPausedNSTmplSet := func() *toolchainv1alpha1.NSTemplateSet {
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{pause.Annotation: "true"}
+		return nsTmplSet
+	}
+	getPausedCond := func(t *testing.T, cl client.Client) (toolchainv1alpha1.Condition, bool) {
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err := cl.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, nsTmplSet)
+		require.NoError(t, err)
+		return condition.FindConditionByType(nsTmplSet.Status.Conditions, pause.ConditionType)
+	}
+
+	t.Run("provisioning skipped while paused", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newPausedNSTmplSet())
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: username + "-dev"}, &corev1.Namespace{})
+		assert.True(t, apierros.IsNotFound(err))
+		pausedCond, found := getPausedCond(t, fakeClient)
+		require.True(t, found)
+		assert.Equal(t, corev1.ConditionTrue, pausedCond.Status)
+		assert.Equal(t, pause.PausedReason, pausedCond.Reason)
+	})
+
+	t.Run("clean up skipped while paused", func(t *testing.T) {
+		// given
+		nsTmplSet := newPausedNSTmplSet()
+		deletionTS := metav1.Now()
+		nsTmplSet.DeletionTimestamp = &deletionTS
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+		devNS := createNamespace(t, fakeClient, "abcde11", "dev")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: devNS.Name}, &corev1.Namespace{})
+		require.NoError(t, err)
+	})
+
+	t.Run("provisioning resumed", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Status.Conditions = []toolchainv1alpha1.Condition{pause.PausedCondition()}
+		r, req, fakeClient := prepareReconcile(t, nsTmplSet)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Provisioning")
+		checkNamespace(t, r.client, username, "dev")
+		pausedCond, found := getPausedCond(t, fakeClient)
+		require.True(t, found)
+		assert.Equal(t, corev1.ConditionFalse, pausedCond.Status)
+		assert.Equal(t, pause.ResumedReason, pausedCond.Reason)
+	})
+
+	t.Run("no paused condition when never paused", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSet())
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		_, found := getPausedCond(t, fakeClient)
+		assert.False(t, found)
+	})
+}
+
 func TestUpdateStatus(t *testing.T) {
 	logf.SetLogger(logf.ZapLogger(true))
 	s := scheme.Scheme
@@ -527,18 +1363,57 @@ func createNamespace(t *testing.T, client client.Client, revision, typeName stri
 	return ns
 }
 
+// activateNamespaces sets the phase of the given namespaces of the user (if they exist) to `Active`, as the API server does once they are created
+func activateNamespaces(t *testing.T, cl client.Client, typeNames ...string) {
+	t.Helper()
+
+	for _, typeName := range typeNames {
+		ns := &corev1.Namespace{}
+		err := cl.Get(context.TODO(), types.NamespacedName{Name: fmt.Sprintf("%s-%s", username, typeName)}, ns)
+		if apierros.IsNotFound(err) {
+			continue
+		}
+		require.NoError(t, err)
+		ns.Status.Phase = corev1.NamespaceActive
+		err = cl.Update(context.TODO(), ns)
+		require.NoError(t, err)
+	}
+}
+
 func checkReadyCond(t *testing.T, client *test.FakeClient, wantStatus corev1.ConditionStatus, wantReason string) {
 	t.Helper()
 
 	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
 	err := client.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, nsTmplSet)
 	require.NoError(t, err)
-	wantCond := toolchainv1alpha1.Condition{
-		Type:   toolchainv1alpha1.ConditionReady,
-		Status: wantStatus,
-		Reason: wantReason,
-	}
-	test.AssertConditionsMatch(t, nsTmplSet.Status.Conditions, wantCond)
+	readyCond, found := condition.FindConditionByType(nsTmplSet.Status.Conditions, toolchainv1alpha1.ConditionReady)
+	require.True(t, found)
+	assert.Equal(t, wantStatus, readyCond.Status)
+	assert.Equal(t, wantReason, readyCond.Reason)
+}
+
+func checkNamespaceCond(t *testing.T, client *test.FakeClient, typeName string, wantStatus corev1.ConditionStatus, wantReason, wantMsg string) {
+	t.Helper()
+
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	err := client.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, nsTmplSet)
+	require.NoError(t, err)
+	nsCond, found := condition.FindConditionByType(nsTmplSet.Status.Conditions, namespaceConditionType(typeName))
+	require.True(t, found)
+	assert.Equal(t, wantStatus, nsCond.Status)
+	assert.Equal(t, wantReason, nsCond.Reason)
+	assert.Contains(t, nsCond.Message, wantMsg)
+}
+
+func getNamespaceCond(t *testing.T, client *test.FakeClient, typeName string) toolchainv1alpha1.Condition {
+	t.Helper()
+
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	err := client.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: namespaceName}, nsTmplSet)
+	require.NoError(t, err)
+	nsCond, found := condition.FindConditionByType(nsTmplSet.Status.Conditions, namespaceConditionType(typeName))
+	require.True(t, found)
+	return nsCond
 }
 
 func checkNamespace(t *testing.T, cl client.Client, username, typeName string) {
@@ -589,8 +1464,9 @@ func checkStatus(t *testing.T, client *test.FakeClient, wantReason string) {
 func newNSTmplSet() *toolchainv1alpha1.NSTemplateSet {
 	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      username,
-			Namespace: namespaceName,
+			Name:       username,
+			Namespace:  namespaceName,
+			Finalizers: []string{nsTmplSetFinalizerName},
 		},
 		Spec: toolchainv1alpha1.NSTemplateSetSpec{
 			TierName: "basic",
@@ -616,6 +1492,8 @@ func prepareController(t *testing.T, initObjs ...runtime.Object) (*ReconcileNSTe
 	decoder := codecFactory.UniversalDeserializer()
 
 	fakeClient := test.NewFakeClient(t, initObjs...)
+	// the configuration is loaded by the MemberOperatorConfig controller
+	require.NoError(t, config.LoadMemberOperatorConfig(fakeClient, namespaceName))
 	r := &ReconcileNSTemplateSet{
 		client:             fakeClient,
 		scheme:             s,
@@ -0,0 +1,112 @@
+package nstemplateset
+
+import (
+	"fmt"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// the labels of the namespaces which set the Pod Security Admission level enforced, audited and warned on their pods
+	podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+	podSecurityAuditLabel   = "pod-security.kubernetes.io/audit"
+	podSecurityWarnLabel    = "pod-security.kubernetes.io/warn"
+	// podSecurityLabelSyncLabel the label which disables the synchronization of the Pod Security Admission labels with the SCCs by OpenShift,
+	// which would otherwise overwrite the level of the tier
+	podSecurityLabelSyncLabel = "security.openshift.io/scc.podSecurityLabelSync"
+
+	// podSecuritySCCName the name of the Role and of the RoleBinding which allow the service accounts of a user namespace to use
+	// the SCC of its tier
+	podSecuritySCCName = "pod-security-scc"
+)
+
+// podSecurityLevel returns the Pod Security Admission level of the namespaces of the given NSTemplateSet, or an empty string if none
+// is configured for its tier
+func podSecurityLevel(nsTmplSet *toolchainv1alpha1.NSTemplateSet) string {
+	profile, _ := config.GetPodSecurity(nsTmplSet.Spec.TierName)
+	return profile.Level
+}
+
+// setPodSecurityLabels sets the Pod Security Admission labels of the given level on the namespace. The labels are left untouched if the
+// level is empty, since they may be set by the template of the namespace
+func setPodSecurityLabels(namespace *corev1.Namespace, level string) {
+	if level == "" {
+		return
+	}
+	if namespace.Labels == nil {
+		namespace.Labels = make(map[string]string)
+	}
+	for _, label := range []string{podSecurityEnforceLabel, podSecurityAuditLabel, podSecurityWarnLabel} {
+		namespace.Labels[label] = level
+	}
+	namespace.Labels[podSecurityLabelSyncLabel] = "false"
+}
+
+// hasPodSecurityLabels returns true if the given namespace has the Pod Security Admission labels of the given level, or if the level is empty
+func hasPodSecurityLabels(namespace corev1.Namespace, level string) bool {
+	if level == "" {
+		return true
+	}
+	for _, label := range []string{podSecurityEnforceLabel, podSecurityAuditLabel, podSecurityWarnLabel} {
+		if namespace.Labels[label] != level {
+			return false
+		}
+	}
+	return namespace.Labels[podSecurityLabelSyncLabel] == "false"
+}
+
+// podSecurityRoleBindings returns the Role and the RoleBinding which allow the service accounts of the given namespace to use the SCC
+// configured for the tier of the NSTemplateSet, if any. Their RBAC kinds are enforced, so that the users cannot bind their service
+// accounts to another SCC by changing them
+func podSecurityRoleBindings(nsTmplSet *toolchainv1alpha1.NSTemplateSet, namespace string) []runtime.RawExtension {
+	profile, _ := config.GetPodSecurity(nsTmplSet.Spec.TierName)
+	if profile.SCC == "" {
+		return nil
+	}
+	metadata := func() map[string]interface{} {
+		return map[string]interface{}{
+			"name":      podSecuritySCCName,
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				"owner":    nsTmplSet.GetName(),
+				"provider": "codeready-toolchain",
+			},
+		}
+	}
+	role := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       "Role",
+		"metadata":   metadata(),
+		"rules": []interface{}{
+			map[string]interface{}{
+				"apiGroups":     []interface{}{"security.openshift.io"},
+				"resources":     []interface{}{"securitycontextconstraints"},
+				"resourceNames": []interface{}{profile.SCC},
+				"verbs":         []interface{}{"use"},
+			},
+		},
+	}}
+	roleBinding := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       "RoleBinding",
+		"metadata":   metadata(),
+		"roleRef": map[string]interface{}{
+			"apiGroup": "rbac.authorization.k8s.io",
+			"kind":     "Role",
+			"name":     podSecuritySCCName,
+		},
+		"subjects": []interface{}{
+			map[string]interface{}{
+				"apiGroup": "rbac.authorization.k8s.io",
+				"kind":     "Group",
+				"name":     fmt.Sprintf("system:serviceaccounts:%s", namespace),
+			},
+		},
+	}}
+	return []runtime.RawExtension{{Object: role}, {Object: roleBinding}}
+}
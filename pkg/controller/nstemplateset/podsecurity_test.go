@@ -0,0 +1,113 @@
+package nstemplateset
+
+import (
+	"testing"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPodSecurity(t *testing.T) {
+	nsTmplSet := newNSTmplSet()
+
+	t.Run("no pod security configured", func(t *testing.T) {
+		assert.Empty(t, podSecurityLevel(nsTmplSet))
+		assert.Empty(t, podSecurityRoleBindings(nsTmplSet, "johnsmith-dev"))
+	})
+
+	loadPodSecurity(t, &memberv1alpha1.PodSecurityConfig{
+		Default: &memberv1alpha1.PodSecurityProfile{Level: "baseline"},
+		Tiers: map[string]memberv1alpha1.PodSecurityProfile{
+			nsTmplSet.Spec.TierName: {SCC: "restricted-v2", Level: "restricted"},
+		},
+	})
+	defer loadPodSecurity(t, nil)
+
+	t.Run("SCC granted to the service accounts", func(t *testing.T) {
+		// when
+		objs := podSecurityRoleBindings(nsTmplSet, "johnsmith-dev")
+
+		// then
+		require.Len(t, objs, 2)
+		role, ok := objs[0].Object.(*unstructured.Unstructured)
+		require.True(t, ok)
+		assert.Equal(t, "Role", role.GetKind())
+		assert.Equal(t, "pod-security-scc", role.GetName())
+		assert.Equal(t, "johnsmith-dev", role.GetNamespace())
+		assert.Equal(t, username, role.GetLabels()["owner"])
+		rules, _, _ := unstructured.NestedSlice(role.Object, "rules")
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"apiGroups":     []interface{}{"security.openshift.io"},
+			"resources":     []interface{}{"securitycontextconstraints"},
+			"resourceNames": []interface{}{"restricted-v2"},
+			"verbs":         []interface{}{"use"},
+		}}, rules)
+		rb, ok := objs[1].Object.(*unstructured.Unstructured)
+		require.True(t, ok)
+		assert.Equal(t, "RoleBinding", rb.GetKind())
+		assert.Equal(t, "pod-security-scc", rb.GetName())
+		roleName, _, _ := unstructured.NestedString(rb.Object, "roleRef", "name")
+		assert.Equal(t, "pod-security-scc", roleName)
+		subjects, _, _ := unstructured.NestedSlice(rb.Object, "subjects")
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"apiGroup": "rbac.authorization.k8s.io",
+			"kind":     "Group",
+			"name":     "system:serviceaccounts:johnsmith-dev",
+		}}, subjects)
+	})
+
+	t.Run("level of the tier", func(t *testing.T) {
+		// given
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "johnsmith-dev"}}
+
+		// when
+		setPodSecurityLabels(ns, podSecurityLevel(nsTmplSet))
+
+		// then
+		assert.Equal(t, map[string]string{
+			"pod-security.kubernetes.io/enforce":             "restricted",
+			"pod-security.kubernetes.io/audit":               "restricted",
+			"pod-security.kubernetes.io/warn":                "restricted",
+			"security.openshift.io/scc.podSecurityLabelSync": "false",
+		}, ns.Labels)
+		assert.True(t, hasPodSecurityLabels(*ns, "restricted"))
+		assert.False(t, hasPodSecurityLabels(*ns, "baseline"))
+	})
+
+	t.Run("default level without SCC", func(t *testing.T) {
+		// given
+		other := newNSTmplSet()
+		other.Spec.TierName = "other"
+
+		// then
+		assert.Equal(t, "baseline", podSecurityLevel(other))
+		assert.Empty(t, podSecurityRoleBindings(other, "johnsmith-dev"))
+	})
+
+	t.Run("labels left untouched without level", func(t *testing.T) {
+		// given
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "johnsmith-dev", Labels: map[string]string{"pod-security.kubernetes.io/enforce": "privileged"}}}
+
+		// when
+		setPodSecurityLabels(ns, "")
+
+		// then
+		assert.Equal(t, map[string]string{"pod-security.kubernetes.io/enforce": "privileged"}, ns.Labels)
+		assert.True(t, hasPodSecurityLabels(*ns, ""))
+	})
+}
+
+func loadPodSecurity(t *testing.T, podSecurity *memberv1alpha1.PodSecurityConfig) {
+	cfg := &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+		Spec:       memberv1alpha1.MemberOperatorConfigSpec{PodSecurity: podSecurity},
+	}
+	require.NoError(t, config.LoadMemberOperatorConfig(test.NewFakeClient(t, cfg), namespaceName))
+}
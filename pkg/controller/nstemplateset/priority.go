@@ -0,0 +1,171 @@
+package nstemplateset
+
+import (
+	"sync"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+const (
+	// deferredReconcileDelay the delay after which a deferred reconciliation is retried
+	deferredReconcileDelay = 2 * time.Second
+	// pendingAccountTimeout the time after which a new account which is still not provisioned (eg, because its provisioning keeps failing)
+	// does not defer the other reconciliations anymore
+	pendingAccountTimeout = 5 * time.Minute
+)
+
+// fairShare reserves some of the concurrent reconciliations for the provisioning of the new accounts: the reconciliations of the
+// NSTemplateSets which were already provisioned (eg, the bulk upgrades after a change of tier) are deferred while new accounts are
+// waiting and they would use one of the reserved reconciliations. The new accounts are tracked from the moment their NSTemplateSet
+// is enqueued until it is provisioned.
+type fairShare struct {
+	lock     sync.Mutex
+	workers  int
+	reserved int
+	// upgrades the number of reconciliations of the NSTemplateSets which were already provisioned, in progress
+	upgrades int
+	// pending the NSTemplateSets of the new accounts which are not provisioned yet, along with the time at which they were seen first
+	pending map[types.NamespacedName]time.Time
+}
+
+// newFairShare returns the fair share of the reconciliations configured for the controller with the given name,
+// or `nil` if no reconciliation is reserved for the new accounts
+func newFairShare(name string) *fairShare {
+	cfg := config.GetControllerConfig(name)
+	if cfg.ReservedReconciles <= 0 {
+		return nil
+	}
+	workers := int(cfg.MaxConcurrentReconciles)
+	if workers < 1 {
+		workers = 1
+	}
+	return &fairShare{
+		workers:  workers,
+		reserved: int(cfg.ReservedReconciles),
+		pending:  map[types.NamespacedName]time.Time{},
+	}
+}
+
+// isNewAccount returns `true` if the given NSTemplateSet is being provisioned for the first time
+func isNewAccount(nsTmplSet *toolchainv1alpha1.NSTemplateSet) bool {
+	if nsTmplSet.GetDeletionTimestamp() != nil {
+		return false
+	}
+	ready, found := condition.FindConditionByType(nsTmplSet.Status.Conditions, toolchainv1alpha1.ConditionReady)
+	return !found || ready.Reason == provisioningReason || ready.Reason == unableToProvisionReason
+}
+
+// observe starts tracking the given object if it is the NSTemplateSet of a new account, or stops tracking it otherwise
+func (s *fairShare) observe(obj runtime.Object) {
+	nsTmplSet, ok := obj.(*toolchainv1alpha1.NSTemplateSet)
+	if s == nil || !ok {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.track(nsTmplSet)
+}
+
+// track starts tracking the given NSTemplateSet if it is the one of a new account, or stops tracking it otherwise.
+// Must be called with the lock held
+func (s *fairShare) track(nsTmplSet *toolchainv1alpha1.NSTemplateSet) {
+	key := types.NamespacedName{Namespace: nsTmplSet.Namespace, Name: nsTmplSet.Name}
+	if !isNewAccount(nsTmplSet) {
+		delete(s.pending, key)
+		return
+	}
+	if _, found := s.pending[key]; !found {
+		s.pending[key] = time.Now()
+	}
+}
+
+// forget stops tracking the NSTemplateSet with the given key, eg, once it was deleted
+func (s *fairShare) forget(key types.NamespacedName) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.pending, key)
+}
+
+// admit returns `false` if the reconciliation of the given NSTemplateSet must be deferred to leave the reserved reconciliations
+// to the new accounts. Otherwise, returns the func to call once the reconciliation is complete
+func (s *fairShare) admit(nsTmplSet *toolchainv1alpha1.NSTemplateSet) (func(), bool) {
+	if s == nil {
+		return func() {}, true
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.track(nsTmplSet)
+	if isNewAccount(nsTmplSet) {
+		return s.release(nsTmplSet, false), true
+	}
+	if s.upgrades >= s.workers-s.reserved && s.waitingAccounts() {
+		return nil, false
+	}
+	s.upgrades++
+	return s.release(nsTmplSet, true), true
+}
+
+// release returns the func which ends the reconciliation of the given NSTemplateSet
+func (s *fairShare) release(nsTmplSet *toolchainv1alpha1.NSTemplateSet, upgrade bool) func() {
+	return func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if upgrade {
+			s.upgrades--
+		}
+		// the status of the NSTemplateSet was updated during the reconciliation
+		s.track(nsTmplSet)
+	}
+}
+
+// waitingAccounts returns `true` if some new accounts are waiting to be provisioned. The accounts which are waiting for too long
+// are not considered, so that the upgrades are not deferred forever by an account whose provisioning keeps failing.
+// Must be called with the lock held
+func (s *fairShare) waitingAccounts() bool {
+	for _, since := range s.pending {
+		if time.Since(since) < pendingAccountTimeout {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueWithFairShare enqueues the NSTemplateSets like `handler.EnqueueRequestForObject` and tracks the ones of the new accounts
+type enqueueWithFairShare struct {
+	handler.EnqueueRequestForObject
+	share *fairShare
+}
+
+var _ handler.EventHandler = &enqueueWithFairShare{}
+
+// Create tracks the created NSTemplateSet and enqueues it
+func (e *enqueueWithFairShare) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	e.share.observe(evt.Object)
+	e.EnqueueRequestForObject.Create(evt, q)
+}
+
+// Update tracks the updated NSTemplateSet and enqueues it
+func (e *enqueueWithFairShare) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	e.share.observe(evt.ObjectNew)
+	e.EnqueueRequestForObject.Update(evt, q)
+}
+
+// Delete stops tracking the deleted NSTemplateSet and enqueues it
+func (e *enqueueWithFairShare) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	if evt.Meta != nil {
+		e.share.forget(types.NamespacedName{Namespace: evt.Meta.GetNamespace(), Name: evt.Meta.GetName()})
+	}
+	e.EnqueueRequestForObject.Delete(evt, q)
+}
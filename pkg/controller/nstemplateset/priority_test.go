@@ -0,0 +1,193 @@
+package nstemplateset
+
+import (
+	"testing"
+	"time"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestNewFairShare(t *testing.T) {
+
+	t.Run("no reserved reconciliation", func(t *testing.T) {
+		assert.Nil(t, newFairShare("nstemplateset"))
+	})
+
+	t.Run("reserved reconciliations", func(t *testing.T) {
+		// given
+		cfg := &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				Controllers: map[string]memberv1alpha1.ControllerConfig{
+					"nstemplateset": {MaxConcurrentReconciles: 5, ReservedReconciles: 2},
+				},
+			},
+		}
+		require.NoError(t, config.LoadMemberOperatorConfig(test.NewFakeClient(t, cfg), namespaceName))
+		defer func() {
+			require.NoError(t, config.LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName))
+		}()
+
+		// when
+		share := newFairShare("nstemplateset")
+
+		// then
+		require.NotNil(t, share)
+		assert.Equal(t, 5, share.workers)
+		assert.Equal(t, 2, share.reserved)
+	})
+}
+
+func TestIsNewAccount(t *testing.T) {
+	assert.True(t, isNewAccount(newNSTmplSet()))
+	assert.True(t, isNewAccount(withReadyReason(newNSTmplSet(), provisioningReason)))
+	assert.True(t, isNewAccount(withReadyReason(newNSTmplSet(), unableToProvisionReason)))
+	assert.False(t, isNewAccount(withReadyReason(newNSTmplSet(), provisionedReason)))
+	assert.False(t, isNewAccount(withReadyReason(newNSTmplSet(), updatingReason)))
+	assert.False(t, isNewAccount(withReadyReason(newNSTmplSet(), unableToProvisionNamespaceReason)))
+}
+
+func TestFairShareAdmit(t *testing.T) {
+	newAccount := newNSTmplSet()
+	provisioned := func(name string) *toolchainv1alpha1.NSTemplateSet {
+		nsTmplSet := withReadyReason(newNSTmplSet(), provisionedReason)
+		nsTmplSet.Name = name
+		return nsTmplSet
+	}
+
+	t.Run("nil share admits everything", func(t *testing.T) {
+		// given
+		var share *fairShare
+
+		// when
+		release, admitted := share.admit(provisioned("upgrade"))
+
+		// then
+		assert.True(t, admitted)
+		release()
+	})
+
+	t.Run("upgrades admitted when no new account is waiting", func(t *testing.T) {
+		// given
+		share := &fairShare{workers: 2, reserved: 1, pending: map[types.NamespacedName]time.Time{}}
+
+		// when
+		_, admitted1 := share.admit(provisioned("upgrade1"))
+		_, admitted2 := share.admit(provisioned("upgrade2"))
+
+		// then
+		assert.True(t, admitted1)
+		assert.True(t, admitted2)
+		assert.Equal(t, 2, share.upgrades)
+	})
+
+	t.Run("upgrades deferred while new accounts are waiting", func(t *testing.T) {
+		// given
+		share := &fairShare{workers: 2, reserved: 1, pending: map[types.NamespacedName]time.Time{}}
+		share.observe(newAccount)
+
+		// when
+		release1, admitted1 := share.admit(provisioned("upgrade1"))
+		_, admitted2 := share.admit(provisioned("upgrade2"))
+
+		// then
+		assert.True(t, admitted1)
+		assert.False(t, admitted2)
+
+		t.Run("new account admitted", func(t *testing.T) {
+			// when
+			release, admitted := share.admit(newAccount)
+
+			// then
+			assert.True(t, admitted)
+
+			t.Run("upgrades admitted once the new account is provisioned", func(t *testing.T) {
+				// given
+				withReadyReason(newAccount, provisionedReason)
+				release()
+				release1()
+
+				// when
+				_, admitted1 := share.admit(provisioned("upgrade1"))
+				_, admitted2 := share.admit(provisioned("upgrade2"))
+
+				// then
+				assert.True(t, admitted1)
+				assert.True(t, admitted2)
+				assert.Empty(t, share.pending)
+			})
+		})
+	})
+
+	t.Run("upgrades not deferred by the accounts waiting for too long", func(t *testing.T) {
+		// given
+		share := &fairShare{workers: 1, reserved: 1, pending: map[types.NamespacedName]time.Time{
+			{Namespace: namespaceName, Name: "failing"}: time.Now().Add(-pendingAccountTimeout),
+		}}
+
+		// when
+		_, admitted := share.admit(provisioned("upgrade"))
+
+		// then
+		assert.True(t, admitted)
+	})
+
+	t.Run("deleted account forgotten", func(t *testing.T) {
+		// given
+		share := &fairShare{workers: 1, reserved: 1, pending: map[types.NamespacedName]time.Time{}}
+		h := &enqueueWithFairShare{share: share}
+		q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		defer q.ShutDown()
+		h.Create(event.CreateEvent{Meta: newAccount, Object: newNSTmplSet()}, q)
+		require.Len(t, share.pending, 1)
+
+		// when
+		h.Delete(event.DeleteEvent{Meta: newAccount, Object: newAccount}, q)
+
+		// then
+		assert.Empty(t, share.pending)
+		_, admitted := share.admit(provisioned("upgrade"))
+		assert.True(t, admitted)
+	})
+}
+
+func TestReconcileDeferredUpgrade(t *testing.T) {
+	// given
+	nsTmplSet := withReadyReason(newNSTmplSet(), provisionedReason)
+	r, req, _ := prepareReconcile(t, nsTmplSet)
+	r.share = &fairShare{workers: 1, reserved: 1, pending: map[types.NamespacedName]time.Time{
+		{Namespace: namespaceName, Name: "newcomer"}: time.Now(),
+	}}
+
+	// when
+	res, err := r.Reconcile(req)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, deferredReconcileDelay, res.RequeueAfter)
+	assert.Equal(t, 0, r.share.upgrades)
+}
+
+func withReadyReason(nsTmplSet *toolchainv1alpha1.NSTemplateSet, reason string) *toolchainv1alpha1.NSTemplateSet {
+	status := corev1.ConditionFalse
+	if reason == provisionedReason {
+		status = corev1.ConditionTrue
+	}
+	nsTmplSet.Status.Conditions = []toolchainv1alpha1.Condition{{
+		Type:   toolchainv1alpha1.ConditionReady,
+		Status: status,
+		Reason: reason,
+	}}
+	return nsTmplSet
+}
@@ -0,0 +1,62 @@
+package nstemplateset
+
+import (
+	"context"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/go-logr/logr"
+	projectv1 "github.com/openshift/api/project/v1"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// requestProjects creates the Namespaces among the given objects with OpenShift ProjectRequests when the namespace creation mode
+// is `project`, so that they get the annotations (eg, the UID ranges) and the objects of the project template of the cluster.
+// The annotations of the created Namespaces are copied into the given objects, so that they are retained when the objects are applied.
+// Nothing is done when the cluster does not serve the ProjectRequests (eg, on vanilla Kubernetes): the Namespaces are then created
+// as usual when the objects are applied.
+func (r *ReconcileNSTemplateSet) requestProjects(logger logr.Logger, objs []runtime.RawExtension) error {
+	if config.GetNamespaceCreationMode() != config.NamespaceCreationModeProject {
+		return nil
+	}
+	for _, rawObj := range objs {
+		if rawObj.Object == nil || rawObj.Object.GetObjectKind().GroupVersionKind().Kind != "Namespace" {
+			continue
+		}
+		acc, err := meta.Accessor(rawObj.Object)
+		if err != nil {
+			return errs.Wrap(err, "invalid namespace")
+		}
+		request := &projectv1.ProjectRequest{ObjectMeta: metav1.ObjectMeta{Name: acc.GetName()}}
+		if err := r.client.Create(context.TODO(), request); err != nil {
+			if meta.IsNoMatchError(err) {
+				logger.Info("the cluster does not serve the ProjectRequests, creating a plain namespace", "namespace", acc.GetName())
+				return nil
+			}
+			if !errors.IsAlreadyExists(err) {
+				return errs.Wrapf(err, "failed to request the project '%s'", acc.GetName())
+			}
+		}
+		logger.Info("project requested", "namespace", acc.GetName())
+		ns := &corev1.Namespace{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: acc.GetName()}, ns); err != nil {
+			return errs.Wrapf(err, "failed to get the namespace of the project '%s'", acc.GetName())
+		}
+		annotations := acc.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		for key, value := range ns.Annotations {
+			if _, found := annotations[key]; !found {
+				annotations[key] = value
+			}
+		}
+		acc.SetAnnotations(annotations)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package nstemplateset
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	projectv1 "github.com/openshift/api/project/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestProvisionNamespaceWithProjectRequest(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := os.Setenv(config.NamespaceCreationModeEnvVar, config.NamespaceCreationModeProject)
+	require.NoError(t, err)
+	defer func() {
+		err := os.Unsetenv(config.NamespaceCreationModeEnvVar)
+		require.NoError(t, err)
+	}()
+
+	t.Run("namespace created with a project request", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSet())
+		var requested []string
+		fakeClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if request, ok := obj.(*projectv1.ProjectRequest); ok {
+				requested = append(requested, request.Name)
+				// the project template of the cluster sets the UID range of the namespace
+				return fakeClient.Client.Create(ctx, &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        request.Name,
+						Annotations: map[string]string{"openshift.io/sa.scc.uid-range": "1000620000/10000"},
+					},
+				})
+			}
+			return fakeClient.Client.Create(ctx, obj, opts...)
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{username + "-dev"}, requested)
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Provisioning")
+		checkNamespace(t, r.client, username, "dev")
+		ns := &corev1.Namespace{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Name: username + "-dev"}, ns)
+		require.NoError(t, err)
+		// the annotations of the project template are retained
+		assert.Equal(t, "1000620000/10000", ns.Annotations["openshift.io/sa.scc.uid-range"])
+	})
+
+	t.Run("fallback to plain namespace when project requests are not served", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSet())
+		fakeClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*projectv1.ProjectRequest); ok {
+				return &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "project.openshift.io", Kind: "ProjectRequest"}}
+			}
+			return fakeClient.Client.Create(ctx, obj, opts...)
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Provisioning")
+		checkNamespace(t, r.client, username, "dev")
+	})
+
+	t.Run("project request fails", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSet())
+		fakeClient.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*projectv1.ProjectRequest); ok {
+				return errors.New("mock error")
+			}
+			return fakeClient.Client.Create(ctx, obj, opts...)
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to request the project 'johnsmith-dev': mock error")
+		checkNamespaceCond(t, fakeClient, "dev", corev1.ConditionFalse, "UnableToProvisionNamespace", "failed to request the project 'johnsmith-dev': mock error")
+	})
+}
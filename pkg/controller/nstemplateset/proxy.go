@@ -0,0 +1,46 @@
+package nstemplateset
+
+import (
+	"context"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// trustedCABundle returns the copy of the trusted CA bundle of the operator namespace to apply in the given namespace, so that it can
+// be mounted in the pods of the user (see the pod proxy webhook), or nothing if there is no trusted CA bundle in the MemberOperatorConfig
+func (r *ReconcileNSTemplateSet) trustedCABundle(nsTmplSet *toolchainv1alpha1.NSTemplateSet, namespace string) ([]runtime.RawExtension, error) {
+	name := config.GetProxy().TrustedCABundle
+	if name == "" {
+		return nil, nil
+	}
+	source := &corev1.ConfigMap{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: nsTmplSet.Namespace, Name: name}, source); err != nil {
+		return nil, errs.Wrapf(err, "unable to get the trusted CA bundle '%s'", name)
+	}
+	bundle, found := source.Data[config.TrustedCABundleKey]
+	if !found {
+		return nil, errs.Errorf("no '%s' in the trusted CA bundle '%s'", config.TrustedCABundleKey, name)
+	}
+	return []runtime.RawExtension{{Object: &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      config.TrustedCABundleConfigMapName,
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				"owner":    nsTmplSet.GetName(),
+				"provider": "codeready-toolchain",
+			},
+		},
+		"data": map[string]interface{}{
+			config.TrustedCABundleKey: bundle,
+		},
+	}}}}, nil
+}
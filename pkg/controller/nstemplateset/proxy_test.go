@@ -0,0 +1,90 @@
+package nstemplateset
+
+import (
+	"testing"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestTrustedCABundle(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: "proxy-ca"},
+		Data:       map[string]string{"ca-bundle.crt": "-----BEGIN CERTIFICATE-----"},
+	}
+
+	t.Run("no trusted CA bundle configured", func(t *testing.T) {
+		// given
+		r, _ := prepareController(t, source)
+
+		// when
+		objs, err := r.trustedCABundle(newNSTmplSet(), "johnsmith-dev")
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, objs)
+	})
+
+	loadProxy(t, &memberv1alpha1.ProxyConfig{TrustedCABundle: "proxy-ca"})
+	defer loadProxy(t, nil)
+
+	t.Run("trusted CA bundle copied", func(t *testing.T) {
+		// given
+		r, _ := prepareController(t, source)
+
+		// when
+		objs, err := r.trustedCABundle(newNSTmplSet(), "johnsmith-dev")
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 1)
+		cm, ok := objs[0].Object.(*unstructured.Unstructured)
+		require.True(t, ok)
+		assert.Equal(t, "ConfigMap", cm.GetKind())
+		assert.Equal(t, "trusted-ca-bundle", cm.GetName())
+		assert.Equal(t, "johnsmith-dev", cm.GetNamespace())
+		assert.Equal(t, username, cm.GetLabels()["owner"])
+		bundle, _, _ := unstructured.NestedString(cm.Object, "data", "ca-bundle.crt")
+		assert.Equal(t, "-----BEGIN CERTIFICATE-----", bundle)
+	})
+
+	t.Run("trusted CA bundle not found", func(t *testing.T) {
+		// given
+		r, _ := prepareController(t)
+
+		// when
+		_, err := r.trustedCABundle(newNSTmplSet(), "johnsmith-dev")
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to get the trusted CA bundle 'proxy-ca'")
+	})
+
+	t.Run("no bundle in the config map", func(t *testing.T) {
+		// given
+		empty := source.DeepCopy()
+		empty.Data = nil
+		r, _ := prepareController(t, empty)
+
+		// when
+		_, err := r.trustedCABundle(newNSTmplSet(), "johnsmith-dev")
+
+		// then
+		require.EqualError(t, err, "no 'ca-bundle.crt' in the trusted CA bundle 'proxy-ca'")
+	})
+}
+
+func loadProxy(t *testing.T, proxy *memberv1alpha1.ProxyConfig) {
+	cfg := &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+		Spec:       memberv1alpha1.MemberOperatorConfigSpec{Proxy: proxy},
+	}
+	require.NoError(t, config.LoadMemberOperatorConfig(test.NewFakeClient(t, cfg), namespaceName))
+}
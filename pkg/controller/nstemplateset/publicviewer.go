@@ -0,0 +1,68 @@
+package nstemplateset
+
+import (
+	"fmt"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// publicViewerAnnotation the annotation on the NSTemplateSet which opts the namespaces in for the read-only access of the public viewer
+	// configured in the MemberOperatorConfig. The same annotation is set on each namespace with the subject that was granted access.
+	publicViewerAnnotation = "toolchain.dev.openshift.com/public-viewer"
+
+	// publicViewerRoleBindingName the name of the RoleBinding of the public viewer in the shared namespaces
+	publicViewerRoleBindingName = "public-viewer"
+)
+
+// publicViewer returns the subject (along with its cluster role) which is granted access to the namespaces of the given NSTemplateSet,
+// or an empty string if the NSTemplateSet did not opt in or if no public viewer is configured
+func publicViewer(nsTmplSet *toolchainv1alpha1.NSTemplateSet) string {
+	if nsTmplSet.GetAnnotations()[publicViewerAnnotation] != "true" {
+		return ""
+	}
+	viewer, found := config.GetPublicViewer()
+	if !found {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s:%s:%s", viewer.Kind, viewer.Namespace, viewer.Name, viewer.ClusterRole)
+}
+
+// publicViewerRoleBindings returns the RoleBinding to create in the given namespace for the public viewer, if the NSTemplateSet opted in
+func publicViewerRoleBindings(nsTmplSet *toolchainv1alpha1.NSTemplateSet, namespace string) []runtime.RawExtension {
+	if publicViewer(nsTmplSet) == "" {
+		return nil
+	}
+	viewer, _ := config.GetPublicViewer()
+	subject := map[string]interface{}{
+		"kind": viewer.Kind,
+		"name": viewer.Name,
+	}
+	if viewer.Kind == "ServiceAccount" {
+		subject["namespace"] = viewer.Namespace
+	} else {
+		subject["apiGroup"] = "rbac.authorization.k8s.io"
+	}
+	return []runtime.RawExtension{{Object: &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       "RoleBinding",
+		"metadata": map[string]interface{}{
+			"name":      publicViewerRoleBindingName,
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				"owner":    nsTmplSet.GetName(),
+				"provider": "codeready-toolchain",
+			},
+		},
+		"roleRef": map[string]interface{}{
+			"apiGroup": "rbac.authorization.k8s.io",
+			"kind":     "ClusterRole",
+			"name":     viewer.ClusterRole,
+		},
+		"subjects": []interface{}{subject},
+	}}}}
+}
@@ -0,0 +1,84 @@
+package nstemplateset
+
+import (
+	"testing"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPublicViewerRoleBindings(t *testing.T) {
+	shared := newNSTmplSet()
+	shared.Annotations = map[string]string{publicViewerAnnotation: "true"}
+
+	t.Run("no public viewer configured", func(t *testing.T) {
+		assert.Empty(t, publicViewer(shared))
+		assert.Empty(t, publicViewerRoleBindings(shared, "johnsmith-dev"))
+	})
+
+	loadPublicViewer(t, &memberv1alpha1.PublicViewerConfig{Name: "system:authenticated"})
+	defer loadPublicViewer(t, nil)
+
+	t.Run("not opted in", func(t *testing.T) {
+		assert.Empty(t, publicViewer(newNSTmplSet()))
+		assert.Empty(t, publicViewerRoleBindings(newNSTmplSet(), "johnsmith-dev"))
+	})
+
+	t.Run("group granted read-only access", func(t *testing.T) {
+		// when
+		objs := publicViewerRoleBindings(shared, "johnsmith-dev")
+
+		// then
+		assert.Equal(t, "Group::system:authenticated:view", publicViewer(shared))
+		require.Len(t, objs, 1)
+		rb, ok := objs[0].Object.(*unstructured.Unstructured)
+		require.True(t, ok)
+		assert.Equal(t, "RoleBinding", rb.GetKind())
+		assert.Equal(t, "public-viewer", rb.GetName())
+		assert.Equal(t, "johnsmith-dev", rb.GetNamespace())
+		assert.Equal(t, username, rb.GetLabels()["owner"])
+		roleName, _, _ := unstructured.NestedString(rb.Object, "roleRef", "name")
+		assert.Equal(t, "view", roleName)
+		subjects, _, _ := unstructured.NestedSlice(rb.Object, "subjects")
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"apiGroup": "rbac.authorization.k8s.io",
+			"kind":     "Group",
+			"name":     "system:authenticated",
+		}}, subjects)
+	})
+
+	t.Run("service account granted custom role", func(t *testing.T) {
+		// given
+		loadPublicViewer(t, &memberv1alpha1.PublicViewerConfig{Kind: "ServiceAccount", Name: "viewer", Namespace: "portal", ClusterRole: "toolchain-viewer"})
+
+		// when
+		objs := publicViewerRoleBindings(shared, "johnsmith-dev")
+
+		// then
+		assert.Equal(t, "ServiceAccount:portal:viewer:toolchain-viewer", publicViewer(shared))
+		require.Len(t, objs, 1)
+		rb := objs[0].Object.(*unstructured.Unstructured)
+		roleName, _, _ := unstructured.NestedString(rb.Object, "roleRef", "name")
+		assert.Equal(t, "toolchain-viewer", roleName)
+		subjects, _, _ := unstructured.NestedSlice(rb.Object, "subjects")
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"kind":      "ServiceAccount",
+			"name":      "viewer",
+			"namespace": "portal",
+		}}, subjects)
+	})
+}
+
+func loadPublicViewer(t *testing.T, viewer *memberv1alpha1.PublicViewerConfig) {
+	cfg := &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+		Spec:       memberv1alpha1.MemberOperatorConfigSpec{PublicViewer: viewer},
+	}
+	require.NoError(t, config.LoadMemberOperatorConfig(test.NewFakeClient(t, cfg), namespaceName))
+}
@@ -0,0 +1,49 @@
+package nstemplateset
+
+import (
+	"context"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/quota"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// updateQuotaUsage reports the current consumption of the ResourceQuotas of the given user namespaces in the quota usage annotation
+// of the NSTemplateSet, and the persistent storage consumption of the user in the storage usage annotation, if they changed
+func (r *ReconcileNSTemplateSet) updateQuotaUsage(nsTmplSet *toolchainv1alpha1.NSTemplateSet, userNamespaces []corev1.Namespace) error {
+	usage := quota.Usage{}
+	storageUsage := quota.StorageUsage{}
+	for _, ns := range userNamespaces {
+		quotas := &corev1.ResourceQuotaList{}
+		if err := r.client.List(context.TODO(), quotas, client.InNamespace(ns.Name)); err != nil {
+			return errs.Wrapf(err, "failed to list the resource quotas in namespace '%s'", ns.Name)
+		}
+		usage.Set(ns.Name, quotas.Items)
+		claims := &corev1.PersistentVolumeClaimList{}
+		if err := r.client.List(context.TODO(), claims, client.InNamespace(ns.Name)); err != nil {
+			return errs.Wrapf(err, "failed to list the persistent volume claims in namespace '%s'", ns.Name)
+		}
+		storageUsage.Add(claims.Items)
+	}
+	content, err := usage.String()
+	if err != nil {
+		return err
+	}
+	storageContent, err := storageUsage.String()
+	if err != nil {
+		return err
+	}
+	annotations := nsTmplSet.GetAnnotations()
+	if annotations[quota.UsageAnnotation] == content && annotations[quota.StorageUsageAnnotation] == storageContent {
+		return nil
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[quota.UsageAnnotation] = content
+	annotations[quota.StorageUsageAnnotation] = storageContent
+	nsTmplSet.SetAnnotations(annotations)
+	return r.client.Update(context.TODO(), nsTmplSet)
+}
@@ -0,0 +1,140 @@
+package nstemplateset
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/quota"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileQuotaUsage(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	devQuota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "compute-resources"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("7Gi")},
+			Used: corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("1Gi")},
+		},
+	}
+
+	t.Run("quota usage reported", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSet(), devQuota)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionTrue, "Provisioned")
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err = fakeClient.Get(context.TODO(), req.NamespacedName, nsTmplSet)
+		require.NoError(t, err)
+		usage, err := quota.ParseUsage(nsTmplSet.Annotations[quota.UsageAnnotation])
+		require.NoError(t, err)
+		require.Len(t, usage["johnsmith-dev"], 1)
+		assert.Equal(t, "compute-resources", usage["johnsmith-dev"][0].Name)
+		used := usage["johnsmith-dev"][0].Used[corev1.ResourceLimitsMemory]
+		assert.Equal(t, "1Gi", used.String())
+		assert.Empty(t, usage["johnsmith-code"])
+
+		t.Run("not updated when unchanged", func(t *testing.T) {
+			// given
+			fakeClient.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+				if _, ok := obj.(*toolchainv1alpha1.NSTemplateSet); ok {
+					return errors.New("unexpected update")
+				}
+				return fakeClient.Client.Update(ctx, obj, opts...)
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("storage usage reported", func(t *testing.T) {
+		// given
+		storageClass := "gp2"
+		devClaim := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "data"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &storageClass,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+				},
+			},
+		}
+		codeClaim := devClaim.DeepCopy()
+		codeClaim.Namespace = "johnsmith-code"
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSet(), devQuota, devClaim, codeClaim)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err = fakeClient.Get(context.TODO(), req.NamespacedName, nsTmplSet)
+		require.NoError(t, err)
+		assert.Equal(t, `{"gp2":{"claims":2,"requested":"2Gi"}}`, nsTmplSet.Annotations[quota.StorageUsageAnnotation])
+	})
+
+	t.Run("fail to list the persistent volume claims", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSet(), devQuota)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+		fakeClient.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			if _, ok := list.(*corev1.PersistentVolumeClaimList); ok {
+				return errors.New("mock error")
+			}
+			return fakeClient.Client.List(ctx, list, opts...)
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, "failed to update the quota usage: failed to list the persistent volume claims in namespace 'johnsmith-dev': mock error")
+		checkStatus(t, fakeClient, "UnableToProvision")
+	})
+
+	t.Run("fail to list the quotas", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newNSTmplSet(), devQuota)
+		createNamespace(t, fakeClient, "abcde11", "dev")
+		createNamespace(t, fakeClient, "abcde21", "code")
+		fakeClient.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			if _, ok := list.(*corev1.ResourceQuotaList); ok {
+				return errors.New("mock error")
+			}
+			return fakeClient.Client.List(ctx, list, opts...)
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, "failed to update the quota usage: failed to list the resource quotas in namespace 'johnsmith-dev': mock error")
+		checkStatus(t, fakeClient, "UnableToProvision")
+	})
+}
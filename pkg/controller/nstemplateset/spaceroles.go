@@ -0,0 +1,91 @@
+package nstemplateset
+
+import (
+	"encoding/json"
+	"fmt"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// spaceRolesAnnotation the annotation on the NSTemplateSet which holds the list of users who have access to the namespaces
+	// along with their role. The same annotation is set on each namespace with the value that was applied.
+	spaceRolesAnnotation = "toolchain.dev.openshift.com/space-roles"
+)
+
+// spaceRoleClusterRoles the ClusterRoles granted to the users for each space role
+var spaceRoleClusterRoles = map[string]string{
+	"admin":       "admin",
+	"contributor": "edit",
+	"viewer":      "view",
+}
+
+// SpaceRole a user with a role in all the namespaces of the NSTemplateSet
+type SpaceRole struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// parseSpaceRoles returns the space roles of the given NSTemplateSet
+func parseSpaceRoles(nsTmplSet *toolchainv1alpha1.NSTemplateSet) ([]SpaceRole, error) {
+	content := nsTmplSet.GetAnnotations()[spaceRolesAnnotation]
+	if content == "" {
+		return nil, nil
+	}
+	var spaceRoles []SpaceRole
+	if err := json.Unmarshal([]byte(content), &spaceRoles); err != nil {
+		return nil, errs.Wrap(err, "unable to parse the space roles")
+	}
+	for _, spaceRole := range spaceRoles {
+		if spaceRole.Username == "" {
+			return nil, fmt.Errorf("invalid space role: missing username")
+		}
+		if _, found := spaceRoleClusterRoles[spaceRole.Role]; !found {
+			return nil, fmt.Errorf("invalid space role '%s' for user '%s'", spaceRole.Role, spaceRole.Username)
+		}
+	}
+	return spaceRoles, nil
+}
+
+// spaceRoleBindings returns the RoleBindings to create in the given namespace for the space roles of the NSTemplateSet
+func spaceRoleBindings(nsTmplSet *toolchainv1alpha1.NSTemplateSet, namespace string) ([]runtime.RawExtension, error) {
+	spaceRoles, err := parseSpaceRoles(nsTmplSet)
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]runtime.RawExtension, 0, len(spaceRoles))
+	for _, spaceRole := range spaceRoles {
+		objs = append(objs, runtime.RawExtension{Object: newSpaceRoleBinding(nsTmplSet.GetName(), namespace, spaceRole)})
+	}
+	return objs, nil
+}
+
+func newSpaceRoleBinding(owner, namespace string, spaceRole SpaceRole) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       "RoleBinding",
+		"metadata": map[string]interface{}{
+			"name":      fmt.Sprintf("space-%s-%s", spaceRole.Role, spaceRole.Username),
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				"owner":    owner,
+				"provider": "codeready-toolchain",
+			},
+		},
+		"roleRef": map[string]interface{}{
+			"apiGroup": "rbac.authorization.k8s.io",
+			"kind":     "ClusterRole",
+			"name":     spaceRoleClusterRoles[spaceRole.Role],
+		},
+		"subjects": []interface{}{
+			map[string]interface{}{
+				"apiGroup": "rbac.authorization.k8s.io",
+				"kind":     "User",
+				"name":     spaceRole.Username,
+			},
+		},
+	}}
+}
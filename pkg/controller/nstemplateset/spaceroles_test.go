@@ -0,0 +1,98 @@
+package nstemplateset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseSpaceRoles(t *testing.T) {
+
+	t.Run("no space roles", func(t *testing.T) {
+		// when
+		spaceRoles, err := parseSpaceRoles(newNSTmplSet())
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, spaceRoles)
+	})
+
+	t.Run("valid space roles", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{
+			spaceRolesAnnotation: `[{"username":"jane","role":"admin"},{"username":"joe","role":"viewer"}]`,
+		}
+
+		// when
+		spaceRoles, err := parseSpaceRoles(nsTmplSet)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []SpaceRole{{Username: "jane", Role: "admin"}, {Username: "joe", Role: "viewer"}}, spaceRoles)
+	})
+
+	t.Run("invalid content", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{spaceRolesAnnotation: `{invalid`}
+
+		// when
+		_, err := parseSpaceRoles(nsTmplSet)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to parse the space roles")
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{spaceRolesAnnotation: `[{"username":"jane","role":"owner"}]`}
+
+		// when
+		_, err := parseSpaceRoles(nsTmplSet)
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, "invalid space role 'owner' for user 'jane'", err.Error())
+	})
+
+	t.Run("missing username", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet()
+		nsTmplSet.Annotations = map[string]string{spaceRolesAnnotation: `[{"role":"admin"}]`}
+
+		// when
+		_, err := parseSpaceRoles(nsTmplSet)
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, "invalid space role: missing username", err.Error())
+	})
+}
+
+func TestSpaceRoleBindings(t *testing.T) {
+	// given
+	nsTmplSet := newNSTmplSet()
+	nsTmplSet.Annotations = map[string]string{
+		spaceRolesAnnotation: `[{"username":"jane","role":"contributor"}]`,
+	}
+
+	// when
+	objs, err := spaceRoleBindings(nsTmplSet, "johnsmith-dev")
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, objs, 1)
+	rb, ok := objs[0].Object.(*unstructured.Unstructured)
+	require.True(t, ok)
+	assert.Equal(t, "RoleBinding", rb.GetKind())
+	assert.Equal(t, "space-contributor-jane", rb.GetName())
+	assert.Equal(t, "johnsmith-dev", rb.GetNamespace())
+	assert.Equal(t, username, rb.GetLabels()["owner"])
+	roleName, _, _ := unstructured.NestedString(rb.Object, "roleRef", "name")
+	assert.Equal(t, "edit", roleName)
+}
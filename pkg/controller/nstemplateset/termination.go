@@ -0,0 +1,83 @@
+package nstemplateset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/go-logr/logr"
+	errs "github.com/pkg/errors"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// checkStuckNamespaces reports the given user namespaces which have been terminating for longer than the timeout specified
+// in the MemberOperatorConfig (eg, because of the finalizers of the custom resources created by the user), and removes
+// the known-safe finalizers of the resources left in them. The NSTemplateSet is requeued so that the namespaces are checked
+// again once they may be stuck.
+func (r *ReconcileNSTemplateSet) checkStuckNamespaces(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, namespaces []corev1.Namespace) (reconcile.Result, error) {
+	timeout := config.GetStuckNamespaceTimeout()
+	requeueAfter := timeout
+	var stuck []string
+	for _, ns := range namespaces {
+		if ns.DeletionTimestamp == nil {
+			// deletion just requested
+			continue
+		}
+		terminating := time.Since(ns.DeletionTimestamp.Time)
+		if terminating < timeout {
+			if timeout-terminating < requeueAfter {
+				requeueAfter = timeout - terminating
+			}
+			continue
+		}
+		stuck = append(stuck, ns.Name)
+		if err := r.removeSafeFinalizers(logger, ns.Name, config.GetSafeFinalizers()); err != nil {
+			return reconcile.Result{}, r.wrapErrorWithStatusUpdate(logger, nsTmplSet, r.setStatusTerminationFailed, err,
+				"failed to remove the finalizers of the resources in namespace '%s'", ns.Name)
+		}
+	}
+	if len(stuck) == 0 {
+		return reconcile.Result{RequeueAfter: requeueAfter}, r.setStatusTerminating(nsTmplSet)
+	}
+	logger.Info("namespaces stuck in Terminating", "namespaces", stuck, "timeout", timeout)
+	return reconcile.Result{RequeueAfter: timeout}, r.setStatusTerminationStuck(nsTmplSet,
+		fmt.Sprintf("namespaces terminating for more than %s: %s", timeout, strings.Join(stuck, ", ")))
+}
+
+// removeSafeFinalizers removes the given finalizers from the resources of the given namespace. The kinds of resources which
+// do not exist in the cluster are ignored.
+func (r *ReconcileNSTemplateSet) removeSafeFinalizers(logger logr.Logger, namespace string, finalizers []memberv1alpha1.SafeFinalizer) error {
+	for _, finalizer := range finalizers {
+		objs := &unstructured.UnstructuredList{}
+		objs.SetAPIVersion(finalizer.APIVersion)
+		objs.SetKind(finalizer.Kind + "List")
+		if err := r.client.List(context.TODO(), objs, client.InNamespace(namespace)); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return errs.Wrapf(err, "failed to list the resources of kind '%s'", finalizer.Kind)
+		}
+		for i := range objs.Items {
+			obj := &objs.Items[i]
+			if !util.HasFinalizer(obj, finalizer.Finalizer) {
+				continue
+			}
+			logger.Info("removing finalizer", "namespace", namespace, "kind", finalizer.Kind, "name", obj.GetName(), "finalizer", finalizer.Finalizer)
+			util.RemoveFinalizer(obj, finalizer.Finalizer)
+			if err := r.client.Update(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+				return errs.Wrapf(err, "failed to remove the finalizer of the %s '%s'", finalizer.Kind, obj.GetName())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package nstemplateset
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestStuckNamespaces(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	defer func() {
+		err := config.LoadMemberOperatorConfig(test.NewFakeClient(t), namespaceName)
+		require.NoError(t, err)
+	}()
+
+	newDeletedNSTmplSet := func() *toolchainv1alpha1.NSTemplateSet {
+		nsTmplSet := newNSTmplSet()
+		deletionTS := metav1.Now()
+		nsTmplSet.DeletionTimestamp = &deletionTS
+		return nsTmplSet
+	}
+	terminate := func(t *testing.T, cl client.Client, ns *corev1.Namespace, since time.Duration) {
+		deletionTS := metav1.NewTime(time.Now().Add(-since))
+		ns.DeletionTimestamp = &deletionTS
+		ns.Status.Phase = corev1.NamespaceTerminating
+		err := cl.Update(context.TODO(), ns)
+		require.NoError(t, err)
+	}
+	newLeftover := func(finalizers ...string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: username + "-dev", Name: "leftover", Finalizers: finalizers},
+		}
+	}
+	newConfig := func(safeFinalizers ...memberv1alpha1.SafeFinalizer) *memberv1alpha1.MemberOperatorConfig {
+		return &memberv1alpha1.MemberOperatorConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+			Spec: memberv1alpha1.MemberOperatorConfigSpec{
+				NamespaceTermination: &memberv1alpha1.NamespaceTerminationConfig{
+					StuckTimeout:   "30m",
+					SafeFinalizers: safeFinalizers,
+				},
+			},
+		}
+	}
+	getLeftover := func(t *testing.T, cl client.Client) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		err := cl.Get(context.TODO(), types.NamespacedName{Namespace: username + "-dev", Name: "leftover"}, cm)
+		require.NoError(t, err)
+		return cm
+	}
+
+	t.Run("namespace terminating within the timeout", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newDeletedNSTmplSet(), newConfig())
+		ns := createNamespace(t, fakeClient, "abcde11", "dev")
+		terminate(t, fakeClient, ns, 10*time.Minute)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "Terminating")
+		// requeued when the namespace may be stuck
+		assert.True(t, res.RequeueAfter > 19*time.Minute && res.RequeueAfter <= 20*time.Minute)
+	})
+
+	t.Run("stuck namespace reported", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newDeletedNSTmplSet(), newConfig(), newLeftover("example.com/cleanup"))
+		ns := createNamespace(t, fakeClient, "abcde11", "dev")
+		terminate(t, fakeClient, ns, time.Hour)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Minute, res.RequeueAfter)
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "TerminationStuck")
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: username}, nsTmplSet)
+		require.NoError(t, err)
+		readyCond, _ := condition.FindConditionByType(nsTmplSet.Status.Conditions, toolchainv1alpha1.ConditionReady)
+		assert.Equal(t, "namespaces terminating for more than 30m0s: johnsmith-dev", readyCond.Message)
+		// no finalizer removed since none is known to be safe
+		assert.Equal(t, []string{"example.com/cleanup"}, getLeftover(t, fakeClient).Finalizers)
+		assert.Equal(t, []string{nsTmplSetFinalizerName}, nsTmplSet.Finalizers)
+	})
+
+	t.Run("safe finalizers removed from stuck namespace", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newDeletedNSTmplSet(),
+			newConfig(memberv1alpha1.SafeFinalizer{APIVersion: "v1", Kind: "ConfigMap", Finalizer: "example.com/cleanup"}),
+			newLeftover("example.com/cleanup", "example.com/backup"))
+		ns := createNamespace(t, fakeClient, "abcde11", "dev")
+		terminate(t, fakeClient, ns, time.Hour)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReadyCond(t, fakeClient, corev1.ConditionFalse, "TerminationStuck")
+		assert.Equal(t, []string{"example.com/backup"}, getLeftover(t, fakeClient).Finalizers)
+	})
+
+	t.Run("removal of the finalizers fails", func(t *testing.T) {
+		// given
+		r, req, fakeClient := prepareReconcile(t, newDeletedNSTmplSet(),
+			newConfig(memberv1alpha1.SafeFinalizer{APIVersion: "v1", Kind: "ConfigMap", Finalizer: "example.com/cleanup"}),
+			newLeftover("example.com/cleanup"))
+		ns := createNamespace(t, fakeClient, "abcde11", "dev")
+		terminate(t, fakeClient, ns, time.Hour)
+		fakeClient.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			if _, ok := obj.(*toolchainv1alpha1.NSTemplateSet); ok {
+				return fakeClient.Client.Update(ctx, obj, opts...)
+			}
+			return errors.New("mock error")
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to remove the finalizers of the resources in namespace 'johnsmith-dev'")
+		checkStatus(t, fakeClient, "UnableToTerminate")
+	})
+}
@@ -0,0 +1,275 @@
+package secretpropagation
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/clustercache"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+	"github.com/codeready-toolchain/member-operator/pkg/pause"
+
+	"github.com/go-logr/logr"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"github.com/operator-framework/operator-sdk/pkg/predicate"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crpredicate "sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_secretpropagation")
+
+const (
+	// PropagateLabel the label to set to `true` on the Secrets of the operator namespace which should be copied into every user namespace
+	// (eg, pull secrets, SMTP credentials or trusted CA bundles)
+	PropagateLabel = "toolchain.dev.openshift.com/propagate"
+	// ExcludedTiersAnnotation the annotation of a propagated Secret which holds the comma-separated names of the tiers whose namespaces
+	// do not receive a copy of the Secret
+	ExcludedTiersAnnotation = "toolchain.dev.openshift.com/excluded-tiers"
+	// PropagatedFromLabel the label set on the copies of the propagated Secrets, with the namespace of the operator as its value
+	PropagatedFromLabel = "toolchain.dev.openshift.com/propagated-from"
+)
+
+// Add creates a new SecretPropagation Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	// the copies of the secrets are in the user namespaces, which are not in the cache of the manager
+	userObjects, err := clustercache.New(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, newReconciler(mgr, userObjects, namespace), userObjects, namespace)
+}
+
+func newReconciler(mgr manager.Manager, userObjects client.Reader, namespace string) reconcile.Reconciler {
+	return &ReconcileSecretPropagation{
+		client:      config.ControllerClient("secretpropagation", mgr.GetClient()),
+		userObjects: userObjects,
+		scheme:      mgr.GetScheme(),
+		namespace:   namespace,
+	}
+}
+
+func add(mgr manager.Manager, r reconcile.Reconciler, userObjects cache.Cache, namespace string) error {
+	c, err := controller.New("secretpropagation-controller", mgr, config.ControllerOptions("secretpropagation", r))
+	if err != nil {
+		return err
+	}
+	// Watch for the NSTemplateSets which are created or moved to another tier
+	if err := c.Watch(&source.Kind{Type: &toolchainv1alpha1.NSTemplateSet{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+	// Watch for the user namespaces, so that the Secrets are copied as soon as the namespaces are provisioned
+	enqueueOwner := &handler.EnqueueRequestsFromMapFunc{ToRequests: toNSTemplateSetOfNamespace(namespace)}
+	if err := c.Watch(&source.Kind{Type: &corev1.Namespace{}}, enqueueOwner, crpredicate.ResourceVersionChangedPredicate{}); err != nil {
+		return err
+	}
+	// Watch for changes to the propagated Secrets, which are copied again into all the user namespaces, and to their copies,
+	// which are restored if they are changed or deleted
+	enqueueSecret := &handler.EnqueueRequestsFromMapFunc{ToRequests: toNSTemplateSetsOfSecret(mgr.GetClient(), namespace)}
+	secrets, err := clustercache.Kind(userObjects, &corev1.Secret{})
+	if err != nil {
+		return err
+	}
+	return c.Watch(secrets, enqueueSecret, crpredicate.ResourceVersionChangedPredicate{})
+}
+
+// toNSTemplateSetOfNamespace returns a mapper which maps the user namespaces to the NSTemplateSet of their owner in the given namespace
+func toNSTemplateSetOfNamespace(namespace string) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		owner, found := obj.Meta.GetLabels()["owner"]
+		if !found || owner == "" {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: owner}}}
+	}
+}
+
+// toNSTemplateSetsOfSecret returns a mapper which maps the propagated Secrets to all the NSTemplateSets in the given namespace,
+// and the copies of the propagated Secrets to the NSTemplateSet of the owner of their namespace
+func toNSTemplateSetsOfSecret(cl client.Client, namespace string) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		if obj.Meta.GetNamespace() == namespace {
+			if obj.Meta.GetLabels()[PropagateLabel] != "true" {
+				return nil
+			}
+			nsTmplSets := &toolchainv1alpha1.NSTemplateSetList{}
+			if err := cl.List(context.TODO(), nsTmplSets, client.InNamespace(namespace)); err != nil {
+				log.Error(err, "failed to list the NSTemplateSets", "namespace", namespace)
+				return nil
+			}
+			requests := make([]reconcile.Request, len(nsTmplSets.Items))
+			for i, nsTmplSet := range nsTmplSets.Items {
+				requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: nsTmplSet.Name}}
+			}
+			return requests
+		}
+		if obj.Meta.GetLabels()[PropagatedFromLabel] != namespace {
+			return nil
+		}
+		ns := &corev1.Namespace{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Name: obj.Meta.GetNamespace()}, ns); err != nil {
+			log.Error(err, "failed to get the namespace of the secret", "namespace", obj.Meta.GetNamespace(), "name", obj.Meta.GetName())
+			return nil
+		}
+		return toNSTemplateSetOfNamespace(namespace)(handler.MapObject{Meta: ns, Object: ns})
+	}
+}
+
+var _ reconcile.Reconciler = &ReconcileSecretPropagation{}
+
+// ReconcileSecretPropagation copies the Secrets of the operator namespace which have the `toolchain.dev.openshift.com/propagate=true` label
+// into the namespaces of each user, and keeps the copies in sync with their source
+type ReconcileSecretPropagation struct {
+	client client.Client
+	// userObjects the reader of the copies of the secrets, in the user namespaces (see `clustercache.New`)
+	userObjects client.Reader
+	scheme      *runtime.Scheme
+	namespace   string
+}
+
+// Reconcile copies the propagated Secrets into the namespaces of the NSTemplateSet (except the Secrets which exclude its tier),
+// updates the copies whose content changed and deletes the copies whose source is not propagated anymore
+func (r *ReconcileSecretPropagation) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := logging.ForRequest(log, request)
+
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: r.namespace, Name: request.Name}, nsTmplSet); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errs.Wrapf(err, "failed to get the NSTemplateSet '%s'", request.Name)
+	}
+	// the namespaces are about to be deleted, or must be left untouched while the reconciliation is paused
+	if nsTmplSet.DeletionTimestamp != nil || pause.IsPaused(nsTmplSet) {
+		return reconcile.Result{}, nil
+	}
+
+	sources := &corev1.SecretList{}
+	if err := r.client.List(context.TODO(), sources, client.InNamespace(r.namespace), client.MatchingLabels{PropagateLabel: "true"}); err != nil {
+		return reconcile.Result{}, errs.Wrap(err, "failed to list the propagated secrets")
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := r.client.List(context.TODO(), namespaces, client.MatchingLabels{"owner": nsTmplSet.Name}); err != nil {
+		return reconcile.Result{}, errs.Wrapf(err, "failed to list the namespaces of user '%s'", nsTmplSet.Name)
+	}
+	for _, ns := range namespaces.Items {
+		if ns.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+		if err := r.propagate(reqLogger, ns.Name, nsTmplSet.Spec.TierName, sources.Items); err != nil {
+			return reconcile.Result{}, errs.Wrapf(err, "failed to propagate the secrets into namespace '%s'", ns.Name)
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+// propagate copies the given Secrets into the given namespace (except those which exclude the given tier) and deletes the stale copies
+func (r *ReconcileSecretPropagation) propagate(logger logr.Logger, namespace, tier string, sources []corev1.Secret) error {
+	propagated := map[string]bool{}
+	for i := range sources {
+		src := &sources[i]
+		if excludesTier(src, tier) {
+			continue
+		}
+		propagated[src.Name] = true
+		if err := r.ensureCopy(logger, namespace, src); err != nil {
+			return err
+		}
+	}
+	copies := &corev1.SecretList{}
+	if err := r.userObjects.List(context.TODO(), copies, client.InNamespace(namespace), client.MatchingLabels{PropagatedFromLabel: r.namespace}); err != nil {
+		return errs.Wrap(err, "failed to list the copies of the secrets")
+	}
+	for i := range copies.Items {
+		cp := &copies.Items[i]
+		if propagated[cp.Name] {
+			continue
+		}
+		logger.Info("deleting the copy of a secret which is not propagated anymore", "namespace", namespace, "name", cp.Name)
+		if err := r.client.Delete(context.TODO(), cp); err != nil && !errors.IsNotFound(err) {
+			return errs.Wrapf(err, "failed to delete the secret '%s'", cp.Name)
+		}
+	}
+	return nil
+}
+
+// ensureCopy creates or updates the copy of the given Secret in the given namespace. A Secret of the same name which is not a copy
+// (ie, which was created by the user) is left untouched
+func (r *ReconcileSecretPropagation) ensureCopy(logger logr.Logger, namespace string, src *corev1.Secret) error {
+	existing := &corev1.Secret{}
+	if err := r.userObjects.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: src.Name}, existing); err != nil {
+		if !errors.IsNotFound(err) {
+			return errs.Wrapf(err, "failed to get the secret '%s'", src.Name)
+		}
+		logger.Info("copying secret", "namespace", namespace, "name", src.Name)
+		if err := r.client.Create(context.TODO(), r.newCopy(namespace, src)); err != nil {
+			return errs.Wrapf(err, "failed to create the secret '%s'", src.Name)
+		}
+		return nil
+	}
+	if existing.Labels[PropagatedFromLabel] != r.namespace {
+		logger.Info("not overwriting a secret created by the user", "namespace", namespace, "name", src.Name)
+		return nil
+	}
+	if existing.Type == src.Type && reflect.DeepEqual(existing.Data, src.Data) {
+		return nil
+	}
+	logger.Info("updating the copy of a secret", "namespace", namespace, "name", src.Name)
+	if existing.Type != src.Type {
+		// the type of a secret is immutable
+		if err := r.client.Delete(context.TODO(), existing); err != nil && !errors.IsNotFound(err) {
+			return errs.Wrapf(err, "failed to delete the secret '%s'", src.Name)
+		}
+		if err := r.client.Create(context.TODO(), r.newCopy(namespace, src)); err != nil {
+			return errs.Wrapf(err, "failed to create the secret '%s'", src.Name)
+		}
+		return nil
+	}
+	existing.Data = src.Data
+	if err := r.client.Update(context.TODO(), existing); err != nil {
+		return errs.Wrapf(err, "failed to update the secret '%s'", src.Name)
+	}
+	return nil
+}
+
+// newCopy returns a copy of the given Secret in the given namespace
+func (r *ReconcileSecretPropagation) newCopy(namespace string, src *corev1.Secret) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      src.Name,
+			Labels:    map[string]string{PropagatedFromLabel: r.namespace},
+		},
+		Type: src.Type,
+		Data: src.Data,
+	}
+}
+
+// excludesTier returns true if the given Secret should not be copied into the namespaces of the given tier
+func excludesTier(src *corev1.Secret, tier string) bool {
+	for _, excluded := range strings.Split(src.Annotations[ExcludedTiersAnnotation], ",") {
+		if strings.TrimSpace(excluded) == tier {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,385 @@
+package secretpropagation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/member-operator/pkg/pause"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const operatorNamespace = "toolchain-member-operator"
+
+func TestReconcile(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+
+	t.Run("secrets copied into the user namespaces", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, "johnsmith", newNSTmplSet("johnsmith", "basic"),
+			newUserNamespace("johnsmith", "dev"), newUserNamespace("johnsmith", "stage"), newUserNamespace("jane", "dev"),
+			newSource("pull-secret", "token"), newSource("smtp", "password"),
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: "internal"}, Data: map[string][]byte{"key": []byte("secret")}})
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		for _, ns := range []string{"johnsmith-dev", "johnsmith-stage"} {
+			assertCopy(t, cl, ns, "pull-secret", "token")
+			assertCopy(t, cl, ns, "smtp", "password")
+			assertNoSecret(t, cl, ns, "internal")
+		}
+		assertNoSecret(t, cl, "jane-dev", "pull-secret")
+
+		t.Run("copies updated when the source changes", func(t *testing.T) {
+			// given
+			src := getSecret(t, cl, operatorNamespace, "smtp")
+			src.Data["value"] = []byte("new-password")
+			err := cl.Update(context.TODO(), src)
+			require.NoError(t, err)
+
+			// when
+			_, err = r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assertCopy(t, cl, "johnsmith-dev", "smtp", "new-password")
+			assertCopy(t, cl, "johnsmith-stage", "smtp", "new-password")
+		})
+
+		t.Run("deleted copy restored", func(t *testing.T) {
+			// given
+			err := cl.Delete(context.TODO(), getSecret(t, cl, "johnsmith-dev", "pull-secret"))
+			require.NoError(t, err)
+
+			// when
+			_, err = r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assertCopy(t, cl, "johnsmith-dev", "pull-secret", "token")
+		})
+
+		t.Run("copies deleted when the source is not propagated anymore", func(t *testing.T) {
+			// given
+			src := getSecret(t, cl, operatorNamespace, "pull-secret")
+			delete(src.Labels, PropagateLabel)
+			err := cl.Update(context.TODO(), src)
+			require.NoError(t, err)
+
+			// when
+			_, err = r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assertNoSecret(t, cl, "johnsmith-dev", "pull-secret")
+			assertNoSecret(t, cl, "johnsmith-stage", "pull-secret")
+			assertCopy(t, cl, "johnsmith-dev", "smtp", "new-password")
+		})
+	})
+
+	t.Run("secret not propagated to the excluded tiers", func(t *testing.T) {
+		// given
+		excluded := newSource("ca-bundle", "cert")
+		excluded.Annotations = map[string]string{ExcludedTiersAnnotation: "team, basic"}
+		r, req, cl := prepareReconcile(t, "johnsmith", newNSTmplSet("johnsmith", "basic"), newUserNamespace("johnsmith", "dev"),
+			excluded, newSource("pull-secret", "token"), newCopy("johnsmith-dev", "ca-bundle", "cert"))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertNoSecret(t, cl, "johnsmith-dev", "ca-bundle")
+		assertCopy(t, cl, "johnsmith-dev", "pull-secret", "token")
+	})
+
+	t.Run("secret of the user not overwritten", func(t *testing.T) {
+		// given
+		own := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "pull-secret"},
+			Data:       map[string][]byte{"value": []byte("mine")},
+		}
+		r, req, cl := prepareReconcile(t, "johnsmith", newNSTmplSet("johnsmith", "basic"), newUserNamespace("johnsmith", "dev"),
+			newSource("pull-secret", "token"), own)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		secret := getSecret(t, cl, "johnsmith-dev", "pull-secret")
+		assert.Equal(t, "mine", string(secret.Data["value"]))
+		assert.NotContains(t, secret.Labels, PropagatedFromLabel)
+	})
+
+	t.Run("copy recreated when the type of the source changes", func(t *testing.T) {
+		// given
+		src := newSource("pull-secret", "token")
+		src.Type = corev1.SecretTypeDockerConfigJson
+		r, req, cl := prepareReconcile(t, "johnsmith", newNSTmplSet("johnsmith", "basic"), newUserNamespace("johnsmith", "dev"),
+			src, newCopy("johnsmith-dev", "pull-secret", "token"))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		secret := assertCopy(t, cl, "johnsmith-dev", "pull-secret", "token")
+		assert.Equal(t, corev1.SecretTypeDockerConfigJson, secret.Type)
+	})
+
+	t.Run("terminating namespace skipped", func(t *testing.T) {
+		// given
+		ns := newUserNamespace("johnsmith", "dev")
+		ns.Status.Phase = corev1.NamespaceTerminating
+		r, req, cl := prepareReconcile(t, "johnsmith", newNSTmplSet("johnsmith", "basic"), ns, newSource("pull-secret", "token"))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertNoSecret(t, cl, "johnsmith-dev", "pull-secret")
+	})
+
+	t.Run("nothing copied while paused", func(t *testing.T) {
+		// given
+		nsTmplSet := newNSTmplSet("johnsmith", "basic")
+		nsTmplSet.Annotations = map[string]string{pause.Annotation: "true"}
+		r, req, cl := prepareReconcile(t, "johnsmith", nsTmplSet, newUserNamespace("johnsmith", "dev"), newSource("pull-secret", "token"))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertNoSecret(t, cl, "johnsmith-dev", "pull-secret")
+	})
+
+	t.Run("nstemplateset not found", func(t *testing.T) {
+		// given
+		r, req, _ := prepareReconcile(t, "johnsmith", newSource("pull-secret", "token"))
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("list of the secrets fails", func(t *testing.T) {
+			// given
+			r, req, cl := prepareReconcile(t, "johnsmith", newNSTmplSet("johnsmith", "basic"), newUserNamespace("johnsmith", "dev"))
+			cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+				if _, ok := list.(*corev1.SecretList); ok {
+					return errors.New("mock error")
+				}
+				return cl.Client.List(ctx, list, opts...)
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.EqualError(t, err, "failed to list the propagated secrets: mock error")
+		})
+
+		t.Run("creation of the copy fails", func(t *testing.T) {
+			// given
+			r, req, cl := prepareReconcile(t, "johnsmith", newNSTmplSet("johnsmith", "basic"), newUserNamespace("johnsmith", "dev"),
+				newSource("pull-secret", "token"))
+			cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+				return errors.New("mock error")
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.EqualError(t, err, "failed to propagate the secrets into namespace 'johnsmith-dev': failed to create the secret 'pull-secret': mock error")
+		})
+	})
+}
+
+func TestMappers(t *testing.T) {
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	t.Run("namespace mapped to the nstemplateset of its owner", func(t *testing.T) {
+		// given
+		ns := newUserNamespace("johnsmith", "dev")
+
+		// when
+		requests := toNSTemplateSetOfNamespace(operatorNamespace)(handler.MapObject{Meta: ns, Object: ns})
+
+		// then
+		require.Len(t, requests, 1)
+		assert.Equal(t, types.NamespacedName{Namespace: operatorNamespace, Name: "johnsmith"}, requests[0].NamespacedName)
+	})
+
+	t.Run("namespace without owner not mapped", func(t *testing.T) {
+		// given
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "openshift-monitoring"}}
+
+		// when
+		requests := toNSTemplateSetOfNamespace(operatorNamespace)(handler.MapObject{Meta: ns, Object: ns})
+
+		// then
+		assert.Empty(t, requests)
+	})
+
+	t.Run("source secret mapped to all nstemplatesets", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet("johnsmith", "basic"), newNSTmplSet("jane", "team"))
+		src := newSource("pull-secret", "token")
+
+		// when
+		requests := toNSTemplateSetsOfSecret(cl, operatorNamespace)(handler.MapObject{Meta: src, Object: src})
+
+		// then
+		assert.Len(t, requests, 2)
+	})
+
+	t.Run("copy mapped to the nstemplateset of the owner of its namespace", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet("johnsmith", "basic"), newUserNamespace("johnsmith", "dev"))
+		cp := newCopy("johnsmith-dev", "pull-secret", "token")
+
+		// when
+		requests := toNSTemplateSetsOfSecret(cl, operatorNamespace)(handler.MapObject{Meta: cp, Object: cp})
+
+		// then
+		require.Len(t, requests, 1)
+		assert.Equal(t, types.NamespacedName{Namespace: operatorNamespace, Name: "johnsmith"}, requests[0].NamespacedName)
+	})
+
+	t.Run("other secrets not mapped", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet("johnsmith", "basic"), newUserNamespace("johnsmith", "dev"))
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "builder-token"}}
+
+		// when
+		requests := toNSTemplateSetsOfSecret(cl, operatorNamespace)(handler.MapObject{Meta: secret, Object: secret})
+
+		// then
+		assert.Empty(t, requests)
+	})
+}
+
+func prepareReconcile(t *testing.T, name string, initObjs ...runtime.Object) (*ReconcileSecretPropagation, reconcile.Request, *test.FakeClient) {
+	cl := test.NewFakeClient(t, initObjs...)
+	r := &ReconcileSecretPropagation{
+		// the client of the manager, whose cache only holds the objects of the operator namespace
+		client:      namespacedClient{Client: cl, namespace: operatorNamespace},
+		userObjects: cl,
+		scheme:      scheme.Scheme,
+		namespace:   operatorNamespace,
+	}
+	return r, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: operatorNamespace, Name: name}}, cl
+}
+
+// namespacedClient a client which only reads the namespaced objects of the given namespace, like the client of the manager
+type namespacedClient struct {
+	client.Client
+	namespace string
+}
+
+func (c namespacedClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if key.Namespace != "" && key.Namespace != c.namespace {
+		return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c namespacedClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if listOpts := (&client.ListOptions{}).ApplyOptions(opts); listOpts.Namespace != "" && listOpts.Namespace != c.namespace {
+		return nil
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func newNSTmplSet(name, tier string) *toolchainv1alpha1.NSTemplateSet {
+	return &toolchainv1alpha1.NSTemplateSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: name},
+		Spec:       toolchainv1alpha1.NSTemplateSetSpec{TierName: tier},
+	}
+}
+
+func newUserNamespace(owner, typeName string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   owner + "-" + typeName,
+			Labels: map[string]string{"owner": owner, "type": typeName},
+		},
+		Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+	}
+}
+
+func newSource(name, value string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: operatorNamespace,
+			Name:      name,
+			Labels:    map[string]string{PropagateLabel: "true"},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{"value": []byte(value)},
+	}
+}
+
+func newCopy(namespace, name, value string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{PropagatedFromLabel: operatorNamespace},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{"value": []byte(value)},
+	}
+}
+
+func getSecret(t *testing.T, cl client.Client, namespace, name string) *corev1.Secret {
+	secret := &corev1.Secret{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, secret)
+	require.NoError(t, err)
+	return secret
+}
+
+func assertCopy(t *testing.T, cl client.Client, namespace, name, value string) *corev1.Secret {
+	secret := getSecret(t, cl, namespace, name)
+	assert.Equal(t, operatorNamespace, secret.Labels[PropagatedFromLabel])
+	assert.Equal(t, value, string(secret.Data["value"]))
+	return secret
+}
+
+func assertNoSecret(t *testing.T, cl client.Client, namespace, name string) {
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err), "secret '%s' should not exist in namespace '%s'", name, namespace)
+}
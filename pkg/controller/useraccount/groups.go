@@ -0,0 +1,105 @@
+package useraccount
+
+import (
+	"context"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/go-logr/logr"
+	userv1 "github.com/openshift/api/user/v1"
+	errs "github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// groupsAnnotation the annotation of the UserAccount which lists the OpenShift groups the user is a member of, as comma-separated
+	// group names (e.g. `crw-users,trusted-users`), so that the cluster-level policies bound to these groups apply to the user
+	groupsAnnotation = "toolchain.dev.openshift.com/groups"
+	// providerLabel the label set on the groups whose members are managed by the operator
+	providerLabel = "provider"
+	// toolchainProvider the value of the provider label
+	toolchainProvider = "codeready-toolchain"
+)
+
+// groupNames returns the names of the groups listed in the annotation of the given user account, without duplicates
+func groupNames(userAcc *toolchainv1alpha1.UserAccount) []string {
+	var names []string
+	for _, name := range strings.Split(userAcc.Annotations[groupsAnnotation], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || containsString(names, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// ensureGroups adds the user to the given groups (creating the groups which do not exist) and removes it from the other groups
+// managed by the operator
+func (r *ReconcileUserAccount) ensureGroups(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount, groups []string) error {
+	for _, name := range groups {
+		group := &userv1.Group{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: name}, group); err != nil {
+			if !errors.IsNotFound(err) {
+				return errs.Wrapf(err, "failed to get group '%s'", name)
+			}
+			group = &userv1.Group{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{providerLabel: toolchainProvider},
+				},
+				Users: userv1.OptionalNames{userAcc.Name},
+			}
+			logger.Info("creating group", "name", name)
+			if err := r.client.Create(context.TODO(), group); err != nil {
+				return errs.Wrapf(err, "failed to create group '%s'", name)
+			}
+			continue
+		}
+		if containsString(group.Users, userAcc.Name) && group.Labels[providerLabel] == toolchainProvider {
+			continue
+		}
+		if group.Labels == nil {
+			group.Labels = map[string]string{}
+		}
+		group.Labels[providerLabel] = toolchainProvider
+		if !containsString(group.Users, userAcc.Name) {
+			group.Users = append(group.Users, userAcc.Name)
+		}
+		logger.Info("adding user to group", "name", name)
+		if err := r.client.Update(context.TODO(), group); err != nil {
+			return errs.Wrapf(err, "failed to add user '%s' to group '%s'", userAcc.Name, name)
+		}
+	}
+	return r.removeFromGroups(logger, userAcc, groups)
+}
+
+// removeFromGroups removes the user from the groups managed by the operator, except those in `keep`. The groups themselves are kept,
+// since they may be referenced by cluster-level policies
+func (r *ReconcileUserAccount) removeFromGroups(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount, keep []string) error {
+	groups := &userv1.GroupList{}
+	if err := r.client.List(context.TODO(), groups); err != nil {
+		return errs.Wrap(err, "failed to list the groups")
+	}
+	for i := range groups.Items {
+		group := &groups.Items[i]
+		if group.Labels[providerLabel] != toolchainProvider || containsString(keep, group.Name) || !containsString(group.Users, userAcc.Name) {
+			continue
+		}
+		users := userv1.OptionalNames{}
+		for _, user := range group.Users {
+			if user != userAcc.Name {
+				users = append(users, user)
+			}
+		}
+		group.Users = users
+		logger.Info("removing user from group", "name", group.Name)
+		if err := r.client.Update(context.TODO(), group); err != nil {
+			return errs.Wrapf(err, "failed to remove user '%s' from group '%s'", userAcc.Name, group.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package useraccount
+
+import (
+	"context"
+	"testing"
+
+	userv1 "github.com/openshift/api/user/v1"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGroupNames(t *testing.T) {
+	// given
+	userAcc := newUserAccount("johnsmith", "12345")
+
+	t.Run("no group", func(t *testing.T) {
+		assert.Empty(t, groupNames(userAcc))
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		// given
+		userAcc := userAcc.DeepCopy()
+		userAcc.Annotations = map[string]string{groupsAnnotation: "crw-users, trusted-users,,crw-users"}
+
+		// then
+		assert.Equal(t, []string{"crw-users", "trusted-users"}, groupNames(userAcc))
+	})
+}
+
+func TestEnsureGroups(t *testing.T) {
+	username := "johnsmith"
+	userAcc := newUserAccount(username, uuid.NewV4().String())
+
+	t.Run("group created", func(t *testing.T) {
+		// given
+		r, _, cl := prepareReconcile(t, username, userAcc)
+
+		// when
+		err := r.ensureGroups(log, userAcc, []string{"crw-users"})
+
+		// then
+		require.NoError(t, err)
+		group := getGroup(t, cl, "crw-users")
+		assert.Equal(t, userv1.OptionalNames{username}, group.Users)
+		assert.Equal(t, "codeready-toolchain", group.Labels["provider"])
+	})
+
+	t.Run("user added to existing group", func(t *testing.T) {
+		// given
+		r, _, cl := prepareReconcile(t, username, userAcc, newGroup("trusted-users", false, "janedoe"))
+
+		// when
+		err := r.ensureGroups(log, userAcc, []string{"trusted-users"})
+
+		// then
+		require.NoError(t, err)
+		group := getGroup(t, cl, "trusted-users")
+		assert.Equal(t, userv1.OptionalNames{"janedoe", username}, group.Users)
+		assert.Equal(t, "codeready-toolchain", group.Labels["provider"])
+	})
+
+	t.Run("user removed from the managed groups only", func(t *testing.T) {
+		// given
+		r, _, cl := prepareReconcile(t, username, userAcc,
+			newGroup("crw-users", true, username, "janedoe"),
+			newGroup("trusted-users", true, username),
+			newGroup("admins", false, username))
+
+		// when
+		err := r.ensureGroups(log, userAcc, []string{"trusted-users"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, userv1.OptionalNames{"janedoe"}, getGroup(t, cl, "crw-users").Users)
+		assert.Equal(t, userv1.OptionalNames{username}, getGroup(t, cl, "trusted-users").Users)
+		assert.Equal(t, userv1.OptionalNames{username}, getGroup(t, cl, "admins").Users)
+	})
+
+	t.Run("user removed when disabled", func(t *testing.T) {
+		// given
+		userAcc := newDisabledUserAccount(username, userAcc.Spec.UserID)
+		userAcc.Annotations = map[string]string{groupsAnnotation: "crw-users"}
+		r, req, cl := prepareReconcile(t, username, userAcc, newGroup("crw-users", true, username))
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, getGroup(t, cl, "crw-users").Users)
+	})
+}
+
+func newGroup(name string, managed bool, users ...string) *userv1.Group {
+	group := &userv1.Group{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Users:      users,
+	}
+	if managed {
+		group.Labels = map[string]string{"provider": "codeready-toolchain"}
+	}
+	return group
+}
+
+func getGroup(t *testing.T, cl client.Client, name string) *userv1.Group {
+	group := &userv1.Group{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Name: name}, group))
+	return group
+}
@@ -0,0 +1,86 @@
+package useraccount
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	userv1 "github.com/openshift/api/user/v1"
+	errs "github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// additionalIdentitiesAnnotation the annotation of the UserAccount which lists the identities to provision in addition to
+	// the primary one, as comma-separated `<provider>:<user id>` values (e.g. `github:12345,sso:abcd`)
+	additionalIdentitiesAnnotation = "toolchain.dev.openshift.com/additional-identities"
+	// ownerLabel the label set on the identities created by the operator, whose value is the name of the UserAccount
+	ownerLabel = "owner"
+)
+
+// identityNames returns the names of the identities of the given user account: the primary identity (from the `spec.userID`)
+// followed by the additional identities listed in the annotation, without duplicates
+func identityNames(userAcc *toolchainv1alpha1.UserAccount) ([]string, error) {
+	names := []string{ToIdentityName(userAcc.Spec.UserID)}
+	value, found := userAcc.Annotations[additionalIdentitiesAnnotation]
+	if !found {
+		return names, nil
+	}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || containsString(names, name) {
+			continue
+		}
+		if provider, userID := splitIdentityName(name); provider == "" || userID == "" {
+			return nil, fmt.Errorf("invalid identity '%s': expected format is '<provider>:<user id>'", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// splitIdentityName returns the provider name and the provider user name of the given identity name
+func splitIdentityName(name string) (string, string) {
+	segments := strings.SplitN(name, ":", 2)
+	if len(segments) != 2 {
+		return "", ""
+	}
+	return segments[0], segments[1]
+}
+
+// deleteOwnedIdentities deletes the identities of the given user account (ie, labelled with its name or controlled by it),
+// except those in `keep`. This includes the identities named after a previous identity provider.
+// Returns `true` if at least one identity was deleted
+func (r *ReconcileUserAccount) deleteOwnedIdentities(userAcc *toolchainv1alpha1.UserAccount, keep []string) (bool, error) {
+	identities := &userv1.IdentityList{}
+	if err := r.client.List(context.TODO(), identities); err != nil {
+		return false, errs.Wrap(err, "failed to list the identities")
+	}
+	deleted := false
+	for i := range identities.Items {
+		identity := &identities.Items[i]
+		if !isOwnedBy(identity, userAcc) || containsString(keep, identity.Name) {
+			continue
+		}
+		if err := r.client.Delete(context.TODO(), identity); err != nil {
+			return false, errs.Wrapf(err, "failed to delete identity '%s'", identity.Name)
+		}
+		deleted = true
+	}
+	return deleted, nil
+}
+
+func isOwnedBy(identity *userv1.Identity, userAcc *toolchainv1alpha1.UserAccount) bool {
+	return identity.Labels[ownerLabel] == userAcc.Name || metav1.IsControlledBy(identity, userAcc)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
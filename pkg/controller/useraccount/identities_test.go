@@ -0,0 +1,67 @@
+package useraccount
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityNames(t *testing.T) {
+	// given
+	userAcc := newUserAccount("johnsmith", "12345")
+	primary := ToIdentityName("12345")
+
+	t.Run("primary identity only", func(t *testing.T) {
+		// when
+		names, err := identityNames(userAcc)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{primary}, names)
+	})
+
+	t.Run("additional identities", func(t *testing.T) {
+		// given
+		userAcc := userAcc.DeepCopy()
+		userAcc.Annotations = map[string]string{
+			additionalIdentitiesAnnotation: fmt.Sprintf("github:67890, sso:abc:def,,%s,github:67890", primary),
+		}
+
+		// when
+		names, err := identityNames(userAcc)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{primary, "github:67890", "sso:abc:def"}, names)
+	})
+
+	t.Run("invalid identity", func(t *testing.T) {
+		for _, value := range []string{"github", "github:", ":67890"} {
+			t.Run(value, func(t *testing.T) {
+				// given
+				userAcc := userAcc.DeepCopy()
+				userAcc.Annotations = map[string]string{
+					additionalIdentitiesAnnotation: value,
+				}
+
+				// when
+				_, err := identityNames(userAcc)
+
+				// then
+				require.EqualError(t, err, fmt.Sprintf("invalid identity '%s': expected format is '<provider>:<user id>'", value))
+			})
+		}
+	})
+}
+
+func TestSplitIdentityName(t *testing.T) {
+	provider, userID := splitIdentityName("sso:abc:def")
+	assert.Equal(t, "sso", provider)
+	assert.Equal(t, "abc:def", userID)
+
+	provider, userID = splitIdentityName("sso")
+	assert.Empty(t, provider)
+	assert.Empty(t, userID)
+}
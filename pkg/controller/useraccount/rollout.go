@@ -0,0 +1,123 @@
+package useraccount
+
+import (
+	"context"
+	"fmt"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/conditions"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/go-logr/logr"
+	errs "github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// nsTemplateSetUpdatingReason the reason of the `Ready` condition of the NSTemplateSets whose upgrade is in progress
+const nsTemplateSetUpdatingReason = conditions.UpdatingReason
+
+// nsTemplateSetFailureReasons the reasons of the `Ready` condition of the NSTemplateSets whose upgrade failed
+var nsTemplateSetFailureReasons = []string{"UnableToProvision", "UnableToProvisionNamespace", "UnableToProvisionClusterResources"}
+
+// rolloutState the progress of the rollout of a new revision of a tier among the NSTemplateSets of the member cluster
+type rolloutState struct {
+	// upgraded the number of NSTemplateSets which were upgraded to the new revision
+	upgraded int32
+	// ready and failed the number of upgraded NSTemplateSets which are ready (resp. whose upgrade failed). The other ones are in progress
+	ready  int32
+	failed int32
+}
+
+// canUpgrade returns `true` if one more NSTemplateSet can be upgraded with the given rollout configuration, otherwise the reason
+// why the upgrade has to wait: the canaries are upgraded first, then the other NSTemplateSets once all the canaries are ready,
+// with at most `MaxUnavailable` upgrades in progress or failed at once. The rollout is paused when the `FailureThreshold` is reached.
+func (s rolloutState) canUpgrade(cfg memberv1alpha1.TierRolloutConfig) (bool, string) {
+	if s.failed >= cfg.FailureThreshold {
+		return false, fmt.Sprintf("rollout paused: %d upgrade(s) failed", s.failed)
+	}
+	if s.upgraded < cfg.Canary {
+		return true, ""
+	}
+	if s.ready < cfg.Canary {
+		return false, fmt.Sprintf("waiting for the canaries: %d out of %d ready", s.ready, cfg.Canary)
+	}
+	if unavailable := s.upgraded - s.ready; unavailable >= cfg.MaxUnavailable {
+		return false, fmt.Sprintf("waiting for the current batch: %d upgrade(s) in progress or failed", unavailable)
+	}
+	return true, ""
+}
+
+// tierRolloutState returns the progress of the rollout of the given spec among the NSTemplateSets of the same tier in the given namespace
+func tierRolloutState(cl client.Client, namespace string, target toolchainv1alpha1.NSTemplateSetSpec) (rolloutState, error) {
+	nsTmplSets := &toolchainv1alpha1.NSTemplateSetList{}
+	if err := cl.List(context.TODO(), nsTmplSets, client.InNamespace(namespace)); err != nil {
+		return rolloutState{}, errs.Wrap(err, "failed to list the NSTemplateSets")
+	}
+	state := rolloutState{}
+	for _, nsTmplSet := range nsTmplSets.Items {
+		if nsTmplSet.Spec.TierName != target.TierName || !nsTmplSet.Spec.CompareTo(target) {
+			continue
+		}
+		state.upgraded++
+		ready, found := condition.FindConditionByType(nsTmplSet.Status.Conditions, toolchainv1alpha1.ConditionReady)
+		if !found {
+			continue
+		}
+		if ready.Status == corev1.ConditionTrue {
+			state.ready++
+		} else if ready.Status == corev1.ConditionFalse && containsString(nsTemplateSetFailureReasons, ready.Reason) {
+			state.failed++
+		}
+	}
+	return state, nil
+}
+
+// holdNSTemplateSetUpdate returns `true` if the update of the NSTemplateSet of the given user account to a new revision of its tier
+// has to wait for the rollout of this revision to progress. The creation of the NSTemplateSets and the changes of tier are never held.
+// Note: the limits of the rollout are approximate when the reconciliations run concurrently, since they are checked against the cached NSTemplateSets
+func (r *ReconcileUserAccount) holdNSTemplateSetUpdate(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount) (bool, error) {
+	cfg, enabled := config.GetTierRollout()
+	if !enabled {
+		return false, nil
+	}
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: userAcc.Namespace, Name: userAcc.Name}, nsTmplSet); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errs.Wrapf(err, "failed to get NSTemplateSet '%s'", userAcc.Name)
+	}
+	target := userAcc.Spec.NSTemplateSet
+	if nsTmplSet.Spec.TierName != target.TierName || nsTmplSet.Spec.CompareTo(target) {
+		return false, nil
+	}
+	state, err := tierRolloutState(r.client, userAcc.Namespace, target)
+	if err != nil {
+		return false, err
+	}
+	if ok, reason := state.canUpgrade(cfg); !ok {
+		logger.Info("upgrade of the NSTemplateSet held", "tier", target.TierName, "reason", reason)
+		return true, nil
+	}
+	return false, nil
+}
+
+// setNSTemplateSetUpdating sets the `Ready` condition of the given NSTemplateSet which was just upgraded to `Updating`, so that the
+// condition of the previous revision is not counted as the result of the upgrade by the rollout
+func (r *ReconcileUserAccount) setNSTemplateSetUpdating(nsTmplSet *toolchainv1alpha1.NSTemplateSet) error {
+	var updated bool
+	nsTmplSet.Status.Conditions, updated = conditions.Update(nsTmplSet.Status.Conditions, toolchainv1alpha1.Condition{
+		Type:   toolchainv1alpha1.ConditionReady,
+		Status: corev1.ConditionFalse,
+		Reason: nsTemplateSetUpdatingReason,
+	})
+	if !updated {
+		return nil
+	}
+	return r.client.Status().Update(context.TODO(), nsTmplSet)
+}
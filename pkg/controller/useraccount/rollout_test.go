@@ -0,0 +1,160 @@
+package useraccount
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	userv1 "github.com/openshift/api/user/v1"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCanUpgrade(t *testing.T) {
+	// given
+	cfg := memberv1alpha1.TierRolloutConfig{Canary: 2, MaxUnavailable: 3, FailureThreshold: 2}
+
+	for name, tc := range map[string]struct {
+		state    rolloutState
+		expected bool
+		reason   string
+	}{
+		"first canary":         {state: rolloutState{}, expected: true},
+		"second canary":        {state: rolloutState{upgraded: 1}, expected: true},
+		"canaries in progress": {state: rolloutState{upgraded: 2, ready: 1}, expected: false, reason: "waiting for the canaries: 1 out of 2 ready"},
+		"canaries ready":       {state: rolloutState{upgraded: 2, ready: 2}, expected: true},
+		"batch in progress":    {state: rolloutState{upgraded: 6, ready: 4, failed: 1}, expected: true},
+		"batch full":           {state: rolloutState{upgraded: 7, ready: 4, failed: 1}, expected: false, reason: "waiting for the current batch: 3 upgrade(s) in progress or failed"},
+		"rollout paused":       {state: rolloutState{upgraded: 4, ready: 2, failed: 2}, expected: false, reason: "rollout paused: 2 upgrade(s) failed"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// when
+			ok, reason := tc.state.canUpgrade(cfg)
+
+			// then
+			assert.Equal(t, tc.expected, ok)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
+func TestTierRollout(t *testing.T) {
+	// given
+	username := "johnsmith"
+	userID := uuid.NewV4().String()
+	userAcc := newUserAccount(username, userID)
+	userAcc.Spec.NSTemplateSet.Namespaces[0].Revision = "abcde12"
+	user := &userv1.User{ObjectMeta: metav1.ObjectMeta{Name: username, UID: types.UID(username + "user")}, Identities: []string{ToIdentityName(userID)}}
+	identity := &userv1.Identity{ObjectMeta: metav1.ObjectMeta{Name: ToIdentityName(userID)}, User: corev1.ObjectReference{Name: username, UID: user.UID}}
+	nsTmplSet := newRolloutNSTmplSet(username, newNSTmplSetSpec(), corev1.ConditionTrue, "Provisioned")
+	cfg := &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "toolchain-member", Name: memberv1alpha1.MemberOperatorConfigName},
+		Spec: memberv1alpha1.MemberOperatorConfigSpec{
+			TierRollout: &memberv1alpha1.TierRolloutConfig{Canary: 1, MaxUnavailable: 1, FailureThreshold: 1, CheckInterval: "1m"},
+		},
+	}
+	defer func() {
+		require.NoError(t, config.LoadMemberOperatorConfig(test.NewFakeClient(t), "toolchain-member"))
+	}()
+
+	t.Run("canary upgraded", func(t *testing.T) {
+		// given
+		r, req, cl := prepareReconcile(t, username, cfg, userAcc, user, identity, nsTmplSet)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		upgraded := &toolchainv1alpha1.NSTemplateSet{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: "toolchain-member", Name: username}, upgraded))
+		assert.Equal(t, "abcde12", upgraded.Spec.Namespaces[0].Revision)
+		ready, found := condition.FindConditionByType(upgraded.Status.Conditions, toolchainv1alpha1.ConditionReady)
+		require.True(t, found)
+		assert.Equal(t, corev1.ConditionFalse, ready.Status)
+		assert.Equal(t, "Updating", ready.Reason)
+	})
+
+	t.Run("upgrade held while the canary is in progress", func(t *testing.T) {
+		// given
+		canary := newRolloutNSTmplSet("janedoe", userAcc.Spec.NSTemplateSet, corev1.ConditionFalse, "Updating")
+		r, req, cl := prepareReconcile(t, username, cfg, userAcc, user, identity, nsTmplSet, canary)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: time.Minute}, res)
+		assertRevision(t, cl, username, "abcde11")
+	})
+
+	t.Run("upgrade resumed when the canary is ready", func(t *testing.T) {
+		// given
+		canary := newRolloutNSTmplSet("janedoe", userAcc.Spec.NSTemplateSet, corev1.ConditionTrue, "Provisioned")
+		r, req, cl := prepareReconcile(t, username, cfg, userAcc, user, identity, nsTmplSet, canary)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertRevision(t, cl, username, "abcde12")
+	})
+
+	t.Run("rollout paused when the canary failed", func(t *testing.T) {
+		// given
+		canary := newRolloutNSTmplSet("janedoe", userAcc.Spec.NSTemplateSet, corev1.ConditionFalse, "UnableToProvisionNamespace")
+		r, req, cl := prepareReconcile(t, username, cfg, userAcc, user, identity, nsTmplSet, canary)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{RequeueAfter: time.Minute}, res)
+		assertRevision(t, cl, username, "abcde11")
+	})
+
+	t.Run("change of tier not held", func(t *testing.T) {
+		// given
+		canary := newRolloutNSTmplSet("janedoe", userAcc.Spec.NSTemplateSet, corev1.ConditionFalse, "Updating")
+		promoted := userAcc.DeepCopy()
+		promoted.Spec.NSTemplateSet.TierName = "advanced"
+		r, req, cl := prepareReconcile(t, username, cfg, promoted, user, identity, nsTmplSet, canary)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assertRevision(t, cl, username, "abcde12")
+	})
+}
+
+func newRolloutNSTmplSet(name string, spec toolchainv1alpha1.NSTemplateSetSpec, status corev1.ConditionStatus, reason string) *toolchainv1alpha1.NSTemplateSet {
+	return &toolchainv1alpha1.NSTemplateSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "toolchain-member", Name: name},
+		Spec:       *spec.DeepCopy(),
+		Status: toolchainv1alpha1.NSTemplateSetStatus{
+			Conditions: []toolchainv1alpha1.Condition{{Type: toolchainv1alpha1.ConditionReady, Status: status, Reason: reason}},
+		},
+	}
+}
+
+func assertRevision(t *testing.T, cl *test.FakeClient, name, revision string) {
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: "toolchain-member", Name: name}, nsTmplSet))
+	assert.Equal(t, revision, nsTmplSet.Spec.Namespaces[0].Revision)
+}
@@ -3,9 +3,20 @@ package useraccount
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/audittrail"
+	"github.com/codeready-toolchain/member-operator/pkg/conditions"
 	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+	"github.com/codeready-toolchain/member-operator/pkg/metrics"
+	"github.com/codeready-toolchain/member-operator/pkg/pause"
+	memberpredicate "github.com/codeready-toolchain/member-operator/pkg/predicate"
+	"github.com/codeready-toolchain/member-operator/pkg/snapshot"
+	"github.com/codeready-toolchain/member-operator/pkg/tracing"
 	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 	"github.com/go-logr/logr"
 	userv1 "github.com/openshift/api/user/v1"
@@ -36,11 +47,28 @@ const (
 	unableToCreateIdentityReason      = "UnableToCreateIdentity"
 	unableToCreateMappingReason       = "UnableToCreateMapping"
 	unableToCreateNSTemplateSetReason = "UnableToCreateNSTemplateSet"
-	provisioningReason                = "Provisioning"
-	provisionedReason                 = "Provisioned"
+	unableToUpdateNSTemplateSetReason = "UnableToUpdateNSTemplateSet"
+	unableToDisableReason             = "UnableToDisable"
+	unableToEnableReason              = "UnableToEnable"
+	unableToUpdateGroupsReason        = "UnableToUpdateGroups"
+	disabledReason                    = "Disabled"
+	provisioningReason                = conditions.ProvisioningReason
+	provisionedReason                 = conditions.ProvisionedReason
+	waitingForIdentityReason          = "WaitingForIdentity"
+	terminatingReason                 = conditions.TerminatingReason
+	unableToTerminateReason           = conditions.UnableToTerminateReason
+
+	// Status condition types of the provisioning phases, in addition to the `Ready` condition
+	userCreatedCondition        toolchainv1alpha1.ConditionType = "UserCreated"
+	identityCreatedCondition    toolchainv1alpha1.ConditionType = "IdentityCreated"
+	mappingCreatedCondition     toolchainv1alpha1.ConditionType = "MappingCreated"
+	nsTemplateSetReadyCondition toolchainv1alpha1.ConditionType = "NSTemplateSetReady"
 
 	// Finalizers
 	userAccFinalizerName = "finalizer.toolchain.dev.openshift.com"
+
+	// identityLookupInterval the delay before checking again if the identity was created by the identity provider
+	identityLookupInterval = 30 * time.Second
 )
 
 var log = logf.Log.WithName("controller_useraccount")
@@ -48,15 +76,20 @@ var log = logf.Log.WithName("controller_useraccount")
 // Add creates a new UserAccount Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	return add(mgr, NewReconciler(mgr))
 }
 
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileUserAccount{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+// NewReconciler returns a new UserAccount reconciler
+func NewReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileUserAccount{
+		client: config.ControllerClient("useraccount", mgr.GetClient()),
+		scheme: mgr.GetScheme(),
+		trail:  audittrail.NewRecorder(mgr.GetClient(), mgr.GetScheme(), config.GetAuditTrailSize()),
+	}
 }
 
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
-	c, err := controller.New("useraccount-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("useraccount-controller", mgr, config.ControllerOptions("useraccount", r))
 	if err != nil {
 		return err
 	}
@@ -66,6 +99,16 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	if err != nil {
 		return err
 	}
+	// pausing or resuming the reconciliation does not increase the generation
+	err = c.Watch(&source.Kind{Type: &toolchainv1alpha1.UserAccount{}}, &handler.EnqueueRequestForObject{}, memberpredicate.AnnotationChanged{Key: pause.Annotation})
+	if err != nil {
+		return err
+	}
+	// neither does requesting a snapshot of the resources of the user
+	err = c.Watch(&source.Kind{Type: &toolchainv1alpha1.UserAccount{}}, &handler.EnqueueRequestForObject{}, memberpredicate.AnnotationChanged{Key: snapshot.Annotation})
+	if err != nil {
+		return err
+	}
 
 	// Watch for changes to secondary resource
 	enqueueRequestForOwner := &handler.EnqueueRequestForOwner{
@@ -82,9 +125,31 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to the MemberOperatorConfig, since the identity provider is part of the name of the identities
+	enqueueAllUserAccounts := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(allUserAccounts(mgr.GetClient()))}
+	if err := c.Watch(&source.Kind{Type: &memberv1alpha1.MemberOperatorConfig{}}, enqueueAllUserAccounts, predicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// allUserAccounts returns a mapper which enqueues all the UserAccounts in the namespace of the mapped object
+func allUserAccounts(cl client.Client) func(handler.MapObject) []reconcile.Request {
+	return func(obj handler.MapObject) []reconcile.Request {
+		userAccs := &toolchainv1alpha1.UserAccountList{}
+		if err := cl.List(context.TODO(), userAccs, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+			log.Error(err, "failed to list the user accounts", "namespace", obj.Meta.GetNamespace())
+			return nil
+		}
+		requests := make([]reconcile.Request, len(userAccs.Items))
+		for i, userAcc := range userAccs.Items {
+			requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userAcc.Namespace, Name: userAcc.Name}}
+		}
+		return requests
+	}
+}
+
 var _ reconcile.Reconciler = &ReconcileUserAccount{}
 
 // ReconcileUserAccount reconciles a UserAccount object
@@ -93,6 +158,28 @@ type ReconcileUserAccount struct {
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
 	scheme *runtime.Scheme
+	trail  *audittrail.Recorder // optional recorder of the mutations in the audit trail of the users
+	span   *tracing.Span        // optional span of the reconciliation in progress, in the copy of the reconciler used by the reconciliation
+}
+
+// withSpan returns a copy of this reconciler which traces its operations under the given span
+func (r *ReconcileUserAccount) withSpan(span *tracing.Span) *ReconcileUserAccount {
+	if span == nil {
+		return r
+	}
+	c := *r
+	c.span = span
+	return &c
+}
+
+// withAuditTrail returns a copy of this reconciler whose client records the mutations in the audit trail of the given user
+func (r *ReconcileUserAccount) withAuditTrail(namespace, username string) *ReconcileUserAccount {
+	if r.trail == nil {
+		return r
+	}
+	c := *r
+	c.client = audittrail.NewClient(r.client, r.trail, namespace, username, "useraccount/"+username)
+	return &c
 }
 
 // Reconcile reads that state of the cluster for a UserAccount object and makes changes based on the state read
@@ -101,7 +188,14 @@ type ReconcileUserAccount struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileUserAccount) Reconcile(request reconcile.Request) (reconcile.Result, error) {
-	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	span := tracing.Start("UserAccount.Reconcile", "namespace", request.Namespace, "name", request.Name)
+	res, err := r.withSpan(span).reconcileUserAccount(request)
+	span.End(err)
+	return res, err
+}
+
+func (r *ReconcileUserAccount) reconcileUserAccount(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := logging.ForRequest(log, request)
 	reqLogger.Info("Reconciling UserAccount")
 	var err error
 
@@ -112,6 +206,7 @@ func (r *ReconcileUserAccount) Reconcile(request reconcile.Request) (reconcile.R
 			return reconcile.Result{}, err
 		}
 	}
+	r = r.withAuditTrail(namespace, request.Name)
 
 	// Fetch the UserAccount instance
 	userAcc := &toolchainv1alpha1.UserAccount{}
@@ -126,6 +221,21 @@ func (r *ReconcileUserAccount) Reconcile(request reconcile.Request) (reconcile.R
 		return reconcile.Result{}, err
 	}
 
+	// Take the snapshot of the resources of the user, if requested. Snapshots are also taken while the reconciliation is paused,
+	// and a failed snapshot does not prevent the reconciliation: it can be requested again with a new value of the annotation
+	if _, err := snapshot.Take(r.client, r.scheme, userAcc); err != nil {
+		reqLogger.Error(err, "unable to take the snapshot of the user", "request", userAcc.Annotations[snapshot.Annotation])
+	}
+
+	// Skip the reconciliation (including the deletion) while it is paused, eg, to debug the resources of the user
+	if pause.IsPaused(userAcc) {
+		reqLogger.Info("reconciliation is paused")
+		return reconcile.Result{}, r.updateStatusConditions(userAcc, pause.PausedCondition())
+	}
+	if err := r.updateStatusConditions(userAcc, pause.ResumedConditions(userAcc.Status.Conditions)...); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	// If the UserAccount has not been deleted, create or update user and identity resources.
 	// If the UserAccount has been deleted, delete secondary resources identity and user.
 	if !util.IsBeingDeleted(userAcc) {
@@ -134,28 +244,65 @@ func (r *ReconcileUserAccount) Reconcile(request reconcile.Request) (reconcile.R
 			return reconcile.Result{}, err
 		}
 
+		// If the UserAccount is disabled, remove the user and identity but keep the namespaces
+		if userAcc.Spec.Disabled {
+			return reconcile.Result{}, r.disable(reqLogger, userAcc)
+		}
+		if err := r.scaleUpWorkloads(userAcc); err != nil {
+			return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, userAcc, r.setStatusEnablingFailed, err, "failed to scale up the workloads of user '%s'", userAcc.Name)
+		}
+
+		identities, err := identityNames(userAcc)
+		if err != nil {
+			return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, userAcc, r.setStatusIdentityCreationFailed, err, "invalid identities for user account '%s'", userAcc.Name)
+		}
+
 		var createdOrUpdated bool
 		var user *userv1.User
-		if user, createdOrUpdated, err = r.ensureUser(reqLogger, userAcc); err != nil || createdOrUpdated {
+		if user, createdOrUpdated, err = r.ensureUser(reqLogger, userAcc, identities); err != nil || createdOrUpdated {
+			return reconcile.Result{}, err
+		}
+		if err := r.setStatusPhasesCompleted(userAcc, userCreatedCondition); err != nil {
 			return reconcile.Result{}, err
 		}
 
-		if _, createdOrUpdated, err = r.ensureIdentity(reqLogger, userAcc, user); err != nil || createdOrUpdated {
+		var identity *userv1.Identity
+		if identity, createdOrUpdated, err = r.ensureIdentities(reqLogger, userAcc, user, identities); err != nil || createdOrUpdated {
 			return reconcile.Result{}, err
 		}
+		if identity != nil {
+			if err := r.setStatusPhasesCompleted(userAcc, identityCreatedCondition, mappingCreatedCondition); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
 
+		// the groups are not watched, so the reconcile carries on once they are updated
+		if err := r.ensureGroups(reqLogger, userAcc, groupNames(userAcc)); err != nil {
+			return reconcile.Result{}, r.wrapErrorWithStatusUpdate(reqLogger, userAcc, r.setStatusGroupsUpdateFailed, err, "failed to update the groups of user '%s'", userAcc.Name)
+		}
+
+		// the upgrades to a new revision of the tier are rolled out progressively, if configured so
+		if held, err := r.holdNSTemplateSetUpdate(reqLogger, userAcc); err != nil || held {
+			return reconcile.Result{RequeueAfter: config.GetTierRolloutCheckInterval()}, err
+		}
 		if _, createdOrUpdated, err = r.ensureNSTemplateSet(reqLogger, userAcc); err != nil || createdOrUpdated {
 			return reconcile.Result{}, err
 		}
-	} else if util.HasFinalizer(userAcc, userAccFinalizerName) {
-		if err = r.manageCleanUp(userAcc); err != nil {
+		if err := r.setStatusPhasesCompleted(userAcc, nsTemplateSetReadyCondition); err != nil {
 			return reconcile.Result{}, err
 		}
+
+		if identity == nil {
+			// the identity has not been created by the identity provider yet, check again later
+			return reconcile.Result{RequeueAfter: identityLookupInterval}, r.setStatusWaitingForIdentity(userAcc)
+		}
+	} else if util.HasFinalizer(userAcc, userAccFinalizerName) {
+		return reconcile.Result{}, r.manageCleanUp(reqLogger, userAcc)
 	}
 	return reconcile.Result{}, r.setStatusReady(userAcc)
 }
 
-func (r *ReconcileUserAccount) ensureUser(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount) (*userv1.User, bool, error) {
+func (r *ReconcileUserAccount) ensureUser(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount, identities []string) (*userv1.User, bool, error) {
 	user := &userv1.User{}
 	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: userAcc.Name}, user); err != nil {
 		if errors.IsNotFound(err) {
@@ -163,7 +310,7 @@ func (r *ReconcileUserAccount) ensureUser(logger logr.Logger, userAcc *toolchain
 			if err := r.setStatusProvisioning(userAcc); err != nil {
 				return nil, false, err
 			}
-			user = newUser(userAcc)
+			user = newUser(userAcc, identities)
 			if err := controllerutil.SetControllerReference(userAcc, user, r.scheme); err != nil {
 				return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusUserCreationFailed, err, "failed to set controller reference for user '%s'", userAcc.Name)
 			}
@@ -180,13 +327,14 @@ func (r *ReconcileUserAccount) ensureUser(logger logr.Logger, userAcc *toolchain
 	}
 	logger.Info("user already exists", "name", userAcc.Name)
 
-	// ensure mapping
-	if user.Identities == nil || len(user.Identities) < 1 || user.Identities[0] != ToIdentityName(userAcc.Spec.UserID) {
+	// ensure mapping (only when the User directly references the Identity, otherwise the mapping is handled by a UserIdentityMapping)
+	if config.GetIdentityMappingStrategy() == config.IdentityMappingStrategyDirect &&
+		!reflect.DeepEqual(user.Identities, identities) {
 		logger.Info("user is missing a reference to identity; updating the reference", "name", userAcc.Name)
 		if err := r.setStatusProvisioning(userAcc); err != nil {
 			return nil, false, err
 		}
-		user.Identities = []string{ToIdentityName(userAcc.Spec.UserID)}
+		user.Identities = identities
 		if err := r.client.Update(context.TODO(), user); err != nil {
 			return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusMappingCreationFailed, err, "failed to update user '%s'", userAcc.Name)
 		}
@@ -199,8 +347,39 @@ func (r *ReconcileUserAccount) ensureUser(logger logr.Logger, userAcc *toolchain
 	return user, false, nil
 }
 
-func (r *ReconcileUserAccount) ensureIdentity(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount, user *userv1.User) (*userv1.Identity, bool, error) {
-	name := ToIdentityName(userAcc.Spec.UserID)
+// ensureIdentities ensures that all the given identities exist and are mapped to the user, and deletes the identities
+// of the user which are not listed anymore. Returns the primary identity (i.e, the first one)
+func (r *ReconcileUserAccount) ensureIdentities(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount, user *userv1.User, identities []string) (*userv1.Identity, bool, error) {
+	var primary *userv1.Identity
+	for i, name := range identities {
+		identity, createdOrUpdated, err := r.ensureIdentity(logger, userAcc, user, name)
+		if err != nil || createdOrUpdated {
+			return identity, createdOrUpdated, err
+		}
+		if i == 0 {
+			primary = identity
+		}
+	}
+	deleted, err := r.deleteOwnedIdentities(userAcc, identities)
+	if err != nil {
+		return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusIdentityCreationFailed, err, "failed to delete the identities no longer listed for user '%s'", userAcc.Name)
+	}
+	return primary, deleted, nil
+}
+
+func (r *ReconcileUserAccount) ensureIdentity(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount, user *userv1.User, name string) (*userv1.Identity, bool, error) {
+	switch config.GetIdentityMappingStrategy() {
+	case config.IdentityMappingStrategyMapping:
+		return r.ensureIdentityWithMapping(logger, userAcc, user, name, true)
+	case config.IdentityMappingStrategyLookup:
+		return r.ensureIdentityWithMapping(logger, userAcc, user, name, false)
+	default:
+		return r.ensureIdentityWithReference(logger, userAcc, user, name)
+	}
+}
+
+// ensureIdentityWithReference ensures that the identity exists and that it directly references the user
+func (r *ReconcileUserAccount) ensureIdentityWithReference(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount, user *userv1.User, name string) (*userv1.Identity, bool, error) {
 	identity := &userv1.Identity{}
 	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: name}, identity); err != nil {
 		if errors.IsNotFound(err) {
@@ -208,7 +387,7 @@ func (r *ReconcileUserAccount) ensureIdentity(logger logr.Logger, userAcc *toolc
 			if err := r.setStatusProvisioning(userAcc); err != nil {
 				return nil, false, err
 			}
-			identity = newIdentity(userAcc, user)
+			identity = newIdentity(userAcc, name, user)
 			if err := controllerutil.SetControllerReference(userAcc, identity, r.scheme); err != nil {
 				return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusIdentityCreationFailed, err, "failed to set controller reference for identity '%s'", name)
 			}
@@ -248,6 +427,61 @@ func (r *ReconcileUserAccount) ensureIdentity(logger logr.Logger, userAcc *toolc
 	return identity, false, nil
 }
 
+// ensureIdentityWithMapping ensures that the identity exists (creating it if `create` is true, otherwise waiting for it to be created
+// by the identity provider) and that it is linked to the user with a UserIdentityMapping
+func (r *ReconcileUserAccount) ensureIdentityWithMapping(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount, user *userv1.User, name string, create bool) (*userv1.Identity, bool, error) {
+	identity := &userv1.Identity{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: name}, identity); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusIdentityCreationFailed, err, "failed to get identity '%s'", name)
+		}
+		if !create {
+			logger.Info("waiting for the identity to be created by the identity provider", "name", name)
+			return nil, false, nil
+		}
+		logger.Info("creating a new identity", "name", name)
+		if err := r.setStatusProvisioning(userAcc); err != nil {
+			return nil, false, err
+		}
+		identity = newIdentity(userAcc, name, nil)
+		if err := controllerutil.SetControllerReference(userAcc, identity, r.scheme); err != nil {
+			return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusIdentityCreationFailed, err, "failed to set controller reference for identity '%s'", name)
+		}
+		if err := r.client.Create(context.TODO(), identity); err != nil {
+			return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusIdentityCreationFailed, err, "failed to create identity '%s'", name)
+		}
+		logger.Info("identity created successfully", "name", name)
+		return identity, true, nil
+	}
+	logger.Info("identity already exists", "name", name)
+
+	// ensure mapping
+	if identity.User.Name == user.Name && identity.User.UID == user.UID {
+		return identity, false, nil
+	}
+	logger.Info("identity is not mapped to the user; creating the mapping", "identity", name, "user", user.Name)
+	if err := r.setStatusProvisioning(userAcc); err != nil {
+		return nil, false, err
+	}
+	mapping := newUserIdentityMapping(identity, user)
+	if err := r.client.Create(context.TODO(), mapping); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusMappingCreationFailed, err, "failed to create the mapping between identity '%s' and user '%s'", name, user.Name)
+		}
+		// the identity is mapped to another user
+		existing := &userv1.UserIdentityMapping{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: mapping.Name}, existing); err != nil {
+			return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusMappingCreationFailed, err, "failed to get the mapping of identity '%s'", name)
+		}
+		existing.User = mapping.User
+		if err := r.client.Update(context.TODO(), existing); err != nil {
+			return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusMappingCreationFailed, err, "failed to update the mapping between identity '%s' and user '%s'", name, user.Name)
+		}
+	}
+	logger.Info("identity mapped successfully", "identity", name, "user", user.Name)
+	return identity, true, nil
+}
+
 func (r *ReconcileUserAccount) ensureNSTemplateSet(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount) (*toolchainv1alpha1.NSTemplateSet, bool, error) {
 	name := userAcc.Name
 
@@ -275,10 +509,25 @@ func (r *ReconcileUserAccount) ensureNSTemplateSet(logger logr.Logger, userAcc *
 	}
 	logger.Info("NSTemplateSet already exists", "name", name)
 
-	// update if not same
+	// update if not same (eg, the tier templates changed revision)
 	equal := nsTmplSet.Spec.CompareTo(userAcc.Spec.NSTemplateSet)
 	if !equal {
-		return nil, false, fmt.Errorf("update of NSTemplateSet is not supported")
+		logger.Info("updating the NSTemplateSet", "name", name)
+		if err := r.setStatusProvisioning(userAcc); err != nil {
+			return nil, false, err
+		}
+		nsTmplSet.Spec = userAcc.Spec.NSTemplateSet
+		if err := r.client.Update(context.TODO(), nsTmplSet); err != nil {
+			return nil, false, r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusNSTemplateSetUpdateFailed, err,
+				"failed to update NSTemplateSet '%s'", name)
+		}
+		if _, rollout := config.GetTierRollout(); rollout {
+			if err := r.setNSTemplateSetUpdating(nsTmplSet); err != nil {
+				return nil, false, errs.Wrapf(err, "failed to update the status of NSTemplateSet '%s'", name)
+			}
+		}
+		logger.Info("NSTemplateSet updated successfully", "name", name)
+		return nsTmplSet, true, nil
 	}
 
 	// update status if ready=false
@@ -309,23 +558,65 @@ func (r *ReconcileUserAccount) addFinalizer(userAcc *toolchainv1alpha1.UserAccou
 	return nil
 }
 
-// manageCleanUp deletes the identity, user and finalizer when the UserAccount is being deleted
-func (r *ReconcileUserAccount) manageCleanUp(userAcc *toolchainv1alpha1.UserAccount) error {
+// manageCleanUp deletes the resources of the user when the UserAccount is being deleted, one step at a time:
+// the identity mappings first (while the user still exists), then the identities, the memberships of the groups, the user and finally the NSTemplateSet,
+// whose deletion is awaited before the finalizer is removed. The progress is reported in the `Ready` condition with the `Terminating` reason.
+func (r *ReconcileUserAccount) manageCleanUp(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount) error {
+	if err := r.deleteIdentityMappings(userAcc); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusTerminationFailed, err, "failed to delete the identity mappings of user '%s'", userAcc.Name)
+	}
 	if deleted, err := r.deleteIdentity(userAcc); err != nil || deleted {
-		return err
+		if err != nil {
+			return r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusTerminationFailed, err, "failed to delete the identity of user '%s'", userAcc.Name)
+		}
+		return r.setStatusTerminating(userAcc, "deleting the identities")
+	}
+	if err := r.removeFromGroups(logger, userAcc, nil); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusTerminationFailed, err, "failed to remove user '%s' from the groups", userAcc.Name)
 	}
 	if deleted, err := r.deleteUser(userAcc); err != nil || deleted {
-		return err
+		if err != nil {
+			return r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusTerminationFailed, err, "failed to delete user '%s'", userAcc.Name)
+		}
+		return r.setStatusTerminating(userAcc, "deleting the user")
+	}
+	if deleting, err := r.deleteNSTemplateSet(userAcc); err != nil || deleting {
+		if err != nil {
+			return r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusTerminationFailed, err, "failed to delete the NSTemplateSet of user '%s'", userAcc.Name)
+		}
+		// wait until the NSTemplateSet is gone (its deletion triggers a new reconcile of its owner)
+		return r.setStatusTerminating(userAcc, "deleting the NSTemplateSet")
 	}
 	// Remove finalizer from UserAccount
 	util.RemoveFinalizer(userAcc, userAccFinalizerName)
 	if err := r.client.Update(context.Background(), userAcc); err != nil {
 		return err
 	}
-
+	logger.Info("user account cleaned up")
 	return nil
 }
 
+// disable deletes the identity and the user so that the user can no longer log in, removes the user from its groups, and scales down the workloads in the
+// user namespaces if configured so. The namespaces are kept, so that the user can be re-enabled later on.
+func (r *ReconcileUserAccount) disable(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount) error {
+	if deleted, err := r.deleteIdentity(userAcc); err != nil || deleted {
+		return r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusDisablingFailed, err, "failed to delete the identity of user '%s'", userAcc.Name)
+	}
+	if err := r.removeFromGroups(logger, userAcc, nil); err != nil {
+		return r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusDisablingFailed, err, "failed to remove user '%s' from the groups", userAcc.Name)
+	}
+	if deleted, err := r.deleteUser(userAcc); err != nil || deleted {
+		return r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusDisablingFailed, err, "failed to delete user '%s'", userAcc.Name)
+	}
+	if config.ScaleDownDisabledUsers() {
+		if err := r.scaleDownWorkloads(userAcc); err != nil {
+			return r.wrapErrorWithStatusUpdate(logger, userAcc, r.setStatusDisablingFailed, err, "failed to scale down the workloads of user '%s'", userAcc.Name)
+		}
+	}
+	logger.Info("user account disabled")
+	return r.setStatusDisabled(userAcc)
+}
+
 // deleteUser deletes the user resource. Returns `true` if the user was deleted, `false` otherwise,
 // with the underlying error if the user existed and something wrong happened. If the user did not
 // exist, this func returns `false, nil`
@@ -347,9 +638,9 @@ func (r *ReconcileUserAccount) deleteUser(userAcc *toolchainv1alpha1.UserAccount
 	return true, nil
 }
 
-// deleteIdentity deletes the identity resource. Returns `true` if the identity was deleted, `false` otherwise,
-// with the underlying error if the identity existed and something wrong happened. If the identity did not
-// exist, this func returns `false, nil`
+// deleteIdentity deletes the identity resources, starting with the primary one. Returns `true` if an identity was deleted, `false` otherwise,
+// with the underlying error if an identity existed and something wrong happened. If no identity
+// existed, this func returns `false, nil`
 func (r *ReconcileUserAccount) deleteIdentity(userAcc *toolchainv1alpha1.UserAccount) (bool, error) {
 	// Get the Identity associated with the UserAccount
 	identity := &userv1.Identity{}
@@ -359,7 +650,8 @@ func (r *ReconcileUserAccount) deleteIdentity(userAcc *toolchainv1alpha1.UserAcc
 		if !errors.IsNotFound(err) {
 			return false, err
 		}
-		return false, nil
+		// the primary identity is already gone, delete the additional ones
+		return r.deleteOwnedIdentities(userAcc, nil)
 	}
 
 	// Delete Identity associated with UserAccount
@@ -370,6 +662,56 @@ func (r *ReconcileUserAccount) deleteIdentity(userAcc *toolchainv1alpha1.UserAcc
 	return true, nil
 }
 
+// deleteIdentityMappings deletes the mappings between the identities of the user account and the user when the identities
+// are linked with a UserIdentityMapping, so that the identities which are not deleted along with the user account
+// (eg, the additional ones created by the identity provider) do not reference the deleted user
+func (r *ReconcileUserAccount) deleteIdentityMappings(userAcc *toolchainv1alpha1.UserAccount) error {
+	if config.GetIdentityMappingStrategy() == config.IdentityMappingStrategyDirect {
+		return nil
+	}
+	names, err := identityNames(userAcc)
+	if err != nil {
+		// the additional identities cannot be determined, but the primary one can
+		names = []string{ToIdentityName(userAcc.Spec.UserID)}
+	}
+	for _, name := range names {
+		identity := &userv1.Identity{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: name}, identity); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return errs.Wrapf(err, "failed to get identity '%s'", name)
+		}
+		if identity.User.Name != userAcc.Name {
+			continue
+		}
+		mapping := &userv1.UserIdentityMapping{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if err := r.client.Delete(context.TODO(), mapping); err != nil && !errors.IsNotFound(err) {
+			return errs.Wrapf(err, "failed to delete the mapping of identity '%s'", name)
+		}
+	}
+	return nil
+}
+
+// deleteNSTemplateSet deletes the NSTemplateSet of the user account. Returns `true` if the NSTemplateSet still exists (ie, its deletion
+// is in progress), `false` if it is gone, with the underlying error if something wrong happened
+func (r *ReconcileUserAccount) deleteNSTemplateSet(userAcc *toolchainv1alpha1.UserAccount) (bool, error) {
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: userAcc.Namespace, Name: userAcc.Name}, nsTmplSet); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if util.IsBeingDeleted(nsTmplSet) {
+		return true, nil
+	}
+	if err := r.client.Delete(context.TODO(), nsTmplSet); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
 // wrapErrorWithStatusUpdate wraps the error and update the user account status. If the update failed then logs the error.
 func (r *ReconcileUserAccount) wrapErrorWithStatusUpdate(logger logr.Logger, userAcc *toolchainv1alpha1.UserAccount, statusUpdater func(userAcc *toolchainv1alpha1.UserAccount, message string) error, err error, format string, args ...interface{}) error {
 	if err == nil {
@@ -389,6 +731,12 @@ func (r *ReconcileUserAccount) setStatusUserCreationFailed(userAcc *toolchainv1a
 			Status:  corev1.ConditionFalse,
 			Reason:  unableToCreateUserReason,
 			Message: message,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    userCreatedCondition,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToCreateUserReason,
+			Message: message,
 		})
 }
 
@@ -400,6 +748,12 @@ func (r *ReconcileUserAccount) setStatusIdentityCreationFailed(userAcc *toolchai
 			Status:  corev1.ConditionFalse,
 			Reason:  unableToCreateIdentityReason,
 			Message: message,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    identityCreatedCondition,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToCreateIdentityReason,
+			Message: message,
 		})
 }
 
@@ -411,6 +765,12 @@ func (r *ReconcileUserAccount) setStatusMappingCreationFailed(userAcc *toolchain
 			Status:  corev1.ConditionFalse,
 			Reason:  unableToCreateMappingReason,
 			Message: message,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    mappingCreatedCondition,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToCreateMappingReason,
+			Message: message,
 		})
 }
 
@@ -422,6 +782,29 @@ func (r *ReconcileUserAccount) setStatusNSTemplateSetCreationFailed(userAcc *too
 			Status:  corev1.ConditionFalse,
 			Reason:  unableToCreateNSTemplateSetReason,
 			Message: message,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    nsTemplateSetReadyCondition,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToCreateNSTemplateSetReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserAccount) setStatusNSTemplateSetUpdateFailed(userAcc *toolchainv1alpha1.UserAccount, message string) error {
+	return r.updateStatusConditions(
+		userAcc,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToUpdateNSTemplateSetReason,
+			Message: message,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    nsTemplateSetReadyCondition,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToUpdateNSTemplateSetReason,
+			Message: message,
 		})
 }
 
@@ -433,9 +816,89 @@ func (r *ReconcileUserAccount) setStatusFromNSTemplateSet(userAcc *toolchainv1al
 			Status:  corev1.ConditionFalse,
 			Reason:  reason,
 			Message: message,
+		},
+		toolchainv1alpha1.Condition{
+			Type:    nsTemplateSetReadyCondition,
+			Status:  corev1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserAccount) setStatusEnablingFailed(userAcc *toolchainv1alpha1.UserAccount, message string) error {
+	return r.updateStatusConditions(
+		userAcc,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToEnableReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserAccount) setStatusGroupsUpdateFailed(userAcc *toolchainv1alpha1.UserAccount, message string) error {
+	return r.updateStatusConditions(
+		userAcc,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToUpdateGroupsReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserAccount) setStatusDisablingFailed(userAcc *toolchainv1alpha1.UserAccount, message string) error {
+	return r.updateStatusConditions(
+		userAcc,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToDisableReason,
+			Message: message,
 		})
 }
 
+func (r *ReconcileUserAccount) setStatusTerminating(userAcc *toolchainv1alpha1.UserAccount, message string) error {
+	return r.updateStatusConditions(
+		userAcc,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  terminatingReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserAccount) setStatusTerminationFailed(userAcc *toolchainv1alpha1.UserAccount, message string) error {
+	return r.updateStatusConditions(
+		userAcc,
+		toolchainv1alpha1.Condition{
+			Type:    toolchainv1alpha1.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  unableToTerminateReason,
+			Message: message,
+		})
+}
+
+func (r *ReconcileUserAccount) setStatusDisabled(userAcc *toolchainv1alpha1.UserAccount) error {
+	conditions := []toolchainv1alpha1.Condition{
+		{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: disabledReason,
+		},
+	}
+	// the user and identities were deleted
+	for _, phase := range []toolchainv1alpha1.ConditionType{userCreatedCondition, identityCreatedCondition, mappingCreatedCondition} {
+		conditions = append(conditions, toolchainv1alpha1.Condition{
+			Type:   phase,
+			Status: corev1.ConditionFalse,
+			Reason: disabledReason,
+		})
+	}
+	return r.updateStatusConditions(userAcc, conditions...)
+}
+
 func (r *ReconcileUserAccount) setStatusProvisioning(userAcc *toolchainv1alpha1.UserAccount) error {
 	return r.updateStatusConditions(
 		userAcc,
@@ -446,7 +909,36 @@ func (r *ReconcileUserAccount) setStatusProvisioning(userAcc *toolchainv1alpha1.
 		})
 }
 
+func (r *ReconcileUserAccount) setStatusWaitingForIdentity(userAcc *toolchainv1alpha1.UserAccount) error {
+	return r.updateStatusConditions(
+		userAcc,
+		toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: provisioningReason,
+		},
+		toolchainv1alpha1.Condition{
+			Type:   identityCreatedCondition,
+			Status: corev1.ConditionFalse,
+			Reason: waitingForIdentityReason,
+		})
+}
+
+// setStatusPhasesCompleted sets the conditions of the given provisioning phases to `true`
+func (r *ReconcileUserAccount) setStatusPhasesCompleted(userAcc *toolchainv1alpha1.UserAccount, phases ...toolchainv1alpha1.ConditionType) error {
+	conditions := make([]toolchainv1alpha1.Condition, len(phases))
+	for i, phase := range phases {
+		conditions[i] = toolchainv1alpha1.Condition{
+			Type:   phase,
+			Status: corev1.ConditionTrue,
+			Reason: provisionedReason,
+		}
+	}
+	return r.updateStatusConditions(userAcc, conditions...)
+}
+
 func (r *ReconcileUserAccount) setStatusReady(userAcc *toolchainv1alpha1.UserAccount) error {
+	metrics.RecordProvisioned(metrics.UserAccountKind, userAcc.CreationTimestamp, userAcc.Status.Conditions)
 	return r.updateStatusConditions(
 		userAcc,
 		toolchainv1alpha1.Condition{
@@ -459,40 +951,62 @@ func (r *ReconcileUserAccount) setStatusReady(userAcc *toolchainv1alpha1.UserAcc
 // updateStatusConditions updates user account status conditions with the new conditions
 func (r *ReconcileUserAccount) updateStatusConditions(userAcc *toolchainv1alpha1.UserAccount, newConditions ...toolchainv1alpha1.Condition) error {
 	var updated bool
-	userAcc.Status.Conditions, updated = condition.AddOrUpdateStatusConditions(userAcc.Status.Conditions, newConditions...)
+	userAcc.Status.Conditions, updated = conditions.Update(userAcc.Status.Conditions, newConditions...)
 	if !updated {
 		// Nothing changed
 		return nil
 	}
-	return r.client.Status().Update(context.TODO(), userAcc)
+	span := r.span.Child("update status")
+	err := r.client.Status().Update(context.TODO(), userAcc)
+	span.End(err)
+	return err
 }
 
-func newUser(userAcc *toolchainv1alpha1.UserAccount) *userv1.User {
+func newUser(userAcc *toolchainv1alpha1.UserAccount, identities []string) *userv1.User {
 	user := &userv1.User{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: userAcc.Name,
 		},
-		Identities: []string{ToIdentityName(userAcc.Spec.UserID)},
+	}
+	if config.GetIdentityMappingStrategy() == config.IdentityMappingStrategyDirect {
+		user.Identities = identities
 	}
 	return user
 }
 
-func newIdentity(userAcc *toolchainv1alpha1.UserAccount, user *userv1.User) *userv1.Identity {
-	name := ToIdentityName(userAcc.Spec.UserID)
+func newIdentity(userAcc *toolchainv1alpha1.UserAccount, name string, user *userv1.User) *userv1.Identity {
+	providerName, providerUserName := splitIdentityName(name)
 	identity := &userv1.Identity{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: name,
+			Name:   name,
+			Labels: map[string]string{ownerLabel: userAcc.Name},
 		},
-		ProviderName:     config.GetIdP(),
-		ProviderUserName: userAcc.Spec.UserID,
-		User: corev1.ObjectReference{
+		ProviderName:     providerName,
+		ProviderUserName: providerUserName,
+	}
+	if user != nil {
+		identity.User = corev1.ObjectReference{
 			Name: user.Name,
 			UID:  user.UID,
-		},
+		}
 	}
 	return identity
 }
 
+func newUserIdentityMapping(identity *userv1.Identity, user *userv1.User) *userv1.UserIdentityMapping {
+	return &userv1.UserIdentityMapping{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: identity.Name,
+		},
+		Identity: corev1.ObjectReference{
+			Name: identity.Name,
+		},
+		User: corev1.ObjectReference{
+			Name: user.Name,
+		},
+	}
+}
+
 func newNSTemplateSet(userAcc *toolchainv1alpha1.UserAccount) *toolchainv1alpha1.NSTemplateSet {
 	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"testing"
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
 	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
 	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/pause"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 	userv1 "github.com/openshift/api/user/v1"
 	"github.com/redhat-cop/operator-utils/pkg/util"
@@ -17,6 +20,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierros "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -26,6 +30,7 @@ import (
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
@@ -178,7 +183,8 @@ func TestReconcile(t *testing.T) {
 					Status:  corev1.ConditionFalse,
 					Reason:  "UnableToCreateUser",
 					Message: "unable to create user",
-				})
+				},
+				phaseFailed(userCreatedCondition, "UnableToCreateUser", "unable to create user"))
 		})
 		t.Run("update", func(t *testing.T) {
 			// given
@@ -211,7 +217,8 @@ func TestReconcile(t *testing.T) {
 					Status:  corev1.ConditionFalse,
 					Reason:  "UnableToCreateMapping",
 					Message: "unable to update user",
-				})
+				},
+				phaseFailed(mappingCreatedCondition, "UnableToCreateMapping", "unable to update user"))
 		})
 	})
 
@@ -234,7 +241,8 @@ func TestReconcile(t *testing.T) {
 					Type:   toolchainv1alpha1.ConditionReady,
 					Status: corev1.ConditionFalse,
 					Reason: "Provisioning",
-				})
+				},
+				phaseCompleted(userCreatedCondition))
 
 			// Check the created/updated identity
 			identity := &userv1.Identity{}
@@ -291,7 +299,9 @@ func TestReconcile(t *testing.T) {
 					Status:  corev1.ConditionFalse,
 					Reason:  "UnableToCreateIdentity",
 					Message: "unable to create identity",
-				})
+				},
+				phaseCompleted(userCreatedCondition),
+				phaseFailed(identityCreatedCondition, "UnableToCreateIdentity", "unable to create identity"))
 		})
 		t.Run("update", func(t *testing.T) {
 			// given
@@ -324,7 +334,9 @@ func TestReconcile(t *testing.T) {
 					Status:  corev1.ConditionFalse,
 					Reason:  "UnableToCreateMapping",
 					Message: "unable to update identity",
-				})
+				},
+				phaseCompleted(userCreatedCondition),
+				phaseFailed(mappingCreatedCondition, "UnableToCreateMapping", "unable to update identity"))
 		})
 	})
 
@@ -336,7 +348,8 @@ func TestReconcile(t *testing.T) {
 			_, err := r.Reconcile(req)
 
 			require.NoError(t, err)
-			checkStatus(t, r.client, username, corev1.ConditionFalse, "Provisioning", "")
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "Provisioning", "",
+				phaseCompleted(userCreatedCondition), phaseCompleted(identityCreatedCondition), phaseCompleted(mappingCreatedCondition))
 			checkNSTmplSet(t, r.client, username)
 		})
 
@@ -350,7 +363,8 @@ func TestReconcile(t *testing.T) {
 			_, err := r.Reconcile(req)
 
 			require.NoError(t, err)
-			checkStatus(t, r.client, username, corev1.ConditionFalse, "", "")
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "", "",
+				phaseCompleted(userCreatedCondition), phaseCompleted(identityCreatedCondition), phaseCompleted(mappingCreatedCondition))
 			checkNSTmplSet(t, r.client, username)
 		})
 
@@ -364,7 +378,9 @@ func TestReconcile(t *testing.T) {
 			_, err := r.Reconcile(req)
 
 			require.NoError(t, err)
-			checkStatus(t, r.client, username, corev1.ConditionFalse, "UnableToProvisionNamespace", "error message")
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "UnableToProvisionNamespace", "error message",
+				phaseCompleted(userCreatedCondition), phaseCompleted(identityCreatedCondition), phaseCompleted(mappingCreatedCondition),
+				phaseFailed(nsTemplateSetReadyCondition, "UnableToProvisionNamespace", "error message"))
 			checkNSTmplSet(t, r.client, username)
 		})
 
@@ -377,11 +393,53 @@ func TestReconcile(t *testing.T) {
 			_, err := r.Reconcile(req)
 
 			require.NoError(t, err)
-			checkStatus(t, r.client, username, corev1.ConditionTrue, "Provisioned", "")
+			checkStatus(t, r.client, username, corev1.ConditionTrue, "Provisioned", "",
+				phaseCompleted(userCreatedCondition), phaseCompleted(identityCreatedCondition), phaseCompleted(mappingCreatedCondition), phaseCompleted(nsTemplateSetReadyCondition))
 			checkNSTmplSet(t, r.client, username)
 		})
 	})
 
+	t.Run("update nstmplset", func(t *testing.T) {
+		t.Run("revision changed", func(t *testing.T) {
+			// given
+			userAcc := newUserAccount(username, userID)
+			userAcc.Spec.NSTemplateSet.Namespaces[0].Revision = "abcde12"
+			r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser, preexistingIdentity, preexistingNsTmplSet)
+
+			// test
+			_, err := r.Reconcile(req)
+
+			require.NoError(t, err)
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "Provisioning", "",
+				phaseCompleted(userCreatedCondition), phaseCompleted(identityCreatedCondition), phaseCompleted(mappingCreatedCondition))
+			nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: "toolchain-member"}, nsTmplSet)
+			require.NoError(t, err)
+			assert.Equal(t, "abcde12", nsTmplSet.Spec.Namespaces[0].Revision)
+		})
+
+		t.Run("update failed", func(t *testing.T) {
+			// given
+			userAcc := newUserAccount(username, userID)
+			userAcc.Spec.NSTemplateSet.Namespaces[0].Revision = "abcde12"
+			r, req, fakeClient := prepareReconcile(t, username, userAcc, preexistingUser, preexistingIdentity, preexistingNsTmplSet)
+			fakeClient.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+				if _, ok := obj.(*toolchainv1alpha1.NSTemplateSet); ok {
+					return errors.New("unable to update NSTemplateSet")
+				}
+				return fakeClient.Client.Update(ctx, obj, opts...)
+			}
+
+			// test
+			_, err := r.Reconcile(req)
+
+			require.Error(t, err)
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "UnableToUpdateNSTemplateSet", "unable to update NSTemplateSet",
+				phaseCompleted(userCreatedCondition), phaseCompleted(identityCreatedCondition), phaseCompleted(mappingCreatedCondition),
+				phaseFailed(nsTemplateSetReadyCondition, "UnableToUpdateNSTemplateSet", "unable to update NSTemplateSet"))
+		})
+	})
+
 	t.Run("create nstmplset failed", func(t *testing.T) {
 		t.Run("create", func(t *testing.T) {
 			r, req, fakeClient := prepareReconcile(t, username, userAcc, preexistingUser, preexistingIdentity)
@@ -393,7 +451,9 @@ func TestReconcile(t *testing.T) {
 			_, err := r.Reconcile(req)
 
 			require.Error(t, err)
-			checkStatus(t, r.client, username, corev1.ConditionFalse, "UnableToCreateNSTemplateSet", "unable to create NSTemplateSet")
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "UnableToCreateNSTemplateSet", "unable to create NSTemplateSet",
+				phaseCompleted(userCreatedCondition), phaseCompleted(identityCreatedCondition), phaseCompleted(mappingCreatedCondition),
+				phaseFailed(nsTemplateSetReadyCondition, "UnableToCreateNSTemplateSet", "unable to create NSTemplateSet"))
 		})
 
 		t.Run("provision status failed", func(t *testing.T) {
@@ -448,7 +508,11 @@ func TestReconcile(t *testing.T) {
 				Type:   toolchainv1alpha1.ConditionReady,
 				Status: corev1.ConditionTrue,
 				Reason: "Provisioned",
-			})
+			},
+			phaseCompleted(userCreatedCondition),
+			phaseCompleted(identityCreatedCondition),
+			phaseCompleted(mappingCreatedCondition),
+			phaseCompleted(nsTemplateSetReadyCondition))
 	})
 
 	// Delete useraccount and ensure related resources are also removed
@@ -496,6 +560,27 @@ func TestReconcile(t *testing.T) {
 		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userAcc.Name}, user)
 		require.Error(t, err)
 		assert.True(t, apierros.IsNotFound(err))
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "Terminating", "deleting the user",
+			phaseCompleted(userCreatedCondition),
+			phaseCompleted(identityCreatedCondition),
+			phaseCompleted(mappingCreatedCondition),
+			phaseCompleted(nsTemplateSetReadyCondition))
+
+		res, err = r.Reconcile(req)
+		assert.Equal(t, reconcile.Result{}, res)
+		require.NoError(t, err)
+
+		// Check that the associated NSTemplateSet has been deleted
+		// when reconciling the useraccount with a deletion timestamp
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userAcc.Name, Namespace: "toolchain-member"}, nsTmplSet)
+		require.Error(t, err)
+		assert.True(t, apierros.IsNotFound(err))
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "Terminating", "deleting the NSTemplateSet",
+			phaseCompleted(userCreatedCondition),
+			phaseCompleted(identityCreatedCondition),
+			phaseCompleted(mappingCreatedCondition),
+			phaseCompleted(nsTemplateSetReadyCondition))
 
 		res, err = r.Reconcile(req)
 		assert.Equal(t, reconcile.Result{}, res)
@@ -572,6 +657,10 @@ func TestReconcile(t *testing.T) {
 		require.Error(t, err)
 		assert.True(t, apierros.IsNotFound(err))
 
+		res, err = r.Reconcile(req)
+		assert.Equal(t, reconcile.Result{}, res)
+		require.NoError(t, err)
+
 		// Mock finalizer removal failure
 		fakeClient.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
 			return fmt.Errorf("unable to remove finalizer for user account %s", userAcc.Name)
@@ -619,7 +708,12 @@ func TestReconcile(t *testing.T) {
 
 		res, err = r.Reconcile(req)
 		assert.Equal(t, reconcile.Result{}, res)
-		require.EqualError(t, err, fmt.Sprintf("unable to delete identity for user account %s", userAcc.Name))
+		require.EqualError(t, err, fmt.Sprintf("failed to delete the identity of user '%s': unable to delete identity for user account %s", userAcc.Name, userAcc.Name))
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "UnableToTerminate", fmt.Sprintf("unable to delete identity for user account %s", userAcc.Name),
+			phaseCompleted(userCreatedCondition),
+			phaseCompleted(identityCreatedCondition),
+			phaseCompleted(mappingCreatedCondition),
+			phaseCompleted(nsTemplateSetReadyCondition))
 
 		// Check that the associated identity has not been deleted
 		// when reconciling the useraccount with a deletion timestamp
@@ -669,7 +763,12 @@ func TestReconcile(t *testing.T) {
 
 		res, err = r.Reconcile(req)
 		assert.Equal(t, reconcile.Result{}, res)
-		require.EqualError(t, err, fmt.Sprintf("unable to delete user for user account %s", userAcc.Name))
+		require.EqualError(t, err, fmt.Sprintf("failed to delete user '%s': unable to delete user for user account %s", userAcc.Name, userAcc.Name))
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "UnableToTerminate", fmt.Sprintf("unable to delete user for user account %s", userAcc.Name),
+			phaseCompleted(userCreatedCondition),
+			phaseCompleted(identityCreatedCondition),
+			phaseCompleted(mappingCreatedCondition),
+			phaseCompleted(nsTemplateSetReadyCondition))
 
 		// Check that the associated user has not been deleted
 		// when reconciling the useraccount with a deletion timestamp
@@ -677,6 +776,646 @@ func TestReconcile(t *testing.T) {
 		err = r.client.Get(context.TODO(), types.NamespacedName{Name: userAcc.Name}, user)
 		require.NoError(t, err)
 	})
+	// NSTemplateSet still being deleted
+	t.Run("finalizer kept while the nstmplset is being deleted", func(t *testing.T) {
+		// given
+		userAcc := newUserAccountWithFinalizer(username, userID)
+		userAcc.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		terminatingNsTmplSet := newNSTmplSetWithStatus(username, "", "")
+		terminatingNsTmplSet.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		r, req, _ := prepareReconcile(t, username, userAcc, terminatingNsTmplSet)
+
+		// when
+		res, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, reconcile.Result{}, res)
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "Terminating", "deleting the NSTemplateSet")
+		updatedAcc := &toolchainv1alpha1.UserAccount{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: "toolchain-member"}, updatedAcc)
+		require.NoError(t, err)
+		require.True(t, util.HasFinalizer(updatedAcc, userAccFinalizerName))
+	})
+}
+
+func TestIdentityMappingStrategies(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	username := "johnsmith"
+	userID := uuid.NewV4().String()
+	userAcc := newUserAccount(username, userID)
+	identityName := ToIdentityName(userID)
+	userUID := types.UID(username + "user")
+	preexistingUser := &userv1.User{ObjectMeta: metav1.ObjectMeta{
+		Name: username,
+		UID:  userUID,
+	}}
+	preexistingIdentityWithNoMapping := &userv1.Identity{ObjectMeta: metav1.ObjectMeta{
+		Name: identityName,
+		UID:  types.UID(username + "identity"),
+	}}
+	preexistingNsTmplSet := &toolchainv1alpha1.NSTemplateSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      username,
+			Namespace: "toolchain-member",
+		},
+		Spec: newNSTmplSetSpec(),
+		Status: toolchainv1alpha1.NSTemplateSetStatus{
+			Conditions: []toolchainv1alpha1.Condition{
+				{Type: toolchainv1alpha1.ConditionReady, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+
+	t.Run("default strategy", func(t *testing.T) {
+		assert.Equal(t, config.IdentityMappingStrategyDirect, config.GetIdentityMappingStrategy())
+		defer setIdentityMappingStrategy(t, "unknown")()
+		assert.Equal(t, config.IdentityMappingStrategyDirect, config.GetIdentityMappingStrategy())
+	})
+
+	t.Run("mapping strategy", func(t *testing.T) {
+		defer setIdentityMappingStrategy(t, config.IdentityMappingStrategyMapping)()
+
+		t.Run("user created without identities", func(t *testing.T) {
+			// given
+			r, req, _ := prepareReconcile(t, username, userAcc)
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			user := &userv1.User{}
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: username}, user)
+			require.NoError(t, err)
+			assert.Empty(t, user.Identities)
+		})
+
+		t.Run("identity created without user reference", func(t *testing.T) {
+			// given
+			r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser)
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			identity := &userv1.Identity{}
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: identityName}, identity)
+			require.NoError(t, err)
+			assert.Empty(t, identity.User.Name)
+			require.Len(t, identity.GetOwnerReferences(), 1)
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "Provisioning", "", phaseCompleted(userCreatedCondition))
+		})
+
+		t.Run("mapping created", func(t *testing.T) {
+			// given
+			r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser, preexistingIdentityWithNoMapping)
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			checkUserIdentityMapping(t, r.client, identityName, username)
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "Provisioning", "", phaseCompleted(userCreatedCondition))
+		})
+
+		t.Run("mapping updated", func(t *testing.T) {
+			// given
+			preexistingMapping := &userv1.UserIdentityMapping{
+				ObjectMeta: metav1.ObjectMeta{Name: identityName},
+				Identity:   corev1.ObjectReference{Name: identityName},
+				User:       corev1.ObjectReference{Name: "another"},
+			}
+			r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser, preexistingIdentityWithNoMapping, preexistingMapping)
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			checkUserIdentityMapping(t, r.client, identityName, username)
+		})
+
+		t.Run("mapping creation failed", func(t *testing.T) {
+			// given
+			r, req, cl := prepareReconcile(t, username, userAcc, preexistingUser, preexistingIdentityWithNoMapping)
+			cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+				if _, ok := obj.(*userv1.UserIdentityMapping); ok {
+					return errors.New("unable to create mapping")
+				}
+				return cl.Client.Create(ctx, obj, opts...)
+			}
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.EqualError(t, err, fmt.Sprintf("failed to create the mapping between identity '%s' and user '%s': unable to create mapping", identityName, username))
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "UnableToCreateMapping", "unable to create mapping",
+				phaseCompleted(userCreatedCondition), phaseFailed(mappingCreatedCondition, "UnableToCreateMapping", "unable to create mapping"))
+		})
+
+		t.Run("provisioned", func(t *testing.T) {
+			// given
+			mappedIdentity := preexistingIdentityWithNoMapping.DeepCopy()
+			mappedIdentity.User = corev1.ObjectReference{Name: username, UID: userUID}
+			r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser, mappedIdentity, preexistingNsTmplSet)
+
+			// when
+			res, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{}, res)
+			checkStatus(t, r.client, username, corev1.ConditionTrue, "Provisioned", "",
+				phaseCompleted(userCreatedCondition), phaseCompleted(identityCreatedCondition), phaseCompleted(mappingCreatedCondition), phaseCompleted(nsTemplateSetReadyCondition))
+		})
+
+		t.Run("mapping deleted before the identity and the user", func(t *testing.T) {
+			// given
+			deletedAcc := newUserAccountWithFinalizer(username, userID)
+			deletedAcc.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			mappedIdentity := preexistingIdentityWithNoMapping.DeepCopy()
+			mappedIdentity.User = corev1.ObjectReference{Name: username, UID: userUID}
+			preexistingMapping := newUserIdentityMapping(mappedIdentity, preexistingUser)
+			r, req, _ := prepareReconcile(t, username, deletedAcc, preexistingUser, mappedIdentity, preexistingMapping)
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: identityName}, &userv1.UserIdentityMapping{})
+			require.True(t, apierros.IsNotFound(err))
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: identityName}, &userv1.Identity{})
+			require.True(t, apierros.IsNotFound(err))
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: username}, &userv1.User{})
+			require.NoError(t, err)
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "Terminating", "deleting the identities")
+		})
+	})
+
+	t.Run("lookup strategy", func(t *testing.T) {
+		defer setIdentityMappingStrategy(t, config.IdentityMappingStrategyLookup)()
+
+		t.Run("identity not created yet", func(t *testing.T) {
+			// given
+			r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser, preexistingNsTmplSet)
+
+			// when
+			res, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, reconcile.Result{RequeueAfter: identityLookupInterval}, res)
+			err = r.client.Get(context.TODO(), types.NamespacedName{Name: identityName}, &userv1.Identity{})
+			require.True(t, apierros.IsNotFound(err))
+			checkStatus(t, r.client, username, corev1.ConditionFalse, "Provisioning", "",
+				phaseCompleted(userCreatedCondition),
+				phaseFailed(identityCreatedCondition, "WaitingForIdentity", ""),
+				phaseCompleted(nsTemplateSetReadyCondition))
+		})
+
+		t.Run("mapping created", func(t *testing.T) {
+			// given
+			r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser, preexistingIdentityWithNoMapping)
+
+			// when
+			_, err := r.Reconcile(req)
+
+			// then
+			require.NoError(t, err)
+			checkUserIdentityMapping(t, r.client, identityName, username)
+		})
+	})
+}
+
+func setIdentityMappingStrategy(t *testing.T, strategy string) func() {
+	err := os.Setenv(config.IdentityMappingStrategyEnvVar, strategy)
+	require.NoError(t, err)
+	return func() {
+		err := os.Unsetenv(config.IdentityMappingStrategyEnvVar)
+		require.NoError(t, err)
+	}
+}
+
+func TestDisabledUserAccount(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	username := "johnsmith"
+	userID := uuid.NewV4().String()
+	userUID := types.UID(username + "user")
+	preexistingIdentity := &userv1.Identity{ObjectMeta: metav1.ObjectMeta{
+		Name: ToIdentityName(userID),
+		UID:  types.UID(username + "identity"),
+	}, User: corev1.ObjectReference{
+		Name: username,
+		UID:  userUID,
+	}}
+	preexistingUser := &userv1.User{ObjectMeta: metav1.ObjectMeta{
+		Name: username,
+		UID:  userUID,
+	}, Identities: []string{ToIdentityName(userID)}}
+	devNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   username + "-dev",
+		Labels: map[string]string{"owner": username},
+	}}
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: devNs.Name},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+
+	t.Run("identity deleted", func(t *testing.T) {
+		// given
+		userAcc := newDisabledUserAccount(username, userID)
+		r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser, preexistingIdentity)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: preexistingIdentity.Name}, &userv1.Identity{})
+		require.True(t, apierros.IsNotFound(err))
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: username}, &userv1.User{})
+		require.NoError(t, err)
+	})
+
+	t.Run("user deleted", func(t *testing.T) {
+		// given
+		userAcc := newDisabledUserAccount(username, userID)
+		r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: username}, &userv1.User{})
+		require.True(t, apierros.IsNotFound(err))
+	})
+
+	t.Run("disabled without scaling down", func(t *testing.T) {
+		// given
+		userAcc := newDisabledUserAccount(username, userID)
+		r, req, _ := prepareReconcile(t, username, userAcc, devNs, deployment)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "Disabled", "",
+			phaseFailed(userCreatedCondition, "Disabled", ""), phaseFailed(identityCreatedCondition, "Disabled", ""), phaseFailed(mappingCreatedCondition, "Disabled", ""))
+		checkReplicas(t, r.client, deployment, 3, "")
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: devNs.Name}, &corev1.Namespace{})
+		require.NoError(t, err)
+	})
+
+	t.Run("disabled with scaling down", func(t *testing.T) {
+		// given
+		defer setScaleDownDisabledUsers(t)()
+		userAcc := newDisabledUserAccount(username, userID)
+		r, req, _ := prepareReconcile(t, username, userAcc, devNs, deployment)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "Disabled", "",
+			phaseFailed(userCreatedCondition, "Disabled", ""), phaseFailed(identityCreatedCondition, "Disabled", ""), phaseFailed(mappingCreatedCondition, "Disabled", ""))
+		checkReplicas(t, r.client, deployment, 0, "3")
+		updatedAcc := &toolchainv1alpha1.UserAccount{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, updatedAcc)
+		require.NoError(t, err)
+		assert.Equal(t, "true", updatedAcc.Annotations[scaledDownAnnotation])
+	})
+
+	t.Run("delete identity fails", func(t *testing.T) {
+		// given
+		userAcc := newDisabledUserAccount(username, userID)
+		r, req, cl := prepareReconcile(t, username, userAcc, preexistingUser, preexistingIdentity)
+		cl.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("unable to delete identity")
+		}
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, fmt.Sprintf("failed to delete the identity of user '%s': unable to delete identity", username))
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "UnableToDisable", "unable to delete identity")
+	})
+
+	t.Run("re-enabled", func(t *testing.T) {
+		// given
+		userAcc := newUserAccount(username, userID)
+		userAcc.Annotations = map[string]string{scaledDownAnnotation: "true"}
+		zero := int32(0)
+		scaledDownDeployment := deployment.DeepCopy()
+		scaledDownDeployment.Spec.Replicas = &zero
+		scaledDownDeployment.Annotations = map[string]string{replicasAnnotation: "3"}
+		r, req, _ := prepareReconcile(t, username, userAcc, devNs, scaledDownDeployment)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		checkReplicas(t, r.client, deployment, 3, "")
+		updatedAcc := &toolchainv1alpha1.UserAccount{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, updatedAcc)
+		require.NoError(t, err)
+		assert.NotContains(t, updatedAcc.Annotations, scaledDownAnnotation)
+		// the user is provisioned again
+		user := &userv1.User{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: username}, user)
+		require.NoError(t, err)
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "Provisioning", "")
+	})
+}
+
+func newDisabledUserAccount(userName, userID string) *toolchainv1alpha1.UserAccount {
+	userAcc := newUserAccount(userName, userID)
+	userAcc.Spec.Disabled = true
+	return userAcc
+}
+
+func setScaleDownDisabledUsers(t *testing.T) func() {
+	err := os.Setenv(config.ScaleDownDisabledUsersEnvVar, "true")
+	require.NoError(t, err)
+	return func() {
+		err := os.Unsetenv(config.ScaleDownDisabledUsersEnvVar)
+		require.NoError(t, err)
+	}
+}
+
+func checkReplicas(t *testing.T, cl client.Client, deployment *appsv1.Deployment, expectedReplicas int32, expectedAnnotation string) {
+	t.Helper()
+
+	updated := &appsv1.Deployment{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: deployment.Namespace, Name: deployment.Name}, updated)
+	require.NoError(t, err)
+	require.NotNil(t, updated.Spec.Replicas)
+	assert.Equal(t, expectedReplicas, *updated.Spec.Replicas)
+	assert.Equal(t, expectedAnnotation, updated.Annotations[replicasAnnotation])
+}
+
+func TestPausedUserAccount(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	username := "johnsmith"
+	userID := uuid.NewV4().String()
+
+	t.Run("reconciliation skipped while paused", func(t *testing.T) {
+		// given
+		userAcc := newUserAccount(username, userID)
+		userAcc.Annotations = map[string]string{pause.Annotation: "true"}
+		r, req, _ := prepareReconcile(t, username, userAcc)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: username}, &userv1.User{})
+		require.True(t, apierros.IsNotFound(err))
+		updatedAcc := &toolchainv1alpha1.UserAccount{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, updatedAcc)
+		require.NoError(t, err)
+		assert.NotContains(t, updatedAcc.Finalizers, userAccFinalizerName)
+		test.AssertConditionsMatch(t, updatedAcc.Status.Conditions, pause.PausedCondition())
+	})
+
+	t.Run("reconciliation resumed", func(t *testing.T) {
+		// given
+		userAcc := newUserAccount(username, userID)
+		userAcc.Status.Conditions = []toolchainv1alpha1.Condition{pause.PausedCondition()}
+		r, req, _ := prepareReconcile(t, username, userAcc)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: username}, &userv1.User{})
+		require.NoError(t, err)
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "Provisioning", "",
+			toolchainv1alpha1.Condition{Type: pause.ConditionType, Status: corev1.ConditionFalse, Reason: pause.ResumedReason})
+	})
+}
+
+func TestMultipleIdentities(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	username := "johnsmith"
+	userID := uuid.NewV4().String()
+	userUID := types.UID(username + "user")
+	primaryIdentityName := ToIdentityName(userID)
+	userAcc := newUserAccount(username, userID)
+	userAcc.Annotations = map[string]string{additionalIdentitiesAnnotation: "github:12345"}
+	preexistingPrimaryIdentity := &userv1.Identity{ObjectMeta: metav1.ObjectMeta{
+		Name: primaryIdentityName,
+		UID:  types.UID(username + "identity"),
+	}, User: corev1.ObjectReference{
+		Name: username,
+		UID:  userUID,
+	}}
+
+	t.Run("user created with all identities", func(t *testing.T) {
+		// given
+		r, req, _ := prepareReconcile(t, username, userAcc)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		user := &userv1.User{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: username}, user)
+		require.NoError(t, err)
+		assert.Equal(t, []string{primaryIdentityName, "github:12345"}, user.Identities)
+	})
+
+	t.Run("user updated with additional identity", func(t *testing.T) {
+		// given
+		preexistingUser := &userv1.User{ObjectMeta: metav1.ObjectMeta{
+			Name: username,
+			UID:  userUID,
+		}, Identities: []string{primaryIdentityName}}
+		r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		user := &userv1.User{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: username}, user)
+		require.NoError(t, err)
+		assert.Equal(t, []string{primaryIdentityName, "github:12345"}, user.Identities)
+	})
+
+	t.Run("additional identity created", func(t *testing.T) {
+		// given
+		preexistingUser := &userv1.User{ObjectMeta: metav1.ObjectMeta{
+			Name: username,
+			UID:  userUID,
+		}, Identities: []string{primaryIdentityName, "github:12345"}}
+		r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser, preexistingPrimaryIdentity)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		identity := &userv1.Identity{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "github:12345"}, identity)
+		require.NoError(t, err)
+		assert.Equal(t, "github", identity.ProviderName)
+		assert.Equal(t, "12345", identity.ProviderUserName)
+		assert.Equal(t, username, identity.Labels[ownerLabel])
+		assert.Equal(t, username, identity.User.Name)
+		assert.Equal(t, userUID, identity.User.UID)
+	})
+
+	t.Run("identity no longer listed deleted", func(t *testing.T) {
+		// given
+		userAcc := newUserAccount(username, userID)
+		preexistingUser := &userv1.User{ObjectMeta: metav1.ObjectMeta{
+			Name: username,
+			UID:  userUID,
+		}, Identities: []string{primaryIdentityName}}
+		preexistingGithubIdentity := &userv1.Identity{ObjectMeta: metav1.ObjectMeta{
+			Name:   "github:12345",
+			Labels: map[string]string{ownerLabel: username},
+		}, User: corev1.ObjectReference{
+			Name: username,
+			UID:  userUID,
+		}}
+		r, req, _ := prepareReconcile(t, username, userAcc, preexistingUser, preexistingPrimaryIdentity, preexistingGithubIdentity)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "github:12345"}, &userv1.Identity{})
+		require.True(t, apierros.IsNotFound(err))
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: primaryIdentityName}, &userv1.Identity{})
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid additional identity", func(t *testing.T) {
+		// given
+		userAcc := newUserAccount(username, userID)
+		userAcc.Annotations = map[string]string{additionalIdentitiesAnnotation: "github"}
+		r, req, _ := prepareReconcile(t, username, userAcc)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.EqualError(t, err, fmt.Sprintf("invalid identities for user account '%s': invalid identity 'github': expected format is '<provider>:<user id>'", username))
+		checkStatus(t, r.client, username, corev1.ConditionFalse, "UnableToCreateIdentity", "invalid identity 'github': expected format is '<provider>:<user id>'",
+			phaseFailed(identityCreatedCondition, "UnableToCreateIdentity", "invalid identity 'github': expected format is '<provider>:<user id>'"))
+	})
+
+	t.Run("all identities deleted with user account", func(t *testing.T) {
+		// given
+		userAcc := newUserAccountWithFinalizer(username, userID)
+		userAcc.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		preexistingGithubIdentity := &userv1.Identity{ObjectMeta: metav1.ObjectMeta{
+			Name:   "github:12345",
+			Labels: map[string]string{ownerLabel: username},
+		}}
+		r, req, _ := prepareReconcile(t, username, userAcc, preexistingGithubIdentity)
+
+		// when
+		_, err := r.Reconcile(req)
+
+		// then
+		require.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "github:12345"}, &userv1.Identity{})
+		require.True(t, apierros.IsNotFound(err))
+	})
+}
+
+func TestIdentityProviderChanged(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	username := "johnsmith"
+	userID := uuid.NewV4().String()
+	userUID := types.UID(username + "user")
+	userAcc := newUserAccount(username, userID)
+	isController := true
+	previousIdentity := &userv1.Identity{ObjectMeta: metav1.ObjectMeta{
+		Name:            "rhd:" + userID,
+		UID:             types.UID(username + "identity"),
+		OwnerReferences: []metav1.OwnerReference{{UID: userAcc.UID, Controller: &isController}},
+	}, User: corev1.ObjectReference{
+		Name: username,
+		UID:  userUID,
+	}}
+	preexistingUser := &userv1.User{ObjectMeta: metav1.ObjectMeta{
+		Name: username,
+		UID:  userUID,
+	}, Identities: []string{previousIdentity.Name}}
+	memberOperatorConfig := &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      memberv1alpha1.MemberOperatorConfigName,
+			Namespace: "toolchain-member",
+		},
+		Spec: memberv1alpha1.MemberOperatorConfigSpec{
+			IdentityProvider: "sso",
+		},
+	}
+	r, req, cl := prepareReconcile(t, username, userAcc, preexistingUser, previousIdentity, memberOperatorConfig)
+	defer func() {
+		// restore the default identity provider
+		err := config.LoadMemberOperatorConfig(test.NewFakeClient(t), "toolchain-member")
+		require.NoError(t, err)
+	}()
+
+	// when
+	for i := 0; i < 3; i++ {
+		_, err := r.Reconcile(req)
+		require.NoError(t, err)
+	}
+
+	// then
+	assert.Equal(t, "sso", config.GetIdP())
+	user := &userv1.User{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Name: username}, user)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sso:" + userID}, user.Identities)
+	identity := &userv1.Identity{}
+	err = cl.Get(context.TODO(), types.NamespacedName{Name: "sso:" + userID}, identity)
+	require.NoError(t, err)
+	assert.Equal(t, "sso", identity.ProviderName)
+	assert.Equal(t, userID, identity.ProviderUserName)
+	err = cl.Get(context.TODO(), types.NamespacedName{Name: previousIdentity.Name}, &userv1.Identity{})
+	require.True(t, apierros.IsNotFound(err))
+}
+
+func TestAllUserAccounts(t *testing.T) {
+	// given
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	cl := test.NewFakeClient(t, newUserAccount("johnsmith", "1"), newUserAccount("janedoe", "2"))
+	mapper := allUserAccounts(cl)
+	cfg := &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      memberv1alpha1.MemberOperatorConfigName,
+			Namespace: "toolchain-member",
+		},
+	}
+
+	// when
+	requests := mapper(handler.MapObject{Meta: cfg, Object: cfg})
+
+	// then
+	assert.ElementsMatch(t, []reconcile.Request{newReconcileRequest("johnsmith"), newReconcileRequest("janedoe")}, requests)
 }
 
 func TestUpdateStatus(t *testing.T) {
@@ -873,19 +1612,49 @@ func checkMapping(t *testing.T, user *userv1.User, identity *userv1.Identity) {
 	assert.Equal(t, identity.Name, user.Identities[0])
 }
 
-func checkStatus(t *testing.T, client client.Client, username string, status corev1.ConditionStatus, wantReason, wantMsg string) {
+func checkUserIdentityMapping(t *testing.T, client client.Client, identityName, username string) {
+	t.Helper()
+
+	mapping := &userv1.UserIdentityMapping{}
+	err := client.Get(context.TODO(), types.NamespacedName{Name: identityName}, mapping)
+	require.NoError(t, err)
+	assert.Equal(t, identityName, mapping.Identity.Name)
+	assert.Equal(t, username, mapping.User.Name)
+}
+
+// checkStatus verifies the `Ready` condition of the UserAccount, along with the conditions of the provisioning phases
+func checkStatus(t *testing.T, client client.Client, username string, status corev1.ConditionStatus, wantReason, wantMsg string, wantPhases ...toolchainv1alpha1.Condition) {
 	t.Helper()
 
 	updatedAcc := &toolchainv1alpha1.UserAccount{}
 	err := client.Get(context.TODO(), types.NamespacedName{Name: username, Namespace: "toolchain-member"}, updatedAcc)
 	require.NoError(t, err)
-	test.AssertConditionsMatch(t, updatedAcc.Status.Conditions,
-		toolchainv1alpha1.Condition{
+	wantConditions := append([]toolchainv1alpha1.Condition{
+		{
 			Type:    toolchainv1alpha1.ConditionReady,
 			Status:  status,
 			Reason:  wantReason,
 			Message: wantMsg,
-		})
+		},
+	}, wantPhases...)
+	test.AssertConditionsMatch(t, updatedAcc.Status.Conditions, wantConditions...)
+}
+
+func phaseCompleted(phase toolchainv1alpha1.ConditionType) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:   phase,
+		Status: corev1.ConditionTrue,
+		Reason: "Provisioned",
+	}
+}
+
+func phaseFailed(phase toolchainv1alpha1.ConditionType, reason, msg string) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:    phase,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: msg,
+	}
 }
 
 func checkNSTmplSet(t *testing.T, client client.Client, username string) {
@@ -906,6 +1675,8 @@ func prepareReconcile(t *testing.T, username string, initObjs ...runtime.Object)
 	err := apis.AddToScheme(s)
 	require.NoError(t, err)
 	fakeClient := test.NewFakeClient(t, initObjs...)
+	// the configuration is loaded by the MemberOperatorConfig controller
+	require.NoError(t, config.LoadMemberOperatorConfig(fakeClient, "toolchain-member"))
 
 	r := &ReconcileUserAccount{
 		client: fakeClient,
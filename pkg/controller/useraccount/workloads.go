@@ -0,0 +1,125 @@
+package useraccount
+
+import (
+	"context"
+	"strconv"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	errs "github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// scaledDownAnnotation the annotation set on the UserAccount when the workloads in the user namespaces were scaled down
+	scaledDownAnnotation = "toolchain.dev.openshift.com/scaled-down"
+	// replicasAnnotation the annotation in which the number of replicas of a workload is kept while it is scaled down
+	replicasAnnotation = "toolchain.dev.openshift.com/replicas"
+)
+
+// scaleDownWorkloads scales down to zero the Deployments and StatefulSets in the user namespaces and marks the UserAccount
+// accordingly. Does nothing if the workloads were already scaled down.
+func (r *ReconcileUserAccount) scaleDownWorkloads(userAcc *toolchainv1alpha1.UserAccount) error {
+	if _, found := userAcc.Annotations[scaledDownAnnotation]; found {
+		return nil
+	}
+	if err := r.scaleWorkloads(userAcc, scaleDown); err != nil {
+		return err
+	}
+	if userAcc.Annotations == nil {
+		userAcc.Annotations = map[string]string{}
+	}
+	userAcc.Annotations[scaledDownAnnotation] = "true"
+	return r.client.Update(context.TODO(), userAcc)
+}
+
+// scaleUpWorkloads restores the number of replicas of the Deployments and StatefulSets in the user namespaces if they were
+// scaled down when the UserAccount was disabled. Does nothing otherwise.
+func (r *ReconcileUserAccount) scaleUpWorkloads(userAcc *toolchainv1alpha1.UserAccount) error {
+	if _, found := userAcc.Annotations[scaledDownAnnotation]; !found {
+		return nil
+	}
+	if err := r.scaleWorkloads(userAcc, scaleUp); err != nil {
+		return err
+	}
+	delete(userAcc.Annotations, scaledDownAnnotation)
+	return r.client.Update(context.TODO(), userAcc)
+}
+
+// scaleWorkloads applies the given func on all the Deployments and StatefulSets in the user namespaces, and updates
+// those whose number of replicas changed
+func (r *ReconcileUserAccount) scaleWorkloads(userAcc *toolchainv1alpha1.UserAccount, scale func(obj metav1.Object, replicas *int32) (*int32, bool)) error {
+	namespaces := &corev1.NamespaceList{}
+	if err := r.client.List(context.TODO(), namespaces, client.MatchingLabels(map[string]string{"owner": userAcc.Name})); err != nil {
+		return errs.Wrap(err, "failed to list the user namespaces")
+	}
+	for _, ns := range namespaces.Items {
+		deployments := &appsv1.DeploymentList{}
+		if err := r.client.List(context.TODO(), deployments, client.InNamespace(ns.Name)); err != nil {
+			return errs.Wrapf(err, "failed to list the deployments in namespace '%s'", ns.Name)
+		}
+		for i := range deployments.Items {
+			deployment := &deployments.Items[i]
+			if replicas, changed := scale(deployment, deployment.Spec.Replicas); changed {
+				deployment.Spec.Replicas = replicas
+				if err := r.client.Update(context.TODO(), deployment); err != nil {
+					return errs.Wrapf(err, "failed to scale deployment '%s' in namespace '%s'", deployment.Name, ns.Name)
+				}
+			}
+		}
+		statefulSets := &appsv1.StatefulSetList{}
+		if err := r.client.List(context.TODO(), statefulSets, client.InNamespace(ns.Name)); err != nil {
+			return errs.Wrapf(err, "failed to list the statefulsets in namespace '%s'", ns.Name)
+		}
+		for i := range statefulSets.Items {
+			statefulSet := &statefulSets.Items[i]
+			if replicas, changed := scale(statefulSet, statefulSet.Spec.Replicas); changed {
+				statefulSet.Spec.Replicas = replicas
+				if err := r.client.Update(context.TODO(), statefulSet); err != nil {
+					return errs.Wrapf(err, "failed to scale statefulset '%s' in namespace '%s'", statefulSet.Name, ns.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// scaleDown returns zero replicas and keeps the current number of replicas in an annotation of the given object
+func scaleDown(obj metav1.Object, replicas *int32) (*int32, bool) {
+	if replicas != nil && *replicas == 0 {
+		return replicas, false
+	}
+	current := int32(1) // default value when not specified
+	if replicas != nil {
+		current = *replicas
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[replicasAnnotation] = strconv.Itoa(int(current))
+	obj.SetAnnotations(annotations)
+	zero := int32(0)
+	return &zero, true
+}
+
+// scaleUp returns the number of replicas kept in the annotation of the given object, and removes this annotation
+func scaleUp(obj metav1.Object, replicas *int32) (*int32, bool) {
+	annotations := obj.GetAnnotations()
+	value, found := annotations[replicasAnnotation]
+	if !found {
+		return replicas, false
+	}
+	delete(annotations, replicasAnnotation)
+	obj.SetAnnotations(annotations)
+	previous, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		// invalid annotation: just remove it
+		return replicas, true
+	}
+	restored := int32(previous)
+	return &restored, true
+}
@@ -0,0 +1,99 @@
+package useraccount
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestScaleDown(t *testing.T) {
+
+	t.Run("replicas set", func(t *testing.T) {
+		// given
+		obj := &metav1.ObjectMeta{}
+		replicas := int32(2)
+
+		// when
+		result, changed := scaleDown(obj, &replicas)
+
+		// then
+		assert.True(t, changed)
+		require.NotNil(t, result)
+		assert.Equal(t, int32(0), *result)
+		assert.Equal(t, "2", obj.GetAnnotations()[replicasAnnotation])
+	})
+
+	t.Run("replicas not set", func(t *testing.T) {
+		// given
+		obj := &metav1.ObjectMeta{Annotations: map[string]string{"foo": "bar"}}
+
+		// when
+		result, changed := scaleDown(obj, nil)
+
+		// then
+		assert.True(t, changed)
+		require.NotNil(t, result)
+		assert.Equal(t, int32(0), *result)
+		assert.Equal(t, map[string]string{"foo": "bar", replicasAnnotation: "1"}, obj.GetAnnotations())
+	})
+
+	t.Run("already scaled down", func(t *testing.T) {
+		// given
+		obj := &metav1.ObjectMeta{}
+		replicas := int32(0)
+
+		// when
+		_, changed := scaleDown(obj, &replicas)
+
+		// then
+		assert.False(t, changed)
+		assert.Empty(t, obj.GetAnnotations())
+	})
+}
+
+func TestScaleUp(t *testing.T) {
+
+	t.Run("annotation set", func(t *testing.T) {
+		// given
+		obj := &metav1.ObjectMeta{Annotations: map[string]string{replicasAnnotation: "2"}}
+		replicas := int32(0)
+
+		// when
+		result, changed := scaleUp(obj, &replicas)
+
+		// then
+		assert.True(t, changed)
+		require.NotNil(t, result)
+		assert.Equal(t, int32(2), *result)
+		assert.Empty(t, obj.GetAnnotations())
+	})
+
+	t.Run("annotation not set", func(t *testing.T) {
+		// given
+		obj := &metav1.ObjectMeta{}
+		replicas := int32(0)
+
+		// when
+		result, changed := scaleUp(obj, &replicas)
+
+		// then
+		assert.False(t, changed)
+		assert.Equal(t, &replicas, result)
+	})
+
+	t.Run("invalid annotation removed", func(t *testing.T) {
+		// given
+		obj := &metav1.ObjectMeta{Annotations: map[string]string{replicasAnnotation: "foo"}}
+		replicas := int32(0)
+
+		// when
+		result, changed := scaleUp(obj, &replicas)
+
+		// then
+		assert.True(t, changed)
+		assert.Equal(t, &replicas, result)
+		assert.Empty(t, obj.GetAnnotations())
+	})
+}
@@ -3,6 +3,8 @@ package useraccountstatus
 import (
 	"context"
 	"fmt"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
 	"github.com/codeready-toolchain/member-operator/pkg/predicate"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 	"k8s.io/apimachinery/pkg/types"
@@ -31,7 +33,7 @@ func Add(mgr manager.Manager) error {
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 	return &ReconcileUserAccountStatus{
-		client:         mgr.GetClient(),
+		client:         config.ControllerClient("useraccountstatus", mgr.GetClient()),
 		scheme:         mgr.GetScheme(),
 		getHostCluster: cluster.GetHostCluster,
 	}
@@ -40,7 +42,7 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
-	c, err := controller.New("useraccount_status-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("useraccount_status-controller", mgr, config.ControllerOptions("useraccountstatus", r))
 	if err != nil {
 		return err
 	}
@@ -71,7 +73,7 @@ type ReconcileUserAccountStatus struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileUserAccountStatus) Reconcile(request reconcile.Request) (reconcile.Result, error) {
-	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger := logging.ForRequest(log, request)
 	reqLogger.Info("Reconciling UserAccountStatus")
 
 	// Fetch the UserAccount object
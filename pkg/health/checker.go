@@ -0,0 +1,263 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/go-logr/logr"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	errs "github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("health_checker")
+
+const (
+	// PathAnnotation the annotation on a Service or a Route provided by a template, which defines the path of the health check endpoint
+	PathAnnotation = "toolchain.dev.openshift.com/health-check-path"
+	// PortAnnotation the optional annotation on a Service which defines the port of the health check endpoint.
+	// Defaults to the first port of the Service
+	PortAnnotation = "toolchain.dev.openshift.com/health-check-port"
+	// ExpectedStatusAnnotation the optional annotation which defines the expected HTTP status code of the health check endpoint.
+	// Defaults to `200`
+	ExpectedStatusAnnotation = "toolchain.dev.openshift.com/health-check-expected-status"
+
+	// StatusAnnotation the annotation on the NSTemplateSet which holds the results of the health checks of the user's environment
+	StatusAnnotation = "toolchain.dev.openshift.com/health"
+
+	// DefaultInterval the default interval between two runs of the health checks
+	DefaultInterval = 5 * time.Minute
+	// DefaultTimeout the default timeout of a single health check
+	DefaultTimeout = 5 * time.Second
+)
+
+// Result the result of the health check of a single object
+type Result struct {
+	Kind          string      `json:"kind"`
+	Namespace     string      `json:"namespace"`
+	Name          string      `json:"name"`
+	URL           string      `json:"url,omitempty"`
+	Healthy       bool        `json:"healthy"`
+	Message       string      `json:"message,omitempty"`
+	LastCheckTime metav1.Time `json:"lastCheckTime"`
+}
+
+// Add creates a new health Checker and adds it to the Manager. The Checker only runs on the leader.
+func Add(mgr manager.Manager) error {
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	return mgr.Add(NewChecker(mgr.GetClient(), &http.Client{Timeout: DefaultTimeout}, namespace, DefaultInterval))
+}
+
+// Checker periodically evaluates the health checks defined on the objects that were applied for each user
+// (as recorded in the inventory of the NSTemplateSets) and publishes the results in an annotation of the NSTemplateSet
+type Checker struct {
+	client     client.Client
+	httpClient *http.Client
+	namespace  string
+	interval   time.Duration
+}
+
+// NewChecker returns a new Checker for the NSTemplateSets in the given namespace
+func NewChecker(cl client.Client, httpClient *http.Client, namespace string, interval time.Duration) *Checker {
+	return &Checker{
+		client:     cl,
+		httpClient: httpClient,
+		namespace:  namespace,
+		interval:   interval,
+	}
+}
+
+// Start runs the health checks at every interval, until the given channel is closed
+func (c *Checker) Start(stop <-chan struct{}) error {
+	log.Info("starting the health checker", "interval", c.interval)
+	wait.Until(func() {
+		if err := c.CheckAll(); err != nil {
+			log.Error(err, "failed to run the health checks")
+		}
+	}, c.interval, stop)
+	return nil
+}
+
+// CheckAll runs the health checks of all the NSTemplateSets
+func (c *Checker) CheckAll() error {
+	nsTmplSets := &toolchainv1alpha1.NSTemplateSetList{}
+	if err := c.client.List(context.TODO(), nsTmplSets, client.InNamespace(c.namespace)); err != nil {
+		return errs.Wrap(err, "failed to list the NSTemplateSets")
+	}
+	for i := range nsTmplSets.Items {
+		nsTmplSet := &nsTmplSets.Items[i]
+		logger := log.WithValues("NSTemplateSet", nsTmplSet.Name)
+		if err := c.check(logger, nsTmplSet); err != nil {
+			// do not prevent the health checks of the other users
+			logger.Error(err, "failed to run the health checks")
+		}
+	}
+	return nil
+}
+
+func (c *Checker) check(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet) error {
+	inventory, err := template.ParseInventory(nsTmplSet.GetAnnotations()[template.InventoryAnnotation])
+	if err != nil {
+		return err
+	}
+	results := []Result{}
+	for _, entry := range inventory.Entries {
+		if entry.Kind != "Service" && entry.Kind != "Route" {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(entry.APIVersion)
+		obj.SetKind(entry.Kind)
+		if err := c.client.Get(context.TODO(), types.NamespacedName{Namespace: entry.Namespace, Name: entry.Name}, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return errs.Wrapf(err, "unable to get the resource of kind '%s' and name '%s' in namespace '%s'", entry.Kind, entry.Name, entry.Namespace)
+		}
+		if _, found := obj.GetAnnotations()[PathAnnotation]; !found {
+			continue
+		}
+		result := c.checkObject(obj)
+		logger.Info("health check evaluated", "kind", result.Kind, "namespace", result.Namespace, "name", result.Name, "healthy", result.Healthy)
+		results = append(results, result)
+	}
+
+	// the results are only published when they changed since the previous run, so that the NSTemplateSets are not updated
+	// at every interval. The check time of the published results is thus the time at which they changed
+	if previous, found := publishedResults(nsTmplSet); found && sameResults(previous, results) {
+		return nil
+	}
+	content, err := json.Marshal(results)
+	if err != nil {
+		return errs.Wrap(err, "unable to marshal the health check results")
+	}
+	patch := client.MergeFrom(nsTmplSet.DeepCopy())
+	annotations := nsTmplSet.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[StatusAnnotation] = string(content)
+	nsTmplSet.SetAnnotations(annotations)
+	if err := c.client.Patch(context.TODO(), nsTmplSet, patch); err != nil {
+		return errs.Wrap(err, "unable to publish the health check results")
+	}
+	return nil
+}
+
+// publishedResults returns the results which are published on the given NSTemplateSet, if any and if they are valid
+func publishedResults(nsTmplSet *toolchainv1alpha1.NSTemplateSet) ([]Result, bool) {
+	value, found := nsTmplSet.GetAnnotations()[StatusAnnotation]
+	if !found {
+		return nil, false
+	}
+	var results []Result
+	if err := json.Unmarshal([]byte(value), &results); err != nil {
+		return nil, false
+	}
+	return results, true
+}
+
+// sameResults returns true if the given results only differ by their check time
+func sameResults(previous, current []Result) bool {
+	if len(previous) != len(current) {
+		return false
+	}
+	for i := range current {
+		result := previous[i]
+		result.LastCheckTime = current[i].LastCheckTime
+		if result != current[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkObject evaluates the health check defined on the given object
+func (c *Checker) checkObject(obj *unstructured.Unstructured) Result {
+	result := Result{
+		Kind:          obj.GetKind(),
+		Namespace:     obj.GetNamespace(),
+		Name:          obj.GetName(),
+		LastCheckTime: metav1.Now(),
+	}
+	url, err := healthCheckURL(obj)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.URL = url
+	expectedStatus := http.StatusOK
+	if value, found := obj.GetAnnotations()[ExpectedStatusAnnotation]; found {
+		if expectedStatus, err = strconv.Atoi(value); err != nil {
+			result.Message = fmt.Sprintf("invalid expected status '%s'", value)
+			return result
+		}
+	}
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expectedStatus {
+		result.Message = fmt.Sprintf("expected status %d but got %d", expectedStatus, resp.StatusCode)
+		return result
+	}
+	result.Healthy = true
+	return result
+}
+
+// healthCheckURL returns the URL of the health check endpoint of the given Service or Route.
+// The host is always derived from the object itself, so that the annotations cannot be used
+// to make the operator call an arbitrary endpoint.
+func healthCheckURL(obj *unstructured.Unstructured) (string, error) {
+	path := obj.GetAnnotations()[PathAnnotation]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	switch obj.GetKind() {
+	case "Service":
+		port := obj.GetAnnotations()[PortAnnotation]
+		if port == "" {
+			ports, _, _ := unstructured.NestedSlice(obj.Object, "spec", "ports")
+			if len(ports) == 0 {
+				return "", fmt.Errorf("service has no port")
+			}
+			p, _ := ports[0].(map[string]interface{})
+			value, _, _ := unstructured.NestedInt64(p, "port")
+			port = strconv.FormatInt(value, 10)
+		} else if _, err := strconv.Atoi(port); err != nil {
+			return "", fmt.Errorf("invalid port '%s'", port)
+		}
+		return fmt.Sprintf("http://%s.%s.svc:%s%s", obj.GetName(), obj.GetNamespace(), port, path), nil
+	case "Route":
+		host, _, _ := unstructured.NestedString(obj.Object, "spec", "host")
+		if host == "" {
+			return "", fmt.Errorf("route has no host")
+		}
+		scheme := "http"
+		if _, found, _ := unstructured.NestedMap(obj.Object, "spec", "tls"); found {
+			scheme = "https"
+		}
+		return fmt.Sprintf("%s://%s%s", scheme, host, path), nil
+	default:
+		return "", fmt.Errorf("health checks are not supported on resources of kind '%s'", obj.GetKind())
+	}
+}
@@ -0,0 +1,334 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	username      = "johnsmith"
+	namespaceName = "toolchain-member"
+)
+
+func TestCheckAll(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	// the test server replies with `200` on `/healthz` and with `503` on all other paths
+	var requestedURLs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	// all requests are sent to the test server, regardless of the host derived from the objects
+	httpClient := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requestedURLs = append(requestedURLs, req.URL.String())
+			req.URL.Scheme = serverURL.Scheme
+			req.URL.Host = serverURL.Host
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+	inventory := `{"entries":[` +
+		`{"apiVersion":"v1","kind":"Service","namespace":"johnsmith-dev","name":"api"},` +
+		`{"apiVersion":"v1","kind":"Service","namespace":"johnsmith-dev","name":"db"},` +
+		`{"apiVersion":"v1","kind":"Service","namespace":"johnsmith-dev","name":"unchecked"},` +
+		`{"apiVersion":"v1","kind":"Service","namespace":"johnsmith-dev","name":"deleted"},` +
+		`{"apiVersion":"v1","kind":"ConfigMap","namespace":"johnsmith-dev","name":"config"}]}`
+
+	t.Run("results published", func(t *testing.T) {
+		// given
+		requestedURLs = nil
+		cl := test.NewFakeClient(t, newNSTmplSet(inventory),
+			newService("api", map[string]string{PathAnnotation: "/healthz"}),
+			newService("db", map[string]string{PathAnnotation: "/ready", PortAnnotation: "5432"}),
+			newService("unchecked", nil))
+		c := NewChecker(cl, httpClient, namespaceName, DefaultInterval)
+
+		// when
+		err := c.CheckAll()
+
+		// then
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"http://api.johnsmith-dev.svc:8080/healthz", "http://db.johnsmith-dev.svc:5432/ready"}, requestedURLs)
+		results := getResults(t, cl)
+		require.Len(t, results, 2)
+		assert.Equal(t, "api", results[0].Name)
+		assert.True(t, results[0].Healthy)
+		assert.Empty(t, results[0].Message)
+		assert.Equal(t, "db", results[1].Name)
+		assert.False(t, results[1].Healthy)
+		assert.Equal(t, "expected status 200 but got 503", results[1].Message)
+	})
+
+	t.Run("results not published again when unchanged", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(inventory),
+			newService("api", map[string]string{PathAnnotation: "/healthz"}))
+		patches := &patchCounter{Client: cl}
+		c := NewChecker(patches, httpClient, namespaceName, DefaultInterval)
+		err := c.CheckAll()
+		require.NoError(t, err)
+		require.Equal(t, 1, patches.count)
+		published := getResults(t, cl)
+
+		// when
+		err = c.CheckAll()
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, patches.count)
+		results := getResults(t, cl)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].LastCheckTime.Equal(&published[0].LastCheckTime))
+
+		t.Run("results published again when changed", func(t *testing.T) {
+			// given
+			svc := &corev1.Service{}
+			err := cl.Get(context.TODO(), types.NamespacedName{Namespace: username + "-dev", Name: "api"}, svc)
+			require.NoError(t, err)
+			svc.Annotations[PathAnnotation] = "/ready"
+			err = cl.Update(context.TODO(), svc)
+			require.NoError(t, err)
+
+			// when
+			err = c.CheckAll()
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, 2, patches.count)
+			results := getResults(t, cl)
+			require.Len(t, results, 1)
+			assert.False(t, results[0].Healthy)
+			assert.Equal(t, "expected status 200 but got 503", results[0].Message)
+		})
+	})
+
+	t.Run("expected status", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(inventory),
+			newService("db", map[string]string{PathAnnotation: "/ready", ExpectedStatusAnnotation: "503"}))
+		c := NewChecker(cl, httpClient, namespaceName, DefaultInterval)
+
+		// when
+		err := c.CheckAll()
+
+		// then
+		require.NoError(t, err)
+		results := getResults(t, cl)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Healthy)
+	})
+
+	t.Run("invalid expected status", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(inventory),
+			newService("db", map[string]string{PathAnnotation: "/ready", ExpectedStatusAnnotation: "ok"}))
+		c := NewChecker(cl, httpClient, namespaceName, DefaultInterval)
+
+		// when
+		err := c.CheckAll()
+
+		// then
+		require.NoError(t, err)
+		results := getResults(t, cl)
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Healthy)
+		assert.Equal(t, "invalid expected status 'ok'", results[0].Message)
+	})
+
+	t.Run("failed to get object", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(inventory))
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			if _, ok := obj.(*unstructured.Unstructured); ok {
+				return errors.New("mock error")
+			}
+			return cl.Client.Get(ctx, key, obj)
+		}
+		c := NewChecker(cl, httpClient, namespaceName, DefaultInterval)
+
+		// when
+		err := c.CheckAll()
+
+		// then the failure is only logged, and the previous results are left untouched
+		require.NoError(t, err)
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+		err = cl.Client.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: username}, nsTmplSet)
+		require.NoError(t, err)
+		assert.NotContains(t, nsTmplSet.Annotations, StatusAnnotation)
+	})
+
+	t.Run("failed to list NSTemplateSets", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			return errors.New("mock error")
+		}
+		c := NewChecker(cl, httpClient, namespaceName, DefaultInterval)
+
+		// when
+		err := c.CheckAll()
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list the NSTemplateSets")
+	})
+}
+
+func TestHealthCheckURL(t *testing.T) {
+
+	t.Run("service with default port", func(t *testing.T) {
+		// when
+		u, err := healthCheckURL(toUnstructured(t, newService("api", map[string]string{PathAnnotation: "healthz"})))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "http://api.johnsmith-dev.svc:8080/healthz", u)
+	})
+
+	t.Run("service with invalid port", func(t *testing.T) {
+		// when
+		_, err := healthCheckURL(toUnstructured(t, newService("api", map[string]string{PathAnnotation: "/healthz", PortAnnotation: "evil.com"})))
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, "invalid port 'evil.com'", err.Error())
+	})
+
+	t.Run("service without port", func(t *testing.T) {
+		// given
+		svc := newService("api", map[string]string{PathAnnotation: "/healthz"})
+		svc.Spec.Ports = nil
+
+		// when
+		_, err := healthCheckURL(toUnstructured(t, svc))
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, "service has no port", err.Error())
+	})
+
+	t.Run("route", func(t *testing.T) {
+		// given
+		route := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "route.openshift.io/v1",
+			"kind":       "Route",
+			"metadata": map[string]interface{}{
+				"name":        "api",
+				"namespace":   "johnsmith-dev",
+				"annotations": map[string]interface{}{PathAnnotation: "/healthz"},
+			},
+			"spec": map[string]interface{}{
+				"host": "api-johnsmith-dev.apps.example.com",
+				"tls":  map[string]interface{}{"termination": "edge"},
+			},
+		}}
+
+		// when
+		u, err := healthCheckURL(route)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "https://api-johnsmith-dev.apps.example.com/healthz", u)
+	})
+
+	t.Run("route without host", func(t *testing.T) {
+		// given
+		route := &unstructured.Unstructured{}
+		route.SetKind("Route")
+
+		// when
+		_, err := healthCheckURL(route)
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, "route has no host", err.Error())
+	})
+}
+
+// patchCounter counts the patches of the objects
+type patchCounter struct {
+	client.Client
+	count int
+}
+
+func (c *patchCounter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.count++
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func getResults(t *testing.T, cl *test.FakeClient) []Result {
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: username}, nsTmplSet)
+	require.NoError(t, err)
+	var results []Result
+	err = json.Unmarshal([]byte(nsTmplSet.Annotations[StatusAnnotation]), &results)
+	require.NoError(t, err)
+	return results
+}
+
+func newNSTmplSet(inventory string) *toolchainv1alpha1.NSTemplateSet {
+	return &toolchainv1alpha1.NSTemplateSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        username,
+			Namespace:   namespaceName,
+			Annotations: map[string]string{template.InventoryAnnotation: inventory},
+		},
+	}
+}
+
+func newService(name string, annotations map[string]string) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   username + "-dev",
+			Annotations: annotations,
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 8080}},
+		},
+	}
+}
+
+func toUnstructured(t *testing.T, obj runtime.Object) *unstructured.Unstructured {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	require.NoError(t, err)
+	return &unstructured.Unstructured{Object: content}
+}
@@ -0,0 +1,192 @@
+package leadership
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("leadership")
+
+const (
+	// LivenessPath the path of the liveness probe, which succeeds as long as the operator is running
+	LivenessPath = "/healthz"
+	// ReadinessPath the path of the readiness probe, which succeeds once all the readiness checks pass, on the leader
+	// as well as on the standby replicas (which serve the webhooks too)
+	ReadinessPath = "/readyz"
+	// LeadershipPath the path of the endpoint which tells whether the replica is the leader (`200`) or a standby replica (`503`)
+	LeadershipPath = "/leader"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// Check a readiness check, which returns an error as long as the replica is not ready
+type Check func() error
+
+// Status tracks whether this replica of the operator is the leader, and whether it is ready. It must be added to the manager,
+// which only starts it once the leadership is acquired.
+type Status struct {
+	leader int32
+	lock   sync.RWMutex
+	checks map[string]Check
+}
+
+// NewStatus returns a new Status, which is not the leader until it is started
+func NewStatus() *Status {
+	return &Status{
+		checks: map[string]Check{},
+	}
+}
+
+// Start marks this replica as the leader until the given channel is closed
+func (s *Status) Start(stop <-chan struct{}) error {
+	atomic.StoreInt32(&s.leader, 1)
+	log.Info("leadership acquired")
+	<-stop
+	atomic.StoreInt32(&s.leader, 0)
+	log.Info("leadership released")
+	return nil
+}
+
+// IsLeader returns `true` if this replica is the leader
+func (s *Status) IsLeader() bool {
+	return atomic.LoadInt32(&s.leader) == 1
+}
+
+// AddReadinessCheck adds the given check to the readiness probe
+func (s *Status) AddReadinessCheck(name string, check Check) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.checks[name] = check
+}
+
+// Ready returns an error if one of the readiness checks does not pass
+func (s *Status) Ready() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	names := make([]string, 0, len(s.checks))
+	for name := range s.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.checks[name](); err != nil {
+			return fmt.Errorf("%s: %s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+// NewProbesHandler returns the handler of the liveness and readiness probes, and of the leadership endpoint
+func NewProbesHandler(s *Status) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(LivenessPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc(ReadinessPath, func(w http.ResponseWriter, _ *http.Request) {
+		if err := s.Ready(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc(LeadershipPath, func(w http.ResponseWriter, _ *http.Request) {
+		if !s.IsLeader() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("standby"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("leader"))
+	})
+	return mux
+}
+
+// ServeProbes serves the liveness and readiness probes on the given address until the given channel is closed
+func ServeProbes(addr string, s *Status, stop <-chan struct{}) {
+	server := &http.Server{Addr: addr, Handler: NewProbesHandler(s)}
+	go func() {
+		log.Info("serving the probes", "address", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error(err, "unable to serve the probes")
+		}
+	}()
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error(err, "unable to stop serving the probes")
+		}
+	}()
+}
+
+// CacheSync tracks whether a cache is synced. It must be added to the manager, which starts it on all the replicas
+type CacheSync struct {
+	cache  cache.Cache
+	synced int32
+}
+
+// NewCacheSync returns a new CacheSync of the given cache
+func NewCacheSync(c cache.Cache) *CacheSync {
+	return &CacheSync{
+		cache: c,
+	}
+}
+
+// Start waits until the cache is synced or until the given channel is closed
+func (c *CacheSync) Start(stop <-chan struct{}) error {
+	if c.cache.WaitForCacheSync(stop) {
+		atomic.StoreInt32(&c.synced, 1)
+		log.Info("cache synced")
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false, since the caches of the standby replicas are synced too
+func (c *CacheSync) NeedLeaderElection() bool {
+	return false
+}
+
+// Synced returns an error as long as the cache is not synced
+func (c *CacheSync) Synced() error {
+	if atomic.LoadInt32(&c.synced) == 0 {
+		return fmt.Errorf("cache not synced")
+	}
+	return nil
+}
+
+// OnAllReplicas returns a Manager which starts the runnables added to it (eg, a controller) on all the replicas, instead of only on the leader.
+// The fields of the runnables are not injected by the Manager, hence they must be set when the runnables are created (as the controllers are).
+func OnAllReplicas(mgr manager.Manager) manager.Manager {
+	return allReplicas{Manager: mgr}
+}
+
+type allReplicas struct {
+	manager.Manager
+}
+
+// Add adds the given runnable to the Manager, which starts it without waiting for the leadership
+func (m allReplicas) Add(r manager.Runnable) error {
+	return m.Manager.Add(nonLeaderRunnable{Runnable: r})
+}
+
+type nonLeaderRunnable struct {
+	manager.Runnable
+}
+
+// NeedLeaderElection returns false, since the runnable runs on all the replicas
+func (r nonLeaderRunnable) NeedLeaderElection() bool {
+	return false
+}
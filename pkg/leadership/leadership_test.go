@@ -0,0 +1,161 @@
+package leadership_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/leadership"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+func TestProbes(t *testing.T) {
+	// given
+	status := leadership.NewStatus()
+	handler := leadership.NewProbesHandler(status)
+
+	probe := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	t.Run("standby", func(t *testing.T) {
+		assert.False(t, status.IsLeader())
+		code, _ := probe(leadership.LivenessPath)
+		assert.Equal(t, http.StatusOK, code)
+		code, _ = probe(leadership.ReadinessPath)
+		assert.Equal(t, http.StatusOK, code)
+		code, body := probe(leadership.LeadershipPath)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "standby", body)
+	})
+
+	t.Run("leader", func(t *testing.T) {
+		// when
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			_ = status.Start(stop)
+			close(done)
+		}()
+
+		// then
+		assert.Eventually(t, status.IsLeader, time.Second, 10*time.Millisecond)
+		code, _ := probe(leadership.ReadinessPath)
+		assert.Equal(t, http.StatusOK, code)
+		code, body := probe(leadership.LeadershipPath)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "leader", body)
+
+		t.Run("leadership released", func(t *testing.T) {
+			// when
+			close(stop)
+			<-done
+
+			// then
+			assert.False(t, status.IsLeader())
+			code, _ := probe(leadership.ReadinessPath)
+			assert.Equal(t, http.StatusOK, code)
+			code, _ = probe(leadership.LeadershipPath)
+			assert.Equal(t, http.StatusServiceUnavailable, code)
+		})
+	})
+
+	t.Run("not ready until all checks pass", func(t *testing.T) {
+		// given
+		status := leadership.NewStatus()
+		handler := leadership.NewProbesHandler(status)
+		webhookErr := errors.New("not serving")
+		status.AddReadinessCheck("cache", func() error { return nil })
+		status.AddReadinessCheck("webhook server", func() error { return webhookErr })
+
+		// when
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, leadership.ReadinessPath, nil))
+
+		// then
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "webhook server: not serving", rec.Body.String())
+
+		t.Run("ready", func(t *testing.T) {
+			// given
+			webhookErr = nil
+
+			// when
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, leadership.ReadinessPath, nil))
+
+			// then
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+	})
+}
+
+func TestCacheSync(t *testing.T) {
+
+	t.Run("synced", func(t *testing.T) {
+		// given
+		sync := leadership.NewCacheSync(&informertest.FakeInformers{})
+		require.Error(t, sync.Synced())
+
+		// when
+		err := sync.Start(make(chan struct{}))
+
+		// then
+		require.NoError(t, err)
+		assert.NoError(t, sync.Synced())
+		assert.False(t, sync.NeedLeaderElection())
+	})
+
+	t.Run("not synced", func(t *testing.T) {
+		// given
+		synced := false
+		sync := leadership.NewCacheSync(&informertest.FakeInformers{Synced: &synced})
+
+		// when
+		err := sync.Start(make(chan struct{}))
+
+		// then
+		require.NoError(t, err)
+		assert.EqualError(t, sync.Synced(), "cache not synced")
+	})
+}
+
+func TestOnAllReplicas(t *testing.T) {
+	// given
+	mgr := &recordingManager{}
+	started := false
+	runnable := manager.RunnableFunc(func(<-chan struct{}) error {
+		started = true
+		return nil
+	})
+
+	// when
+	err := leadership.OnAllReplicas(mgr).Add(runnable)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, mgr.added, 1)
+	require.Implements(t, (*interface{ NeedLeaderElection() bool })(nil), mgr.added[0])
+	assert.False(t, mgr.added[0].(interface{ NeedLeaderElection() bool }).NeedLeaderElection())
+	require.NoError(t, mgr.added[0].Start(make(chan struct{})))
+	assert.True(t, started)
+}
+
+// recordingManager a Manager which records the runnables added to it
+type recordingManager struct {
+	manager.Manager
+	added []manager.Runnable
+}
+
+func (m *recordingManager) Add(r manager.Runnable) error {
+	m.added = append(m.added, r)
+	return nil
+}
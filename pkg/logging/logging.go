@@ -0,0 +1,182 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync/atomic"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+
+	"github.com/go-logr/logr"
+	errs "github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// CorrelationIDKey the key of the correlation ID in the log lines of a reconciliation
+const CorrelationIDKey = "correlation_id"
+
+// The levels of the logs, from the least to the most verbose
+const (
+	// ErrorLevel only the errors are logged
+	ErrorLevel = "error"
+	// InfoLevel the errors and the informational messages are logged (default)
+	InfoLevel = "info"
+	// DebugLevel the debug messages (ie, the messages logged with `V(1)`, such as the apply of each template object) are also logged
+	DebugLevel = "debug"
+)
+
+// ForRequest returns the logger of the reconciliation of the given request: all its log lines hold the namespace and the name
+// of the request, along with a correlation ID which is unique to the reconciliation, so that the lines of a reconciliation can be
+// told apart from those of the other reconciliations (including the previous reconciliations of the same resource)
+func ForRequest(logger logr.Logger, request reconcile.Request) logr.Logger {
+	return logger.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name, CorrelationIDKey, NewCorrelationID())
+}
+
+// NewCorrelationID returns a new random correlation ID
+func NewCorrelationID() string {
+	id := make([]byte, 8)
+	// the IDs only need to be unique, a failure leaves them partially random
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// verbosities the maximum verbosity of the logged messages, by default and by logger name
+type verbosities struct {
+	level   int
+	loggers map[string]int
+}
+
+// of returns the maximum verbosity of the messages of the logger with the given name, ie, the verbosity of its name or of the
+// closest of its parents (eg, `controller_nstemplateset` for `controller_nstemplateset.template`), or the default verbosity
+func (v verbosities) of(name string) int {
+	for name != "" {
+		if level, found := v.loggers[name]; found {
+			return level
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return v.level
+}
+
+var current atomic.Value
+
+func init() {
+	current.Store(verbosities{})
+}
+
+func currentVerbosities() verbosities {
+	return current.Load().(verbosities)
+}
+
+// Configure changes the levels of the logs of the loggers returned by `NewLogger` according to the given configuration, which takes
+// effect right away. Returns an error (and leaves the levels unchanged) if one of the levels is invalid
+func Configure(cfg memberv1alpha1.LoggingConfig) error {
+	level, err := verbosityOf(cfg.Level)
+	if err != nil {
+		return err
+	}
+	v := verbosities{level: level, loggers: make(map[string]int, len(cfg.Loggers))}
+	for name, l := range cfg.Loggers {
+		if v.loggers[name], err = verbosityOf(l); err != nil {
+			return errs.Wrapf(err, "invalid level of the '%s' logger", name)
+		}
+	}
+	current.Store(v)
+	setZapLevel(v.max())
+	return nil
+}
+
+// max returns the highest verbosity, by default or of any logger
+func (v verbosities) max() int {
+	max := v.level
+	for _, level := range v.loggers {
+		if level > max {
+			max = level
+		}
+	}
+	return max
+}
+
+// verbosityOf returns the maximum verbosity of the messages logged with the given level. The messages logged with `Info` have the
+// verbosity `0`, and those logged with `V(n).Info` have the verbosity `n`
+func verbosityOf(level string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "", InfoLevel:
+		return 0, nil
+	case ErrorLevel:
+		return -1, nil
+	case DebugLevel:
+		return 1, nil
+	default:
+		return 0, errs.Errorf("invalid log level '%s' (expected '%s', '%s' or '%s')", level, ErrorLevel, InfoLevel, DebugLevel)
+	}
+}
+
+// NewLogger returns a logger which only passes the messages to the given logger if their verbosity is enabled by the current
+// configuration (see `Configure`), so that the level of the logs can be changed without restarting the operator.
+// The errors are always logged.
+func NewLogger(delegate logr.Logger) logr.Logger {
+	return &levelLogger{delegate: delegate}
+}
+
+type levelLogger struct {
+	delegate logr.Logger
+	name     string
+}
+
+var _ logr.Logger = &levelLogger{}
+
+func (l *levelLogger) enabled(verbosity int) bool {
+	return verbosity <= currentVerbosities().of(l.name)
+}
+
+func (l *levelLogger) Info(msg string, keysAndValues ...interface{}) {
+	if l.enabled(0) {
+		l.delegate.Info(msg, keysAndValues...)
+	}
+}
+
+func (l *levelLogger) Enabled() bool {
+	return l.enabled(0) && l.delegate.Enabled()
+}
+
+func (l *levelLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.delegate.Error(err, msg, keysAndValues...)
+}
+
+func (l *levelLogger) V(level int) logr.InfoLogger {
+	return &levelInfoLogger{delegate: l.delegate.V(level), logger: l, verbosity: level}
+}
+
+func (l *levelLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &levelLogger{delegate: l.delegate.WithValues(keysAndValues...), name: l.name}
+}
+
+func (l *levelLogger) WithName(name string) logr.Logger {
+	fullName := name
+	if l.name != "" {
+		fullName = l.name + "." + name
+	}
+	return &levelLogger{delegate: l.delegate.WithName(name), name: fullName}
+}
+
+type levelInfoLogger struct {
+	delegate  logr.InfoLogger
+	logger    *levelLogger
+	verbosity int
+}
+
+func (l *levelInfoLogger) Info(msg string, keysAndValues ...interface{}) {
+	if l.logger.enabled(l.verbosity) {
+		l.delegate.Info(msg, keysAndValues...)
+	}
+}
+
+func (l *levelInfoLogger) Enabled() bool {
+	return l.logger.enabled(l.verbosity) && l.delegate.Enabled()
+}
@@ -0,0 +1,152 @@
+package logging_test
+
+import (
+	"errors"
+	"testing"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/logging"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestForRequest(t *testing.T) {
+	// given
+	lines := &[]line{}
+	logger := &recorder{lines: lines}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "toolchain-member-operator", Name: "johnsmith"}}
+
+	// when
+	logging.ForRequest(logger, request).Info("reconciling")
+	logging.ForRequest(logger, request).Info("reconciling")
+
+	// then
+	require.Len(t, *lines, 2)
+	first, second := (*lines)[0].values, (*lines)[1].values
+	assert.Equal(t, "toolchain-member-operator", first["Request.Namespace"])
+	assert.Equal(t, "johnsmith", first["Request.Name"])
+	require.Contains(t, first, logging.CorrelationIDKey)
+	assert.Len(t, first[logging.CorrelationIDKey], 16)
+	assert.NotEqual(t, first[logging.CorrelationIDKey], second[logging.CorrelationIDKey])
+}
+
+func TestLevels(t *testing.T) {
+	defer func() {
+		require.NoError(t, logging.Configure(memberv1alpha1.LoggingConfig{}))
+	}()
+	lines := &[]line{}
+	logger := logging.NewLogger(&recorder{lines: lines})
+	controllerLogger := logger.WithName("controller_nstemplateset").WithValues("Request.Name", "johnsmith")
+	templateLogger := controllerLogger.WithName("template")
+	logAll := func() []string {
+		*lines = nil
+		logger.Error(errors.New("mock error"), "default error")
+		logger.Info("default info")
+		logger.V(1).Info("default debug")
+		controllerLogger.Info("controller info")
+		controllerLogger.V(1).Info("controller debug")
+		templateLogger.V(1).Info("template debug")
+		msgs := make([]string, 0, len(*lines))
+		for _, l := range *lines {
+			msgs = append(msgs, l.msg)
+		}
+		return msgs
+	}
+
+	t.Run("info by default", func(t *testing.T) {
+		assert.Equal(t, []string{"default error", "default info", "controller info"}, logAll())
+		assert.True(t, logger.Enabled())
+		assert.False(t, logger.V(1).Enabled())
+	})
+
+	t.Run("debug", func(t *testing.T) {
+		// when
+		err := logging.Configure(memberv1alpha1.LoggingConfig{Level: "DEBUG"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default error", "default info", "default debug", "controller info", "controller debug", "template debug"}, logAll())
+		assert.True(t, logger.V(1).Enabled())
+	})
+
+	t.Run("errors only", func(t *testing.T) {
+		// when
+		err := logging.Configure(memberv1alpha1.LoggingConfig{Level: "error"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default error"}, logAll())
+		assert.False(t, logger.Enabled())
+	})
+
+	t.Run("level per logger", func(t *testing.T) {
+		// when
+		err := logging.Configure(memberv1alpha1.LoggingConfig{
+			Level:   "error",
+			Loggers: map[string]string{"controller_nstemplateset": "debug", "controller_nstemplateset.template": "info"},
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default error", "controller info", "controller debug"}, logAll())
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		// given
+		err := logging.Configure(memberv1alpha1.LoggingConfig{Level: "debug"})
+		require.NoError(t, err)
+
+		// when
+		err = logging.Configure(memberv1alpha1.LoggingConfig{Loggers: map[string]string{"controller_nstemplateset": "verbose"}})
+
+		// then the previous levels are left unchanged
+		require.EqualError(t, err, "invalid level of the 'controller_nstemplateset' logger: invalid log level 'verbose' (expected 'error', 'info' or 'debug')")
+		assert.Contains(t, logAll(), "default debug")
+	})
+}
+
+type line struct {
+	msg    string
+	values map[string]interface{}
+}
+
+// recorder a logger which records the logged lines along with their values
+type recorder struct {
+	lines  *[]line
+	values []interface{}
+}
+
+var _ logr.Logger = &recorder{}
+
+func (r *recorder) Info(msg string, keysAndValues ...interface{}) {
+	values := map[string]interface{}{}
+	all := append(append([]interface{}{}, r.values...), keysAndValues...)
+	for i := 0; i+1 < len(all); i += 2 {
+		values[all[i].(string)] = all[i+1]
+	}
+	*r.lines = append(*r.lines, line{msg: msg, values: values})
+}
+
+func (r *recorder) Enabled() bool {
+	return true
+}
+
+func (r *recorder) Error(err error, msg string, keysAndValues ...interface{}) {
+	r.Info(msg, append(keysAndValues, "error", err.Error())...)
+}
+
+func (r *recorder) V(level int) logr.InfoLogger {
+	return r
+}
+
+func (r *recorder) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &recorder{lines: r.lines, values: append(append([]interface{}{}, r.values...), keysAndValues...)}
+}
+
+func (r *recorder) WithName(name string) logr.Logger {
+	return r
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// zapLevel the minimum level of the messages encoded by the zap loggers returned by `NewZapLogger`. It is `info` unless a logger is
+// configured with the `debug` level (see `Configure`), so that the debug messages are not even passed to the encoder otherwise
+var zapLevel = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+// NewZapLogger returns a zap logger which writes to the standard error, either in JSON (production) or in a human-readable
+// format (development). Its level is adjusted when the levels of the logs are configured (see `Configure`)
+func NewZapLogger(development bool) logr.Logger {
+	var encoder zapcore.Encoder
+	var opts []zap.Option
+	if development {
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+		opts = append(opts, zap.Development(), zap.AddStacktrace(zapcore.ErrorLevel))
+	} else {
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+		opts = append(opts, zap.AddStacktrace(zapcore.WarnLevel))
+	}
+	sink := zapcore.Lock(os.Stderr)
+	opts = append(opts, zap.AddCallerSkip(1), zap.ErrorOutput(sink))
+	return zapr.NewLogger(zap.New(zapcore.NewCore(encoder, sink, zapLevel), opts...))
+}
+
+// setZapLevel sets the level of the zap loggers so that the messages of the given verbosity are encoded
+func setZapLevel(verbosity int) {
+	if verbosity <= 0 {
+		zapLevel.SetLevel(zapcore.InfoLevel)
+		return
+	}
+	// zapr logs the messages of `V(n)` with the level `-n`
+	zapLevel.SetLevel(zapcore.Level(-verbosity))
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"testing"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestZapLevel(t *testing.T) {
+	defer func() {
+		require.NoError(t, Configure(memberv1alpha1.LoggingConfig{}))
+	}()
+
+	t.Run("info by default", func(t *testing.T) {
+		assert.Equal(t, zapcore.InfoLevel, zapLevel.Level())
+	})
+
+	t.Run("debug", func(t *testing.T) {
+		// when
+		err := Configure(memberv1alpha1.LoggingConfig{Level: "debug"})
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, zapLevel.Enabled(zapcore.Level(-1)))
+	})
+
+	t.Run("debug for a single logger", func(t *testing.T) {
+		// when
+		err := Configure(memberv1alpha1.LoggingConfig{Level: "error", Loggers: map[string]string{"controller_nstemplateset": "debug"}})
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, zapLevel.Enabled(zapcore.Level(-1)))
+	})
+
+	t.Run("back to info", func(t *testing.T) {
+		// when
+		err := Configure(memberv1alpha1.LoggingConfig{Level: "error"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, zapcore.InfoLevel, zapLevel.Level())
+		assert.False(t, zapLevel.Enabled(zapcore.Level(-1)))
+	})
+}
@@ -0,0 +1,215 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	errs "github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("metrics")
+
+// The kinds of resources whose provisioning duration is recorded
+const (
+	UserAccountKind   = "UserAccount"
+	NSTemplateSetKind = "NSTemplateSet"
+)
+
+// DefaultUserNamespacesInterval the default interval between two counts of the user namespaces
+const DefaultUserNamespacesInterval = time.Minute
+
+// provisioningDuration observes the time taken to provision the UserAccounts and NSTemplateSets, ie, the time between their creation
+// (or the last time their `Ready` condition changed) and the time they became ready
+var provisioningDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "member_operator_provisioning_duration_seconds",
+	Help:    "Time taken to provision the UserAccounts and NSTemplateSets, per kind",
+	Buckets: []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600},
+}, []string{"kind"})
+
+// userNamespaces the number of namespaces owned by the users on the cluster
+var userNamespaces = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "member_operator_user_namespaces",
+	Help: "Number of namespaces owned by the users",
+})
+
+// idledWorkloads counts the workloads idled by the Idler controller, per kind
+var idledWorkloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "member_operator_idler_idled_workloads_total",
+	Help: "Number of workloads idled after running for longer than the timeout of their Idler, per kind",
+}, []string{"kind"})
+
+// apiRequests counts the requests sent by the operator to the API server, per status code class (eg: `2xx`, `429`)
+var apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "member_operator_api_requests_total",
+	Help: "Number of requests sent to the API server, per status code class (the throttled requests are counted apart as `429`)",
+}, []string{"code"})
+
+// clientThrottlingDuration measures how long the requests which were throttled by the client-side rate limiter of the operator waited
+// before being sent to the API server
+var clientThrottlingDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "member_operator_client_throttling_duration_seconds",
+	Help:    "Time spent by the requests throttled client-side (by the QPS and burst of the operator) before being sent to the API server",
+	Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30},
+})
+
+// controllerRequests and controllerThrottledRequests count the requests sent by each controller to create, update, patch or delete
+// objects, and those which waited for the API budget of the controller
+var controllerRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "member_operator_controller_api_requests_total",
+	Help: "Number of requests sent to the API server to create, update, patch or delete objects, per controller",
+}, []string{"controller"})
+var controllerThrottledRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "member_operator_controller_throttled_requests_total",
+	Help: "Number of requests which waited for the API budget of their controller, per controller",
+}, []string{"controller"})
+
+// sentRequests and throttledRequests count the requests sent to the API server and those which were throttled (ie, rejected with a
+// `429 Too Many Requests`), so that the throttling level can be checked without reading back the prometheus counters
+var sentRequests, throttledRequests uint64
+
+func init() {
+	crmetrics.Registry.MustRegister(provisioningDuration, userNamespaces, idledWorkloads, apiRequests, clientThrottlingDuration,
+		controllerRequests, controllerThrottledRequests)
+}
+
+// RecordProvisioned records the provisioning duration of a resource of the given kind with the given conditions, which is about to
+// become ready. Nothing is recorded if the resource is already ready.
+func RecordProvisioned(kind string, creationTimestamp metav1.Time, conditions []toolchainv1alpha1.Condition) {
+	start := creationTimestamp.Time
+	if ready, found := condition.FindConditionByType(conditions, toolchainv1alpha1.ConditionReady); found {
+		if ready.Status == corev1.ConditionTrue {
+			return
+		}
+		start = ready.LastTransitionTime.Time
+	}
+	if start.IsZero() {
+		return
+	}
+	provisioningDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+}
+
+// RecordIdled records a workload of the given kind idled by the Idler controller
+func RecordIdled(kind string) {
+	idledWorkloads.WithLabelValues(kind).Inc()
+}
+
+// WrapTransport wraps the given transport so that the requests sent to the API server and their status codes are counted.
+// It is meant to be set as the `WrapTransport` of the rest config of the operator
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &countingRoundTripper{delegate: rt}
+}
+
+// APIRequests returns the number of requests sent to the API server since the operator started, and how many of them were throttled
+func APIRequests() (sent, throttled uint64) {
+	return atomic.LoadUint64(&sentRequests), atomic.LoadUint64(&throttledRequests)
+}
+
+// RecordControllerRequest records a request sent by the given controller to create, update, patch or delete an object, and whether
+// it waited for the API budget of the controller
+func RecordControllerRequest(controller string, throttled bool) {
+	controllerRequests.WithLabelValues(controller).Inc()
+	if throttled {
+		controllerThrottledRequests.WithLabelValues(controller).Inc()
+	}
+}
+
+// NewRateLimiter returns a token bucket rate limiter with the given QPS and burst, which measures how long the throttled requests wait.
+// It is meant to be set as the `RateLimiter` of the rest config of the operator
+func NewRateLimiter(qps float32, burst int) flowcontrol.RateLimiter {
+	return &measuringRateLimiter{RateLimiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst)}
+}
+
+type measuringRateLimiter struct {
+	flowcontrol.RateLimiter
+}
+
+// Accept returns once a token is available, and records the time spent waiting for it if there was none available right away
+func (l *measuringRateLimiter) Accept() {
+	if l.RateLimiter.TryAccept() {
+		return
+	}
+	start := time.Now()
+	l.RateLimiter.Accept()
+	clientThrottlingDuration.Observe(time.Since(start).Seconds())
+}
+
+type countingRoundTripper struct {
+	delegate http.RoundTripper
+}
+
+// RoundTrip sends the given request with the delegate transport and counts it along with its status code
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.delegate.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	atomic.AddUint64(&sentRequests, 1)
+	code := fmt.Sprintf("%dxx", resp.StatusCode/100)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		atomic.AddUint64(&throttledRequests, 1)
+		code = "429"
+	}
+	apiRequests.WithLabelValues(code).Inc()
+	return resp, nil
+}
+
+// Add creates a new UserNamespacesCounter and adds it to the Manager
+func Add(mgr manager.Manager) error {
+	return mgr.Add(NewUserNamespacesCounter(mgr.GetClient(), DefaultUserNamespacesInterval))
+}
+
+// UserNamespacesCounter periodically counts the namespaces owned by the users (ie, which have an `owner` label) and publishes
+// the result in the `member_operator_user_namespaces` gauge
+type UserNamespacesCounter struct {
+	client   client.Client
+	interval time.Duration
+}
+
+// NewUserNamespacesCounter returns a new UserNamespacesCounter
+func NewUserNamespacesCounter(cl client.Client, interval time.Duration) *UserNamespacesCounter {
+	return &UserNamespacesCounter{
+		client:   cl,
+		interval: interval,
+	}
+}
+
+// Start counts the user namespaces at every interval, until the given channel is closed
+func (c *UserNamespacesCounter) Start(stop <-chan struct{}) error {
+	log.Info("starting the user namespaces counter", "interval", c.interval)
+	wait.Until(func() {
+		if err := c.Count(); err != nil {
+			log.Error(err, "failed to count the user namespaces")
+		}
+	}, c.interval, stop)
+	return nil
+}
+
+// Count counts the user namespaces and updates the gauge
+func (c *UserNamespacesCounter) Count() error {
+	namespaces := &corev1.NamespaceList{}
+	if err := c.client.List(context.TODO(), namespaces); err != nil {
+		return errs.Wrap(err, "failed to list the namespaces")
+	}
+	count := 0
+	for _, ns := range namespaces.Items {
+		if ns.Labels["owner"] != "" {
+			count++
+		}
+	}
+	userNamespaces.Set(float64(count))
+	return nil
+}
@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func TestRecordProvisioned(t *testing.T) {
+
+	t.Run("recorded since the creation", func(t *testing.T) {
+		// given
+		count := provisioned(t, "Test1")
+
+		// when
+		RecordProvisioned("Test1", metav1.NewTime(time.Now().Add(-10*time.Second)), nil)
+
+		// then
+		assert.Equal(t, count+1, provisioned(t, "Test1"))
+	})
+
+	t.Run("recorded since the last transition", func(t *testing.T) {
+		// given
+		count := provisioned(t, "Test2")
+		conditions := []toolchainv1alpha1.Condition{
+			{
+				Type:               toolchainv1alpha1.ConditionReady,
+				Status:             corev1.ConditionFalse,
+				Reason:             "Provisioning",
+				LastTransitionTime: metav1.NewTime(time.Now().Add(-5 * time.Second)),
+			},
+		}
+
+		// when
+		RecordProvisioned("Test2", metav1.NewTime(time.Now().Add(-time.Hour)), conditions)
+
+		// then
+		assert.Equal(t, count+1, provisioned(t, "Test2"))
+	})
+
+	t.Run("not recorded when already ready", func(t *testing.T) {
+		// given
+		count := provisioned(t, "Test3")
+		conditions := []toolchainv1alpha1.Condition{
+			{
+				Type:   toolchainv1alpha1.ConditionReady,
+				Status: corev1.ConditionTrue,
+				Reason: "Provisioned",
+			},
+		}
+
+		// when
+		RecordProvisioned("Test3", metav1.NewTime(time.Now().Add(-time.Hour)), conditions)
+
+		// then
+		assert.Equal(t, count, provisioned(t, "Test3"))
+	})
+}
+
+func TestRecordIdled(t *testing.T) {
+	// given
+	count := counter(t, "member_operator_idler_idled_workloads_total", "Deployment")
+
+	// when
+	RecordIdled("Deployment")
+
+	// then
+	assert.Equal(t, count+1, counter(t, "member_operator_idler_idled_workloads_total", "Deployment"))
+}
+
+func TestWrapTransport(t *testing.T) {
+	// given
+	sent, throttled := APIRequests()
+	rt := WrapTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/throttled":
+			return &http.Response{StatusCode: http.StatusTooManyRequests}, nil
+		case "/failed":
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}))
+
+	// when
+	for _, path := range []string{"/ok", "/throttled", "/ok", "/failed"} {
+		req, err := http.NewRequest(http.MethodGet, "https://api.cluster.example.com"+path, nil)
+		require.NoError(t, err)
+		_, _ = rt.RoundTrip(req)
+	}
+
+	// then
+	newSent, newThrottled := APIRequests()
+	assert.Equal(t, sent+3, newSent)
+	assert.Equal(t, throttled+1, newThrottled)
+}
+
+func TestRecordControllerRequest(t *testing.T) {
+	// given
+	requests := controllerCounter(t, "member_operator_controller_api_requests_total", "useraccount")
+	throttled := controllerCounter(t, "member_operator_controller_throttled_requests_total", "useraccount")
+
+	// when
+	RecordControllerRequest("useraccount", false)
+	RecordControllerRequest("useraccount", true)
+
+	// then
+	assert.Equal(t, requests+2, controllerCounter(t, "member_operator_controller_api_requests_total", "useraccount"))
+	assert.Equal(t, throttled+1, controllerCounter(t, "member_operator_controller_throttled_requests_total", "useraccount"))
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	// given
+	count := throttlingCount(t)
+	limiter := NewRateLimiter(100, 1)
+
+	// when
+	limiter.Accept()
+	limiter.Accept()
+
+	// then the second request waited for a token
+	assert.Equal(t, count+1, throttlingCount(t))
+	assert.Equal(t, float32(100), limiter.QPS())
+}
+
+// throttlingCount returns the number of requests which were throttled by the client-side rate limiter
+func throttlingCount(t *testing.T) uint64 {
+	for _, m := range metricsOf(t, "member_operator_client_throttling_duration_seconds") {
+		return m.GetHistogram().GetSampleCount()
+	}
+	return 0
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCountUserNamespaces(t *testing.T) {
+	// given
+	cl := test.NewFakeClient(t,
+		newNamespace("johnsmith-dev", "johnsmith"),
+		newNamespace("johnsmith-stage", "johnsmith"),
+		newNamespace("jane-dev", "jane"),
+		newNamespace("openshift-monitoring", ""))
+	c := NewUserNamespacesCounter(cl, time.Minute)
+
+	// when
+	err := c.Count()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, float64(3), gauge(t, "member_operator_user_namespaces"))
+
+	t.Run("list fails", func(t *testing.T) {
+		// given
+		cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			return errors.New("mock error")
+		}
+
+		// when
+		err := c.Count()
+
+		// then
+		require.EqualError(t, err, "failed to list the namespaces: mock error")
+		assert.Equal(t, float64(3), gauge(t, "member_operator_user_namespaces"))
+	})
+}
+
+func newNamespace(name, owner string) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if owner != "" {
+		ns.Labels = map[string]string{"owner": owner}
+	}
+	return ns
+}
+
+// provisioned returns the number of provisioning durations observed for the given kind
+func provisioned(t *testing.T, kind string) uint64 {
+	for _, m := range metricsOf(t, "member_operator_provisioning_duration_seconds") {
+		if labelValue(m.GetLabel(), "kind") == kind {
+			return m.GetHistogram().GetSampleCount()
+		}
+	}
+	return 0
+}
+
+// counter returns the current value of the given counter for the given kind
+func counter(t *testing.T, name, kind string) float64 {
+	for _, m := range metricsOf(t, name) {
+		if labelValue(m.GetLabel(), "kind") == kind {
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+// controllerCounter returns the current value of the given counter for the given controller
+func controllerCounter(t *testing.T, name, controller string) float64 {
+	for _, m := range metricsOf(t, name) {
+		if labelValue(m.GetLabel(), "controller") == controller {
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+// gauge returns the current value of the given gauge
+func gauge(t *testing.T, name string) float64 {
+	for _, m := range metricsOf(t, name) {
+		return m.GetGauge().GetValue()
+	}
+	return 0
+}
+
+// metricsOf returns the metrics of the given family, as served by the metrics endpoint of the manager
+func metricsOf(t *testing.T, name string) []*dto.Metric {
+	families, err := crmetrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()
+		}
+	}
+	return nil
+}
+
+func labelValue(labels []*dto.LabelPair, name string) string {
+	for _, l := range labels {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
@@ -1,4 +1,5 @@
-package template
+// Package nstemplatetier retrieves the templates of the NSTemplateTiers from the host cluster
+package nstemplatetier
 
 import (
 	"context"
@@ -51,3 +52,30 @@ func GetNSTemplates(hostClusterFunc cluster.GetHostClusterFunc, tierName string)
 	}
 	return result, nil
 }
+
+// FragmentGetter retrieves the shared fragments included by the templates of the tiers (see `template.IncludeAnnotation`), ie, the
+// Templates with the given names in the operator namespace of the host cluster
+type FragmentGetter struct {
+	hostClusterFunc cluster.GetHostClusterFunc
+}
+
+// NewFragmentGetter returns a new FragmentGetter which retrieves the fragments from the host cluster returned by the given func
+func NewFragmentGetter(hostClusterFunc cluster.GetHostClusterFunc) FragmentGetter {
+	return FragmentGetter{hostClusterFunc: hostClusterFunc}
+}
+
+// GetFragment gets the fragment with the given name from the host cluster
+func (g FragmentGetter) GetFragment(name string) (*templatev1.Template, error) {
+	host, ok := g.hostClusterFunc()
+	if !ok {
+		return nil, fmt.Errorf("unable to connect to the host cluster: unknown cluster")
+	}
+	if !util.IsClusterReady(host.ClusterStatus) {
+		return nil, fmt.Errorf("the host cluster is not ready")
+	}
+	fragment := &templatev1.Template{}
+	if err := host.Client.Get(context.TODO(), types.NamespacedName{Namespace: host.OperatorNamespace, Name: name}, fragment); err != nil {
+		return nil, errors.Wrapf(err, "unable to retrieve the fragment '%s' from 'Host' cluster", name)
+	}
+	return fragment, nil
+}
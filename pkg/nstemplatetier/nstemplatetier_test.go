@@ -1,11 +1,11 @@
-package template_test
+package nstemplatetier_test
 
 import (
 	"testing"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
 	"github.com/codeready-toolchain/member-operator/pkg/apis"
-	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/member-operator/pkg/nstemplatetier"
 	"github.com/codeready-toolchain/toolchain-common/pkg/cluster"
 
 	templatev1 "github.com/openshift/api/template/v1"
@@ -118,12 +118,12 @@ func TestGetNSTemplateTier(t *testing.T) {
 			Status: apiv1.ConditionTrue,
 		})
 		// when
-		tmpls, err := template.GetNSTemplates(hostCluster, "basic")
+		tmpls, err := nstemplatetier.GetNSTemplates(hostCluster, "basic")
 
 		// then
 		require.NoError(t, err)
 		require.Len(t, tmpls, 3)
-		assert.Equal(t, template.RevisionedTemplate{
+		assert.Equal(t, nstemplatetier.RevisionedTemplate{
 			Revision: "abcdef",
 			Template: templatev1.Template{
 				ObjectMeta: metav1.ObjectMeta{
@@ -131,7 +131,7 @@ func TestGetNSTemplateTier(t *testing.T) {
 				},
 			},
 		}, tmpls["code"])
-		assert.Equal(t, template.RevisionedTemplate{
+		assert.Equal(t, nstemplatetier.RevisionedTemplate{
 			Revision: "123456",
 			Template: templatev1.Template{
 				ObjectMeta: metav1.ObjectMeta{
@@ -139,7 +139,7 @@ func TestGetNSTemplateTier(t *testing.T) {
 				},
 			},
 		}, tmpls["dev"])
-		assert.Equal(t, template.RevisionedTemplate{
+		assert.Equal(t, nstemplatetier.RevisionedTemplate{
 			Revision: "1a2b3c",
 			Template: templatev1.Template{
 				ObjectMeta: metav1.ObjectMeta{
@@ -157,7 +157,7 @@ func TestGetNSTemplateTier(t *testing.T) {
 				return nil, false
 			}
 			// when
-			_, err := template.GetNSTemplates(hostCluster, "unknown")
+			_, err := nstemplatetier.GetNSTemplates(hostCluster, "unknown")
 			// then
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "unable to connect to the host cluster: unknown cluster")
@@ -170,7 +170,7 @@ func TestGetNSTemplateTier(t *testing.T) {
 				Status: apiv1.ConditionFalse,
 			})
 			// when
-			_, err := template.GetNSTemplates(hostCluster, "unknown")
+			_, err := nstemplatetier.GetNSTemplates(hostCluster, "unknown")
 			// then
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "the host cluster is not ready")
@@ -183,7 +183,7 @@ func TestGetNSTemplateTier(t *testing.T) {
 				Status: apiv1.ConditionTrue,
 			})
 			// when
-			_, err := template.GetNSTemplates(hostCluster, "unknown")
+			_, err := nstemplatetier.GetNSTemplates(hostCluster, "unknown")
 			// then
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "unable to retrieve the NSTemplateTier 'unknown' from 'Host' cluster")
@@ -196,7 +196,7 @@ func TestGetNSTemplateTier(t *testing.T) {
 				Status: apiv1.ConditionTrue,
 			})
 			// when
-			_, err := template.GetNSTemplates(hostCluster, "other")
+			_, err := nstemplatetier.GetNSTemplates(hostCluster, "other")
 			// then
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "unable to retrieve the NSTemplateTier 'other' from 'Host' cluster")
@@ -205,6 +205,52 @@ func TestGetNSTemplateTier(t *testing.T) {
 
 }
 
+func TestGetFragment(t *testing.T) {
+	// given
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	rbac := &templatev1.Template{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rbac-common",
+			Namespace: "toolchain-host-operator",
+		},
+	}
+	cl := fake.NewFakeClient(rbac)
+	ready := fedv1b1.ClusterCondition{
+		Type:   fedcommon.ClusterReady,
+		Status: apiv1.ConditionTrue,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		// when
+		fragment, err := nstemplatetier.NewFragmentGetter(newHostCluster(cl, ready)).GetFragment("rbac-common")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "rbac-common", fragment.Name)
+	})
+
+	t.Run("unknown fragment", func(t *testing.T) {
+		// when
+		_, err := nstemplatetier.NewFragmentGetter(newHostCluster(cl, ready)).GetFragment("unknown")
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to retrieve the fragment 'unknown' from 'Host' cluster")
+	})
+
+	t.Run("host cluster not ready", func(t *testing.T) {
+		// when
+		_, err := nstemplatetier.NewFragmentGetter(newHostCluster(cl, fedv1b1.ClusterCondition{
+			Type:   fedcommon.ClusterReady,
+			Status: apiv1.ConditionFalse,
+		})).GetFragment("rbac-common")
+
+		// then
+		require.EqualError(t, err, "the host cluster is not ready")
+	})
+}
+
 func newHostCluster(cl client.Client, condition fedv1b1.ClusterCondition) cluster.GetHostClusterFunc {
 	return func() (*cluster.FedCluster, bool) {
 		return &cluster.FedCluster{
@@ -0,0 +1,185 @@
+package orphans
+
+import (
+	"context"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	errs "github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("orphaned_namespaces_collector")
+
+const (
+	// OrphanedSinceAnnotation the annotation set on the user namespaces which have neither NSTemplateSet nor UserAccount,
+	// with the time (RFC3339) when they were found orphaned for the first time
+	OrphanedSinceAnnotation = "toolchain.dev.openshift.com/orphaned-since"
+
+	// DefaultInterval the default interval between two collections
+	DefaultInterval = 10 * time.Minute
+
+	orphanedEventReason       = "Orphaned"
+	deletedEventReason        = "OrphanDeleted"
+	deletionFailedEventReason = "OrphanDeletionFailed"
+)
+
+// orphanedNamespaces the number of user namespaces which have neither NSTemplateSet nor UserAccount, as of the last collection
+var orphanedNamespaces = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "member_operator_orphaned_namespaces",
+	Help: "Number of user namespaces which have neither NSTemplateSet nor UserAccount",
+})
+
+// orphanedNamespaceDeletions counts the deletions of the orphaned user namespaces, per result (`success` or `failure`)
+var orphanedNamespaceDeletions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "member_operator_orphaned_namespace_deletions_total",
+	Help: "Number of deletions of the orphaned user namespaces, per result",
+}, []string{"result"})
+
+func init() {
+	metrics.Registry.MustRegister(orphanedNamespaces, orphanedNamespaceDeletions)
+}
+
+// Add creates a new Collector and adds it to the Manager. The Collector only runs on the leader.
+func Add(mgr manager.Manager) error {
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	return mgr.Add(NewCollector(mgr.GetClient(), mgr.GetEventRecorderFor("orphaned-namespaces-collector"), namespace,
+		DefaultInterval, config.GetOrphanedNamespacesGracePeriod()))
+}
+
+// Collector periodically deletes the user namespaces (ie, the namespaces with an `owner` and a `type` label) whose owner has
+// neither NSTemplateSet nor UserAccount anymore, for example because the deletion of the namespace failed while the NSTemplateSet
+// was being deleted. The namespaces are first marked as orphaned with an annotation, and deleted once they remained orphaned
+// for longer than the grace period.
+type Collector struct {
+	client      client.Client
+	recorder    record.EventRecorder
+	namespace   string
+	interval    time.Duration
+	gracePeriod time.Duration
+}
+
+// NewCollector returns a new Collector for the NSTemplateSets and UserAccounts in the given namespace
+func NewCollector(cl client.Client, recorder record.EventRecorder, namespace string, interval, gracePeriod time.Duration) *Collector {
+	return &Collector{
+		client:      cl,
+		recorder:    recorder,
+		namespace:   namespace,
+		interval:    interval,
+		gracePeriod: gracePeriod,
+	}
+}
+
+// Start collects the orphaned namespaces at every interval, until the given channel is closed
+func (c *Collector) Start(stop <-chan struct{}) error {
+	log.Info("starting the orphaned namespaces collector", "interval", c.interval, "grace_period", c.gracePeriod)
+	wait.Until(func() {
+		if err := c.Collect(); err != nil {
+			log.Error(err, "failed to collect the orphaned namespaces")
+		}
+	}, c.interval, stop)
+	return nil
+}
+
+// Collect marks the user namespaces which became orphaned, unmarks those which have an owner again,
+// and deletes those which have been orphaned for longer than the grace period
+func (c *Collector) Collect() error {
+	owners, err := c.owners()
+	if err != nil {
+		return err
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := c.client.List(context.TODO(), namespaces); err != nil {
+		return errs.Wrap(err, "failed to list the namespaces")
+	}
+	orphaned := 0
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		owner, isUserNamespace := ns.Labels["owner"]
+		if !isUserNamespace || ns.Labels["type"] == "" || ns.DeletionTimestamp != nil {
+			continue
+		}
+		if owners[owner] {
+			if _, found := ns.Annotations[OrphanedSinceAnnotation]; found {
+				// the owner was (re)created in the mean time
+				log.Info("namespace is not orphaned anymore", "namespace", ns.Name)
+				delete(ns.Annotations, OrphanedSinceAnnotation)
+				if err := c.client.Update(context.TODO(), ns); err != nil {
+					log.Error(err, "failed to unmark the namespace", "namespace", ns.Name)
+				}
+			}
+			continue
+		}
+		orphaned++
+		if err := c.collect(ns, owner); err != nil {
+			// do not prevent the collection of the other namespaces
+			log.Error(err, "failed to collect the orphaned namespace", "namespace", ns.Name)
+		}
+	}
+	orphanedNamespaces.Set(float64(orphaned))
+	return nil
+}
+
+// owners returns the names of the NSTemplateSets and UserAccounts, ie, the users who can own namespaces
+func (c *Collector) owners() (map[string]bool, error) {
+	owners := map[string]bool{}
+	nsTmplSets := &toolchainv1alpha1.NSTemplateSetList{}
+	if err := c.client.List(context.TODO(), nsTmplSets, client.InNamespace(c.namespace)); err != nil {
+		return nil, errs.Wrap(err, "failed to list the NSTemplateSets")
+	}
+	for _, nsTmplSet := range nsTmplSets.Items {
+		owners[nsTmplSet.Name] = true
+	}
+	userAccs := &toolchainv1alpha1.UserAccountList{}
+	if err := c.client.List(context.TODO(), userAccs, client.InNamespace(c.namespace)); err != nil {
+		return nil, errs.Wrap(err, "failed to list the UserAccounts")
+	}
+	for _, userAcc := range userAccs.Items {
+		owners[userAcc.Name] = true
+	}
+	return owners, nil
+}
+
+// collect marks the given orphaned namespace, or deletes it if it was marked for longer than the grace period
+func (c *Collector) collect(ns *corev1.Namespace, owner string) error {
+	since, found := ns.Annotations[OrphanedSinceAnnotation]
+	orphanedSince, err := time.Parse(time.RFC3339, since)
+	if !found || err != nil {
+		log.Info("namespace is orphaned", "namespace", ns.Name, "owner", owner)
+		if ns.Annotations == nil {
+			ns.Annotations = map[string]string{}
+		}
+		ns.Annotations[OrphanedSinceAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		if err := c.client.Update(context.TODO(), ns); err != nil {
+			return errs.Wrapf(err, "failed to mark the namespace '%s' as orphaned", ns.Name)
+		}
+		c.recorder.Eventf(ns, corev1.EventTypeWarning, orphanedEventReason,
+			"neither NSTemplateSet nor UserAccount '%s' exists, the namespace will be deleted after %s", owner, c.gracePeriod)
+		return nil
+	}
+	if time.Since(orphanedSince) < c.gracePeriod {
+		return nil
+	}
+	log.Info("deleting orphaned namespace", "namespace", ns.Name, "owner", owner, "orphaned_since", since)
+	if err := c.client.Delete(context.TODO(), ns); err != nil && !apierrors.IsNotFound(err) {
+		orphanedNamespaceDeletions.WithLabelValues("failure").Inc()
+		c.recorder.Eventf(ns, corev1.EventTypeWarning, deletionFailedEventReason, "failed to delete the orphaned namespace: %s", err.Error())
+		return errs.Wrapf(err, "failed to delete the namespace '%s'", ns.Name)
+	}
+	orphanedNamespaceDeletions.WithLabelValues("success").Inc()
+	c.recorder.Eventf(ns, corev1.EventTypeNormal, deletedEventReason, "the namespace was orphaned since %s", since)
+	return nil
+}
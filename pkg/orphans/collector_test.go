@@ -0,0 +1,199 @@
+package orphans
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	namespaceName = "toolchain-member"
+	gracePeriod   = time.Hour
+)
+
+func TestCollect(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	t.Run("orphaned namespace marked", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet("johnsmith"), newUserAccount("jane"),
+			newUserNamespace("johnsmith", "dev", ""), newUserNamespace("jane", "dev", ""), newUserNamespace("bob", "dev", ""),
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "openshift-monitoring"}})
+		recorder := record.NewFakeRecorder(10)
+		c := NewCollector(cl, recorder, namespaceName, DefaultInterval, gracePeriod)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		assert.NotEmpty(t, getNamespace(t, cl, "bob-dev").Annotations[OrphanedSinceAnnotation])
+		assert.Empty(t, getNamespace(t, cl, "johnsmith-dev").Annotations[OrphanedSinceAnnotation])
+		assert.Empty(t, getNamespace(t, cl, "jane-dev").Annotations[OrphanedSinceAnnotation])
+		assert.Empty(t, getNamespace(t, cl, "openshift-monitoring").Annotations[OrphanedSinceAnnotation])
+		assert.Equal(t, float64(1), gaugeValue(t))
+		require.Len(t, recorder.Events, 1)
+		assert.Equal(t, "Warning Orphaned neither NSTemplateSet nor UserAccount 'bob' exists, the namespace will be deleted after 1h0m0s", <-recorder.Events)
+	})
+
+	t.Run("orphaned namespace kept during the grace period", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newUserNamespace("bob", "dev", time.Now().Add(-10*time.Minute).UTC().Format(time.RFC3339)))
+		recorder := record.NewFakeRecorder(10)
+		c := NewCollector(cl, recorder, namespaceName, DefaultInterval, gracePeriod)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		getNamespace(t, cl, "bob-dev")
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("orphaned namespace deleted after the grace period", func(t *testing.T) {
+		// given
+		since := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+		cl := test.NewFakeClient(t, newUserNamespace("bob", "dev", since))
+		recorder := record.NewFakeRecorder(10)
+		c := NewCollector(cl, recorder, namespaceName, DefaultInterval, gracePeriod)
+		deleted := counterValue(t, "success")
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: "bob-dev"}, &corev1.Namespace{})
+		assert.True(t, apierrors.IsNotFound(err))
+		assert.Equal(t, deleted+1, counterValue(t, "success"))
+		require.Len(t, recorder.Events, 1)
+		assert.Equal(t, "Normal OrphanDeleted the namespace was orphaned since "+since, <-recorder.Events)
+	})
+
+	t.Run("namespace not orphaned anymore", func(t *testing.T) {
+		// given
+		since := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+		cl := test.NewFakeClient(t, newNSTmplSet("bob"), newUserNamespace("bob", "dev", since))
+		c := NewCollector(cl, record.NewFakeRecorder(10), namespaceName, DefaultInterval, gracePeriod)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		ns := getNamespace(t, cl, "bob-dev")
+		assert.NotContains(t, ns.Annotations, OrphanedSinceAnnotation)
+		assert.Equal(t, float64(0), gaugeValue(t))
+	})
+
+	t.Run("deletion fails", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newUserNamespace("bob", "dev", time.Now().Add(-2*time.Hour).UTC().Format(time.RFC3339)),
+			newUserNamespace("alice", "dev", time.Now().Add(-2*time.Hour).UTC().Format(time.RFC3339)))
+		cl.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			if ns, ok := obj.(*corev1.Namespace); ok && ns.Name == "bob-dev" {
+				return errors.New("mock error")
+			}
+			return cl.Client.Delete(ctx, obj, opts...)
+		}
+		recorder := record.NewFakeRecorder(10)
+		c := NewCollector(cl, recorder, namespaceName, DefaultInterval, gracePeriod)
+		failed := counterValue(t, "failure")
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		getNamespace(t, cl, "bob-dev")
+		// the other namespaces are still collected
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: "alice-dev"}, &corev1.Namespace{})
+		assert.True(t, apierrors.IsNotFound(err))
+		assert.Equal(t, failed+1, counterValue(t, "failure"))
+		require.Len(t, recorder.Events, 2)
+		events := []string{<-recorder.Events, <-recorder.Events}
+		assert.Contains(t, events, "Warning OrphanDeletionFailed failed to delete the orphaned namespace: mock error")
+	})
+
+	t.Run("list fails", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			if _, ok := list.(*toolchainv1alpha1.UserAccountList); ok {
+				return errors.New("mock error")
+			}
+			return cl.Client.List(ctx, list, opts...)
+		}
+		c := NewCollector(cl, record.NewFakeRecorder(10), namespaceName, DefaultInterval, gracePeriod)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.EqualError(t, err, "failed to list the UserAccounts: mock error")
+	})
+}
+
+func newNSTmplSet(name string) *toolchainv1alpha1.NSTemplateSet {
+	return &toolchainv1alpha1.NSTemplateSet{ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: name}}
+}
+
+func newUserAccount(name string) *toolchainv1alpha1.UserAccount {
+	return &toolchainv1alpha1.UserAccount{ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: name}}
+}
+
+func newUserNamespace(owner, typeName, orphanedSince string) *corev1.Namespace {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   owner + "-" + typeName,
+			Labels: map[string]string{"owner": owner, "type": typeName},
+		},
+		Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+	}
+	if orphanedSince != "" {
+		ns.Annotations = map[string]string{OrphanedSinceAnnotation: orphanedSince}
+	}
+	return ns
+}
+
+func getNamespace(t *testing.T, cl client.Client, name string) *corev1.Namespace {
+	ns := &corev1.Namespace{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Name: name}, ns)
+	require.NoError(t, err)
+	return ns
+}
+
+func gaugeValue(t *testing.T) float64 {
+	m := &dto.Metric{}
+	err := orphanedNamespaces.Write(m)
+	require.NoError(t, err)
+	return m.GetGauge().GetValue()
+}
+
+func counterValue(t *testing.T, result string) float64 {
+	m := &dto.Metric{}
+	err := orphanedNamespaceDeletions.WithLabelValues(result).Write(m)
+	require.NoError(t, err)
+	return m.GetCounter().GetValue()
+}
@@ -0,0 +1,55 @@
+package pause
+
+import (
+	"strconv"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Annotation the annotation which pauses the reconciliation of a UserAccount or an NSTemplateSet when set to `true`,
+	// so that the resources of the user can be changed (eg, while debugging) without the operator reverting the changes
+	Annotation = "toolchain.dev.openshift.com/paused"
+
+	// ConditionType the type of the condition which reports whether the reconciliation of the resource is paused
+	ConditionType toolchainv1alpha1.ConditionType = "Paused"
+
+	// PausedReason the reason of the Paused condition when the reconciliation is paused
+	PausedReason = "Paused"
+	// ResumedReason the reason of the Paused condition when the reconciliation was resumed
+	ResumedReason = "Resumed"
+)
+
+// IsPaused returns true if the reconciliation of the given object is paused
+func IsPaused(obj metav1.Object) bool {
+	paused, _ := strconv.ParseBool(obj.GetAnnotations()[Annotation])
+	return paused
+}
+
+// PausedCondition returns the condition to set when the reconciliation is paused
+func PausedCondition() toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:    ConditionType,
+		Status:  corev1.ConditionTrue,
+		Reason:  PausedReason,
+		Message: "the reconciliation is paused by the '" + Annotation + "' annotation",
+	}
+}
+
+// ResumedConditions returns the condition to set when the reconciliation was resumed, if the given conditions say that
+// it was paused. Returns an empty slice otherwise, so that no Paused condition is added to resources which were never paused
+func ResumedConditions(conditions []toolchainv1alpha1.Condition) []toolchainv1alpha1.Condition {
+	if paused, found := condition.FindConditionByType(conditions, ConditionType); !found || paused.Status != corev1.ConditionTrue {
+		return nil
+	}
+	return []toolchainv1alpha1.Condition{
+		{
+			Type:   ConditionType,
+			Status: corev1.ConditionFalse,
+			Reason: ResumedReason,
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package pause
+
+import (
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsPaused(t *testing.T) {
+	for value, expected := range map[string]bool{"true": true, "True": true, "false": false, "": false, "yes": false} {
+		t.Run(value, func(t *testing.T) {
+			// given
+			nsTmplSet := &toolchainv1alpha1.NSTemplateSet{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{Annotation: value}},
+			}
+
+			// when
+			paused := IsPaused(nsTmplSet)
+
+			// then
+			assert.Equal(t, expected, paused)
+		})
+	}
+
+	t.Run("no annotation", func(t *testing.T) {
+		assert.False(t, IsPaused(&toolchainv1alpha1.UserAccount{}))
+	})
+}
+
+func TestResumedConditions(t *testing.T) {
+	t.Run("was paused", func(t *testing.T) {
+		// when
+		conditions := ResumedConditions([]toolchainv1alpha1.Condition{PausedCondition()})
+
+		// then
+		assert.Equal(t, []toolchainv1alpha1.Condition{{Type: ConditionType, Status: corev1.ConditionFalse, Reason: ResumedReason}}, conditions)
+	})
+
+	t.Run("already resumed", func(t *testing.T) {
+		// when
+		conditions := ResumedConditions([]toolchainv1alpha1.Condition{{Type: ConditionType, Status: corev1.ConditionFalse, Reason: ResumedReason}})
+
+		// then
+		assert.Empty(t, conditions)
+	})
+
+	t.Run("never paused", func(t *testing.T) {
+		// when
+		conditions := ResumedConditions([]toolchainv1alpha1.Condition{{Type: toolchainv1alpha1.ConditionReady, Status: corev1.ConditionTrue}})
+
+		// then
+		assert.Empty(t, conditions)
+	})
+}
@@ -1,6 +1,8 @@
 package predicate
 
 import (
+	"reflect"
+
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
@@ -49,3 +51,63 @@ func (OnlyUpdateWhenGenerationNotChanged) Delete(e event.DeleteEvent) bool {
 func (OnlyUpdateWhenGenerationNotChanged) Generic(e event.GenericEvent) bool {
 	return false
 }
+
+// AnnotationChanged implements an update predicate function which returns true when the value of the annotation
+// with the given key changed. Other predicate functions return false for all cases
+type AnnotationChanged struct {
+	Key string
+}
+
+// Update implements UpdateEvent filter for validating a change of the annotation
+func (p AnnotationChanged) Update(e event.UpdateEvent) bool {
+	if e.MetaOld == nil || e.MetaNew == nil {
+		log.Error(nil, "Update event has no old or new metadata", "event", e)
+		return false
+	}
+	return e.MetaOld.GetAnnotations()[p.Key] != e.MetaNew.GetAnnotations()[p.Key]
+}
+
+// Create implements Predicate
+func (AnnotationChanged) Create(e event.CreateEvent) bool {
+	return false
+}
+
+// Delete implements Predicate
+func (AnnotationChanged) Delete(e event.DeleteEvent) bool {
+	return false
+}
+
+// Generic implements Predicate
+func (AnnotationChanged) Generic(e event.GenericEvent) bool {
+	return false
+}
+
+// LabelsChangedOrCreatedDeleted implements an update predicate function which returns true when the labels changed,
+// as well as create and delete predicate functions which return true for all cases. The generic predicate function returns false.
+// This is useful to watch the objects whose status is frequently updated (such as the Nodes) when only their labels matter.
+type LabelsChangedOrCreatedDeleted struct {
+}
+
+// Update implements UpdateEvent filter for validating a change of the labels
+func (LabelsChangedOrCreatedDeleted) Update(e event.UpdateEvent) bool {
+	if e.MetaOld == nil || e.MetaNew == nil {
+		log.Error(nil, "Update event has no old or new metadata", "event", e)
+		return false
+	}
+	return !reflect.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels())
+}
+
+// Create implements Predicate
+func (LabelsChangedOrCreatedDeleted) Create(e event.CreateEvent) bool {
+	return true
+}
+
+// Delete implements Predicate
+func (LabelsChangedOrCreatedDeleted) Delete(e event.DeleteEvent) bool {
+	return true
+}
+
+// Generic implements Predicate
+func (LabelsChangedOrCreatedDeleted) Generic(e event.GenericEvent) bool {
+	return false
+}
@@ -98,3 +98,109 @@ func TestPredicateGenericShouldReturnFalse(t *testing.T) {
 	// then
 	assert.False(t, ok)
 }
+
+func TestAnnotationChangedPredicate(t *testing.T) {
+	annotationChanged := AnnotationChanged{Key: "toolchain.dev.openshift.com/space-roles"}
+
+	t.Run("update should return true as annotation changed", func(t *testing.T) {
+		// given
+		updateEvent := event.UpdateEvent{
+			MetaNew: &metav1.ObjectMeta{Annotations: map[string]string{"toolchain.dev.openshift.com/space-roles": "new"}},
+			MetaOld: &metav1.ObjectMeta{Annotations: map[string]string{"toolchain.dev.openshift.com/space-roles": "old"}},
+		}
+
+		// when
+		ok := annotationChanged.Update(updateEvent)
+
+		// then
+		assert.True(t, ok)
+	})
+
+	t.Run("update should return true as annotation added", func(t *testing.T) {
+		// given
+		updateEvent := event.UpdateEvent{
+			MetaNew: &metav1.ObjectMeta{Annotations: map[string]string{"toolchain.dev.openshift.com/space-roles": "new"}},
+			MetaOld: &metav1.ObjectMeta{},
+		}
+
+		// when
+		ok := annotationChanged.Update(updateEvent)
+
+		// then
+		assert.True(t, ok)
+	})
+
+	t.Run("update should return false as annotation not changed", func(t *testing.T) {
+		// given
+		updateEvent := event.UpdateEvent{
+			MetaNew: &metav1.ObjectMeta{Annotations: map[string]string{"toolchain.dev.openshift.com/space-roles": "same", "other": "new"}},
+			MetaOld: &metav1.ObjectMeta{Annotations: map[string]string{"toolchain.dev.openshift.com/space-roles": "same"}},
+		}
+
+		// when
+		ok := annotationChanged.Update(updateEvent)
+
+		// then
+		assert.False(t, ok)
+	})
+
+	t.Run("update should return false because of missing data", func(t *testing.T) {
+		// when
+		ok := annotationChanged.Update(event.UpdateEvent{})
+
+		// then
+		assert.False(t, ok)
+	})
+
+	t.Run("other events should return false", func(t *testing.T) {
+		assert.False(t, annotationChanged.Create(event.CreateEvent{}))
+		assert.False(t, annotationChanged.Delete(event.DeleteEvent{}))
+		assert.False(t, annotationChanged.Generic(event.GenericEvent{}))
+	})
+}
+
+func TestLabelsChangedOrCreatedDeletedPredicate(t *testing.T) {
+	labelsChanged := LabelsChangedOrCreatedDeleted{}
+
+	t.Run("update should return true as labels changed", func(t *testing.T) {
+		// given
+		updateEvent := event.UpdateEvent{
+			MetaNew: &metav1.ObjectMeta{Labels: map[string]string{"failure-domain.beta.kubernetes.io/zone": "us-east-1a"}},
+			MetaOld: &metav1.ObjectMeta{},
+		}
+
+		// when
+		ok := labelsChanged.Update(updateEvent)
+
+		// then
+		assert.True(t, ok)
+	})
+
+	t.Run("update should return false as labels not changed", func(t *testing.T) {
+		// given
+		updateEvent := event.UpdateEvent{
+			MetaNew: &metav1.ObjectMeta{Labels: map[string]string{"failure-domain.beta.kubernetes.io/zone": "us-east-1a"}, ResourceVersion: "2"},
+			MetaOld: &metav1.ObjectMeta{Labels: map[string]string{"failure-domain.beta.kubernetes.io/zone": "us-east-1a"}, ResourceVersion: "1"},
+		}
+
+		// when
+		ok := labelsChanged.Update(updateEvent)
+
+		// then
+		assert.False(t, ok)
+	})
+
+	t.Run("update should return false because of missing data", func(t *testing.T) {
+		// when
+		ok := labelsChanged.Update(event.UpdateEvent{})
+
+		// then
+		assert.False(t, ok)
+	})
+
+	t.Run("create and delete events should return true", func(t *testing.T) {
+		assert.True(t, labelsChanged.Create(event.CreateEvent{}))
+		assert.True(t, labelsChanged.Delete(event.DeleteEvent{}))
+		assert.False(t, labelsChanged.Generic(event.GenericEvent{}))
+	})
+}
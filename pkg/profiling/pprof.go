@@ -0,0 +1,46 @@
+package profiling
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("profiling")
+
+const shutdownTimeout = 5 * time.Second
+
+// NewHandler returns the handler of the pprof endpoints, under the `/debug/pprof/` path
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Serve serves the pprof endpoints on the given address until the given channel is closed.
+// The endpoints are not exposed by default (see the `--pprof-port` flag of the operator), since they reveal
+// the internals of the operator and can be expensive to call.
+func Serve(addr string, stop <-chan struct{}) {
+	server := &http.Server{Addr: addr, Handler: NewHandler()}
+	go func() {
+		log.Info("serving the pprof endpoints", "address", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error(err, "unable to serve the pprof endpoints")
+		}
+	}()
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error(err, "unable to stop serving the pprof endpoints")
+		}
+	}()
+}
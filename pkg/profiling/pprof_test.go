@@ -0,0 +1,27 @@
+package profiling_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/profiling"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	// given
+	handler := profiling.NewHandler()
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/goroutine"} {
+		t.Run(path, func(t *testing.T) {
+			// when
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			// then
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
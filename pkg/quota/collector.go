@@ -0,0 +1,137 @@
+package quota
+
+import (
+	"context"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/go-logr/logr"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("quota_collector")
+
+const (
+	// UsageHistoryAnnotation the annotation on the NSTemplateSet which holds the history of the quota utilization
+	// of the user namespaces
+	UsageHistoryAnnotation = "toolchain.dev.openshift.com/quota-usage-history"
+
+	// DefaultInterval the default interval between two samples
+	DefaultInterval = time.Hour
+	// DefaultHistorySize the default number of samples kept per namespace (ie, a day with the default interval)
+	DefaultHistorySize = config.DefaultQuotaUsageHistorySize
+	// DefaultMaxSize the default maximum size (in bytes) of the history annotation
+	DefaultMaxSize = config.DefaultAnnotationMaxSize
+)
+
+// Add creates a new quota usage Collector and adds it to the Manager. The Collector only runs on the leader.
+func Add(mgr manager.Manager) error {
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	return mgr.Add(NewCollector(mgr.GetClient(), namespace, DefaultInterval, config.GetQuotaUsageHistorySize(), config.GetAnnotationMaxSize()))
+}
+
+// Collector periodically samples the quota utilization of the user namespaces and records it
+// in an annotation of the corresponding NSTemplateSet. The history is bounded both by the number of samples
+// per namespace and by the size of the annotation, beyond which the oldest samples are dropped.
+type Collector struct {
+	client      client.Client
+	namespace   string
+	interval    time.Duration
+	historySize int
+	maxSize     int
+}
+
+// NewCollector returns a new Collector for the NSTemplateSets in the given namespace
+func NewCollector(cl client.Client, namespace string, interval time.Duration, historySize, maxSize int) *Collector {
+	return &Collector{
+		client:      cl,
+		namespace:   namespace,
+		interval:    interval,
+		historySize: historySize,
+		maxSize:     maxSize,
+	}
+}
+
+// Start collects the quota utilization at every interval, until the given channel is closed
+func (c *Collector) Start(stop <-chan struct{}) error {
+	log.Info("starting the quota usage collector", "interval", c.interval)
+	wait.Until(func() {
+		if err := c.Collect(); err != nil {
+			log.Error(err, "failed to collect the quota usage")
+		}
+	}, c.interval, stop)
+	return nil
+}
+
+// Collect samples the quota utilization of the namespaces of all the NSTemplateSets
+func (c *Collector) Collect() error {
+	nsTmplSets := &toolchainv1alpha1.NSTemplateSetList{}
+	if err := c.client.List(context.TODO(), nsTmplSets, client.InNamespace(c.namespace)); err != nil {
+		return errs.Wrap(err, "failed to list the NSTemplateSets")
+	}
+	now := metav1.Now()
+	for i := range nsTmplSets.Items {
+		nsTmplSet := &nsTmplSets.Items[i]
+		logger := log.WithValues("NSTemplateSet", nsTmplSet.Name)
+		if err := c.collect(logger, nsTmplSet, now); err != nil {
+			// do not prevent the collection for the other users
+			logger.Error(err, "failed to collect the quota usage")
+		}
+	}
+	return nil
+}
+
+func (c *Collector) collect(logger logr.Logger, nsTmplSet *toolchainv1alpha1.NSTemplateSet, now metav1.Time) error {
+	history, err := ParseUsageHistory(nsTmplSet.GetAnnotations()[UsageHistoryAnnotation])
+	if err != nil {
+		// start over with an empty history rather than being stuck with an invalid one
+		logger.Error(err, "resetting the quota usage history")
+		history = UsageHistory{}
+	}
+	userNamespaces := &corev1.NamespaceList{}
+	if err := c.client.List(context.TODO(), userNamespaces, client.MatchingLabels(map[string]string{"owner": nsTmplSet.Name})); err != nil {
+		return errs.Wrapf(err, "failed to list namespace with label owner '%s'", nsTmplSet.Name)
+	}
+	names := make([]string, 0, len(userNamespaces.Items))
+	for _, ns := range userNamespaces.Items {
+		quotas := &corev1.ResourceQuotaList{}
+		if err := c.client.List(context.TODO(), quotas, client.InNamespace(ns.Name)); err != nil {
+			return errs.Wrapf(err, "failed to list the resource quotas in namespace '%s'", ns.Name)
+		}
+		names = append(names, ns.Name)
+		history.Add(ns.Name, UsageSample{Time: now, Usage: UsagePercentages(quotas.Items)}, c.historySize)
+	}
+	history.Retain(names)
+	dropped, err := history.Compact(c.maxSize)
+	if err != nil {
+		return err
+	}
+	if dropped > 0 {
+		logger.Info("compacted the quota usage history", "dropped_samples", dropped)
+		template.RecordCompaction(UsageHistoryAnnotation)
+	}
+
+	content, err := history.String()
+	if err != nil {
+		return err
+	}
+	annotations := nsTmplSet.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[UsageHistoryAnnotation] = content
+	nsTmplSet.SetAnnotations(annotations)
+	return c.client.Update(context.TODO(), nsTmplSet)
+}
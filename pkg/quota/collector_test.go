@@ -0,0 +1,203 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	username      = "johnsmith"
+	namespaceName = "toolchain-member"
+)
+
+func TestCollect(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	t.Run("samples recorded", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(""), newUserNamespace("dev"), newUserNamespace("code"),
+			newResourceQuota("dev", "4", "1"), newResourceQuota("code", "4", "3"))
+		c := NewCollector(cl, namespaceName, DefaultInterval, 2, DefaultMaxSize)
+
+		// when
+		err := c.Collect()
+		require.NoError(t, err)
+		err = c.Collect()
+		require.NoError(t, err)
+		err = c.Collect()
+		require.NoError(t, err)
+
+		// then
+		history := getHistory(t, cl)
+		require.Len(t, history, 2)
+		require.Len(t, history[username+"-dev"], 2)
+		assert.Equal(t, 25, history[username+"-dev"][1].Usage[corev1.ResourcePods])
+		require.Len(t, history[username+"-code"], 2)
+		assert.Equal(t, 75, history[username+"-code"][1].Usage[corev1.ResourcePods])
+	})
+
+	t.Run("history of deleted namespace removed", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(`{"johnsmith-stage":[{"time":"2019-11-12T10:00:00Z","usage":{"pods":10}}]}`),
+			newUserNamespace("dev"), newResourceQuota("dev", "4", "1"))
+		c := NewCollector(cl, namespaceName, DefaultInterval, DefaultHistorySize, DefaultMaxSize)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		history := getHistory(t, cl)
+		require.Len(t, history, 1)
+		assert.Len(t, history[username+"-dev"], 1)
+	})
+
+	t.Run("history compacted", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(`{"johnsmith-dev":[{"time":"2019-11-12T10:00:00Z","usage":{"pods":10}},{"time":"2019-11-12T11:00:00Z","usage":{"pods":20}}]}`),
+			newUserNamespace("dev"), newResourceQuota("dev", "4", "1"))
+		c := NewCollector(cl, namespaceName, DefaultInterval, DefaultHistorySize, 100)
+		compactions := compactionsOf(t, UsageHistoryAnnotation)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		history := getHistory(t, cl)
+		require.Len(t, history[username+"-dev"], 1)
+		assert.Equal(t, 25, history[username+"-dev"][0].Usage[corev1.ResourcePods])
+		assert.Equal(t, compactions+1, compactionsOf(t, UsageHistoryAnnotation))
+	})
+
+	t.Run("invalid history reset", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet("{invalid"), newUserNamespace("dev"), newResourceQuota("dev", "4", "1"))
+		c := NewCollector(cl, namespaceName, DefaultInterval, DefaultHistorySize, DefaultMaxSize)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.NoError(t, err)
+		history := getHistory(t, cl)
+		assert.Len(t, history[username+"-dev"], 1)
+	})
+
+	t.Run("failed to list NSTemplateSets", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			return errors.New("mock error")
+		}
+		c := NewCollector(cl, namespaceName, DefaultInterval, DefaultHistorySize, DefaultMaxSize)
+
+		// when
+		err := c.Collect()
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list the NSTemplateSets")
+	})
+
+	t.Run("failed to list resource quotas", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newNSTmplSet(""), newUserNamespace("dev"))
+		cl.MockList = func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			if _, ok := list.(*corev1.ResourceQuotaList); ok {
+				return errors.New("mock error")
+			}
+			return cl.Client.List(ctx, list, opts...)
+		}
+		c := NewCollector(cl, namespaceName, DefaultInterval, DefaultHistorySize, DefaultMaxSize)
+
+		// when
+		err := c.Collect()
+
+		// then the failure is only logged, and the history is left untouched
+		require.NoError(t, err)
+		assert.Empty(t, getHistory(t, cl))
+	})
+}
+
+// compactionsOf returns the current value of the compactions counter for the given annotation
+func compactionsOf(t *testing.T, annotation string) float64 {
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "member_operator_annotation_compactions_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "annotation" && l.GetValue() == annotation {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func getHistory(t *testing.T, cl client.Client) UsageHistory {
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: username}, nsTmplSet)
+	require.NoError(t, err)
+	history, err := ParseUsageHistory(nsTmplSet.Annotations[UsageHistoryAnnotation])
+	require.NoError(t, err)
+	return history
+}
+
+func newNSTmplSet(history string) *toolchainv1alpha1.NSTemplateSet {
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      username,
+			Namespace: namespaceName,
+		},
+	}
+	if history != "" {
+		nsTmplSet.Annotations = map[string]string{UsageHistoryAnnotation: history}
+	}
+	return nsTmplSet
+}
+
+func newUserNamespace(typeName string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   username + "-" + typeName,
+			Labels: map[string]string{"owner": username, "type": typeName},
+		},
+	}
+}
+
+func newResourceQuota(typeName, hard, used string) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "compute-resources",
+			Namespace: username + "-" + typeName,
+		},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse(hard)},
+			Used: corev1.ResourceList{corev1.ResourcePods: resource.MustParse(used)},
+		},
+	}
+}
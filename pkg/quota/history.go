@@ -0,0 +1,125 @@
+package quota
+
+import (
+	"encoding/json"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// UsageSample the utilization of the quotas of a namespace at a given time, as a percentage of the hard limits
+type UsageSample struct {
+	Time  metav1.Time                 `json:"time"`
+	Usage map[corev1.ResourceName]int `json:"usage,omitempty"`
+}
+
+// UsageHistory the rolling history of the quota utilization samples, per namespace
+type UsageHistory map[string][]UsageSample
+
+// ParseUsageHistory parses the given (JSON) content into a UsageHistory. An empty content results in an empty UsageHistory
+func ParseUsageHistory(content string) (UsageHistory, error) {
+	history := UsageHistory{}
+	if content == "" {
+		return history, nil
+	}
+	if err := json.Unmarshal([]byte(content), &history); err != nil {
+		return nil, errs.Wrap(err, "unable to parse the quota usage history")
+	}
+	return history, nil
+}
+
+// String returns the JSON representation of the UsageHistory
+func (h UsageHistory) String() (string, error) {
+	content, err := json.Marshal(h)
+	if err != nil {
+		return "", errs.Wrap(err, "unable to marshal the quota usage history")
+	}
+	return string(content), nil
+}
+
+// Add appends the given sample to the history of the given namespace, dropping the oldest samples
+// so that no more than `size` samples are kept
+func (h UsageHistory) Add(namespace string, sample UsageSample, size int) {
+	samples := append(h[namespace], sample)
+	if len(samples) > size {
+		samples = samples[len(samples)-size:]
+	}
+	h[namespace] = samples
+}
+
+// Retain removes the history of all the namespaces which are not in the given list
+func (h UsageHistory) Retain(namespaces []string) {
+	for ns := range h {
+		if !contains(namespaces, ns) {
+			delete(h, ns)
+		}
+	}
+}
+
+// Compact drops the oldest samples, regardless of their namespace, until the JSON representation of the history
+// is no larger than `maxSize` bytes. Returns the number of dropped samples
+func (h UsageHistory) Compact(maxSize int) (int, error) {
+	dropped := 0
+	for {
+		content, err := h.String()
+		if err != nil {
+			return dropped, err
+		}
+		if len(content) <= maxSize {
+			return dropped, nil
+		}
+		oldest := ""
+		for ns, samples := range h {
+			if len(samples) == 0 {
+				delete(h, ns)
+				continue
+			}
+			if oldest == "" || samples[0].Time.Before(&h[oldest][0].Time) ||
+				(samples[0].Time.Equal(&h[oldest][0].Time) && ns < oldest) {
+				oldest = ns
+			}
+		}
+		if oldest == "" {
+			// nothing left to drop
+			return dropped, nil
+		}
+		if len(h[oldest]) == 1 {
+			delete(h, oldest)
+		} else {
+			h[oldest] = h[oldest][1:]
+		}
+		dropped++
+	}
+}
+
+// UsagePercentages returns the utilization of the given quotas, as a percentage of their hard limits.
+// When several quotas constrain the same resource, the highest utilization is retained
+func UsagePercentages(quotas []corev1.ResourceQuota) map[corev1.ResourceName]int {
+	usage := map[corev1.ResourceName]int{}
+	for _, q := range quotas {
+		for name, hard := range q.Status.Hard {
+			if hard.IsZero() {
+				continue
+			}
+			used, found := q.Status.Used[name]
+			if !found {
+				continue
+			}
+			percentage := int(used.MilliValue() * 100 / hard.MilliValue())
+			if current, exists := usage[name]; !exists || percentage > current {
+				usage[name] = percentage
+			}
+		}
+	}
+	return usage
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
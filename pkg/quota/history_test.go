@@ -0,0 +1,185 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUsageHistory(t *testing.T) {
+
+	t.Run("parse empty", func(t *testing.T) {
+		// when
+		history, err := ParseUsageHistory("")
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("parse invalid", func(t *testing.T) {
+		// when
+		_, err := ParseUsageHistory("{invalid")
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to parse the quota usage history")
+	})
+
+	t.Run("roundtrip", func(t *testing.T) {
+		// given
+		history := UsageHistory{}
+		history.Add("john-dev", UsageSample{
+			Time:  metav1.NewTime(time.Date(2019, 11, 12, 10, 0, 0, 0, time.UTC)),
+			Usage: map[corev1.ResourceName]int{corev1.ResourceLimitsCPU: 42},
+		}, 3)
+
+		// when
+		content, err := history.String()
+		require.NoError(t, err)
+		result, err := ParseUsageHistory(content)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, result["john-dev"], 1)
+		assert.Equal(t, 42, result["john-dev"][0].Usage[corev1.ResourceLimitsCPU])
+	})
+
+	t.Run("oldest samples dropped", func(t *testing.T) {
+		// given
+		history := UsageHistory{}
+
+		// when
+		for i := 0; i < 5; i++ {
+			history.Add("john-dev", UsageSample{Usage: map[corev1.ResourceName]int{corev1.ResourcePods: i}}, 3)
+		}
+
+		// then
+		require.Len(t, history["john-dev"], 3)
+		assert.Equal(t, 2, history["john-dev"][0].Usage[corev1.ResourcePods])
+		assert.Equal(t, 4, history["john-dev"][2].Usage[corev1.ResourcePods])
+	})
+
+	t.Run("retain", func(t *testing.T) {
+		// given
+		history := UsageHistory{}
+		history.Add("john-dev", UsageSample{}, 3)
+		history.Add("john-code", UsageSample{}, 3)
+
+		// when
+		history.Retain([]string{"john-dev"})
+
+		// then
+		assert.Len(t, history, 1)
+		assert.Contains(t, history, "john-dev")
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		newSample := func(hour, pods int) UsageSample {
+			return UsageSample{
+				Time:  metav1.NewTime(time.Date(2019, 11, 12, hour, 0, 0, 0, time.UTC)),
+				Usage: map[corev1.ResourceName]int{corev1.ResourcePods: pods},
+			}
+		}
+
+		t.Run("oldest samples dropped across namespaces", func(t *testing.T) {
+			// given
+			history := UsageHistory{}
+			history.Add("john-dev", newSample(10, 1), 3)
+			history.Add("john-code", newSample(11, 2), 3)
+			history.Add("john-dev", newSample(12, 3), 3)
+
+			// when
+			dropped, err := history.Compact(130)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, 1, dropped)
+			require.Len(t, history["john-dev"], 1)
+			assert.Equal(t, 3, history["john-dev"][0].Usage[corev1.ResourcePods])
+			require.Len(t, history["john-code"], 1)
+
+			t.Run("namespace without samples removed", func(t *testing.T) {
+				// when
+				dropped, err := history.Compact(100)
+
+				// then
+				require.NoError(t, err)
+				assert.Equal(t, 1, dropped)
+				assert.NotContains(t, history, "john-code")
+				assert.Len(t, history["john-dev"], 1)
+			})
+		})
+
+		t.Run("not compacted when small enough", func(t *testing.T) {
+			// given
+			history := UsageHistory{}
+			history.Add("john-dev", newSample(10, 1), 3)
+
+			// when
+			dropped, err := history.Compact(1024)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, 0, dropped)
+			assert.Len(t, history["john-dev"], 1)
+		})
+	})
+}
+
+func TestUsagePercentages(t *testing.T) {
+	// given
+	quotas := []corev1.ResourceQuota{
+		newQuota("compute", map[corev1.ResourceName]string{
+			corev1.ResourceLimitsCPU:    "2",
+			corev1.ResourceLimitsMemory: "1Gi",
+		}, map[corev1.ResourceName]string{
+			corev1.ResourceLimitsCPU:    "500m",
+			corev1.ResourceLimitsMemory: "768Mi",
+		}),
+		newQuota("objects", map[corev1.ResourceName]string{
+			corev1.ResourcePods:         "10",
+			corev1.ResourceLimitsCPU:    "1",
+			corev1.ResourceConfigMaps:   "0",
+			corev1.ResourceRequestsCPU:  "1",
+			corev1.ResourceLimitsMemory: "4Gi",
+		}, map[corev1.ResourceName]string{
+			corev1.ResourcePods:         "3",
+			corev1.ResourceLimitsCPU:    "500m",
+			corev1.ResourceConfigMaps:   "0",
+			corev1.ResourceLimitsMemory: "768Mi",
+		}),
+	}
+
+	// when
+	usage := UsagePercentages(quotas)
+
+	// then
+	assert.Equal(t, map[corev1.ResourceName]int{
+		corev1.ResourceLimitsCPU:    50, // highest of both quotas
+		corev1.ResourceLimitsMemory: 75,
+		corev1.ResourcePods:         30,
+	}, usage)
+}
+
+func newQuota(name string, hard, used map[corev1.ResourceName]string) corev1.ResourceQuota {
+	q := corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{},
+			Used: corev1.ResourceList{},
+		},
+	}
+	for n, v := range hard {
+		q.Status.Hard[n] = resource.MustParse(v)
+	}
+	for n, v := range used {
+		q.Status.Used[n] = resource.MustParse(v)
+	}
+	return q
+}
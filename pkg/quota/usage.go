@@ -0,0 +1,106 @@
+package quota
+
+import (
+	"encoding/json"
+	"sort"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// UsageAnnotation the annotation on the NSTemplateSet which holds the current consumption of the quotas of the user namespaces
+const UsageAnnotation = "toolchain.dev.openshift.com/quota-usage"
+
+// StorageUsageAnnotation the annotation on the NSTemplateSet which holds the current persistent storage consumption of the user,
+// per StorageClass
+const StorageUsageAnnotation = "toolchain.dev.openshift.com/storage-usage"
+
+// noStorageClass the key of the StorageUsage for the PersistentVolumeClaims which do not specify a StorageClass
+const noStorageClass = "none"
+
+// betaStorageClassAnnotation the (deprecated) annotation of the StorageClass of a PersistentVolumeClaim
+const betaStorageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+
+// QuotaUsage the hard limits and the current consumption of a ResourceQuota
+type QuotaUsage struct {
+	Name string              `json:"name"`
+	Hard corev1.ResourceList `json:"hard,omitempty"`
+	Used corev1.ResourceList `json:"used,omitempty"`
+}
+
+// Usage the consumption of the quotas, per namespace
+type Usage map[string][]QuotaUsage
+
+// ParseUsage parses the given (JSON) content into a Usage. An empty content results in an empty Usage
+func ParseUsage(content string) (Usage, error) {
+	usage := Usage{}
+	if content == "" {
+		return usage, nil
+	}
+	if err := json.Unmarshal([]byte(content), &usage); err != nil {
+		return nil, errs.Wrap(err, "unable to parse the quota usage")
+	}
+	return usage, nil
+}
+
+// String returns the JSON representation of the Usage
+func (u Usage) String() (string, error) {
+	content, err := json.Marshal(u)
+	if err != nil {
+		return "", errs.Wrap(err, "unable to marshal the quota usage")
+	}
+	return string(content), nil
+}
+
+// Set sets the consumption of the given quotas of the given namespace, sorted by name
+func (u Usage) Set(namespace string, quotas []corev1.ResourceQuota) {
+	usages := make([]QuotaUsage, 0, len(quotas))
+	for _, q := range quotas {
+		usages = append(usages, QuotaUsage{
+			Name: q.Name,
+			Hard: q.Status.Hard,
+			Used: q.Status.Used,
+		})
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Name < usages[j].Name
+	})
+	u[namespace] = usages
+}
+
+// StorageClassUsage the number of PersistentVolumeClaims and the total storage they request, for a StorageClass
+type StorageClassUsage struct {
+	Claims    int               `json:"claims"`
+	Requested resource.Quantity `json:"requested"`
+}
+
+// StorageUsage the persistent storage consumption of a user, per StorageClass (`none` for the claims which do not specify one)
+type StorageUsage map[string]StorageClassUsage
+
+// Add adds the given PersistentVolumeClaims to the storage consumption
+func (u StorageUsage) Add(claims []corev1.PersistentVolumeClaim) {
+	for _, pvc := range claims {
+		storageClass := noStorageClass
+		if className, found := pvc.Annotations[betaStorageClassAnnotation]; found {
+			storageClass = className
+		} else if pvc.Spec.StorageClassName != nil {
+			storageClass = *pvc.Spec.StorageClassName
+		}
+		usage := u[storageClass]
+		usage.Claims++
+		if requested, found := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; found {
+			usage.Requested.Add(requested)
+		}
+		u[storageClass] = usage
+	}
+}
+
+// String returns the JSON representation of the StorageUsage
+func (u StorageUsage) String() (string, error) {
+	content, err := json.Marshal(u)
+	if err != nil {
+		return "", errs.Wrap(err, "unable to marshal the storage usage")
+	}
+	return string(content), nil
+}
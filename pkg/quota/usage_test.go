@@ -0,0 +1,122 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUsage(t *testing.T) {
+
+	t.Run("set and marshal", func(t *testing.T) {
+		// given
+		usage := Usage{}
+		quotas := []corev1.ResourceQuota{
+			newQuota("objects", map[corev1.ResourceName]string{corev1.ResourcePods: "10"}, map[corev1.ResourceName]string{corev1.ResourcePods: "3"}),
+			newQuota("compute", map[corev1.ResourceName]string{corev1.ResourceLimitsCPU: "2"}, map[corev1.ResourceName]string{corev1.ResourceLimitsCPU: "500m"}),
+		}
+
+		// when
+		usage.Set("johnsmith-dev", quotas)
+		content, err := usage.String()
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, `{"johnsmith-dev":[`+
+			`{"name":"compute","hard":{"limits.cpu":"2"},"used":{"limits.cpu":"500m"}},`+
+			`{"name":"objects","hard":{"pods":"10"},"used":{"pods":"3"}}]}`, content)
+
+		t.Run("parse", func(t *testing.T) {
+			// when
+			parsed, err := ParseUsage(content)
+
+			// then
+			require.NoError(t, err)
+			require.Len(t, parsed["johnsmith-dev"], 2)
+			used := parsed["johnsmith-dev"][0].Used[corev1.ResourceLimitsCPU]
+			assert.Equal(t, "500m", used.String())
+		})
+	})
+
+	t.Run("set without quotas", func(t *testing.T) {
+		// given
+		usage := Usage{}
+
+		// when
+		usage.Set("johnsmith-dev", nil)
+		content, err := usage.String()
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, `{"johnsmith-dev":[]}`, content)
+	})
+
+	t.Run("parse empty content", func(t *testing.T) {
+		// when
+		usage, err := ParseUsage("")
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, usage)
+	})
+
+	t.Run("parse invalid content", func(t *testing.T) {
+		// when
+		_, err := ParseUsage("{invalid")
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to parse the quota usage")
+	})
+}
+
+func TestStorageUsage(t *testing.T) {
+
+	t.Run("add and marshal", func(t *testing.T) {
+		// given
+		usage := StorageUsage{}
+		claims := []corev1.PersistentVolumeClaim{
+			newClaim("data", "gp2", "1Gi"),
+			newClaim("logs", "gp2", "500Mi"),
+			newClaim("cache", "", "100Mi"),
+		}
+		claims[2].Annotations = map[string]string{"volume.beta.kubernetes.io/storage-class": "standard"}
+
+		// when
+		usage.Add(claims)
+		usage.Add([]corev1.PersistentVolumeClaim{newClaim("tmp", "", "")})
+		content, err := usage.String()
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, `{"gp2":{"claims":2,"requested":"1524Mi"},`+
+			`"none":{"claims":1,"requested":"0"},`+
+			`"standard":{"claims":1,"requested":"100Mi"}}`, content)
+	})
+
+	t.Run("marshal without claims", func(t *testing.T) {
+		// when
+		content, err := StorageUsage{}.String()
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, `{}`, content)
+	})
+}
+
+func newClaim(name, storageClass, requested string) corev1.PersistentVolumeClaim {
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: name},
+	}
+	if storageClass != "" {
+		pvc.Spec.StorageClassName = &storageClass
+	}
+	if requested != "" {
+		pvc.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(requested)}
+	}
+	return pvc
+}
@@ -0,0 +1,142 @@
+// Package render renders the templates of a tier from local files, without any connection to a cluster,
+// so that the tier authors can review and diff the objects which would be provisioned for a user
+package render
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	templatev1 "github.com/openshift/api/template/v1"
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+)
+
+// Options the settings of the rendering
+type Options struct {
+	// TemplatesDir the directory which contains the templates of the tiers, in files named `<tier>-<type>.yaml`
+	// (eg: `basic-dev.yaml` or `basic-clusterresources.yaml`)
+	TemplatesDir string
+	// Tier the name of the tier to render
+	Tier string
+	// Username the name of the user for whom the templates are rendered
+	Username string
+	// Types the types of the templates to render. All the templates of the tier are rendered if it is empty
+	Types []string
+}
+
+// Render processes the templates of the tier with the `USERNAME` parameter, the same way as the NSTemplateSet controller,
+// and writes the resulting objects to the given writer as a stream of YAML documents. The templates are rendered in the order
+// of their types, with the cluster resources last, since they are provisioned once all the namespaces exist.
+func Render(out io.Writer, opts Options) error {
+	if opts.Tier == "" || opts.Username == "" {
+		return fmt.Errorf("the tier and the username are required")
+	}
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		return errs.Wrap(err, "failed to initialize the scheme")
+	}
+	files, err := templateFiles(opts)
+	if err != nil {
+		return err
+	}
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	encoder := json.NewYAMLSerializer(json.DefaultMetaFactory, nil, nil)
+	// the templates are only processed, hence the processor needs no client
+	processor := template.NewProcessor(nil, s)
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file.path)
+		if err != nil {
+			return errs.Wrapf(err, "failed to read the template of type '%s'", file.typeName)
+		}
+		tmpl := &templatev1.Template{}
+		if _, _, err := decoder.Decode(content, nil, tmpl); err != nil {
+			return errs.Wrapf(err, "failed to decode the template of type '%s'", file.typeName)
+		}
+		objs, err := processor.Process(tmpl, map[string]string{"USERNAME": opts.Username})
+		if err != nil {
+			return errs.Wrapf(err, "failed to process the template of type '%s'", file.typeName)
+		}
+		for _, rawObj := range objs {
+			obj, err := toUnstructured(rawObj)
+			if err != nil {
+				return errs.Wrapf(err, "failed to decode an object of the template of type '%s'", file.typeName)
+			}
+			if _, err := fmt.Fprintf(out, "---\n# Source: %s\n", filepath.Base(file.path)); err != nil {
+				return err
+			}
+			if err := encoder.Encode(obj, out); err != nil {
+				return errs.Wrapf(err, "failed to encode the %s '%s'", obj.GetKind(), obj.GetName())
+			}
+		}
+	}
+	return nil
+}
+
+type templateFile struct {
+	typeName string
+	path     string
+}
+
+// templateFiles returns the files of the templates to render, sorted by type with the cluster resources last
+func templateFiles(opts Options) ([]templateFile, error) {
+	paths, err := filepath.Glob(filepath.Join(opts.TemplatesDir, opts.Tier+"-*.yaml"))
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to list the templates")
+	}
+	found := map[string]string{}
+	for _, path := range paths {
+		found[strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), opts.Tier+"-"), ".yaml")] = path
+	}
+	types := opts.Types
+	if len(types) == 0 {
+		for typeName := range found {
+			types = append(types, typeName)
+		}
+	}
+	var files []templateFile
+	for _, typeName := range types {
+		path, ok := found[typeName]
+		if !ok {
+			return nil, fmt.Errorf("no template of type '%s' for tier '%s' in '%s'", typeName, opts.Tier, opts.TemplatesDir)
+		}
+		files = append(files, templateFile{typeName: typeName, path: path})
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no template for tier '%s' in '%s'", opts.Tier, opts.TemplatesDir)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if (files[i].typeName == template.ClusterResourcesType) != (files[j].typeName == template.ClusterResourcesType) {
+			return files[j].typeName == template.ClusterResourcesType
+		}
+		return files[i].typeName < files[j].typeName
+	})
+	return files, nil
+}
+
+// toUnstructured returns the given processed object as an Unstructured
+func toUnstructured(rawObj runtime.RawExtension) (*unstructured.Unstructured, error) {
+	if obj, ok := rawObj.Object.(*unstructured.Unstructured); ok {
+		return obj, nil
+	}
+	content := rawObj.Raw
+	if len(content) == 0 {
+		var err error
+		if content, err = runtime.Encode(unstructured.UnstructuredJSONScheme, rawObj.Object); err != nil {
+			return nil, err
+		}
+	}
+	obj := &unstructured.Unstructured{}
+	if _, _, err := unstructured.UnstructuredJSONScheme.Decode(content, nil, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
@@ -0,0 +1,74 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the templates used by the tests of the NSTemplateSet controller
+const templatesDir = "../controller/nstemplateset/test-files"
+
+func TestRender(t *testing.T) {
+
+	t.Run("all templates of the tier", func(t *testing.T) {
+		// given
+		out := &bytes.Buffer{}
+
+		// when
+		err := Render(out, Options{TemplatesDir: templatesDir, Tier: "basic", Username: "jsmith"})
+
+		// then
+		require.NoError(t, err)
+		rendered := out.String()
+		assert.Contains(t, rendered, "name: jsmith-dev")
+		assert.Contains(t, rendered, "name: jsmith-code")
+		assert.NotContains(t, rendered, "${USERNAME}")
+		// the cluster resources are rendered last
+		assert.True(t, strings.Index(rendered, "# Source: basic-dev.yaml") < strings.Index(rendered, "# Source: basic-clusterresources.yaml"))
+		assert.True(t, strings.Index(rendered, "# Source: basic-code.yaml") < strings.Index(rendered, "# Source: basic-dev.yaml"))
+	})
+
+	t.Run("single type", func(t *testing.T) {
+		// given
+		out := &bytes.Buffer{}
+
+		// when
+		err := Render(out, Options{TemplatesDir: templatesDir, Tier: "basic", Username: "jsmith", Types: []string{"dev"}})
+
+		// then
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "name: jsmith-dev")
+		assert.NotContains(t, out.String(), "# Source: basic-code.yaml")
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("unknown type", func(t *testing.T) {
+			// when
+			err := Render(&bytes.Buffer{}, Options{TemplatesDir: templatesDir, Tier: "basic", Username: "jsmith", Types: []string{"stage"}})
+
+			// then
+			require.EqualError(t, err, "no template of type 'stage' for tier 'basic' in '"+templatesDir+"'")
+		})
+
+		t.Run("unknown tier", func(t *testing.T) {
+			// when
+			err := Render(&bytes.Buffer{}, Options{TemplatesDir: templatesDir, Tier: "advanced", Username: "jsmith"})
+
+			// then
+			require.EqualError(t, err, "no template for tier 'advanced' in '"+templatesDir+"'")
+		})
+
+		t.Run("missing username", func(t *testing.T) {
+			// when
+			err := Render(&bytes.Buffer{}, Options{TemplatesDir: templatesDir, Tier: "basic"})
+
+			// then
+			require.EqualError(t, err, "the tier and the username are required")
+		})
+	})
+}
@@ -0,0 +1,154 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/conditions"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	errs "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CompleteReason the reason of the condition when the rollout of the workload is complete
+	CompleteReason = "RolloutComplete"
+	// InProgressReason the reason of the condition while the workload is rolling out
+	InProgressReason = "RollingOut"
+	// TimedOutReason the reason of the condition when the workload is still not rolled out once the timeout expired
+	TimedOutReason = "RolloutTimedOut"
+
+	// CheckPeriod the period after which the progress of a rollout is checked again, since the changes of the status of the workloads
+	// do not trigger any reconciliation
+	CheckPeriod = 15 * time.Second
+)
+
+// Progress the progress of the rollout of a workload
+type Progress struct {
+	// Done true if the rollout is complete
+	Done bool
+	// Message the details of the rollout while it is not complete
+	Message string
+	// Failed true if the rollout cannot complete anymore (eg, if the progress deadline of a Deployment was exceeded)
+	Failed bool
+}
+
+// Complete the progress of a rolled out workload
+var Complete = Progress{Done: true}
+
+// DeploymentProgress returns the progress of the rollout of the given Deployment: it is complete once its latest generation is observed
+// and all its replicas are updated and available
+func DeploymentProgress(deployment *appsv1.Deployment) Progress {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	for _, cond := range deployment.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded" {
+			return Progress{Message: fmt.Sprintf("the progress deadline of the deployment was exceeded: %s", cond.Message), Failed: true}
+		}
+	}
+	switch {
+	case deployment.Status.ObservedGeneration < deployment.Generation:
+		return Progress{Message: "the latest version of the deployment is not observed yet"}
+	case deployment.Status.UpdatedReplicas < replicas:
+		return Progress{Message: fmt.Sprintf("%d out of %d replicas are updated", deployment.Status.UpdatedReplicas, replicas)}
+	case deployment.Status.AvailableReplicas < replicas:
+		return Progress{Message: fmt.Sprintf("%d out of %d replicas are available", deployment.Status.AvailableReplicas, replicas)}
+	default:
+		return Complete
+	}
+}
+
+// RouteProgress returns the progress of the rollout of the given Route: it is complete once it is admitted by a router
+func RouteProgress(route *unstructured.Unstructured) Progress {
+	ingresses, _, _ := unstructured.NestedSlice(route.Object, "status", "ingress")
+	for _, ingress := range ingresses {
+		conds, _, _ := unstructured.NestedSlice(ingress.(map[string]interface{}), "conditions")
+		for _, c := range conds {
+			cond := c.(map[string]interface{})
+			if cond["type"] != "Admitted" {
+				continue
+			}
+			if cond["status"] == string(corev1.ConditionTrue) {
+				return Complete
+			}
+			return Progress{Message: fmt.Sprintf("the route was not admitted: %v", cond["message"]), Failed: true}
+		}
+	}
+	return Progress{Message: "the route is not admitted by a router yet"}
+}
+
+// Combine returns the progress of the rollout of several objects, along with their descriptions: it is complete once all of them are
+// rolled out
+func Combine(progresses map[string]Progress) Progress {
+	var messages []string
+	failed := false
+	for obj, progress := range progresses {
+		if progress.Done {
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s", obj, progress.Message))
+		failed = failed || progress.Failed
+	}
+	if len(messages) == 0 {
+		return Complete
+	}
+	// the map is not ordered
+	sort.Strings(messages)
+	return Progress{Message: strings.Join(messages, "; "), Failed: failed}
+}
+
+// Condition returns the condition of the given type which reflects the given progress: `True` once the rollout is complete, `False`
+// with the `RollingOut` reason while it is in progress, or with the `RolloutTimedOut` reason if it failed or if it is still in progress
+// after the given timeout (since the existing condition of the same type became `False`)
+func Condition(existing []toolchainv1alpha1.Condition, condType toolchainv1alpha1.ConditionType, progress Progress, timeout time.Duration) toolchainv1alpha1.Condition {
+	if progress.Done {
+		return toolchainv1alpha1.Condition{Type: condType, Status: corev1.ConditionTrue, Reason: CompleteReason}
+	}
+	reason := InProgressReason
+	if cond, found := condition.FindConditionByType(existing, condType); progress.Failed ||
+		(found && cond.Status == corev1.ConditionFalse && time.Since(cond.LastTransitionTime.Time) > timeout) {
+		reason = TimedOutReason
+	}
+	return toolchainv1alpha1.Condition{Type: condType, Status: corev1.ConditionFalse, Reason: reason, Message: progress.Message}
+}
+
+// UpdateConfigStatus sets the condition of the given type which reflects the given progress on the MemberOperatorConfig of the given
+// namespace, or removes it if the progress is nil (eg, when the workload is not deployed). Does nothing if there is no MemberOperatorConfig.
+// Returns the duration after which the progress should be checked again, or `0` if the rollout is complete
+func UpdateConfigStatus(cl client.Client, namespace string, condType toolchainv1alpha1.ConditionType, progress *Progress, timeout time.Duration) (time.Duration, error) {
+	cfg := &memberv1alpha1.MemberOperatorConfig{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: memberv1alpha1.MemberOperatorConfigName}, cfg); err != nil {
+		if errors.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, errs.Wrap(err, "failed to get the MemberOperatorConfig")
+	}
+	var updated bool
+	if progress == nil {
+		cfg.Status.Conditions, updated = conditions.Remove(cfg.Status.Conditions, condType)
+	} else {
+		cfg.Status.Conditions, updated = conditions.Update(cfg.Status.Conditions, Condition(cfg.Status.Conditions, condType, *progress, timeout))
+	}
+	if updated {
+		if err := cl.Status().Update(context.TODO(), cfg); err != nil {
+			return 0, errs.Wrapf(err, "failed to update the %s condition of the MemberOperatorConfig", condType)
+		}
+	}
+	if progress == nil || progress.Done {
+		return 0, nil
+	}
+	return CheckPeriod, nil
+}
@@ -0,0 +1,288 @@
+package rollout_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/rollout"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	namespace = "toolchain-member-operator"
+	condType  = memberv1alpha1.MemberConsoleReadyCondition
+)
+
+func TestDeploymentProgress(t *testing.T) {
+	replicas := int32(2)
+
+	for _, tc := range []struct {
+		name     string
+		status   appsv1.DeploymentStatus
+		expected rollout.Progress
+	}{
+		{
+			name:     "latest generation not observed",
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 2, AvailableReplicas: 2},
+			expected: rollout.Progress{Message: "the latest version of the deployment is not observed yet"},
+		},
+		{
+			name:     "replicas not updated",
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 1, AvailableReplicas: 2},
+			expected: rollout.Progress{Message: "1 out of 2 replicas are updated"},
+		},
+		{
+			name:     "replicas not available",
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 2, AvailableReplicas: 1},
+			expected: rollout.Progress{Message: "1 out of 2 replicas are available"},
+		},
+		{
+			name: "progress deadline exceeded",
+			status: appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 1, Conditions: []appsv1.DeploymentCondition{{
+				Type:    appsv1.DeploymentProgressing,
+				Status:  corev1.ConditionFalse,
+				Reason:  "ProgressDeadlineExceeded",
+				Message: "ReplicaSet \"member-console-5d4f\" has timed out progressing.",
+			}}},
+			expected: rollout.Progress{
+				Message: "the progress deadline of the deployment was exceeded: ReplicaSet \"member-console-5d4f\" has timed out progressing.",
+				Failed:  true,
+			},
+		},
+		{
+			name:     "rolled out",
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			expected: rollout.Complete,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+				Status:     tc.status,
+			}
+
+			// when
+			progress := rollout.DeploymentProgress(deployment)
+
+			// then
+			assert.Equal(t, tc.expected, progress)
+		})
+	}
+}
+
+func TestRouteProgress(t *testing.T) {
+
+	t.Run("not admitted yet", func(t *testing.T) {
+		assert.Equal(t, rollout.Progress{Message: "the route is not admitted by a router yet"}, rollout.RouteProgress(newRoute()))
+	})
+
+	t.Run("admitted", func(t *testing.T) {
+		assert.Equal(t, rollout.Complete, rollout.RouteProgress(newRoute(map[string]interface{}{"type": "Admitted", "status": "True"})))
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		// given
+		route := newRoute(map[string]interface{}{"type": "Admitted", "status": "False", "message": "route host already claimed"})
+
+		// when
+		progress := rollout.RouteProgress(route)
+
+		// then
+		assert.Equal(t, rollout.Progress{Message: "the route was not admitted: route host already claimed", Failed: true}, progress)
+	})
+}
+
+func TestCombine(t *testing.T) {
+
+	t.Run("all rolled out", func(t *testing.T) {
+		assert.Equal(t, rollout.Complete, rollout.Combine(map[string]rollout.Progress{"deployment": rollout.Complete, "route": rollout.Complete}))
+	})
+
+	t.Run("some rolling out", func(t *testing.T) {
+		// when
+		progress := rollout.Combine(map[string]rollout.Progress{
+			"route":      {Message: "not admitted", Failed: true},
+			"deployment": {Message: "0 out of 1 replicas are updated"},
+			"service":    rollout.Complete,
+		})
+
+		// then
+		assert.Equal(t, rollout.Progress{Message: "deployment: 0 out of 1 replicas are updated; route: not admitted", Failed: true}, progress)
+	})
+}
+
+func TestCondition(t *testing.T) {
+	timeout := 10 * time.Minute
+	rollingOut := rollout.Progress{Message: "0 out of 1 replicas are updated"}
+
+	t.Run("complete", func(t *testing.T) {
+		// when
+		cond := rollout.Condition(nil, condType, rollout.Complete, timeout)
+
+		// then
+		assert.Equal(t, toolchainv1alpha1.Condition{Type: condType, Status: corev1.ConditionTrue, Reason: rollout.CompleteReason}, cond)
+	})
+
+	t.Run("rolling out", func(t *testing.T) {
+		// given
+		existing := []toolchainv1alpha1.Condition{newCondition(corev1.ConditionFalse, time.Now().Add(-time.Minute))}
+
+		// when
+		cond := rollout.Condition(existing, condType, rollingOut, timeout)
+
+		// then
+		assert.Equal(t, toolchainv1alpha1.Condition{Type: condType, Status: corev1.ConditionFalse, Reason: rollout.InProgressReason, Message: rollingOut.Message}, cond)
+	})
+
+	t.Run("rolling out again after it was complete", func(t *testing.T) {
+		// given
+		existing := []toolchainv1alpha1.Condition{newCondition(corev1.ConditionTrue, time.Now().Add(-time.Hour))}
+
+		// when
+		cond := rollout.Condition(existing, condType, rollingOut, timeout)
+
+		// then
+		assert.Equal(t, rollout.InProgressReason, cond.Reason)
+	})
+
+	t.Run("timed out", func(t *testing.T) {
+		// given
+		existing := []toolchainv1alpha1.Condition{newCondition(corev1.ConditionFalse, time.Now().Add(-time.Hour))}
+
+		// when
+		cond := rollout.Condition(existing, condType, rollingOut, timeout)
+
+		// then
+		assert.Equal(t, toolchainv1alpha1.Condition{Type: condType, Status: corev1.ConditionFalse, Reason: rollout.TimedOutReason, Message: rollingOut.Message}, cond)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		// when
+		cond := rollout.Condition(nil, condType, rollout.Progress{Message: "deadline exceeded", Failed: true}, timeout)
+
+		// then
+		assert.Equal(t, rollout.TimedOutReason, cond.Reason)
+	})
+}
+
+func TestUpdateConfigStatus(t *testing.T) {
+	s := scheme.Scheme
+	err := apis.AddToScheme(s)
+	require.NoError(t, err)
+	timeout := 10 * time.Minute
+
+	t.Run("condition set while rolling out", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newConfig())
+
+		// when
+		requeueAfter, err := rollout.UpdateConfigStatus(cl, namespace, condType, &rollout.Progress{Message: "rolling out"}, timeout)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, rollout.CheckPeriod, requeueAfter)
+		conds := getConditions(t, cl)
+		require.Len(t, conds, 1)
+		assert.Equal(t, rollout.InProgressReason, conds[0].Reason)
+		assert.Equal(t, "rolling out", conds[0].Message)
+
+		t.Run("condition set once rolled out", func(t *testing.T) {
+			// when
+			requeueAfter, err := rollout.UpdateConfigStatus(cl, namespace, condType, &rollout.Complete, timeout)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, time.Duration(0), requeueAfter)
+			conds := getConditions(t, cl)
+			require.Len(t, conds, 1)
+			assert.Equal(t, corev1.ConditionTrue, conds[0].Status)
+			assert.Equal(t, rollout.CompleteReason, conds[0].Reason)
+
+			t.Run("condition removed", func(t *testing.T) {
+				// when
+				requeueAfter, err := rollout.UpdateConfigStatus(cl, namespace, condType, nil, timeout)
+
+				// then
+				require.NoError(t, err)
+				assert.Equal(t, time.Duration(0), requeueAfter)
+				assert.Empty(t, getConditions(t, cl))
+			})
+		})
+	})
+
+	t.Run("no config", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		requeueAfter, err := rollout.UpdateConfigStatus(cl, namespace, condType, &rollout.Progress{Message: "rolling out"}, timeout)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), requeueAfter)
+	})
+
+	t.Run("update fails", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, newConfig())
+		cl.MockStatusUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			return errors.New("mock error")
+		}
+
+		// when
+		_, err := rollout.UpdateConfigStatus(cl, namespace, condType, &rollout.Complete, timeout)
+
+		// then
+		require.EqualError(t, err, "failed to update the MemberConsoleReady condition of the MemberOperatorConfig: mock error")
+	})
+}
+
+func newRoute(conds ...interface{}) *unstructured.Unstructured {
+	route := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	route.SetAPIVersion("route.openshift.io/v1")
+	route.SetKind("Route")
+	if len(conds) > 0 {
+		ingress := map[string]interface{}{"host": "member-console.apps.example.com", "conditions": conds}
+		route.Object["status"] = map[string]interface{}{"ingress": []interface{}{ingress}}
+	}
+	return route
+}
+
+func newCondition(status corev1.ConditionStatus, lastTransitionTime time.Time) toolchainv1alpha1.Condition {
+	return toolchainv1alpha1.Condition{
+		Type:               condType,
+		Status:             status,
+		LastTransitionTime: metav1.NewTime(lastTransitionTime),
+	}
+}
+
+func newConfig() *memberv1alpha1.MemberOperatorConfig {
+	return &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: memberv1alpha1.MemberOperatorConfigName},
+	}
+}
+
+func getConditions(t *testing.T, cl client.Client) []toolchainv1alpha1.Condition {
+	cfg := &memberv1alpha1.MemberOperatorConfig{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: memberv1alpha1.MemberOperatorConfigName}, cfg)
+	require.NoError(t, err)
+	return cfg.Status.Conditions
+}
@@ -0,0 +1,65 @@
+package shutdown
+
+import (
+	"sync/atomic"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("shutdown")
+
+var (
+	// initiated `1` once the shutdown of the operator is initiated
+	initiated int32
+	// inFlightReconciles the number of reconciliations in progress, which are awaited when the operator shuts down
+	inFlightReconciles int64
+	// pollInterval the interval between two checks of the reconciliations in progress during the shutdown
+	pollInterval = 100 * time.Millisecond
+)
+
+// InitiateOnStop initiates the shutdown as soon as the given channel is closed, ie, when the operator receives a SIGTERM
+func InitiateOnStop(stop <-chan struct{}) {
+	go func() {
+		<-stop
+		Initiate()
+	}()
+}
+
+// Initiate marks the beginning of the shutdown of the operator: the long operations stop at their next safe point
+func Initiate() {
+	if atomic.CompareAndSwapInt32(&initiated, 0, 1) {
+		log.Info("shutdown initiated", "in_flight_reconciles", atomic.LoadInt64(&inFlightReconciles))
+	}
+}
+
+// Initiated returns `true` if the shutdown of the operator was initiated
+func Initiated() bool {
+	return atomic.LoadInt32(&initiated) == 1
+}
+
+// Reset cancels the shutdown. It is meant to be used by the tests
+func Reset() {
+	atomic.StoreInt32(&initiated, 0)
+}
+
+// TrackReconcile marks the beginning of a reconciliation and returns the func to call once it is complete
+func TrackReconcile() func() {
+	atomic.AddInt64(&inFlightReconciles, 1)
+	return func() {
+		atomic.AddInt64(&inFlightReconciles, -1)
+	}
+}
+
+// WaitForInFlightReconciles waits until the reconciliations in progress are complete, or until the given timeout expires.
+// Returns `false` if some reconciliations were still in progress when the timeout expired
+func WaitForInFlightReconciles(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&inFlightReconciles) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package shutdown_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/shutdown"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitiateOnStop(t *testing.T) {
+	defer shutdown.Reset()
+
+	// given
+	stop := make(chan struct{})
+	shutdown.InitiateOnStop(stop)
+	assert.False(t, shutdown.Initiated())
+
+	// when
+	close(stop)
+
+	// then
+	assert.Eventually(t, shutdown.Initiated, time.Second, 10*time.Millisecond)
+}
+
+func TestWaitForInFlightReconciles(t *testing.T) {
+
+	t.Run("no reconcile in progress", func(t *testing.T) {
+		assert.True(t, shutdown.WaitForInFlightReconciles(time.Second))
+	})
+
+	t.Run("reconcile in progress", func(t *testing.T) {
+		// given
+		done := shutdown.TrackReconcile()
+
+		t.Run("timeout expired", func(t *testing.T) {
+			assert.False(t, shutdown.WaitForInFlightReconciles(200*time.Millisecond))
+		})
+
+		t.Run("reconcile complete", func(t *testing.T) {
+			// when
+			done()
+
+			// then
+			assert.True(t, shutdown.WaitForInFlightReconciles(time.Second))
+		})
+	})
+}
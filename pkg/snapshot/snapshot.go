@@ -0,0 +1,249 @@
+// Package snapshot collects all the objects managed by the operator for a user into a single YAML bundle, for the support
+// escalations and the user data export requests
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	userv1 "github.com/openshift/api/user/v1"
+	errs "github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("snapshot")
+
+const (
+	// Annotation the annotation of the UserAccount which requests a snapshot of the resources of the user. A new snapshot is taken
+	// each time its value changes, hence the value is typically the number of the support ticket or a timestamp
+	Annotation = "toolchain.dev.openshift.com/snapshot"
+	// RequestAnnotation the annotation of the snapshot ConfigMap which holds the value of the request annotation it was taken for
+	RequestAnnotation = "toolchain.dev.openshift.com/snapshot-request"
+	// UserLabel the label of the snapshot ConfigMaps which holds the name of the user
+	UserLabel = "toolchain.dev.openshift.com/snapshot-of"
+	// BundleKey the key of the data of the snapshot ConfigMaps which holds the YAML bundle
+	BundleKey = "bundle.yaml"
+
+	configMapPrefix = "snapshot-"
+	// maxBundleSize the maximum size of a bundle, which has to fit in a ConfigMap (1MiB including the metadata)
+	maxBundleSize = 1000 * 1024
+	redacted      = "<redacted>"
+)
+
+// ConfigMapName returns the name of the ConfigMap which holds the snapshot of the given user
+func ConfigMapName(user string) string {
+	return configMapPrefix + user
+}
+
+// IsRequested returns true if the snapshot requested by the annotation of the given user account was not taken yet, according
+// to the given snapshot ConfigMap (which may be nil)
+func IsRequested(userAcc *toolchainv1alpha1.UserAccount, cm *corev1.ConfigMap) bool {
+	request := userAcc.Annotations[Annotation]
+	if request == "" {
+		return false
+	}
+	return cm == nil || cm.Annotations[RequestAnnotation] != request
+}
+
+// Take takes the snapshot of the resources of the given user account if it is requested by its annotation and was not taken yet,
+// and stores it in a ConfigMap owned by the user account, so that it is deleted along with it. Returns true if a snapshot was taken
+func Take(cl client.Client, scheme *runtime.Scheme, userAcc *toolchainv1alpha1.UserAccount) (bool, error) {
+	if userAcc.Annotations[Annotation] == "" {
+		return false, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: userAcc.Namespace, Name: ConfigMapName(userAcc.Name)}, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, errs.Wrap(err, "unable to get the snapshot")
+		}
+		cm = nil
+	}
+	if !IsRequested(userAcc, cm) {
+		return false, nil
+	}
+	bundle, err := Collect(cl, scheme, userAcc)
+	if err != nil {
+		return false, err
+	}
+	if len(bundle) > maxBundleSize {
+		return false, fmt.Errorf("the snapshot of user '%s' is too large: %d bytes", userAcc.Name, len(bundle))
+	}
+	request := userAcc.Annotations[Annotation]
+	if cm == nil {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   userAcc.Namespace,
+				Name:        ConfigMapName(userAcc.Name),
+				Labels:      map[string]string{UserLabel: userAcc.Name},
+				Annotations: map[string]string{RequestAnnotation: request},
+			},
+			Data: map[string]string{BundleKey: bundle},
+		}
+		if err := controllerutil.SetControllerReference(userAcc, cm, scheme); err != nil {
+			return false, errs.Wrap(err, "unable to set the owner of the snapshot")
+		}
+		if err := cl.Create(context.TODO(), cm); err != nil {
+			return false, errs.Wrap(err, "unable to create the snapshot")
+		}
+	} else {
+		if cm.Annotations == nil {
+			cm.Annotations = map[string]string{}
+		}
+		cm.Annotations[RequestAnnotation] = request
+		cm.Data = map[string]string{BundleKey: bundle}
+		if err := cl.Update(context.TODO(), cm); err != nil {
+			return false, errs.Wrap(err, "unable to update the snapshot")
+		}
+	}
+	log.Info("snapshot taken", "user", userAcc.Name, "request", request, "size", len(bundle))
+	return true, nil
+}
+
+// Collect returns the objects managed by the operator for the given user account as a stream of YAML documents, in a stable order:
+// the UserAccount, the User, the Identities and the Groups, then the NSTemplateSet, the namespaces and the objects of its inventory.
+// The data of the Secrets is redacted.
+func Collect(cl client.Client, scheme *runtime.Scheme, userAcc *toolchainv1alpha1.UserAccount) (string, error) {
+	c := &collector{client: cl, scheme: scheme, seen: map[string]bool{}}
+	if err := c.add(userAcc); err != nil {
+		return "", err
+	}
+	user := &userv1.User{}
+	if err := c.get(types.NamespacedName{Name: userAcc.Name}, user); err != nil {
+		return "", err
+	}
+	owned := client.MatchingLabels(map[string]string{"owner": userAcc.Name})
+	identities := &userv1.IdentityList{}
+	if err := cl.List(context.TODO(), identities, owned); err != nil {
+		return "", errs.Wrap(err, "unable to list the identities")
+	}
+	for i := range identities.Items {
+		if err := c.add(&identities.Items[i]); err != nil {
+			return "", err
+		}
+	}
+	groups := &userv1.GroupList{}
+	if err := cl.List(context.TODO(), groups); err != nil {
+		return "", errs.Wrap(err, "unable to list the groups")
+	}
+	for i := range groups.Items {
+		if !contains(groups.Items[i].Users, userAcc.Name) {
+			continue
+		}
+		if err := c.add(&groups.Items[i]); err != nil {
+			return "", err
+		}
+	}
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	if err := c.get(types.NamespacedName{Namespace: userAcc.Namespace, Name: userAcc.Name}, nsTmplSet); err != nil {
+		return "", err
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := cl.List(context.TODO(), namespaces, owned); err != nil {
+		return "", errs.Wrap(err, "unable to list the namespaces")
+	}
+	for i := range namespaces.Items {
+		if err := c.add(&namespaces.Items[i]); err != nil {
+			return "", err
+		}
+	}
+	inv, err := template.ParseInventory(nsTmplSet.GetAnnotations()[template.InventoryAnnotation])
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range inv.Entries {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(entry.APIVersion)
+		obj.SetKind(entry.Kind)
+		if err := c.get(types.NamespacedName{Namespace: entry.Namespace, Name: entry.Name}, obj); err != nil {
+			return "", err
+		}
+	}
+	return c.out.String(), nil
+}
+
+// collector encodes the collected objects, skipping the ones which were already collected
+type collector struct {
+	client client.Client
+	scheme *runtime.Scheme
+	seen   map[string]bool
+	out    bytes.Buffer
+}
+
+// get fetches the object with the given name and adds it to the bundle. Missing objects are skipped
+func (c *collector) get(name types.NamespacedName, obj runtime.Object) error {
+	if err := c.client.Get(context.TODO(), name, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errs.Wrapf(err, "unable to get '%s'", name.String())
+	}
+	return c.add(obj)
+}
+
+// add adds the given object to the bundle
+func (c *collector) add(obj runtime.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return errs.Wrapf(err, "unable to convert the %s", gvk.Kind)
+		}
+		u = &unstructured.Unstructured{Object: content}
+	}
+	u.SetGroupVersionKind(gvk)
+	key := gvk.String() + "/" + u.GetNamespace() + "/" + u.GetName()
+	if c.seen[key] {
+		return nil
+	}
+	c.seen[key] = true
+	if gvk.Group == "" && gvk.Kind == "Secret" {
+		redact(u, "data")
+		redact(u, "stringData")
+	}
+	if _, err := fmt.Fprintln(&c.out, "---"); err != nil {
+		return err
+	}
+	if err := json.NewYAMLSerializer(json.DefaultMetaFactory, nil, nil).Encode(u, &c.out); err != nil {
+		return errs.Wrapf(err, "unable to encode the %s '%s'", gvk.Kind, u.GetName())
+	}
+	return nil
+}
+
+// redact replaces the values of the given field of the given object
+func redact(obj *unstructured.Unstructured, field string) {
+	values, found, _ := unstructured.NestedMap(obj.Object, field)
+	if !found {
+		return
+	}
+	for key := range values {
+		values[key] = redacted
+	}
+	_ = unstructured.SetNestedMap(obj.Object, values, field)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
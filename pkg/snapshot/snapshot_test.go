@@ -0,0 +1,197 @@
+package snapshot_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/member-operator/pkg/snapshot"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	userv1 "github.com/openshift/api/user/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	operatorNamespace = "toolchain-member-operator"
+	username          = "johnsmith"
+)
+
+func TestCollect(t *testing.T) {
+	// given
+	s := newScheme(t)
+	userAcc := newUserAccount("")
+	objs := userObjects(t)
+
+	t.Run("all the objects of the user", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, objs...)
+
+		// when
+		bundle, err := snapshot.Collect(cl, s, userAcc)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"kind: UserAccount/johnsmith",
+			"kind: User/johnsmith",
+			"kind: Identity/rhd:12345",
+			"kind: Group/crw-users",
+			"kind: NSTemplateSet/johnsmith",
+			"kind: Namespace/johnsmith-dev",
+			"kind: Secret/credentials",
+		}, kinds(bundle))
+		assert.Contains(t, bundle, "password: <redacted>")
+		assert.NotContains(t, bundle, "c2VjcmV0")
+		assert.NotContains(t, bundle, "janedoe-dev")
+	})
+
+	t.Run("missing objects skipped", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		bundle, err := snapshot.Collect(cl, s, userAcc)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"kind: UserAccount/johnsmith"}, kinds(bundle))
+	})
+}
+
+func TestTake(t *testing.T) {
+	// given
+	s := newScheme(t)
+
+	t.Run("snapshot not requested", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, userObjects(t)...)
+
+		// when
+		taken, err := snapshot.Take(cl, s, newUserAccount(""))
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, taken)
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: snapshot.ConfigMapName(username)}, &corev1.ConfigMap{})
+		assert.Error(t, err)
+	})
+
+	t.Run("snapshot taken once per request", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, userObjects(t)...)
+		userAcc := newUserAccount("TICKET-1")
+
+		// when
+		taken, err := snapshot.Take(cl, s, userAcc)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, taken)
+		cm := getSnapshot(t, cl)
+		assert.Equal(t, "TICKET-1", cm.Annotations[snapshot.RequestAnnotation])
+		assert.Equal(t, username, cm.Labels[snapshot.UserLabel])
+		assert.Contains(t, cm.Data[snapshot.BundleKey], "kind: NSTemplateSet")
+		require.Len(t, cm.OwnerReferences, 1)
+		assert.Equal(t, "UserAccount", cm.OwnerReferences[0].Kind)
+
+		t.Run("same request ignored", func(t *testing.T) {
+			// when
+			taken, err := snapshot.Take(cl, s, userAcc)
+
+			// then
+			require.NoError(t, err)
+			assert.False(t, taken)
+		})
+
+		t.Run("new request taken", func(t *testing.T) {
+			// when
+			taken, err := snapshot.Take(cl, s, newUserAccount("TICKET-2"))
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, taken)
+			assert.Equal(t, "TICKET-2", getSnapshot(t, cl).Annotations[snapshot.RequestAnnotation])
+		})
+	})
+}
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	s := scheme.Scheme
+	require.NoError(t, apis.AddToScheme(s))
+	return s
+}
+
+func newUserAccount(request string) *toolchainv1alpha1.UserAccount {
+	userAcc := &toolchainv1alpha1.UserAccount{
+		ObjectMeta: metav1.ObjectMeta{Namespace: operatorNamespace, Name: username, UID: "johnsmith-uid"},
+		Spec:       toolchainv1alpha1.UserAccountSpec{UserID: "12345"},
+	}
+	if request != "" {
+		userAcc.Annotations = map[string]string{snapshot.Annotation: request}
+	}
+	return userAcc
+}
+
+func userObjects(t *testing.T) []runtime.Object {
+	inv := template.NewInventory()
+	inv.Entries = []template.InventoryEntry{
+		{APIVersion: "v1", Kind: "Namespace", Name: "johnsmith-dev"},
+		{APIVersion: "v1", Kind: "Secret", Namespace: "johnsmith-dev", Name: "credentials"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "johnsmith-dev", Name: "deleted"},
+	}
+	content, err := inv.String()
+	require.NoError(t, err)
+	return []runtime.Object{
+		&userv1.User{ObjectMeta: metav1.ObjectMeta{Name: username}},
+		&userv1.Identity{ObjectMeta: metav1.ObjectMeta{Name: "rhd:12345", Labels: map[string]string{"owner": username}}},
+		&userv1.Identity{ObjectMeta: metav1.ObjectMeta{Name: "rhd:67890", Labels: map[string]string{"owner": "janedoe"}}},
+		&userv1.Group{ObjectMeta: metav1.ObjectMeta{Name: "crw-users"}, Users: userv1.OptionalNames{"janedoe", username}},
+		&userv1.Group{ObjectMeta: metav1.ObjectMeta{Name: "admins"}, Users: userv1.OptionalNames{"janedoe"}},
+		&toolchainv1alpha1.NSTemplateSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   operatorNamespace,
+			Name:        username,
+			Annotations: map[string]string{template.InventoryAnnotation: content},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "johnsmith-dev", Labels: map[string]string{"owner": username}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "janedoe-dev", Labels: map[string]string{"owner": "janedoe"}}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "credentials"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		},
+	}
+}
+
+// kinds returns the kind and the name of the objects of the given bundle, in order
+func kinds(bundle string) []string {
+	var result []string
+	for _, doc := range strings.Split(bundle, "---\n") {
+		var kind, name string
+		for _, line := range strings.Split(doc, "\n") {
+			if strings.HasPrefix(line, "kind: ") {
+				kind = line
+			} else if strings.HasPrefix(line, "  name: ") && name == "" {
+				name = strings.TrimPrefix(line, "  name: ")
+			}
+		}
+		if kind != "" {
+			result = append(result, kind+"/"+name)
+		}
+	}
+	return result
+}
+
+func getSnapshot(t *testing.T, cl *test.FakeClient) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: snapshot.ConfigMapName(username)}, cm))
+	return cm
+}
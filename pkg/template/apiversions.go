@@ -0,0 +1,71 @@
+package template
+
+import (
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// movedKinds the kinds which were moved out of the deprecated API groups, along with the group in which they are now served
+var movedKinds = map[schema.GroupKind]string{
+	{Group: "extensions", Kind: "Deployment"}:        "apps",
+	{Group: "extensions", Kind: "DaemonSet"}:         "apps",
+	{Group: "extensions", Kind: "ReplicaSet"}:        "apps",
+	{Group: "extensions", Kind: "NetworkPolicy"}:     "networking.k8s.io",
+	{Group: "extensions", Kind: "Ingress"}:           "networking.k8s.io",
+	{Group: "extensions", Kind: "PodSecurityPolicy"}: "policy",
+}
+
+// upgradeAPIVersions replaces the API version of the given objects which is not served by the cluster anymore (eg, `extensions/v1beta1`
+// after an upgrade of the cluster) with the preferred version of their kind, in the same group or in the group the kind was moved to.
+// Only the API version is changed, hence the objects whose schema changed between both versions still need to be fixed in the templates.
+// Does nothing if the Processor has no RESTMapper.
+func (p Processor) upgradeAPIVersions(objs []runtime.RawExtension) error {
+	if p.mapper == nil {
+		return nil
+	}
+	for _, rawObj := range objs {
+		if rawObj.Object == nil {
+			continue
+		}
+		gvk := rawObj.Object.GetObjectKind().GroupVersionKind()
+		_, err := p.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err == nil {
+			continue
+		}
+		if !meta.IsNoMatchError(err) {
+			return errs.Wrapf(err, "unable to find the served versions of kind: %s, version: %s", gvk.Kind, gvk.Version)
+		}
+		preferred, found, err := p.preferredGroupVersionKind(gvk)
+		if err != nil {
+			return err
+		}
+		if !found {
+			// let the apply fail with the usual error
+			continue
+		}
+		rawObj.Object.GetObjectKind().SetGroupVersionKind(preferred)
+		recordUpgradedAPIVersion(gvk, preferred)
+	}
+	return nil
+}
+
+// preferredGroupVersionKind returns the preferred served version of the given kind, looking first in the group the kind was moved to (if any)
+func (p Processor) preferredGroupVersionKind(gvk schema.GroupVersionKind) (schema.GroupVersionKind, bool, error) {
+	candidates := []schema.GroupKind{gvk.GroupKind()}
+	if group, moved := movedKinds[gvk.GroupKind()]; moved {
+		candidates = append([]schema.GroupKind{{Group: group, Kind: gvk.Kind}}, candidates...)
+	}
+	for _, gk := range candidates {
+		mapping, err := p.mapper.RESTMapping(gk)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return schema.GroupVersionKind{}, false, errs.Wrapf(err, "unable to find the preferred version of kind: %s", gk.String())
+		}
+		return mapping.GroupVersionKind, true, nil
+	}
+	return schema.GroupVersionKind{}, false, nil
+}
@@ -0,0 +1,102 @@
+package template_test
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+const deprecatedAPIVersionsTmpl = `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: deprecated
+objects:
+- apiVersion: extensions/v1beta1
+  kind: NetworkPolicy
+  metadata:
+    name: allow-same-namespace
+    namespace: ${USERNAME}-dev
+  spec:
+    podSelector: {}
+- apiVersion: rbac.authorization.k8s.io/v1beta1
+  kind: RoleBinding
+  metadata:
+    name: user-edit
+    namespace: ${USERNAME}-dev
+  roleRef:
+    apiGroup: rbac.authorization.k8s.io
+    kind: ClusterRole
+    name: edit
+- apiVersion: v1
+  kind: ResourceQuota
+  metadata:
+    name: compute-resources
+    namespace: ${USERNAME}-dev
+- apiVersion: example.com/v1alpha1
+  kind: Widget
+  metadata:
+    name: widget
+    namespace: ${USERNAME}-dev
+parameters:
+- name: USERNAME
+  required: true
+`
+
+func TestUpgradeAPIVersions(t *testing.T) {
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	// a cluster which does not serve the `extensions/v1beta1` nor the `rbac.authorization.k8s.io/v1beta1` API versions anymore
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{
+		{Group: "", Version: "v1"},
+		{Group: "networking.k8s.io", Version: "v1"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1"},
+	})
+	mapper.Add(schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ResourceQuota"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"}, meta.RESTScopeNamespace)
+
+	t.Run("deprecated api versions upgraded", func(t *testing.T) {
+		// given
+		p := template.NewProcessorWithOptions(test.NewFakeClient(t), s, template.Options{RESTMapper: mapper})
+		tmpl, err := decodeTemplate(decoder, deprecatedAPIVersionsTmpl)
+		require.NoError(t, err)
+
+		// when
+		objs, err := p.Process(tmpl, map[string]string{"USERNAME": "johnsmith"})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 4)
+		// moved to another group
+		assert.Equal(t, schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"}, objs[0].Object.GetObjectKind().GroupVersionKind())
+		// upgraded in the same group
+		assert.Equal(t, schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"}, objs[1].Object.GetObjectKind().GroupVersionKind())
+		// served
+		assert.Equal(t, schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ResourceQuota"}, objs[2].Object.GetObjectKind().GroupVersionKind())
+		// unknown kind left untouched
+		assert.Equal(t, schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Widget"}, objs[3].Object.GetObjectKind().GroupVersionKind())
+	})
+
+	t.Run("api versions untouched without mapper", func(t *testing.T) {
+		// given
+		p := template.NewProcessor(test.NewFakeClient(t), s)
+		tmpl, err := decodeTemplate(decoder, deprecatedAPIVersionsTmpl)
+		require.NoError(t, err)
+
+		// when
+		objs, err := p.Process(tmpl, map[string]string{"USERNAME": "johnsmith"})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 4)
+		assert.Equal(t, schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "NetworkPolicy"}, objs[0].Object.GetObjectKind().GroupVersionKind())
+		assert.Equal(t, schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "RoleBinding"}, objs[1].Object.GetObjectKind().GroupVersionKind())
+	})
+}
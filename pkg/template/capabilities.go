@@ -0,0 +1,91 @@
+package template
+
+import (
+	"strings"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// RequiresAPIAnnotation the annotation of the template objects which are only applied on the clusters which serve the listed kinds,
+// as comma-separated `Kind.group` or `Kind.version.group` (eg: `Route.route.openshift.io` or `ClusterResourceQuota.v1.quota.openshift.io`),
+// the `self` value standing for the kind of the object itself. It allows the same tier templates to be used on OpenShift and on
+// vanilla Kubernetes clusters. The objects which were applied before the cluster stopped serving the listed kinds are pruned.
+const RequiresAPIAnnotation = "toolchain.dev.openshift.com/requires-api"
+
+// requiresAPISelf the value of the `RequiresAPIAnnotation` which stands for the kind of the annotated object
+const requiresAPISelf = "self"
+
+// retainServedObjects returns the given objects without those which require kinds that are not served by the cluster
+// (see `RequiresAPIAnnotation`). Returns all the objects if the Processor has no RESTMapper.
+func (p Processor) retainServedObjects(objs []runtime.RawExtension) ([]runtime.RawExtension, error) {
+	if p.mapper == nil {
+		return objs, nil
+	}
+	result := make([]runtime.RawExtension, 0, len(objs))
+	for _, rawObj := range objs {
+		if rawObj.Object == nil {
+			result = append(result, rawObj)
+			continue
+		}
+		acc, err := meta.Accessor(rawObj.Object)
+		if err != nil {
+			return nil, errs.Wrap(err, "unable to access the metadata of the template object")
+		}
+		served, err := p.servesAll(acc.GetAnnotations()[RequiresAPIAnnotation], rawObj.Object.GetObjectKind().GroupVersionKind())
+		if err != nil {
+			return nil, errs.Wrapf(err, "unable to check the APIs required by the %s '%s'", rawObj.Object.GetObjectKind().GroupVersionKind().Kind, acc.GetName())
+		}
+		if served {
+			result = append(result, rawObj)
+		}
+	}
+	return result, nil
+}
+
+// servesAll returns true if the cluster serves all the kinds listed in the given value of the `RequiresAPIAnnotation`
+func (p Processor) servesAll(required string, self schema.GroupVersionKind) (bool, error) {
+	for _, kind := range strings.Split(required, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		var candidates []schema.GroupVersionKind
+		if kind == requiresAPISelf {
+			candidates = []schema.GroupVersionKind{self}
+		} else {
+			// as with kubectl, `Kind.version.group` is tried first, then `Kind.group` (eg: `Route.route.openshift.io`
+			// is either the kind `Route` in the version `route` of the group `openshift.io` or in the group `route.openshift.io`)
+			gvk, gk := schema.ParseKindArg(kind)
+			if gvk != nil {
+				candidates = append(candidates, *gvk)
+			}
+			candidates = append(candidates, gk.WithVersion(""))
+		}
+		served, err := p.servesAny(candidates)
+		if err != nil || !served {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// servesAny returns true if the cluster serves any of the given kinds (in any version if the version is empty)
+func (p Processor) servesAny(candidates []schema.GroupVersionKind) (bool, error) {
+	for _, gvk := range candidates {
+		var versions []string
+		if gvk.Version != "" {
+			versions = append(versions, gvk.Version)
+		}
+		if _, err := p.mapper.RESTMapping(gvk.GroupKind(), versions...); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
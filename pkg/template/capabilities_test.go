@@ -0,0 +1,130 @@
+package template_test
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+const requiresAPITmpl = `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: requires-api
+objects:
+- apiVersion: v1
+  kind: ResourceQuota
+  metadata:
+    name: compute-resources
+    namespace: ${USERNAME}-dev
+- apiVersion: route.openshift.io/v1
+  kind: Route
+  metadata:
+    name: console
+    namespace: ${USERNAME}-dev
+    annotations:
+      toolchain.dev.openshift.com/requires-api: Route.route.openshift.io
+  spec:
+    to:
+      kind: Service
+      name: console
+- apiVersion: quota.openshift.io/v1
+  kind: ClusterResourceQuota
+  metadata:
+    name: for-${USERNAME}
+    annotations:
+      toolchain.dev.openshift.com/requires-api: self
+- apiVersion: networking.k8s.io/v1
+  kind: Ingress
+  metadata:
+    name: console
+    namespace: ${USERNAME}-dev
+    annotations:
+      toolchain.dev.openshift.com/requires-api: Ingress.v1.networking.k8s.io, IngressClass.networking.k8s.io
+parameters:
+- name: USERNAME
+  required: true
+`
+
+func TestRequiresAPI(t *testing.T) {
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+
+	t.Run("openshift cluster", func(t *testing.T) {
+		// given
+		mapper := newMapper(
+			schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ResourceQuota"},
+			schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"},
+			schema.GroupVersionKind{Group: "quota.openshift.io", Version: "v1", Kind: "ClusterResourceQuota"},
+			schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1beta1", Kind: "Ingress"})
+		p := template.NewProcessorWithOptions(test.NewFakeClient(t), s, template.Options{RESTMapper: mapper})
+		tmpl, err := decodeTemplate(decoder, requiresAPITmpl)
+		require.NoError(t, err)
+
+		// when
+		objs, err := p.Process(tmpl, map[string]string{"USERNAME": "johnsmith"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ResourceQuota", "Route", "ClusterResourceQuota"}, kindsOf(objs))
+	})
+
+	t.Run("kubernetes cluster", func(t *testing.T) {
+		// given
+		mapper := newMapper(
+			schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ResourceQuota"},
+			schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+			schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "IngressClass"})
+		p := template.NewProcessorWithOptions(test.NewFakeClient(t), s, template.Options{RESTMapper: mapper})
+		tmpl, err := decodeTemplate(decoder, requiresAPITmpl)
+		require.NoError(t, err)
+
+		// when
+		objs, err := p.Process(tmpl, map[string]string{"USERNAME": "johnsmith"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ResourceQuota", "Ingress"}, kindsOf(objs))
+	})
+
+	t.Run("all objects retained without mapper", func(t *testing.T) {
+		// given
+		p := template.NewProcessor(test.NewFakeClient(t), s)
+		tmpl, err := decodeTemplate(decoder, requiresAPITmpl)
+		require.NoError(t, err)
+
+		// when
+		objs, err := p.Process(tmpl, map[string]string{"USERNAME": "johnsmith"})
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, objs, 4)
+	})
+}
+
+func newMapper(kinds ...schema.GroupVersionKind) meta.RESTMapper {
+	var versions []schema.GroupVersion
+	for _, gvk := range kinds {
+		versions = append(versions, gvk.GroupVersion())
+	}
+	mapper := meta.NewDefaultRESTMapper(versions)
+	for _, gvk := range kinds {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	return mapper
+}
+
+func kindsOf(objs []runtime.RawExtension) []string {
+	kinds := make([]string, len(objs))
+	for i, obj := range objs {
+		kinds[i] = obj.Object.GetObjectKind().GroupVersionKind().Kind
+	}
+	return kinds
+}
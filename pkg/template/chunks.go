@@ -0,0 +1,72 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ErrInterrupted the error returned by `ApplyChunks` when it stopped before the next chunk because the apply was interrupted
+// (see `Options.Interrupted`)
+var ErrInterrupted = errors.New("interrupted")
+
+// IsInterrupted returns `true` if the given error (or its cause) is `ErrInterrupted`
+func IsInterrupted(err error) bool {
+	return errs.Cause(err) == ErrInterrupted
+}
+
+// CheckpointFunc records that all the chunks before the given chunk were applied, so that a later apply of the same objects
+// can resume from this chunk
+type CheckpointFunc func(next int) error
+
+// ApplyChunks applies the given objects in chunks of the given size, starting from the given chunk (eg, the chunk recorded by
+// the last checkpoint of an apply which was interrupted). Each chunk is applied as a single unit (see `ApplyAll`) and is followed
+// by a call to the checkpoint function with the index of the next chunk. The chunks which were applied before a failure are kept.
+// If the apply is interrupted (see `Options.Interrupted`), it stops before the next chunk and returns an error caused by `ErrInterrupted`.
+// All the objects are applied as a single chunk if the size is not positive.
+func (p Processor) ApplyChunks(objs []runtime.RawExtension, size, from int, checkpoint CheckpointFunc) error {
+	if size <= 0 {
+		size = len(objs)
+	}
+	for chunk := from; chunk*size < len(objs); chunk++ {
+		if p.interrupted != nil && p.interrupted() {
+			return errs.Wrapf(ErrInterrupted, "applied %d of %d chunks", chunk, ChunksCount(len(objs), size))
+		}
+		end := (chunk + 1) * size
+		if end > len(objs) {
+			end = len(objs)
+		}
+		if err := p.ApplyAll(objs[chunk*size : end]); err != nil {
+			return errs.Wrapf(err, "failed to apply chunk %d of %d", chunk+1, ChunksCount(len(objs), size))
+		}
+		if err := checkpoint(chunk + 1); err != nil {
+			return errs.Wrapf(err, "failed to record the checkpoint of chunk %d", chunk+1)
+		}
+	}
+	return nil
+}
+
+// ChunksCount returns the number of chunks of the given size needed to apply the given number of objects
+func ChunksCount(count, size int) int {
+	if size <= 0 {
+		return 1
+	}
+	return (count + size - 1) / size
+}
+
+// ObjectsHash returns the hash of the given objects, which identifies a set of objects across the checkpoints of its apply
+func ObjectsHash(objs []runtime.RawExtension) (string, error) {
+	hash := sha256.New()
+	for _, rawObj := range objs {
+		data, err := json.Marshal(rawObj.Object)
+		if err != nil {
+			return "", errs.Wrap(err, "unable to marshal the object")
+		}
+		_, _ = hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
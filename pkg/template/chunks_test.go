@@ -0,0 +1,174 @@
+package template_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	errs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestApplyChunks(t *testing.T) {
+	s := addToScheme(t)
+
+	t.Run("all chunks applied with checkpoints", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessor(cl, s)
+		var checkpoints []int
+
+		// when
+		err := p.ApplyChunks(newConfigMaps(5), 2, 0, func(next int) error {
+			checkpoints = append(checkpoints, next)
+			return nil
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, checkpoints)
+		for i := 0; i < 5; i++ {
+			assertConfigMapExists(t, cl, fmt.Sprintf("cm-%d", i))
+		}
+	})
+
+	t.Run("apply resumed from the given chunk", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessor(cl, s)
+		var checkpoints []int
+
+		// when
+		err := p.ApplyChunks(newConfigMaps(5), 2, 2, func(next int) error {
+			checkpoints = append(checkpoints, next)
+			return nil
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []int{3}, checkpoints)
+		assertConfigMapExists(t, cl, "cm-4")
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "cm-3"}, &corev1.ConfigMap{})
+		require.Error(t, err)
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("failed chunk rolled back", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if obj.(metav1.Object).GetName() == "cm-3" {
+				return errors.New("mock error")
+			}
+			return cl.Client.Create(ctx, obj, opts...)
+		}
+		p := template.NewProcessor(cl, s)
+		var checkpoints []int
+
+		// when
+		err := p.ApplyChunks(newConfigMaps(5), 2, 0, func(next int) error {
+			checkpoints = append(checkpoints, next)
+			return nil
+		})
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to apply chunk 2 of 3")
+		assert.Equal(t, []int{1}, checkpoints)
+		assertConfigMapExists(t, cl, "cm-1")
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "cm-2"}, &corev1.ConfigMap{})
+		require.Error(t, err)
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("apply interrupted", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		interrupted := false
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Interrupted: func() bool { return interrupted }})
+		var checkpoints []int
+
+		// when
+		err := p.ApplyChunks(newConfigMaps(5), 2, 0, func(next int) error {
+			checkpoints = append(checkpoints, next)
+			interrupted = true
+			return nil
+		})
+
+		// then
+		require.Error(t, err)
+		assert.True(t, template.IsInterrupted(err))
+		assert.Equal(t, "applied 1 of 3 chunks: interrupted", err.Error())
+		assert.Equal(t, []int{1}, checkpoints)
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "cm-2"}, &corev1.ConfigMap{})
+		require.Error(t, err)
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("checkpoint fails", func(t *testing.T) {
+		// given
+		p := template.NewProcessor(test.NewFakeClient(t), s)
+
+		// when
+		err := p.ApplyChunks(newConfigMaps(5), 2, 0, func(next int) error {
+			return errors.New("mock error")
+		})
+
+		// then
+		require.EqualError(t, err, "failed to record the checkpoint of chunk 1: mock error")
+	})
+}
+
+func TestObjectsHash(t *testing.T) {
+	// when
+	hash1, err1 := template.ObjectsHash(newConfigMaps(3))
+	hash2, err2 := template.ObjectsHash(newConfigMaps(3))
+	hash3, err3 := template.ObjectsHash(newConfigMaps(4))
+
+	// then
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	require.NoError(t, err3)
+	assert.Equal(t, hash1, hash2)
+	assert.NotEqual(t, hash1, hash3)
+}
+
+func TestIsInterrupted(t *testing.T) {
+	assert.True(t, template.IsInterrupted(template.ErrInterrupted))
+	assert.True(t, template.IsInterrupted(errs.Wrap(template.ErrInterrupted, "applied 2 of 5 chunks")))
+	assert.False(t, template.IsInterrupted(errors.New("mock error")))
+	assert.False(t, template.IsInterrupted(nil))
+}
+
+func TestChunksCount(t *testing.T) {
+	assert.Equal(t, 3, template.ChunksCount(5, 2))
+	assert.Equal(t, 2, template.ChunksCount(4, 2))
+	assert.Equal(t, 1, template.ChunksCount(4, 0))
+}
+
+func newConfigMaps(count int) []runtime.RawExtension {
+	objs := make([]runtime.RawExtension, count)
+	for i := range objs {
+		objs[i] = runtime.RawExtension{Object: &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: fmt.Sprintf("cm-%d", i)},
+		}}
+	}
+	return objs
+}
+
+func assertConfigMapExists(t *testing.T, cl client.Client, name string) {
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: name}, &corev1.ConfigMap{})
+	require.NoError(t, err)
+}
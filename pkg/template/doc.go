@@ -0,0 +1,17 @@
+// Package template processes the OpenShift templates of the tiers and applies the resulting objects on a cluster.
+//
+// The package does not depend on the controllers of the operator, so that the host operator and the e2e tests can rely on the same
+// processing and apply logic. A Processor only needs a Client and a Converter (ie, a `*runtime.Scheme` with the OpenShift template API),
+// along with some optional settings in its Options.
+//
+// The extension points are:
+// - the FilterFuncs, which select the objects to keep after the processing of a template,
+// - the Inventory, which records the applied objects so that the obsolete ones can be pruned, and which can be stored anywhere
+// (eg, in an annotation) thanks to its JSON representation,
+// - the ChurnRecorder, which records the objects created, updated and deleted by the Processor,
+// - the protobuf Client, which applies the objects of native kinds with the protobuf content type,
+// - the Decrypter, which decrypts the values of the encrypted Secrets and of the SealedSecrets before they are applied,
+// - the FragmentGetter, which returns the shared fragments included by the templates before they are processed,
+// - the Interrupted func, which stops the applies in chunks at their next chunk (eg, when the operator shuts down).
+// The objects returned by the processing can also be modified before they are applied (eg, with `SetDefaultQuota`).
+package template
@@ -0,0 +1,87 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Guardrails the limits enforced on the processed templates, so that a malformed template cannot flood the API server with objects.
+// The zero value enforces no limit.
+type Guardrails struct {
+	// MaxObjects the maximum number of objects of a template, including the objects of the fragments it includes. Unlimited if it is `0`
+	MaxObjects int
+	// MaxObjectSize the maximum size (in bytes) of each object of a template, once serialized in JSON. Unlimited if it is `0`
+	MaxObjectSize int64
+	// AllowedKinds the kinds of the objects that the templates may contain. All kinds are allowed if it is empty
+	AllowedKinds []schema.GroupKind
+}
+
+// ValidationError the error returned when a processed template does not comply with the Guardrails of the Processor
+type ValidationError struct {
+	// Template the name of the invalid template
+	Template string
+	// Reason the guardrail which the template violates
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid template '%s': %s", e.Template, e.Reason)
+}
+
+// IsValidationError returns `true` if the given error (or its cause) is a `*ValidationError`
+func IsValidationError(err error) bool {
+	_, ok := errs.Cause(err).(*ValidationError)
+	return ok
+}
+
+// validate checks the given objects of the given template against the guardrails
+func (g Guardrails) validate(tmplName string, objs []runtime.RawExtension) error {
+	if g.MaxObjects > 0 && len(objs) > g.MaxObjects {
+		return &ValidationError{Template: tmplName, Reason: fmt.Sprintf("%d objects exceed the maximum of %d objects", len(objs), g.MaxObjects)}
+	}
+	for _, rawObj := range objs {
+		if rawObj.Object == nil {
+			if g.MaxObjectSize > 0 && int64(len(rawObj.Raw)) > g.MaxObjectSize {
+				return &ValidationError{Template: tmplName, Reason: fmt.Sprintf("size of an object (%d bytes) exceeds the maximum of %d bytes", len(rawObj.Raw), g.MaxObjectSize)}
+			}
+			continue
+		}
+		gvk := rawObj.Object.GetObjectKind().GroupVersionKind()
+		name := ""
+		if acc, err := meta.Accessor(rawObj.Object); err == nil {
+			name = acc.GetName()
+		}
+		if !g.allows(gvk.GroupKind()) {
+			return &ValidationError{Template: tmplName, Reason: fmt.Sprintf("kind '%s' of object '%s' is not allowed", gvk.GroupKind(), name)}
+		}
+		if g.MaxObjectSize <= 0 {
+			continue
+		}
+		content, err := json.Marshal(rawObj.Object)
+		if err != nil {
+			return errs.Wrapf(err, "unable to serialize the %s '%s'", gvk.Kind, name)
+		}
+		if size := int64(len(content)); size > g.MaxObjectSize {
+			return &ValidationError{Template: tmplName, Reason: fmt.Sprintf("size of the %s '%s' (%d bytes) exceeds the maximum of %d bytes", gvk.Kind, name, size, g.MaxObjectSize)}
+		}
+	}
+	return nil
+}
+
+// allows returns `true` if the given kind is in the allow-list, or if the allow-list is empty
+func (g Guardrails) allows(kind schema.GroupKind) bool {
+	if len(g.AllowedKinds) == 0 {
+		return true
+	}
+	for _, allowed := range g.AllowedKinds {
+		if allowed == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package template_test
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	errs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+func TestGuardrails(t *testing.T) {
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	tmpl, err := decodeTemplate(decoder, requiresAPITmpl)
+	require.NoError(t, err)
+	values := map[string]string{"USERNAME": "johnsmith"}
+
+	t.Run("template within the guardrails", func(t *testing.T) {
+		// given
+		p := template.NewProcessorWithOptions(test.NewFakeClient(t), s, template.Options{
+			Guardrails: template.Guardrails{
+				MaxObjects:    4,
+				MaxObjectSize: 1024,
+				AllowedKinds: []schema.GroupKind{
+					{Kind: "ResourceQuota"},
+					{Group: "route.openshift.io", Kind: "Route"},
+					{Group: "quota.openshift.io", Kind: "ClusterResourceQuota"},
+					{Group: "networking.k8s.io", Kind: "Ingress"},
+				},
+			},
+		})
+
+		// when
+		objs, err := p.Process(tmpl.DeepCopy(), values)
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, objs, 4)
+	})
+
+	t.Run("no guardrails", func(t *testing.T) {
+		// given
+		p := template.NewProcessor(test.NewFakeClient(t), s)
+
+		// when
+		objs, err := p.Process(tmpl.DeepCopy(), values)
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, objs, 4)
+	})
+
+	t.Run("too many objects", func(t *testing.T) {
+		// given
+		p := template.NewProcessorWithOptions(test.NewFakeClient(t), s, template.Options{
+			Guardrails: template.Guardrails{MaxObjects: 3},
+		})
+
+		// when
+		_, err := p.Process(tmpl.DeepCopy(), values)
+
+		// then
+		require.EqualError(t, err, "invalid template 'requires-api': 4 objects exceed the maximum of 3 objects")
+		assert.True(t, template.IsValidationError(err))
+		assert.True(t, template.IsValidationError(errs.Wrap(err, "failed to process template")))
+	})
+
+	t.Run("object too large", func(t *testing.T) {
+		// given
+		p := template.NewProcessorWithOptions(test.NewFakeClient(t), s, template.Options{
+			Guardrails: template.Guardrails{MaxObjectSize: 100},
+		})
+
+		// when
+		_, err := p.Process(tmpl.DeepCopy(), values)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid template 'requires-api': size of the ResourceQuota 'compute-resources'")
+		assert.Contains(t, err.Error(), "exceeds the maximum of 100 bytes")
+		assert.True(t, template.IsValidationError(err))
+	})
+
+	t.Run("kind not allowed", func(t *testing.T) {
+		// given
+		p := template.NewProcessorWithOptions(test.NewFakeClient(t), s, template.Options{
+			Guardrails: template.Guardrails{
+				AllowedKinds: []schema.GroupKind{{Kind: "ResourceQuota"}, {Group: "route.openshift.io", Kind: "Route"}},
+			},
+		})
+
+		// when
+		_, err := p.Process(tmpl.DeepCopy(), values)
+
+		// then
+		require.EqualError(t, err, "invalid template 'requires-api': kind 'ClusterResourceQuota.quota.openshift.io' of object 'for-johnsmith' is not allowed")
+		assert.True(t, template.IsValidationError(err))
+	})
+
+	t.Run("other errors are not validation errors", func(t *testing.T) {
+		assert.False(t, template.IsValidationError(errs.New("mock error")))
+	})
+}
@@ -0,0 +1,91 @@
+package template
+
+import (
+	"strings"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// podSpecPaths the paths of the pod specs of the workloads, by kind
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"PodTemplate":           {"template", "spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"DeploymentConfig":      {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// containerFields the fields of a pod spec which hold containers
+var containerFields = []string{"initContainers", "containers", "ephemeralContainers"}
+
+// ImageMirrors the mirrors of the container images by source, ie, by registry (eg: `quay.io`) or by repository
+// (eg: `quay.io/codeready-toolchain`), so that the workloads of disconnected clusters pull their images from an internal registry.
+// It is a Mutator which rewrites the images of the containers of the processed workloads
+type ImageMirrors map[string]string
+
+// Rewrite returns the given image reference pulled from its mirror, or the reference itself if none of the sources matches it.
+// When several sources match the reference, the longest one is used. The references are matched as they are written, ie, the short
+// names of the Docker Hub images (eg: `busybox`) only match the `busybox` source
+func (m ImageMirrors) Rewrite(image string) string {
+	longest := ""
+	for source := range m {
+		if len(source) > len(longest) && matchesSource(image, source) {
+			longest = source
+		}
+	}
+	if longest == "" {
+		return image
+	}
+	return m[longest] + strings.TrimPrefix(image, longest)
+}
+
+// matchesSource returns true if the given image reference belongs to the given registry or repository
+func matchesSource(image, source string) bool {
+	if !strings.HasPrefix(image, source) {
+		return false
+	}
+	rest := image[len(source):]
+	if rest == "" || strings.HasPrefix(rest, "/") {
+		return true
+	}
+	// a tag or a digest only follows a repository, while a colon following a registry introduces its port
+	return strings.Contains(source, "/") && (strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "@"))
+}
+
+// Mutate rewrites the images of all the containers of the given workload (see `Rewrite`). The objects which are not workloads
+// are left untouched
+func (m ImageMirrors) Mutate(obj *unstructured.Unstructured) error {
+	path, found := podSpecPaths[obj.GetKind()]
+	if !found || len(m) == 0 {
+		return nil
+	}
+	for _, field := range containerFields {
+		fields := append(append([]string{}, path...), field)
+		containers, found, err := unstructured.NestedSlice(obj.Object, fields...)
+		if err != nil {
+			return errs.Wrapf(err, "invalid %s", strings.Join(fields, "."))
+		}
+		if !found {
+			continue
+		}
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, ok := container["image"].(string); ok {
+				container["image"] = m.Rewrite(image)
+			}
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, containers, fields...); err != nil {
+			return errs.Wrapf(err, "unable to set the %s", strings.Join(fields, "."))
+		}
+	}
+	return nil
+}
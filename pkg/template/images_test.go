@@ -0,0 +1,148 @@
+package template_test
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+var mirrors = template.ImageMirrors{
+	"quay.io": "registry.example.com:5000/quay",
+	"quay.io/codeready-toolchain/member-console": "registry.example.com:5000/console",
+	"registry.redhat.io:443":                     "registry.example.com:5000/redhat",
+	"busybox":                                    "registry.example.com:5000/library/busybox",
+}
+
+func TestRewriteImage(t *testing.T) {
+	for _, tc := range []struct {
+		image    string
+		expected string
+	}{
+		{"quay.io/codeready-toolchain/app:latest", "registry.example.com:5000/quay/codeready-toolchain/app:latest"},
+		{"quay.io/codeready-toolchain/member-console:123abc", "registry.example.com:5000/console:123abc"},
+		{"quay.io/codeready-toolchain/member-console@sha256:0123", "registry.example.com:5000/console@sha256:0123"},
+		{"quay.io/codeready-toolchain/member-console-proxy:123abc", "registry.example.com:5000/quay/codeready-toolchain/member-console-proxy:123abc"},
+		{"registry.redhat.io:443/ubi8/ubi", "registry.example.com:5000/redhat/ubi8/ubi"},
+		{"registry.redhat.io/ubi8/ubi", "registry.redhat.io/ubi8/ubi"},
+		{"quay.io.example.com/app", "quay.io.example.com/app"},
+		{"busybox", "registry.example.com:5000/library/busybox"},
+		{"docker.io/library/busybox", "docker.io/library/busybox"},
+	} {
+		t.Run(tc.image, func(t *testing.T) {
+			assert.Equal(t, tc.expected, mirrors.Rewrite(tc.image))
+		})
+	}
+
+	t.Run("no mirror", func(t *testing.T) {
+		assert.Equal(t, "quay.io/codeready-toolchain/app:latest", template.ImageMirrors{}.Rewrite("quay.io/codeready-toolchain/app:latest"))
+	})
+}
+
+func TestMirrorImages(t *testing.T) {
+
+	t.Run("containers of the workloads", func(t *testing.T) {
+		for kind, path := range map[string][]string{
+			"Pod":              {"spec"},
+			"Deployment":       {"spec", "template", "spec"},
+			"DeploymentConfig": {"spec", "template", "spec"},
+			"CronJob":          {"spec", "jobTemplate", "spec", "template", "spec"},
+		} {
+			t.Run(kind, func(t *testing.T) {
+				// given
+				obj := newWorkload(t, kind, path)
+
+				// when
+				err := mirrors.Mutate(obj)
+
+				// then
+				require.NoError(t, err)
+				assert.Equal(t, []string{"registry.example.com:5000/quay/codeready-toolchain/setup:latest"}, imagesOf(t, obj, path, "initContainers"))
+				assert.Equal(t, []string{"registry.example.com:5000/quay/codeready-toolchain/app:latest", "registry.example.com:5000/library/busybox"},
+					imagesOf(t, obj, path, "containers"))
+			})
+		}
+	})
+
+	t.Run("other objects left untouched", func(t *testing.T) {
+		// given
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"data":       map[string]interface{}{"image": "quay.io/codeready-toolchain/app:latest"},
+		}}
+		expected := obj.DeepCopy()
+
+		// when
+		err := mirrors.Mutate(obj)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, expected, obj)
+	})
+
+	t.Run("invalid containers", func(t *testing.T) {
+		// given
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"spec":       map[string]interface{}{"containers": "app"},
+		}}
+
+		// when
+		err := mirrors.Mutate(obj)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid spec.containers")
+	})
+
+	t.Run("processed templates", func(t *testing.T) {
+		// given
+		s := addToScheme(t)
+		tmpl, err := decodeTemplate(serializer.NewCodecFactory(s).UniversalDeserializer(), mutatedTmpl)
+		require.NoError(t, err)
+		p := template.NewProcessorWithOptions(test.NewFakeClient(t), s, template.Options{Mutators: []template.Mutator{mirrors}})
+
+		// when
+		objs, err := p.Process(tmpl, map[string]string{"USERNAME": "johnsmith"})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 2)
+		pod := objs[1].Object.(*unstructured.Unstructured)
+		assert.Equal(t, []string{"registry.example.com:5000/quay/codeready-toolchain/app:latest"}, imagesOf(t, pod, []string{"spec"}, "containers"))
+	})
+}
+
+func newWorkload(t *testing.T, kind string, path []string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetKind(kind)
+	obj.SetName("app")
+	err := unstructured.SetNestedSlice(obj.Object, []interface{}{
+		map[string]interface{}{"name": "setup", "image": "quay.io/codeready-toolchain/setup:latest"},
+	}, append(append([]string{}, path...), "initContainers")...)
+	require.NoError(t, err)
+	err = unstructured.SetNestedSlice(obj.Object, []interface{}{
+		map[string]interface{}{"name": "app", "image": "quay.io/codeready-toolchain/app:latest"},
+		map[string]interface{}{"name": "sidecar", "image": "busybox"},
+	}, append(append([]string{}, path...), "containers")...)
+	require.NoError(t, err)
+	return obj
+}
+
+func imagesOf(t *testing.T, obj *unstructured.Unstructured, path []string, field string) []string {
+	containers, found, err := unstructured.NestedSlice(obj.Object, append(append([]string{}, path...), field)...)
+	require.NoError(t, err)
+	require.True(t, found)
+	images := make([]string, 0, len(containers))
+	for _, c := range containers {
+		images = append(images, c.(map[string]interface{})["image"].(string))
+	}
+	return images
+}
@@ -0,0 +1,150 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	templatev1 "github.com/openshift/api/template/v1"
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// IncludeAnnotation the annotation of the templates which lists the names of the shared fragments (eg, a common RBAC fragment)
+// whose objects and parameters are included in the template before it is processed, separated by commas
+const IncludeAnnotation = "toolchain.dev.openshift.com/include"
+
+// FragmentGetter returns the shared fragments included by the templates, ie, templates whose objects are composed into other templates
+// (see `IncludeAnnotation`)
+type FragmentGetter interface {
+	GetFragment(name string) (*templatev1.Template, error)
+}
+
+// Fragments a FragmentGetter which returns the fragments of the map, by name
+type Fragments map[string]templatev1.Template
+
+// GetFragment returns the fragment with the given name, or an error if there is none
+func (f Fragments) GetFragment(name string) (*templatev1.Template, error) {
+	fragment, found := f[name]
+	if !found {
+		return nil, errs.Errorf("fragment '%s' not found", name)
+	}
+	return &fragment, nil
+}
+
+// Compose returns a copy of the given template in which the objects and the parameters of the fragments it includes are appended,
+// along with those of the fragments that they include in turn. The objects and the parameters of the template take precedence over
+// those of the fragments with the same kind, namespace and name (resp. with the same name), so that a tier can override a shared object
+// or the default value of a shared parameter. A fragment included several times is only added once, and an include cycle is an error.
+// The template is returned as is if it does not include any fragment.
+func Compose(tmpl *templatev1.Template, fragments FragmentGetter) (*templatev1.Template, error) {
+	if len(includes(tmpl)) == 0 {
+		return tmpl, nil
+	}
+	if fragments == nil {
+		return nil, errs.Errorf("unable to include the fragments of template '%s': no fragment getter", tmpl.Name)
+	}
+	result := tmpl.DeepCopy()
+	c := &composer{
+		fragments: fragments,
+		included:  map[string]bool{},
+		objects:   map[string]bool{},
+		params:    map[string]bool{},
+	}
+	for _, param := range result.Parameters {
+		c.params[param.Name] = true
+	}
+	for _, obj := range result.Objects {
+		key, err := objectKey(obj)
+		if err != nil {
+			return nil, errs.Wrapf(err, "invalid object in template '%s'", tmpl.Name)
+		}
+		c.objects[key] = true
+	}
+	if err := c.include(result, tmpl, []string{tmpl.Name}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// composer appends the objects and the parameters of the fragments to a template, while keeping track of what was already appended
+type composer struct {
+	fragments FragmentGetter
+	// included the names of the fragments already included
+	included map[string]bool
+	// objects and params the keys of the objects and the names of the parameters already in the template
+	objects map[string]bool
+	params  map[string]bool
+}
+
+// include appends the fragments included by the given template (or fragment) to the result. The path is the chain of the templates
+// which led to the given one, used to detect the cycles
+func (c *composer) include(result, tmpl *templatev1.Template, path []string) error {
+	for _, name := range includes(tmpl) {
+		for _, p := range path {
+			if p == name {
+				return errs.Errorf("include cycle: %s -> %s", strings.Join(path, " -> "), name)
+			}
+		}
+		if c.included[name] {
+			continue
+		}
+		fragment, err := c.fragments.GetFragment(name)
+		if err != nil {
+			return errs.Wrapf(err, "unable to include fragment '%s' in template '%s'", name, tmpl.Name)
+		}
+		c.included[name] = true
+		for _, param := range fragment.Parameters {
+			if !c.params[param.Name] {
+				c.params[param.Name] = true
+				result.Parameters = append(result.Parameters, param)
+			}
+		}
+		for _, obj := range fragment.Objects {
+			key, err := objectKey(obj)
+			if err != nil {
+				return errs.Wrapf(err, "invalid object in fragment '%s'", name)
+			}
+			if !c.objects[key] {
+				c.objects[key] = true
+				result.Objects = append(result.Objects, *obj.DeepCopy())
+			}
+		}
+		if err := c.include(result, fragment, append(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// includes returns the names of the fragments included by the given template
+func includes(tmpl *templatev1.Template) []string {
+	var names []string
+	for _, name := range strings.Split(tmpl.Annotations[IncludeAnnotation], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// objectKey returns the identity of the given (unprocessed) template object, ie, its kind, namespace and name
+func objectKey(obj runtime.RawExtension) (string, error) {
+	u := &unstructured.Unstructured{}
+	switch o := obj.Object.(type) {
+	case nil:
+		if err := json.Unmarshal(obj.Raw, &u.Object); err != nil {
+			return "", err
+		}
+	case runtime.Unstructured:
+		u.Object = o.UnstructuredContent()
+	default:
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+		if err != nil {
+			return "", err
+		}
+		u.Object = content
+	}
+	return fmt.Sprintf("%s/%s/%s", u.GetKind(), u.GetNamespace(), u.GetName()), nil
+}
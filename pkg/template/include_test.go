@@ -0,0 +1,142 @@
+package template_test
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	templatev1 "github.com/openshift/api/template/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+func TestProcessWithFragments(t *testing.T) {
+	// given
+	user := getNameWithTimestamp("user")
+	commit := getNameWithTimestamp("sha")
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	tmpl, err := decodeTemplate(decoder, namespaceTmpl)
+	require.NoError(t, err)
+	tmpl.Annotations = map[string]string{template.IncludeAnnotation: "rbac-common"}
+	fragment, err := decodeTemplate(decoder, rolebindingTmpl)
+	require.NoError(t, err)
+	p := template.NewProcessorWithOptions(test.NewFakeClient(t), s, template.Options{
+		Fragments: template.Fragments{"rbac-common": *fragment},
+	})
+
+	// when
+	objs, err := p.Process(tmpl, map[string]string{
+		"USERNAME": user,
+		"COMMIT":   commit,
+	})
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, objs, 2)
+	assertObject(t, expectedObj{
+		template: namespaceObj,
+		username: user,
+		commit:   commit,
+	}, objs[0])
+	assertObject(t, expectedObj{
+		template: rolebindingObj,
+		username: user,
+		commit:   commit,
+	}, objs[1])
+
+	t.Run("no fragment getter", func(t *testing.T) {
+		// given
+		p := template.NewProcessor(test.NewFakeClient(t), s)
+
+		// when
+		_, err := p.Process(tmpl, map[string]string{"USERNAME": user})
+
+		// then
+		require.EqualError(t, err, "unable to include the fragments of template 'basic-tier-template': no fragment getter")
+	})
+}
+
+func TestCompose(t *testing.T) {
+
+	t.Run("nothing to include", func(t *testing.T) {
+		// given
+		tmpl := newTemplate("dev", "", configMap("quota"))
+
+		// when
+		result, err := template.Compose(tmpl, nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Same(t, tmpl, result)
+	})
+
+	t.Run("fragments included", func(t *testing.T) {
+		// given
+		tmpl := newTemplate("dev", "rbac-common, network-common", configMap("quota"), configMap("overridden"))
+		tmpl.Parameters = []templatev1.Parameter{{Name: "USERNAME"}, {Name: "MEMORY", Value: "1Gi"}}
+		rbac := newTemplate("rbac-common", "base", configMap("rbac"), configMap("overridden"))
+		rbac.Parameters = []templatev1.Parameter{{Name: "MEMORY", Value: "2Gi"}, {Name: "ROLE", Value: "edit"}}
+		network := newTemplate("network-common", "base", configMap("network"))
+		base := newTemplate("base", "", configMap("base"))
+		fragments := template.Fragments{"rbac-common": *rbac, "network-common": *network, "base": *base}
+
+		// when
+		result, err := template.Compose(tmpl, fragments)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []runtime.RawExtension{configMap("quota"), configMap("overridden"), configMap("rbac"), configMap("base"), configMap("network")}, result.Objects)
+		assert.Equal(t, []templatev1.Parameter{{Name: "USERNAME"}, {Name: "MEMORY", Value: "1Gi"}, {Name: "ROLE", Value: "edit"}}, result.Parameters)
+		// the given template is not modified
+		assert.Len(t, tmpl.Objects, 2)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("unknown fragment", func(t *testing.T) {
+			// given
+			tmpl := newTemplate("dev", "unknown")
+
+			// when
+			_, err := template.Compose(tmpl, template.Fragments{})
+
+			// then
+			require.EqualError(t, err, "unable to include fragment 'unknown' in template 'dev': fragment 'unknown' not found")
+		})
+
+		t.Run("include cycle", func(t *testing.T) {
+			// given
+			tmpl := newTemplate("dev", "rbac-common")
+			fragments := template.Fragments{
+				"rbac-common": *newTemplate("rbac-common", "base"),
+				"base":        *newTemplate("base", "rbac-common"),
+			}
+
+			// when
+			_, err := template.Compose(tmpl, fragments)
+
+			// then
+			require.EqualError(t, err, "include cycle: dev -> rbac-common -> base -> rbac-common")
+		})
+	})
+}
+
+func newTemplate(name, includes string, objs ...runtime.RawExtension) *templatev1.Template {
+	tmpl := &templatev1.Template{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Objects:    objs,
+	}
+	if includes != "" {
+		tmpl.Annotations = map[string]string{template.IncludeAnnotation: includes}
+	}
+	return tmpl
+}
+
+func configMap(name string) runtime.RawExtension {
+	return runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + name + `","namespace":"${USERNAME}"}}`)}
+}
@@ -0,0 +1,34 @@
+package template
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// inFlightApplies the number of applies in progress, which are awaited when the operator shuts down
+// so that the sets of objects are not left half applied when another replica takes over
+var inFlightApplies int64
+
+// pollInterval the interval between two checks of the applies in progress during the shutdown
+var pollInterval = 100 * time.Millisecond
+
+// trackApply marks the beginning of an apply and returns the func to call once it is complete
+func trackApply() func() {
+	atomic.AddInt64(&inFlightApplies, 1)
+	return func() {
+		atomic.AddInt64(&inFlightApplies, -1)
+	}
+}
+
+// WaitForInFlightApplies waits until the applies in progress are complete, or until the given timeout expires.
+// Returns `false` if some applies were still in progress when the timeout expired
+func WaitForInFlightApplies(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&inFlightApplies) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package template_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestWaitForInFlightApplies(t *testing.T) {
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	user := getNameWithTimestamp("user")
+
+	t.Run("no apply in progress", func(t *testing.T) {
+		assert.True(t, template.WaitForInFlightApplies(time.Second))
+	})
+
+	t.Run("apply in progress", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		started := make(chan struct{})
+		release := make(chan struct{})
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			close(started)
+			<-release
+			return cl.Client.Create(ctx, obj, opts...)
+		}
+		p := template.NewProcessor(cl, s)
+		tmpl, err := decodeTemplate(decoder, namespaceTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, map[string]string{"USERNAME": user})
+		require.NoError(t, err)
+		done := make(chan error)
+		go func() {
+			done <- p.ApplyAll(objs)
+		}()
+		<-started
+
+		t.Run("timeout expired", func(t *testing.T) {
+			assert.False(t, template.WaitForInFlightApplies(200*time.Millisecond))
+		})
+
+		t.Run("apply complete", func(t *testing.T) {
+			// when
+			close(release)
+
+			// then
+			assert.True(t, template.WaitForInFlightApplies(5*time.Second))
+			require.NoError(t, <-done)
+			assertNamespaceExists(t, cl, user)
+		})
+	})
+}
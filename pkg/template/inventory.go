@@ -0,0 +1,371 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// InventoryAnnotation the annotation on the NSTemplateSet which holds the inventory of the objects that were applied
+const InventoryAnnotation = "toolchain.dev.openshift.com/inventory"
+
+// Inventory keeps track of the objects that were applied by the Processor. It is mainly used to
+// find again the objects which were created using a `metadata.generateName`, since their actual
+// name is only known once they were created on the cluster
+type Inventory struct {
+	Entries []InventoryEntry `json:"entries,omitempty"`
+}
+
+// InventoryEntry a reference to an object applied by the Processor
+type InventoryEntry struct {
+	APIVersion   string `json:"apiVersion"`
+	Kind         string `json:"kind"`
+	Namespace    string `json:"namespace,omitempty"`
+	Name         string `json:"name"`
+	GenerateName string `json:"generateName,omitempty"`
+	// TemplateType the type of the template from which the object was applied, for the objects which are pruned separately from
+	// the other objects of their namespace (eg, the cluster resources, which share the empty namespace with the user namespaces themselves).
+	// See `Processor.WithTemplateType`
+	TemplateType string `json:"templateType,omitempty"`
+	// SpecHash the hash of the `spec` of the object as it was applied, for the objects which are enforced (ie, restored when they are changed).
+	// See `SpecHash`
+	SpecHash string `json:"specHash,omitempty"`
+	// AppliedHash the hash of the object as it was processed from the template when it was last applied, which lets the Processor
+	// skip the objects which did not change since then (see `Options.DeltaOnly`). See `ObjectHash`
+	AppliedHash string `json:"appliedHash,omitempty"`
+}
+
+// InventoryLimits the limits of the Inventory, so that the annotation which holds it remains well below the size limit of the annotations.
+// The zero value enforces no limit.
+type InventoryLimits struct {
+	// MaxSize the maximum size (in bytes) of the Inventory, once serialized in JSON. Unlimited if it is `0`
+	MaxSize int
+	// MaxEntries the maximum number of entries of the Inventory. Unlimited if it is `0`
+	MaxEntries int
+}
+
+// InventoryLimitError the error returned when the Inventory exceeds its limits
+type InventoryLimitError struct {
+	// Reason the limit which the Inventory exceeds
+	Reason string
+}
+
+func (e *InventoryLimitError) Error() string {
+	return fmt.Sprintf("the inventory exceeds its limits: %s", e.Reason)
+}
+
+// IsInventoryLimitError returns `true` if the given error (or its cause) is an `*InventoryLimitError`
+func IsInventoryLimitError(err error) bool {
+	_, ok := errs.Cause(err).(*InventoryLimitError)
+	return ok
+}
+
+// NewInventory returns a new, empty Inventory
+func NewInventory() *Inventory {
+	return &Inventory{}
+}
+
+// ParseInventory parses the given (JSON) content into an Inventory. An empty content results in an empty Inventory
+func ParseInventory(content string) (*Inventory, error) {
+	inv := NewInventory()
+	if content == "" {
+		return inv, nil
+	}
+	if err := json.Unmarshal([]byte(content), inv); err != nil {
+		return nil, errs.Wrap(err, "unable to parse the inventory")
+	}
+	return inv, nil
+}
+
+// String returns the JSON representation of the Inventory
+func (i *Inventory) String() (string, error) {
+	content, err := json.Marshal(i)
+	if err != nil {
+		return "", errs.Wrap(err, "unable to marshal the inventory")
+	}
+	return string(content), nil
+}
+
+// Bounded returns the JSON representation of the Inventory within the given limits. The hashes of the applied objects are dropped
+// if the Inventory exceeds the maximum size, in which case `true` is returned and all the objects are applied again at the next
+// update (see `Options.DeltaOnly`). The entries themselves are needed to find again and to delete the objects, hence an
+// `*InventoryLimitError` is returned if the Inventory has too many entries, or if it still exceeds the maximum size
+func (i *Inventory) Bounded(limits InventoryLimits) (string, bool, error) {
+	if limits.MaxEntries > 0 && len(i.Entries) > limits.MaxEntries {
+		return "", false, &InventoryLimitError{Reason: fmt.Sprintf("%d entries exceed the maximum of %d entries", len(i.Entries), limits.MaxEntries)}
+	}
+	content, err := i.String()
+	if err != nil || limits.MaxSize <= 0 || len(content) <= limits.MaxSize {
+		return content, false, err
+	}
+	i.forgetAllAppliedHashes()
+	if content, err = i.String(); err != nil {
+		return "", false, err
+	}
+	if len(content) > limits.MaxSize {
+		return "", true, &InventoryLimitError{Reason: fmt.Sprintf("size (%d bytes) exceeds the maximum of %d bytes", len(content), limits.MaxSize)}
+	}
+	return content, true, nil
+}
+
+// FindGenerated returns the name of the object of the given kind, in the given namespace, which was created using the given `generateName`
+func (i *Inventory) FindGenerated(gvk schema.GroupVersionKind, namespace, generateName string) (string, bool) {
+	if i == nil {
+		return "", false
+	}
+	for _, e := range i.Entries {
+		if generateName != "" && e.matches(gvk, namespace, "", generateName) {
+			return e.Name, true
+		}
+	}
+	return "", false
+}
+
+// Record records the given object in the Inventory, replacing the existing entry for the same object if there was one
+// (ie, same kind, namespace and name, or same kind, namespace and generateName)
+func (i *Inventory) Record(gvk schema.GroupVersionKind, namespace, name, generateName string) {
+	i.RecordWithTemplateType("", gvk, namespace, name, generateName)
+}
+
+// RecordWithTemplateType records the given object in the Inventory under the given template type (see `InventoryEntry.TemplateType`),
+// replacing the existing entry for the same object if there was one
+func (i *Inventory) RecordWithTemplateType(templateType string, gvk schema.GroupVersionKind, namespace, name, generateName string) {
+	if i == nil {
+		return
+	}
+	apiVersion, kind := gvk.ToAPIVersionAndKind()
+	entry := InventoryEntry{
+		APIVersion:   apiVersion,
+		Kind:         kind,
+		Namespace:    namespace,
+		Name:         name,
+		GenerateName: generateName,
+		TemplateType: templateType,
+	}
+	for idx, e := range i.Entries {
+		if e.matches(gvk, namespace, name, "") || (generateName != "" && e.matches(gvk, namespace, "", generateName)) {
+			i.Entries[idx] = entry
+			return
+		}
+	}
+	i.Entries = append(i.Entries, entry)
+}
+
+// RecordSpecHash sets the hash of the `spec` of the recorded object of the given kind, namespace and name.
+// Does nothing if the object is not recorded in the Inventory
+func (i *Inventory) RecordSpecHash(gvk schema.GroupVersionKind, namespace, name, hash string) {
+	if i == nil {
+		return
+	}
+	for idx, e := range i.Entries {
+		if e.matches(gvk, namespace, name, "") {
+			i.Entries[idx].SpecHash = hash
+			return
+		}
+	}
+}
+
+// RecordAppliedHash sets the hash of the recorded object of the given kind, namespace and name as it was last applied.
+// Does nothing if the object is not recorded in the Inventory
+func (i *Inventory) RecordAppliedHash(gvk schema.GroupVersionKind, namespace, name, hash string) {
+	if i == nil {
+		return
+	}
+	for idx, e := range i.Entries {
+		if e.matches(gvk, namespace, name, "") {
+			i.Entries[idx].AppliedHash = hash
+			return
+		}
+	}
+}
+
+// AppliedHash returns the hash of the recorded object of the given kind, namespace and name as it was last applied,
+// or an empty string if the object is not recorded in the Inventory or if its hash is unknown
+func (i *Inventory) AppliedHash(gvk schema.GroupVersionKind, namespace, name string) string {
+	if i == nil {
+		return ""
+	}
+	for _, e := range i.Entries {
+		if e.matches(gvk, namespace, name, "") {
+			return e.AppliedHash
+		}
+	}
+	return ""
+}
+
+// ForgetAppliedHashes removes the hashes of the objects of the given namespace as they were last applied, so that all of them
+// are applied again, eg, when they may have been changed or deleted on the cluster
+func (i *Inventory) ForgetAppliedHashes(namespace string) {
+	if i == nil {
+		return
+	}
+	for idx, e := range i.Entries {
+		if e.Namespace == namespace {
+			i.Entries[idx].AppliedHash = ""
+		}
+	}
+}
+
+// forgetAllAppliedHashes removes the hashes of all the objects as they were last applied
+func (i *Inventory) forgetAllAppliedHashes() {
+	for idx := range i.Entries {
+		i.Entries[idx].AppliedHash = ""
+	}
+}
+
+// ObjectHash returns the hash of the given object, as processed from its template
+func ObjectHash(obj runtime.Object) (string, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return "", errs.Wrap(err, "unable to marshal the object")
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(raw))[:16], nil
+}
+
+// SpecHash returns the hash of the `spec` of the given object. For the kinds without `spec` (eg, RoleBindings), the hash covers
+// all the top-level fields but the type, the metadata and the status. Returns an empty string if there is nothing to hash
+func SpecHash(obj runtime.Object) (string, error) {
+	var content map[string]interface{}
+	if u, ok := obj.(runtime.Unstructured); ok {
+		content = u.UnstructuredContent()
+	} else {
+		var err error
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return "", errs.Wrap(err, "unable to convert the object")
+		}
+	}
+	spec, found := content["spec"]
+	if !found {
+		fields := map[string]interface{}{}
+		for name, value := range content {
+			switch name {
+			case "apiVersion", "kind", "metadata", "status":
+				continue
+			}
+			// the empty fields of the typed objects are not returned by the API server
+			if value != nil {
+				fields[name] = value
+			}
+		}
+		if len(fields) == 0 {
+			return "", nil
+		}
+		spec = fields
+	}
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return "", errs.Wrap(err, "unable to marshal the spec of the object")
+	}
+	// the first 8 bytes are enough to detect the changes, and keep the inventory small
+	return fmt.Sprintf("%x", sha256.Sum256(raw))[:16], nil
+}
+
+// EntriesInNamespace returns the entries of the objects in the given namespace
+func (i *Inventory) EntriesInNamespace(namespace string) []InventoryEntry {
+	if i == nil {
+		return nil
+	}
+	var result []InventoryEntry
+	for _, e := range i.Entries {
+		if e.Namespace == namespace {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// EntriesOfTemplateType returns the entries of the objects in the given namespace which were recorded under the given template type
+// (see `InventoryEntry.TemplateType`)
+func (i *Inventory) EntriesOfTemplateType(templateType, namespace string) []InventoryEntry {
+	var result []InventoryEntry
+	for _, e := range i.EntriesInNamespace(namespace) {
+		if e.TemplateType == templateType {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Contains returns true if the Inventory has an entry for the object of the given kind, namespace and name
+func (i *Inventory) Contains(gvk schema.GroupVersionKind, namespace, name string) bool {
+	if i == nil {
+		return false
+	}
+	for _, e := range i.Entries {
+		if e.matches(gvk, namespace, name, "") {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove removes the given entry from the Inventory
+func (i *Inventory) Remove(entry InventoryEntry) {
+	if i == nil {
+		return
+	}
+	for idx, e := range i.Entries {
+		if e == entry {
+			i.Entries = append(i.Entries[:idx], i.Entries[idx+1:]...)
+			return
+		}
+	}
+}
+
+// Compact removes the entries of the namespaced objects which are not in one of the given namespaces (ie, whose namespace
+// was deleted along with the objects) and returns the number of removed entries. The entries of the cluster-scoped objects are kept.
+func (i *Inventory) Compact(namespaces []string) int {
+	if i == nil {
+		return 0
+	}
+	entries := make([]InventoryEntry, 0, len(i.Entries))
+	for _, e := range i.Entries {
+		if e.Namespace == "" || containsString(namespaces, e.Namespace) {
+			entries = append(entries, e)
+		}
+	}
+	removed := len(i.Entries) - len(entries)
+	i.Entries = entries
+	return removed
+}
+
+// matches returns true if the given object corresponds to this entry. The objects are matched on their group and kind rather than
+// on their API version, since all the versions of a kind address the same objects, including those of the group which the kind
+// was moved to (eg, a `Deployment` of `extensions/v1beta1` and of `apps/v1`, see `movedKinds`)
+func (e InventoryEntry) matches(gvk schema.GroupVersionKind, namespace, name, generateName string) bool {
+	if !sameGroupKind(schema.FromAPIVersionAndKind(e.APIVersion, e.Kind).GroupKind(), gvk.GroupKind()) || e.Namespace != namespace {
+		return false
+	}
+	if generateName != "" && name == "" {
+		return e.GenerateName == generateName
+	}
+	return e.Name == name
+}
+
+// sameGroupKind returns true if both kinds are the same, or if one of them was moved to the group of the other one
+func sameGroupKind(a, b schema.GroupKind) bool {
+	if a == b {
+		return true
+	}
+	if a.Kind != b.Kind {
+		return false
+	}
+	if group, moved := movedKinds[a]; moved && group == b.Group {
+		return true
+	}
+	group, moved := movedKinds[b]
+	return moved && group == a.Group
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
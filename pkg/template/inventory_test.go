@@ -0,0 +1,313 @@
+package template_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestInventory(t *testing.T) {
+
+	cmGVK := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	secretGVK := corev1.SchemeGroupVersion.WithKind("Secret")
+
+	t.Run("record and find generated object", func(t *testing.T) {
+		// given
+		inv := template.NewInventory()
+
+		// when
+		inv.Record(cmGVK, "johnsmith-dev", "config-abcde", "config-")
+		inv.Record(secretGVK, "johnsmith-dev", "secret", "")
+
+		// then
+		name, found := inv.FindGenerated(cmGVK, "johnsmith-dev", "config-")
+		assert.True(t, found)
+		assert.Equal(t, "config-abcde", name)
+		_, found = inv.FindGenerated(cmGVK, "johnsmith-code", "config-")
+		assert.False(t, found)
+		_, found = inv.FindGenerated(secretGVK, "johnsmith-dev", "")
+		assert.False(t, found)
+	})
+
+	t.Run("record replaces existing entry", func(t *testing.T) {
+		// given
+		inv := template.NewInventory()
+		inv.Record(cmGVK, "johnsmith-dev", "config-abcde", "config-")
+		inv.Record(secretGVK, "johnsmith-dev", "secret", "")
+
+		// when
+		inv.Record(cmGVK, "johnsmith-dev", "config-fghij", "config-")
+		inv.Record(secretGVK, "johnsmith-dev", "secret", "")
+
+		// then
+		require.Len(t, inv.Entries, 2)
+		name, found := inv.FindGenerated(cmGVK, "johnsmith-dev", "config-")
+		assert.True(t, found)
+		assert.Equal(t, "config-fghij", name)
+	})
+
+	t.Run("contains", func(t *testing.T) {
+		// given
+		inv := template.NewInventory()
+		inv.Record(cmGVK, "johnsmith-dev", "config-abcde", "config-")
+		inv.Record(secretGVK, "johnsmith-dev", "secret", "")
+
+		// then
+		assert.True(t, inv.Contains(cmGVK, "johnsmith-dev", "config-abcde"))
+		assert.True(t, inv.Contains(secretGVK, "johnsmith-dev", "secret"))
+		assert.False(t, inv.Contains(cmGVK, "johnsmith-dev", "secret"))
+		assert.False(t, inv.Contains(secretGVK, "johnsmith-code", "secret"))
+	})
+
+	t.Run("marshal and parse", func(t *testing.T) {
+		// given
+		inv := template.NewInventory()
+		inv.Record(cmGVK, "johnsmith-dev", "config-abcde", "config-")
+
+		// when
+		content, err := inv.String()
+		require.NoError(t, err)
+		result, err := template.ParseInventory(content)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, inv, result)
+	})
+
+	t.Run("parse empty content", func(t *testing.T) {
+		// when
+		result, err := template.ParseInventory("")
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, result.Entries)
+	})
+
+	t.Run("parse invalid content", func(t *testing.T) {
+		// when
+		_, err := template.ParseInventory("{foo")
+
+		// then
+		require.Error(t, err)
+	})
+
+	t.Run("nil inventory", func(t *testing.T) {
+		// given
+		var inv *template.Inventory
+
+		// when
+		inv.Record(cmGVK, "johnsmith-dev", "config-abcde", "config-")
+		_, found := inv.FindGenerated(cmGVK, "johnsmith-dev", "config-")
+
+		// then
+		assert.False(t, found)
+		assert.Equal(t, 0, inv.Compact(nil))
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		// given
+		crqGVK := schema.GroupVersionKind{Group: "quota.openshift.io", Version: "v1", Kind: "ClusterResourceQuota"}
+		inv := template.NewInventory()
+		inv.Record(cmGVK, "johnsmith-dev", "config", "")
+		inv.Record(cmGVK, "johnsmith-stage", "config", "")
+		inv.Record(secretGVK, "johnsmith-stage", "secret", "")
+		inv.Record(crqGVK, "", "for-johnsmith", "")
+
+		// when
+		removed := inv.Compact([]string{"johnsmith-dev", "johnsmith-code"})
+
+		// then
+		assert.Equal(t, 2, removed)
+		assert.True(t, inv.Contains(cmGVK, "johnsmith-dev", "config"))
+		assert.True(t, inv.Contains(crqGVK, "", "for-johnsmith"))
+		assert.False(t, inv.Contains(cmGVK, "johnsmith-stage", "config"))
+		assert.False(t, inv.Contains(secretGVK, "johnsmith-stage", "secret"))
+	})
+
+	t.Run("record spec hash", func(t *testing.T) {
+		// given
+		inv := template.NewInventory()
+		inv.Record(cmGVK, "johnsmith-dev", "config", "")
+
+		// when
+		inv.RecordSpecHash(cmGVK, "johnsmith-dev", "config", "0123456789abcdef")
+		inv.RecordSpecHash(secretGVK, "johnsmith-dev", "unknown", "0123456789abcdef")
+
+		// then
+		require.Len(t, inv.Entries, 1)
+		assert.Equal(t, "0123456789abcdef", inv.Entries[0].SpecHash)
+		// recording the object again resets the hash
+		inv.Record(cmGVK, "johnsmith-dev", "config", "")
+		assert.Empty(t, inv.Entries[0].SpecHash)
+	})
+}
+
+func TestInventoryBounded(t *testing.T) {
+
+	cmGVK := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	newInventory := func(entries int) *template.Inventory {
+		inv := template.NewInventory()
+		for i := 0; i < entries; i++ {
+			name := fmt.Sprintf("config-%d", i)
+			inv.Record(cmGVK, "johnsmith-dev", name, "")
+			inv.RecordAppliedHash(cmGVK, "johnsmith-dev", name, "0123456789abcdef")
+		}
+		return inv
+	}
+
+	t.Run("within the limits", func(t *testing.T) {
+		// given
+		inv := newInventory(10)
+		expected, err := inv.String()
+		require.NoError(t, err)
+
+		// when
+		content, dropped, err := inv.Bounded(template.InventoryLimits{MaxSize: len(expected), MaxEntries: 10})
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, dropped)
+		assert.Equal(t, expected, content)
+	})
+
+	t.Run("applied hashes dropped when the size is exceeded", func(t *testing.T) {
+		// given
+		inv := newInventory(10)
+		full, err := inv.String()
+		require.NoError(t, err)
+
+		// when
+		content, dropped, err := inv.Bounded(template.InventoryLimits{MaxSize: len(full) - 1})
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, dropped)
+		assert.True(t, len(content) < len(full))
+		require.Len(t, inv.Entries, 10)
+		for _, e := range inv.Entries {
+			assert.Empty(t, e.AppliedHash)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		// given
+		inv := newInventory(10)
+
+		// when
+		content, _, err := inv.Bounded(template.InventoryLimits{MaxSize: 100})
+
+		// then
+		require.Error(t, err)
+		assert.True(t, template.IsInventoryLimitError(err))
+		assert.Empty(t, content)
+		// the entries are kept, since they are needed to delete the objects
+		assert.Len(t, inv.Entries, 10)
+	})
+
+	t.Run("too many entries", func(t *testing.T) {
+		// given
+		inv := newInventory(11)
+
+		// when
+		content, _, err := inv.Bounded(template.InventoryLimits{MaxEntries: 10})
+
+		// then
+		require.EqualError(t, err, "the inventory exceeds its limits: 11 entries exceed the maximum of 10 entries")
+		assert.True(t, template.IsInventoryLimitError(err))
+		assert.Empty(t, content)
+	})
+}
+
+func TestSpecHash(t *testing.T) {
+
+	t.Run("same spec in typed and unstructured objects", func(t *testing.T) {
+		// given
+		quota := &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "compute"},
+			Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("7Gi")}},
+		}
+		unstructuredQuota := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ResourceQuota",
+			"metadata":   map[string]interface{}{"namespace": "johnsmith-dev", "name": "compute", "resourceVersion": "2"},
+			"spec":       map[string]interface{}{"hard": map[string]interface{}{"limits.memory": "7Gi"}},
+			"status":     map[string]interface{}{"used": map[string]interface{}{"limits.memory": "1Gi"}},
+		}}
+
+		// when
+		hash, err := template.SpecHash(quota)
+		require.NoError(t, err)
+		unstructuredHash, err := template.SpecHash(unstructuredQuota)
+		require.NoError(t, err)
+
+		// then
+		assert.Len(t, hash, 16)
+		assert.Equal(t, hash, unstructuredHash)
+	})
+
+	t.Run("different spec", func(t *testing.T) {
+		// given
+		quota := &corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("7Gi")}}}
+		changed := quota.DeepCopy()
+		changed.Spec.Hard[corev1.ResourceLimitsMemory] = resource.MustParse("70Gi")
+
+		// when
+		hash, err := template.SpecHash(quota)
+		require.NoError(t, err)
+		changedHash, err := template.SpecHash(changed)
+		require.NoError(t, err)
+
+		// then
+		assert.NotEqual(t, hash, changedHash)
+	})
+
+	t.Run("same fields in typed and unstructured objects without spec", func(t *testing.T) {
+		// given
+		roleBinding := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "johnsmith-dev", Name: "user-edit"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "edit"},
+			Subjects:   []rbacv1.Subject{{APIGroup: "rbac.authorization.k8s.io", Kind: "User", Name: "johnsmith"}},
+		}
+		unstructuredRoleBinding := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "RoleBinding",
+			"metadata":   map[string]interface{}{"namespace": "johnsmith-dev", "name": "user-edit", "resourceVersion": "2"},
+			"roleRef":    map[string]interface{}{"apiGroup": "rbac.authorization.k8s.io", "kind": "ClusterRole", "name": "edit"},
+			"subjects":   []interface{}{map[string]interface{}{"apiGroup": "rbac.authorization.k8s.io", "kind": "User", "name": "johnsmith"}},
+		}}
+		changed := roleBinding.DeepCopy()
+		changed.RoleRef.Name = "admin"
+
+		// when
+		hash, err := template.SpecHash(roleBinding)
+		require.NoError(t, err)
+		unstructuredHash, err := template.SpecHash(unstructuredRoleBinding)
+		require.NoError(t, err)
+		changedHash, err := template.SpecHash(changed)
+		require.NoError(t, err)
+
+		// then
+		assert.Len(t, hash, 16)
+		assert.Equal(t, hash, unstructuredHash)
+		assert.NotEqual(t, hash, changedHash)
+	})
+
+	t.Run("no spec", func(t *testing.T) {
+		// when
+		hash, err := template.SpecHash(&unstructured.Unstructured{Object: map[string]interface{}{"kind": "ConfigMap"}})
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, hash)
+	})
+}
@@ -0,0 +1,115 @@
+package template
+
+import (
+	"time"
+
+	errs "github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// The operations recorded by the ChurnRecorder
+const (
+	CreateOperation = "create"
+	UpdateOperation = "update"
+	DeleteOperation = "delete"
+)
+
+// ChurnRecorder records the objects created, updated and deleted by the Processor, per kind and operation.
+// Consumers of this package can provide their own implementation in the Options of the Processor (eg, to use another metrics registry)
+type ChurnRecorder interface {
+	RecordChurn(kind, operation string)
+}
+
+// The outcomes of the objects applied by the Processor
+const (
+	createdOutcome   = "created"
+	updatedOutcome   = "updated"
+	unchangedOutcome = "unchanged"
+	skippedOutcome   = "skipped"
+	failedOutcome    = "failed"
+)
+
+// objectChurn counts the objects created, updated and deleted by the Processor, per kind and operation.
+// It is served along with the other metrics of the manager, and the churn over a time window can be obtained with a query
+// such as `topk(10, sum by (kind, operation) (increase(member_operator_template_objects_total[1h])))`
+var objectChurn = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "member_operator_template_objects_total",
+	Help: "Number of objects created, updated or deleted when applying the templates, per kind and operation",
+}, []string{"kind", "operation"})
+
+// annotationCompactions counts the compactions of the inventory and history annotations, per annotation
+var annotationCompactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "member_operator_annotation_compactions_total",
+	Help: "Number of compactions of the inventory and history annotations, per annotation",
+}, []string{"annotation"})
+
+// applyFailures counts the objects which could not be applied by the Processor, per kind and reason (as returned by the API server)
+var applyFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "member_operator_template_apply_failures_total",
+	Help: "Number of objects which could not be applied when applying the templates, per kind and reason",
+}, []string{"kind", "reason"})
+
+// appliedObjects counts the objects applied by the Processor, per kind and outcome (`created`, `updated`, `unchanged`, `skipped`
+// when they did not change since they were last applied, or `failed`).
+// The kinds which fail the most during a mass tier upgrade can be obtained with a query such as
+// `topk(10, sum by (kind) (increase(member_operator_template_applied_objects_total{outcome="failed"}[1h])))`
+var appliedObjects = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "member_operator_template_applied_objects_total",
+	Help: "Number of objects applied when applying the templates, per kind and outcome",
+}, []string{"kind", "outcome"})
+
+// applyDuration observes the time taken to apply a single object, per kind, including the failed attempts
+var applyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "member_operator_template_apply_duration_seconds",
+	Help:    "Time taken to apply a single object when applying the templates, per kind",
+	Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+}, []string{"kind"})
+
+// upgradedAPIVersions counts the objects of the templates whose API version was not served by the cluster anymore and was replaced
+// with the preferred version of their kind, per kind and API versions. The templates which still need to be fixed can be found with
+// a query such as `sum by (kind, from) (increase(member_operator_template_upgraded_api_versions_total[1d]))`
+var upgradedAPIVersions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "member_operator_template_upgraded_api_versions_total",
+	Help: "Number of objects of the templates whose API version was not served and was replaced with the preferred version, per kind and API versions",
+}, []string{"kind", "from", "to"})
+
+func init() {
+	metrics.Registry.MustRegister(objectChurn, annotationCompactions, applyFailures, appliedObjects, applyDuration, upgradedAPIVersions)
+}
+
+// recordApplied records an object of the given kind applied with the given outcome, which took the given duration
+func recordApplied(kind, outcome string, duration time.Duration) {
+	appliedObjects.WithLabelValues(kind, outcome).Inc()
+	applyDuration.WithLabelValues(kind).Observe(duration.Seconds())
+}
+
+// recordUpgradedAPIVersion records an object whose API version was replaced with the preferred version of its kind
+func recordUpgradedAPIVersion(from, to schema.GroupVersionKind) {
+	upgradedAPIVersions.WithLabelValues(from.Kind, from.GroupVersion().String(), to.GroupVersion().String()).Inc()
+}
+
+// RecordCompaction records a compaction of the given annotation
+func RecordCompaction(annotation string) {
+	annotationCompactions.WithLabelValues(annotation).Inc()
+}
+
+// recordApplyFailure records an object of the given kind which could not be applied because of the given error
+func recordApplyFailure(kind string, err error) {
+	reason := apierrors.ReasonForError(errs.Cause(err))
+	if reason == metav1.StatusReasonUnknown {
+		reason = "Unknown"
+	}
+	applyFailures.WithLabelValues(kind, string(reason)).Inc()
+}
+
+// prometheusChurnRecorder the default ChurnRecorder, which increments the objectChurn counter
+type prometheusChurnRecorder struct{}
+
+// RecordChurn increments the objectChurn counter for the given kind and operation
+func (prometheusChurnRecorder) RecordChurn(kind, operation string) {
+	objectChurn.WithLabelValues(kind, operation).Inc()
+}
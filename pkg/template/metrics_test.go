@@ -0,0 +1,253 @@
+package template_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func TestObjectChurnMetrics(t *testing.T) {
+	// given
+	user := getNameWithTimestamp("user")
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	obsolete := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: user, Name: "obsolete"}}
+	cl := test.NewFakeClient(t, obsolete)
+	inventory := template.NewInventory()
+	inventory.Record(corev1.SchemeGroupVersion.WithKind("ConfigMap"), user, "obsolete", "")
+	p := template.NewProcessor(cl, s).WithInventory(inventory)
+	tmpl, err := decodeTemplate(decoder, rolebindingTmpl)
+	require.NoError(t, err)
+	objs, err := p.Process(tmpl, map[string]string{"USERNAME": user})
+	require.NoError(t, err)
+	created := churn(t, "RoleBinding", "create")
+	updated := churn(t, "RoleBinding", "update")
+	deleted := churn(t, "ConfigMap", "delete")
+
+	// when
+	err = p.Apply(objs)
+	require.NoError(t, err)
+	// apply the template again, which updates the objects
+	tmpl, err = decodeTemplate(decoder, rolebindingTmpl)
+	require.NoError(t, err)
+	objs, err = p.Process(tmpl, map[string]string{"USERNAME": user})
+	require.NoError(t, err)
+	err = p.Apply(objs)
+	require.NoError(t, err)
+	err = p.Prune(user, objs)
+	require.NoError(t, err)
+
+	// then
+	assert.Equal(t, created+1, churn(t, "RoleBinding", "create"))
+	assert.Equal(t, updated+1, churn(t, "RoleBinding", "update"))
+	assert.Equal(t, deleted+1, churn(t, "ConfigMap", "delete"))
+}
+
+func TestCustomChurnRecorder(t *testing.T) {
+	// given
+	user := getNameWithTimestamp("user")
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	obsolete := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: user, Name: "obsolete"}}
+	cl := test.NewFakeClient(t, obsolete)
+	inventory := template.NewInventory()
+	inventory.Record(corev1.SchemeGroupVersion.WithKind("ConfigMap"), user, "obsolete", "")
+	recorder := &fakeChurnRecorder{}
+	p := template.NewProcessorWithOptions(cl, s, template.Options{
+		Inventory:     inventory,
+		ChurnRecorder: recorder,
+	})
+	tmpl, err := decodeTemplate(decoder, rolebindingTmpl)
+	require.NoError(t, err)
+	objs, err := p.Process(tmpl, map[string]string{"USERNAME": user})
+	require.NoError(t, err)
+
+	// when
+	err = p.Apply(objs)
+	require.NoError(t, err)
+	err = p.Prune(user, objs)
+	require.NoError(t, err)
+
+	// then
+	assert.Equal(t, []string{"RoleBinding/" + template.CreateOperation, "ConfigMap/" + template.DeleteOperation}, recorder.records)
+}
+
+func TestApplyFailureMetrics(t *testing.T) {
+	// given
+	user := getNameWithTimestamp("user")
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	cl := test.NewFakeClient(t)
+	cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+		return apierrors.NewForbidden(schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "rolebindings"}, "edit", errors.New("mock error"))
+	}
+	p := template.NewProcessor(cl, s)
+	tmpl, err := decodeTemplate(decoder, rolebindingTmpl)
+	require.NoError(t, err)
+	objs, err := p.Process(tmpl, map[string]string{"USERNAME": user})
+	require.NoError(t, err)
+	failures := applyFailures(t, "RoleBinding", "Forbidden")
+
+	// when
+	err = p.Apply(objs)
+
+	// then
+	require.Error(t, err)
+	assert.Equal(t, failures+1, applyFailures(t, "RoleBinding", "Forbidden"))
+}
+
+func TestAppliedObjectsMetrics(t *testing.T) {
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+
+	t.Run("created and updated", func(t *testing.T) {
+		// given
+		user := getNameWithTimestamp("user")
+		p := template.NewProcessor(test.NewFakeClient(t), s)
+		created := appliedObjects(t, "RoleBinding", "created")
+		updated := appliedObjects(t, "RoleBinding", "updated")
+		durations := applyDurations(t, "RoleBinding")
+
+		// when
+		for i := 0; i < 2; i++ {
+			tmpl, err := decodeTemplate(decoder, rolebindingTmpl)
+			require.NoError(t, err)
+			objs, err := p.Process(tmpl, map[string]string{"USERNAME": user})
+			require.NoError(t, err)
+			err = p.Apply(objs)
+			require.NoError(t, err)
+		}
+
+		// then
+		assert.Equal(t, created+1, appliedObjects(t, "RoleBinding", "created"))
+		assert.Equal(t, updated+1, appliedObjects(t, "RoleBinding", "updated"))
+		assert.Equal(t, durations+2, applyDurations(t, "RoleBinding"))
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		// given
+		user := getNameWithTimestamp("user")
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessor(cl, s)
+		tmpl, err := decodeTemplate(decoder, rolebindingTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, map[string]string{"USERNAME": user})
+		require.NoError(t, err)
+		err = p.Apply(objs)
+		require.NoError(t, err)
+		// the API server keeps the resource version when the update is a no-op
+		cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			return nil
+		}
+		unchanged := appliedObjects(t, "RoleBinding", "unchanged")
+		updated := churn(t, "RoleBinding", "update")
+
+		// when
+		tmpl, err = decodeTemplate(decoder, rolebindingTmpl)
+		require.NoError(t, err)
+		objs, err = p.Process(tmpl, map[string]string{"USERNAME": user})
+		require.NoError(t, err)
+		err = p.Apply(objs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, unchanged+1, appliedObjects(t, "RoleBinding", "unchanged"))
+		// not counted as churn
+		assert.Equal(t, updated, churn(t, "RoleBinding", "update"))
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		// given
+		user := getNameWithTimestamp("user")
+		cl := test.NewFakeClient(t)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("mock error")
+		}
+		p := template.NewProcessor(cl, s)
+		tmpl, err := decodeTemplate(decoder, rolebindingTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, map[string]string{"USERNAME": user})
+		require.NoError(t, err)
+		failed := appliedObjects(t, "RoleBinding", "failed")
+		durations := applyDurations(t, "RoleBinding")
+
+		// when
+		err = p.Apply(objs)
+
+		// then
+		require.Error(t, err)
+		assert.Equal(t, failed+1, appliedObjects(t, "RoleBinding", "failed"))
+		assert.Equal(t, durations+1, applyDurations(t, "RoleBinding"))
+	})
+}
+
+// fakeChurnRecorder records the churn as `<kind>/<operation>` entries
+type fakeChurnRecorder struct {
+	records []string
+}
+
+func (r *fakeChurnRecorder) RecordChurn(kind, operation string) {
+	r.records = append(r.records, kind+"/"+operation)
+}
+
+// churn returns the current value of the churn counter for the given kind and operation
+func churn(t *testing.T, kind, operation string) float64 {
+	return counterValue(t, "member_operator_template_objects_total", map[string]string{"kind": kind, "operation": operation})
+}
+
+// applyFailures returns the current value of the apply failures counter for the given kind and reason
+func applyFailures(t *testing.T, kind, reason string) float64 {
+	return counterValue(t, "member_operator_template_apply_failures_total", map[string]string{"kind": kind, "reason": reason})
+}
+
+// appliedObjects returns the current value of the applied objects counter for the given kind and outcome
+func appliedObjects(t *testing.T, kind, outcome string) float64 {
+	return counterValue(t, "member_operator_template_applied_objects_total", map[string]string{"kind": kind, "outcome": outcome})
+}
+
+// applyDurations returns the current number of observations of the apply duration histogram for the given kind
+func applyDurations(t *testing.T, kind string) uint64 {
+	return findMetric(t, "member_operator_template_apply_duration_seconds", map[string]string{"kind": kind}).GetHistogram().GetSampleCount()
+}
+
+// counterValue returns the current value of the given counter for the given labels
+func counterValue(t *testing.T, name string, expected map[string]string) float64 {
+	return findMetric(t, name, expected).GetCounter().GetValue()
+}
+
+// findMetric returns the given metric for the given labels, or nil if it was not recorded yet
+func findMetric(t *testing.T, name string, expected map[string]string) *dto.Metric {
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if reflect.DeepEqual(labels, expected) {
+				return m
+			}
+		}
+	}
+	return nil
+}
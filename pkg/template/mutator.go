@@ -0,0 +1,59 @@
+package template
+
+import (
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Mutator a hook which tweaks the processed objects before they are applied (eg, to inject annotations for a sidecar, or to rewrite
+// the registries of the images on disconnected clusters), so that the templates do not need to be forked per environment
+type Mutator interface {
+	// Mutate changes the given object in place. Returns an error if the object cannot be mutated, in which case the template
+	// is not processed
+	Mutate(obj *unstructured.Unstructured) error
+}
+
+// MutatorFunc a func which can be used as a Mutator
+type MutatorFunc func(obj *unstructured.Unstructured) error
+
+// Mutate calls the func with the given object
+func (f MutatorFunc) Mutate(obj *unstructured.Unstructured) error {
+	return f(obj)
+}
+
+// WithMutators returns a copy of this Processor which also runs the given mutators on the processed objects, after the mutators
+// it already has
+func (p Processor) WithMutators(mutators ...Mutator) Processor {
+	p.mutators = append(append(make([]Mutator, 0, len(p.mutators)+len(mutators)), p.mutators...), mutators...)
+	return p
+}
+
+// mutate runs the mutators of the Processor on the given objects, in order. The typed objects are converted into unstructured ones
+// beforehand, so that all the mutators deal with the same representation
+func (p Processor) mutate(objs []runtime.RawExtension) error {
+	if len(p.mutators) == 0 {
+		return nil
+	}
+	for i := range objs {
+		if objs[i].Object == nil {
+			continue
+		}
+		obj, ok := objs[i].Object.(*unstructured.Unstructured)
+		if !ok {
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(objs[i].Object)
+			if err != nil {
+				gvk := objs[i].Object.GetObjectKind().GroupVersionKind()
+				return errs.Wrapf(err, "unable to convert resource of kind: %s, version: %s", gvk.Kind, gvk.Version)
+			}
+			obj = &unstructured.Unstructured{Object: content}
+			objs[i].Object = obj
+		}
+		for _, mutator := range p.mutators {
+			if err := mutator.Mutate(obj); err != nil {
+				return errs.Wrapf(err, "unable to mutate the %s '%s'", obj.GetKind(), obj.GetName())
+			}
+		}
+	}
+	return nil
+}
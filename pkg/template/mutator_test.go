@@ -0,0 +1,159 @@
+package template_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const mutatedTmpl = `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: mutated
+objects:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+    namespace: ${USERNAME}-dev
+  data:
+    image: quay.io/codeready-toolchain/app:latest
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: app
+    namespace: ${USERNAME}-dev
+  spec:
+    containers:
+    - name: app
+      image: quay.io/codeready-toolchain/app:latest
+parameters:
+- name: USERNAME
+  required: true
+`
+
+func TestMutators(t *testing.T) {
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	tmpl, err := decodeTemplate(decoder, mutatedTmpl)
+	require.NoError(t, err)
+	values := map[string]string{"USERNAME": "johnsmith"}
+
+	t.Run("objects mutated in order", func(t *testing.T) {
+		// given
+		p := template.NewProcessorWithOptions(test.NewFakeClient(t), s, template.Options{
+			Mutators: []template.Mutator{annotate("sidecar.istio.io/inject", "true")},
+		}).WithMutators(rewriteRegistry("quay.io", "registry.local:5000"), annotate("sidecar.istio.io/inject", "false"))
+
+		// when
+		objs, err := p.Process(tmpl.DeepCopy(), values)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, objs, 2)
+		for _, rawObj := range objs {
+			obj, ok := rawObj.Object.(*unstructured.Unstructured)
+			require.True(t, ok)
+			assert.Equal(t, "false", obj.GetAnnotations()["sidecar.istio.io/inject"])
+		}
+		containers, _, _ := unstructured.NestedSlice(objs[1].Object.(*unstructured.Unstructured).Object, "spec", "containers")
+		require.Len(t, containers, 1)
+		assert.Equal(t, "registry.local:5000/codeready-toolchain/app:latest", containers[0].(map[string]interface{})["image"])
+		// the other fields are left untouched
+		assert.Equal(t, "quay.io/codeready-toolchain/app:latest", objs[0].Object.(*unstructured.Unstructured).Object["data"].(map[string]interface{})["image"])
+	})
+
+	t.Run("mutated objects applied", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessor(cl, s).WithMutators(annotate("sidecar.istio.io/inject", "true"))
+		objs, err := p.Process(tmpl.DeepCopy(), values)
+		require.NoError(t, err)
+
+		// when
+		err = p.Apply(objs)
+
+		// then
+		require.NoError(t, err)
+		cm := &corev1.ConfigMap{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "settings"}, cm)
+		require.NoError(t, err)
+		assert.Equal(t, "true", cm.Annotations["sidecar.istio.io/inject"])
+	})
+
+	t.Run("original processor not changed", func(t *testing.T) {
+		// given
+		p := template.NewProcessor(test.NewFakeClient(t), s)
+		_ = p.WithMutators(annotate("sidecar.istio.io/inject", "true"))
+
+		// when
+		objs, err := p.Process(tmpl.DeepCopy(), values)
+
+		// then
+		require.NoError(t, err)
+		for _, rawObj := range objs {
+			obj := rawObj.Object.(*unstructured.Unstructured)
+			assert.NotContains(t, obj.GetAnnotations(), "sidecar.istio.io/inject")
+		}
+	})
+
+	t.Run("mutation fails", func(t *testing.T) {
+		// given
+		p := template.NewProcessor(test.NewFakeClient(t), s).WithMutators(template.MutatorFunc(func(obj *unstructured.Unstructured) error {
+			if obj.GetKind() == "Pod" {
+				return errors.New("mock error")
+			}
+			return nil
+		}))
+
+		// when
+		_, err := p.Process(tmpl.DeepCopy(), values)
+
+		// then
+		require.EqualError(t, err, "unable to mutate the Pod 'app': mock error")
+	})
+}
+
+// annotate returns a Mutator which sets the given annotation on all the objects
+func annotate(key, value string) template.Mutator {
+	return template.MutatorFunc(func(obj *unstructured.Unstructured) error {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[key] = value
+		obj.SetAnnotations(annotations)
+		return nil
+	})
+}
+
+// rewriteRegistry returns a Mutator which replaces the given registry in the images of the containers of the pods
+func rewriteRegistry(from, to string) template.Mutator {
+	return template.MutatorFunc(func(obj *unstructured.Unstructured) error {
+		if obj.GetKind() != "Pod" {
+			return nil
+		}
+		containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "containers")
+		if err != nil {
+			return err
+		}
+		for _, c := range containers {
+			container := c.(map[string]interface{})
+			if image, ok := container["image"].(string); ok && strings.HasPrefix(image, from+"/") {
+				container["image"] = to + strings.TrimPrefix(image, from)
+			}
+		}
+		return unstructured.SetNestedSlice(obj.Object, containers, "spec", "containers")
+	})
+}
@@ -0,0 +1,100 @@
+package template
+
+import (
+	"context"
+	"reflect"
+
+	errs "github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ApplyPolicyAnnotation the annotation of the template objects which defines how they are applied when they already exist
+const ApplyPolicyAnnotation = "toolchain.dev.openshift.com/apply-policy"
+
+// ApplyPolicy the way a template object is applied when it already exists
+type ApplyPolicy string
+
+const (
+	// EnforcePolicy the existing object is replaced with the template object, hence the changes of the users are reverted (default)
+	EnforcePolicy ApplyPolicy = "enforce"
+	// CreateOnlyPolicy the object is created if it does not exist, and left untouched otherwise, hence the users can freely edit it
+	CreateOnlyPolicy ApplyPolicy = "create-only"
+	// MergePolicy the fields of the template object are set on the existing object, while the other fields (eg, added by the users) are kept.
+	// The maps (eg, the labels) are merged, while the lists are replaced.
+	MergePolicy ApplyPolicy = "merge"
+)
+
+// applyPolicyOf returns the apply policy of the given object, as specified in its annotation. Defaults to `enforce`
+func applyPolicyOf(acc metav1.Object) (ApplyPolicy, error) {
+	switch policy := ApplyPolicy(acc.GetAnnotations()[ApplyPolicyAnnotation]); policy {
+	case "":
+		return EnforcePolicy, nil
+	case EnforcePolicy, CreateOnlyPolicy, MergePolicy:
+		return policy, nil
+	default:
+		return "", errs.Errorf("invalid apply policy '%s' of resource '%s' in namespace '%s'", policy, acc.GetName(), acc.GetNamespace())
+	}
+}
+
+// createObj creates the given object, unless it already exists. Returns the outcome of the operation (`created` or `unchanged`)
+func createObj(cl Client, obj runtime.Object) (string, error) {
+	if err := cl.Create(context.TODO(), obj); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return unchangedOutcome, nil
+		}
+		return "", errs.Wrapf(err, "failed to create object %v", obj)
+	}
+	return createdOutcome, nil
+}
+
+// mergeObj creates the given object, or sets its fields on the existing object. Returns the outcome of the operation
+// (`created`, `updated` or `unchanged`, when the existing object already has all the fields of the given object)
+func mergeObj(cl Client, obj *unstructured.Unstructured) (string, error) {
+	if err := cl.Create(context.TODO(), obj); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return "", errs.Wrapf(err, "failed to create object %v", obj)
+		}
+	} else {
+		return createdOutcome, nil
+	}
+	gvk := obj.GroupVersionKind()
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(gvk)
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, existing); err != nil {
+		return "", errs.Wrapf(err, "unable to get the resource of kind '%s' and name '%s' in namespace '%s'", gvk.Kind, obj.GetName(), obj.GetNamespace())
+	}
+	content := runtime.DeepCopyJSON(obj.Object)
+	unstructured.RemoveNestedField(content, "metadata", "resourceVersion")
+	merged := existing.DeepCopy()
+	mergeInto(merged.Object, content)
+	if reflect.DeepEqual(merged.Object, existing.Object) {
+		obj.Object = existing.Object
+		return unchangedOutcome, nil
+	}
+	if err := cl.Update(context.TODO(), merged); err != nil {
+		return "", errs.Wrapf(err, "unable to update the resource of kind '%s' and name '%s' in namespace '%s'", gvk.Kind, obj.GetName(), obj.GetNamespace())
+	}
+	obj.Object = merged.Object
+	if merged.GetResourceVersion() == existing.GetResourceVersion() {
+		return unchangedOutcome, nil
+	}
+	return updatedOutcome, nil
+}
+
+// mergeInto sets the fields of the given source on the given destination. The nested maps are merged recursively,
+// while the other values (including the lists) are replaced
+func mergeInto(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeInto(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
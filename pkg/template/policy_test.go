@@ -0,0 +1,139 @@
+package template_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const configMapWithApplyPolicyTmpl = `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: settings
+objects:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+    namespace: ${USERNAME}-dev
+    annotations:
+      toolchain.dev.openshift.com/apply-policy: ${POLICY}
+    labels:
+      provider: codeready-toolchain
+  data:
+    color: blue
+parameters:
+- name: USERNAME
+  required: true
+- name: POLICY
+  required: true
+`
+
+func TestApplyPolicy(t *testing.T) {
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+
+	// the config map as edited by the user
+	edited := func() *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "johnsmith-dev",
+				Name:      "settings",
+				Labels:    map[string]string{"team": "blue"},
+			},
+			Data: map[string]string{
+				"color": "red",
+				"size":  "large",
+			},
+		}
+	}
+
+	apply := func(t *testing.T, policy string) (*corev1.ConfigMap, error) {
+		cl := test.NewFakeClient(t, edited())
+		p := template.NewProcessor(cl, s)
+		tmpl, err := decodeTemplate(decoder, configMapWithApplyPolicyTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, map[string]string{"USERNAME": "johnsmith", "POLICY": policy})
+		require.NoError(t, err)
+		if err := p.Apply(objs); err != nil {
+			return nil, err
+		}
+		cm := &corev1.ConfigMap{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "settings"}, cm)
+		require.NoError(t, err)
+		return cm, nil
+	}
+
+	t.Run("enforce", func(t *testing.T) {
+		// when
+		cm, err := apply(t, "enforce")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"color": "blue"}, cm.Data)
+		assert.Equal(t, map[string]string{"provider": "codeready-toolchain"}, cm.Labels)
+	})
+
+	t.Run("create-only", func(t *testing.T) {
+		// when
+		cm, err := apply(t, "create-only")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, edited().Data, cm.Data)
+		assert.Equal(t, edited().Labels, cm.Labels)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		// when
+		cm, err := apply(t, "merge")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"color": "blue", "size": "large"}, cm.Data)
+		assert.Equal(t, map[string]string{"provider": "codeready-toolchain", "team": "blue"}, cm.Labels)
+		assert.Equal(t, "merge", cm.Annotations[template.ApplyPolicyAnnotation])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		// when
+		_, err := apply(t, "replace")
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid apply policy 'replace' of resource 'settings' in namespace 'johnsmith-dev'")
+	})
+
+	t.Run("created regardless of the policy", func(t *testing.T) {
+		for _, policy := range []string{"enforce", "create-only", "merge"} {
+			t.Run(policy, func(t *testing.T) {
+				// given
+				cl := test.NewFakeClient(t)
+				p := template.NewProcessor(cl, s)
+				tmpl, err := decodeTemplate(decoder, configMapWithApplyPolicyTmpl)
+				require.NoError(t, err)
+				objs, err := p.Process(tmpl, map[string]string{"USERNAME": "johnsmith", "POLICY": policy})
+				require.NoError(t, err)
+
+				// when
+				err = p.Apply(objs)
+
+				// then
+				require.NoError(t, err)
+				cm := &corev1.ConfigMap{}
+				err = cl.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: "settings"}, cm)
+				require.NoError(t, err)
+				assert.Equal(t, map[string]string{"color": "blue"}, cm.Data)
+			})
+		}
+	})
+}
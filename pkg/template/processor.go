@@ -2,35 +2,195 @@ package template
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
 	"math/rand"
 	"time"
 
+	"github.com/codeready-toolchain/member-operator/pkg/tracing"
+	"github.com/go-logr/logr"
 	templatev1 "github.com/openshift/api/template/v1"
 	"github.com/openshift/library-go/pkg/template/generator"
 	"github.com/openshift/library-go/pkg/template/templateprocessing"
 	"github.com/pkg/errors"
 	errs "github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
 
+var log = logf.Log.WithName("template")
+
+// ClusterResourcesType the reserved type of the template which contains the cluster-scoped resources
+// to provision for each user (eg, ClusterResourceQuotas, ClusterRoleBindings, etc.), rather than a namespace
+const ClusterResourcesType = "clusterresources"
+
+// GeneratedObjectLabel the label of the objects created with a `metadata.generateName`, whose value is the key of the template object
+// from which they were created, ie, a hash of the type of the template and of the `generateName`
+const GeneratedObjectLabel = "toolchain.dev.openshift.com/generated-object"
+
+// Client the operations of the Kubernetes client used by the Processor to apply and delete the objects.
+// It is satisfied by the clients of the controller-runtime.
+type Client interface {
+	client.Reader
+	client.Writer
+}
+
+// Converter converts the templates between their internal and external versions. It is satisfied by a `*runtime.Scheme`
+// in which the OpenShift template API is registered.
+type Converter interface {
+	Convert(in, out interface{}, context interface{}) error
+}
+
+// Options the optional settings of a Processor
+type Options struct {
+	// ProtobufClient the client used to apply the objects of native kinds with the protobuf content type (see `NewProtobufClient`).
+	// The default client is used if it is nil
+	ProtobufClient Client
+	// Inventory the inventory in which the applied objects are recorded, and which is used to find the objects that were previously
+	// created with a `metadata.generateName`. The objects are not recorded if it is nil
+	Inventory *Inventory
+	// ChurnRecorder the recorder of the objects created, updated and deleted by the Processor. Defaults to the Prometheus counter
+	// served with the metrics of the manager
+	ChurnRecorder ChurnRecorder
+	// RESTMapper the mapper used to replace the API versions of the processed objects which are not served by the cluster anymore
+	// with the preferred version of their kind. The API versions are left untouched if it is nil
+	RESTMapper meta.RESTMapper
+	// Cache the reader in which the existing objects are looked up before being updated, typically the cache of the manager.
+	// The objects which are not found in the cache are read from the API server. All objects are read from the API server if it is nil
+	Cache client.Reader
+	// Decrypter the decrypter of the values of the encrypted Secrets and of the SealedSecrets of the templates (see `EncryptedAnnotation`).
+	// The SealedSecrets are applied as is and the encrypted Secrets cannot be applied if it is nil
+	Decrypter Decrypter
+	// Fragments the getter of the shared fragments included by the templates (see `IncludeAnnotation`). The templates which include
+	// fragments cannot be processed if it is nil
+	Fragments FragmentGetter
+	// Span the span (typically of the reconciliation) under which the processing of the templates and the apply of each object
+	// are traced. Nothing is traced if it is nil
+	Span *tracing.Span
+	// Guardrails the limits on the number, the size and the kinds of the objects of the processed templates. No limit is enforced by default
+	Guardrails Guardrails
+	// DeltaOnly only the objects which were added or changed since they were last applied (according to the hashes recorded in the
+	// Inventory) are applied, the other ones are left untouched. All the objects are applied if it is false or if there is no Inventory
+	DeltaOnly bool
+	// Mutators the hooks which tweak the processed objects before they are applied, in order (see `Mutator`)
+	Mutators []Mutator
+	// TemplateType the type of the template under which the applied objects are recorded in the Inventory, and which restricts the
+	// objects deleted by `Prune` to those recorded under the same type (see `InventoryEntry.TemplateType`). Empty by default
+	TemplateType string
+	// Interrupted the func which tells whether the applies in chunks must stop before their next chunk, typically because the operator
+	// is shutting down (see `ApplyChunks`). The applies are never interrupted if it is nil
+	Interrupted func() bool
+	// Logger the logger of the apply of each object (at the debug level, ie, `V(1)`), typically the logger of the reconciliation
+	// so that the lines hold its correlation ID. Defaults to the `template` logger
+	Logger logr.Logger
+}
+
 // Processor the tool that will process and apply a template with variables
 type Processor struct {
-	cl     client.Client
-	scheme *runtime.Scheme
+	cl            Client
+	protoClient   Client
+	scheme        Converter
+	inventory     *Inventory
+	churnRecorder ChurnRecorder
+	mapper        meta.RESTMapper
+	cache         client.Reader
+	decrypter     Decrypter
+	fragments     FragmentGetter
+	span          *tracing.Span
+	guardrails    Guardrails
+	deltaOnly     bool
+	mutators      []Mutator
+	templateType  string
+	interrupted   func() bool
+	logger        logr.Logger
 }
 
 // NewProcessor returns a new Processor
-func NewProcessor(cl client.Client, scheme *runtime.Scheme) Processor {
-	return Processor{cl: cl, scheme: scheme}
+func NewProcessor(cl Client, scheme Converter) Processor {
+	return NewProcessorWithOptions(cl, scheme, Options{})
+}
+
+// NewProcessorWithOptions returns a new Processor with the given options
+func NewProcessorWithOptions(cl Client, scheme Converter, options Options) Processor {
+	churnRecorder := options.ChurnRecorder
+	if churnRecorder == nil {
+		churnRecorder = prometheusChurnRecorder{}
+	}
+	logger := options.Logger
+	if logger == nil {
+		logger = log
+	}
+	return Processor{
+		cl:            cl,
+		protoClient:   options.ProtobufClient,
+		scheme:        scheme,
+		inventory:     options.Inventory,
+		churnRecorder: churnRecorder,
+		mapper:        options.RESTMapper,
+		cache:         options.Cache,
+		decrypter:     options.Decrypter,
+		fragments:     options.Fragments,
+		span:          options.Span,
+		guardrails:    options.Guardrails,
+		deltaOnly:     options.DeltaOnly,
+		mutators:      options.Mutators,
+		templateType:  options.TemplateType,
+		interrupted:   options.Interrupted,
+		logger:        logger,
+	}
+}
+
+// WithInventory returns a copy of this Processor which records the applied objects in the given inventory,
+// and which relies on it to find the objects that were previously created with a `metadata.generateName`
+func (p Processor) WithInventory(inv *Inventory) Processor {
+	p.inventory = inv
+	return p
+}
+
+// WithTemplateType returns a copy of this Processor which records the applied objects under the given template type in the inventory,
+// and which only prunes the objects recorded under that type (see `Options.TemplateType`)
+func (p Processor) WithTemplateType(templateType string) Processor {
+	p.templateType = templateType
+	return p
+}
+
+// WithLogger returns a copy of this Processor which logs the apply of each object with the given logger (see `Options.Logger`)
+func (p Processor) WithLogger(logger logr.Logger) Processor {
+	p.logger = logger
+	return p
+}
+
+// WithDeltaOnly returns a copy of this Processor which only applies the objects that were added or changed since they were
+// last applied, if the given flag is true (see `Options.DeltaOnly`)
+func (p Processor) WithDeltaOnly(deltaOnly bool) Processor {
+	p.deltaOnly = deltaOnly
+	return p
 }
 
-// Process processes the template (ie, replaces the variables with their actual values) and optionally filters the result
-// to return a subset of the template objects
+// Process processes the template (ie, includes the fragments it refers to and replaces the variables with their actual values)
+// and optionally filters the result to return a subset of the template objects. The objects which require kinds that are not served
+// by the cluster are left out (see `RequiresAPIAnnotation`) and the remaining ones are tweaked by the mutators of the Processor.
+// Returns a `*ValidationError` if the objects of the template violate the guardrails of the Processor
 func (p Processor) Process(tmpl *templatev1.Template, values map[string]string, filters ...FilterFunc) ([]runtime.RawExtension, error) {
+	span := p.span.Child("process template", "template", tmpl.Name)
+	objs, err := p.process(tmpl, values, filters...)
+	span.SetAttributes("objects", len(objs))
+	span.End(err)
+	return objs, err
+}
+
+func (p Processor) process(tmpl *templatev1.Template, values map[string]string, filters ...FilterFunc) ([]runtime.RawExtension, error) {
+	tmpl, err := Compose(tmpl, p.fragments)
+	if err != nil {
+		return nil, err
+	}
 	// inject variables in the twmplate
 	for param, val := range values {
 		v := templateprocessing.GetParameterByName(tmpl, param)
@@ -50,52 +210,275 @@ func (p Processor) Process(tmpl *templatev1.Template, values map[string]string,
 	if err := p.scheme.Convert(tmpl, &result, nil); err != nil {
 		return nil, errs.Wrap(err, "failed to convert template to external template object")
 	}
-	return Filter(result.Objects, filters...), nil
+	if err := p.guardrails.validate(tmpl.Name, result.Objects); err != nil {
+		return nil, err
+	}
+	if err := p.upgradeAPIVersions(result.Objects); err != nil {
+		return nil, err
+	}
+	objs, err := p.retainServedObjects(result.Objects)
+	if err != nil {
+		return nil, err
+	}
+	objs = Filter(objs, filters...)
+	if err := p.mutate(objs); err != nil {
+		return nil, err
+	}
+	return objs, nil
 }
 
-// Apply applies the objects, ie, creates or updates them on the cluster
+// Apply applies the objects, ie, creates or updates them on the cluster.
+// Objects with a `metadata.generateName` but no `metadata.name` are only created once: their generated name
+// is recorded in the inventory (if any), they are labelled with the `GeneratedObjectLabel` and they are left untouched
+// during the subsequent calls.
 func (p Processor) Apply(objs []runtime.RawExtension) error {
+	defer trackApply()()
 	for _, rawObj := range objs {
-		obj := rawObj.Object
-		if obj == nil {
-			continue
-		}
-		gvk := obj.GetObjectKind().GroupVersionKind()
-		if err := createOrUpdateObj(p.cl, obj); err != nil {
-			return errs.Wrapf(err, "unable to create resource of kind: %s, version: %s", gvk.Kind, gvk.Version)
+		if _, _, err := p.applyObj(rawObj.Object); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func createOrUpdateObj(cl client.Client, obj runtime.Object) error {
-	if err := cl.Create(context.TODO(), obj); err != nil {
-		if !apierrors.IsAlreadyExists(err) {
-			return errs.Wrapf(err, "failed to create object %v", obj)
+// applyObj applies the given object, or the Secret it holds if it is encrypted (see `Decrypter`). Returns the object which was
+// applied, and `true` if it was created, `false` if it was updated or left untouched. The objects which did not change since
+// they were last applied are skipped if the Processor only applies the deltas
+func (p Processor) applyObj(obj runtime.Object) (runtime.Object, bool, error) {
+	if obj == nil {
+		return nil, false, nil
+	}
+	hash, skip, err := p.unchangedSinceApplied(obj)
+	if err != nil || skip {
+		return obj, false, err
+	}
+	decrypted, err := p.decrypt(obj)
+	if err != nil {
+		return nil, false, err
+	}
+	gvk := decrypted.GetObjectKind().GroupVersionKind()
+	cl, applied, err := p.clientFor(decrypted)
+	if err != nil {
+		return nil, false, err
+	}
+	acc, err := meta.Accessor(applied)
+	if err != nil {
+		return nil, false, errs.Wrapf(err, "invalid resource of kind: %s, version: %s", gvk.Kind, gvk.Version)
+	}
+	span := p.span.Child("apply", "kind", gvk.Kind, "namespace", acc.GetNamespace(), "name", acc.GetName())
+	start := time.Now()
+	var outcome string
+	if acc.GetName() == "" && acc.GetGenerateName() != "" {
+		var created bool
+		created, err = p.createGeneratedObj(cl, gvk, applied, acc)
+		outcome = unchangedOutcome
+		if created {
+			outcome = createdOutcome
+		}
+	} else {
+		outcome, err = p.applyWithPolicy(cl, applied, acc)
+		if err == nil {
+			p.inventory.RecordWithTemplateType(p.templateType, gvk, acc.GetNamespace(), acc.GetName(), "")
+			p.inventory.RecordAppliedHash(gvk, acc.GetNamespace(), acc.GetName(), hash)
+		}
+	}
+	if err != nil {
+		recordApplied(gvk.Kind, failedOutcome, time.Since(start))
+		recordApplyFailure(gvk.Kind, err)
+		span.End(err)
+		return nil, false, errs.Wrapf(err, "unable to create resource of kind: %s, version: %s", gvk.Kind, gvk.Version)
+	}
+	recordApplied(gvk.Kind, outcome, time.Since(start))
+	p.logger.V(1).Info("object applied", "kind", gvk.Kind, "namespace", acc.GetNamespace(), "name", acc.GetName(), "outcome", outcome,
+		"duration", time.Since(start).String())
+	span.SetAttributes("outcome", outcome)
+	span.End(nil)
+	switch outcome {
+	case createdOutcome:
+		p.churnRecorder.RecordChurn(gvk.Kind, CreateOperation)
+	case updatedOutcome:
+		p.churnRecorder.RecordChurn(gvk.Kind, UpdateOperation)
+	}
+	// the decrypted content is not copied back into the template object
+	if decrypted != obj {
+		return applied, outcome == createdOutcome, nil
+	}
+	if err := syncBack(applied, obj); err != nil {
+		return nil, false, errs.Wrapf(err, "unable to convert resource of kind: %s, version: %s", gvk.Kind, gvk.Version)
+	}
+	return obj, outcome == createdOutcome, nil
+}
+
+// unchangedSinceApplied returns the hash of the given object as processed from its template, and `true` if the Processor only
+// applies the deltas and the object was already applied with the same hash. The objects with a `metadata.generateName` are never skipped
+func (p Processor) unchangedSinceApplied(obj runtime.Object) (string, bool, error) {
+	if p.inventory == nil {
+		return "", false, nil
+	}
+	acc, err := meta.Accessor(obj)
+	if err != nil || acc.GetName() == "" {
+		// left to the apply itself
+		return "", false, nil
+	}
+	hash, err := ObjectHash(obj)
+	if err != nil {
+		return "", false, err
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if !p.deltaOnly || p.inventory.AppliedHash(gvk, acc.GetNamespace(), acc.GetName()) != hash {
+		return hash, false, nil
+	}
+	// not observed in the durations of the applies, since nothing was sent to the API server
+	appliedObjects.WithLabelValues(gvk.Kind, skippedOutcome).Inc()
+	p.logger.V(1).Info("object applied", "kind", gvk.Kind, "namespace", acc.GetNamespace(), "name", acc.GetName(), "outcome", skippedOutcome)
+	return hash, true, nil
+}
+
+// applyWithPolicy applies the given object according to its apply policy (see `ApplyPolicyAnnotation`)
+func (p Processor) applyWithPolicy(cl Client, obj runtime.Object, acc metav1.Object) (string, error) {
+	policy, err := applyPolicyOf(acc)
+	if err != nil {
+		return "", err
+	}
+	switch policy {
+	case CreateOnlyPolicy:
+		return createObj(cl, obj)
+	case MergePolicy:
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return "", errs.Errorf("unable to merge resource '%s' in namespace '%s': not an unstructured object", acc.GetName(), acc.GetNamespace())
 		}
-		if u, ok := obj.(*unstructured.Unstructured); ok {
-			// get the existing NSTemplateTier
-			existing := &unstructured.Unstructured{}
-			existing.SetKind(u.GetKind())
-			existing.SetAPIVersion(u.GetAPIVersion())
-			err = cl.Get(context.TODO(), types.NamespacedName{
-				Namespace: u.GetNamespace(),
-				Name:      u.GetName(),
-			}, existing)
-			if err != nil {
-				return errors.Wrapf(err, "unable to get the resource of kind '%s' and name '%s' in namespace '%s'", u.GetKind(), u.GetName(), u.GetNamespace())
+		return mergeObj(cl, u)
+	default:
+		return createOrUpdateObj(cl, p.cache, obj)
+	}
+}
+
+// createGeneratedObj creates the given object which has a `generateName`, unless an object created during a previous
+// call was recorded in the inventory and still exists on the cluster. The object is labelled with the key of its `generateName`
+// (see `GeneratedObjectLabel`), so that it is found again even if the inventory was not saved after it was created (eg, because
+// the operator was stopped in the mean time). Returns `true` if the object was created
+func (p Processor) createGeneratedObj(cl Client, gvk schema.GroupVersionKind, obj runtime.Object, acc metav1.Object) (bool, error) {
+	generateName := acc.GetGenerateName()
+	if name, found := p.inventory.FindGenerated(gvk, acc.GetNamespace(), generateName); found {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(gvk)
+		err := p.cl.Get(context.TODO(), types.NamespacedName{Namespace: acc.GetNamespace(), Name: name}, existing)
+		if err == nil {
+			// object is create-only, nothing else to do
+			return false, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return false, errs.Wrapf(err, "unable to get the resource of kind '%s' and name '%s' in namespace '%s'", gvk.Kind, name, acc.GetNamespace())
+		}
+		// the object was deleted in the mean time, so it needs to be created again
+	}
+	labels := acc.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[GeneratedObjectLabel] = generatedObjectKey(p.templateType, generateName)
+	acc.SetLabels(labels)
+	name, found, err := p.findGeneratedObj(gvk, acc)
+	if err != nil {
+		return false, err
+	}
+	if found {
+		acc.SetName(name)
+		p.inventory.RecordWithTemplateType(p.templateType, gvk, acc.GetNamespace(), name, generateName)
+		return false, nil
+	}
+	if err := cl.Create(context.TODO(), obj); err != nil {
+		return false, errs.Wrapf(err, "failed to create object with generateName '%s'", generateName)
+	}
+	p.inventory.RecordWithTemplateType(p.templateType, gvk, acc.GetNamespace(), acc.GetName(), generateName)
+	return true, nil
+}
+
+// findGeneratedObj returns the name of the existing object of the given kind and namespace which has the same labels as the given
+// object, including its `GeneratedObjectLabel`
+func (p Processor) findGeneratedObj(gvk schema.GroupVersionKind, acc metav1.Object) (string, bool, error) {
+	existing := &unstructured.UnstructuredList{}
+	existing.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := p.cl.List(context.TODO(), existing, client.InNamespace(acc.GetNamespace()), client.MatchingLabels(acc.GetLabels())); err != nil {
+		return "", false, errs.Wrapf(err, "unable to list the resources of kind '%s' in namespace '%s'", gvk.Kind, acc.GetNamespace())
+	}
+	if len(existing.Items) == 0 {
+		return "", false, nil
+	}
+	return existing.Items[0].GetName(), true, nil
+}
+
+// generatedObjectKey returns the value of the `GeneratedObjectLabel` of the objects created with the given `generateName`
+// from a template of the given type
+func generatedObjectKey(templateType, generateName string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(templateType+"/"+generateName)))[:16]
+}
+
+// createOrUpdateObj creates the given object, or updates it if it already exists. Returns the outcome of the operation
+// (`created`, `updated` or `unchanged`, when the update was a no-op and the API server kept the resource version).
+// If a cache is given, the existing object is first looked up in the cache, so that the objects which already exist are updated
+// without any other request to the API server. The live object is used when it is not found in the cache, or when the cached object is stale
+// (ie, when the update fails with a conflict, or because the object was deleted).
+func createOrUpdateObj(cl Client, cache client.Reader, obj runtime.Object) (string, error) {
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return "", errs.Wrapf(err, "failed to update object %v", obj)
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	key := types.NamespacedName{Namespace: acc.GetNamespace(), Name: acc.GetName()}
+	if cache != nil {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(gvk)
+		// any failure to read from the cache is treated as a cache miss
+		if err := cache.Get(context.TODO(), key, existing); err == nil {
+			outcome, err := updateObj(cl, obj, acc, existing)
+			if err == nil {
+				return outcome, nil
+			}
+			if cause := errs.Cause(err); !apierrors.IsConflict(cause) && !apierrors.IsNotFound(cause) {
+				return "", err
 			}
-			// retrieve the current 'resourceVersion' to set it in the resource passed to the `client.Update()`
-			// otherwise we would get an error with the following message:
-			// "nstemplatetiers.toolchain.dev.openshift.com \"basic\" is invalid: metadata.resourceVersion: Invalid value: 0x0: must be specified for an update"
-			u.SetResourceVersion(existing.GetResourceVersion())
-			if err := cl.Update(context.TODO(), u); err != nil {
-				return errors.Wrapf(err, "unable to update the resource of kind '%s' and name '%s' in namespace '%s'", u.GetKind(), u.GetName(), u.GetNamespace())
+			// the cached object is stale, update the live object instead
+			live := &unstructured.Unstructured{}
+			live.SetGroupVersionKind(gvk)
+			if err := cl.Get(context.TODO(), key, live); err == nil {
+				return updateObj(cl, obj, acc, live)
+			} else if !apierrors.IsNotFound(err) {
+				return "", errors.Wrapf(err, "unable to get the resource of kind '%s' and name '%s' in namespace '%s'", gvk.Kind, acc.GetName(), acc.GetNamespace())
 			}
-		} else if err = cl.Update(context.TODO(), obj); err != nil {
-			return errs.Wrapf(err, "failed to update object %v", obj)
+			// the object was deleted in the mean time: it is created again, without the resource version of the cached object
+			acc.SetResourceVersion("")
 		}
-		return nil
 	}
-	return nil
+	if err := cl.Create(context.TODO(), obj); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return "", errs.Wrapf(err, "failed to create object %v", obj)
+		}
+		// get the existing object
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(gvk)
+		if err := cl.Get(context.TODO(), key, existing); err != nil {
+			return "", errors.Wrapf(err, "unable to get the resource of kind '%s' and name '%s' in namespace '%s'", gvk.Kind, acc.GetName(), acc.GetNamespace())
+		}
+		return updateObj(cl, obj, acc, existing)
+	}
+	return createdOutcome, nil
+}
+
+// updateObj updates the given object with the resource version of the existing object. Returns the outcome of the operation
+// (`updated` or `unchanged`)
+func updateObj(cl Client, obj runtime.Object, acc metav1.Object, existing *unstructured.Unstructured) (string, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	// retrieve the current 'resourceVersion' to set it in the resource passed to the `client.Update()`
+	// otherwise we would get an error with the following message:
+	// "nstemplatetiers.toolchain.dev.openshift.com \"basic\" is invalid: metadata.resourceVersion: Invalid value: 0x0: must be specified for an update"
+	acc.SetResourceVersion(existing.GetResourceVersion())
+	if err := cl.Update(context.TODO(), obj); err != nil {
+		return "", errors.Wrapf(err, "unable to update the resource of kind '%s' and name '%s' in namespace '%s'", gvk.Kind, acc.GetName(), acc.GetNamespace())
+	}
+	if acc.GetResourceVersion() == existing.GetResourceVersion() {
+		return unchangedOutcome, nil
+	}
+	return updatedOutcome, nil
 }
@@ -18,9 +18,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -428,6 +431,428 @@ func TestProcessAndApply(t *testing.T) {
 	})
 }
 
+func TestApplyWithCache(t *testing.T) {
+
+	user := getNameWithTimestamp("user")
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	values := map[string]string{
+		"USERNAME": user,
+	}
+
+	process := func(t *testing.T, p template.Processor, content string) []runtime.RawExtension {
+		tmpl, err := decodeTemplate(decoder, content)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+		return objs
+	}
+
+	t.Run("should update object found in cache without creating it", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cache := test.NewFakeClient(t)
+		err := template.NewProcessor(cache, s).Apply(process(t, template.NewProcessor(cache, s), rolebindingTmpl))
+		require.NoError(t, err)
+		err = template.NewProcessor(cl, s).Apply(process(t, template.NewProcessor(cl, s), rolebindingTmpl))
+		require.NoError(t, err)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("should not create the object")
+		}
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Cache: cache})
+
+		// when
+		err = p.Apply(process(t, p, namespaceAndRolebindingWithExtraUserTmpl)[1:])
+
+		// then
+		require.NoError(t, err)
+		binding := assertRoleBindingExists(t, cl, user)
+		require.Len(t, binding.Subjects, 2)
+	})
+
+	t.Run("should create object not found in cache", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Cache: test.NewFakeClient(t)})
+
+		// when
+		err := p.Apply(process(t, p, rolebindingTmpl))
+
+		// then
+		require.NoError(t, err)
+		assertRoleBindingExists(t, cl, user)
+	})
+
+	t.Run("should update live object when cached object is stale", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cache := test.NewFakeClient(t)
+		err := template.NewProcessor(cache, s).Apply(process(t, template.NewProcessor(cache, s), rolebindingTmpl))
+		require.NoError(t, err)
+		err = template.NewProcessor(cl, s).Apply(process(t, template.NewProcessor(cl, s), rolebindingTmpl))
+		require.NoError(t, err)
+		conflicts := 0
+		cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			if conflicts == 0 {
+				conflicts++
+				return apierrors.NewConflict(schema.GroupResource{Group: "authorization.openshift.io", Resource: "rolebindings"}, user+"-edit", errors.New("stale"))
+			}
+			return cl.Client.Update(ctx, obj, opts...)
+		}
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Cache: cache})
+
+		// when
+		err = p.Apply(process(t, p, namespaceAndRolebindingWithExtraUserTmpl)[1:])
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, conflicts)
+		binding := assertRoleBindingExists(t, cl, user)
+		require.Len(t, binding.Subjects, 2)
+	})
+
+	t.Run("should create object again when cached object was deleted", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cache := test.NewFakeClient(t)
+		err := template.NewProcessor(cache, s).Apply(process(t, template.NewProcessor(cache, s), rolebindingTmpl))
+		require.NoError(t, err)
+		var createdResourceVersion *string
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			acc, err := meta.Accessor(obj)
+			require.NoError(t, err)
+			resourceVersion := acc.GetResourceVersion()
+			createdResourceVersion = &resourceVersion
+			return cl.Client.Create(ctx, obj, opts...)
+		}
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Cache: cache})
+
+		// when
+		err = p.Apply(process(t, p, rolebindingTmpl))
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, createdResourceVersion)
+		assert.Empty(t, *createdResourceVersion)
+		assertRoleBindingExists(t, cl, user)
+	})
+
+	t.Run("should fail to get live object when cached object is stale", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cache := test.NewFakeClient(t)
+		err := template.NewProcessor(cache, s).Apply(process(t, template.NewProcessor(cache, s), rolebindingTmpl))
+		require.NoError(t, err)
+		cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			return apierrors.NewConflict(schema.GroupResource{Group: "authorization.openshift.io", Resource: "rolebindings"}, user+"-edit", errors.New("stale"))
+		}
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			return errors.New("mock error")
+		}
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("should not create the object")
+		}
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Cache: cache})
+
+		// when
+		err = p.Apply(process(t, p, rolebindingTmpl))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "mock error")
+	})
+
+	t.Run("should fail to update object found in cache", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cache := test.NewFakeClient(t)
+		err := template.NewProcessor(cache, s).Apply(process(t, template.NewProcessor(cache, s), rolebindingTmpl))
+		require.NoError(t, err)
+		cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			return errors.New("failed to update resource")
+		}
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Cache: cache})
+
+		// when
+		err = p.Apply(process(t, p, rolebindingTmpl))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to update resource")
+	})
+}
+
+func TestApplyDeltaOnly(t *testing.T) {
+
+	user := getNameWithTimestamp("user")
+	s := addToScheme(t)
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	values := map[string]string{
+		"USERNAME": user,
+	}
+
+	process := func(t *testing.T, p template.Processor, content string) []runtime.RawExtension {
+		tmpl, err := decodeTemplate(decoder, content)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+		return objs
+	}
+
+	// applied returns a fake client on which the rolebinding was applied, along with the inventory in which it was recorded
+	applied := func(t *testing.T) (*test.FakeClient, *template.Inventory) {
+		cl := test.NewFakeClient(t)
+		inventory := template.NewInventory()
+		p := template.NewProcessorWithOptions(cl, s, template.Options{DeltaOnly: true}).WithInventory(inventory)
+		err := p.Apply(process(t, p, rolebindingTmpl))
+		require.NoError(t, err)
+		return cl, inventory
+	}
+
+	t.Run("should record the hash of the applied object", func(t *testing.T) {
+		// given
+		_, inventory := applied(t)
+		p := template.NewProcessor(test.NewFakeClient(t), s)
+		obj := process(t, p, rolebindingTmpl)[0].Object
+		hash, err := template.ObjectHash(obj)
+		require.NoError(t, err)
+
+		// then
+		assert.Equal(t, hash, inventory.AppliedHash(obj.GetObjectKind().GroupVersionKind(), user, user+"-edit"))
+	})
+
+	t.Run("should skip object which did not change", func(t *testing.T) {
+		// given
+		cl, inventory := applied(t)
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			return errors.New("should not get the object")
+		}
+		cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			return errors.New("should not update the object")
+		}
+		p := template.NewProcessorWithOptions(cl, s, template.Options{DeltaOnly: true}).WithInventory(inventory)
+
+		// when
+		err := p.Apply(process(t, p, rolebindingTmpl))
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("should apply object which changed", func(t *testing.T) {
+		// given
+		cl, inventory := applied(t)
+		p := template.NewProcessorWithOptions(cl, s, template.Options{DeltaOnly: true}).WithInventory(inventory)
+
+		// when
+		err := p.Apply(process(t, p, namespaceAndRolebindingWithExtraUserTmpl)[1:])
+
+		// then
+		require.NoError(t, err)
+		binding := assertRoleBindingExists(t, cl, user)
+		require.Len(t, binding.Subjects, 2)
+	})
+
+	t.Run("should apply unchanged object", func(t *testing.T) {
+
+		t.Run("when not only applying the deltas", func(t *testing.T) {
+			// given
+			cl, inventory := applied(t)
+			updates := 0
+			cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+				updates++
+				return cl.Client.Update(ctx, obj, opts...)
+			}
+			p := template.NewProcessor(cl, s).WithInventory(inventory)
+
+			// when
+			err := p.Apply(process(t, p, rolebindingTmpl))
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, 1, updates)
+		})
+
+		t.Run("when its hash was forgotten", func(t *testing.T) {
+			// given
+			cl, inventory := applied(t)
+			inventory.ForgetAppliedHashes(user)
+			updates := 0
+			cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+				updates++
+				return cl.Client.Update(ctx, obj, opts...)
+			}
+			p := template.NewProcessorWithOptions(cl, s, template.Options{DeltaOnly: true}).WithInventory(inventory)
+
+			// when
+			err := p.Apply(process(t, p, rolebindingTmpl))
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, 1, updates)
+		})
+	})
+}
+
+func TestApplyWithGenerateName(t *testing.T) {
+
+	user := getNameWithTimestamp("user")
+	s := addToScheme(t)
+	codecFactory := serializer.NewCodecFactory(s)
+	decoder := codecFactory.UniversalDeserializer()
+	values := map[string]string{
+		"USERNAME": user,
+	}
+
+	// newClient returns a fake client which generates the name of the objects which have a `generateName`,
+	// along with a pointer to the number of calls to `Create()`
+	newClient := func(t *testing.T) (*test.FakeClient, *int) {
+		cl := test.NewFakeClient(t)
+		count := 0
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			count++
+			if u, ok := obj.(*unstructured.Unstructured); ok && u.GetName() == "" {
+				u.SetName(fmt.Sprintf("%s%d", u.GetGenerateName(), count))
+			}
+			return cl.Client.Create(ctx, obj, opts...)
+		}
+		return cl, &count
+	}
+
+	t.Run("should create object and record it in inventory", func(t *testing.T) {
+		// given
+		cl, count := newClient(t)
+		inventory := template.NewInventory()
+		p := template.NewProcessor(cl, s).WithInventory(inventory)
+		tmpl, err := decodeTemplate(decoder, configMapWithGenerateNameTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+
+		// when
+		err = p.Apply(objs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, *count)
+		name, found := inventory.FindGenerated(corev1.SchemeGroupVersion.WithKind("ConfigMap"), user, "config-")
+		require.True(t, found)
+		assert.Equal(t, "config-1", name)
+		cm := &corev1.ConfigMap{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: user, Name: name}, cm)
+		require.NoError(t, err)
+	})
+
+	t.Run("should not create nor update object which was already created", func(t *testing.T) {
+		// given
+		cl, count := newClient(t)
+		inventory := template.NewInventory()
+		p := template.NewProcessor(cl, s).WithInventory(inventory)
+		tmpl, err := decodeTemplate(decoder, configMapWithGenerateNameTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+		require.NoError(t, err)
+		cl.MockUpdate = func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+			return errors.New("should not update")
+		}
+
+		// when
+		objs, err = p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, *count)
+		require.Len(t, inventory.Entries, 1)
+	})
+
+	t.Run("should not create object again when the inventory was not saved", func(t *testing.T) {
+		// given an object which was created, but whose inventory was lost (eg, the operator was stopped before it was saved)
+		cl, count := newClient(t)
+		tmpl, err := decodeTemplate(decoder, configMapWithGenerateNameTmpl)
+		require.NoError(t, err)
+		p := template.NewProcessor(cl, s).WithInventory(template.NewInventory())
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+		require.NoError(t, err)
+		inventory := template.NewInventory()
+		p = template.NewProcessor(cl, s).WithInventory(inventory)
+
+		// when
+		objs, err = p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, *count)
+		name, found := inventory.FindGenerated(corev1.SchemeGroupVersion.WithKind("ConfigMap"), user, "config-")
+		require.True(t, found)
+		assert.Equal(t, "config-1", name)
+		cm := &corev1.ConfigMap{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: user, Name: name}, cm)
+		require.NoError(t, err)
+		assert.NotEmpty(t, cm.Labels[template.GeneratedObjectLabel])
+	})
+
+	t.Run("should create object again when it was deleted", func(t *testing.T) {
+		// given
+		cl, count := newClient(t)
+		inventory := template.NewInventory()
+		p := template.NewProcessor(cl, s).WithInventory(inventory)
+		tmpl, err := decodeTemplate(decoder, configMapWithGenerateNameTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+		require.NoError(t, err)
+		err = cl.Delete(context.TODO(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: user, Name: "config-1"}})
+		require.NoError(t, err)
+
+		// when
+		objs, err = p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 2, *count)
+		name, found := inventory.FindGenerated(corev1.SchemeGroupVersion.WithKind("ConfigMap"), user, "config-")
+		require.True(t, found)
+		assert.Equal(t, "config-2", name)
+		require.Len(t, inventory.Entries, 1)
+	})
+
+	t.Run("should fail to get object recorded in inventory", func(t *testing.T) {
+		// given
+		cl, _ := newClient(t)
+		inventory := template.NewInventory()
+		p := template.NewProcessor(cl, s).WithInventory(inventory)
+		tmpl, err := decodeTemplate(decoder, configMapWithGenerateNameTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+		require.NoError(t, err)
+		cl.MockGet = func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+			return errors.New("mock error")
+		}
+
+		// when
+		objs, err = p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "mock error")
+	})
+}
+
 func addToScheme(t *testing.T) *runtime.Scheme {
 	s := scheme.Scheme
 	err := apis.AddToScheme(s)
@@ -635,6 +1060,22 @@ parameters:
   required: true
 - name: COMMIT
   value: 123abc
+  required: true`
+
+	configMapWithGenerateNameTmpl = `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: basic-tier-template
+objects:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    generateName: config-
+    namespace: ${USERNAME}
+  data:
+    owner: ${USERNAME}
+parameters:
+- name: USERNAME
   required: true`
 
 	namespaceObj = `{ 
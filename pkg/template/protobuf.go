@@ -0,0 +1,72 @@
+package template
+
+import (
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewProtobufClient returns a new client which sends and accepts the protobuf content type, falling back to JSON
+// in responses when protobuf is not supported by the server
+func NewProtobufClient(cfg *rest.Config, options client.Options) (client.Client, error) {
+	protoCfg := rest.CopyConfig(cfg)
+	protoCfg.ContentType = runtime.ContentTypeProtobuf
+	protoCfg.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+	return client.New(protoCfg, options)
+}
+
+// WithProtobufClient returns a copy of this Processor which uses the given client to apply the objects of native kinds
+// (eg, Namespaces, RoleBindings, ResourceQuotas). The objects of other kinds (eg, custom resources) are still applied
+// with the default client, using the JSON content type.
+func (p Processor) WithProtobufClient(cl Client) Processor {
+	p.protoClient = cl
+	return p
+}
+
+// isNativeKind returns true if the given object is of a kind provided by Kubernetes itself, which supports the protobuf content type
+func isNativeKind(obj runtime.Object) bool {
+	return clientgoscheme.Scheme.Recognizes(obj.GetObjectKind().GroupVersionKind())
+}
+
+// clientFor returns the client to use to apply the given object, along with the object to pass to this client.
+// When the Processor has a protobuf client and the given object is an unstructured object of a native kind,
+// the returned object is its typed counterpart, since unstructured objects can only be sent as JSON.
+// The objects with the `merge` apply policy are always applied as unstructured objects with the default client,
+// since their content is merged with the one of the existing objects.
+func (p Processor) clientFor(obj runtime.Object) (Client, runtime.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if p.protoClient == nil || !ok || !isNativeKind(obj) || ApplyPolicy(u.GetAnnotations()[ApplyPolicyAnnotation]) == MergePolicy {
+		return p.cl, obj, nil
+	}
+	gvk := u.GroupVersionKind()
+	typed, err := clientgoscheme.Scheme.New(gvk)
+	if err != nil {
+		return nil, nil, errs.Wrapf(err, "unable to create a typed object of kind: %s, version: %s", gvk.Kind, gvk.Version)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+		return nil, nil, errs.Wrapf(err, "unable to convert resource of kind: %s, version: %s", gvk.Kind, gvk.Version)
+	}
+	typed.GetObjectKind().SetGroupVersionKind(gvk)
+	return p.protoClient, typed, nil
+}
+
+// syncBack copies the state of the given typed object (eg, its generated name or resource version)
+// back into the original unstructured object
+func syncBack(typed, obj runtime.Object) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || typed == obj {
+		return nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typed)
+	if err != nil {
+		return err
+	}
+	// the typed object may have lost its kind while being decoded from the response
+	gvk := u.GroupVersionKind()
+	u.Object = content
+	u.SetGroupVersionKind(gvk)
+	return nil
+}
@@ -0,0 +1,130 @@
+package template_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestApplyWithProtobufClient(t *testing.T) {
+
+	user := getNameWithTimestamp("user")
+	s := addToScheme(t)
+	codecFactory := serializer.NewCodecFactory(s)
+	decoder := codecFactory.UniversalDeserializer()
+	values := map[string]string{
+		"USERNAME": user,
+		"COMMIT":   "abcd1234",
+	}
+
+	t.Run("should apply native kinds with protobuf client only", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		protoCl := test.NewFakeClient(t)
+		p := template.NewProcessor(cl, s).WithProtobufClient(protoCl)
+		tmpl, err := decodeTemplate(decoder, namespaceAndRolebindingTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+
+		// when
+		err = p.Apply(objs)
+
+		// then
+		require.NoError(t, err)
+		// the namespace is a native kind
+		assertNamespaceExists(t, protoCl, user)
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: user}, &corev1.Namespace{})
+		assert.True(t, apierrors.IsNotFound(err))
+		// the OpenShift role binding is not
+		assertRoleBindingExists(t, cl, user)
+		// the processed objects are still unstructured
+		for _, obj := range objs {
+			assert.IsType(t, &unstructured.Unstructured{}, obj.Object)
+		}
+	})
+
+	t.Run("should update existing object with protobuf client", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		protoCl := test.NewFakeClient(t)
+		p := template.NewProcessor(cl, s).WithProtobufClient(protoCl)
+		tmpl, err := decodeTemplate(decoder, namespaceTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+		require.NoError(t, err)
+
+		// when
+		values["COMMIT"] = "abcd5678"
+		objs, err = p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+
+		// then
+		require.NoError(t, err)
+		ns := assertNamespaceExists(t, protoCl, user)
+		assert.Equal(t, "abcd5678", ns.Labels["version"])
+	})
+
+	t.Run("should record generated name of object created with protobuf client", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		protoCl := test.NewFakeClient(t)
+		protoCl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			// the typed object is sent to the protobuf client
+			require.IsType(t, &corev1.ConfigMap{}, obj)
+			acc, err := meta.Accessor(obj)
+			require.NoError(t, err)
+			if acc.GetName() == "" {
+				acc.SetName(fmt.Sprintf("%s%d", acc.GetGenerateName(), 1))
+			}
+			return protoCl.Client.Create(ctx, obj, opts...)
+		}
+		inventory := template.NewInventory()
+		p := template.NewProcessor(cl, s).WithInventory(inventory).WithProtobufClient(protoCl)
+		tmpl, err := decodeTemplate(decoder, configMapWithGenerateNameTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+
+		// when
+		err = p.Apply(objs)
+
+		// then
+		require.NoError(t, err)
+		name, found := inventory.FindGenerated(corev1.SchemeGroupVersion.WithKind("ConfigMap"), user, "config-")
+		require.True(t, found)
+		assert.Equal(t, "config-1", name)
+		// the generated name is copied back into the processed object
+		acc, err := meta.Accessor(objs[0].Object)
+		require.NoError(t, err)
+		assert.Equal(t, "config-1", acc.GetName())
+		assert.Equal(t, "ConfigMap", objs[0].Object.GetObjectKind().GroupVersionKind().Kind)
+	})
+}
+
+func TestNewProtobufClient(t *testing.T) {
+	// when
+	cl, err := template.NewProtobufClient(&rest.Config{Host: "https://localhost:6443"}, client.Options{Scheme: addToScheme(t), Mapper: meta.NewDefaultRESTMapper(nil)})
+
+	// then
+	require.NoError(t, err)
+	assert.NotNil(t, cl)
+}
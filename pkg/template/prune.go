@@ -0,0 +1,58 @@
+package template
+
+import (
+	"context"
+
+	errs "github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Prune deletes the objects of the given namespace which were recorded in the inventory (under the template type of the
+// Processor, see `WithTemplateType`) but which are not part of the given objects anymore (eg, because they were removed
+// from the template in a newer revision).
+// Does nothing if the Processor has no inventory.
+func (p Processor) Prune(namespace string, objs []runtime.RawExtension) error {
+	for _, entry := range p.inventory.EntriesOfTemplateType(p.templateType, namespace) {
+		found, err := containsEntry(objs, entry)
+		if err != nil {
+			return err
+		}
+		if found {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(entry.APIVersion)
+		obj.SetKind(entry.Kind)
+		obj.SetNamespace(entry.Namespace)
+		obj.SetName(entry.Name)
+		if err := p.cl.Delete(context.TODO(), obj); err != nil {
+			// the kind may not be served anymore (see `RequiresAPIAnnotation`), in which case the object is gone
+			if !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+				return errs.Wrapf(err, "unable to delete the resource of kind '%s' and name '%s' in namespace '%s'", entry.Kind, entry.Name, entry.Namespace)
+			}
+		} else {
+			p.churnRecorder.RecordChurn(entry.Kind, DeleteOperation)
+		}
+		p.inventory.Remove(entry)
+	}
+	return nil
+}
+
+func containsEntry(objs []runtime.RawExtension, entry InventoryEntry) (bool, error) {
+	for _, rawObj := range objs {
+		if rawObj.Object == nil {
+			continue
+		}
+		acc, err := meta.Accessor(rawObj.Object)
+		if err != nil {
+			return false, err
+		}
+		if entry.matches(rawObj.Object.GetObjectKind().GroupVersionKind(), acc.GetNamespace(), acc.GetName(), acc.GetGenerateName()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
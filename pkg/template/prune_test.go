@@ -0,0 +1,199 @@
+package template_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestPrune(t *testing.T) {
+
+	user := getNameWithTimestamp("user")
+	s := addToScheme(t)
+	codecFactory := serializer.NewCodecFactory(s)
+	decoder := codecFactory.UniversalDeserializer()
+	values := map[string]string{
+		"USERNAME": user,
+	}
+	cmGVK := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+
+	t.Run("should delete obsolete objects", func(t *testing.T) {
+		// given
+		obsolete := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: user, Name: "obsolete"}}
+		other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "obsolete"}}
+		cl := test.NewFakeClient(t, obsolete, other)
+		inventory := template.NewInventory()
+		inventory.Record(cmGVK, user, "obsolete", "")
+		inventory.Record(cmGVK, "other", "obsolete", "")
+		p := template.NewProcessor(cl, s).WithInventory(inventory)
+		tmpl, err := decodeTemplate(decoder, rolebindingTmpl)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+		err = p.Apply(objs)
+		require.NoError(t, err)
+
+		// when
+		err = p.Prune(user, objs)
+
+		// then
+		require.NoError(t, err)
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: user, Name: "obsolete"}, &corev1.ConfigMap{})
+		assert.True(t, apierrors.IsNotFound(err))
+		// object in another namespace is kept
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: "other", Name: "obsolete"}, &corev1.ConfigMap{})
+		require.NoError(t, err)
+		assertRoleBindingExists(t, cl, user)
+		require.Len(t, inventory.EntriesInNamespace(user), 1)
+		assert.Equal(t, "RoleBinding", inventory.EntriesInNamespace(user)[0].Kind)
+	})
+
+	t.Run("should ignore objects already deleted", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		inventory := template.NewInventory()
+		inventory.Record(cmGVK, user, "obsolete", "")
+		p := template.NewProcessor(cl, s).WithInventory(inventory)
+
+		// when
+		err := p.Prune(user, nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, inventory.Entries)
+	})
+
+	t.Run("should fail to delete obsolete object", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("mock error")
+		}
+		inventory := template.NewInventory()
+		inventory.Record(cmGVK, user, "obsolete", "")
+		p := template.NewProcessor(cl, s).WithInventory(inventory)
+
+		// when
+		err := p.Prune(user, nil)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "mock error")
+		assert.Len(t, inventory.Entries, 1)
+	})
+
+	t.Run("should keep objects whose API version changed", func(t *testing.T) {
+		// given
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: user, Name: "app"}}
+		policy := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: user, Name: "default-deny"}}
+		cl := test.NewFakeClient(t, deployment, policy)
+		inventory := template.NewInventory()
+		inventory.Record(schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Deployment"}, user, "app", "")
+		inventory.Record(schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "NetworkPolicy"}, user, "default-deny", "")
+		p := template.NewProcessor(cl, s).WithInventory(inventory)
+		objs := []runtime.RawExtension{
+			{Object: newUnstructured("apps/v1", "Deployment", user, "app")},
+			{Object: newUnstructured("networking.k8s.io/v1", "NetworkPolicy", user, "default-deny")},
+		}
+
+		// when
+		err := p.Prune(user, objs)
+
+		// then
+		require.NoError(t, err)
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: user, Name: "app"}, &appsv1.Deployment{})
+		require.NoError(t, err)
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: user, Name: "default-deny"}, &networkingv1.NetworkPolicy{})
+		require.NoError(t, err)
+		assert.Len(t, inventory.Entries, 2)
+
+		t.Run("entries replaced with the new API version", func(t *testing.T) {
+			// when
+			inventory.Record(appsv1.SchemeGroupVersion.WithKind("Deployment"), user, "app", "")
+
+			// then
+			require.Len(t, inventory.Entries, 2)
+			assert.Equal(t, "apps/v1", inventory.Entries[0].APIVersion)
+		})
+	})
+
+	t.Run("should delete objects of another kind with the same name", func(t *testing.T) {
+		// given
+		obsolete := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: user, Name: "app"}}
+		cl := test.NewFakeClient(t, obsolete)
+		inventory := template.NewInventory()
+		inventory.Record(cmGVK, user, "app", "")
+		p := template.NewProcessor(cl, s).WithInventory(inventory)
+
+		// when
+		err := p.Prune(user, []runtime.RawExtension{{Object: newUnstructured("v1", "Secret", user, "app")}})
+
+		// then
+		require.NoError(t, err)
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: user, Name: "app"}, &corev1.ConfigMap{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("should only delete objects of the same template type", func(t *testing.T) {
+		// given
+		nsGVK := corev1.SchemeGroupVersion.WithKind("Namespace")
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: user + "-dev"}}
+		obsolete := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: user + "-obsolete"}}
+		cl := test.NewFakeClient(t, ns, obsolete)
+		inventory := template.NewInventory()
+		inventory.Record(nsGVK, "", ns.Name, "")
+		inventory.RecordWithTemplateType(template.ClusterResourcesType, nsGVK, "", obsolete.Name, "")
+		p := template.NewProcessor(cl, s).WithInventory(inventory).WithTemplateType(template.ClusterResourcesType)
+
+		// when
+		err := p.Prune("", nil)
+
+		// then
+		require.NoError(t, err)
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: obsolete.Name}, &corev1.Namespace{})
+		assert.True(t, apierrors.IsNotFound(err))
+		// the object recorded without the template type is kept
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: ns.Name}, &corev1.Namespace{})
+		require.NoError(t, err)
+		require.Len(t, inventory.Entries, 1)
+		assert.Equal(t, ns.Name, inventory.Entries[0].Name)
+	})
+
+	t.Run("should do nothing without inventory", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessor(cl, s)
+
+		// when
+		err := p.Prune(user, nil)
+
+		// then
+		require.NoError(t, err)
+	})
+}
+
+func newUnstructured(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
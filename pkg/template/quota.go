@@ -0,0 +1,55 @@
+package template
+
+import (
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// SetDefaultQuota adds the given hard limits to the ResourceQuotas and ClusterResourceQuotas among the given objects, unless they
+// already define them. The quotas with scopes are left untouched, since the limits would only apply to a subset of the pods.
+func SetDefaultQuota(objs []runtime.RawExtension, hard corev1.ResourceList) error {
+	if len(hard) == 0 {
+		return nil
+	}
+	for _, rawObj := range objs {
+		obj, ok := rawObj.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		var path []string
+		switch obj.GetKind() {
+		case "ResourceQuota":
+			path = []string{"spec"}
+		case "ClusterResourceQuota":
+			path = []string{"spec", "quota"}
+		default:
+			continue
+		}
+		if scoped(obj, path) {
+			continue
+		}
+		for name, quantity := range hard {
+			fields := append(append([]string{}, path...), "hard", string(name))
+			if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, fields...); found {
+				continue
+			}
+			if err := unstructured.SetNestedField(obj.Object, quantity.String(), fields...); err != nil {
+				return errs.Wrapf(err, "unable to set the '%s' quota of %s '%s'", name, obj.GetKind(), obj.GetName())
+			}
+		}
+	}
+	return nil
+}
+
+// scoped returns true if the quota spec at the given path has scopes or a scope selector
+func scoped(obj *unstructured.Unstructured, path []string) bool {
+	for _, field := range []string{"scopes", "scopeSelector"} {
+		fields := append(append([]string{}, path...), field)
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, fields...); found {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package template_test
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestSetDefaultQuota(t *testing.T) {
+	hard := corev1.ResourceList{
+		corev1.ResourceRequestsEphemeralStorage: resource.MustParse("10Gi"),
+		corev1.ResourceLimitsEphemeralStorage:   resource.MustParse("10Gi"),
+	}
+
+	t.Run("resource quota", func(t *testing.T) {
+		// given
+		quota := newQuota("ResourceQuota", map[string]interface{}{
+			"hard": map[string]interface{}{
+				"limits.cpu":               "2",
+				"limits.ephemeral-storage": "5Gi",
+			},
+		})
+
+		// when
+		err := template.SetDefaultQuota([]runtime.RawExtension{{Object: quota}}, hard)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"limits.cpu":                 "2",
+			"limits.ephemeral-storage":   "5Gi", // already defined
+			"requests.ephemeral-storage": "10Gi",
+		}, quota.Object["spec"].(map[string]interface{})["hard"])
+	})
+
+	t.Run("cluster resource quota", func(t *testing.T) {
+		// given
+		quota := newQuota("ClusterResourceQuota", map[string]interface{}{
+			"quota": map[string]interface{}{
+				"hard": map[string]interface{}{
+					"limits.cpu": "2",
+				},
+			},
+		})
+
+		// when
+		err := template.SetDefaultQuota([]runtime.RawExtension{{Object: quota}}, hard)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"limits.cpu":                 "2",
+			"limits.ephemeral-storage":   "10Gi",
+			"requests.ephemeral-storage": "10Gi",
+		}, quota.Object["spec"].(map[string]interface{})["quota"].(map[string]interface{})["hard"])
+	})
+
+	t.Run("scoped quota and other objects left untouched", func(t *testing.T) {
+		// given
+		scopedQuota := newQuota("ResourceQuota", map[string]interface{}{
+			"hard": map[string]interface{}{
+				"limits.cpu": "2",
+			},
+			"scopes": []interface{}{"Terminating"},
+		})
+		limitRange := newQuota("LimitRange", map[string]interface{}{})
+
+		// when
+		err := template.SetDefaultQuota([]runtime.RawExtension{{Object: scopedQuota}, {Object: limitRange}}, hard)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"limits.cpu": "2",
+		}, scopedQuota.Object["spec"].(map[string]interface{})["hard"])
+		assert.Equal(t, map[string]interface{}{}, limitRange.Object["spec"])
+	})
+}
+
+func newQuota(kind string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind": kind,
+			"metadata": map[string]interface{}{
+				"name": "quota",
+			},
+			"spec": spec,
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package template
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TemplateRefsHashAnnotation the annotation on the user namespaces which holds the hash of the template refs applied to them
+// (see `ComputeTemplateRefsHash`)
+const TemplateRefsHashAnnotation = "toolchain.dev.openshift.com/template-refs-hash"
+
+// TemplateRef a reference to a template applied to a namespace, ie, the tier and the type of the template along with its revision
+type TemplateRef struct {
+	Tier     string
+	Type     string
+	Revision string
+}
+
+// String returns the `<tier>-<type>-<revision>` representation of the TemplateRef
+func (r TemplateRef) String() string {
+	return fmt.Sprintf("%s-%s-%s", r.Tier, r.Type, r.Revision)
+}
+
+// ComputeTemplateRefsHash returns the hash of the given set of template refs, regardless of their order. Comparing the hash of the refs
+// which should be applied to a namespace with the one recorded in its annotation is enough to know whether the namespace needs to be updated,
+// without fetching nor processing any template.
+func ComputeTemplateRefsHash(refs ...TemplateRef) string {
+	values := make([]string, len(refs))
+	for i, ref := range refs {
+		values[i] = ref.String()
+	}
+	sort.Strings(values)
+	// the first 8 bytes are enough to detect the changes, and keep the annotation small
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(values, ","))))[:16]
+}
+
+// SetTemplateRefsHash sets the annotation of the given object with the hash of the given template refs.
+// Returns `true` if the annotation changed
+func SetTemplateRefsHash(obj metav1.Object, refs ...TemplateRef) bool {
+	hash := ComputeTemplateRefsHash(refs...)
+	annotations := obj.GetAnnotations()
+	if annotations[TemplateRefsHashAnnotation] == hash {
+		return false
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[TemplateRefsHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+	return true
+}
+
+// TemplateRefsHashMatches returns `true` if the annotation of the given object holds the hash of the given template refs,
+// ie, if the object is up to date
+func TemplateRefsHashMatches(obj metav1.Object, refs ...TemplateRef) bool {
+	return obj.GetAnnotations()[TemplateRefsHashAnnotation] == ComputeTemplateRefsHash(refs...)
+}
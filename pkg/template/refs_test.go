@@ -0,0 +1,76 @@
+package template_test
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestComputeTemplateRefsHash(t *testing.T) {
+
+	dev := template.TemplateRef{Tier: "basic", Type: "dev", Revision: "abcde11"}
+	code := template.TemplateRef{Tier: "basic", Type: "code", Revision: "abcde21"}
+
+	t.Run("same hash regardless of the order", func(t *testing.T) {
+		assert.Equal(t, template.ComputeTemplateRefsHash(dev, code), template.ComputeTemplateRefsHash(code, dev))
+		assert.Len(t, template.ComputeTemplateRefsHash(dev, code), 16)
+	})
+
+	t.Run("different hash when a revision changes", func(t *testing.T) {
+		updated := template.TemplateRef{Tier: "basic", Type: "dev", Revision: "abcde12"}
+		assert.NotEqual(t, template.ComputeTemplateRefsHash(dev, code), template.ComputeTemplateRefsHash(updated, code))
+	})
+
+	t.Run("different hash when the tier changes", func(t *testing.T) {
+		advanced := template.TemplateRef{Tier: "advanced", Type: "dev", Revision: "abcde11"}
+		assert.NotEqual(t, template.ComputeTemplateRefsHash(dev), template.ComputeTemplateRefsHash(advanced))
+	})
+
+	t.Run("different hash when a ref is removed", func(t *testing.T) {
+		assert.NotEqual(t, template.ComputeTemplateRefsHash(dev, code), template.ComputeTemplateRefsHash(dev))
+	})
+}
+
+func TestSetTemplateRefsHash(t *testing.T) {
+
+	dev := template.TemplateRef{Tier: "basic", Type: "dev", Revision: "abcde11"}
+
+	t.Run("annotation set", func(t *testing.T) {
+		// given
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "johnsmith-dev"}}
+		assert.False(t, template.TemplateRefsHashMatches(ns, dev))
+
+		// when
+		changed := template.SetTemplateRefsHash(ns, dev)
+
+		// then
+		assert.True(t, changed)
+		assert.Equal(t, template.ComputeTemplateRefsHash(dev), ns.Annotations[template.TemplateRefsHashAnnotation])
+		assert.True(t, template.TemplateRefsHashMatches(ns, dev))
+
+		t.Run("annotation unchanged", func(t *testing.T) {
+			// when
+			changed := template.SetTemplateRefsHash(ns, dev)
+
+			// then
+			assert.False(t, changed)
+		})
+
+		t.Run("annotation updated", func(t *testing.T) {
+			// given
+			updated := template.TemplateRef{Tier: "basic", Type: "dev", Revision: "abcde12"}
+			assert.False(t, template.TemplateRefsHashMatches(ns, updated))
+
+			// when
+			changed := template.SetTemplateRefsHash(ns, updated)
+
+			// then
+			assert.True(t, changed)
+			assert.True(t, template.TemplateRefsHashMatches(ns, updated))
+		})
+	})
+}
@@ -0,0 +1,242 @@
+package template
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// EncryptedAnnotation the annotation of the template Secrets whose values are encrypted, which are decrypted by the Processor
+	// with its Decrypter before being applied
+	EncryptedAnnotation = "toolchain.dev.openshift.com/encrypted"
+
+	// sealedSecretsGroup the API group of the SealedSecrets of Bitnami
+	sealedSecretsGroup = "bitnami.com"
+	// sealedSecretNamespaceWideAnnotation the annotation of the SealedSecrets which can be unsealed in any name within their namespace
+	sealedSecretNamespaceWideAnnotation = "sealedsecrets.bitnami.com/namespace-wide"
+	// sealedSecretClusterWideAnnotation the annotation of the SealedSecrets which can be unsealed in any name and in any namespace
+	sealedSecretClusterWideAnnotation = "sealedsecrets.bitnami.com/cluster-wide"
+)
+
+// Decrypter decrypts the encrypted values of the template Secrets and SealedSecrets, eg, with a private key or by calling a KMS.
+// The label is the scope to which the value was bound when it was encrypted (see the scopes of the SealedSecrets), and is empty
+// for the values of the Secrets with the `EncryptedAnnotation`
+type Decrypter interface {
+	Decrypt(ciphertext []byte, label string) ([]byte, error)
+}
+
+// decrypt returns the plaintext Secret of the given template object if it is an encrypted Secret or a SealedSecret, or the object
+// itself otherwise. The SealedSecrets are only converted when the Processor has a Decrypter (otherwise, they are applied as is, and
+// unsealed by the controller of the SealedSecrets, if any), while the encrypted Secrets cannot be applied without a Decrypter
+func (p Processor) decrypt(obj runtime.Object) (runtime.Object, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	isSealedSecret := gvk.Group == sealedSecretsGroup && gvk.Kind == "SealedSecret"
+	isEncryptedSecret := gvk.Group == "" && gvk.Kind == "Secret"
+	if !isSealedSecret && !isEncryptedSecret {
+		return obj, nil
+	}
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, errs.Wrapf(err, "invalid resource of kind: %s, version: %s", gvk.Kind, gvk.Version)
+	}
+	if isEncryptedSecret && acc.GetAnnotations()[EncryptedAnnotation] != "true" {
+		return obj, nil
+	}
+	if p.decrypter == nil {
+		if isSealedSecret {
+			return obj, nil
+		}
+		return nil, errs.Errorf("unable to decrypt the secret '%s' in namespace '%s': no decrypter", acc.GetName(), acc.GetNamespace())
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to convert resource of kind: %s, version: %s", gvk.Kind, gvk.Version)
+	}
+	u := &unstructured.Unstructured{Object: content}
+	if isSealedSecret {
+		return p.unseal(u)
+	}
+	return p.decryptSecret(u)
+}
+
+// decryptSecret returns a copy of the given Secret with its values decrypted, and without the `EncryptedAnnotation`
+func (p Processor) decryptSecret(u *unstructured.Unstructured) (runtime.Object, error) {
+	data, _, err := unstructured.NestedStringMap(u.Object, "data")
+	if err != nil {
+		return nil, errs.Wrapf(err, "invalid data of the secret '%s' in namespace '%s'", u.GetName(), u.GetNamespace())
+	}
+	decrypted, err := p.decryptValues(data, "")
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to decrypt the secret '%s' in namespace '%s'", u.GetName(), u.GetNamespace())
+	}
+	secret := u.DeepCopy()
+	if err := unstructured.SetNestedStringMap(secret.Object, decrypted, "data"); err != nil {
+		return nil, err
+	}
+	annotations := secret.GetAnnotations()
+	delete(annotations, EncryptedAnnotation)
+	secret.SetAnnotations(annotations)
+	return secret, nil
+}
+
+// unseal returns the Secret of the given SealedSecret, ie, its template with its decrypted values
+func (p Processor) unseal(u *unstructured.Unstructured) (runtime.Object, error) {
+	encryptedData, _, err := unstructured.NestedStringMap(u.Object, "spec", "encryptedData")
+	if err != nil {
+		return nil, errs.Wrapf(err, "invalid encrypted data of the sealed secret '%s' in namespace '%s'", u.GetName(), u.GetNamespace())
+	}
+	// the values are bound to the name and namespace of the SealedSecret, unless its scope is wider
+	label := u.GetNamespace() + "/" + u.GetName()
+	if u.GetAnnotations()[sealedSecretClusterWideAnnotation] == "true" {
+		label = ""
+	} else if u.GetAnnotations()[sealedSecretNamespaceWideAnnotation] == "true" {
+		label = u.GetNamespace()
+	}
+	data, err := p.decryptValues(encryptedData, label)
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to unseal the sealed secret '%s' in namespace '%s'", u.GetName(), u.GetNamespace())
+	}
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if tmpl, found, err := unstructured.NestedMap(u.Object, "spec", "template"); err != nil {
+		return nil, errs.Wrapf(err, "invalid template of the sealed secret '%s' in namespace '%s'", u.GetName(), u.GetNamespace())
+	} else if found {
+		secret.Object = tmpl
+	}
+	secret.SetAPIVersion("v1")
+	secret.SetKind("Secret")
+	secret.SetName(u.GetName())
+	secret.SetNamespace(u.GetNamespace())
+	// the labels, annotations (eg, the apply policy) and owner of the SealedSecret are set on the Secret
+	secret.SetLabels(mergeMaps(secret.GetLabels(), u.GetLabels()))
+	annotations := mergeMaps(secret.GetAnnotations(), u.GetAnnotations())
+	delete(annotations, sealedSecretNamespaceWideAnnotation)
+	delete(annotations, sealedSecretClusterWideAnnotation)
+	secret.SetAnnotations(annotations)
+	secret.SetOwnerReferences(u.GetOwnerReferences())
+	if err := unstructured.SetNestedStringMap(secret.Object, data, "data"); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// mergeMaps returns the given map with the entries of the other map
+func mergeMaps(m, other map[string]string) map[string]string {
+	if len(other) == 0 {
+		return m
+	}
+	if m == nil {
+		m = make(map[string]string, len(other))
+	}
+	for key, value := range other {
+		m[key] = value
+	}
+	return m
+}
+
+// decryptValues returns the given base64-encoded encrypted values, decrypted and base64-encoded
+func (p Processor) decryptValues(values map[string]string, label string) (map[string]string, error) {
+	decrypted := make(map[string]string, len(values))
+	for key, value := range values {
+		ciphertext, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, errs.Wrapf(err, "invalid encrypted value of '%s'", key)
+		}
+		plaintext, err := p.decrypter.Decrypt(ciphertext, label)
+		if err != nil {
+			return nil, errs.Wrapf(err, "unable to decrypt the value of '%s'", key)
+		}
+		decrypted[key] = base64.StdEncoding.EncodeToString(plaintext)
+	}
+	return decrypted, nil
+}
+
+// RSADecrypter decrypts the values which were encrypted with the public key of one of its private keys, in the hybrid format of the
+// SealedSecrets (ie, an AES-GCM session key encrypted with RSA-OAEP, followed by the value encrypted with the session key). Hence, the
+// values can be encrypted with `kubeseal --raw` and the public certificate of the keys
+type RSADecrypter struct {
+	keys []*rsa.PrivateKey
+}
+
+var _ Decrypter = &RSADecrypter{}
+
+// NewRSADecrypter returns a new decrypter with the given private keys, which are tried in turn (eg, the current and the previous keys
+// after a rotation)
+func NewRSADecrypter(keys ...*rsa.PrivateKey) *RSADecrypter {
+	return &RSADecrypter{keys: keys}
+}
+
+// ParsePrivateKeys returns the RSA private keys of the given PEM content, in PKCS#1 or PKCS#8 format
+func ParsePrivateKeys(content []byte) ([]*rsa.PrivateKey, error) {
+	var keys []*rsa.PrivateKey
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			return keys, nil
+		}
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, errs.Wrap(err, "invalid RSA private key")
+			}
+			keys = append(keys, key)
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, errs.Wrap(err, "invalid private key")
+			}
+			rsaKey, ok := key.(*rsa.PrivateKey)
+			if !ok {
+				return nil, errs.New("not an RSA private key")
+			}
+			keys = append(keys, rsaKey)
+		}
+	}
+}
+
+// Decrypt decrypts the given ciphertext with the first private key which can decrypt its session key
+func (d *RSADecrypter) Decrypt(ciphertext []byte, label string) ([]byte, error) {
+	if len(ciphertext) < 2 {
+		return nil, errs.New("ciphertext too short")
+	}
+	sessionKeyLen := int(binary.BigEndian.Uint16(ciphertext))
+	if len(ciphertext) < sessionKeyLen+2 {
+		return nil, errs.New("ciphertext too short")
+	}
+	encryptedSessionKey := ciphertext[2 : sessionKeyLen+2]
+	encryptedValue := ciphertext[sessionKeyLen+2:]
+	for _, key := range d.keys {
+		sessionKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, encryptedSessionKey, []byte(label))
+		if err != nil {
+			continue
+		}
+		block, err := aes.NewCipher(sessionKey)
+		if err != nil {
+			return nil, errs.Wrap(err, "invalid session key")
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errs.Wrap(err, "invalid session key")
+		}
+		// the session key is only used once, hence the zero nonce
+		plaintext, err := gcm.Open(nil, make([]byte, gcm.NonceSize()), encryptedValue, nil)
+		if err != nil {
+			return nil, errs.Wrap(err, "unable to decrypt the value with the session key")
+		}
+		return plaintext, nil
+	}
+	return nil, errs.New("no private key can decrypt the value")
+}
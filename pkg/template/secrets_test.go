@@ -0,0 +1,277 @@
+package template_test
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestApplyEncryptedSecrets(t *testing.T) {
+	s := addToScheme(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	decrypter := template.NewRSADecrypter(key)
+
+	t.Run("encrypted secret decrypted", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Decrypter: decrypter})
+		secret := newEncryptedSecret("credentials", map[string]interface{}{"password": encrypt(t, &key.PublicKey, "s3cr3t", "")})
+
+		// when
+		err := p.Apply([]runtime.RawExtension{{Object: secret}})
+
+		// then
+		require.NoError(t, err)
+		applied := assertSecret(t, cl, "credentials", "password", "s3cr3t")
+		assert.NotContains(t, applied.Annotations, template.EncryptedAnnotation)
+		// the template object still holds the encrypted value
+		assert.Equal(t, "true", secret.GetAnnotations()[template.EncryptedAnnotation])
+	})
+
+	t.Run("plain secret left untouched", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Decrypter: decrypter})
+		secret := newEncryptedSecret("credentials", map[string]interface{}{"password": base64.StdEncoding.EncodeToString([]byte("s3cr3t"))})
+		secret.SetAnnotations(nil)
+
+		// when
+		err := p.Apply([]runtime.RawExtension{{Object: secret}})
+
+		// then
+		require.NoError(t, err)
+		assertSecret(t, cl, "credentials", "password", "s3cr3t")
+	})
+
+	t.Run("encrypted secret without decrypter", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessor(cl, s)
+		secret := newEncryptedSecret("credentials", map[string]interface{}{"password": encrypt(t, &key.PublicKey, "s3cr3t", "")})
+
+		// when
+		err := p.Apply([]runtime.RawExtension{{Object: secret}})
+
+		// then
+		require.EqualError(t, err, "unable to decrypt the secret 'credentials' in namespace 'johnsmith-dev': no decrypter")
+		assertSecretNotFound(t, cl, "credentials")
+	})
+
+	t.Run("encrypted with another key", func(t *testing.T) {
+		// given
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Decrypter: decrypter})
+		secret := newEncryptedSecret("credentials", map[string]interface{}{"password": encrypt(t, &otherKey.PublicKey, "s3cr3t", "")})
+
+		// when
+		err = p.Apply([]runtime.RawExtension{{Object: secret}})
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to decrypt the value of 'password': no private key can decrypt the value")
+		assertSecretNotFound(t, cl, "credentials")
+	})
+
+	t.Run("sealed secrets unsealed", func(t *testing.T) {
+		for scope, label := range map[string]string{
+			"": "johnsmith-dev/credentials",
+			"sealedsecrets.bitnami.com/namespace-wide": "johnsmith-dev",
+			"sealedsecrets.bitnami.com/cluster-wide":   "",
+		} {
+			t.Run(label, func(t *testing.T) {
+				// given
+				cl := test.NewFakeClient(t)
+				p := template.NewProcessorWithOptions(cl, s, template.Options{Decrypter: decrypter})
+				sealed := newSealedSecret("credentials", map[string]interface{}{"password": encrypt(t, &key.PublicKey, "s3cr3t", label)})
+				if scope != "" {
+					sealed.SetAnnotations(map[string]string{scope: "true"})
+				}
+
+				// when
+				err := p.Apply([]runtime.RawExtension{{Object: sealed}})
+
+				// then
+				require.NoError(t, err)
+				applied := assertSecret(t, cl, "credentials", "username", "johnsmith")
+				assertSecret(t, cl, "credentials", "password", "s3cr3t")
+				assert.Equal(t, corev1.SecretTypeBasicAuth, applied.Type)
+				assert.Equal(t, map[string]string{"app": "db", "owner": "johnsmith"}, applied.Labels)
+				assert.NotContains(t, applied.Annotations, scope)
+			})
+		}
+	})
+
+	t.Run("sealed secret bound to another name", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Decrypter: decrypter})
+		sealed := newSealedSecret("credentials", map[string]interface{}{"password": encrypt(t, &key.PublicKey, "s3cr3t", "johnsmith-dev/other")})
+
+		// when
+		err := p.Apply([]runtime.RawExtension{{Object: sealed}})
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to unseal the sealed secret 'credentials' in namespace 'johnsmith-dev'")
+	})
+
+	t.Run("unsealed secret rolled back", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if obj.(metav1.Object).GetName() == "cm-0" {
+				return errors.New("mock error")
+			}
+			return cl.Client.Create(ctx, obj, opts...)
+		}
+		p := template.NewProcessorWithOptions(cl, s, template.Options{Decrypter: decrypter})
+		sealed := newSealedSecret("credentials", map[string]interface{}{"password": encrypt(t, &key.PublicKey, "s3cr3t", "johnsmith-dev/credentials")})
+
+		// when
+		err := p.ApplyAll([]runtime.RawExtension{{Object: sealed}}, newConfigMaps(1))
+
+		// then
+		require.Error(t, err)
+		assertSecretNotFound(t, cl, "credentials")
+	})
+}
+
+func TestParsePrivateKeys(t *testing.T) {
+	// given
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key2)
+	require.NoError(t, err)
+	content := append(
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key1)}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})...)
+	content = append(content, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("ignored")})...)
+
+	// when
+	keys, err := template.ParsePrivateKeys(content)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, key1.N, keys[0].N)
+	assert.Equal(t, key2.N, keys[1].N)
+
+	t.Run("values decrypted with any of the keys", func(t *testing.T) {
+		// given
+		decrypter := template.NewRSADecrypter(keys...)
+		ciphertext, err := base64.StdEncoding.DecodeString(encrypt(t, &key2.PublicKey, "s3cr3t", ""))
+		require.NoError(t, err)
+
+		// when
+		plaintext, err := decrypter.Decrypt(ciphertext, "")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "s3cr3t", string(plaintext))
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		// when
+		_, err := template.ParsePrivateKeys(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("invalid")}))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid RSA private key")
+	})
+}
+
+// encrypt encrypts the given value with the given public key and label, in the hybrid format of the SealedSecrets
+func encrypt(t *testing.T, key *rsa.PublicKey, value, label string) string {
+	sessionKey := make([]byte, 32)
+	_, err := rand.Read(sessionKey)
+	require.NoError(t, err)
+	encryptedSessionKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key, sessionKey, []byte(label))
+	require.NoError(t, err)
+	block, err := aes.NewCipher(sessionKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	ciphertext := make([]byte, 2)
+	binary.BigEndian.PutUint16(ciphertext, uint16(len(encryptedSessionKey)))
+	ciphertext = append(ciphertext, encryptedSessionKey...)
+	ciphertext = append(ciphertext, gcm.Seal(nil, make([]byte, gcm.NonceSize()), []byte(value), nil)...)
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+func newEncryptedSecret(name string, data map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name":        name,
+			"namespace":   "johnsmith-dev",
+			"annotations": map[string]interface{}{template.EncryptedAnnotation: "true"},
+		},
+		"data": data,
+	}}
+}
+
+func newSealedSecret(name string, encryptedData map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "bitnami.com/v1alpha1",
+		"kind":       "SealedSecret",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "johnsmith-dev",
+			"labels":    map[string]interface{}{"owner": "johnsmith"},
+		},
+		"spec": map[string]interface{}{
+			"encryptedData": encryptedData,
+			"template": map[string]interface{}{
+				"type": "kubernetes.io/basic-auth",
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"app": "db"},
+				},
+				"data": map[string]interface{}{
+					"username": base64.StdEncoding.EncodeToString([]byte("johnsmith")),
+				},
+			},
+		},
+	}}
+}
+
+func assertSecret(t *testing.T, cl client.Client, name, key, value string) *corev1.Secret {
+	secret := &corev1.Secret{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: name}, secret)
+	require.NoError(t, err)
+	assert.Equal(t, value, string(secret.Data[key]))
+	return secret
+}
+
+func assertSecretNotFound(t *testing.T, cl client.Client, name string) {
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "johnsmith-dev", Name: name}, &corev1.Secret{})
+	require.Error(t, err)
+	assert.True(t, apierrors.IsNotFound(err))
+}
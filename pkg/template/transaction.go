@@ -0,0 +1,67 @@
+package template
+
+import (
+	"context"
+
+	errs "github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Transaction applies sets of objects and keeps track of the objects it created, so that they
+// can be deleted if one of the subsequent applies fails. Objects which already existed before the
+// transaction and which were updated are not restored.
+type Transaction struct {
+	p       Processor
+	created []runtime.Object
+}
+
+// NewTransaction returns a new Transaction which uses this Processor to apply the objects
+func (p Processor) NewTransaction() *Transaction {
+	return &Transaction{p: p}
+}
+
+// Apply applies the given objects and records the ones that were created
+func (t *Transaction) Apply(objs []runtime.RawExtension) error {
+	for _, rawObj := range objs {
+		applied, created, err := t.p.applyObj(rawObj.Object)
+		if err != nil {
+			return err
+		}
+		if created {
+			t.created = append(t.created, applied)
+		}
+	}
+	return nil
+}
+
+// Rollback deletes all the objects that were created during this transaction, in the reverse order of their creation
+func (t *Transaction) Rollback() error {
+	var failures []error
+	for i := len(t.created) - 1; i >= 0; i-- {
+		obj := t.created[i]
+		if err := t.p.cl.Delete(context.TODO(), obj); err != nil && !apierrors.IsNotFound(err) {
+			gvk := obj.GetObjectKind().GroupVersionKind()
+			failures = append(failures, errs.Wrapf(err, "unable to delete resource of kind: %s, version: %s", gvk.Kind, gvk.Version))
+		}
+	}
+	t.created = nil
+	return errors.NewAggregate(failures)
+}
+
+// ApplyAll applies all the given sets of objects (eg, resulting from the processing of several templates)
+// as a single unit: if any object fails to be applied, all objects created until then are deleted
+func (p Processor) ApplyAll(objSets ...[]runtime.RawExtension) error {
+	defer trackApply()()
+	tx := p.NewTransaction()
+	for _, objs := range objSets {
+		if err := tx.Apply(objs); err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				return errs.Wrapf(err, "failed to rollback the created resources: %s", rollbackErr.Error())
+			}
+			return err
+		}
+	}
+	return nil
+}
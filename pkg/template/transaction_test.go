@@ -0,0 +1,155 @@
+package template_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	authv1 "github.com/openshift/api/authorization/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestApplyAll(t *testing.T) {
+
+	user := getNameWithTimestamp("user")
+	s := addToScheme(t)
+	codecFactory := serializer.NewCodecFactory(s)
+	decoder := codecFactory.UniversalDeserializer()
+	values := map[string]string{
+		"USERNAME": user,
+	}
+
+	process := func(t *testing.T, p template.Processor, content string) []runtime.RawExtension {
+		tmpl, err := decodeTemplate(decoder, content)
+		require.NoError(t, err)
+		objs, err := p.Process(tmpl, values)
+		require.NoError(t, err)
+		return objs
+	}
+
+	t.Run("should apply all templates", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessor(cl, s)
+
+		// when
+		err := p.ApplyAll(process(t, p, namespaceTmpl), process(t, p, rolebindingTmpl))
+
+		// then
+		require.NoError(t, err)
+		assertNamespaceExists(t, cl, user)
+		assertRoleBindingExists(t, cl, user)
+	})
+
+	t.Run("should delete created objects when apply fails", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if u, ok := obj.(*unstructured.Unstructured); ok && u.GetKind() == "RoleBinding" {
+				return errors.New("unable to create rolebinding")
+			}
+			return cl.Client.Create(ctx, obj, opts...)
+		}
+		p := template.NewProcessor(cl, s)
+
+		// when
+		err := p.ApplyAll(process(t, p, namespaceTmpl), process(t, p, rolebindingTmpl))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to create rolebinding")
+		ns := &corev1.Namespace{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: user}, ns)
+		require.Error(t, err)
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("should not delete objects which existed before", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		p := template.NewProcessor(cl, s)
+		err := p.Apply(process(t, p, namespaceTmpl))
+		require.NoError(t, err)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if u, ok := obj.(*unstructured.Unstructured); ok && u.GetKind() == "RoleBinding" {
+				return errors.New("unable to create rolebinding")
+			}
+			return cl.Client.Create(ctx, obj, opts...)
+		}
+
+		// when
+		err = p.ApplyAll(process(t, p, namespaceTmpl), process(t, p, rolebindingTmpl))
+
+		// then
+		require.Error(t, err)
+		assertNamespaceExists(t, cl, user)
+	})
+
+	t.Run("should report rollback failure", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			if u, ok := obj.(*unstructured.Unstructured); ok && u.GetKind() == "RoleBinding" {
+				return errors.New("unable to create rolebinding")
+			}
+			return cl.Client.Create(ctx, obj, opts...)
+		}
+		cl.MockDelete = func(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+			return errors.New("unable to delete namespace")
+		}
+		p := template.NewProcessor(cl, s)
+
+		// when
+		err := p.ApplyAll(process(t, p, namespaceTmpl), process(t, p, rolebindingTmpl))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to create rolebinding")
+		assert.Contains(t, err.Error(), "unable to delete namespace")
+	})
+}
+
+func TestTransactionRollback(t *testing.T) {
+
+	user := getNameWithTimestamp("user")
+	s := addToScheme(t)
+	codecFactory := serializer.NewCodecFactory(s)
+	decoder := codecFactory.UniversalDeserializer()
+	values := map[string]string{
+		"USERNAME": user,
+	}
+
+	// given
+	cl := test.NewFakeClient(t)
+	p := template.NewProcessor(cl, s)
+	tmpl, err := decodeTemplate(decoder, namespaceAndRolebindingTmpl)
+	require.NoError(t, err)
+	objs, err := p.Process(tmpl, values)
+	require.NoError(t, err)
+	tx := p.NewTransaction()
+	err = tx.Apply(objs)
+	require.NoError(t, err)
+
+	// when
+	err = tx.Rollback()
+
+	// then
+	require.NoError(t, err)
+	rb := &authv1.RoleBinding{}
+	err = cl.Get(context.TODO(), types.NamespacedName{Namespace: user, Name: user + "-edit"}, rb)
+	assert.True(t, apierrors.IsNotFound(err))
+	ns := &corev1.Namespace{}
+	err = cl.Get(context.TODO(), types.NamespacedName{Name: user}, ns)
+	assert.True(t, apierrors.IsNotFound(err))
+}
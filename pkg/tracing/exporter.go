@@ -0,0 +1,201 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	errs "github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("tracing")
+
+const (
+	// ServiceName the name of the service of the exported spans
+	ServiceName = "member-operator"
+	// tracesPath the path of the OTLP/HTTP endpoint which receives the traces
+	tracesPath = "/v1/traces"
+	// queueSize the maximum number of ended spans waiting for the export. The spans which are ended while the queue is full are dropped
+	queueSize = 2048
+	// batchSize the maximum number of spans exported per request
+	batchSize = 512
+	// exportPeriod the period of the export of the queued spans
+	exportPeriod = 5 * time.Second
+	// exportTimeout the timeout of the requests to the collector
+	exportTimeout = 10 * time.Second
+
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+// Exporter exports the ended spans to an OpenTelemetry collector, in batches, with the OTLP/HTTP protocol (JSON encoding).
+// It is meant to be added to the manager, and runs on all the replicas.
+type Exporter struct {
+	url    string
+	client *http.Client
+	spans  chan spanData
+}
+
+var _ manager.Runnable = &Exporter{}
+
+// NewExporter returns a new Exporter which sends the spans to the given OTLP/HTTP endpoint (eg: `http://otel-collector:4318`)
+func NewExporter(endpoint string) *Exporter {
+	return &Exporter{
+		url:    strings.TrimSuffix(endpoint, "/") + tracesPath,
+		client: &http.Client{Timeout: exportTimeout},
+		spans:  make(chan spanData, queueSize),
+	}
+}
+
+// NeedLeaderElection returns false, since the spans of the reconciliations of the webhooks and of the standby replicas are exported too
+func (e *Exporter) NeedLeaderElection() bool {
+	return false
+}
+
+// Start exports the queued spans periodically, or as soon as a batch is full, until the given channel is closed.
+// The remaining spans are exported before returning
+func (e *Exporter) Start(stop <-chan struct{}) error {
+	log.Info("exporting the traces", "url", e.url)
+	ticker := time.NewTicker(exportPeriod)
+	defer ticker.Stop()
+	batch := make([]spanData, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			log.Error(err, "unable to export the spans", "count", len(batch))
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case <-stop:
+			for {
+				select {
+				case span := <-e.spans:
+					batch = append(batch, span)
+					if len(batch) == batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return nil
+				}
+			}
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) == batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// queue queues the given ended span for the export, or drops it if the queue is full
+func (e *Exporter) queue(span spanData) {
+	select {
+	case e.spans <- span:
+	default:
+		log.Info("dropping span, the export queue is full", "name", span.Name)
+	}
+}
+
+// export sends the given spans to the collector
+func (e *Exporter) export(spans []spanData) error {
+	content, err := json.Marshal(exportRequest{
+		ResourceSpans: []resourceSpans{
+			{
+				Resource: resource{Attributes: []attribute{{Key: "service.name", Value: attributeValue{StringValue: ServiceName}}}},
+				ScopeSpans: []scopeSpans{
+					{
+						Scope: scope{Name: ServiceName},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return errs.Wrap(err, "unable to marshal the spans")
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return errs.Wrap(err, "unable to send the spans")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response from the collector: %s", resp.Status)
+	}
+	return nil
+}
+
+// The types of the OTLP/HTTP JSON encoding of the traces, in which the IDs are hex-encoded and the timestamps are strings
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanData `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanData struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []attribute `json:"attributes,omitempty"`
+	Status            *status     `json:"status,omitempty"`
+}
+
+type attribute struct {
+	Key   string         `json:"key"`
+	Value attributeValue `json:"value"`
+}
+
+type attributeValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// attributes returns the given attributes sorted by key
+func attributes(values map[string]string) []attribute {
+	result := make([]attribute, 0, len(values))
+	for key, value := range values {
+		result = append(result, attribute{Key: key, Value: attributeValue{StringValue: value}})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	lock     sync.RWMutex
+	exporter *Exporter
+)
+
+// Enable exports the spans which are started from now on with the given Exporter. The spans are not recorded if it is nil
+func Enable(e *Exporter) {
+	lock.Lock()
+	defer lock.Unlock()
+	exporter = e
+}
+
+func currentExporter() *Exporter {
+	lock.RLock()
+	defer lock.RUnlock()
+	return exporter
+}
+
+// Span an operation of a trace, such as the reconciliation of a resource, the processing of a template or the apply of an object.
+// All the methods of a nil Span do nothing, so that the code does not need to check whether the tracing is enabled.
+type Span struct {
+	exporter   *Exporter
+	traceID    string
+	spanID     string
+	parentID   string
+	name       string
+	start      time.Time
+	lock       sync.Mutex
+	attributes map[string]string
+}
+
+// Start starts a new trace with a root span of the given name and attributes (as key/value pairs), or returns nil if the tracing is disabled
+func Start(name string, keysAndValues ...interface{}) *Span {
+	e := currentExporter()
+	if e == nil {
+		return nil
+	}
+	return newSpan(e, newID(16), "", name, keysAndValues)
+}
+
+// Child starts a new span of the given name and attributes (as key/value pairs) in the trace of this span, as a child of this span
+func (s *Span) Child(name string, keysAndValues ...interface{}) *Span {
+	if s == nil {
+		return nil
+	}
+	return newSpan(s.exporter, s.traceID, s.spanID, name, keysAndValues)
+}
+
+func newSpan(e *Exporter, traceID, parentID, name string, keysAndValues []interface{}) *Span {
+	s := &Span{
+		exporter:   e,
+		traceID:    traceID,
+		spanID:     newID(8),
+		parentID:   parentID,
+		name:       name,
+		start:      time.Now(),
+		attributes: map[string]string{},
+	}
+	s.SetAttributes(keysAndValues...)
+	return s
+}
+
+// SetAttributes sets the given attributes (as key/value pairs) on the span
+func (s *Span) SetAttributes(keysAndValues ...interface{}) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		s.attributes[fmt.Sprint(keysAndValues[i])] = fmt.Sprint(keysAndValues[i+1])
+	}
+}
+
+// End ends the span, with an error status if the given error is not nil, and queues it for the export
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	data := spanData{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: fmt.Sprint(s.start.UnixNano()),
+		EndTimeUnixNano:   fmt.Sprint(time.Now().UnixNano()),
+		Attributes:        attributes(s.attributes),
+	}
+	if err != nil {
+		data.Status = &status{Code: statusCodeError, Message: err.Error()}
+	}
+	s.lock.Unlock()
+	s.exporter.queue(data)
+}
+
+// newID returns a random ID of the given size, in hexadecimal
+func newID(size int) string {
+	id := make([]byte, size)
+	// the IDs only need to be unique, a failure leaves them partially random
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
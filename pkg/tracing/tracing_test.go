@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracing(t *testing.T) {
+
+	t.Run("spans exported", func(t *testing.T) {
+		// given
+		var lock sync.Mutex
+		var received []exportRequest
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/traces", r.URL.Path)
+			req := exportRequest{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			lock.Lock()
+			defer lock.Unlock()
+			received = append(received, req)
+		}))
+		defer collector.Close()
+		e := NewExporter(collector.URL + "/")
+		Enable(e)
+		defer Enable(nil)
+		stop := make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- e.Start(stop)
+		}()
+
+		// when
+		root := Start("NSTemplateSet.Reconcile", "username", "johnsmith")
+		child := root.Child("apply", "kind", "Namespace")
+		child.End(errors.New("mock error"))
+		root.SetAttributes("tier", "basic")
+		root.End(nil)
+		close(stop)
+
+		// then
+		require.NoError(t, <-done)
+		lock.Lock()
+		defer lock.Unlock()
+		require.Len(t, received, 1)
+		require.Len(t, received[0].ResourceSpans, 1)
+		assert.Equal(t, []attribute{{Key: "service.name", Value: attributeValue{StringValue: ServiceName}}}, received[0].ResourceSpans[0].Resource.Attributes)
+		spans := received[0].ResourceSpans[0].ScopeSpans[0].Spans
+		require.Len(t, spans, 2)
+		assert.Equal(t, "apply", spans[0].Name)
+		assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+		assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+		assert.Len(t, spans[0].TraceID, 32)
+		assert.Len(t, spans[0].SpanID, 16)
+		assert.Equal(t, &status{Code: statusCodeError, Message: "mock error"}, spans[0].Status)
+		assert.Equal(t, "NSTemplateSet.Reconcile", spans[1].Name)
+		assert.Empty(t, spans[1].ParentSpanID)
+		assert.Nil(t, spans[1].Status)
+		assert.Equal(t, []attribute{
+			{Key: "tier", Value: attributeValue{StringValue: "basic"}},
+			{Key: "username", Value: attributeValue{StringValue: "johnsmith"}},
+		}, spans[1].Attributes)
+	})
+
+	t.Run("nothing recorded when disabled", func(t *testing.T) {
+		// when
+		root := Start("NSTemplateSet.Reconcile")
+		child := root.Child("apply")
+		child.SetAttributes("kind", "Namespace")
+		child.End(nil)
+		root.End(nil)
+
+		// then
+		assert.Nil(t, root)
+		assert.Nil(t, child)
+	})
+}
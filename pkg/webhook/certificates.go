@@ -0,0 +1,341 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	errs "github.com/pkg/errors"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ServiceName the name of the Service of the webhooks, which is referenced by their configurations
+	ServiceName = "member-operator-webhook"
+	// CertificatesSecretName the name of the Secret of the operator namespace which holds the CA and the serving certificate
+	// generated by the operator, so that they are shared by all its replicas
+	CertificatesSecretName = "member-operator-webhook-ca"
+
+	caCertKey     = "ca.crt"
+	caKeyKey      = "ca.key"
+	caBundleKey   = "ca-bundle.crt"
+	caValidity    = 2 * 365 * 24 * time.Hour
+	certValidity  = 90 * 24 * time.Hour
+	syncInterval  = time.Hour
+	certsFileMode = 0600
+)
+
+// CertDir the directory of the serving certificate generated by the operator. It differs from the default directory of the webhook server,
+// in which the certificate of the service CA operator is mounted (read-only)
+var CertDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "operator-serving-certs")
+
+// CertManager generates the CA and the serving certificate of the webhooks, renews them when a third of their validity is left,
+// sets the CA bundle on the configurations of the webhooks which reference the webhook Service, and writes the serving certificate
+// in the directory of the webhook server (which reloads it when it changes). When the CA is renewed, the previous one is kept
+// in the CA bundle until it expires, so that the serving certificates it signed remain trusted.
+type CertManager struct {
+	client    client.Client
+	namespace string
+	certDir   string
+}
+
+// NewCertManager returns a new CertManager of the webhooks of the operator deployed in the given namespace
+func NewCertManager(cl client.Client, namespace, certDir string) *CertManager {
+	return &CertManager{
+		client:    cl,
+		namespace: namespace,
+		certDir:   certDir,
+	}
+}
+
+// Start syncs the certificates every hour, until the given channel is closed
+func (m *CertManager) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			if err := m.Sync(time.Now()); err != nil {
+				log.Error(err, "unable to sync the certificates of the webhooks")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, since all the replicas serve the webhooks
+func (m *CertManager) NeedLeaderElection() bool {
+	return false
+}
+
+// Sync renews the certificates which expire soon (or which do not exist yet), then updates the CA bundle of the webhook configurations
+// and the serving certificate of the webhook server. The replicas which race to renew the certificates retry with the winner's ones
+func (m *CertManager) Sync(now time.Time) error {
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		if err = m.sync(now); err == nil || !(errors.IsConflict(errs.Cause(err)) || errors.IsAlreadyExists(errs.Cause(err))) {
+			return err
+		}
+	}
+	return err
+}
+
+func (m *CertManager) sync(now time.Time) error {
+	secret := &corev1.Secret{}
+	err := m.client.Get(context.TODO(), types.NamespacedName{Namespace: m.namespace, Name: CertificatesSecretName}, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return errs.Wrap(err, "unable to get the certificates of the webhooks")
+	}
+	notFound := errors.IsNotFound(err)
+	if notFound {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: m.namespace, Name: CertificatesSecretName},
+			Type:       corev1.SecretTypeOpaque,
+		}
+	}
+	data := map[string][]byte{}
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+	renewed, err := renewCertificates(data, m.namespace, now)
+	if err != nil {
+		return err
+	}
+	if renewed {
+		secret.Data = data
+		if notFound {
+			err = m.client.Create(context.TODO(), secret)
+		} else {
+			err = m.client.Update(context.TODO(), secret)
+		}
+		if err != nil {
+			return errs.Wrap(err, "unable to store the certificates of the webhooks")
+		}
+		log.Info("certificates of the webhooks renewed")
+	}
+	// the CA bundle is updated before the serving certificate, so that the new certificate is trusted once it is served
+	if err := m.setCABundle(data[caBundleKey]); err != nil {
+		return err
+	}
+	return m.writeServingCertificate(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey])
+}
+
+// renewCertificates renews the CA and the serving certificate of the webhook Service of the given namespace in the given data if they are
+// missing, invalid or expire soon, and prunes the expired CAs from the CA bundle. Returns true if the data changed
+func renewCertificates(data map[string][]byte, namespace string, now time.Time) (bool, error) {
+	renewed := false
+	ca, caKey, err := parseKeyPair(data[caCertKey], data[caKeyKey])
+	if err != nil || expiresSoon(ca, caValidity, now) {
+		if ca, caKey, err = newCertificate(nil, nil, "member-operator-webhook-ca", nil, caValidity, now); err != nil {
+			return false, errs.Wrap(err, "unable to generate the CA of the webhooks")
+		}
+		data[caCertKey], data[caKeyKey], err = encodeKeyPair(ca, caKey)
+		if err != nil {
+			return false, err
+		}
+		renewed = true
+	}
+	// the current CA first, then the previous ones which are still valid
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	for _, cert := range parseCertificates(data[caBundleKey]) {
+		if !cert.Equal(ca) && now.Before(cert.NotAfter) {
+			bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+		}
+	}
+	if !bytes.Equal(bundle, data[caBundleKey]) {
+		data[caBundleKey] = bundle
+		renewed = true
+	}
+
+	serviceDNSName := fmt.Sprintf("%s.%s.svc", ServiceName, namespace)
+	cert, _, err := parseKeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey])
+	if err == nil && !expiresSoon(cert, certValidity, now) && isTrusted(cert, bundle, serviceDNSName, now) {
+		return renewed, nil
+	}
+	dnsNames := []string{ServiceName, ServiceName + "." + namespace, serviceDNSName, serviceDNSName + ".cluster.local"}
+	cert, key, err := newCertificate(ca, caKey, serviceDNSName, dnsNames, certValidity, now)
+	if err != nil {
+		return false, errs.Wrap(err, "unable to generate the serving certificate of the webhooks")
+	}
+	data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey], err = encodeKeyPair(cert, key)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// newCertificate generates a new certificate (and its key) signed by the given CA, or a self-signed CA if the given CA is nil
+func newCertificate(ca *x509.Certificate, caKey *ecdsa.PrivateKey, commonName string, dnsNames []string, validity time.Duration, now time.Time) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour), // tolerates the clock skew between the nodes
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	parent, signer := tmpl, key
+	if ca == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+		tmpl.ExtKeyUsage = nil
+	} else {
+		parent, signer = ca, caKey
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(raw)
+	return cert, key, err
+}
+
+// expiresSoon returns true if less than a third of the given validity is left
+func expiresSoon(cert *x509.Certificate, validity time.Duration, now time.Time) bool {
+	return now.After(cert.NotAfter.Add(-validity / 3))
+}
+
+// isTrusted returns true if the given certificate is valid for the given DNS name and signed by a CA of the given bundle
+func isTrusted(cert *x509.Certificate, bundle []byte, dnsName string, now time.Time) bool {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(bundle) {
+		return false
+	}
+	_, err := cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: dnsName, CurrentTime: now})
+	return err == nil
+}
+
+// parseKeyPair parses the given PEM certificate and EC private key
+func parseKeyPair(certPEM, keyPEM []byte) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certs := parseCertificates(certPEM)
+	if len(certs) == 0 {
+		return nil, nil, fmt.Errorf("no certificate")
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("no private key")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certs[0], key, nil
+}
+
+// parseCertificates parses the given PEM certificates, skipping the invalid ones
+func parseCertificates(content []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		if block, content = pem.Decode(content); block == nil {
+			return certs
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+// encodeKeyPair returns the PEM encoding of the given certificate and private key
+func encodeKeyPair(cert *x509.Certificate, key *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	raw, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, errs.Wrap(err, "unable to encode the private key")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: raw}), nil
+}
+
+// setCABundle sets the given CA bundle on the webhooks which reference the webhook Service in their validating or mutating configuration
+func (m *CertManager) setCABundle(bundle []byte) error {
+	validating := &admissionregistrationv1beta1.ValidatingWebhookConfigurationList{}
+	if err := m.client.List(context.TODO(), validating); err != nil {
+		return errs.Wrap(err, "unable to list the validating webhook configurations")
+	}
+	for i := range validating.Items {
+		cfg := &validating.Items[i]
+		if !m.setWebhooksCABundle(cfg.Webhooks, bundle) {
+			continue
+		}
+		log.Info("updating the CA bundle of the webhook configuration", "name", cfg.Name)
+		if err := m.client.Update(context.TODO(), cfg); err != nil {
+			return errs.Wrapf(err, "unable to update the CA bundle of the webhook configuration '%s'", cfg.Name)
+		}
+	}
+	mutating := &admissionregistrationv1beta1.MutatingWebhookConfigurationList{}
+	if err := m.client.List(context.TODO(), mutating); err != nil {
+		return errs.Wrap(err, "unable to list the mutating webhook configurations")
+	}
+	for i := range mutating.Items {
+		cfg := &mutating.Items[i]
+		if !m.setWebhooksCABundle(cfg.Webhooks, bundle) {
+			continue
+		}
+		log.Info("updating the CA bundle of the webhook configuration", "name", cfg.Name)
+		if err := m.client.Update(context.TODO(), cfg); err != nil {
+			return errs.Wrapf(err, "unable to update the CA bundle of the webhook configuration '%s'", cfg.Name)
+		}
+	}
+	return nil
+}
+
+// setWebhooksCABundle sets the given CA bundle on the given webhooks which reference the webhook Service. Returns true if a webhook changed
+func (m *CertManager) setWebhooksCABundle(webhooks []admissionregistrationv1beta1.Webhook, bundle []byte) bool {
+	changed := false
+	for i := range webhooks {
+		svc := webhooks[i].ClientConfig.Service
+		if svc == nil || svc.Name != ServiceName || svc.Namespace != m.namespace || bytes.Equal(webhooks[i].ClientConfig.CABundle, bundle) {
+			continue
+		}
+		webhooks[i].ClientConfig.CABundle = bundle
+		changed = true
+	}
+	return changed
+}
+
+// writeServingCertificate writes the given certificate and key in the directory of the webhook server, unless they are already there
+func (m *CertManager) writeServingCertificate(cert, key []byte) error {
+	if err := os.MkdirAll(m.certDir, 0700); err != nil {
+		return errs.Wrap(err, "unable to create the directory of the serving certificate")
+	}
+	// the key first, since the webhook server reloads the key pair when the certificate changes
+	for _, file := range []struct {
+		name    string
+		content []byte
+	}{{corev1.TLSPrivateKeyKey, key}, {corev1.TLSCertKey, cert}} {
+		path, content := filepath.Join(m.certDir, file.name), file.content
+		if existing, err := ioutil.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+			continue
+		}
+		if err := ioutil.WriteFile(path, content, certsFileMode); err != nil {
+			return errs.Wrapf(err, "unable to write '%s'", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCertManagerSync(t *testing.T) {
+	// given
+	namespace := "toolchain-member-operator"
+	now := time.Now()
+	newWebhookConfigs := func() (*admissionregistrationv1beta1.ValidatingWebhookConfiguration, *admissionregistrationv1beta1.MutatingWebhookConfiguration) {
+		validating := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "member-operator-hosts"},
+			Webhooks: []admissionregistrationv1beta1.Webhook{
+				{Name: "hosts.member-operator.toolchain.dev.openshift.com", ClientConfig: webhookClientConfig(namespace, ServiceName)},
+			},
+		}
+		mutating := &admissionregistrationv1beta1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "member-operator-pods"},
+			Webhooks: []admissionregistrationv1beta1.Webhook{
+				{Name: "pods.member-operator.toolchain.dev.openshift.com", ClientConfig: webhookClientConfig(namespace, ServiceName)},
+				{Name: "pods.other-operator.example.com", ClientConfig: webhookClientConfig("other-operator", "other-webhook")},
+			},
+		}
+		return validating, mutating
+	}
+
+	t.Run("certificates generated", func(t *testing.T) {
+		// given
+		validating, mutating := newWebhookConfigs()
+		cl := test.NewFakeClient(t, validating, mutating)
+		certDir := tempDir(t)
+		defer os.RemoveAll(certDir)
+		m := NewCertManager(cl, namespace, certDir)
+
+		// when
+		err := m.Sync(now)
+
+		// then
+		require.NoError(t, err)
+		secret := getCertificatesSecret(t, cl, namespace)
+		bundle := secret.Data[caBundleKey]
+		require.NotEmpty(t, bundle)
+		assertServingCertificate(t, certDir, bundle, "member-operator-webhook.toolchain-member-operator.svc", now)
+		assertCABundles(t, cl, namespace, bundle)
+		mutating = getMutatingWebhookConfig(t, cl)
+		assert.Empty(t, mutating.Webhooks[1].ClientConfig.CABundle)
+
+		t.Run("certificates unchanged", func(t *testing.T) {
+			// when
+			err := m.Sync(now.Add(24 * time.Hour))
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, secret.Data, getCertificatesSecret(t, cl, namespace).Data)
+		})
+
+		t.Run("serving certificate renewed", func(t *testing.T) {
+			// given
+			later := now.Add(70 * 24 * time.Hour)
+
+			// when
+			err := m.Sync(later)
+
+			// then
+			require.NoError(t, err)
+			renewed := getCertificatesSecret(t, cl, namespace)
+			assert.Equal(t, secret.Data[caCertKey], renewed.Data[caCertKey])
+			assert.Equal(t, bundle, renewed.Data[caBundleKey])
+			assert.NotEqual(t, secret.Data[corev1.TLSCertKey], renewed.Data[corev1.TLSCertKey])
+			assertServingCertificate(t, certDir, bundle, "member-operator-webhook.toolchain-member-operator.svc", later)
+		})
+
+		t.Run("CA renewed and previous CA kept in the bundle", func(t *testing.T) {
+			// given
+			later := now.Add(500 * 24 * time.Hour)
+
+			// when
+			err := m.Sync(later)
+
+			// then
+			require.NoError(t, err)
+			renewed := getCertificatesSecret(t, cl, namespace)
+			assert.NotEqual(t, secret.Data[caCertKey], renewed.Data[caCertKey])
+			cas := parseCertificates(renewed.Data[caBundleKey])
+			require.Len(t, cas, 2)
+			assert.Equal(t, parseCertificates(renewed.Data[caCertKey])[0], cas[0])
+			assert.Equal(t, parseCertificates(secret.Data[caCertKey])[0], cas[1])
+			assertCABundles(t, cl, namespace, renewed.Data[caBundleKey])
+
+			t.Run("expired CA removed from the bundle", func(t *testing.T) {
+				// given
+				later := now.Add(750 * 24 * time.Hour)
+
+				// when
+				err := m.Sync(later)
+
+				// then
+				require.NoError(t, err)
+				cas := parseCertificates(getCertificatesSecret(t, cl, namespace).Data[caBundleKey])
+				require.Len(t, cas, 1)
+				assert.Equal(t, parseCertificates(renewed.Data[caCertKey])[0], cas[0])
+			})
+		})
+	})
+
+	t.Run("certificates of another replica reused", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		certDir := tempDir(t)
+		defer os.RemoveAll(certDir)
+		require.NoError(t, NewCertManager(cl, namespace, certDir).Sync(now))
+		secret := getCertificatesSecret(t, cl, namespace)
+		otherCertDir := tempDir(t)
+		defer os.RemoveAll(otherCertDir)
+
+		// when
+		err := NewCertManager(cl, namespace, otherCertDir).Sync(now)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, secret.Data, getCertificatesSecret(t, cl, namespace).Data)
+		cert, err := ioutil.ReadFile(filepath.Join(otherCertDir, corev1.TLSCertKey))
+		require.NoError(t, err)
+		assert.Equal(t, secret.Data[corev1.TLSCertKey], cert)
+	})
+
+	t.Run("invalid certificates replaced", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: CertificatesSecretName},
+			Data:       map[string][]byte{caCertKey: []byte("invalid"), corev1.TLSCertKey: []byte("invalid")},
+		})
+		certDir := tempDir(t)
+		defer os.RemoveAll(certDir)
+
+		// when
+		err := NewCertManager(cl, namespace, certDir).Sync(now)
+
+		// then
+		require.NoError(t, err)
+		bundle := getCertificatesSecret(t, cl, namespace).Data[caBundleKey]
+		assertServingCertificate(t, certDir, bundle, "member-operator-webhook.toolchain-member-operator.svc", now)
+	})
+}
+
+func webhookClientConfig(namespace, name string) admissionregistrationv1beta1.WebhookClientConfig {
+	return admissionregistrationv1beta1.WebhookClientConfig{
+		Service: &admissionregistrationv1beta1.ServiceReference{Namespace: namespace, Name: name},
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "webhook-certs")
+	require.NoError(t, err)
+	return dir
+}
+
+func getCertificatesSecret(t *testing.T, cl *test.FakeClient, namespace string) *corev1.Secret {
+	secret := &corev1.Secret{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: CertificatesSecretName}, secret))
+	return secret
+}
+
+func getMutatingWebhookConfig(t *testing.T, cl *test.FakeClient) *admissionregistrationv1beta1.MutatingWebhookConfiguration {
+	cfg := &admissionregistrationv1beta1.MutatingWebhookConfiguration{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Name: "member-operator-pods"}, cfg))
+	return cfg
+}
+
+func assertCABundles(t *testing.T, cl *test.FakeClient, namespace string, bundle []byte) {
+	validating := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Name: "member-operator-hosts"}, validating))
+	assert.Equal(t, bundle, validating.Webhooks[0].ClientConfig.CABundle)
+	assert.Equal(t, bundle, getMutatingWebhookConfig(t, cl).Webhooks[0].ClientConfig.CABundle)
+}
+
+func assertServingCertificate(t *testing.T, certDir string, bundle []byte, dnsName string, now time.Time) {
+	content, err := ioutil.ReadFile(filepath.Join(certDir, corev1.TLSCertKey))
+	require.NoError(t, err)
+	_, err = ioutil.ReadFile(filepath.Join(certDir, corev1.TLSPrivateKeyKey))
+	require.NoError(t, err)
+	block, _ := pem.Decode(content)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(bundle))
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: dnsName, CurrentTime: now})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	routev1 "github.com/openshift/api/route/v1"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// HostValidationPath the path on which the host validation webhook is served
+	HostValidationPath = "/validate-hosts"
+
+	// usernamePlaceholder the placeholder of the user host pattern which is replaced with the owner of the namespace
+	usernamePlaceholder = "{username}"
+	// ownerLabel the label set on the user namespaces, with the name of their owner
+	ownerLabel = "owner"
+)
+
+// HostValidator denies the Routes and Ingresses of the user namespaces whose hosts do not match the user host pattern
+// of the MemberOperatorConfig, so that the users cannot hijack the hostnames of the other users or of the platform.
+// The objects in the namespaces which are not owned by a user are always allowed.
+type HostValidator struct {
+	client client.Client
+}
+
+var _ admission.Handler = &HostValidator{}
+
+// NewHostValidator returns a new HostValidator
+func NewHostValidator(cl client.Client) *HostValidator {
+	return &HostValidator{
+		client: cl,
+	}
+}
+
+// Handle validates the hosts of the Route or Ingress in the given request
+func (v *HostValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	hosts, err := requestedHosts(req)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if len(hosts) == 0 {
+		return admission.Allowed("")
+	}
+
+	if !config.HostValidationEnforced() {
+		return admission.Allowed("the host validation is disabled")
+	}
+	pattern := config.GetUserHostPattern()
+	if pattern == "" {
+		return admission.Allowed("the hosts are not restricted")
+	}
+
+	ns := &corev1.Namespace{}
+	if err := v.client.Get(ctx, types.NamespacedName{Name: req.Namespace}, ns); err != nil {
+		log.Error(err, "unable to get the namespace", "namespace", req.Namespace)
+		return admission.Errored(http.StatusInternalServerError, errs.Wrapf(err, "failed to get namespace '%s'", req.Namespace))
+	}
+	owner, ok := ns.Labels[ownerLabel]
+	if !ok || owner == "" {
+		return admission.Allowed("not a user namespace")
+	}
+
+	allowed := strings.Replace(pattern, usernamePlaceholder, owner, -1)
+	for _, host := range hosts {
+		matched, err := path.Match(allowed, host)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, errs.Wrapf(err, "invalid user host pattern '%s'", pattern))
+		}
+		if !matched {
+			log.Info("denying host", "namespace", req.Namespace, "name", req.Name, "kind", req.Kind.Kind, "host", host)
+			return admission.Denied(fmt.Sprintf("host '%s' is not allowed: the hosts in the namespaces of user '%s' must match '%s'", host, owner, allowed))
+		}
+	}
+	return admission.Allowed("")
+}
+
+// requestedHosts returns the hosts claimed by the Route or Ingress of the given request. Other kinds of objects don't claim any host.
+func requestedHosts(req admission.Request) ([]string, error) {
+	if len(req.Object.Raw) == 0 {
+		return nil, nil
+	}
+	var hosts []string
+	switch req.Kind.Kind {
+	case "Route":
+		route := &routev1.Route{}
+		if err := json.Unmarshal(req.Object.Raw, route); err != nil {
+			return nil, errs.Wrap(err, "failed to decode the route")
+		}
+		hosts = append(hosts, route.Spec.Host)
+	case "Ingress":
+		ingress := &extensionsv1beta1.Ingress{}
+		if err := json.Unmarshal(req.Object.Raw, ingress); err != nil {
+			return nil, errs.Wrap(err, "failed to decode the ingress")
+		}
+		for _, rule := range ingress.Spec.Rules {
+			hosts = append(hosts, rule.Host)
+		}
+		for _, tls := range ingress.Spec.TLS {
+			hosts = append(hosts, tls.Hosts...)
+		}
+	}
+	// the hosts left empty are generated by the cluster
+	result := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if host != "" {
+			result = append(result, host)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	username      = "johnsmith"
+	namespaceName = "toolchain-member"
+	hostPattern   = "*.{username}.apps.example.com"
+)
+
+func TestHandle(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	t.Run("route", func(t *testing.T) {
+
+		t.Run("host allowed", func(t *testing.T) {
+			// given
+			v := NewHostValidator(newFakeClient(t, newConfig(hostPattern), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newRouteRequest(t, "app.johnsmith.apps.example.com"))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+
+		t.Run("host of another user allowed when validation disabled", func(t *testing.T) {
+			// given
+			disabled := false
+			cfg := newConfig(hostPattern)
+			cfg.Spec.Webhooks = &memberv1alpha1.WebhooksConfig{HostValidation: &disabled}
+			v := NewHostValidator(newFakeClient(t, cfg, newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newRouteRequest(t, "app.janedoe.apps.example.com"))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+
+		t.Run("host of another user denied", func(t *testing.T) {
+			// given
+			v := NewHostValidator(newFakeClient(t, newConfig(hostPattern), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newRouteRequest(t, "app.janedoe.apps.example.com"))
+
+			// then
+			assert.False(t, resp.Allowed)
+			assert.Equal(t, "host 'app.janedoe.apps.example.com' is not allowed: the hosts in the namespaces of user 'johnsmith' must match '*.johnsmith.apps.example.com'", string(resp.Result.Reason))
+		})
+
+		t.Run("platform host denied", func(t *testing.T) {
+			// given
+			v := NewHostValidator(newFakeClient(t, newConfig(hostPattern), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newRouteRequest(t, "console-openshift-console.apps.example.com"))
+
+			// then
+			assert.False(t, resp.Allowed)
+		})
+
+		t.Run("generated host allowed", func(t *testing.T) {
+			// given
+			v := NewHostValidator(newFakeClient(t, newConfig(hostPattern), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newRouteRequest(t, ""))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+	})
+
+	t.Run("ingress", func(t *testing.T) {
+
+		t.Run("hosts allowed", func(t *testing.T) {
+			// given
+			v := NewHostValidator(newFakeClient(t, newConfig(hostPattern), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newIngressRequest(t, "app.johnsmith.apps.example.com", "app.johnsmith.apps.example.com"))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+
+		t.Run("tls host denied", func(t *testing.T) {
+			// given
+			v := NewHostValidator(newFakeClient(t, newConfig(hostPattern), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newIngressRequest(t, "app.johnsmith.apps.example.com", "app.janedoe.apps.example.com"))
+
+			// then
+			assert.False(t, resp.Allowed)
+		})
+	})
+
+	t.Run("not a user namespace", func(t *testing.T) {
+		// given
+		ns := newUserNamespace()
+		ns.Labels = nil
+		v := NewHostValidator(newFakeClient(t, newConfig(hostPattern), ns))
+
+		// when
+		resp := v.Handle(context.TODO(), newRouteRequest(t, "console-openshift-console.apps.example.com"))
+
+		// then
+		assert.True(t, resp.Allowed)
+	})
+
+	t.Run("hosts not restricted", func(t *testing.T) {
+		// given
+		v := NewHostValidator(newFakeClient(t, newUserNamespace()))
+
+		// when
+		resp := v.Handle(context.TODO(), newRouteRequest(t, "console-openshift-console.apps.example.com"))
+
+		// then
+		assert.True(t, resp.Allowed)
+	})
+
+	t.Run("other kind allowed", func(t *testing.T) {
+		// given
+		v := NewHostValidator(newFakeClient(t, newConfig(hostPattern), newUserNamespace()))
+		req := newRouteRequest(t, "app.janedoe.apps.example.com")
+		req.Kind.Kind = "Service"
+
+		// when
+		resp := v.Handle(context.TODO(), req)
+
+		// then
+		assert.True(t, resp.Allowed)
+	})
+
+	t.Run("invalid object", func(t *testing.T) {
+		// given
+		v := NewHostValidator(newFakeClient(t, newConfig(hostPattern), newUserNamespace()))
+		req := newRouteRequest(t, "app.johnsmith.apps.example.com")
+		req.Object.Raw = []byte("{invalid")
+
+		// when
+		resp := v.Handle(context.TODO(), req)
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusBadRequest), resp.Result.Code)
+	})
+
+	t.Run("namespace not found", func(t *testing.T) {
+		// given
+		v := NewHostValidator(newFakeClient(t, newConfig(hostPattern)))
+
+		// when
+		resp := v.Handle(context.TODO(), newRouteRequest(t, "app.johnsmith.apps.example.com"))
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusInternalServerError), resp.Result.Code)
+	})
+}
+
+// newFakeClient returns a new fake client with the given objects, once the MemberOperatorConfig among them (if any) is loaded,
+// as the MemberOperatorConfig controller does
+func newFakeClient(t *testing.T, initObjs ...runtime.Object) *test.FakeClient {
+	cl := test.NewFakeClient(t, initObjs...)
+	require.NoError(t, config.LoadMemberOperatorConfig(cl, namespaceName))
+	return cl
+}
+
+func newConfig(pattern string) *memberv1alpha1.MemberOperatorConfig {
+	return &memberv1alpha1.MemberOperatorConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: memberv1alpha1.MemberOperatorConfigName},
+		Spec:       memberv1alpha1.MemberOperatorConfigSpec{UserHostPattern: pattern},
+	}
+}
+
+func newUserNamespace() *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   username + "-dev",
+			Labels: map[string]string{"owner": username},
+		},
+	}
+}
+
+func newRouteRequest(t *testing.T, host string) admission.Request {
+	route := &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{Namespace: username + "-dev", Name: "app"},
+		Spec:       routev1.RouteSpec{Host: host},
+	}
+	return newRequest(t, "Route", route)
+}
+
+func newIngressRequest(t *testing.T, ruleHost, tlsHost string) admission.Request {
+	ingress := &extensionsv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: username + "-dev", Name: "app"},
+		Spec: extensionsv1beta1.IngressSpec{
+			Rules: []extensionsv1beta1.IngressRule{{Host: ruleHost}},
+			TLS:   []extensionsv1beta1.IngressTLS{{Hosts: []string{tlsHost}}},
+		},
+	}
+	return newRequest(t, "Ingress", ingress)
+}
+
+func newRequest(t *testing.T, kind string, obj runtime.Object) admission.Request {
+	raw, err := json.Marshal(obj)
+	require.NoError(t, err)
+	return admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: kind},
+			Namespace: username + "-dev",
+			Name:      "app",
+			Operation: admissionv1beta1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+}
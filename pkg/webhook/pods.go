@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// PodMutationPath the path on which the pod mutation webhook is served
+const PodMutationPath = "/mutate-pods"
+
+// PodMutator sets the ephemeral storage requests and limits of the containers of the pods in the user namespaces which do not
+// specify them, and caps the size of their emptyDir volumes, as configured in the MemberOperatorConfig. This prevents the pods
+// with an unbounded usage of their scratch space from causing disk-pressure evictions on the nodes.
+// The pods in the namespaces which are not owned by a user are left untouched.
+type PodMutator struct {
+	client client.Client
+}
+
+var _ admission.Handler = &PodMutator{}
+
+// NewPodMutator returns a new PodMutator
+func NewPodMutator(cl client.Client) *PodMutator {
+	return &PodMutator{
+		client: cl,
+	}
+}
+
+// ephemeralStorageLimits the parsed ephemeral storage limits of the MemberOperatorConfig. The limits which are not configured are nil.
+type ephemeralStorageLimits struct {
+	defaultRequest  *resource.Quantity
+	defaultLimit    *resource.Quantity
+	maxEmptyDirSize *resource.Quantity
+}
+
+// Handle sets the ephemeral storage requests and limits of the Pod in the given request
+func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Kind.Kind != "Pod" || len(req.Object.Raw) == 0 {
+		return admission.Allowed("")
+	}
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, errs.Wrap(err, "failed to decode the pod"))
+	}
+
+	if !config.PodMutationEnforced() {
+		return admission.Allowed("the pod mutation is disabled")
+	}
+	limits, err := parseEphemeralStorageLimits()
+	if err != nil {
+		log.Error(err, "unable to mutate the pod", "namespace", req.Namespace, "name", req.Name)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if limits.defaultRequest == nil && limits.defaultLimit == nil && limits.maxEmptyDirSize == nil {
+		return admission.Allowed("the ephemeral storage is not restricted")
+	}
+
+	ns := &corev1.Namespace{}
+	if err := m.client.Get(ctx, types.NamespacedName{Name: req.Namespace}, ns); err != nil {
+		log.Error(err, "unable to get the namespace", "namespace", req.Namespace)
+		return admission.Errored(http.StatusInternalServerError, errs.Wrapf(err, "failed to get namespace '%s'", req.Namespace))
+	}
+	if owner, ok := ns.Labels[ownerLabel]; !ok || owner == "" {
+		return admission.Allowed("not a user namespace")
+	}
+
+	if !limits.apply(pod) {
+		return admission.Allowed("")
+	}
+	mutated, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, errs.Wrap(err, "failed to encode the pod"))
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// parseEphemeralStorageLimits parses the ephemeral storage limits of the last loaded MemberOperatorConfig
+func parseEphemeralStorageLimits() (ephemeralStorageLimits, error) {
+	cfg := config.GetEphemeralStorage()
+	limits := ephemeralStorageLimits{}
+	for _, l := range []struct {
+		name   string
+		value  string
+		target **resource.Quantity
+	}{
+		{name: "default request", value: cfg.DefaultRequest, target: &limits.defaultRequest},
+		{name: "default limit", value: cfg.DefaultLimit, target: &limits.defaultLimit},
+		{name: "max emptyDir size", value: cfg.MaxEmptyDirSize, target: &limits.maxEmptyDirSize},
+	} {
+		if l.value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(l.value)
+		if err != nil {
+			return limits, errs.Wrapf(err, "invalid ephemeral storage %s '%s'", l.name, l.value)
+		}
+		*l.target = &quantity
+	}
+	return limits, nil
+}
+
+// apply sets the ephemeral storage requests and limits of the containers of the given pod, and caps the size of its emptyDir volumes.
+// Returns true if the pod was changed.
+func (l ephemeralStorageLimits) apply(pod *corev1.Pod) bool {
+	changed := false
+	for i := range pod.Spec.InitContainers {
+		changed = l.applyToContainer(&pod.Spec.InitContainers[i]) || changed
+	}
+	for i := range pod.Spec.Containers {
+		changed = l.applyToContainer(&pod.Spec.Containers[i]) || changed
+	}
+	if l.maxEmptyDirSize == nil {
+		return changed
+	}
+	for _, volume := range pod.Spec.Volumes {
+		// the size of the emptyDir volumes backed by memory counts towards the memory of the containers
+		if volume.EmptyDir == nil || volume.EmptyDir.Medium == corev1.StorageMediumMemory {
+			continue
+		}
+		if volume.EmptyDir.SizeLimit == nil || volume.EmptyDir.SizeLimit.Cmp(*l.maxEmptyDirSize) > 0 {
+			size := l.maxEmptyDirSize.DeepCopy()
+			volume.EmptyDir.SizeLimit = &size
+			changed = true
+		}
+	}
+	return changed
+}
+
+// applyToContainer sets the ephemeral storage request and limit of the given container if they are not specified. The default request
+// does not exceed the limit of the container, and the default limit is not lower than the request of the container.
+// Returns true if the container was changed.
+func (l ephemeralStorageLimits) applyToContainer(container *corev1.Container) bool {
+	changed := false
+	request, hasRequest := container.Resources.Requests[corev1.ResourceEphemeralStorage]
+	limit, hasLimit := container.Resources.Limits[corev1.ResourceEphemeralStorage]
+	if !hasRequest && l.defaultRequest != nil {
+		request = l.defaultRequest.DeepCopy()
+		if hasLimit && request.Cmp(limit) > 0 {
+			request = limit.DeepCopy()
+		}
+		if container.Resources.Requests == nil {
+			container.Resources.Requests = corev1.ResourceList{}
+		}
+		container.Resources.Requests[corev1.ResourceEphemeralStorage] = request
+		hasRequest = true
+		changed = true
+	}
+	if !hasLimit && l.defaultLimit != nil {
+		limit = l.defaultLimit.DeepCopy()
+		if hasRequest && request.Cmp(limit) > 0 {
+			limit = request.DeepCopy()
+		}
+		if container.Resources.Limits == nil {
+			container.Resources.Limits = corev1.ResourceList{}
+		}
+		container.Resources.Limits[corev1.ResourceEphemeralStorage] = limit
+		changed = true
+	}
+	return changed
+}
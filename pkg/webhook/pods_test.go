@@ -0,0 +1,258 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestPodMutatorHandle(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	storage := &memberv1alpha1.EphemeralStorageConfig{
+		DefaultRequest:  "100Mi",
+		DefaultLimit:    "1Gi",
+		MaxEmptyDirSize: "2Gi",
+	}
+
+	t.Run("pod mutated", func(t *testing.T) {
+		// given
+		m := NewPodMutator(newFakeClient(t, newEphemeralStorageConfig(storage), newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.NotEmpty(t, resp.Patches)
+	})
+
+	t.Run("pod already limited", func(t *testing.T) {
+		// given
+		m := NewPodMutator(newFakeClient(t, newEphemeralStorageConfig(storage), newUserNamespace()))
+		pod := newPod()
+		pod.Spec.Containers[0].Resources = newEphemeralStorageRequirements("100Mi", "1Gi")
+		size := resource.MustParse("1Gi")
+		pod.Spec.Volumes[0].EmptyDir.SizeLimit = &size
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", pod))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("not a user namespace", func(t *testing.T) {
+		// given
+		ns := newUserNamespace()
+		ns.Labels = nil
+		m := NewPodMutator(newFakeClient(t, newEphemeralStorageConfig(storage), ns))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("ephemeral storage not restricted", func(t *testing.T) {
+		// given
+		m := NewPodMutator(newFakeClient(t, newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("pod mutation disabled", func(t *testing.T) {
+		// given
+		disabled := false
+		cfg := newEphemeralStorageConfig(storage)
+		cfg.Spec.Webhooks = &memberv1alpha1.WebhooksConfig{PodMutation: &disabled}
+		m := NewPodMutator(newFakeClient(t, cfg, newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("other kind allowed", func(t *testing.T) {
+		// given
+		m := NewPodMutator(newFakeClient(t, newEphemeralStorageConfig(storage), newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRouteRequest(t, "app.johnsmith.apps.example.com"))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("invalid object", func(t *testing.T) {
+		// given
+		m := NewPodMutator(newFakeClient(t, newEphemeralStorageConfig(storage), newUserNamespace()))
+		req := newRequest(t, "Pod", newPod())
+		req.Object.Raw = []byte("{invalid")
+
+		// when
+		resp := m.Handle(context.TODO(), req)
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusBadRequest), resp.Result.Code)
+	})
+
+	t.Run("invalid quantity", func(t *testing.T) {
+		// given
+		invalid := &memberv1alpha1.EphemeralStorageConfig{DefaultLimit: "a lot"}
+		m := NewPodMutator(newFakeClient(t, newEphemeralStorageConfig(invalid), newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusInternalServerError), resp.Result.Code)
+	})
+}
+
+func TestApplyEphemeralStorageLimits(t *testing.T) {
+	defaultRequest := resource.MustParse("100Mi")
+	defaultLimit := resource.MustParse("1Gi")
+	maxEmptyDirSize := resource.MustParse("2Gi")
+	limits := ephemeralStorageLimits{
+		defaultRequest:  &defaultRequest,
+		defaultLimit:    &defaultLimit,
+		maxEmptyDirSize: &maxEmptyDirSize,
+	}
+
+	t.Run("defaults set", func(t *testing.T) {
+		// given
+		pod := newPod()
+
+		// when
+		changed := limits.apply(pod)
+
+		// then
+		assert.True(t, changed)
+		assertEphemeralStorage(t, "100Mi", "1Gi", pod.Spec.InitContainers[0].Resources)
+		assertEphemeralStorage(t, "100Mi", "1Gi", pod.Spec.Containers[0].Resources)
+		assert.Equal(t, "2Gi", pod.Spec.Volumes[0].EmptyDir.SizeLimit.String())
+		assert.Nil(t, pod.Spec.Volumes[1].EmptyDir.SizeLimit)
+	})
+
+	t.Run("default request capped at the limit", func(t *testing.T) {
+		// given
+		pod := newPod()
+		pod.Spec.Containers[0].Resources = newEphemeralStorageRequirements("", "50Mi")
+
+		// when
+		limits.apply(pod)
+
+		// then
+		assertEphemeralStorage(t, "50Mi", "50Mi", pod.Spec.Containers[0].Resources)
+	})
+
+	t.Run("default limit not lower than the request", func(t *testing.T) {
+		// given
+		pod := newPod()
+		pod.Spec.Containers[0].Resources = newEphemeralStorageRequirements("5Gi", "")
+
+		// when
+		limits.apply(pod)
+
+		// then
+		assertEphemeralStorage(t, "5Gi", "5Gi", pod.Spec.Containers[0].Resources)
+	})
+
+	t.Run("emptyDir size capped", func(t *testing.T) {
+		// given
+		pod := newPod()
+		size := resource.MustParse("10Gi")
+		pod.Spec.Volumes[0].EmptyDir.SizeLimit = &size
+
+		// when
+		limits.apply(pod)
+
+		// then
+		assert.Equal(t, "2Gi", pod.Spec.Volumes[0].EmptyDir.SizeLimit.String())
+	})
+
+	t.Run("smaller emptyDir size kept", func(t *testing.T) {
+		// given
+		pod := newPod()
+		pod.Spec.Containers[0].Resources = newEphemeralStorageRequirements("100Mi", "1Gi")
+		pod.Spec.InitContainers[0].Resources = newEphemeralStorageRequirements("100Mi", "1Gi")
+		size := resource.MustParse("500Mi")
+		pod.Spec.Volumes[0].EmptyDir.SizeLimit = &size
+
+		// when
+		changed := limits.apply(pod)
+
+		// then
+		assert.False(t, changed)
+		assert.Equal(t, "500Mi", pod.Spec.Volumes[0].EmptyDir.SizeLimit.String())
+	})
+}
+
+func assertEphemeralStorage(t *testing.T, expectedRequest, expectedLimit string, actual corev1.ResourceRequirements) {
+	request := actual.Requests[corev1.ResourceEphemeralStorage]
+	limit := actual.Limits[corev1.ResourceEphemeralStorage]
+	assert.Equal(t, expectedRequest, request.String())
+	assert.Equal(t, expectedLimit, limit.String())
+}
+
+func newEphemeralStorageConfig(storage *memberv1alpha1.EphemeralStorageConfig) *memberv1alpha1.MemberOperatorConfig {
+	cfg := newConfig("")
+	cfg.Spec.EphemeralStorage = storage
+	return cfg
+}
+
+func newEphemeralStorageRequirements(request, limit string) corev1.ResourceRequirements {
+	requirements := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{},
+		Limits:   corev1.ResourceList{},
+	}
+	if request != "" {
+		requirements.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse(request)
+	}
+	if limit != "" {
+		requirements.Limits[corev1.ResourceEphemeralStorage] = resource.MustParse(limit)
+	}
+	return requirements
+}
+
+// newPod returns a pod with an init container, a container, an emptyDir volume and an emptyDir volume backed by memory
+func newPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: username + "-dev", Name: "app"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+			Containers:     []corev1.Container{{Name: "app", Image: "busybox"}},
+			Volumes: []corev1.Volume{
+				{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}}},
+			},
+		},
+	}
+}
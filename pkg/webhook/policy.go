@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	errs "github.com/pkg/errors"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// PolicyEngine consults an external policy service (eg, an OPA server) about the admission requests, so that custom rules can be
+// enforced on the user namespaces without changing the operator.
+// The admission request is sent in the `input` field of a JSON document with a POST request on the URL of the policy engine, and
+// the decision is expected in the `result` field of the response, such as: `{"result": {"allowed": false, "reason": "..."}}`.
+type PolicyEngine struct {
+	url        string
+	httpClient *http.Client
+	failOpen   bool
+}
+
+// NewPolicyEngine returns a new PolicyEngine which posts the admission requests on the given URL. The given HTTP client defines the timeout
+// of the calls. When `failOpen` is true, the requests are allowed if the policy engine cannot be consulted. Otherwise they are denied.
+func NewPolicyEngine(url string, httpClient *http.Client, failOpen bool) *PolicyEngine {
+	return &PolicyEngine{
+		url:        url,
+		httpClient: httpClient,
+		failOpen:   failOpen,
+	}
+}
+
+// policyInput the document sent to the policy engine
+type policyInput struct {
+	Input admissionv1beta1.AdmissionRequest `json:"input"`
+}
+
+// policyOutput the document returned by the policy engine
+type policyOutput struct {
+	Result *PolicyDecision `json:"result,omitempty"`
+}
+
+// PolicyDecision the decision of the policy engine about an admission request
+type PolicyDecision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Evaluate returns the decision of the policy engine about the given request
+func (e *PolicyEngine) Evaluate(ctx context.Context, req admission.Request) (PolicyDecision, error) {
+	body, err := json.Marshal(policyInput{Input: req.AdmissionRequest})
+	if err != nil {
+		return PolicyDecision{}, errs.Wrap(err, "failed to encode the admission request")
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return PolicyDecision{}, errs.Wrapf(err, "invalid policy engine URL '%s'", e.url)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := e.httpClient.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return PolicyDecision{}, errs.Wrap(err, "failed to call the policy engine")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return PolicyDecision{}, fmt.Errorf("the policy engine returned an unexpected status: %d", resp.StatusCode)
+	}
+	output := policyOutput{}
+	if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+		return PolicyDecision{}, errs.Wrap(err, "failed to decode the response of the policy engine")
+	}
+	if output.Result == nil {
+		return PolicyDecision{}, fmt.Errorf("the policy engine returned no decision")
+	}
+	return *output.Result, nil
+}
+
+// PolicyHandler consults the PolicyEngine about the requests allowed by the wrapped handler, so that the external policies can only
+// restrict what the operator allows
+type PolicyHandler struct {
+	handler admission.Handler
+	engine  *PolicyEngine
+}
+
+var _ admission.Handler = &PolicyHandler{}
+
+// NewPolicyHandler returns a new PolicyHandler which wraps the given handler
+func NewPolicyHandler(handler admission.Handler, engine *PolicyEngine) *PolicyHandler {
+	return &PolicyHandler{
+		handler: handler,
+		engine:  engine,
+	}
+}
+
+// Handle handles the given request with the wrapped handler, then with the policy engine if the request was allowed
+func (h *PolicyHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	resp := h.handler.Handle(ctx, req)
+	if !resp.Allowed {
+		return resp
+	}
+	decision, err := h.engine.Evaluate(ctx, req)
+	if err != nil {
+		log.Error(err, "unable to consult the policy engine", "namespace", req.Namespace, "name", req.Name, "kind", req.Kind.Kind, "fail_open", h.engine.failOpen)
+		if h.engine.failOpen {
+			return resp
+		}
+		return admission.Denied(fmt.Sprintf("the policy engine could not be consulted: %s", err.Error()))
+	}
+	if !decision.Allowed {
+		log.Info("denied by the policy engine", "namespace", req.Namespace, "name", req.Name, "kind", req.Kind.Kind, "reason", decision.Reason)
+		if decision.Reason == "" {
+			return admission.Denied("denied by the policy engine")
+		}
+		return admission.Denied(decision.Reason)
+	}
+	return resp
+}
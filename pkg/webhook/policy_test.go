@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestPolicyHandler(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	allowAll := admission.HandlerFunc(func(ctx context.Context, req admission.Request) admission.Response {
+		return admission.Allowed("")
+	})
+
+	t.Run("allowed by the policy engine", func(t *testing.T) {
+		// given
+		var input map[string]interface{}
+		server := newPolicyServer(t, http.StatusOK, `{"result": {"allowed": true}}`, &input)
+		defer server.Close()
+		h := NewPolicyHandler(allowAll, NewPolicyEngine(server.URL, server.Client(), false))
+
+		// when
+		resp := h.Handle(context.TODO(), newRouteRequest(t, "app.johnsmith.apps.example.com"))
+
+		// then
+		assert.True(t, resp.Allowed)
+		require.Contains(t, input, "input")
+		assert.Equal(t, username+"-dev", input["input"].(map[string]interface{})["namespace"])
+	})
+
+	t.Run("denied by the policy engine", func(t *testing.T) {
+		// given
+		server := newPolicyServer(t, http.StatusOK, `{"result": {"allowed": false, "reason": "routes are not allowed in the sandbox"}}`, nil)
+		defer server.Close()
+		h := NewPolicyHandler(allowAll, NewPolicyEngine(server.URL, server.Client(), false))
+
+		// when
+		resp := h.Handle(context.TODO(), newRouteRequest(t, "app.johnsmith.apps.example.com"))
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, "routes are not allowed in the sandbox", string(resp.Result.Reason))
+	})
+
+	t.Run("denied by the wrapped handler", func(t *testing.T) {
+		// given
+		server := newPolicyServer(t, http.StatusOK, `{"result": {"allowed": true}}`, nil)
+		defer server.Close()
+		denyAll := admission.HandlerFunc(func(ctx context.Context, req admission.Request) admission.Response {
+			return admission.Denied("denied")
+		})
+		h := NewPolicyHandler(denyAll, NewPolicyEngine(server.URL, server.Client(), false))
+
+		// when
+		resp := h.Handle(context.TODO(), newRouteRequest(t, "app.johnsmith.apps.example.com"))
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, "denied", string(resp.Result.Reason))
+	})
+
+	t.Run("policy engine unavailable", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			status int
+			body   string
+		}{
+			{name: "server error", status: http.StatusInternalServerError, body: `{}`},
+			{name: "no decision", status: http.StatusOK, body: `{}`},
+			{name: "invalid response", status: http.StatusOK, body: `{invalid`},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				server := newPolicyServer(t, tc.status, tc.body, nil)
+				defer server.Close()
+
+				t.Run("fail closed", func(t *testing.T) {
+					// given
+					h := NewPolicyHandler(allowAll, NewPolicyEngine(server.URL, server.Client(), false))
+
+					// when
+					resp := h.Handle(context.TODO(), newRouteRequest(t, "app.johnsmith.apps.example.com"))
+
+					// then
+					assert.False(t, resp.Allowed)
+					assert.Contains(t, string(resp.Result.Reason), "the policy engine could not be consulted")
+				})
+
+				t.Run("fail open", func(t *testing.T) {
+					// given
+					h := NewPolicyHandler(allowAll, NewPolicyEngine(server.URL, server.Client(), true))
+
+					// when
+					resp := h.Handle(context.TODO(), newRouteRequest(t, "app.johnsmith.apps.example.com"))
+
+					// then
+					assert.True(t, resp.Allowed)
+				})
+			})
+		}
+	})
+
+	t.Run("policy engine timeout", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		h := NewPolicyHandler(allowAll, NewPolicyEngine(server.URL, &http.Client{Timeout: 50 * time.Millisecond}, false))
+
+		// when
+		resp := h.Handle(context.TODO(), newRouteRequest(t, "app.johnsmith.apps.example.com"))
+
+		// then
+		assert.False(t, resp.Allowed)
+	})
+}
+
+// newPolicyServer returns a policy engine which responds with the given status and body, and decodes the received document
+// in the given map (if not nil)
+func newPolicyServer(t *testing.T, status int, body string, input *map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		if input != nil {
+			err := json.NewDecoder(r.Body).Decode(input)
+			assert.NoError(t, err)
+		}
+		w.WriteHeader(status)
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+}
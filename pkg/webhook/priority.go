@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// PodPriorityPath the path on which the pod priority webhook is served
+	PodPriorityPath = "/mutate-pods-priority"
+	// UserPodsPriorityClassName the name of the PriorityClass of the pods in the user namespaces
+	UserPodsPriorityClassName = "sandbox-users-pods"
+	// UserPodsPriority the priority of the pods in the user namespaces, lower than the default priority (`0`) of the pods
+	// which have no priority class, so that they are evicted first when the nodes are under pressure
+	UserPodsPriority = int32(-3)
+)
+
+// PodPriorityMutator sets the (low) priority class of the pods in the user namespaces, so that the sandbox workloads are preempted
+// and evicted before the platform components when the nodes are under pressure. Any priority class specified by the pods is replaced.
+// The pods in the namespaces which are not owned by a user are left untouched.
+type PodPriorityMutator struct {
+	client client.Client
+}
+
+var _ admission.Handler = &PodPriorityMutator{}
+
+// NewPodPriorityMutator returns a new PodPriorityMutator
+func NewPodPriorityMutator(cl client.Client) *PodPriorityMutator {
+	return &PodPriorityMutator{
+		client: cl,
+	}
+}
+
+// Handle sets the priority class of the Pod in the given request
+func (m *PodPriorityMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Kind.Kind != "Pod" || len(req.Object.Raw) == 0 {
+		return admission.Allowed("")
+	}
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, errs.Wrap(err, "failed to decode the pod"))
+	}
+
+	if !config.PodPriorityEnforced() {
+		return admission.Allowed("the pod priority is disabled")
+	}
+
+	ns := &corev1.Namespace{}
+	if err := m.client.Get(ctx, types.NamespacedName{Name: req.Namespace}, ns); err != nil {
+		log.Error(err, "unable to get the namespace", "namespace", req.Namespace)
+		return admission.Errored(http.StatusInternalServerError, errs.Wrapf(err, "failed to get namespace '%s'", req.Namespace))
+	}
+	if owner, ok := ns.Labels[ownerLabel]; !ok || owner == "" {
+		return admission.Allowed("not a user namespace")
+	}
+
+	if !setUserPodsPriority(pod) {
+		return admission.Allowed("")
+	}
+	mutated, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, errs.Wrap(err, "failed to encode the pod"))
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// setUserPodsPriority sets the priority class of the given pod, along with the matching priority since the latter is resolved
+// by the API server before the webhooks are called. Returns true if the pod was changed.
+func setUserPodsPriority(pod *corev1.Pod) bool {
+	if pod.Spec.PriorityClassName == UserPodsPriorityClassName && pod.Spec.Priority != nil && *pod.Spec.Priority == UserPodsPriority {
+		return false
+	}
+	priority := UserPodsPriority
+	pod.Spec.PriorityClassName = UserPodsPriorityClassName
+	pod.Spec.Priority = &priority
+	return true
+}
+
+// CreatePriorityClassIfNotExists creates the PriorityClass of the pods in the user namespaces if it does not exist yet
+func CreatePriorityClassIfNotExists(cl client.Client) error {
+	priorityClass := &schedulingv1.PriorityClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   UserPodsPriorityClassName,
+			Labels: map[string]string{"provider": "codeready-toolchain"},
+		},
+		Value:       UserPodsPriority,
+		Description: "Priority class of the pods in the user namespaces, which are evicted before the platform components",
+	}
+	if err := cl.Create(context.TODO(), priorityClass); err != nil && !errors.IsAlreadyExists(err) {
+		return errs.Wrapf(err, "failed to create the '%s' PriorityClass", UserPodsPriorityClassName)
+	}
+	return nil
+}
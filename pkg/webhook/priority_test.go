@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestPodPriorityMutatorHandle(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	t.Run("priority set", func(t *testing.T) {
+		// given
+		m := NewPodPriorityMutator(newFakeClient(t, newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.NotEmpty(t, resp.Patches)
+	})
+
+	t.Run("priority already set", func(t *testing.T) {
+		// given
+		m := NewPodPriorityMutator(newFakeClient(t, newUserNamespace()))
+		pod := newPod()
+		setUserPodsPriority(pod)
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", pod))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("not a user namespace", func(t *testing.T) {
+		// given
+		ns := newUserNamespace()
+		ns.Labels = nil
+		m := NewPodPriorityMutator(newFakeClient(t, ns))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("pod priority disabled", func(t *testing.T) {
+		// given
+		disabled := false
+		cfg := newConfig("")
+		cfg.Spec.Webhooks = &memberv1alpha1.WebhooksConfig{PodPriority: &disabled}
+		m := NewPodPriorityMutator(newFakeClient(t, cfg, newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("invalid object", func(t *testing.T) {
+		// given
+		m := NewPodPriorityMutator(newFakeClient(t, newUserNamespace()))
+		req := newRequest(t, "Pod", newPod())
+		req.Object.Raw = []byte("{invalid")
+
+		// when
+		resp := m.Handle(context.TODO(), req)
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusBadRequest), resp.Result.Code)
+	})
+
+	t.Run("namespace not found", func(t *testing.T) {
+		// given
+		m := NewPodPriorityMutator(newFakeClient(t))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusInternalServerError), resp.Result.Code)
+	})
+}
+
+func TestSetUserPodsPriority(t *testing.T) {
+
+	t.Run("priority class replaced", func(t *testing.T) {
+		// given
+		pod := newPod()
+		priority := int32(1000)
+		pod.Spec.PriorityClassName = "high-priority"
+		pod.Spec.Priority = &priority
+
+		// when
+		changed := setUserPodsPriority(pod)
+
+		// then
+		assert.True(t, changed)
+		assert.Equal(t, UserPodsPriorityClassName, pod.Spec.PriorityClassName)
+		require.NotNil(t, pod.Spec.Priority)
+		assert.Equal(t, UserPodsPriority, *pod.Spec.Priority)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		// given
+		pod := newPod()
+		setUserPodsPriority(pod)
+
+		// when
+		changed := setUserPodsPriority(pod)
+
+		// then
+		assert.False(t, changed)
+	})
+}
+
+func TestCreatePriorityClassIfNotExists(t *testing.T) {
+
+	t.Run("created", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		err := CreatePriorityClassIfNotExists(cl)
+
+		// then
+		require.NoError(t, err)
+		priorityClass := &schedulingv1.PriorityClass{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: UserPodsPriorityClassName}, priorityClass)
+		require.NoError(t, err)
+		assert.Equal(t, UserPodsPriority, priorityClass.Value)
+		assert.False(t, priorityClass.GlobalDefault)
+	})
+
+	t.Run("already exists", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, &schedulingv1.PriorityClass{
+			ObjectMeta: metav1.ObjectMeta{Name: UserPodsPriorityClassName},
+			Value:      UserPodsPriority,
+		})
+
+		// when
+		err := CreatePriorityClassIfNotExists(cl)
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("create failed", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("mock error")
+		}
+
+		// when
+		err := CreatePriorityClassIfNotExists(cl)
+
+		// then
+		require.EqualError(t, err, "failed to create the 'sandbox-users-pods' PriorityClass: mock error")
+	})
+}
@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// PodProxyPath the path on which the pod proxy webhook is served
+const PodProxyPath = "/mutate-pods-proxy"
+
+const (
+	// trustedCABundleVolume the name of the volume of the trusted CA bundle added to the pods
+	trustedCABundleVolume = "trusted-ca-bundle"
+	// trustedCABundleMountPath the directory in which the trusted CA bundle is mounted in the containers, ie, the location
+	// of the extracted CA bundle of the RHEL-based images
+	trustedCABundleMountPath = "/etc/pki/ca-trust/extracted/pem"
+	// trustedCABundleFile the name of the trusted CA bundle file in its mount path
+	trustedCABundleFile = "tls-ca-bundle.pem"
+)
+
+// PodProxyMutator sets the proxy settings of the cluster specified in the MemberOperatorConfig (ie, the `HTTP_PROXY`, `HTTPS_PROXY`
+// and `NO_PROXY` env vars, along with their lowercase variants) on the containers of the pods in the user namespaces, and mounts
+// the trusted CA bundle copied in the namespace, so that the sandbox builds work behind a corporate proxy. The env vars which
+// are already set by the containers are kept. The pods in the namespaces which are not owned by a user are left untouched.
+type PodProxyMutator struct {
+	client client.Client
+}
+
+var _ admission.Handler = &PodProxyMutator{}
+
+// NewPodProxyMutator returns a new PodProxyMutator
+func NewPodProxyMutator(cl client.Client) *PodProxyMutator {
+	return &PodProxyMutator{
+		client: cl,
+	}
+}
+
+// Handle sets the proxy settings and the trusted CA bundle of the Pod in the given request
+func (m *PodProxyMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Kind.Kind != "Pod" || len(req.Object.Raw) == 0 {
+		return admission.Allowed("")
+	}
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, errs.Wrap(err, "failed to decode the pod"))
+	}
+
+	if !config.PodProxyEnforced() {
+		return admission.Allowed("the pod proxy is disabled")
+	}
+	proxy := config.GetProxy()
+	if proxy == (memberv1alpha1.ProxyConfig{}) {
+		return admission.Allowed("no proxy")
+	}
+
+	ns := &corev1.Namespace{}
+	if err := m.client.Get(ctx, types.NamespacedName{Name: req.Namespace}, ns); err != nil {
+		log.Error(err, "unable to get the namespace", "namespace", req.Namespace)
+		return admission.Errored(http.StatusInternalServerError, errs.Wrapf(err, "failed to get namespace '%s'", req.Namespace))
+	}
+	if owner, ok := ns.Labels[ownerLabel]; !ok || owner == "" {
+		return admission.Allowed("not a user namespace")
+	}
+
+	if !setUserPodsProxy(pod, proxy) {
+		return admission.Allowed("")
+	}
+	mutated, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, errs.Wrap(err, "failed to encode the pod"))
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// setUserPodsProxy sets the proxy env vars on the containers of the given pod, and mounts the trusted CA bundle if there is one.
+// Returns true if the pod was changed.
+func setUserPodsProxy(pod *corev1.Pod, proxy memberv1alpha1.ProxyConfig) bool {
+	var env []corev1.EnvVar
+	for _, e := range []struct {
+		name  string
+		value string
+	}{
+		{name: "HTTP_PROXY", value: proxy.HTTPProxy},
+		{name: "HTTPS_PROXY", value: proxy.HTTPSProxy},
+		{name: "NO_PROXY", value: proxy.NoProxy},
+	} {
+		if e.value == "" {
+			continue
+		}
+		// most tools only read the lowercase variants
+		env = append(env, corev1.EnvVar{Name: e.name, Value: e.value}, corev1.EnvVar{Name: strings.ToLower(e.name), Value: e.value})
+	}
+	withCABundle := proxy.TrustedCABundle != ""
+
+	changed := false
+	for i := range pod.Spec.InitContainers {
+		changed = setContainerProxy(&pod.Spec.InitContainers[i], env, withCABundle) || changed
+	}
+	for i := range pod.Spec.Containers {
+		changed = setContainerProxy(&pod.Spec.Containers[i], env, withCABundle) || changed
+	}
+	if changed && withCABundle && !hasVolume(pod, trustedCABundleVolume) {
+		optional := true
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: trustedCABundleVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: config.TrustedCABundleConfigMapName},
+					Items:                []corev1.KeyToPath{{Key: config.TrustedCABundleKey, Path: trustedCABundleFile}},
+					// the pods are not blocked if the bundle was not copied in the namespace yet
+					Optional: &optional,
+				},
+			},
+		})
+	}
+	return changed
+}
+
+// setContainerProxy adds the given env vars which are not set yet to the given container, and mounts the trusted CA bundle in it
+// unless its mount path is already used. Returns true if the container was changed.
+func setContainerProxy(container *corev1.Container, env []corev1.EnvVar, withCABundle bool) bool {
+	changed := false
+	for _, e := range env {
+		if hasEnvVar(container, e.Name) {
+			continue
+		}
+		container.Env = append(container.Env, e)
+		changed = true
+	}
+	if withCABundle && !hasMountPath(container, trustedCABundleMountPath) {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      trustedCABundleVolume,
+			MountPath: trustedCABundleMountPath,
+			ReadOnly:  true,
+		})
+		changed = true
+	}
+	return changed
+}
+
+// hasEnvVar returns true if the given container already has an env var with the given name
+func hasEnvVar(container *corev1.Container, name string) bool {
+	for _, e := range container.Env {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMountPath returns true if the given container already has a volume mounted on the given path
+func hasMountPath(container *corev1.Container, path string) bool {
+	for _, m := range container.VolumeMounts {
+		if m.MountPath == path {
+			return true
+		}
+	}
+	return false
+}
+
+// hasVolume returns true if the given pod already has a volume with the given name
+func hasVolume(pod *corev1.Pod, name string) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
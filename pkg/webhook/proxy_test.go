@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestPodProxyMutatorHandle(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	t.Run("proxy settings set", func(t *testing.T) {
+		// given
+		m := NewPodProxyMutator(newFakeClient(t, newProxyConfig(), newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.NotEmpty(t, resp.Patches)
+	})
+
+	t.Run("already set", func(t *testing.T) {
+		// given
+		cfg := newProxyConfig()
+		m := NewPodProxyMutator(newFakeClient(t, cfg, newUserNamespace()))
+		pod := newPod()
+		setUserPodsProxy(pod, *cfg.Spec.Proxy)
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", pod))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("nothing to set without config", func(t *testing.T) {
+		// given
+		m := NewPodProxyMutator(newFakeClient(t, newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("not a user namespace", func(t *testing.T) {
+		// given
+		ns := newUserNamespace()
+		ns.Labels = nil
+		m := NewPodProxyMutator(newFakeClient(t, newProxyConfig(), ns))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("pod proxy disabled", func(t *testing.T) {
+		// given
+		disabled := false
+		cfg := newProxyConfig()
+		cfg.Spec.Webhooks = &memberv1alpha1.WebhooksConfig{PodProxy: &disabled}
+		m := NewPodProxyMutator(newFakeClient(t, cfg, newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+}
+
+func TestSetUserPodsProxy(t *testing.T) {
+	proxy := memberv1alpha1.ProxyConfig{
+		HTTPSProxy:      "http://proxy.example.com:3128",
+		NoProxy:         ".cluster.local,.svc",
+		TrustedCABundle: "proxy-ca",
+	}
+
+	t.Run("env vars and CA bundle set", func(t *testing.T) {
+		// given
+		pod := newPod()
+		pod.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "NO_PROXY", Value: "localhost"}}
+
+		// when
+		changed := setUserPodsProxy(pod, proxy)
+
+		// then
+		assert.True(t, changed)
+		assert.Equal(t, []corev1.EnvVar{
+			{Name: "HTTPS_PROXY", Value: "http://proxy.example.com:3128"},
+			{Name: "https_proxy", Value: "http://proxy.example.com:3128"},
+			{Name: "NO_PROXY", Value: ".cluster.local,.svc"},
+			{Name: "no_proxy", Value: ".cluster.local,.svc"},
+		}, pod.Spec.InitContainers[0].Env)
+		// the env var set by the user is kept
+		assert.Equal(t, []corev1.EnvVar{
+			{Name: "NO_PROXY", Value: "localhost"},
+			{Name: "HTTPS_PROXY", Value: "http://proxy.example.com:3128"},
+			{Name: "https_proxy", Value: "http://proxy.example.com:3128"},
+			{Name: "no_proxy", Value: ".cluster.local,.svc"},
+		}, pod.Spec.Containers[0].Env)
+		for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			assert.Equal(t, []corev1.VolumeMount{{Name: "trusted-ca-bundle", MountPath: "/etc/pki/ca-trust/extracted/pem", ReadOnly: true}}, container.VolumeMounts)
+		}
+		require.Len(t, pod.Spec.Volumes, 3)
+		volume := pod.Spec.Volumes[2]
+		assert.Equal(t, "trusted-ca-bundle", volume.Name)
+		require.NotNil(t, volume.ConfigMap)
+		assert.Equal(t, "trusted-ca-bundle", volume.ConfigMap.Name)
+		assert.Equal(t, []corev1.KeyToPath{{Key: "ca-bundle.crt", Path: "tls-ca-bundle.pem"}}, volume.ConfigMap.Items)
+		assert.True(t, *volume.ConfigMap.Optional)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		// given
+		pod := newPod()
+		setUserPodsProxy(pod, proxy)
+
+		// when
+		changed := setUserPodsProxy(pod, proxy)
+
+		// then
+		assert.False(t, changed)
+		assert.Len(t, pod.Spec.Volumes, 3)
+		assert.Len(t, pod.Spec.Containers[0].Env, 4)
+	})
+
+	t.Run("CA bundle path already mounted", func(t *testing.T) {
+		// given
+		pod := newPod()
+		for i := range pod.Spec.InitContainers {
+			pod.Spec.InitContainers[i].VolumeMounts = []corev1.VolumeMount{{Name: "scratch", MountPath: "/etc/pki/ca-trust/extracted/pem"}}
+		}
+		for i := range pod.Spec.Containers {
+			pod.Spec.Containers[i].VolumeMounts = []corev1.VolumeMount{{Name: "scratch", MountPath: "/etc/pki/ca-trust/extracted/pem"}}
+		}
+
+		// when
+		changed := setUserPodsProxy(pod, memberv1alpha1.ProxyConfig{TrustedCABundle: "proxy-ca"})
+
+		// then
+		assert.False(t, changed)
+		assert.Len(t, pod.Spec.Volumes, 2)
+	})
+
+	t.Run("nothing to set", func(t *testing.T) {
+		// given
+		pod := newPod()
+
+		// when
+		changed := setUserPodsProxy(pod, memberv1alpha1.ProxyConfig{})
+
+		// then
+		assert.False(t, changed)
+		assert.Empty(t, pod.Spec.Containers[0].Env)
+		assert.Len(t, pod.Spec.Volumes, 2)
+	})
+}
+
+func newProxyConfig() *memberv1alpha1.MemberOperatorConfig {
+	cfg := newConfig("")
+	cfg.Spec.Proxy = &memberv1alpha1.ProxyConfig{
+		HTTPProxy:  "http://proxy.example.com:3128",
+		HTTPSProxy: "http://proxy.example.com:3128",
+		NoProxy:    ".cluster.local,.svc",
+	}
+	return cfg
+}
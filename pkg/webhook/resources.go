@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ResourceValidationPath the path on which the resource validation webhook is served
+const ResourceValidationPath = "/validate-resources"
+
+// The annotations of the default StorageClass of the cluster, and the (deprecated) annotation of the StorageClass of a PersistentVolumeClaim
+const (
+	defaultStorageClassAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+	betaStorageClassAnnotation        = "volume.beta.kubernetes.io/storage-class"
+)
+
+// The privileges which can be forbidden to the pods of the user namespaces
+const (
+	privilegedPrivilege  = "privileged"
+	hostNetworkPrivilege = "hostNetwork"
+	hostPIDPrivilege     = "hostPID"
+	hostIPCPrivilege     = "hostIPC"
+	hostPathPrivilege    = "hostPath"
+	hostPortPrivilege    = "hostPort"
+)
+
+// ResourceValidator denies the resources of the user namespaces which are forbidden by the MemberOperatorConfig: the RoleBindings
+// of some ClusterRoles, the Services of some types, the Pods requesting some privileges and the PersistentVolumeClaims of the StorageClasses
+// which are not allowed.
+// The RoleBindings and Services requested by the platform (ie, by the `system:` users other than the service accounts of the namespace),
+// such as the default RoleBindings of the namespaces or the objects of the templates applied by the operator, are allowed. The Pods and the
+// PersistentVolumeClaims are validated whoever requested them, since they are usually created by the controllers on behalf of the users.
+// The objects in the namespaces which are not owned by a user are always allowed.
+type ResourceValidator struct {
+	client client.Client
+}
+
+var _ admission.Handler = &ResourceValidator{}
+
+// NewResourceValidator returns a new ResourceValidator
+func NewResourceValidator(cl client.Client) *ResourceValidator {
+	return &ResourceValidator{
+		client: cl,
+	}
+}
+
+// Handle denies the RoleBinding, Service, Pod or PersistentVolumeClaim in the given request if it is forbidden
+func (v *ResourceValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if len(req.Object.Raw) == 0 {
+		return admission.Allowed("")
+	}
+	switch req.Kind.Kind {
+	case "RoleBinding", "Service":
+		if platformRequest(req) {
+			return admission.Allowed("requested by the platform")
+		}
+	case "Pod", "PersistentVolumeClaim":
+		// the pods and the claims are validated whoever requested them
+	default:
+		return admission.Allowed("")
+	}
+
+	if !config.ResourceValidationEnforced() {
+		return admission.Allowed("the resource validation is disabled")
+	}
+
+	ns := &corev1.Namespace{}
+	if err := v.client.Get(ctx, types.NamespacedName{Name: req.Namespace}, ns); err != nil {
+		log.Error(err, "unable to get the namespace", "namespace", req.Namespace)
+		return admission.Errored(http.StatusInternalServerError, errs.Wrapf(err, "failed to get namespace '%s'", req.Namespace))
+	}
+	if owner, ok := ns.Labels[ownerLabel]; !ok || owner == "" {
+		return admission.Allowed("not a user namespace")
+	}
+
+	reason, err := forbiddenReason(config.GetForbiddenResources(), req)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if req.Kind.Kind == "PersistentVolumeClaim" {
+		if reason, err = v.storageClassReason(ctx, config.GetPersistentStorage().AllowedStorageClasses, req); err != nil {
+			log.Error(err, "unable to validate the storage class", "namespace", req.Namespace, "name", req.Name)
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+	if reason != "" {
+		log.Info("denying resource", "namespace", req.Namespace, "name", req.Name, "kind", req.Kind.Kind, "reason", reason)
+		return admission.Denied(reason)
+	}
+	return admission.Allowed("")
+}
+
+// platformRequest returns true if the given request was made by a `system:` user other than the service accounts of its namespace
+func platformRequest(req admission.Request) bool {
+	username := req.UserInfo.Username
+	return strings.HasPrefix(username, "system:") && !strings.HasPrefix(username, fmt.Sprintf("system:serviceaccount:%s:", req.Namespace))
+}
+
+// forbiddenReason returns the reason why the object of the given request is forbidden, or an empty string if it is allowed
+func forbiddenReason(forbidden memberv1alpha1.ForbiddenResourcesConfig, req admission.Request) (string, error) {
+	switch req.Kind.Kind {
+	case "RoleBinding":
+		roleBinding := &rbacv1.RoleBinding{}
+		if err := json.Unmarshal(req.Object.Raw, roleBinding); err != nil {
+			return "", errs.Wrap(err, "failed to decode the role binding")
+		}
+		if roleBinding.RoleRef.Kind != "ClusterRole" {
+			return "", nil
+		}
+		for _, pattern := range forbidden.ClusterRoles {
+			// an invalid pattern never matches
+			if matched, _ := path.Match(pattern, roleBinding.RoleRef.Name); matched {
+				return fmt.Sprintf("binding the '%s' ClusterRole is forbidden in the user namespaces", roleBinding.RoleRef.Name), nil
+			}
+		}
+	case "Service":
+		service := &corev1.Service{}
+		if err := json.Unmarshal(req.Object.Raw, service); err != nil {
+			return "", errs.Wrap(err, "failed to decode the service")
+		}
+		for _, serviceType := range forbidden.ServiceTypes {
+			if string(service.Spec.Type) == serviceType {
+				return fmt.Sprintf("services of type '%s' are forbidden in the user namespaces", serviceType), nil
+			}
+		}
+	case "Pod":
+		pod := &corev1.Pod{}
+		if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+			return "", errs.Wrap(err, "failed to decode the pod")
+		}
+		requested := requestedPrivileges(pod)
+		for _, privilege := range forbidden.PodPrivileges {
+			if requested[privilege] {
+				return fmt.Sprintf("pods requesting the '%s' privilege are forbidden in the user namespaces", privilege), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// requestedPrivileges returns the privileges requested by the given pod
+func requestedPrivileges(pod *corev1.Pod) map[string]bool {
+	privileges := map[string]bool{
+		hostNetworkPrivilege: pod.Spec.HostNetwork,
+		hostPIDPrivilege:     pod.Spec.HostPID,
+		hostIPCPrivilege:     pod.Spec.HostIPC,
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.HostPath != nil {
+			privileges[hostPathPrivilege] = true
+		}
+	}
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, container := range containers {
+		if container.SecurityContext != nil && container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged {
+			privileges[privilegedPrivilege] = true
+		}
+		for _, port := range container.Ports {
+			if port.HostPort != 0 {
+				privileges[hostPortPrivilege] = true
+			}
+		}
+	}
+	return privileges
+}
+
+// storageClassReason returns the reason why the PersistentVolumeClaim of the given request is forbidden, ie, why its StorageClass
+// (or the default StorageClass if it does not specify one) is not among the given allowed StorageClasses. All the StorageClasses
+// are allowed if the given list is empty
+func (v *ResourceValidator) storageClassReason(ctx context.Context, allowed []string, req admission.Request) (string, error) {
+	if len(allowed) == 0 {
+		return "", nil
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := json.Unmarshal(req.Object.Raw, pvc); err != nil {
+		return "", errs.Wrap(err, "failed to decode the persistent volume claim")
+	}
+	var storageClass string
+	if className, found := pvc.Annotations[betaStorageClassAnnotation]; found {
+		storageClass = className
+	} else if pvc.Spec.StorageClassName != nil {
+		storageClass = *pvc.Spec.StorageClassName
+	} else {
+		defaultClass, err := v.defaultStorageClass(ctx)
+		if err != nil {
+			return "", err
+		}
+		if defaultClass == "" {
+			// nothing will be provisioned for the claim
+			return "", nil
+		}
+		storageClass = defaultClass
+	}
+	for _, className := range allowed {
+		if storageClass == className {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf("persistent volume claims of the '%s' storage class are forbidden in the user namespaces (allowed: %s)",
+		storageClass, strings.Join(allowed, ", ")), nil
+}
+
+// defaultStorageClass returns the name of the default StorageClass of the cluster, or an empty string if there is none
+func (v *ResourceValidator) defaultStorageClass(ctx context.Context) (string, error) {
+	classes := &storagev1.StorageClassList{}
+	if err := v.client.List(ctx, classes); err != nil {
+		return "", errs.Wrap(err, "failed to list the storage classes")
+	}
+	for _, class := range classes.Items {
+		if class.Annotations[defaultStorageClassAnnotation] == "true" || class.Annotations[betaDefaultStorageClassAnnotation] == "true" {
+			return class.Name, nil
+		}
+	}
+	return "", nil
+}
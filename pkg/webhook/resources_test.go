@@ -0,0 +1,336 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestResourceValidatorHandle(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	forbidden := &memberv1alpha1.ForbiddenResourcesConfig{
+		ClusterRoles:  []string{"cluster-admin", "system:*"},
+		ServiceTypes:  []string{"NodePort"},
+		PodPrivileges: []string{"privileged", "hostPath"},
+	}
+
+	t.Run("role binding", func(t *testing.T) {
+
+		t.Run("forbidden cluster role denied", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "RoleBinding", newRoleBinding("ClusterRole", "system:image-builder")))
+
+			// then
+			assert.False(t, resp.Allowed)
+			assert.Equal(t, "binding the 'system:image-builder' ClusterRole is forbidden in the user namespaces", string(resp.Result.Reason))
+		})
+
+		t.Run("other cluster role allowed", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "RoleBinding", newRoleBinding("ClusterRole", "view")))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+
+		t.Run("role with forbidden name allowed", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "RoleBinding", newRoleBinding("Role", "cluster-admin")))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+
+		t.Run("requested by the platform", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+			req := newRequest(t, "RoleBinding", newRoleBinding("ClusterRole", "system:image-builder"))
+			req.UserInfo.Username = "system:serviceaccount:openshift-infra:default-rolebindings-controller"
+
+			// when
+			resp := v.Handle(context.TODO(), req)
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+
+		t.Run("requested by a service account of the namespace", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+			req := newRequest(t, "RoleBinding", newRoleBinding("ClusterRole", "cluster-admin"))
+			req.UserInfo.Username = "system:serviceaccount:" + username + "-dev:pipeline"
+
+			// when
+			resp := v.Handle(context.TODO(), req)
+
+			// then
+			assert.False(t, resp.Allowed)
+		})
+	})
+
+	t.Run("service", func(t *testing.T) {
+
+		t.Run("forbidden type denied", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "Service", newService(corev1.ServiceTypeNodePort)))
+
+			// then
+			assert.False(t, resp.Allowed)
+			assert.Equal(t, "services of type 'NodePort' are forbidden in the user namespaces", string(resp.Result.Reason))
+		})
+
+		t.Run("other type allowed", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "Service", newService(corev1.ServiceTypeClusterIP)))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+	})
+
+	t.Run("pod", func(t *testing.T) {
+
+		t.Run("privileged container denied", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+			pod := newPod()
+			privileged := true
+			pod.Spec.InitContainers[0].SecurityContext = &corev1.SecurityContext{Privileged: &privileged}
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "Pod", pod))
+
+			// then
+			assert.False(t, resp.Allowed)
+			assert.Equal(t, "pods requesting the 'privileged' privilege are forbidden in the user namespaces", string(resp.Result.Reason))
+		})
+
+		t.Run("hostPath volume denied even when requested by the platform", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+			pod := newPod()
+			pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+				Name:         "host",
+				VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/run"}},
+			})
+			req := newRequest(t, "Pod", pod)
+			req.UserInfo.Username = "system:serviceaccount:kube-system:replicaset-controller"
+
+			// when
+			resp := v.Handle(context.TODO(), req)
+
+			// then
+			assert.False(t, resp.Allowed)
+		})
+
+		t.Run("privilege not forbidden allowed", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+			pod := newPod()
+			pod.Spec.HostNetwork = true
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "Pod", pod))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+	})
+
+	t.Run("persistent volume claim", func(t *testing.T) {
+		// given
+		cfg := newForbiddenResourcesConfig(forbidden)
+		cfg.Spec.PersistentStorage = &memberv1alpha1.PersistentStorageConfig{AllowedStorageClasses: []string{"gp2", "standard"}}
+		defaultClass := &storagev1.StorageClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "io1", Annotations: map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}},
+		}
+
+		t.Run("allowed storage class", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, cfg, newUserNamespace(), defaultClass))
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "PersistentVolumeClaim", newPVC("standard")))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+
+		t.Run("other storage class denied", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, cfg, newUserNamespace(), defaultClass))
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "PersistentVolumeClaim", newPVC("io1")))
+
+			// then
+			assert.False(t, resp.Allowed)
+			assert.Equal(t, "persistent volume claims of the 'io1' storage class are forbidden in the user namespaces (allowed: gp2, standard)", string(resp.Result.Reason))
+		})
+
+		t.Run("default storage class denied", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, cfg, newUserNamespace(), defaultClass))
+			req := newRequest(t, "PersistentVolumeClaim", newPVC(""))
+			req.UserInfo.Username = "system:serviceaccount:kube-system:statefulset-controller"
+
+			// when
+			resp := v.Handle(context.TODO(), req)
+
+			// then
+			assert.False(t, resp.Allowed)
+		})
+
+		t.Run("default storage class allowed", func(t *testing.T) {
+			// given
+			defaultClass := defaultClass.DeepCopy()
+			defaultClass.Name = "gp2"
+			v := NewResourceValidator(newFakeClient(t, cfg, newUserNamespace(), defaultClass))
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "PersistentVolumeClaim", newPVC("")))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+
+		t.Run("all storage classes allowed by default", func(t *testing.T) {
+			// given
+			v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace(), defaultClass))
+
+			// when
+			resp := v.Handle(context.TODO(), newRequest(t, "PersistentVolumeClaim", newPVC("io1")))
+
+			// then
+			assert.True(t, resp.Allowed)
+		})
+	})
+
+	t.Run("nothing forbidden", func(t *testing.T) {
+		// given
+		v := NewResourceValidator(newFakeClient(t, newUserNamespace()))
+
+		// when
+		resp := v.Handle(context.TODO(), newRequest(t, "Service", newService(corev1.ServiceTypeNodePort)))
+
+		// then
+		assert.True(t, resp.Allowed)
+	})
+
+	t.Run("resource validation disabled", func(t *testing.T) {
+		// given
+		disabled := false
+		cfg := newForbiddenResourcesConfig(forbidden)
+		cfg.Spec.Webhooks = &memberv1alpha1.WebhooksConfig{ResourceValidation: &disabled}
+		v := NewResourceValidator(newFakeClient(t, cfg, newUserNamespace()))
+
+		// when
+		resp := v.Handle(context.TODO(), newRequest(t, "Service", newService(corev1.ServiceTypeNodePort)))
+
+		// then
+		assert.True(t, resp.Allowed)
+	})
+
+	t.Run("not a user namespace", func(t *testing.T) {
+		// given
+		ns := newUserNamespace()
+		ns.Labels = nil
+		v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), ns))
+
+		// when
+		resp := v.Handle(context.TODO(), newRequest(t, "Service", newService(corev1.ServiceTypeNodePort)))
+
+		// then
+		assert.True(t, resp.Allowed)
+	})
+
+	t.Run("other kind allowed", func(t *testing.T) {
+		// given
+		v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+
+		// when
+		resp := v.Handle(context.TODO(), newRouteRequest(t, "app.johnsmith.apps.example.com"))
+
+		// then
+		assert.True(t, resp.Allowed)
+	})
+
+	t.Run("invalid object", func(t *testing.T) {
+		// given
+		v := NewResourceValidator(newFakeClient(t, newForbiddenResourcesConfig(forbidden), newUserNamespace()))
+		req := newRequest(t, "Service", newService(corev1.ServiceTypeNodePort))
+		req.Object.Raw = []byte("{invalid")
+
+		// when
+		resp := v.Handle(context.TODO(), req)
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusBadRequest), resp.Result.Code)
+	})
+}
+
+func newForbiddenResourcesConfig(forbidden *memberv1alpha1.ForbiddenResourcesConfig) *memberv1alpha1.MemberOperatorConfig {
+	cfg := newConfig("")
+	cfg.Spec.ForbiddenResources = forbidden
+	return cfg
+}
+
+// newPVC returns a new PersistentVolumeClaim of the given StorageClass, or without StorageClass if it is empty
+func newPVC(storageClass string) *corev1.PersistentVolumeClaim {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: username + "-dev", Name: "data"},
+	}
+	if storageClass != "" {
+		pvc.Spec.StorageClassName = &storageClass
+	}
+	return pvc
+}
+
+func newRoleBinding(roleKind, roleName string) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: username + "-dev", Name: "app"},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     roleKind,
+			Name:     roleName,
+		},
+		Subjects: []rbacv1.Subject{{Kind: "User", Name: username}},
+	}
+}
+
+func newService(serviceType corev1.ServiceType) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: username + "-dev", Name: "app"},
+		Spec:       corev1.ServiceSpec{Type: serviceType},
+	}
+}
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// PodSchedulingPath the path on which the pod scheduling webhook is served
+const PodSchedulingPath = "/mutate-pods-scheduling"
+
+// PodSchedulingMutator sets the node selector and the tolerations specified in the MemberOperatorConfig on the pods in the user namespaces,
+// so that the sandbox workloads only run on the dedicated worker nodes, and never on the infra or control plane nodes. The labels of the node
+// selector replace the values of the same labels in the node selector of the pods, and the tolerations are added to those of the pods.
+// The pods in the namespaces which are not owned by a user are left untouched.
+type PodSchedulingMutator struct {
+	client client.Client
+}
+
+var _ admission.Handler = &PodSchedulingMutator{}
+
+// NewPodSchedulingMutator returns a new PodSchedulingMutator
+func NewPodSchedulingMutator(cl client.Client) *PodSchedulingMutator {
+	return &PodSchedulingMutator{
+		client: cl,
+	}
+}
+
+// Handle sets the node selector and the tolerations of the Pod in the given request
+func (m *PodSchedulingMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Kind.Kind != "Pod" || len(req.Object.Raw) == 0 {
+		return admission.Allowed("")
+	}
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, errs.Wrap(err, "failed to decode the pod"))
+	}
+
+	if !config.PodSchedulingEnforced() {
+		return admission.Allowed("the pod scheduling is disabled")
+	}
+
+	ns := &corev1.Namespace{}
+	if err := m.client.Get(ctx, types.NamespacedName{Name: req.Namespace}, ns); err != nil {
+		log.Error(err, "unable to get the namespace", "namespace", req.Namespace)
+		return admission.Errored(http.StatusInternalServerError, errs.Wrapf(err, "failed to get namespace '%s'", req.Namespace))
+	}
+	if owner, ok := ns.Labels[ownerLabel]; !ok || owner == "" {
+		return admission.Allowed("not a user namespace")
+	}
+
+	if !setUserPodsScheduling(pod, config.GetPodScheduling()) {
+		return admission.Allowed("")
+	}
+	mutated, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, errs.Wrap(err, "failed to encode the pod"))
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// setUserPodsScheduling sets the labels of the given node selector and adds the given tolerations to the given pod.
+// Returns true if the pod was changed.
+func setUserPodsScheduling(pod *corev1.Pod, scheduling memberv1alpha1.PodSchedulingConfig) bool {
+	changed := false
+	for key, value := range scheduling.NodeSelector {
+		if current, found := pod.Spec.NodeSelector[key]; found && current == value {
+			continue
+		}
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = map[string]string{}
+		}
+		pod.Spec.NodeSelector[key] = value
+		changed = true
+	}
+	for _, toleration := range scheduling.Tolerations {
+		if hasToleration(pod, toleration) {
+			continue
+		}
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, toleration)
+		changed = true
+	}
+	return changed
+}
+
+// hasToleration returns true if the given pod already has the given toleration
+func hasToleration(pod *corev1.Pod, toleration corev1.Toleration) bool {
+	for _, t := range pod.Spec.Tolerations {
+		if reflect.DeepEqual(t, toleration) {
+			return true
+		}
+	}
+	return false
+}
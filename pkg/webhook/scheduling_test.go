@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var sandboxToleration = corev1.Toleration{
+	Key:      "node-role.kubernetes.io/sandbox",
+	Operator: corev1.TolerationOpExists,
+	Effect:   corev1.TaintEffectNoSchedule,
+}
+
+func TestPodSchedulingMutatorHandle(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+
+	t.Run("node selector and tolerations set", func(t *testing.T) {
+		// given
+		m := NewPodSchedulingMutator(newFakeClient(t, newSchedulingConfig(), newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.NotEmpty(t, resp.Patches)
+	})
+
+	t.Run("already set", func(t *testing.T) {
+		// given
+		cfg := newSchedulingConfig()
+		m := NewPodSchedulingMutator(newFakeClient(t, cfg, newUserNamespace()))
+		pod := newPod()
+		setUserPodsScheduling(pod, *cfg.Spec.PodScheduling)
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", pod))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("nothing to set without config", func(t *testing.T) {
+		// given
+		m := NewPodSchedulingMutator(newFakeClient(t, newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("not a user namespace", func(t *testing.T) {
+		// given
+		ns := newUserNamespace()
+		ns.Labels = nil
+		m := NewPodSchedulingMutator(newFakeClient(t, newSchedulingConfig(), ns))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("pod scheduling disabled", func(t *testing.T) {
+		// given
+		disabled := false
+		cfg := newSchedulingConfig()
+		cfg.Spec.Webhooks = &memberv1alpha1.WebhooksConfig{PodScheduling: &disabled}
+		m := NewPodSchedulingMutator(newFakeClient(t, cfg, newUserNamespace()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("invalid object", func(t *testing.T) {
+		// given
+		m := NewPodSchedulingMutator(newFakeClient(t, newSchedulingConfig(), newUserNamespace()))
+		req := newRequest(t, "Pod", newPod())
+		req.Object.Raw = []byte("{invalid")
+
+		// when
+		resp := m.Handle(context.TODO(), req)
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusBadRequest), resp.Result.Code)
+	})
+
+	t.Run("namespace not found", func(t *testing.T) {
+		// given
+		m := NewPodSchedulingMutator(newFakeClient(t, newSchedulingConfig()))
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "Pod", newPod()))
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, int32(http.StatusInternalServerError), resp.Result.Code)
+	})
+}
+
+func TestSetUserPodsScheduling(t *testing.T) {
+	scheduling := memberv1alpha1.PodSchedulingConfig{
+		NodeSelector: map[string]string{"node-role.kubernetes.io/sandbox": ""},
+		Tolerations:  []corev1.Toleration{sandboxToleration},
+	}
+
+	t.Run("node selector replaced and tolerations added", func(t *testing.T) {
+		// given
+		pod := newPod()
+		pod.Spec.NodeSelector = map[string]string{"node-role.kubernetes.io/sandbox": "false", "disktype": "ssd"}
+		userToleration := corev1.Toleration{Key: "gpu", Operator: corev1.TolerationOpExists}
+		pod.Spec.Tolerations = []corev1.Toleration{userToleration}
+
+		// when
+		changed := setUserPodsScheduling(pod, scheduling)
+
+		// then
+		assert.True(t, changed)
+		assert.Equal(t, map[string]string{"node-role.kubernetes.io/sandbox": "", "disktype": "ssd"}, pod.Spec.NodeSelector)
+		assert.Equal(t, []corev1.Toleration{userToleration, sandboxToleration}, pod.Spec.Tolerations)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		// given
+		pod := newPod()
+		setUserPodsScheduling(pod, scheduling)
+
+		// when
+		changed := setUserPodsScheduling(pod, scheduling)
+
+		// then
+		assert.False(t, changed)
+		assert.Len(t, pod.Spec.Tolerations, 1)
+	})
+
+	t.Run("nothing to set", func(t *testing.T) {
+		// given
+		pod := newPod()
+
+		// when
+		changed := setUserPodsScheduling(pod, memberv1alpha1.PodSchedulingConfig{})
+
+		// then
+		assert.False(t, changed)
+		raw, err := json.Marshal(pod)
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), "nodeSelector")
+	})
+}
+
+func newSchedulingConfig() *memberv1alpha1.MemberOperatorConfig {
+	cfg := newConfig("")
+	cfg.Spec.PodScheduling = &memberv1alpha1.PodSchedulingConfig{
+		NodeSelector: map[string]string{"node-role.kubernetes.io/sandbox": ""},
+		Tolerations:  []corev1.Toleration{sandboxToleration},
+	}
+	return cfg
+}
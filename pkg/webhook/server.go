@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	crwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// Server the webhook server of the operator. Unlike the webhook server of the Manager, which only runs on the leader,
+// it runs on all the replicas, so that the standby replicas serve the admission requests too.
+type Server struct {
+	*crwebhook.Server
+	registered int32
+}
+
+// NewServer returns a new Server which listens on the given port
+func NewServer(port int) *Server {
+	return &Server{
+		Server: &crwebhook.Server{Port: port},
+	}
+}
+
+// Register registers the given handler on the given path
+func (s *Server) Register(path string, hook http.Handler) {
+	s.Server.Register(path, hook)
+	atomic.StoreInt32(&s.registered, 1)
+}
+
+// NeedLeaderElection returns false, since all the replicas serve the webhooks
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Ready returns an error as long as the server does not accept the TLS connections, unless no webhook is registered
+// (in which case the server is not started)
+func (s *Server) Ready() error {
+	if atomic.LoadInt32(&s.registered) == 0 {
+		return nil
+	}
+	host := s.Host
+	if host == "" {
+		host = "localhost"
+	}
+	// the handshake only tells that the server accepts the connections, hence the certificate is not verified
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", net.JoinHostPort(host, strconv.Itoa(s.Port)),
+		&tls.Config{InsecureSkipVerify: true}) //nolint: gosec
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
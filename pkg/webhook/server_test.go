@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/leadership"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	crwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestServerOnStandbyReplica(t *testing.T) {
+	// given
+	namespace := "toolchain-member-operator"
+	cl := test.NewFakeClient(t)
+	certDir := tempDir(t)
+	defer os.RemoveAll(certDir)
+	require.NoError(t, NewCertManager(cl, namespace, certDir).Sync(time.Now()))
+
+	server := NewServer(freePort(t))
+	server.Host = "localhost"
+	server.CertDir = certDir
+	require.NoError(t, server.InjectFunc(func(interface{}) error { return nil }))
+	server.Register("/allow", &crwebhook.Admission{Handler: admission.HandlerFunc(func(_ context.Context, _ admission.Request) admission.Response {
+		return admission.Allowed("")
+	})})
+
+	// the status is never started, ie, the replica does not acquire the leadership
+	status := leadership.NewStatus()
+	status.AddReadinessCheck("webhook server", server.Ready)
+	probes := leadership.NewProbesHandler(status)
+	probe := func(path string) int {
+		rec := httptest.NewRecorder()
+		probes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	require.Equal(t, http.StatusServiceUnavailable, probe(leadership.ReadinessPath))
+
+	// when
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		_ = server.Start(stop)
+	}()
+
+	// then
+	assert.False(t, server.NeedLeaderElection())
+	assert.Eventually(t, func() bool {
+		return probe(leadership.ReadinessPath) == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, probe(leadership.LeadershipPath))
+
+	t.Run("admission request served", func(t *testing.T) {
+		// given
+		roots := x509.NewCertPool()
+		require.True(t, roots.AppendCertsFromPEM(getCertificatesSecret(t, cl, namespace).Data[caCertKey]))
+		httpClient := &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "member-operator-webhook.toolchain-member-operator.svc"},
+			},
+		}
+		review, err := json.Marshal(admissionv1beta1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1beta1", Kind: "AdmissionReview"},
+			Request: &admissionv1beta1.AdmissionRequest{
+				UID:       types.UID("some-uid"),
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Namespace: "johnsmith-dev",
+				Operation: admissionv1beta1.Create,
+			},
+		})
+		require.NoError(t, err)
+
+		// when
+		resp, err := httpClient.Post("https://"+net.JoinHostPort("localhost", strconv.Itoa(server.Port))+"/allow", "application/json", bytes.NewReader(review))
+
+		// then
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		result := admissionv1beta1.AdmissionReview{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		require.NotNil(t, result.Response)
+		assert.True(t, result.Response.Allowed)
+	})
+}
+
+func TestServerReadyWithoutWebhook(t *testing.T) {
+	// given
+	server := NewServer(freePort(t))
+
+	// when
+	err := server.Ready()
+
+	// then
+	assert.NoError(t, err)
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,336 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// VirtualMachinePath the path on which the virtual machine webhook is served
+const VirtualMachinePath = "/mutate-virtualmachines"
+
+// quantityField a field of a VirtualMachine (or of one of its DataVolume templates) which holds a quantity
+type quantityField struct {
+	name string
+	path []string
+}
+
+// the fields of the resources in the KubeVirt VirtualMachines
+var (
+	memoryRequestField = quantityField{name: "memory request", path: domainPath("resources", "requests", "memory")}
+	guestMemoryField   = quantityField{name: "guest memory", path: domainPath("memory", "guest")}
+	memoryFields       = []quantityField{
+		memoryRequestField,
+		{name: "memory limit", path: domainPath("resources", "limits", "memory")},
+		guestMemoryField,
+	}
+	cpuRequestField = quantityField{name: "cpu request", path: domainPath("resources", "requests", "cpu")}
+	cpuFields       = []quantityField{
+		cpuRequestField,
+		{name: "cpu limit", path: domainPath("resources", "limits", "cpu")},
+	}
+	cpuTopology = []string{"cores", "sockets", "threads"}
+	diskFields  = []quantityField{
+		{name: "pvc storage", path: []string{"spec", "pvc", "resources", "requests", "storage"}},
+		{name: "storage", path: []string{"spec", "storage", "resources", "requests", "storage"}},
+	}
+)
+
+// domainPath returns the path of the given field of the domain of the VirtualMachines
+func domainPath(fields ...string) []string {
+	return append([]string{"spec", "template", "spec", "domain"}, fields...)
+}
+
+// VirtualMachineMutator sets the default memory and CPU of the KubeVirt VirtualMachines in the user namespaces which do not specify them,
+// and enforces the maximums of their memory, CPU and disk, as configured for the tier of the owner of the namespace in the MemberOperatorConfig.
+// Depending on the configured action, the VirtualMachines which exceed the maximums are either rejected or lowered to the maximums.
+// The VirtualMachines in the namespaces which are not owned by a user are left untouched.
+type VirtualMachineMutator struct {
+	client    client.Client
+	namespace string
+}
+
+var _ admission.Handler = &VirtualMachineMutator{}
+
+// NewVirtualMachineMutator returns a new VirtualMachineMutator using the NSTemplateSets of the given namespace
+func NewVirtualMachineMutator(cl client.Client, namespace string) *VirtualMachineMutator {
+	return &VirtualMachineMutator{
+		client:    cl,
+		namespace: namespace,
+	}
+}
+
+// virtualMachineLimits the parsed limits of the VirtualMachines of a tier. The limits which are not configured are nil (or `0`).
+type virtualMachineLimits struct {
+	defaultMemory   *resource.Quantity
+	maxMemory       *resource.Quantity
+	defaultCPUCores int64
+	maxCPUCores     int64
+	maxDisk         *resource.Quantity
+	capped          bool
+}
+
+// Handle defaults and caps the resources of the VirtualMachine in the given request
+func (m *VirtualMachineMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Kind.Kind != "VirtualMachine" || len(req.Object.Raw) == 0 {
+		return admission.Allowed("")
+	}
+	vm := &unstructured.Unstructured{}
+	if err := vm.UnmarshalJSON(req.Object.Raw); err != nil {
+		return admission.Errored(http.StatusBadRequest, errs.Wrap(err, "failed to decode the virtual machine"))
+	}
+
+	if !config.VirtualMachineLimitsEnforced() {
+		return admission.Allowed("the virtual machine limits are disabled")
+	}
+
+	ns := &corev1.Namespace{}
+	if err := m.client.Get(ctx, types.NamespacedName{Name: req.Namespace}, ns); err != nil {
+		log.Error(err, "unable to get the namespace", "namespace", req.Namespace)
+		return admission.Errored(http.StatusInternalServerError, errs.Wrapf(err, "failed to get namespace '%s'", req.Namespace))
+	}
+	owner, ok := ns.Labels[ownerLabel]
+	if !ok || owner == "" {
+		return admission.Allowed("not a user namespace")
+	}
+	tier, err := m.tierOf(ctx, owner)
+	if err != nil {
+		log.Error(err, "unable to get the tier of the user", "owner", owner)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	cfg, found := config.GetVirtualMachineLimits(tier)
+	if !found {
+		return admission.Allowed("the virtual machines are not restricted")
+	}
+	limits, err := parseVirtualMachineLimits(cfg)
+	if err != nil {
+		log.Error(err, "unable to mutate the virtual machine", "namespace", req.Namespace, "name", req.Name)
+		return admission.Errored(http.StatusInternalServerError, errs.Wrapf(err, "invalid virtual machine limits of tier '%s'", tier))
+	}
+
+	changed, violations, err := limits.apply(vm)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if len(violations) > 0 {
+		return admission.Denied(fmt.Sprintf("the virtual machine exceeds the limits of the user namespaces: %s", strings.Join(violations, ", ")))
+	}
+	if !changed {
+		return admission.Allowed("")
+	}
+	mutated, err := vm.MarshalJSON()
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, errs.Wrap(err, "failed to encode the virtual machine"))
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// tierOf returns the tier of the NSTemplateSet of the given user, or an empty string if the user has no NSTemplateSet
+func (m *VirtualMachineMutator) tierOf(ctx context.Context, owner string) (string, error) {
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{}
+	if err := m.client.Get(ctx, types.NamespacedName{Namespace: m.namespace, Name: owner}, nsTmplSet); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errs.Wrapf(err, "failed to get the NSTemplateSet of user '%s'", owner)
+	}
+	return nsTmplSet.Spec.TierName, nil
+}
+
+// parseVirtualMachineLimits parses the given limits of the VirtualMachines
+func parseVirtualMachineLimits(cfg memberv1alpha1.VirtualMachineLimits) (virtualMachineLimits, error) {
+	limits := virtualMachineLimits{
+		defaultCPUCores: int64(cfg.DefaultCPUCores),
+		maxCPUCores:     int64(cfg.MaxCPUCores),
+		capped:          cfg.Action == memberv1alpha1.VirtualMachineLimitCap,
+	}
+	for _, l := range []struct {
+		name   string
+		value  string
+		target **resource.Quantity
+	}{
+		{name: "default memory", value: cfg.DefaultMemory, target: &limits.defaultMemory},
+		{name: "max memory", value: cfg.MaxMemory, target: &limits.maxMemory},
+		{name: "max disk", value: cfg.MaxDisk, target: &limits.maxDisk},
+	} {
+		if l.value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(l.value)
+		if err != nil {
+			return limits, errs.Wrapf(err, "invalid %s '%s'", l.name, l.value)
+		}
+		*l.target = &quantity
+	}
+	return limits, nil
+}
+
+// apply sets the default memory and CPU of the given VirtualMachine, and enforces the maximums of its resources. The resources which exceed
+// the maximums are lowered if the limits are capped, otherwise they are returned as violations (and the VirtualMachine is left untouched).
+// Returns true if the VirtualMachine was changed.
+func (l virtualMachineLimits) apply(vm *unstructured.Unstructured) (bool, []string, error) {
+	changed := false
+	var violations []string
+	check := func(exceeded bool, violation string, capResource func() error) error {
+		if !exceeded {
+			return nil
+		}
+		if !l.capped {
+			violations = append(violations, violation)
+			return nil
+		}
+		changed = true
+		return capResource()
+	}
+
+	// memory
+	if l.defaultMemory != nil {
+		_, hasRequest, err := nestedQuantity(vm.Object, memoryRequestField)
+		if err != nil {
+			return false, nil, err
+		}
+		_, hasGuest, err := nestedQuantity(vm.Object, guestMemoryField)
+		if err != nil {
+			return false, nil, err
+		}
+		if !hasRequest && !hasGuest {
+			if err := unstructured.SetNestedField(vm.Object, l.defaultMemory.String(), memoryRequestField.path...); err != nil {
+				return false, nil, err
+			}
+			changed = true
+		}
+	}
+	if l.maxMemory != nil {
+		for _, field := range memoryFields {
+			memory, found, err := nestedQuantity(vm.Object, field)
+			if err != nil {
+				return false, nil, err
+			}
+			field := field
+			violation := fmt.Sprintf("%s of %s is greater than %s", field.name, memory.String(), l.maxMemory.String())
+			if err := check(found && memory.Cmp(*l.maxMemory) > 0, violation, func() error {
+				return unstructured.SetNestedField(vm.Object, l.maxMemory.String(), field.path...)
+			}); err != nil {
+				return false, nil, err
+			}
+		}
+	}
+
+	// CPU
+	_, hasCPU, err := unstructured.NestedMap(vm.Object, domainPath("cpu")...)
+	if err != nil {
+		return false, nil, errs.Wrap(err, "invalid cpu of the virtual machine")
+	}
+	_, hasCPURequest, err := nestedQuantity(vm.Object, cpuRequestField)
+	if err != nil {
+		return false, nil, err
+	}
+	if l.defaultCPUCores > 0 && !hasCPU && !hasCPURequest {
+		if err := unstructured.SetNestedField(vm.Object, l.defaultCPUCores, domainPath("cpu", "cores")...); err != nil {
+			return false, nil, err
+		}
+		changed = true
+	}
+	if l.maxCPUCores > 0 {
+		vcpus := int64(1)
+		for _, field := range cpuTopology {
+			value, found, err := unstructured.NestedInt64(vm.Object, domainPath("cpu", field)...)
+			if err != nil {
+				return false, nil, errs.Wrapf(err, "invalid cpu %s of the virtual machine", field)
+			}
+			if found && value > 0 {
+				vcpus *= value
+			}
+		}
+		if err := check(vcpus > l.maxCPUCores, fmt.Sprintf("%d virtual CPUs is greater than %d", vcpus, l.maxCPUCores), func() error {
+			if err := unstructured.SetNestedField(vm.Object, l.maxCPUCores, domainPath("cpu", "cores")...); err != nil {
+				return err
+			}
+			unstructured.RemoveNestedField(vm.Object, domainPath("cpu", "sockets")...)
+			unstructured.RemoveNestedField(vm.Object, domainPath("cpu", "threads")...)
+			return nil
+		}); err != nil {
+			return false, nil, err
+		}
+		maxCPU := resource.NewQuantity(l.maxCPUCores, resource.DecimalSI)
+		for _, field := range cpuFields {
+			cpu, found, err := nestedQuantity(vm.Object, field)
+			if err != nil {
+				return false, nil, err
+			}
+			field := field
+			violation := fmt.Sprintf("%s of %s is greater than %s", field.name, cpu.String(), maxCPU.String())
+			if err := check(found && cpu.Cmp(*maxCPU) > 0, violation, func() error {
+				return unstructured.SetNestedField(vm.Object, maxCPU.String(), field.path...)
+			}); err != nil {
+				return false, nil, err
+			}
+		}
+	}
+
+	// disks
+	if l.maxDisk != nil {
+		templates, _, err := unstructured.NestedSlice(vm.Object, "spec", "dataVolumeTemplates")
+		if err != nil {
+			return false, nil, errs.Wrap(err, "invalid data volume templates of the virtual machine")
+		}
+		templatesChanged := false
+		for i := range templates {
+			template, ok := templates[i].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(template, "metadata", "name")
+			for _, field := range diskFields {
+				disk, found, err := nestedQuantity(template, field)
+				if err != nil {
+					return false, nil, err
+				}
+				field := field
+				violation := fmt.Sprintf("%s of disk '%s' of %s is greater than %s", field.name, name, disk.String(), l.maxDisk.String())
+				if err := check(found && disk.Cmp(*l.maxDisk) > 0, violation, func() error {
+					templatesChanged = true
+					return unstructured.SetNestedField(template, l.maxDisk.String(), field.path...)
+				}); err != nil {
+					return false, nil, err
+				}
+			}
+		}
+		if templatesChanged {
+			if err := unstructured.SetNestedSlice(vm.Object, templates, "spec", "dataVolumeTemplates"); err != nil {
+				return false, nil, err
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return false, violations, nil
+	}
+	return changed, nil, nil
+}
+
+// nestedQuantity returns the quantity of the given field of the given object, which can either be a string or a number
+func nestedQuantity(obj map[string]interface{}, field quantityField) (resource.Quantity, bool, error) {
+	value, found, err := unstructured.NestedFieldNoCopy(obj, field.path...)
+	if err != nil || !found || value == nil {
+		return resource.Quantity{}, false, err
+	}
+	quantity, err := resource.ParseQuantity(fmt.Sprint(value))
+	if err != nil {
+		return resource.Quantity{}, false, errs.Wrapf(err, "invalid %s '%v'", field.name, value)
+	}
+	return quantity, true, nil
+}
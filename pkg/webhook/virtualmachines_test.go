@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	memberv1alpha1 "github.com/codeready-toolchain/member-operator/pkg/apis/member/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestVirtualMachineMutatorHandle(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	err := apis.AddToScheme(scheme.Scheme)
+	require.NoError(t, err)
+	limits := &memberv1alpha1.VirtualMachinesConfig{
+		Default: &memberv1alpha1.VirtualMachineLimits{
+			DefaultMemory:   "1Gi",
+			MaxMemory:       "2Gi",
+			DefaultCPUCores: 1,
+			MaxCPUCores:     2,
+			MaxDisk:         "20Gi",
+		},
+		Tiers: map[string]memberv1alpha1.VirtualMachineLimits{
+			"advanced": {
+				MaxMemory:   "8Gi",
+				MaxCPUCores: 4,
+				MaxDisk:     "50Gi",
+				Action:      memberv1alpha1.VirtualMachineLimitCap,
+			},
+		},
+	}
+
+	t.Run("defaults set", func(t *testing.T) {
+		// given
+		m := NewVirtualMachineMutator(newFakeClient(t, newVirtualMachinesConfig(limits), newUserNamespace()), namespaceName)
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "VirtualMachine", newVM(nil)))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.NotEmpty(t, resp.Patches)
+	})
+
+	t.Run("within the limits", func(t *testing.T) {
+		// given
+		m := NewVirtualMachineMutator(newFakeClient(t, newVirtualMachinesConfig(limits), newUserNamespace()), namespaceName)
+		vm := newVM(map[string]interface{}{
+			"cpu":       map[string]interface{}{"cores": int64(2)},
+			"resources": map[string]interface{}{"requests": map[string]interface{}{"memory": "2Gi"}},
+		})
+		addDataVolumeTemplate(vm, "rootdisk", "20Gi")
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "VirtualMachine", vm))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("over the limits rejected", func(t *testing.T) {
+		// given
+		m := NewVirtualMachineMutator(newFakeClient(t, newVirtualMachinesConfig(limits), newUserNamespace()), namespaceName)
+		vm := newVM(map[string]interface{}{
+			"cpu":       map[string]interface{}{"cores": int64(2), "sockets": int64(2)},
+			"resources": map[string]interface{}{"requests": map[string]interface{}{"memory": "4Gi"}},
+		})
+		addDataVolumeTemplate(vm, "rootdisk", "30Gi")
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "VirtualMachine", vm))
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, "the virtual machine exceeds the limits of the user namespaces: memory request of 4Gi is greater than 2Gi, "+
+			"4 virtual CPUs is greater than 2, storage of disk 'rootdisk' of 30Gi is greater than 20Gi", string(resp.Result.Reason))
+	})
+
+	t.Run("over the limits capped for the tier", func(t *testing.T) {
+		// given
+		nsTmplSet := &toolchainv1alpha1.NSTemplateSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: username},
+			Spec:       toolchainv1alpha1.NSTemplateSetSpec{TierName: "advanced"},
+		}
+		m := NewVirtualMachineMutator(newFakeClient(t, newVirtualMachinesConfig(limits), newUserNamespace(), nsTmplSet), namespaceName)
+		vm := newVM(map[string]interface{}{
+			"cpu":       map[string]interface{}{"cores": int64(4), "threads": int64(2)},
+			"memory":    map[string]interface{}{"guest": "16Gi"},
+			"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": int64(8)}},
+		})
+		addDataVolumeTemplate(vm, "rootdisk", "100Gi")
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "VirtualMachine", vm))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.NotEmpty(t, resp.Patches)
+
+		t.Run("resources lowered to the maximums", func(t *testing.T) {
+			// given
+			l, err := parseVirtualMachineLimits(limits.Tiers["advanced"])
+			require.NoError(t, err)
+
+			// when
+			changed, violations, err := l.apply(vm)
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, changed)
+			assert.Empty(t, violations)
+			cpu, _, _ := unstructured.NestedMap(vm.Object, domainPath("cpu")...)
+			assert.Equal(t, map[string]interface{}{"cores": int64(4)}, cpu)
+			guest, _, _ := unstructured.NestedString(vm.Object, domainPath("memory", "guest")...)
+			assert.Equal(t, "8Gi", guest)
+			cpuLimit, _, _ := unstructured.NestedString(vm.Object, domainPath("resources", "limits", "cpu")...)
+			assert.Equal(t, "4", cpuLimit)
+			templates, _, _ := unstructured.NestedSlice(vm.Object, "spec", "dataVolumeTemplates")
+			storage, _, _ := unstructured.NestedString(templates[0].(map[string]interface{}), "spec", "storage", "resources", "requests", "storage")
+			assert.Equal(t, "50Gi", storage)
+		})
+	})
+
+	t.Run("not restricted without config", func(t *testing.T) {
+		// given
+		m := NewVirtualMachineMutator(newFakeClient(t, newUserNamespace()), namespaceName)
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "VirtualMachine", newVM(nil)))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("disabled at runtime", func(t *testing.T) {
+		// given
+		cfg := newVirtualMachinesConfig(limits)
+		disabled := false
+		cfg.Spec.Webhooks = &memberv1alpha1.WebhooksConfig{VirtualMachines: &disabled}
+		m := NewVirtualMachineMutator(newFakeClient(t, cfg, newUserNamespace()), namespaceName)
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "VirtualMachine", newVM(nil)))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("not a user namespace", func(t *testing.T) {
+		// given
+		ns := newUserNamespace()
+		ns.Labels = nil
+		m := NewVirtualMachineMutator(newFakeClient(t, newVirtualMachinesConfig(limits), ns), namespaceName)
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "VirtualMachine", newVM(nil)))
+
+		// then
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+
+	t.Run("invalid limits", func(t *testing.T) {
+		// given
+		invalid := &memberv1alpha1.VirtualMachinesConfig{Default: &memberv1alpha1.VirtualMachineLimits{MaxMemory: "lots"}}
+		m := NewVirtualMachineMutator(newFakeClient(t, newVirtualMachinesConfig(invalid), newUserNamespace()), namespaceName)
+
+		// when
+		resp := m.Handle(context.TODO(), newRequest(t, "VirtualMachine", newVM(nil)))
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Contains(t, resp.Result.Message, "invalid max memory 'lots'")
+	})
+}
+
+func newVirtualMachinesConfig(limits *memberv1alpha1.VirtualMachinesConfig) *memberv1alpha1.MemberOperatorConfig {
+	cfg := newConfig("")
+	cfg.Spec.VirtualMachines = limits
+	return cfg
+}
+
+// newVM returns a KubeVirt VirtualMachine with the given domain (along with a disk)
+func newVM(domain map[string]interface{}) *unstructured.Unstructured {
+	if domain == nil {
+		domain = map[string]interface{}{}
+	}
+	domain["devices"] = map[string]interface{}{
+		"disks": []interface{}{map[string]interface{}{"name": "rootdisk"}},
+	}
+	vm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"running": false,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"domain": domain,
+				},
+			},
+		},
+	}}
+	vm.SetAPIVersion("kubevirt.io/v1alpha3")
+	vm.SetKind("VirtualMachine")
+	vm.SetNamespace(username + "-dev")
+	vm.SetName("app")
+	return vm
+}
+
+func addDataVolumeTemplate(vm *unstructured.Unstructured, name, storage string) {
+	templates, _, _ := unstructured.NestedSlice(vm.Object, "spec", "dataVolumeTemplates")
+	templates = append(templates, map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"storage": map[string]interface{}{
+				"resources": map[string]interface{}{"requests": map[string]interface{}{"storage": storage}},
+			},
+		},
+	})
+	_ = unstructured.SetNestedSlice(vm.Object, templates, "spec", "dataVolumeTemplates")
+}
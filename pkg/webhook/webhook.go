@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/codeready-toolchain/member-operator/pkg/config"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	crwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var log = logf.Log.WithName("webhook")
+
+// Add registers all the webhooks on the given webhook server, which is added to the Manager, if at least one webhook is enabled:
+// - the HostValidator. The external policy engine is also consulted about the requests allowed by the HostValidator, if configured.
+// - the ResourceValidator.
+// - the PodMutator.
+// - the PodSchedulingMutator.
+// - the PodProxyMutator.
+// - the VirtualMachineMutator.
+// - the PodPriorityMutator. The PriorityClass it assigns is created when the Manager starts.
+// Each webhook allows all the requests as long as it is disabled, since whether it is enabled is checked on each request.
+// The serving certificate is generated and rotated by a CertManager if the operator provides the certificates of the webhooks.
+func Add(mgr manager.Manager, server *Server) error {
+	if !config.HostValidationWebhookEnabled() && !config.ResourceValidationWebhookEnabled() &&
+		!config.PodMutationWebhookEnabled() && !config.PodSchedulingWebhookEnabled() && !config.PodPriorityWebhookEnabled() &&
+		!config.VirtualMachineWebhookEnabled() && !config.PodProxyWebhookEnabled() {
+		return nil
+	}
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	if config.GetWebhookCertificates() == config.WebhookCertificatesOperator {
+		if err := addCertManager(mgr, server, namespace); err != nil {
+			return err
+		}
+	}
+	var hosts admission.Handler = NewHostValidator(mgr.GetClient())
+	if url := config.GetPolicyEngineURL(); url != "" {
+		engine := NewPolicyEngine(url, &http.Client{Timeout: config.GetPolicyEngineTimeout()}, config.PolicyEngineFailOpen())
+		hosts = NewPolicyHandler(hosts, engine)
+	}
+	server.Register(HostValidationPath, &crwebhook.Admission{Handler: NewToggledHandler(config.HostValidationWebhookEnabled, hosts)})
+	server.Register(ResourceValidationPath, &crwebhook.Admission{Handler: NewToggledHandler(config.ResourceValidationWebhookEnabled, NewResourceValidator(mgr.GetClient()))})
+	server.Register(PodMutationPath, &crwebhook.Admission{Handler: NewToggledHandler(config.PodMutationWebhookEnabled, NewPodMutator(mgr.GetClient()))})
+	server.Register(PodSchedulingPath, &crwebhook.Admission{Handler: NewToggledHandler(config.PodSchedulingWebhookEnabled, NewPodSchedulingMutator(mgr.GetClient()))})
+	server.Register(PodProxyPath, &crwebhook.Admission{Handler: NewToggledHandler(config.PodProxyWebhookEnabled, NewPodProxyMutator(mgr.GetClient()))})
+	server.Register(VirtualMachinePath, &crwebhook.Admission{Handler: NewToggledHandler(config.VirtualMachineWebhookEnabled, NewVirtualMachineMutator(mgr.GetClient(), namespace))})
+	server.Register(PodPriorityPath, &crwebhook.Admission{Handler: NewToggledHandler(config.PodPriorityWebhookEnabled, NewPodPriorityMutator(mgr.GetClient()))})
+	if err := mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		return CreatePriorityClassIfNotExists(mgr.GetClient())
+	})); err != nil {
+		return err
+	}
+	return mgr.Add(server)
+}
+
+// ToggledHandler an admission handler which only delegates the requests to its handler while its webhook is enabled,
+// and allows them otherwise
+type ToggledHandler struct {
+	enabled func() bool
+	handler admission.Handler
+}
+
+var _ admission.Handler = &ToggledHandler{}
+
+// NewToggledHandler returns a new ToggledHandler which delegates the requests to the given handler as long as the given func returns true
+func NewToggledHandler(enabled func() bool, handler admission.Handler) *ToggledHandler {
+	return &ToggledHandler{
+		enabled: enabled,
+		handler: handler,
+	}
+}
+
+// Handle delegates the given request to the handler if the webhook is enabled, and allows it otherwise
+func (h *ToggledHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if !h.enabled() {
+		return admission.Allowed("the webhook is disabled")
+	}
+	return h.handler.Handle(ctx, req)
+}
+
+// addCertManager syncs the certificates of the webhooks before the webhook server starts (using a direct client, since the cache of the Manager
+// is not started yet), and adds the CertManager to the Manager, so that the certificates are renewed before they expire
+func addCertManager(mgr manager.Manager, server *Server, namespace string) error {
+	cl, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return err
+	}
+	certs := NewCertManager(cl, namespace, CertDir)
+	if err := certs.Sync(time.Now()); err != nil {
+		return err
+	}
+	server.CertDir = CertDir
+	return mgr.Add(certs)
+}
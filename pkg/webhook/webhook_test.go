@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestToggledHandler(t *testing.T) {
+	// given
+	enabled := false
+	denyAll := admission.HandlerFunc(func(_ context.Context, _ admission.Request) admission.Response {
+		return admission.Denied("denied")
+	})
+	h := NewToggledHandler(func() bool { return enabled }, denyAll)
+
+	t.Run("allowed while disabled", func(t *testing.T) {
+		// when
+		resp := h.Handle(context.TODO(), newRouteRequest(t, "app.janedoe.apps.example.com"))
+
+		// then
+		assert.True(t, resp.Allowed)
+	})
+
+	t.Run("delegated once enabled", func(t *testing.T) {
+		// given
+		enabled = true
+
+		// when
+		resp := h.Handle(context.TODO(), newRouteRequest(t, "app.janedoe.apps.example.com"))
+
+		// then
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, "denied", string(resp.Result.Reason))
+	})
+}
@@ -0,0 +1,122 @@
+package scale
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/rest"
+)
+
+// APICalls counts the calls to the API server, by verb and resource (eg, `CREATE configmaps` or `UPDATE nstemplatesets/status`)
+type APICalls struct {
+	lock   sync.Mutex
+	counts map[string]int
+}
+
+// NewAPICalls returns a new, empty counter of API calls
+func NewAPICalls() *APICalls {
+	return &APICalls{counts: map[string]int{}}
+}
+
+// Wrap returns a copy of the given config whose clients record their calls in this counter
+func (c *APICalls) Wrap(cfg *rest.Config) *rest.Config {
+	wrapped := rest.CopyConfig(cfg)
+	wrapTransport := cfg.WrapTransport
+	wrapped.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrapTransport != nil {
+			rt = wrapTransport(rt)
+		}
+		return &countingRoundTripper{calls: c, delegate: rt}
+	}
+	return wrapped
+}
+
+// Counts returns a copy of the number of calls by verb and resource
+func (c *APICalls) Counts() map[string]int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	counts := make(map[string]int, len(c.counts))
+	for key, count := range c.counts {
+		counts[key] = count
+	}
+	return counts
+}
+
+// Total returns the total number of calls
+func (c *APICalls) Total() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	total := 0
+	for _, count := range c.counts {
+		total += count
+	}
+	return total
+}
+
+func (c *APICalls) record(req *http.Request) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counts[requestKey(req)]++
+}
+
+type countingRoundTripper struct {
+	calls    *APICalls
+	delegate http.RoundTripper
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.calls.record(req)
+	return rt.delegate.RoundTrip(req)
+}
+
+// requestKey returns the verb and the resource of the given request to the API server, eg `LIST namespaces`
+func requestKey(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	// skip the `/api/<version>` or `/apis/<group>/<version>` prefix, then the namespace, if any
+	switch {
+	case len(segments) >= 2 && segments[0] == "api":
+		segments = segments[2:]
+	case len(segments) >= 3 && segments[0] == "apis":
+		segments = segments[3:]
+	default:
+		return strings.ToUpper(req.Method) + " " + req.URL.Path
+	}
+	if len(segments) > 2 && segments[0] == "namespaces" {
+		segments = segments[2:]
+	}
+	if len(segments) == 0 {
+		return "DISCOVERY"
+	}
+	resource := segments[0]
+	if len(segments) > 2 {
+		resource += "/" + segments[2]
+	}
+	return verb(req.Method, len(segments) > 1, req.URL.Query().Get("watch") == "true") + " " + resource
+}
+
+// verb returns the verb of the request with the given HTTP method, on a single object or on a collection
+func verb(method string, single, watch bool) string {
+	switch method {
+	case http.MethodGet:
+		if watch {
+			return "WATCH"
+		}
+		if single {
+			return "GET"
+		}
+		return "LIST"
+	case http.MethodPost:
+		return "CREATE"
+	case http.MethodPut:
+		return "UPDATE"
+	case http.MethodPatch:
+		return "PATCH"
+	case http.MethodDelete:
+		if single {
+			return "DELETE"
+		}
+		return "DELETECOLLECTION"
+	}
+	return method
+}
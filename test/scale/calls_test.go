@@ -0,0 +1,62 @@
+package scale
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+func TestRequestKey(t *testing.T) {
+	for path, expected := range map[string]string{
+		"GET /api/v1/namespaces":                                                                "LIST namespaces",
+		"GET /api/v1/namespaces/johnsmith-dev":                                                  "GET namespaces",
+		"GET /api/v1/namespaces/johnsmith-dev/configmaps?watch=true":                            "WATCH configmaps",
+		"POST /api/v1/namespaces/johnsmith-dev/configmaps":                                      "CREATE configmaps",
+		"PUT /apis/toolchain.dev.openshift.com/v1alpha1/namespaces/ns/nstemplatesets/js/status": "UPDATE nstemplatesets/status",
+		"PATCH /apis/rbac.authorization.k8s.io/v1/namespaces/ns/rolebindings/edit":              "PATCH rolebindings",
+		"DELETE /apis/rbac.authorization.k8s.io/v1/clusterroles/edit":                           "DELETE clusterroles",
+		"GET /apis/rbac.authorization.k8s.io/v1":                                                "DISCOVERY",
+		"GET /version":                                                                          "GET /version",
+	} {
+		t.Run(path, func(t *testing.T) {
+			// given
+			methodAndTarget := strings.SplitN(path, " ", 2)
+			req := httptest.NewRequest(methodAndTarget[0], methodAndTarget[1], nil)
+
+			// when
+			key := requestKey(req)
+
+			// then
+			assert.Equal(t, expected, key)
+		})
+	}
+}
+
+func TestAPICalls(t *testing.T) {
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	calls := NewAPICalls()
+	cfg := calls.Wrap(&rest.Config{Host: server.URL})
+	transport, err := rest.TransportFor(cfg)
+	require.NoError(t, err)
+	httpClient := &http.Client{Transport: transport}
+
+	// when
+	for _, path := range []string{"/api/v1/namespaces", "/api/v1/namespaces", "/api/v1/namespaces/johnsmith-dev"} {
+		resp, err := httpClient.Get(server.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// then
+	assert.Equal(t, map[string]int{"LIST namespaces": 2, "GET namespaces": 1}, calls.Counts())
+	assert.Equal(t, 3, calls.Total())
+}
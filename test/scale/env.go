@@ -0,0 +1,106 @@
+package scale
+
+import (
+	"context"
+	"os"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/member-operator/pkg/controller/nstemplateset"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// TargetEnvTest the target which runs the harness against a local API server started with envtest (see `KUBEBUILDER_ASSETS`)
+	TargetEnvTest = "envtest"
+	// TargetKind the target which runs the harness against the cluster of the current kubeconfig, eg, a kind cluster
+	TargetKind = "kind"
+)
+
+// Environment the cluster against which the harness runs, with the NSTemplateSet controller running in-process
+type Environment struct {
+	// Client the client of the harness, whose calls are not counted
+	Client client.Client
+	// APICalls the calls to the API server made by the controllers
+	APICalls *APICalls
+	testEnv  *envtest.Environment
+	stop     chan struct{}
+}
+
+// Start starts (or connects to) the cluster of the given target, installs the CRDs of the given directory, and runs the NSTemplateSet
+// controller in the given namespace with the templates of the synthetic tier, whose namespaces contain the given number of objects
+func Start(target, crdDir, namespace string, objects int) (*Environment, error) {
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:  []string{crdDir},
+		UseExistingCluster: target == TargetKind,
+	}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to start the '%s' environment", target)
+	}
+	env := &Environment{
+		APICalls: NewAPICalls(),
+		testEnv:  testEnv,
+		stop:     make(chan struct{}),
+	}
+	if err := env.start(cfg, namespace, objects); err != nil {
+		_ = testEnv.Stop()
+		return nil, err
+	}
+	return env, nil
+}
+
+func (env *Environment) start(cfg *rest.Config, namespace string, objects int) error {
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		return err
+	}
+	cl, err := client.New(cfg, client.Options{Scheme: s})
+	if err != nil {
+		return errs.Wrap(err, "failed to create the client of the harness")
+	}
+	env.Client = cl
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	if err := cl.Create(context.TODO(), ns); err != nil && !apierrors.IsAlreadyExists(err) {
+		return errs.Wrapf(err, "failed to create the namespace '%s'", namespace)
+	}
+
+	// the controller watches the namespace of the operator
+	if err := os.Setenv(k8sutil.WatchNamespaceEnvVar, namespace); err != nil {
+		return err
+	}
+	mgr, err := manager.New(env.APICalls.Wrap(cfg), manager.Options{
+		Namespace:          namespace,
+		MetricsBindAddress: "0",
+	})
+	if err != nil {
+		return errs.Wrap(err, "failed to create the manager")
+	}
+	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
+		return err
+	}
+	if err := nstemplateset.AddWithTemplates(mgr, newTemplateContent(mgr.GetScheme(), objects)); err != nil {
+		return errs.Wrap(err, "failed to add the NSTemplateSet controller")
+	}
+	go func() {
+		if err := mgr.Start(env.stop); err != nil {
+			log.Error(err, "the manager stopped")
+		}
+	}()
+	return nil
+}
+
+// Stop stops the controller, as well as the API server if it was started by the harness
+func (env *Environment) Stop() error {
+	close(env.stop)
+	return env.testEnv.Stop()
+}
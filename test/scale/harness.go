@@ -0,0 +1,184 @@
+// Package scale provides a harness which provisions a large number of synthetic NSTemplateSets with the controllers of the operator
+// running in-process, against an envtest API server or a kind cluster, and reports the provisioning throughput, the latency
+// percentiles of the apply and the number of calls to the API server, so that the performance regressions are caught before a release
+package scale
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+
+	errs "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("scale")
+
+// harnessLabel the label of the NSTemplateSets created by the harness
+const harnessLabel = "toolchain.dev.openshift.com/scale-harness"
+
+// pollInterval the interval between two checks of the status of the NSTemplateSets, which is also the resolution of the latencies
+var pollInterval = 100 * time.Millisecond
+
+// Options the options of a run of the harness
+type Options struct {
+	// Namespace the namespace of the operator, in which the NSTemplateSets are created
+	Namespace string
+	// Count the number of NSTemplateSets to provision
+	Count int
+	// Concurrency the number of NSTemplateSets which are created in parallel
+	Concurrency int
+	// Namespaces the number of namespaces of each NSTemplateSet
+	Namespaces int
+	// Timeout the time after which the run fails if some NSTemplateSets are still not ready
+	Timeout time.Duration
+}
+
+// Run creates the NSTemplateSets of the synthetic tier with the given client, waits until they are all ready and reports the time it took,
+// as well as the calls to the API server recorded in the given counter during the run
+func Run(cl client.Client, calls *APICalls, opts Options) (*Report, error) {
+	callsBefore := calls.Counts()
+	start := time.Now()
+
+	created := &sync.Map{}
+	createErr := make(chan error, 1)
+	go func() {
+		createErr <- createNSTemplateSets(cl, opts, created)
+	}()
+
+	latencies := make(map[string]time.Duration, opts.Count)
+	deadline := start.Add(opts.Timeout)
+	for len(latencies) < opts.Count {
+		select {
+		case err := <-createErr:
+			if err != nil {
+				return nil, err
+			}
+		default:
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s with %d of %d NSTemplateSets ready", opts.Timeout, len(latencies), opts.Count)
+		}
+		time.Sleep(pollInterval)
+		nsTmplSets := &toolchainv1alpha1.NSTemplateSetList{}
+		if err := cl.List(context.TODO(), nsTmplSets, client.InNamespace(opts.Namespace), client.MatchingLabels(map[string]string{harnessLabel: "true"})); err != nil {
+			return nil, errs.Wrap(err, "failed to list the NSTemplateSets")
+		}
+		now := time.Now()
+		for _, nsTmplSet := range nsTmplSets.Items {
+			if _, done := latencies[nsTmplSet.Name]; done || !isReady(nsTmplSet) {
+				continue
+			}
+			if createdAt, ok := created.Load(nsTmplSet.Name); ok {
+				latencies[nsTmplSet.Name] = now.Sub(createdAt.(time.Time))
+			}
+		}
+	}
+	elapsed := time.Since(start)
+
+	all := make([]time.Duration, 0, len(latencies))
+	for _, latency := range latencies {
+		all = append(all, latency)
+	}
+	return newReport(elapsed, all, callsSince(callsBefore, calls.Counts())), nil
+}
+
+// Cleanup deletes the NSTemplateSets created by the harness in the given namespace
+func Cleanup(cl client.Client, namespace string) error {
+	nsTmplSets := &toolchainv1alpha1.NSTemplateSetList{}
+	if err := cl.List(context.TODO(), nsTmplSets, client.InNamespace(namespace), client.MatchingLabels(map[string]string{harnessLabel: "true"})); err != nil {
+		return errs.Wrap(err, "failed to list the NSTemplateSets")
+	}
+	for i := range nsTmplSets.Items {
+		if err := cl.Delete(context.TODO(), &nsTmplSets.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+			return errs.Wrapf(err, "failed to delete the NSTemplateSet '%s'", nsTmplSets.Items[i].Name)
+		}
+	}
+	return nil
+}
+
+// createNSTemplateSets creates the NSTemplateSets with the given number of workers and records their creation time
+func createNSTemplateSets(cl client.Client, opts Options, created *sync.Map) error {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	names := make(chan string)
+	failed := make(chan struct{})
+	var failure error
+	once := sync.Once{}
+	wg := sync.WaitGroup{}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				created.Store(name, time.Now())
+				if err := cl.Create(context.TODO(), newNSTemplateSet(opts.Namespace, name, opts.Namespaces)); err != nil {
+					once.Do(func() {
+						failure = errs.Wrapf(err, "failed to create the NSTemplateSet '%s'", name)
+						close(failed)
+					})
+					return
+				}
+			}
+		}()
+	}
+produce:
+	for i := 0; i < opts.Count; i++ {
+		select {
+		case names <- fmt.Sprintf("scale%05d", i):
+		case <-failed:
+			break produce
+		}
+	}
+	close(names)
+	wg.Wait()
+	return failure
+}
+
+// newNSTemplateSet returns a new NSTemplateSet of the synthetic tier, with the given number of namespaces
+func newNSTemplateSet(namespace, name string, namespaces int) *toolchainv1alpha1.NSTemplateSet {
+	nsTmplSet := &toolchainv1alpha1.NSTemplateSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{harnessLabel: "true"},
+		},
+		Spec: toolchainv1alpha1.NSTemplateSetSpec{
+			TierName: TierName,
+		},
+	}
+	for i := 0; i < namespaces; i++ {
+		nsTmplSet.Spec.Namespaces = append(nsTmplSet.Spec.Namespaces, toolchainv1alpha1.NSTemplateSetNamespace{
+			Type:     fmt.Sprintf("ns%d", i),
+			Revision: "1",
+		})
+	}
+	return nsTmplSet
+}
+
+// isReady returns `true` if all the namespaces of the given NSTemplateSet were provisioned
+func isReady(nsTmplSet toolchainv1alpha1.NSTemplateSet) bool {
+	ready, found := condition.FindConditionByType(nsTmplSet.Status.Conditions, toolchainv1alpha1.ConditionReady)
+	return found && ready.Status == corev1.ConditionTrue
+}
+
+// callsSince returns the number of calls made since the given counts were taken
+func callsSince(before, after map[string]int) map[string]int {
+	calls := make(map[string]int, len(after))
+	for key, count := range after {
+		if diff := count - before[key]; diff > 0 {
+			calls[key] = diff
+		}
+	}
+	return calls
+}
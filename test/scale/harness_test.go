@@ -0,0 +1,68 @@
+package scale
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/pkg/apis/toolchain/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const operatorNamespace = "toolchain-member-operator"
+
+func TestCreateNSTemplateSets(t *testing.T) {
+	opts := Options{Namespace: operatorNamespace, Count: 10, Concurrency: 3, Namespaces: 2}
+
+	t.Run("all created", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		created := &sync.Map{}
+
+		// when
+		err := createNSTemplateSets(cl, opts, created)
+
+		// then
+		require.NoError(t, err)
+		nsTmplSets := &toolchainv1alpha1.NSTemplateSetList{}
+		require.NoError(t, cl.List(context.TODO(), nsTmplSets, client.InNamespace(operatorNamespace)))
+		require.Len(t, nsTmplSets.Items, 10)
+		for _, nsTmplSet := range nsTmplSets.Items {
+			assert.Equal(t, TierName, nsTmplSet.Spec.TierName)
+			assert.Len(t, nsTmplSet.Spec.Namespaces, 2)
+			_, ok := created.Load(nsTmplSet.Name)
+			assert.True(t, ok)
+		}
+
+		t.Run("cleanup", func(t *testing.T) {
+			// when
+			err := Cleanup(cl, operatorNamespace)
+
+			// then
+			require.NoError(t, err)
+			require.NoError(t, cl.List(context.TODO(), nsTmplSets, client.InNamespace(operatorNamespace)))
+			assert.Empty(t, nsTmplSets.Items)
+		})
+	})
+
+	t.Run("creation fails", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		cl.MockCreate = func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+			return errors.New("mock error")
+		}
+
+		// when
+		err := createNSTemplateSets(cl, opts, &sync.Map{})
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "mock error")
+	})
+}
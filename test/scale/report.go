@@ -0,0 +1,88 @@
+package scale
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report the results of a run of the harness
+type Report struct {
+	// Count the number of NSTemplateSets which were provisioned
+	Count int
+	// Elapsed the time it took to provision all the NSTemplateSets
+	Elapsed time.Duration
+	// Latencies the time it took to provision each NSTemplateSet, from its creation until it was ready, in ascending order
+	Latencies []time.Duration
+	// APICalls the number of calls to the API server made by the controllers, by verb and resource
+	APICalls map[string]int
+}
+
+// newReport returns the report of the provisioning of NSTemplateSets with the given latencies
+func newReport(elapsed time.Duration, latencies []time.Duration, calls map[string]int) *Report {
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &Report{
+		Count:     len(sorted),
+		Elapsed:   elapsed,
+		Latencies: sorted,
+		APICalls:  calls,
+	}
+}
+
+// Throughput returns the number of NSTemplateSets provisioned per second
+func (r *Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Count) / r.Elapsed.Seconds()
+}
+
+// Percentile returns the latency below which the given percentage of the NSTemplateSets were provisioned (nearest-rank method)
+func (r *Report) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(r.Latencies))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(r.Latencies) {
+		rank = len(r.Latencies)
+	}
+	return r.Latencies[rank-1]
+}
+
+// TotalAPICalls returns the total number of calls to the API server
+func (r *Report) TotalAPICalls() int {
+	total := 0
+	for _, count := range r.APICalls {
+		total += count
+	}
+	return total
+}
+
+// String returns the report in a human readable form
+func (r *Report) String() string {
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "provisioned %d NSTemplateSets in %s (%.2f/s)\n", r.Count, r.Elapsed.Round(time.Millisecond), r.Throughput())
+	fmt.Fprintf(out, "apply latency: p50=%s p90=%s p99=%s max=%s\n",
+		r.Percentile(50).Round(time.Millisecond), r.Percentile(90).Round(time.Millisecond),
+		r.Percentile(99).Round(time.Millisecond), r.Percentile(100).Round(time.Millisecond))
+	fmt.Fprintf(out, "API calls: %d", r.TotalAPICalls())
+	if r.Count > 0 {
+		fmt.Fprintf(out, " (%.1f per NSTemplateSet)", float64(r.TotalAPICalls())/float64(r.Count))
+	}
+	keys := make([]string, 0, len(r.APICalls))
+	for key := range r.APICalls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(out, "\n  %-50s %d", key, r.APICalls[key])
+	}
+	return out.String()
+}
@@ -0,0 +1,43 @@
+package scale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	// given
+	var latencies []time.Duration
+	for i := 100; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	// when
+	report := newReport(4*time.Second, latencies, map[string]int{"CREATE configmaps": 300, "UPDATE nstemplatesets/status": 100})
+
+	// then
+	assert.Equal(t, 100, report.Count)
+	assert.Equal(t, 25.0, report.Throughput())
+	assert.Equal(t, time.Millisecond, report.Percentile(0))
+	assert.Equal(t, 50*time.Millisecond, report.Percentile(50))
+	assert.Equal(t, 95*time.Millisecond, report.Percentile(95))
+	assert.Equal(t, 100*time.Millisecond, report.Percentile(100))
+	assert.Equal(t, 400, report.TotalAPICalls())
+	assert.Equal(t, `provisioned 100 NSTemplateSets in 4s (25.00/s)
+apply latency: p50=50ms p90=90ms p99=99ms max=100ms
+API calls: 400 (4.0 per NSTemplateSet)
+  CREATE configmaps                                  300
+  UPDATE nstemplatesets/status                       100`, report.String())
+}
+
+func TestEmptyReport(t *testing.T) {
+	// when
+	report := newReport(0, nil, map[string]int{})
+
+	// then
+	assert.Equal(t, 0.0, report.Throughput())
+	assert.Equal(t, time.Duration(0), report.Percentile(99))
+	assert.Equal(t, "provisioned 0 NSTemplateSets in 0s (0.00/s)\napply latency: p50=0s p90=0s p99=0s max=0s\nAPI calls: 0", report.String())
+}
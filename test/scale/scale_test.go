@@ -0,0 +1,74 @@
+package scale
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestScale provisions the synthetic NSTemplateSets against the target set in `SCALE_TARGET` (`envtest` or `kind`), and fails
+// if the p95 of the latencies or the throughput exceed the optional `SCALE_MAX_P95` and `SCALE_MIN_THROUGHPUT` thresholds.
+// Skipped unless `SCALE_TARGET` is set (see `make test-scale`)
+func TestScale(t *testing.T) {
+	target := os.Getenv("SCALE_TARGET")
+	if target == "" {
+		t.Skip("SCALE_TARGET is not set")
+	}
+	opts := Options{
+		Namespace:   envOrDefault("SCALE_NAMESPACE", operatorNamespace),
+		Count:       intEnv(t, "SCALE_USERS", 100),
+		Concurrency: intEnv(t, "SCALE_CONCURRENCY", 10),
+		Namespaces:  intEnv(t, "SCALE_NAMESPACES", 2),
+		Timeout:     durationEnv(t, "SCALE_TIMEOUT", 10*time.Minute),
+	}
+	env, err := Start(target, "../../deploy/crds", opts.Namespace, intEnv(t, "SCALE_OBJECTS", 10))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, Cleanup(env.Client, opts.Namespace))
+		assert.NoError(t, env.Stop())
+	}()
+
+	// when
+	report, err := Run(env.Client, env.APICalls, opts)
+
+	// then
+	require.NoError(t, err)
+	t.Log("\n" + report.String())
+	if maxP95 := durationEnv(t, "SCALE_MAX_P95", 0); maxP95 > 0 {
+		assert.True(t, report.Percentile(95) <= maxP95, "the p95 of the latencies (%s) exceeds %s", report.Percentile(95), maxP95)
+	}
+	if minThroughput := intEnv(t, "SCALE_MIN_THROUGHPUT", 0); minThroughput > 0 {
+		assert.True(t, report.Throughput() >= float64(minThroughput), "the throughput (%.2f/s) is below %d/s", report.Throughput(), minThroughput)
+	}
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value, found := os.LookupEnv(key); found {
+		return value
+	}
+	return defaultValue
+}
+
+func intEnv(t *testing.T, key string, defaultValue int) int {
+	value, found := os.LookupEnv(key)
+	if !found {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(value)
+	require.NoError(t, err, "invalid value of %s", key)
+	return i
+}
+
+func durationEnv(t *testing.T, key string, defaultValue time.Duration) time.Duration {
+	value, found := os.LookupEnv(key)
+	if !found {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	require.NoError(t, err, "invalid value of %s", key)
+	return d
+}
@@ -0,0 +1,78 @@
+package scale
+
+import (
+	"fmt"
+	"strings"
+
+	templatev1 "github.com/openshift/api/template/v1"
+	errs "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+// TierName the name of the synthetic tier of the NSTemplateSets created by the harness
+const TierName = "scale"
+
+// newTemplateContent returns the func which retrieves the templates of the synthetic tier, in place of the NSTemplateTiers
+// of the host cluster. Each namespace of the tier contains a ResourceQuota, a LimitRange and the given number of ConfigMaps.
+func newTemplateContent(s *runtime.Scheme, objects int) func(tierName, typeName string) (*templatev1.Template, error) {
+	decoder := serializer.NewCodecFactory(s).UniversalDeserializer()
+	return func(tierName, typeName string) (*templatev1.Template, error) {
+		// a new template is decoded every time, since the processor sets the values of its parameters
+		tmpl := &templatev1.Template{}
+		if _, _, err := decoder.Decode([]byte(syntheticTemplate(typeName, objects)), nil, tmpl); err != nil {
+			return nil, errs.Wrapf(err, "unable to decode the template of the namespace type '%s'", typeName)
+		}
+		return tmpl, nil
+	}
+}
+
+// syntheticTemplate returns the template of the namespace of the given type in the synthetic tier
+func syntheticTemplate(typeName string, objects int) string {
+	tmpl := &strings.Builder{}
+	fmt.Fprintf(tmpl, `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: %[1]s-%[2]s
+objects:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: ${USERNAME}-%[2]s
+- apiVersion: v1
+  kind: ResourceQuota
+  metadata:
+    name: compute-resources
+    namespace: ${USERNAME}-%[2]s
+  spec:
+    hard:
+      limits.cpu: "2"
+      limits.memory: 7Gi
+- apiVersion: v1
+  kind: LimitRange
+  metadata:
+    name: resource-limits
+    namespace: ${USERNAME}-%[2]s
+  spec:
+    limits:
+    - type: Container
+      default:
+        cpu: 500m
+        memory: 512Mi
+`, TierName, typeName)
+	for i := 0; i < objects; i++ {
+		fmt.Fprintf(tmpl, `- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: config-%[1]d
+    namespace: ${USERNAME}-%[2]s
+  data:
+    index: "%[1]d"
+`, i, typeName)
+	}
+	tmpl.WriteString(`parameters:
+- name: USERNAME
+  required: true
+`)
+	return tmpl.String()
+}
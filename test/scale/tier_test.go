@@ -0,0 +1,35 @@
+package scale
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/member-operator/pkg/apis"
+	"github.com/codeready-toolchain/member-operator/pkg/template"
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestSyntheticTemplate(t *testing.T) {
+	// given
+	s := runtime.NewScheme()
+	require.NoError(t, apis.AddToScheme(s))
+	getTemplateContent := newTemplateContent(s, 3)
+
+	// when
+	tmpl, err := getTemplateContent(TierName, "ns0")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "scale-ns0", tmpl.Name)
+	objs, err := template.NewProcessor(test.NewFakeClient(t), s).Process(tmpl, map[string]string{"USERNAME": "johnsmith"})
+	require.NoError(t, err)
+	require.Len(t, objs, 6)
+	kinds := map[string]int{}
+	for _, obj := range objs {
+		kinds[obj.Object.GetObjectKind().GroupVersionKind().Kind]++
+	}
+	assert.Equal(t, map[string]int{"Namespace": 1, "ResourceQuota": 1, "LimitRange": 1, "ConfigMap": 3}, kinds)
+}